@@ -908,6 +908,11 @@ typedef struct S32579 { unsigned char data[1]; } S32579;
 enum Enum40494 { X_40494 };
 union Union40494 { int x; };
 void issue40494(enum Enum40494 e, union Union40494* up) {}
+
+// #cgo fastcall
+// Calls that do not enter a system call.
+#cgo fastcall fastcallAdd
+static int fastcallAdd(int x, int y) { return x + y; }
 */
 import "C"
 
@@ -1082,6 +1087,15 @@ func benchCgoCall(b *testing.B) {
 		}
 	})
 
+	b.Run("add-int-fastcall", func(b *testing.B) {
+		const x = C.int(2)
+		const y = C.int(3)
+
+		for i := 0; i < b.N; i++ {
+			C.fastcallAdd(x, y)
+		}
+	})
+
 	b.Run("one-pointer", func(b *testing.B) {
 		var a0 C.VkDeviceCreateInfo
 		for i := 0; i < b.N; i++ {
@@ -2244,3 +2258,17 @@ var issue39877 *C.void = nil
 func Issue40494() {
 	C.issue40494(C.enum_Enum40494(C.X_40494), (*C.union_Union40494)(nil))
 }
+
+// #cgo fastcall
+
+func testFastcall(t *testing.T) {
+	n := runtime.NumCgoCall()
+	for i := 0; i < 1000; i++ {
+		if r := C.fastcallAdd(C.int(i), 2); r != C.int(i+2) {
+			t.Fatalf("fastcallAdd(%d, 2) = %d, want %d", i, r, i+2)
+		}
+	}
+	if d := runtime.NumCgoCall() - n; d < 1000 {
+		t.Errorf("NumCgoCall grew by %d over 1000 fast calls", d)
+	}
+}
@@ -598,6 +598,50 @@ func TestSignalHandlersWithNotify(t *testing.T) {
 	testSignalHandlers(t, "./libgo5", "main5.c", "testp5")
 }
 
+// test6: test that two Go shared libraries can profile at the same time
+func TestTwoLibrariesProfiling(t *testing.T) {
+	t.Parallel()
+	if GOOS == "windows" {
+		t.Logf("Skipping on %s", GOOS)
+		return
+	}
+
+	// Load two copies of the same library, so that each has a
+	// runtime of its own.
+	pkgname := "./libgo6"
+	liba := pkgname + "a." + libSuffix
+	libb := pkgname + "b." + libSuffix
+	run(t,
+		nil,
+		"go", "build",
+		"-buildmode=c-shared",
+		"-installsuffix", "testcshared",
+		"-o", liba, pkgname,
+	)
+	copyFile(t, libb, liba)
+	adbPush(t, liba)
+	adbPush(t, libb)
+	cmd := "testp6"
+	if GOOS != "freebsd" {
+		runCC(t, "-pthread", "-o", cmd, "main6.c", "-ldl")
+	} else {
+		runCC(t, "-pthread", "-o", cmd, "main6.c")
+	}
+	adbPush(t, cmd)
+
+	bin := cmdToRun(cmd)
+
+	defer os.Remove(liba)
+	defer os.Remove(libb)
+	defer os.Remove(bin)
+	defer os.Remove(pkgname + "a.h")
+
+	out := runExe(t, nil, bin, liba, libb)
+	if strings.TrimSpace(out) != "PASS" {
+		t.Error(run(t, nil, bin, liba, libb, "verbose"))
+	}
+}
+
 func TestPIE(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,111 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "C"
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"runtime/pprof"
+	"time"
+)
+
+var profile bytes.Buffer
+
+// StartProfile starts CPU profiling.
+//export StartProfile
+func StartProfile() C.int {
+	profile.Reset()
+	if err := pprof.StartCPUProfile(&profile); err != nil {
+		return 0
+	}
+	return 1
+}
+
+// Spin keeps a goroutine busy for ms milliseconds.
+//export Spin
+func Spin(ms C.int) {
+	done := make(chan bool)
+	go func() {
+		end := time.Now().Add(time.Duration(ms) * time.Millisecond)
+		for time.Now().Before(end) {
+		}
+		done <- true
+	}()
+	<-done
+}
+
+// StopProfile stops CPU profiling and returns the number of samples
+// in the profile, or -1 if it cannot be decoded.
+//export StopProfile
+func StopProfile() C.int {
+	pprof.StopCPUProfile()
+	r, err := gzip.NewReader(&profile)
+	if err != nil {
+		return -1
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return -1
+	}
+	return C.int(countSamples(b))
+}
+
+// countSamples counts the Sample messages, field 2, of an encoded
+// profile.proto Profile message.
+func countSamples(b []byte) int {
+	n := 0
+	for len(b) > 0 {
+		key, ok := varint(&b)
+		if !ok {
+			return -1
+		}
+		switch key & 7 {
+		case 0:
+			if _, ok := varint(&b); !ok {
+				return -1
+			}
+		case 1:
+			if len(b) < 8 {
+				return -1
+			}
+			b = b[8:]
+		case 2:
+			l, ok := varint(&b)
+			if !ok || uint64(len(b)) < l {
+				return -1
+			}
+			b = b[l:]
+			if key>>3 == 2 {
+				n++
+			}
+		case 5:
+			if len(b) < 4 {
+				return -1
+			}
+			b = b[4:]
+		default:
+			return -1
+		}
+	}
+	return n
+}
+
+func varint(b *[]byte) (uint64, bool) {
+	var x uint64
+	for i, c := range *b {
+		x |= uint64(c&0x7f) << (7 * uint(i))
+		if c < 0x80 {
+			*b = (*b)[i+1:]
+			return x, true
+		}
+	}
+	return 0, false
+}
+
+func main() {
+}
@@ -0,0 +1,110 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Test that two copies of a Go shared library, each with a runtime of
+// its own, can profile at the same time. The library loaded second
+// installs its SIGPROF handler on top of the first one's, so it must
+// pass on the signals meant for the first library's threads, and
+// stopping the first profile must not remove the second handler.
+
+#include <dlfcn.h>
+#include <pthread.h>
+#include <signal.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+
+struct lib {
+	int (*startProfile)(void);
+	void (*spin)(int);
+	int (*stopProfile)(void);
+};
+
+static void* sym(void* handle, const char* name) {
+	void* p;
+
+	p = dlsym(handle, name);
+	if (p == NULL) {
+		fprintf(stderr, "%s\n", dlerror());
+		exit(EXIT_FAILURE);
+	}
+	return p;
+}
+
+static void load(const char* path, struct lib* l) {
+	void* handle;
+
+	handle = dlopen(path, RTLD_NOW | RTLD_LOCAL);
+	if (handle == NULL) {
+		fprintf(stderr, "%s\n", dlerror());
+		exit(EXIT_FAILURE);
+	}
+	l->startProfile = (int (*)(void))sym(handle, "StartProfile");
+	l->spin = (void (*)(int))sym(handle, "Spin");
+	l->stopProfile = (int (*)(void))sym(handle, "StopProfile");
+}
+
+static void* spin(void* arg) {
+	((struct lib*)arg)->spin(500);
+	return NULL;
+}
+
+int main(int argc, char** argv) {
+	int verbose;
+	struct lib a, b;
+	pthread_t ta, tb;
+	struct sigaction sa;
+	int na, nb;
+
+	verbose = argc > 3;
+	setvbuf(stdout, NULL, _IONBF, 0);
+
+	load(argv[1], &a);
+	load(argv[2], &b);
+
+	if (!a.startProfile() || !b.startProfile()) {
+		fprintf(stderr, "StartProfile failed\n");
+		exit(EXIT_FAILURE);
+	}
+
+	if (verbose) {
+		printf("spinning\n");
+	}
+
+	if (pthread_create(&ta, NULL, spin, &a) != 0 || pthread_create(&tb, NULL, spin, &b) != 0) {
+		perror("pthread_create");
+		exit(EXIT_FAILURE);
+	}
+	pthread_join(ta, NULL);
+	pthread_join(tb, NULL);
+
+	na = a.stopProfile();
+
+	// The second library is still profiling, so its handler must
+	// still be installed.
+	memset(&sa, 0, sizeof sa);
+	if (sigaction(SIGPROF, NULL, &sa) < 0) {
+		perror("sigaction");
+		exit(EXIT_FAILURE);
+	}
+	if (sa.sa_handler == SIG_IGN || sa.sa_handler == SIG_DFL) {
+		fprintf(stderr, "SIGPROF handler removed while still profiling\n");
+		exit(EXIT_FAILURE);
+	}
+
+	b.spin(100);
+	nb = b.stopProfile();
+
+	if (verbose) {
+		printf("samples: %d %d\n", na, nb);
+	}
+
+	if (na <= 0 || nb <= 0) {
+		fprintf(stderr, "missing samples: first library %d, second library %d\n", na, nb);
+		exit(EXIT_FAILURE);
+	}
+
+	printf("PASS\n");
+	return 0;
+}
@@ -238,6 +238,19 @@ C compilers are aware of this calling convention and adjust
 the call accordingly, but Go cannot. In Go, you must pass
 the pointer to the first element explicitly: C.f(&C.x[0]).
 
+A call to a C function tells the Go scheduler that the goroutine may
+block, so that other goroutines can run on its processor in the
+meantime. For C functions that return quickly, this bookkeeping can
+cost more than the call itself. The directive
+
+	// #cgo fastcall fname
+
+in the preamble makes calls to C.fname skip it. The function must not
+block, must not call back into Go (doing so crashes the program), and
+should run for no more than a few microseconds: until it returns, its
+processor runs no other goroutine and the garbage collector cannot
+stop the world.
+
 Calling variadic C functions is not supported. It is possible to
 circumvent this by using a C function wrapper. For example:
 
@@ -69,16 +69,22 @@ func cname(s string) string {
 }
 
 // DiscardCgoDirectives processes the import C preamble, and discards
-// all #cgo CFLAGS and LDFLAGS directives, so they don't make their
-// way into _cgo_export.h.
+// all #cgo CFLAGS, LDFLAGS and fastcall directives, so they don't make
+// their way into _cgo_export.h. It records the functions named by
+// fastcall directives in f.FastCalls.
 func (f *File) DiscardCgoDirectives() {
 	linesIn := strings.Split(f.Preamble, "\n")
 	linesOut := make([]string, 0, len(linesIn))
+	f.FastCalls = make(map[string]bool)
 	for _, line := range linesIn {
 		l := strings.TrimSpace(line)
 		if len(l) < 5 || l[:4] != "#cgo" || !unicode.IsSpace(rune(l[4])) {
 			linesOut = append(linesOut, line)
 		} else {
+			// #cgo fastcall <function name>
+			if fields := strings.Fields(l); len(fields) == 3 && fields[1] == "fastcall" {
+				f.FastCalls[fields[2]] = true
+			}
 			linesOut = append(linesOut, "")
 		}
 	}
@@ -48,6 +48,7 @@ type Package struct {
 	Preamble    string          // collected preamble for _cgo_export.h
 	typedefs    map[string]bool // type names that appear in the types of the objects we're interested in
 	typedefList []typedefInfo
+	fastCalls   map[string]bool // accumulated FastCalls from Files
 }
 
 // A typedefInfo is an element on Package.typedefList: a typedef name
@@ -69,6 +70,10 @@ type File struct {
 	Name     map[string]*Name    // map from Go name to Name
 	NamePos  map[*Name]token.Pos // map from Name to position of the first reference
 	Edit     *edit.Buffer
+
+	// FastCalls is the set of C functions named by #cgo fastcall
+	// directives, which are called without entering a system call.
+	FastCalls map[string]bool
 }
 
 func (f *File) offset(p token.Pos) int {
@@ -419,10 +424,11 @@ func newPackage(args []string) *Package {
 	os.Setenv("LC_ALL", "C")
 
 	p := &Package{
-		PtrSize:  ptrSize,
-		IntSize:  intSize,
-		CgoFlags: make(map[string][]string),
-		Written:  make(map[string]bool),
+		PtrSize:   ptrSize,
+		IntSize:   intSize,
+		CgoFlags:  make(map[string][]string),
+		Written:   make(map[string]bool),
+		fastCalls: make(map[string]bool),
 	}
 	p.addToFlag("CFLAGS", args)
 	return p
@@ -456,6 +462,10 @@ func (p *Package) Record(f *File) {
 		}
 	}
 
+	for k := range f.FastCalls {
+		p.fastCalls[k] = true
+	}
+
 	if f.ExpFunc != nil {
 		p.ExpFunc = append(p.ExpFunc, f.ExpFunc...)
 		p.Preamble += "\n" + f.Preamble
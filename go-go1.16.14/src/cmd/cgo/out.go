@@ -598,7 +598,11 @@ func (p *Package) writeDefsFunc(fgo2 io.Writer, n *Name, callsMalloc *bool) {
 	if n.AddError {
 		prefix = "errno := "
 	}
-	fmt.Fprintf(fgo2, "\t%s_cgo_runtime_cgocall(%s, %s)\n", prefix, cname, arg)
+	call := "_cgo_runtime_cgocall"
+	if p.fastCalls[n.C] {
+		call = "_cgo_runtime_cgocallfast"
+	}
+	fmt.Fprintf(fgo2, "\t%s%s(%s, %s)\n", prefix, call, cname, arg)
 	if n.AddError {
 		fmt.Fprintf(fgo2, "\tif errno != 0 { r2 = syscall.Errno(errno) }\n")
 	}
@@ -1561,6 +1565,9 @@ const goProlog = `
 //go:linkname _cgo_runtime_cgocall runtime.cgocall
 func _cgo_runtime_cgocall(unsafe.Pointer, uintptr) int32
 
+//go:linkname _cgo_runtime_cgocallfast runtime.cgocallfast
+func _cgo_runtime_cgocallfast(unsafe.Pointer, uintptr) int32
+
 //go:linkname _cgoCheckPointer runtime.cgoCheckPointer
 func _cgoCheckPointer(interface{}, interface{})
 
@@ -447,6 +447,12 @@ func findLastMems(f *Func) []*Value {
 		if last == nil {
 			b.Fatalf("no last store found - cycle?")
 		}
+		// The last store may be an op that also produces a value,
+		// such as an atomic add, whose memory result nothing in
+		// the block selects. Phis and calls need the memory alone.
+		if last.Type.IsTuple() {
+			last = b.NewValue1(last.Pos, OpSelect1, types.TypeMem, last)
+		}
 		lastMems[b.ID] = last
 	}
 	return lastMems
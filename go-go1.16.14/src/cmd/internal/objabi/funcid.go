@@ -35,6 +35,7 @@ const (
 	FuncID_handleAsyncEvent
 	FuncID_asyncPreempt
 	FuncID_wrapper // any autogenerated code (hash/eq algorithms, method wrappers, etc.)
+	FuncID_debugCallV2
 )
 
 // Get the function ID for the named function in the named file.
@@ -78,6 +79,8 @@ func GetFuncID(name string, isWrapper bool) FuncID {
 		return FuncID_externalthreadhandler
 	case "runtime.debugCallV1":
 		return FuncID_debugCallV1
+	case "runtime.debugCallV2":
+		return FuncID_debugCallV2
 	case "runtime.gopanic":
 		return FuncID_gopanic
 	case "runtime.panicwrap":
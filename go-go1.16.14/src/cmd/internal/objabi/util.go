@@ -134,6 +134,15 @@ func init() {
 	if GOARCH != "amd64" {
 		Regabi_enabled = 0
 	}
+
+	// Targets without asynchronous preemption get preemption
+	// checks on loop backedges instead, so that a loop without
+	// calls cannot hold off the garbage collector indefinitely.
+	// This must agree with runtime.preemptMSupported. js/wasm is
+	// left out: it has no sysmon to ask for preemption.
+	if GOOS == "plan9" || GOOS == "windows" && GOARCH == "arm" {
+		Preemptibleloops_enabled = 1
+	}
 }
 
 // Note: must agree with runtime.framepointer_enabled.
@@ -709,6 +709,11 @@ func (ctxt *Link) linksetup() {
 		sb.SetType(sym.SNOPTRDATA)
 		sb.AddUint8(1)
 	}
+	if ctxt.BuildMode != BuildModeShared {
+		// For runtime.ReadBuildConfig. The runtime of a shared
+		// library serves programs of any build mode.
+		addstrdata1(ctxt, "runtime.buildMode="+ctxt.BuildMode.String())
+	}
 
 	// Recalculate pe parameters now that we have ctxt.LinkMode set.
 	if ctxt.HeadType == objabi.Hwindows {
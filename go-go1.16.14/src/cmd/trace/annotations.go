@@ -290,7 +290,7 @@ func analyzeAnnotations() (annotationAnalysisResult, error) {
 
 	for _, ev := range events {
 		switch typ := ev.Type; typ {
-		case trace.EvUserTaskCreate, trace.EvUserTaskEnd, trace.EvUserLog:
+		case trace.EvUserTaskCreate, trace.EvUserTaskEnd, trace.EvUserLog, trace.EvUserLogInt:
 			taskid := ev.Args[0]
 			task := tasks.task(taskid)
 			task.addEvent(ev)
@@ -739,7 +739,7 @@ func newTaskFilter(r *http.Request) (*taskFilter, error) {
 func taskMatches(t *taskDesc, text string) bool {
 	for _, ev := range t.events {
 		switch ev.Type {
-		case trace.EvUserTaskCreate, trace.EvUserRegion, trace.EvUserLog:
+		case trace.EvUserTaskCreate, trace.EvUserRegion, trace.EvUserLog, trace.EvUserLogInt:
 			for _, s := range ev.SArgs {
 				if strings.Contains(s, text) {
 					return true
@@ -1143,7 +1143,7 @@ func describeEvent(ev *trace.Event) string {
 		return fmt.Sprintf("new goroutine %d: %s", goid, gs[goid].Name)
 	case trace.EvGoEnd, trace.EvGoStop:
 		return "goroutine stopped"
-	case trace.EvUserLog:
+	case trace.EvUserLog, trace.EvUserLogInt:
 		return formatUserLog(ev)
 	case trace.EvUserRegion:
 		if ev.Args[1] == 0 {
@@ -1165,7 +1165,7 @@ func describeEvent(ev *trace.Event) string {
 
 func isUserAnnotationEvent(ev *trace.Event) (taskID uint64, ok bool) {
 	switch ev.Type {
-	case trace.EvUserLog, trace.EvUserRegion, trace.EvUserTaskCreate, trace.EvUserTaskEnd:
+	case trace.EvUserLog, trace.EvUserLogInt, trace.EvUserRegion, trace.EvUserTaskCreate, trace.EvUserTaskEnd:
 		return ev.Args[0], true
 	}
 	return 0, false
@@ -272,7 +272,7 @@ function reloadTable(key, value) {
 </tr>
 {{range .GList}}
   <tr>
-    <td> <a href="/trace?goid={{.ID}}">{{.ID}}</a> </td>
+    <td> <a href="/trace?goid={{.ID}}">{{.ID}}</a>{{if .UserName}} {{.UserName}}{{end}} </td>
     <td> {{prettyDuration .TotalTime}} </td>
     <td>
 	<div class="stacked-bar-graph">
@@ -474,11 +474,20 @@ const (
 type gInfo struct {
 	state      gState // current state
 	name       string // name chosen for this goroutine at first EvGoStart
+	userName   string // name set with runtime.SetGoroutineName, if any
 	isSystemG  bool
 	start      *trace.Event // most recent EvGoStart
 	markAssist *trace.Event // if non-nil, the mark assist currently running.
 }
 
+// label returns the name to display for the goroutine.
+func (info *gInfo) label() string {
+	if info.userName == "" {
+		return info.name
+	}
+	return fmt.Sprintf("%s %q", info.name, info.userName)
+}
+
 type NameArg struct {
 	Name string `json:"name"`
 }
@@ -584,6 +593,8 @@ func generateTrace(params *traceParams, consumer traceConsumer) error {
 
 			ctx.gcount++
 			setGState(ev, newG, gDead, gRunnable)
+		case trace.EvGoName:
+			getGInfo(ev.Args[0]).userName = ev.SArgs[0]
 		case trace.EvGoEnd:
 			ctx.gcount--
 			setGState(ev, ev.G, gRunning, gDead)
@@ -698,7 +709,7 @@ func generateTrace(params *traceParams, consumer traceConsumer) error {
 			if ev.Type == trace.EvGoStartLabel {
 				ctx.emitSlice(ev, ev.SArgs[0])
 			} else {
-				ctx.emitSlice(ev, info.name)
+				ctx.emitSlice(ev, info.label())
 			}
 			if info.markAssist != nil {
 				// If we're in a mark assist, synthesize a new slice, ending
@@ -721,8 +732,18 @@ func generateTrace(params *traceParams, consumer traceConsumer) error {
 			ctx.emitInstant(ev, "syscall", "")
 		case trace.EvGoSysExit:
 			ctx.emitArrow(ev, "sysexit")
-		case trace.EvUserLog:
+		case trace.EvUserLog, trace.EvUserLogInt:
 			ctx.emitInstant(ev, formatUserLog(ev), "user event")
+		case trace.EvUserCounter:
+			ctx.emit(&traceviewer.Event{Name: ev.SArgs[0], Phase: "C", Time: ctx.time(ev), PID: 1, Arg: &userCounterArg{int64(ev.Args[1])}})
+		case trace.EvUserFlow:
+			if ev.Args[1] == 0 {
+				name := ev.SArgs[0]
+				if name == "" {
+					name = "flow"
+				}
+				ctx.emitArrow(ev, name)
+			}
 		case trace.EvUserTaskCreate:
 			ctx.emitInstant(ev, "task start", "user event")
 		case trace.EvUserTaskEnd:
@@ -800,7 +821,7 @@ func generateTrace(params *traceParams, consumer traceConsumer) error {
 			if !ctx.gs[k] {
 				continue
 			}
-			ctx.emitFooter(&traceviewer.Event{Name: "thread_name", Phase: "M", PID: procsSection, TID: k, Arg: &NameArg{v.name}})
+			ctx.emitFooter(&traceviewer.Event{Name: "thread_name", Phase: "M", PID: procsSection, TID: k, Arg: &NameArg{v.label()}})
 		}
 		// Row for the main goroutine (maing)
 		ctx.emitFooter(&traceviewer.Event{Name: "thread_sort_index", Phase: "M", PID: procsSection, TID: ctx.maing, Arg: &SortIndexArg{-2}})
@@ -997,6 +1018,10 @@ func (ctx *traceContext) emitHeapCounters(ev *trace.Event) {
 	ctx.prevHeapStats = ctx.heapStats
 }
 
+type userCounterArg struct {
+	Value int64
+}
+
 type goroutineCountersArg struct {
 	Running   uint64
 	Runnable  uint64
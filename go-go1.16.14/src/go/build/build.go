@@ -1631,6 +1631,11 @@ func (ctxt *Context) saveCgo(filename string, di *Package, cg *ast.CommentGroup)
 			continue
 		}
 
+		// #cgo fastcall <function name> is handled by cmd/cgo.
+		if fields := strings.Fields(line); len(fields) == 3 && fields[1] == "fastcall" {
+			continue
+		}
+
 		// Split at colon.
 		line = strings.TrimSpace(line[4:])
 		i := strings.Index(line, ":")
@@ -514,6 +514,12 @@ var depsRules = `
 
 	FMT, container/heap, math/rand
 	< internal/trace;
+
+	encoding/json, internal/trace, runtime/trace
+	< runtime/trace/chrome;
+
+	internal/trace, runtime/trace
+	< runtime/trace/summary;
 `
 
 // listStdPkgs returns the same list of packages as "go list std".
@@ -10,6 +10,7 @@ import "sort"
 type GDesc struct {
 	ID           uint64
 	Name         string
+	UserName     string // last name set with runtime.SetGoroutineName
 	PC           uint64
 	CreationTime int64
 	StartTime    int64
@@ -150,6 +151,16 @@ type gdesc struct {
 // GoroutineStats generates statistics for all goroutines in the trace.
 func GoroutineStats(events []*Event) map[uint64]*GDesc {
 	gs := make(map[uint64]*GDesc)
+	// getG returns the goroutine with the given id. In partial traces,
+	// goroutines created before the trace started have no EvGoCreate.
+	getG := func(id uint64) *GDesc {
+		g := gs[id]
+		if g == nil {
+			g = &GDesc{ID: id, gdesc: new(gdesc)}
+			gs[id] = g
+		}
+		return g
+	}
 	var lastTs int64
 	var gcStartTime int64 // gcStartTime == 0 indicates gc is inactive.
 	for _, ev := range events {
@@ -173,8 +184,8 @@ func GoroutineStats(events []*Event) map[uint64]*GDesc {
 			}
 			gs[g.ID] = g
 		case EvGoStart, EvGoStartLabel:
-			g := gs[ev.G]
-			if g.PC == 0 {
+			g := getG(ev.G)
+			if g.PC == 0 && len(ev.Stk) > 0 {
 				g.PC = ev.Stk[0].PC
 				g.Name = ev.Stk[0].Fn
 			}
@@ -187,35 +198,37 @@ func GoroutineStats(events []*Event) map[uint64]*GDesc {
 				g.blockSchedTime = 0
 			}
 		case EvGoEnd, EvGoStop:
-			g := gs[ev.G]
+			g := getG(ev.G)
 			g.finalize(ev.Ts, gcStartTime, ev)
+		case EvGoName:
+			getG(ev.Args[0]).UserName = ev.SArgs[0]
 		case EvGoBlockSend, EvGoBlockRecv, EvGoBlockSelect,
 			EvGoBlockSync, EvGoBlockCond:
-			g := gs[ev.G]
+			g := getG(ev.G)
 			g.ExecTime += ev.Ts - g.lastStartTime
 			g.lastStartTime = 0
 			g.blockSyncTime = ev.Ts
 		case EvGoSched, EvGoPreempt:
-			g := gs[ev.G]
+			g := getG(ev.G)
 			g.ExecTime += ev.Ts - g.lastStartTime
 			g.lastStartTime = 0
 			g.blockSchedTime = ev.Ts
 		case EvGoSleep, EvGoBlock:
-			g := gs[ev.G]
+			g := getG(ev.G)
 			g.ExecTime += ev.Ts - g.lastStartTime
 			g.lastStartTime = 0
 		case EvGoBlockNet:
-			g := gs[ev.G]
+			g := getG(ev.G)
 			g.ExecTime += ev.Ts - g.lastStartTime
 			g.lastStartTime = 0
 			g.blockNetTime = ev.Ts
 		case EvGoBlockGC:
-			g := gs[ev.G]
+			g := getG(ev.G)
 			g.ExecTime += ev.Ts - g.lastStartTime
 			g.lastStartTime = 0
 			g.blockGCTime = ev.Ts
 		case EvGoUnblock:
-			g := gs[ev.Args[0]]
+			g := getG(ev.Args[0])
 			if g.blockNetTime != 0 {
 				g.IOTime += ev.Ts - g.blockNetTime
 				g.blockNetTime = 0
@@ -226,12 +239,12 @@ func GoroutineStats(events []*Event) map[uint64]*GDesc {
 			}
 			g.blockSchedTime = ev.Ts
 		case EvGoSysBlock:
-			g := gs[ev.G]
+			g := getG(ev.G)
 			g.ExecTime += ev.Ts - g.lastStartTime
 			g.lastStartTime = 0
 			g.blockSyscallTime = ev.Ts
 		case EvGoSysExit:
-			g := gs[ev.G]
+			g := getG(ev.G)
 			if g.blockSyscallTime != 0 {
 				g.SyscallTime += ev.Ts - g.blockSyscallTime
 				g.blockSyscallTime = 0
@@ -264,7 +277,7 @@ func GoroutineStats(events []*Event) map[uint64]*GDesc {
 			}
 			gcStartTime = 0 // indicates gc is inactive.
 		case EvUserRegion:
-			g := gs[ev.G]
+			g := getG(ev.G)
 			switch mode := ev.Args[1]; mode {
 			case 0: // region start
 				g.activeRegions = append(g.activeRegions, &UserRegionDesc{
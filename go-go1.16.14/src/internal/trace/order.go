@@ -39,6 +39,10 @@ const (
 	garbage   = ^uint64(0) - 1
 	noseq     = ^uint64(0)
 	seqinc    = ^uint64(0) - 1
+
+	// unknownSeq is the sequence of a goroutine in a partial trace
+	// before an event with an explicit sequence number is merged.
+	unknownSeq = ^uint64(0) - 2
 )
 
 // order1007 merges a set of per-P event batches into a single, consistent stream.
@@ -50,7 +54,11 @@ const (
 // event with the lowest timestamp from the subset, merge it and repeat.
 // This approach ensures that we form a consistent stream even if timestamps are
 // incorrect (condition observed on some machines).
-func order1007(m map[int][]*Event) (events []*Event, err error) {
+//
+// If partial is set, the trace lacks a prefix of the events (see
+// runtime/trace.WriteFlightRecorder), and each goroutine is assumed to
+// start out in the state expected by its earliest event.
+func order1007(m map[int][]*Event, partial bool) (events []*Event, err error) {
 	pending := 0
 	var batches []*eventBatch
 	for _, v := range m {
@@ -58,6 +66,27 @@ func order1007(m map[int][]*Event) (events []*Event, err error) {
 		batches = append(batches, &eventBatch{v, false})
 	}
 	gs := make(map[uint64]gState)
+	if partial {
+		first := make(map[uint64]*Event)
+		for _, v := range m {
+			for _, ev := range v {
+				g, _, _ := stateTransition(ev)
+				if g == unordered {
+					continue
+				}
+				if f := first[g]; f == nil || ev.Ts < f.Ts {
+					first[g] = ev
+				}
+			}
+		}
+		for g, ev := range first {
+			_, init, _ := stateTransition(ev)
+			if init.seq == noseq {
+				init.seq = unknownSeq
+			}
+			gs[g] = init
+		}
+	}
 	var frontier []orderEvent
 	for ; pending != 0; pending-- {
 		for i, b := range batches {
@@ -125,6 +154,10 @@ func order1007(m map[int][]*Event) (events []*Event, err error) {
 			}
 			block := lastSysBlock[ev.G]
 			if block == 0 {
+				if partial {
+					// The syscall started before the trace.
+					continue
+				}
 				return nil, fmt.Errorf("stray syscall exit")
 			}
 			if ts < block {
@@ -194,7 +227,7 @@ func stateTransition(ev *Event) (g uint64, init, next gState) {
 }
 
 func transitionReady(g uint64, curr, init gState) bool {
-	return g == unordered || (init.seq == noseq || init.seq == curr.seq) && init.status == curr.status
+	return g == unordered || (init.seq == noseq || init.seq == curr.seq || curr.seq == unknownSeq) && init.status == curr.status
 }
 
 func transition(gs map[uint64]gState, g uint64, init, next gState) {
@@ -209,7 +242,10 @@ func transition(gs map[uint64]gState, g uint64, init, next gState) {
 	case noseq:
 		next.seq = curr.seq
 	case seqinc:
-		next.seq = curr.seq + 1
+		next.seq = curr.seq
+		if curr.seq != unknownSeq {
+			next.seq++
+		}
 	}
 	gs[g] = next
 }
@@ -57,6 +57,7 @@ type Event struct {
 	// for GCMarkAssistStart: the associated GCMarkAssistDone
 	// for UserTaskCreate: the UserTaskEnd
 	// for UserRegion: if the start region, the corresponding UserRegion end event
+	// for UserFlow: if the start of the flow, the corresponding UserFlow end event
 	Link *Event
 }
 
@@ -100,16 +101,16 @@ func Parse(r io.Reader, bin string) (ParseResult, error) {
 // parse parses, post-processes and verifies the trace. It returns the
 // trace version and the list of events.
 func parse(r io.Reader, bin string) (int, ParseResult, error) {
-	ver, rawEvents, strings, err := readTrace(r)
+	ver, partial, rawEvents, strings, err := readTrace(r)
 	if err != nil {
 		return 0, ParseResult{}, err
 	}
-	events, stacks, err := parseEvents(ver, rawEvents, strings)
+	events, stacks, err := parseEvents(ver, partial, rawEvents, strings)
 	if err != nil {
 		return 0, ParseResult{}, err
 	}
 	events = removeFutile(events)
-	err = postProcessTrace(ver, events)
+	err = postProcessTrace(ver, partial, events)
 	if err != nil {
 		return 0, ParseResult{}, err
 	}
@@ -137,7 +138,8 @@ type rawEvent struct {
 
 // readTrace does wire-format parsing and verification.
 // It does not care about specific event types and argument meaning.
-func readTrace(r io.Reader) (ver int, events []rawEvent, strings map[uint64]string, err error) {
+// partial reports whether the trace is a flight recorder snapshot.
+func readTrace(r io.Reader) (ver int, partial bool, events []rawEvent, strings map[uint64]string, err error) {
 	// Read and validate trace header.
 	var buf [16]byte
 	off, err := io.ReadFull(r, buf[:])
@@ -149,6 +151,7 @@ func readTrace(r io.Reader) (ver int, events []rawEvent, strings map[uint64]stri
 	if err != nil {
 		return
 	}
+	partial = bytes.Contains(buf[:], []byte(" flight"))
 	switch ver {
 	case 1005, 1007, 1008, 1009, 1010, 1011:
 		// Note: When adding a new version, add canned traces
@@ -288,7 +291,8 @@ func readStr(r io.Reader, off0 int) (s string, off int, err error) {
 }
 
 // parseHeader parses trace header of the form "go 1.7 trace\x00\x00\x00\x00"
-// and returns parsed version as 1007.
+// and returns parsed version as 1007. Flight recorder snapshots have the
+// header "go 1.11 flight\x00\x00" instead.
 func parseHeader(buf []byte) (int, error) {
 	if len(buf) != 16 {
 		return 0, fmt.Errorf("bad header length")
@@ -305,7 +309,8 @@ func parseHeader(buf []byte) (int, error) {
 		ver = ver*10 + int(buf[6+i]-'0')
 	}
 	ver += int(buf[3]-'0') * 1000
-	if !bytes.Equal(buf[6+i:], []byte(" trace\x00\x00\x00\x00")[:10-i]) {
+	if !bytes.Equal(buf[6+i:], []byte(" trace\x00\x00\x00\x00")[:10-i]) &&
+		!bytes.Equal(buf[6+i:], []byte(" flight\x00\x00\x00")[:10-i]) {
 		return 0, fmt.Errorf("not a trace file")
 	}
 	return ver, nil
@@ -313,7 +318,7 @@ func parseHeader(buf []byte) (int, error) {
 
 // Parse events transforms raw events into events.
 // It does analyze and verify per-event-type arguments.
-func parseEvents(ver int, rawEvents []rawEvent, strings map[uint64]string) (events []*Event, stacks map[uint64][]*Frame, err error) {
+func parseEvents(ver int, partial bool, rawEvents []rawEvent, strings map[uint64]string) (events []*Event, stacks map[uint64][]*Frame, err error) {
 	var ticksPerSec, lastSeq, lastTs int64
 	var lastG uint64
 	var lastP int
@@ -448,6 +453,18 @@ func parseEvents(ver int, rawEvents []rawEvent, strings map[uint64]string) (even
 			case EvUserLog:
 				// e.Args 0: taskID, 1:keyID, 2: stackID
 				e.SArgs = []string{strings[e.Args[1]], raw.sargs[0]}
+			case EvUserLogInt:
+				// e.Args 0: taskID, 1:keyID, 2: value
+				e.SArgs = []string{strings[e.Args[1]], strconv.FormatInt(int64(e.Args[2]), 10)}
+			case EvUserCounter:
+				// e.Args 0: nameID, 1: value
+				e.SArgs = []string{strings[e.Args[0]]}
+			case EvUserFlow:
+				// e.Args 0: flowID, 1: mode, 2: nameID
+				e.SArgs = []string{strings[e.Args[2]]}
+			case EvGoName:
+				// e.Args 0: goroutine id, 1: nameID
+				e.SArgs = []string{strings[e.Args[1]]}
 			}
 			batches[lastP] = append(batches[lastP], e)
 		}
@@ -473,7 +490,7 @@ func parseEvents(ver int, rawEvents []rawEvent, strings map[uint64]string) (even
 	if ver < 1007 {
 		events, err = order1005(batches)
 	} else {
-		events, err = order1007(batches)
+		events, err = order1007(batches, partial)
 	}
 	if err != nil {
 		return
@@ -560,7 +577,9 @@ var ErrTimeOrder = fmt.Errorf("time stamps out of order")
 // The resulting trace is guaranteed to be consistent
 // (for example, a P does not run two Gs at the same time, or a G is indeed
 // blocked before an unblock event).
-func postProcessTrace(ver int, events []*Event) error {
+// If partial is set, goroutines and Ps are assumed to be in whatever
+// state their first event in the trace implies.
+func postProcessTrace(ver int, partial bool, events []*Event) error {
 	const (
 		gDead = iota
 		gRunnable
@@ -576,6 +595,7 @@ func postProcessTrace(ver int, events []*Event) error {
 	}
 	type pdesc struct {
 		running bool
+		assumed bool // running only assumed from a partial trace
 		g       uint64
 		evSTW   *Event
 		evSweep *Event
@@ -585,6 +605,7 @@ func postProcessTrace(ver int, events []*Event) error {
 	ps := make(map[int]pdesc)
 	tasks := make(map[uint64]*Event)           // task id to task creation events
 	activeRegions := make(map[uint64][]*Event) // goroutine id to stack of regions
+	flows := make(map[uint64]*Event)           // flow id to flow start events
 	gs[0] = gdesc{state: gRunning}
 	var evGC, evSTW *Event
 
@@ -603,15 +624,35 @@ func postProcessTrace(ver int, events []*Event) error {
 	}
 
 	for _, ev := range events {
-		g := gs[ev.G]
-		p := ps[ev.P]
+		g, gok := gs[ev.G]
+		p, pok := ps[ev.P]
+		if partial && !pok && ev.P < FakeP && ev.Type != EvProcStart {
+			p.running = true
+			p.assumed = true
+		}
+		if partial && !gok {
+			switch ev.Type {
+			case EvGoStart, EvGoStartLabel, EvGoWaiting, EvGoInSyscall:
+				g.state = gRunnable
+			case EvGoSysExit:
+				g.state = gWaiting
+			default:
+				g.state = gRunning
+				if ev.P < FakeP && p.g == 0 {
+					p.g = ev.G
+				}
+			}
+		}
 
 		switch ev.Type {
 		case EvProcStart:
-			if p.running {
+			// A partial trace can start with events that the P
+			// emitted before its start, such as when tracing starts.
+			if p.running && !p.assumed {
 				return fmt.Errorf("p %v is running before start (offset %v, time %v)", ev.P, ev.Off, ev.Ts)
 			}
 			p.running = true
+			p.assumed = false
 		case EvProcStop:
 			if !p.running {
 				return fmt.Errorf("p %v is not running before stop (offset %v, time %v)", ev.P, ev.Off, ev.Ts)
@@ -620,6 +661,7 @@ func postProcessTrace(ver int, events []*Event) error {
 				return fmt.Errorf("p %v is running a goroutine %v during stop (offset %v, time %v)", ev.P, p.g, ev.Off, ev.Ts)
 			}
 			p.running = false
+			p.assumed = false
 		case EvGCStart:
 			if evGC != nil {
 				return fmt.Errorf("previous GC is not ended before a new one (offset %v, time %v)", ev.Off, ev.Ts)
@@ -629,6 +671,9 @@ func postProcessTrace(ver int, events []*Event) error {
 			ev.P = GCP
 		case EvGCDone:
 			if evGC == nil {
+				if partial {
+					break
+				}
 				return fmt.Errorf("bogus GC end (offset %v, time %v)", ev.Off, ev.Ts)
 			}
 			evGC.Link = ev
@@ -650,6 +695,9 @@ func postProcessTrace(ver int, events []*Event) error {
 				evp = &p.evSTW
 			}
 			if *evp == nil {
+				if partial {
+					break
+				}
 				return fmt.Errorf("bogus STW end (offset %v, time %v)", ev.Off, ev.Ts)
 			}
 			(*evp).Link = ev
@@ -673,6 +721,9 @@ func postProcessTrace(ver int, events []*Event) error {
 			}
 		case EvGCSweepDone:
 			if p.evSweep == nil {
+				if partial {
+					break
+				}
 				return fmt.Errorf("bogus sweeping end (offset %v, time %v)", ev.Off, ev.Ts)
 			}
 			p.evSweep.Link = ev
@@ -725,7 +776,9 @@ func postProcessTrace(ver int, events []*Event) error {
 			if err := checkRunning(p, g, ev, false); err != nil {
 				return err
 			}
-			g.evStart.Link = ev
+			if g.evStart != nil {
+				g.evStart.Link = ev
+			}
 			g.evStart = nil
 			g.state = gDead
 			p.g = 0
@@ -743,7 +796,9 @@ func postProcessTrace(ver int, events []*Event) error {
 				return err
 			}
 			g.state = gRunnable
-			g.evStart.Link = ev
+			if g.evStart != nil {
+				g.evStart.Link = ev
+			}
 			g.evStart = nil
 			p.g = 0
 			g.ev = ev
@@ -754,7 +809,10 @@ func postProcessTrace(ver int, events []*Event) error {
 			if ev.P != TimerP && p.g != ev.G {
 				return fmt.Errorf("p %v is not running g %v while unpark (offset %v, time %v)", ev.P, ev.G, ev.Off, ev.Ts)
 			}
-			g1 := gs[ev.Args[0]]
+			g1, ok := gs[ev.Args[0]]
+			if partial && !ok {
+				g1.state = gWaiting
+			}
 			if g1.state != gWaiting {
 				return fmt.Errorf("g %v is not waiting before unpark (offset %v, time %v)", ev.Args[0], ev.Off, ev.Ts)
 			}
@@ -777,7 +835,9 @@ func postProcessTrace(ver int, events []*Event) error {
 				return err
 			}
 			g.state = gWaiting
-			g.evStart.Link = ev
+			if g.evStart != nil {
+				g.evStart.Link = ev
+			}
 			g.evStart = nil
 			p.g = 0
 		case EvGoSysExit:
@@ -796,7 +856,9 @@ func postProcessTrace(ver int, events []*Event) error {
 			}
 			g.state = gWaiting
 			g.ev = ev
-			g.evStart.Link = ev
+			if g.evStart != nil {
+				g.evStart.Link = ev
+			}
 			g.evStart = nil
 			p.g = 0
 		case EvUserTaskCreate:
@@ -835,6 +897,24 @@ func postProcessTrace(ver int, events []*Event) error {
 			} else {
 				return fmt.Errorf("invalid user region mode: %q", ev)
 			}
+		case EvUserFlow:
+			flowid := ev.Args[0]
+			switch ev.Args[1] {
+			case 0: // flow start
+				if prevEv, ok := flows[flowid]; ok {
+					return fmt.Errorf("flow id conflicts (id:%d), %q vs %q", flowid, ev, prevEv)
+				}
+				flows[flowid] = ev
+			case 1: // flow end
+				if s, ok := flows[flowid]; ok {
+					// Link flow start event with flow end event.
+					s.Link = ev
+					ev.SArgs = s.SArgs
+					delete(flows, flowid)
+				}
+			default:
+				return fmt.Errorf("invalid user flow mode: %q", ev)
+			}
 		}
 
 		gs[ev.G] = g
@@ -1058,7 +1138,11 @@ const (
 	EvUserTaskEnd       = 46 // end of task [timestamp, internal task id, stack]
 	EvUserRegion        = 47 // trace.WithRegion [timestamp, internal task id, mode(0:start, 1:end), stack, name string]
 	EvUserLog           = 48 // trace.Log [timestamp, internal id, key string id, stack, value string]
-	EvCount             = 49
+	EvUserLogInt        = 49 // trace.LogInt [timestamp, internal id, key string id, value, stack]
+	EvUserCounter       = 50 // trace.Counter [timestamp, name string id, value]
+	EvUserFlow          = 51 // trace.StartFlow [timestamp, flow id, mode(0:start, 1:end), name string id, stack]
+	EvGoName            = 52 // runtime.SetGoroutineName [timestamp, goroutine id, name string id]
+	EvCount             = 53
 )
 
 var EventDescriptions = [EvCount]struct {
@@ -1117,4 +1201,8 @@ var EventDescriptions = [EvCount]struct {
 	EvUserTaskEnd:       {"UserTaskEnd", 1011, true, []string{"taskid"}, nil},
 	EvUserRegion:        {"UserRegion", 1011, true, []string{"taskid", "mode", "typeid"}, []string{"name"}},
 	EvUserLog:           {"UserLog", 1011, true, []string{"id", "keyid"}, []string{"category", "message"}},
+	EvUserLogInt:        {"UserLogInt", 1011, true, []string{"id", "keyid", "value"}, []string{"category", "message"}},
+	EvUserCounter:       {"UserCounter", 1011, false, []string{"nameid", "value"}, []string{"name"}},
+	EvUserFlow:          {"UserFlow", 1011, true, []string{"flowid", "mode", "typeid"}, []string{"name"}},
+	EvGoName:            {"GoName", 1011, false, []string{"g", "nameid"}, []string{"name"}},
 }
@@ -56,6 +56,13 @@ func NewFile(fd uintptr, name string) *File {
 	return f
 }
 
+// NewPollFile is like NewFile, but it requires the file descriptor to
+// be added to the runtime poller. It is not supported on Plan 9; it always
+// returns the syscall.EPLAN9 error, wrapped in *PathError.
+func NewPollFile(fd uintptr, name string) (*File, error) {
+	return nil, &PathError{Op: "newpollfile", Path: name, Err: syscall.EPLAN9}
+}
+
 // Auxiliary information if the File describes a directory
 type dirInfo struct {
 	buf  [syscall.STATMAX]byte // buffer for directory I/O
@@ -106,6 +106,56 @@ func NewFile(fd uintptr, name string) *File {
 	return newFile(fd, name, kind)
 }
 
+// NewPollFile is like NewFile, but it requires the file descriptor to
+// be added to the runtime poller, which NewFile only attempts for file
+// descriptors in non-blocking mode. NewPollFile puts fd in non-blocking
+// mode, and returns an error, with fd unchanged, if the poller does not
+// support it; for example, regular files on Linux.
+//
+// NewPollFile is meant for file descriptors that are neither network
+// connections nor opened by this package, such as device files and
+// descriptors returned by inotify_init, signalfd or eventfd. A goroutine
+// reading from or writing to the returned File, directly or through the
+// RawConn returned by its SyscallConn method, waits for the descriptor
+// to be ready without holding an operating system thread, and the
+// SetDeadline methods work. To wait until the descriptor is ready to be
+// read without reading from it, call the Read method of the RawConn with
+// a function that returns false the first time it is called and true the
+// next time.
+func NewPollFile(fd uintptr, name string) (*File, error) {
+	fdi := int(fd)
+	if fdi < 0 {
+		return nil, &PathError{Op: "newpollfile", Path: name, Err: syscall.EBADF}
+	}
+	nb, err := unix.IsNonblock(fdi)
+	if err != nil {
+		return nil, &PathError{Op: "newpollfile", Path: name, Err: err}
+	}
+	if !nb {
+		if err := syscall.SetNonblock(fdi, true); err != nil {
+			return nil, &PathError{Op: "newpollfile", Path: name, Err: err}
+		}
+	}
+	f := &File{&file{
+		pfd: poll.FD{
+			Sysfd:         fdi,
+			IsStream:      true,
+			ZeroReadIsEOF: true,
+		},
+		name:        name,
+		stdoutOrErr: fdi == 1 || fdi == 2,
+		nonblock:    !nb,
+	}}
+	if err := f.pfd.Init("file", true); err != nil {
+		if !nb {
+			syscall.SetNonblock(fdi, false)
+		}
+		return nil, &PathError{Op: "newpollfile", Path: name, Err: err}
+	}
+	runtime.SetFinalizer(f.file, (*file).close)
+	return f, nil
+}
+
 // newFileKind describes the kind of file to newFile.
 type newFileKind int
 
@@ -84,6 +84,13 @@ func NewFile(fd uintptr, name string) *File {
 	return newFile(h, name, "file")
 }
 
+// NewPollFile is like NewFile, but it requires the file descriptor to
+// be added to the runtime poller. It is not supported on Windows; it always
+// returns the syscall.EWINDOWS error, wrapped in *PathError.
+func NewPollFile(fd uintptr, name string) (*File, error) {
+	return nil, &PathError{Op: "newpollfile", Path: name, Err: syscall.EWINDOWS}
+}
+
 // Auxiliary information if the File describes a directory
 type dirInfo struct {
 	data     syscall.Win32finddata
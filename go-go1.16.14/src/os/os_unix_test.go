@@ -296,6 +296,64 @@ func TestNewFileNonBlock(t *testing.T) {
 	newFileTest(t, false)
 }
 
+func TestNewPollFile(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "js" {
+		t.Skip("no poller on js")
+	}
+
+	p := make([]int, 2)
+	if err := syscall.Pipe(p); err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	defer syscall.Close(p[1])
+	file, err := NewPollFile(uintptr(p[0]), "pollpipe")
+	if err != nil {
+		syscall.Close(p[0])
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	// A deadline works, as with a non-blocking file passed to NewFile.
+	b := make([]byte, 1)
+	file.SetReadDeadline(time.Now().Add(time.Millisecond))
+	if _, err := file.Read(b); !isDeadlineExceeded(err) {
+		t.Fatalf("No timeout reading from file: %v", err)
+	}
+	file.SetReadDeadline(time.Time{})
+
+	// Wait for readiness without reading.
+	timer := time.AfterFunc(10*time.Millisecond, func() { syscall.Write(p[1], []byte("a")) })
+	defer timer.Stop()
+	rc, err := file.SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := 0
+	if err := rc.Read(func(fd uintptr) bool {
+		calls++
+		return calls > 1
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := file.Read(b); n != 1 || err != nil || b[0] != 'a' {
+		t.Fatalf("Read = %d, %v, %q; want 1, nil, \"a\"", n, err, b)
+	}
+
+	if runtime.GOOS == "linux" {
+		// epoll does not support regular files.
+		f, err := CreateTemp("", "pollfile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer Remove(f.Name())
+		defer f.Close()
+		if _, err := NewPollFile(f.Fd(), f.Name()); err == nil {
+			t.Error("NewPollFile of a regular file succeeded")
+		}
+	}
+}
+
 func TestSplitPath(t *testing.T) {
 	t.Parallel()
 	for _, tt := range []struct{ path, wantDir, wantBase string }{
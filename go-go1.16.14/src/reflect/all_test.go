@@ -5681,6 +5681,33 @@ func TestTypelinksSorted(t *testing.T) {
 	}
 }
 
+type typeByNameT struct {
+	X int
+}
+
+func TestTypeByName(t *testing.T) {
+	// Refer to *typeByNameT, so that the program has the type.
+	ptr := TypeOf(new(typeByNameT))
+	for _, tt := range []struct {
+		name string
+		want Type
+	}{
+		{"reflect_test.typeByNameT", ptr.Elem()},
+		{"bytes.Buffer", TypeOf(bytes.Buffer{})},
+		{"reflect.Value", TypeOf(Value{})},
+		{"int", TypeOf(0)},
+		{"error", TypeOf((*error)(nil)).Elem()},
+		{"reflect_test.noSuchType", nil},
+		{"reflect.typeByNameT", nil},
+		{"bytes.", nil},
+		{"", nil},
+	} {
+		if got := TypeByName(tt.name); got != tt.want {
+			t.Errorf("TypeByName(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
 func TestFuncOf(t *testing.T) {
 	// check construction and use of type not in binary
 	type K string
@@ -1747,6 +1747,28 @@ func typesByString(s string) []*rtype {
 	return ret
 }
 
+// typeByName is implemented in runtime; it returns the *rtype of the
+// named type with the given package path and name, or nil.
+func typeByName(name string) unsafe.Pointer
+
+// TypeByName returns the named type with the given package path and
+// name, separated by a dot, as in "encoding/json.Decoder", or nil if
+// the program has no such type. Predeclared types are named without
+// a package path, as in "int" or "error".
+//
+// Only types the program refers to from unnamed pointer, slice, array,
+// map, channel, function or struct types, directly or through other
+// named types, are found. These include every type T for which the
+// program uses *T, such as a type with pointer methods or one a value
+// of which is decoded into.
+func TypeByName(name string) Type {
+	t := typeByName(name)
+	if t == nil {
+		return nil
+	}
+	return toType((*rtype)(t))
+}
+
 // The lookupCache caches ArrayOf, ChanOf, MapOf and SliceOf lookups.
 var lookupCache sync.Map // map[cacheKey]*rtype
 
@@ -0,0 +1,42 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime_test
+
+import (
+	"internal/race"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestReadBuildConfig(t *testing.T) {
+	c := runtime.ReadBuildConfig()
+	if c.Mode != "exe" && c.Mode != "pie" {
+		t.Errorf("Mode = %q, want exe or pie", c.Mode)
+	}
+	if c.Library {
+		t.Error("Library is set in a test binary")
+	}
+	if c.Race != race.Enabled {
+		t.Errorf("Race = %v, want %v", c.Race, race.Enabled)
+	}
+	if want := runtime.GOARCH == "amd64"; runtime.GOARCH != "arm64" && c.FramePointers != want {
+		t.Errorf("FramePointers = %v, want %v", c.FramePointers, want)
+	}
+}
+
+func TestReadBuildConfigPIE(t *testing.T) {
+	if runtime.GOOS != "linux" || runtime.GOARCH != "amd64" {
+		t.Skip("-buildmode=pie is only tested on linux/amd64")
+	}
+	exe, err := buildTestProg(t, "testprog", "-buildmode=pie")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.TrimSpace(runBuiltTestProg(t, exe, "BuildConfig"))
+	if want := "pie false"; got != want {
+		t.Errorf("BuildConfig printed %q, want %q", got, want)
+	}
+}
@@ -19,6 +19,8 @@ x_cgo_callers(uintptr_t sig, void *info, void *context, void (*cgoTraceback)(str
 	arg.SigContext = (uintptr_t)(context);
 	arg.Buf = cgoCallers;
 	arg.Max = 32; // must match len(runtime.cgoCallers)
+	arg.Skip = 0;
+	arg.Data = 0;
 	(*cgoTraceback)(&arg);
 	sigtramp(sig, info, context);
 }
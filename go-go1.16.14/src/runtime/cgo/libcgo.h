@@ -104,6 +104,8 @@ struct cgoTracebackArg {
 	uintptr_t  SigContext;
 	uintptr_t* Buf;
 	uintptr_t  Max;
+	uintptr_t  Skip;
+	uintptr_t  Data;
 };
 
 /*
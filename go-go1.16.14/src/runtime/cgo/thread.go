@@ -0,0 +1,38 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cgo
+
+import _ "unsafe" // for go:linkname
+
+// PinThread, called from Go code running on a thread created by C, such
+// as a function exported with //export, reserves for the thread the
+// runtime state it uses to run Go code, which is otherwise taken from a
+// shared pool for each call from C into Go and returned afterwards.
+// Later calls from the thread then never wait for that state to be
+// allocated, and run on the same goroutine.
+//
+// The goroutine gets the profiler label "cgo-thread" with the value
+// name, so that profiles attribute its samples to the thread, and its
+// tracebacks show the name.
+//
+// The thread must call UnpinThread, again from Go code, before it exits;
+// otherwise the reserved state is never freed.
+//
+// PinThread reports whether the thread was pinned. It does nothing on
+// threads created by Go, and is only supported on Linux.
+func PinThread(name string) bool {
+	return pinThread(name)
+}
+
+// UnpinThread undoes the effect of PinThread on the calling thread.
+func UnpinThread() {
+	unpinThread()
+}
+
+//go:linkname pinThread runtime.cgoPinThread
+func pinThread(name string) bool
+
+//go:linkname unpinThread runtime.cgoUnpinThread
+func unpinThread()
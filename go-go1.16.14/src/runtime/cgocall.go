@@ -161,7 +161,12 @@ func cgocall(fn, arg unsafe.Pointer) int32 {
 	osPreemptExtEnter(mp)
 
 	mp.incgo = true
+	// Time spent in C is on CPU, as far as we know.
+	mp.curg.offcpuwhen = 0
+	mp.curg.cgoCalls++
+	t0 := cputicks()
 	errno := asmcgocall(fn, arg)
+	mp.curg.cgoTicks += cputicks() - t0
 
 	// Update accounting before exitsyscall because exitsyscall may
 	// reschedule us on to a different M.
@@ -195,6 +200,55 @@ func cgocall(fn, arg unsafe.Pointer) int32 {
 	return errno
 }
 
+// cgocallfast is cgocall for C functions named by a "#cgo fastcall"
+// directive, which promise to return quickly, not to block, and not to
+// call back into Go. It skips entersyscall and exitsyscall, so the
+// goroutine keeps its P and the scheduler never hands the P off or
+// wakes another M on its behalf. In exchange the call cannot be
+// preempted: a garbage collection or anything else that stops the
+// world waits for it to return.
+//
+//go:nosplit
+func cgocallfast(fn, arg unsafe.Pointer) int32 {
+	if !iscgo && GOOS != "solaris" && GOOS != "illumos" && GOOS != "windows" {
+		throw("cgocall unavailable")
+	}
+
+	if fn == nil {
+		throw("cgocall nil")
+	}
+
+	if raceenabled {
+		racereleasemerge(unsafe.Pointer(&racecgosync))
+	}
+
+	mp := getg().m
+	mp.ncgocall++
+	mp.ncgo++
+
+	// Reset traceback.
+	mp.cgoCallers[0] = 0
+
+	osPreemptExtEnter(mp)
+
+	mp.incgo = true
+	mp.incgofast = true
+	mp.curg.cgoCalls++
+	t0 := cputicks()
+	errno := asmcgocall(fn, arg)
+	mp.curg.cgoTicks += cputicks() - t0
+	mp.incgofast = false
+	mp.incgo = false
+	mp.ncgo--
+
+	osPreemptExtExit(mp)
+
+	if raceenabled {
+		raceacquire(unsafe.Pointer(&racecgosync))
+	}
+	return errno
+}
+
 // Call from C back to Go.
 //go:nosplit
 func cgocallbackg(fn, frame unsafe.Pointer, ctxt uintptr) {
@@ -203,6 +257,11 @@ func cgocallbackg(fn, frame unsafe.Pointer, ctxt uintptr) {
 		println("runtime: bad g in cgocallback")
 		exit(2)
 	}
+	if gp.m.incgofast {
+		// There is no system call to exit, and the P may not be
+		// released while the callback blocks.
+		throw("cgo callback from C function called with #cgo fastcall")
+	}
 
 	// The call from C is on gp.m's g0 stack, so we must ensure
 	// that we stay on that M. We have to do this before calling
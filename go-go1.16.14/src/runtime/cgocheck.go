@@ -14,6 +14,33 @@ import (
 
 const cgoWriteBarrierFail = "Go pointer stored into non-Go memory"
 
+// setCgoCheck sets debug.cgocheck to level, clamped to [0, 2], and
+// returns the previous level. Level 2 checks all pointer writes, so
+// the write barrier is turned on or off with it, which is done with
+// the world stopped and the GC not running so that the write barrier
+// buffers are empty.
+//
+//go:linkname setCgoCheck runtime/debug.setCgoCheck
+func setCgoCheck(level int) int {
+	if level < 0 {
+		level = 0
+	} else if level > 2 {
+		level = 2
+	}
+	stopTheWorldGC("set cgocheck")
+	prev := int(debug.cgocheck)
+	debug.cgocheck = int32(level)
+	if writeBarrier.cgo != (level > 1) {
+		writeBarrier.cgo = level > 1
+		writeBarrier.enabled = writeBarrier.needed || writeBarrier.cgo
+		for _, p := range allp {
+			p.wbBuf.reset()
+		}
+	}
+	startTheWorldGC()
+	return prev
+}
+
 // cgoCheckWriteBarrier is called whenever a pointer is stored into memory.
 // It throws if the program is storing a Go pointer into non-Go memory.
 //
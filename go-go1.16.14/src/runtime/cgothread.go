@@ -0,0 +1,118 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+import (
+	"runtime/internal/atomic"
+	"unsafe"
+)
+
+// Pinned extra Ms, for runtime/cgo.PinThread.
+//
+// A thread created by C borrows an extra M from the extra list each
+// time it calls into Go (needm) and gives it back when the call
+// returns (dropm). A thread that pins its M instead keeps it: dropm
+// parks the M on extraMPinned, and needm on the same thread takes it
+// back. The thread never waits for an extra M to be allocated, and
+// its goroutine keeps its ID and profiler labels from one call to the
+// next.
+
+// extraMPinned is the list of pinned extra Ms whose threads are in C,
+// linked through schedlink. It is protected by lockextra.
+var extraMPinned muintptr
+
+// extraMNumPinned is the number of pinned extra Ms, accessed
+// atomically, so that needm only searches extraMPinned if there are
+// any.
+var extraMNumPinned uint32
+
+// cgoThreadLabel is the profiler label that holds the name of a
+// pinned thread.
+const cgoThreadLabel = "cgo-thread"
+
+//go:linkname cgoPinThread
+func cgoPinThread(name string) bool {
+	mp := acquirem()
+	id := cthreadID()
+	ok := mp.isextra && id != 0
+	if ok {
+		if !mp.cthreadPinned {
+			mp.cthreadPinned = true
+			mp.cthreadID = id
+			atomic.Xadd(&extraMNumPinned, 1)
+		}
+		mp.cthreadName = name
+	}
+	releasem(mp)
+	if ok {
+		setCgoThreadLabel(name)
+	}
+	return ok
+}
+
+//go:linkname cgoUnpinThread
+func cgoUnpinThread() {
+	mp := acquirem()
+	pinned := mp.cthreadPinned
+	if pinned {
+		mp.cthreadPinned = false
+		mp.cthreadID = 0
+		mp.cthreadName = ""
+		atomic.Xadd(&extraMNumPinned, -1)
+	}
+	releasem(mp)
+	if pinned {
+		// The goroutine goes back to the extra list with the M.
+		setCgoThreadLabel("")
+	}
+}
+
+// setCgoThreadLabel sets the cgoThreadLabel profiler label of the
+// calling goroutine to name, or removes it if name is empty, keeping
+// its other labels. The labels are a runtime/pprof labelMap.
+func setCgoThreadLabel(name string) {
+	labels := make(map[string]string)
+	if old := getg().labels; old != nil {
+		for k, v := range *(*map[string]string)(old) {
+			labels[k] = v
+		}
+	}
+	if name != "" {
+		labels[cgoThreadLabel] = name
+	} else {
+		delete(labels, cgoThreadLabel)
+	}
+	if len(labels) == 0 {
+		runtime_setProfLabel(nil)
+		return
+	}
+	lm := new(map[string]string)
+	*lm = labels
+	runtime_setProfLabel(unsafe.Pointer(lm))
+}
+
+// takePinnedExtraM removes the M pinned by the calling thread from
+// extraMPinned and returns it, or returns nil if the thread has not
+// pinned one. It is called by needm, without an m or g.
+//
+//go:nosplit
+func takePinnedExtraM() *m {
+	id := cthreadID()
+	if id == 0 {
+		return nil
+	}
+	var mp *m
+	head := lockextra(true)
+	for pp := &extraMPinned; pp.ptr() != nil; pp = &pp.ptr().schedlink {
+		if pp.ptr().cthreadID == id {
+			mp = pp.ptr()
+			*pp = mp.schedlink
+			mp.schedlink = 0
+			break
+		}
+	}
+	unlockextra(head)
+	return mp
+}
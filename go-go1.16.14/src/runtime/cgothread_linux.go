@@ -0,0 +1,13 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+// cthreadID returns an ID for the calling thread that is unique among
+// running threads, for finding the extra M it pinned.
+//
+//go:nosplit
+func cthreadID() uint64 {
+	return uint64(gettid())
+}
@@ -0,0 +1,15 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux
+
+package runtime
+
+// cthreadID returns 0: threads cannot pin extra Ms, since needm has no
+// way to identify the calling thread without a g.
+//
+//go:nosplit
+func cthreadID() uint64 {
+	return 0
+}
@@ -0,0 +1,39 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+import "runtime/internal/atomic"
+
+// A countedLock is a mutex that counts the acquisitions that found it
+// held, for the contention metrics of the sharded central caches. The
+// count is approximate: an acquisition that races with the release of
+// the holder may or may not be counted.
+type countedLock struct {
+	// contended is written with mu held and read atomically. It
+	// comes first to be 8-byte aligned on 32-bit systems, as long
+	// as the countedLock is.
+	contended uint64
+
+	mu   mutex
+	held uint32 // 1 while mu is held; accessed atomically
+}
+
+func (l *countedLock) init(rank lockRank) {
+	lockInit(&l.mu, rank)
+}
+
+func (l *countedLock) lock() {
+	contended := atomic.Load(&l.held) != 0
+	lock(&l.mu)
+	atomic.Store(&l.held, 1)
+	if contended {
+		atomic.Store64(&l.contended, l.contended+1)
+	}
+}
+
+func (l *countedLock) unlock() {
+	atomic.Store(&l.held, 0)
+	unlock(&l.mu)
+}
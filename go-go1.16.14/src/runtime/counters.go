@@ -0,0 +1,84 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Runtime event counters and gauges.
+//
+// Many runtime subsystems keep a count of some event, or the current
+// level of some quantity, only to publish it as a runtime/metrics
+// metric. Rather than declaring a variable and a compute function in
+// initMetrics for each, a subsystem can declare an eventCounter below,
+// name its metric in eventCounterNames, and update it with add.
+//
+// Every P has a slot for each counter, in p.counters, and updates made
+// without a P go to a global slot. An update is one atomic add to the
+// slot of the current P, whose cache line no other P writes, so that
+// counters on hot paths do not bounce a shared cache line between
+// CPUs. The value of a counter is the sum of its slots, which load
+// takes under allpLock. A gauge may go up on one P and down on
+// another, so a single slot may wrap around, but the sum does not.
+//
+// initMetrics publishes every counter with a metricData whose compute
+// function is nil, which readMetrics reads the counter for, and
+// applications read them like any other metric with
+// runtime/metrics.Read. The description of the metric still belongs in
+// runtime/metrics, which says whether it is cumulative: a counter only
+// ever grows, while a gauge goes up and down with the quantity it
+// tracks.
+
+package runtime
+
+import "runtime/internal/atomic"
+
+// An eventCounter is a runtime counter or gauge.
+type eventCounter int
+
+const (
+	counterNetpollWakeups    eventCounter = iota // blocking netpolls in findrunnable that returned
+	counterPreemptsHandled                       // preemption requests acknowledged in m.preemptGen
+	counterStackCopies                           // calls to copystack
+	gaugeNetpollWaiters                          // goroutines parked in netpollblock
+	counterGoroutinesCreated                     // user goroutines started by newproc1
+	counterGoroutinesExited                      // user goroutines that reached goexit0
+	numEventCounters
+)
+
+// eventCounterNames are the runtime/metrics names of the counters.
+var eventCounterNames = [numEventCounters]string{
+	counterNetpollWakeups:    "/sched/netpoll/wakeups:wakeups",
+	counterPreemptsHandled:   "/sched/preempt/handled:requests",
+	counterStackCopies:       "/sched/stacks/copies:copies",
+	gaugeNetpollWaiters:      "/sched/netpoll/waiters:goroutines",
+	counterGoroutinesCreated: "/sched/goroutines-created:goroutines",
+	counterGoroutinesExited:  "/sched/goroutines-exited:goroutines",
+}
+
+// eventCounters holds the updates made without a P.
+var eventCounters [numEventCounters]uint64
+
+// add adds delta, which may be negative for a gauge, to c. The caller
+// may move to another P before the add, which then goes to the slot of
+// the P it left; that does not change the sum.
+//
+//go:nosplit
+func (c eventCounter) add(delta int64) {
+	if pp := getg().m.p.ptr(); pp != nil {
+		atomic.Xadd64(&pp.counters[c], delta)
+		return
+	}
+	atomic.Xadd64(&eventCounters[c], delta)
+}
+
+// load returns the value of c.
+func (c eventCounter) load() uint64 {
+	v := atomic.Load64(&eventCounters[c])
+	lock(&allpLock)
+	for _, pp := range allp[:cap(allp)] {
+		if pp == nil {
+			continue
+		}
+		v += atomic.Load64(&pp.counters[c])
+	}
+	unlock(&allpLock)
+	return v
+}
@@ -40,6 +40,16 @@ type cpuProfile struct {
 	numExtra   int
 	lostExtra  uint64 // count of frames lost because extra is full
 	lostAtomic uint64 // count of frames lost because of being in atomic64 on mips/arm; updated racily
+
+	// overhead is the time in nanoseconds that the profiling signal
+	// handler has spent collecting samples on Go threads. It is
+	// protected by prof.signalLock. At high profiling rates it can
+	// be a noticeable part of the profile, so it is reported as
+	// samples of _ProfilerOverhead when profiling stops.
+	overhead int64
+	hz       int32 // profiling rate, for converting overhead to samples
+	events   bool  // sampling perf events rather than CPU time
+	clock    bool  // sampling CPU time with setCPUProfileClock
 }
 
 var cpuprof cpuProfile
@@ -70,6 +80,12 @@ func SetCPUProfileRate(hz int) {
 
 		cpuprof.on = true
 		cpuprof.log = newProfBuf(1, 1<<17, 1<<14)
+		cpuprof.overhead = 0
+		cpuprof.hz = int32(hz)
+		if hz > 100 && !cpuprof.events {
+			// Above the default rate, timers may be too coarse.
+			cpuprof.clock = setCPUProfileClock(int32(hz))
+		}
 		hdr := [1]uint64{uint64(hz)}
 		cpuprof.log.write(nil, nanotime(), hdr[:], nil)
 		setcpuprofilerate(int32(hz))
@@ -77,18 +93,41 @@ func SetCPUProfileRate(hz int) {
 		setcpuprofilerate(0)
 		cpuprof.on = false
 		cpuprof.addExtra()
+		cpuprof.addOverhead()
 		cpuprof.log.close()
+		if cpuprof.clock {
+			setCPUProfileEvent(0, 0)
+			cpuprof.clock = false
+		}
 	}
 	unlock(&cpuprof.lock)
 }
 
-// add adds the stack trace to the profile.
+// AddCPUProfileThread registers the thread with operating system thread ID
+// tid, which must have been created by C code, to be sampled by the CPU
+// profiler at the same rate as threads running Go code. On Linux threads
+// running Go code have a profiling timer of their own, while other threads
+// share a single process-wide timer that undercounts when many threads are
+// busy; registering a C thread gives it its own timer as well.
+// The thread should be removed with RemoveCPUProfileThread before it exits.
+// On systems other than Linux AddCPUProfileThread has no effect.
+func AddCPUProfileThread(tid int) {
+	addProfThread(int32(tid))
+}
+
+// RemoveCPUProfileThread undoes the effect of AddCPUProfileThread.
+func RemoveCPUProfileThread(tid int) {
+	removeProfThread(int32(tid))
+}
+
+// add adds the stack trace to the profile, and overhead, the time in
+// nanoseconds it took to collect, to the profiler's overhead.
 // It is called from signal handlers and other limited environments
 // and cannot allocate memory or acquire locks that might be
 // held at the time of the signal, nor can it use substantial amounts
 // of stack.
 //go:nowritebarrierrec
-func (p *cpuProfile) add(gp *g, stk []uintptr) {
+func (p *cpuProfile) add(gp *g, stk []uintptr, overhead int64) {
 	// Simple cas-lock to coordinate with setcpuprofilerate.
 	for !atomic.Cas(&prof.signalLock, 0, 1) {
 		osyield()
@@ -104,6 +143,7 @@ func (p *cpuProfile) add(gp *g, stk []uintptr) {
 		// be correct. See the long comment there before
 		// changing the argument here.
 		cpuprof.log.write(&gp.labels, nanotime(), hdr[:], stk)
+		p.overhead += overhead
 	}
 
 	atomic.Store(&prof.signalLock, 0)
@@ -176,6 +216,27 @@ func (p *cpuProfile) addExtra() {
 
 }
 
+// addOverhead adds the time the signal handler spent collecting samples
+// to the profile log, as samples of _ProfilerOverhead, rounded to whole
+// sampling periods. It is called when profiling stops, so there are no
+// concurrent calls to add. Profiles of perf events have no sampling
+// period in CPU time and leave the overhead out.
+func (p *cpuProfile) addOverhead() {
+	if p.events || p.hz <= 0 {
+		return
+	}
+	period := int64(1e9) / int64(p.hz)
+	if n := (p.overhead + period/2) / period; n > 0 {
+		hdr := [1]uint64{uint64(n)}
+		stk := [2]uintptr{
+			funcPC(_ProfilerOverhead) + sys.PCQuantum,
+			funcPC(_System) + sys.PCQuantum,
+		}
+		p.log.write(nil, 0, hdr[:], stk[:])
+	}
+	p.overhead = 0
+}
+
 // CPUProfile panics.
 // It formerly provided raw access to chunks of
 // a pprof-format profile generated by the runtime.
@@ -202,10 +263,22 @@ func runtime_pprof_runtime_cyclesPerSecond() int64 {
 //
 //go:linkname runtime_pprof_readProfile runtime/pprof.readProfile
 func runtime_pprof_readProfile() ([]uint64, []unsafe.Pointer, bool) {
+	return readCPUProfile(profBufBlocking)
+}
+
+// readProfileNonblocking, provided to runtime/pprof, is like readProfile,
+// but returns no data rather than blocking when none is available.
+//
+//go:linkname runtime_pprof_readProfileNonblocking runtime/pprof.readProfileNonblocking
+func runtime_pprof_readProfileNonblocking() ([]uint64, []unsafe.Pointer, bool) {
+	return readCPUProfile(profBufNonBlocking)
+}
+
+func readCPUProfile(mode profBufReadMode) ([]uint64, []unsafe.Pointer, bool) {
 	lock(&cpuprof.lock)
 	log := cpuprof.log
 	unlock(&cpuprof.lock)
-	data, tags, eof := log.read(profBufBlocking)
+	data, tags, eof := log.read(mode)
 	if len(data) == 0 && eof {
 		lock(&cpuprof.lock)
 		cpuprof.log = nil
@@ -213,3 +286,18 @@ func runtime_pprof_readProfile() ([]uint64, []unsafe.Pointer, bool) {
 	}
 	return data, tags, eof
 }
+
+// setCPUProfileEvent, provided to runtime/pprof, selects the event that
+// CPU profiling samples instead of CPU time, one of the
+// runtime/pprof.CPUProfileEvent values, or CPU time again if event is 0.
+// It must only be called while profiling is disabled, and reports
+// whether the event can be sampled.
+//
+//go:linkname runtime_pprof_setCPUProfileEvent runtime/pprof.setCPUProfileEvent
+func runtime_pprof_setCPUProfileEvent(event int32, period uint64) bool {
+	if !setCPUProfileEvent(event, period) {
+		return false
+	}
+	cpuprof.events = event != 0
+	return true
+}
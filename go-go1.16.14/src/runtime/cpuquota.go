@@ -0,0 +1,230 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// CPU quota aware scheduling.
+//
+// A container may be allowed a fractional number of CPUs, such as 2.5:
+// the kernel's CFS bandwidth controller lets its threads use 250ms of
+// CPU time every 100ms period, and once they have, it stops them all
+// until the next period starts. GOMAXPROCS=2 wastes half a CPU, and
+// GOMAXPROCS=3 lets the program use up its quota early in the period
+// and then freezes it, Ps and all, for the rest: a P frozen in the
+// middle of a GC, holding up the world stopping or the mark phase,
+// stalls every other P until the next period.
+//
+// With a CPU quota set, by GODEBUG=cpuquota=1 or
+// runtime/debug.SetCPUQuota, the runtime throttles itself before the
+// kernel does. sysmon keeps track of the CPU time the Ps use in each
+// period. It lets all GOMAXPROCS Ps run while there is time to spare,
+// and once the rest of the quota only covers the whole number of CPUs
+// it allows, say 2 Ps, for the rest of the period, it lowers
+// sched.pactive to that number. The Ps above it park, as they do when
+// GOMAXPROCS shrinks (see procpark.go), until the next period starts.
+//
+// The GC pacer also aims its background utilization at the CPUs of
+// the quota rather than at GOMAXPROCS, so that the GC does not take
+// more than its share of a quota that does not cover every P.
+//
+// sysmon cannot know when the kernel's periods start, and it counts
+// every P that is not idle as busy, so it errs on the side of
+// throttling early.
+
+package runtime
+
+import (
+	"runtime/internal/atomic"
+	"unsafe"
+)
+
+// cpuQuotaDefaultPeriod is the period of a CPU quota set without one,
+// the CFS default.
+const cpuQuotaDefaultPeriod = 100e6 // 100ms
+
+var cpuQuota struct {
+	// throttled counts the periods in which the Ps were throttled.
+	// cpus is the float64 bits of the CPUs the quota allows, or 0
+	// if there is none. Both are accessed atomically, so they come
+	// first to be 8-byte aligned on 32-bit systems.
+	throttled uint64
+	cpus      uint64
+
+	// quota and period are the CPU time the Ps may use in each
+	// period, in nanoseconds, or 0 if there is no quota. They are
+	// protected by sched.lock, and gen counts their changes.
+	quota  int64
+	period int64
+	gen    uint32
+
+	// limit is the number of Ps allowed to run while throttled, or 0
+	// if the Ps are not throttled. Accessed atomically.
+	limit uint32
+
+	// The rest is only accessed by sysmon.
+	seen  uint32 // gen of q and p
+	q, p  int64  // copies of quota and period
+	start int64  // start of the current period, or 0
+	last  int64  // time of the last tick
+	used  int64  // CPU time used in the current period
+}
+
+func init() {
+	if debug.cpuquota > 0 {
+		if quota, period, ok := readCgroupCPUQuota(); ok {
+			setCPUQuota(quota, period)
+		}
+	}
+}
+
+// cpuQuotaLimit returns the number of Ps allowed to run out of n
+// under the CPU quota.
+//
+//go:nosplit
+func cpuQuotaLimit(n int32) int32 {
+	if l := int32(atomic.Load(&cpuQuota.limit)); l != 0 && l < n {
+		return l
+	}
+	return n
+}
+
+// cpuQuotaProcs returns the number of CPUs the Ps may use: GOMAXPROCS,
+// or fewer under a CPU quota.
+func cpuQuotaProcs() float64 {
+	return cpuQuotaProcsOf(gomaxprocs)
+}
+
+// cpuQuotaProcsOf is cpuQuotaProcs for GOMAXPROCS=procs.
+func cpuQuotaProcsOf(procs int32) float64 {
+	cpus := float64frombits(atomic.Load64(&cpuQuota.cpus))
+	if cpus == 0 || cpus > float64(procs) {
+		return float64(procs)
+	}
+	return cpus
+}
+
+// setCPUQuota sets the CPU quota to quota nanoseconds of CPU time per
+// period, and returns the previous quota and period. A quota of 0 or
+// less removes the quota; a period of 0 or less means
+// cpuQuotaDefaultPeriod.
+//
+//go:linkname setCPUQuota runtime/debug.setCPUQuota
+func setCPUQuota(quota, period int64) (int64, int64) {
+	if quota <= 0 {
+		quota, period = 0, 0
+	} else if period <= 0 {
+		period = cpuQuotaDefaultPeriod
+	}
+	var cpus float64
+	if quota != 0 {
+		cpus = float64(quota) / float64(period)
+	}
+
+	var oldQuota, oldPeriod int64
+	systemstack(func() {
+		lock(&sched.lock)
+		oldQuota, oldPeriod = cpuQuota.quota, cpuQuota.period
+		cpuQuota.quota, cpuQuota.period = quota, period
+		atomic.Xadd(&cpuQuota.gen, 1)
+		atomic.Store64(&cpuQuota.cpus, float64bits(cpus))
+		atomic.Store(&cpuQuota.limit, 0)
+		procsetactive() // releases sched.lock
+
+		// Wake sysmon if it is sleeping, so that it starts
+		// tracking the new quota.
+		lock(&sched.lock)
+		if sched.sysmonwait != 0 {
+			sched.sysmonwait = 0
+			notewakeup(&sched.sysmonnote)
+		}
+		unlock(&sched.lock)
+	})
+	return oldQuota, oldPeriod
+}
+
+// cpuQuotaTick accounts for the CPU time used since the last tick and
+// throttles the Ps, or stops throttling them, as the quota requires.
+// It returns when it needs to run next, or 0 if it does not care.
+//
+// Called by sysmon, so write barriers are not allowed.
+//go:nowritebarrierrec
+func cpuQuotaTick(now int64) int64 {
+	c := &cpuQuota
+	if atomic.Load(&c.gen) != c.seen {
+		lock(&sched.lock)
+		c.seen = atomic.Load(&c.gen)
+		c.q, c.p = c.quota, c.period
+		unlock(&sched.lock)
+		c.start = 0
+	}
+	if c.q == 0 {
+		cpuQuotaRelease()
+		return 0
+	}
+	procs := int64(atomic.Load((*uint32)(unsafe.Pointer(&gomaxprocs))))
+	base := c.q / c.p
+	if base < 1 {
+		base = 1
+	}
+	if base >= procs {
+		// The quota covers every P.
+		c.start = 0
+		cpuQuotaRelease()
+		return 0
+	}
+
+	if c.start == 0 || now-c.start >= c.p {
+		c.start, c.used = now, 0
+		cpuQuotaRelease()
+	} else {
+		// Count every P that is not idle or parked as busy.
+		busy := int64(len(allp)) - int64(atomic.Load(&sched.npidle)) - int64(atomic.Load(&sched.nparked))
+		if busy > procs {
+			busy = procs
+		}
+		c.used += busy * (now - c.last)
+	}
+	c.last = now
+	end := c.start + c.p
+	if atomic.Load(&c.limit) != 0 {
+		return end
+	}
+
+	// The time the Ps may use before base Ps need the rest of the
+	// quota to run until the end of the period.
+	spare := c.q - c.used - base*(end-now)
+	if spare <= 0 {
+		atomic.Store(&c.limit, uint32(base))
+		atomic.Xadd64(&c.throttled, 1)
+		procupdateactive()
+		return end
+	}
+	// At worst, every P is busy.
+	return now + spare/(procs-base)
+}
+
+// cpuQuotaRelease stops throttling the Ps.
+//
+// Called by sysmon, so write barriers are not allowed.
+//go:nowritebarrierrec
+func cpuQuotaRelease() {
+	if atomic.Load(&cpuQuota.limit) != 0 {
+		atomic.Store(&cpuQuota.limit, 0)
+		procupdateactive()
+	}
+}
+
+// cpuQuotaIdle tells cpuQuotaTick that sysmon is about to sleep with
+// every P idle, so that it starts a new period when it wakes rather
+// than count the sleep as busy.
+func cpuQuotaIdle() {
+	cpuQuota.start = 0
+}
+
+// procupdateactive brings sched.pactive in line with the CPU quota.
+//
+// May run without a P, so write barriers are not allowed.
+//go:nowritebarrierrec
+func procupdateactive() {
+	lock(&sched.lock)
+	procsetactive() // releases sched.lock
+}
@@ -0,0 +1,50 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+import "internal/bytealg"
+
+// readCgroupCPUQuota returns the CPU quota and period of the cgroup v2
+// CPU controller of the process, in nanoseconds, and whether it has a
+// quota.
+func readCgroupCPUQuota() (quota, period int64, ok bool) {
+	var buf [4096]byte
+	n := readMemPressureFile(procSelfCgroup, buf[:])
+	if n == 0 {
+		return 0, 0, false
+	}
+	dir, ok := parseCgroup2Path(buf[:n])
+	if !ok {
+		return 0, 0, false
+	}
+	if dir == "/" {
+		dir = ""
+	}
+	path := []byte(cgroup2Root + dir + "/cpu.max\x00")
+	n = readMemPressureFile(path, buf[:])
+	if n == 0 {
+		return 0, 0, false
+	}
+	return parseCPUMax(buf[:n])
+}
+
+// parseCPUMax parses the contents of a cpu.max file, "$MAX $PERIOD" in
+// microseconds, where $MAX is "max" for no quota.
+func parseCPUMax(b []byte) (quota, period int64, ok bool) {
+	line, _ := nextLine(b)
+	i := bytealg.IndexByte(line, ' ')
+	if i < 0 {
+		return 0, 0, false
+	}
+	q, ok := parseCgroupUint(line[:i])
+	if !ok {
+		return 0, 0, false
+	}
+	p, ok := parseCgroupUint(line[i+1:])
+	if !ok || q == 0 || p == 0 {
+		return 0, 0, false
+	}
+	return int64(q) * 1000, int64(p) * 1000, true
+}
@@ -281,6 +281,65 @@ func TestCgoTracebackContext(t *testing.T) {
 	}
 }
 
+func TestCgoTracebackV1(t *testing.T) {
+	t.Parallel()
+	got := runTestProg(t, "testprogcgo", "TracebackV1")
+	want := "OK\n"
+	if got != want {
+		t.Errorf("expected %q got %v", want, got)
+	}
+}
+
+func TestSignalHandlerChain(t *testing.T) {
+	switch runtime.GOOS {
+	case "plan9", "windows":
+		t.Skipf("no signals on %s", runtime.GOOS)
+	}
+	t.Parallel()
+	got := runTestProg(t, "testprogcgo", "SignalHandlerChain")
+	want := "OK\n"
+	if got != want {
+		t.Errorf("expected %q got %v", want, got)
+	}
+}
+
+func TestCgoProfileThread(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skipf("per-thread profiling timers not supported on %s", runtime.GOOS)
+	}
+	t.Parallel()
+	got := runTestProg(t, "testprogcgo", "CgoProfileThread")
+	want := "OK\n"
+	if got != want {
+		t.Errorf("expected %q got %v", want, got)
+	}
+}
+
+func TestCgoSignalStackSize(t *testing.T) {
+	switch runtime.GOOS {
+	case "plan9", "windows":
+		t.Skipf("no signal stacks on %s", runtime.GOOS)
+	case "openbsd":
+		if runtime.GOARCH == "mips64" {
+			t.Skip("larger default signal stack on openbsd/mips64")
+		}
+	}
+	t.Parallel()
+	for _, test := range []struct {
+		godebug string
+		want    string
+	}{
+		{"", "go thread: 32768\nc thread: 1048576\n"},
+		{"GODEBUG=sigstacksize=100000", "go thread: 131072\nc thread: 1048576\n"},
+		{"GODEBUG=sigstacksize=4000000", "go thread: 4194304\nc thread: 4194304\n"},
+	} {
+		got := runTestProg(t, "testprogcgo", "CgoSignalStackSize", test.godebug)
+		if got != test.want {
+			t.Errorf("with %q: expected %q got %q", test.godebug, test.want, got)
+		}
+	}
+}
+
 func testCgoPprof(t *testing.T, buildArg, runArg, top, bottom string) {
 	t.Parallel()
 	if runtime.GOOS != "linux" || (runtime.GOARCH != "amd64" && runtime.GOARCH != "ppc64le") {
@@ -620,6 +679,38 @@ func TestEINTR(t *testing.T) {
 }
 
 // Issue #42207.
+func TestCgoExtraMLimit(t *testing.T) {
+	switch runtime.GOOS {
+	case "plan9", "windows":
+		t.Skipf("no pthreads on %s", runtime.GOOS)
+	}
+	output := runTestProg(t, "testprogcgo", "ExtraMLimit")
+	want := "OK\n"
+	if output != want {
+		t.Fatalf("want %s, got %s\n", want, output)
+	}
+}
+
+func TestSetCgoCheck(t *testing.T) {
+	t.Parallel()
+	got := runTestProg(t, "testprogcgo", "SetCgoCheck")
+	want := "fatal error: Go pointer stored into non-Go memory"
+	if !strings.Contains(got, want) || strings.Contains(got, "SetCgoCheck returned") {
+		t.Errorf("did not see %q, got %q", want, got)
+	}
+}
+
+func TestCgoPinThread(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skipf("cgo.PinThread is not supported on %s", runtime.GOOS)
+	}
+	output := runTestProg(t, "testprogcgo", "PinThread")
+	want := "OK\n"
+	if output != want {
+		t.Fatalf("want %s, got %s\n", want, output)
+	}
+}
+
 func TestNeedmDeadlock(t *testing.T) {
 	switch runtime.GOOS {
 	case "plan9", "windows":
@@ -631,3 +722,15 @@ func TestNeedmDeadlock(t *testing.T) {
 		t.Fatalf("want %s, got %s\n", want, output)
 	}
 }
+
+func TestGoroutineCgoCalls(t *testing.T) {
+	switch runtime.GOOS {
+	case "plan9", "windows":
+		t.Skipf("no pthreads on %s", runtime.GOOS)
+	}
+	output := runTestProg(t, "testprogcgo", "GoroutineCgoCalls")
+	want := "OK\n"
+	if output != want {
+		t.Fatalf("want %s, got %s\n", want, output)
+	}
+}
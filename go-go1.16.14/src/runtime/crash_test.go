@@ -202,6 +202,41 @@ func TestLockedDeadlock(t *testing.T) {
 	testDeadlock(t, "LockedDeadlock")
 }
 
+func TestPartialDeadlock(t *testing.T) {
+	output := runTestProg(t, "testprog", "PartialDeadlock", "GODEBUG=deadlockdetect=2")
+	for _, want := range []string{
+		"partial deadlock detected:\n",
+		"[sync.Mutex.Lock] waits for lock",
+		"[sync.RWMutex.RLock] waits for lock",
+		"main.PartialDeadlock.func1(",
+		"main.PartialDeadlock.func2(",
+		"main.PartialDeadlock.func3(",
+		"fatal error: partial deadlock\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestDeadlockWaitInfo(t *testing.T) {
+	// External linking brings in cgo, causing deadlock detection not working.
+	testenv.MustInternalLink(t)
+
+	output := runTestProg(t, "testprog", "DeadlockWaitInfo", "GODEBUG=deadlockdetect=1")
+	for _, want := range []string{
+		"fatal error: all goroutines are asleep - deadlock!\n",
+		"goroutine 1 [chan receive]: chan 0x",
+		"(elem int, 0/0 buffered), waiting to receive: ",
+		"[sync.Mutex.Lock]: sync.Mutex 0x",
+		", held by goroutine 1\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+}
+
 func TestLockedDeadlock2(t *testing.T) {
 	testDeadlock(t, "LockedDeadlock2")
 }
@@ -247,6 +282,16 @@ func TestThreadExhaustion(t *testing.T) {
 	}
 }
 
+func TestThreadLimitHandler(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("test blocks in nanosleep on Linux")
+	}
+	output := runTestProg(t, "testprog", "ThreadLimitHandler")
+	if output != "OK\n" {
+		t.Fatalf("want OK, got:\n%s", output)
+	}
+}
+
 func TestRecursivePanic(t *testing.T) {
 	output := runTestProg(t, "testprog", "RecursivePanic")
 	want := `wrap: bad
@@ -367,6 +412,18 @@ func TestBreakpoint(t *testing.T) {
 	}
 }
 
+func TestBreakpointIf(t *testing.T) {
+	output := runTestProg(t, "testprog", "BreakpointIf")
+	for _, want := range []string{"no debugger\npredicate false\npredicate true\n", "runtime.BreakpointIf("} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "BAD") {
+		t.Errorf("output contains BAD:\n%s", output)
+	}
+}
+
 func TestGoexitInPanic(t *testing.T) {
 	// External linking brings in cgo, causing deadlock detection not working.
 	testenv.MustInternalLink(t)
@@ -368,3 +368,61 @@ func TestSignalM(t *testing.T) {
 		t.Fatalf("signal sent to M %d, but received on M %d", want, got)
 	}
 }
+
+func TestCrashMinidump(t *testing.T) {
+	t.Parallel()
+	fn := filepath.Join(t.TempDir(), "minidump")
+	got := runTestProg(t, "testprog", "CrashMinidump", "MINIDUMP_FILE="+fn)
+	if !strings.Contains(got, "SIGSEGV") {
+		t.Fatalf("expected crash on SIGSEGV, got:\n%s", got)
+	}
+	b, err := os.ReadFile(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) < 8 || string(b[:8]) != "GOMINI01" {
+		t.Fatalf("minidump does not start with magic: %q", b[:8])
+	}
+	word := func(i int) uint64 {
+		return *(*uint64)(unsafe.Pointer(&b[i]))
+	}
+	kinds := make(map[uint64]int)
+	for i := 8; ; {
+		if i+16 > len(b) {
+			t.Fatalf("minidump truncated at offset %d", i)
+		}
+		kind, n := word(i), int(word(i+8))
+		i += 16
+		if i+n > len(b) {
+			t.Fatalf("record of kind %d at offset %d overruns minidump", kind, i-16)
+		}
+		kinds[kind]++
+		switch kind {
+		case 1: // minidumpSignal
+			if sig := word(i); sig != uint64(syscall.SIGSEGV) {
+				t.Errorf("signal record has signal %d, want %d", sig, syscall.SIGSEGV)
+			}
+		case 2: // minidumpRegs
+			if n == 0 {
+				t.Errorf("empty register record")
+			}
+		case 3: // minidumpStack
+			if n <= 8 {
+				t.Errorf("empty stack record")
+			}
+		case 4: // minidumpModule
+			if minpc, maxpc := word(i), word(i+8); minpc == 0 || minpc >= maxpc {
+				t.Errorf("module record has bad pc range [%#x, %#x)", minpc, maxpc)
+			}
+		}
+		i += (n + 7) &^ 7
+		if kind == 0 {
+			break
+		}
+	}
+	for kind := uint64(1); kind <= 4; kind++ {
+		if kinds[kind] == 0 {
+			t.Errorf("minidump has no record of kind %d", kind)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Soft goroutine deadlines.
+//
+// A goroutine may declare, with runtime/debug.SetGoroutineDeadline,
+// that it should finish within some time. The armed deadlines are kept
+// in a list, and goroutineDeadlines.next holds the earliest of them.
+// Sysmon compares it with the time on every round, like its other
+// periodic checks, and wakes the deadline helper goroutine once it has
+// passed. The helper takes the deadlines that have expired off the
+// list, takes the stack of each goroutine that is still alive with the
+// world stopped, and passes it to the goroutine's callback in a new
+// goroutine. The goroutine itself keeps running.
+//
+// Removing a deadline does not update next, so next may be earlier
+// than any armed deadline. The helper then wakes for nothing and
+// recomputes it.
+
+package runtime
+
+import (
+	"runtime/internal/atomic"
+	_ "unsafe" // for go:linkname
+)
+
+// deadlineStackSize is the size of the buffer the stack of a goroutine
+// that missed its deadline is written to. Longer stacks are truncated.
+const deadlineStackSize = 64 << 10
+
+// A goroutineDeadline is the deadline of a goroutine, linked from
+// gp.deadline while it is armed.
+type goroutineDeadline struct {
+	when       int64 // nanotime
+	gp         *g
+	goid       int64
+	f          func(goid int64, stack []byte)
+	prev, next *goroutineDeadline
+}
+
+var goroutineDeadlines struct {
+	// next is the earliest armed deadline, or 0 if there is none.
+	// It is accessed atomically, so it comes first to be 8-byte
+	// aligned on 32-bit systems.
+	next uint64
+
+	lock    mutex // protects list and g.deadline
+	list    *goroutineDeadline
+	started bool
+	g       *g
+	idle    uint32
+}
+
+// setGoroutineDeadline arms a deadline d nanoseconds from now for the
+// calling goroutine, replacing any it had, or removes its deadline if
+// d <= 0.
+//
+//go:linkname setGoroutineDeadline runtime/debug.setGoroutineDeadline
+func setGoroutineDeadline(d int64, f func(goid int64, stack []byte)) {
+	gp := getg()
+	var nd *goroutineDeadline
+	if d > 0 {
+		nd = &goroutineDeadline{when: nanotime() + d, gp: gp, goid: gp.goid, f: f}
+	}
+	start := false
+	lock(&goroutineDeadlines.lock)
+	if gp.deadline != nil {
+		removeGoroutineDeadline(gp.deadline)
+		gp.deadline = nil
+	}
+	if nd != nil {
+		nd.next = goroutineDeadlines.list
+		if nd.next != nil {
+			nd.next.prev = nd
+		}
+		goroutineDeadlines.list = nd
+		gp.deadline = nd
+		if next := int64(goroutineDeadlines.next); next == 0 || nd.when < next {
+			atomic.Store64(&goroutineDeadlines.next, uint64(nd.when))
+		}
+		start = !goroutineDeadlines.started
+		goroutineDeadlines.started = true
+	}
+	unlock(&goroutineDeadlines.lock)
+	if start {
+		go deadlinehelper()
+	}
+}
+
+// removeGoroutineDeadline unlinks d. goroutineDeadlines.lock must be
+// held.
+func removeGoroutineDeadline(d *goroutineDeadline) {
+	if d.prev != nil {
+		d.prev.next = d.next
+	} else {
+		goroutineDeadlines.list = d.next
+	}
+	if d.next != nil {
+		d.next.prev = d.prev
+	}
+	d.prev, d.next = nil, nil
+}
+
+// clearGoroutineDeadline removes the deadline of gp, which is exiting.
+func clearGoroutineDeadline(gp *g) {
+	lock(&goroutineDeadlines.lock)
+	if gp.deadline != nil {
+		removeGoroutineDeadline(gp.deadline)
+		gp.deadline = nil
+	}
+	unlock(&goroutineDeadlines.lock)
+}
+
+// goroutineDeadlineNext returns the earliest armed deadline, or 0.
+func goroutineDeadlineNext() int64 {
+	return int64(atomic.Load64(&goroutineDeadlines.next))
+}
+
+// checkGoroutineDeadlines wakes the deadline helper if a deadline has
+// passed. It is called by sysmon.
+func checkGoroutineDeadlines(now int64) {
+	if next := goroutineDeadlineNext(); next == 0 || next > now || atomic.Load(&goroutineDeadlines.idle) == 0 {
+		return
+	}
+	lock(&goroutineDeadlines.lock)
+	goroutineDeadlines.idle = 0
+	var list gList
+	list.push(goroutineDeadlines.g)
+	injectglist(&list)
+	unlock(&goroutineDeadlines.lock)
+}
+
+func deadlinehelper() {
+	lock(&goroutineDeadlines.lock)
+	goroutineDeadlines.g = getg()
+	for {
+		atomic.Store(&goroutineDeadlines.idle, 1)
+		goparkunlock(&goroutineDeadlines.lock, waitReasonDeadlineIdle, traceEvGoBlock, 1)
+		// this goroutine is explicitly resumed by sysmon
+
+		fireGoroutineDeadlines()
+		lock(&goroutineDeadlines.lock)
+	}
+}
+
+// fireGoroutineDeadlines removes the deadlines that have passed and
+// calls their callbacks with the stacks of their goroutines.
+func fireGoroutineDeadlines() {
+	now := nanotime()
+	var expired *goroutineDeadline
+	next := int64(0)
+	lock(&goroutineDeadlines.lock)
+	for d := goroutineDeadlines.list; d != nil; {
+		dn := d.next
+		if d.when <= now {
+			removeGoroutineDeadline(d)
+			d.gp.deadline = nil
+			d.next = expired
+			expired = d
+		} else if next == 0 || d.when < next {
+			next = d.when
+		}
+		d = dn
+	}
+	atomic.Store64(&goroutineDeadlines.next, uint64(next))
+	unlock(&goroutineDeadlines.lock)
+
+	for d := expired; d != nil; d = d.next {
+		buf := make([]byte, deadlineStackSize)
+		n := 0
+		stopTheWorld("goroutine deadline")
+		if gp := d.gp; readgstatus(gp) != _Gdead && gp.goid == d.goid {
+			systemstack(func() {
+				g0 := getg()
+				g0.m.traceback = 1
+				g0.writebuf = buf[0:0:len(buf)]
+				goroutineheader(gp)
+				traceback(^uintptr(0), ^uintptr(0), 0, gp)
+				g0.m.traceback = 0
+				n = len(g0.writebuf)
+				g0.writebuf = nil
+			})
+		}
+		startTheWorld()
+		if n > 0 {
+			go d.f(d.goid, buf[:n])
+		}
+	}
+}
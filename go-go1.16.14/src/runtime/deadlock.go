@@ -0,0 +1,499 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Partial deadlock detection.
+//
+// checkdead only reports a deadlock once every goroutine is blocked.
+// With GODEBUG=deadlockdetect=1, sync.Mutex additionally tells the
+// runtime which goroutine holds each mutex it locked on its slow path
+// (see sync/runtime.go), and sysmon
+// periodically builds a wait-for graph from the goroutines queued on
+// sync.Mutex and sync.RWMutex semaphores. A goroutine blocked on a
+// mutex has an edge to the goroutine holding it. Since a blocked
+// goroutine waits for exactly one lock, every goroutine has at most one
+// outgoing edge and a deadlock among a subset of goroutines is a cycle
+// in this graph.
+//
+// Sysmon's check runs without stopping the world, so it may see a
+// cycle that is not really there. When it finds one, it wakes the
+// deadlock detector goroutine, which repeats the check with the world
+// stopped and, if the cycle is still there, prints it along with the
+// stacks of the goroutines in it. With deadlockdetect=2 it then
+// crashes the program.
+//
+// Channels do not have owners, so cycles through channel operations
+// are not detected.
+
+package runtime
+
+import (
+	"runtime/internal/atomic"
+	"runtime/internal/sys"
+	"unsafe"
+)
+
+// deadlockCheckPeriod is how often sysmon looks for partial deadlocks.
+const deadlockCheckPeriod = 1e9 // 1s
+
+// Offsets of the semaphores in sync.Mutex and sync.RWMutex relative to
+// the sync.Mutex whose owner is recorded. RWMutex begins with its
+// writer Mutex, so readers waiting on readerSem wait for the owner of
+// that Mutex. TestSyncMutexOffsets checks them against package sync.
+const (
+	syncMutexSemaOffset        = 4  // sync.Mutex.sema
+	syncRWMutexReaderSemOffset = 12 // sync.RWMutex.readerSem
+)
+
+// syncMutexLocked is the mutexLocked bit of sync.Mutex.state, which
+// is at offset 0.
+const syncMutexLocked = 1
+
+// syncRWMutexWriterSemOffset is the offset of writerSem in
+// sync.RWMutex. A writer waiting on it waits for readers, which are
+// not recorded as owners.
+const syncRWMutexWriterSemOffset = 8
+
+// lockOwner records the goroutine that holds a sync.Mutex.
+//go:notinheap
+type lockOwner struct {
+	next *lockOwner
+	addr uintptr // address of the sync.Mutex
+	goid int64   // goroutine that locked it
+}
+
+const lockOwnerTabSize = 251
+
+// lockOwners maps the address of every locked sync.Mutex to its owner.
+// It is only used if debug.deadlockdetect > 0.
+var lockOwners struct {
+	lock  mutex
+	n     uint32 // number of entries; read w/o the lock
+	free  *lockOwner
+	table [lockOwnerTabSize]*lockOwner
+}
+
+//go:linkname sync_runtime_lockOwnersEnabled sync.runtime_lockOwnersEnabled
+func sync_runtime_lockOwnersEnabled() bool {
+	return debug.deadlockdetect > 0
+}
+
+//go:linkname sync_runtime_setLockOwner sync.runtime_setLockOwner
+func sync_runtime_setLockOwner(m unsafe.Pointer, locked bool) {
+	addr := uintptr(m)
+	bucket := &lockOwners.table[addr%lockOwnerTabSize]
+	lock(&lockOwners.lock)
+	prev := bucket
+	for o := *bucket; o != nil; o = o.next {
+		if o.addr == addr {
+			if locked {
+				o.goid = getg().goid
+			} else if atomic.Load((*uint32)(m))&syncMutexLocked == 0 {
+				// If m has been locked again since, its
+				// new owner has taken over the entry or
+				// will do so, so only remove it otherwise.
+				*prev = o.next
+				o.next = lockOwners.free
+				lockOwners.free = o
+				atomic.Xadd(&lockOwners.n, -1)
+			}
+			unlock(&lockOwners.lock)
+			return
+		}
+		prev = &o.next
+	}
+	if locked {
+		o := lockOwners.free
+		if o != nil {
+			lockOwners.free = o.next
+		} else {
+			o = (*lockOwner)(persistentalloc(unsafe.Sizeof(lockOwner{}), sys.PtrSize, &memstats.other_sys))
+		}
+		o.addr = addr
+		o.goid = getg().goid
+		o.next = *bucket
+		*bucket = o
+		atomic.Xadd(&lockOwners.n, 1)
+	}
+	unlock(&lockOwners.lock)
+}
+
+// lockOwnerOf returns the goroutine ID of the owner of the sync.Mutex
+// at addr, or 0 if it is not locked.
+func lockOwnerOf(addr uintptr) int64 {
+	goid := int64(0)
+	lock(&lockOwners.lock)
+	for o := lockOwners.table[addr%lockOwnerTabSize]; o != nil; o = o.next {
+		if o.addr == addr {
+			goid = o.goid
+			break
+		}
+	}
+	unlock(&lockOwners.lock)
+	return goid
+}
+
+// maxDeadlockWaiters bounds the number of blocked goroutines
+// considered by a single check.
+const maxDeadlockWaiters = 1024
+
+// A deadlockWaiter is a goroutine blocked on a sync.Mutex.
+type deadlockWaiter struct {
+	gp    guintptr
+	goid  int64
+	lock  uintptr // address of the sync.Mutex gp waits for
+	owner int64   // goroutine ID of the owner of lock
+	next  int32   // index of the owner in deadlockGraph.w, or -1
+	mark  int32   // visit stamp used by findCycles
+	cycle int32   // 1 + index of the cycle gp is in, or 0
+}
+
+// A deadlockGraph is the wait-for graph among goroutines blocked on
+// sync mutexes.
+type deadlockGraph struct {
+	n      int
+	w      [maxDeadlockWaiters]deadlockWaiter
+	cycles int
+}
+
+// maxReportedDeadlocks is the number of reported cycles remembered so
+// that each cycle is only reported once.
+const maxReportedDeadlocks = 64
+
+var deadlockDetect struct {
+	// reported holds the smallest goroutine ID in each reported
+	// cycle. Goroutine IDs are not reused, so this identifies the
+	// cycle for as long as it exists. Written with the world
+	// stopped, read by sysmon. It is accessed atomically, so it
+	// comes first to be 8-byte aligned on 32-bit systems.
+	reported [maxReportedDeadlocks]uint64
+	nreport  uint32
+
+	lock mutex
+	g    *g
+	idle uint32
+
+	// sysmonGraph is used by sysmon, and stwGraph by the deadlock
+	// detector goroutine, which runs concurrently with sysmon.
+	sysmonGraph *deadlockGraph
+	stwGraph    *deadlockGraph
+}
+
+func init() {
+	if debug.deadlockdetect > 0 {
+		size := unsafe.Sizeof(deadlockGraph{})
+		deadlockDetect.sysmonGraph = (*deadlockGraph)(persistentalloc(size, sys.PtrSize, &memstats.other_sys))
+		deadlockDetect.stwGraph = (*deadlockGraph)(persistentalloc(size, sys.PtrSize, &memstats.other_sys))
+		go deadlockdetecthelper()
+	}
+}
+
+func deadlockdetecthelper() {
+	deadlockDetect.g = getg()
+	lockInit(&deadlockDetect.lock, lockRankDeadlockDetect)
+	for {
+		lock(&deadlockDetect.lock)
+		atomic.Store(&deadlockDetect.idle, 1)
+		goparkunlock(&deadlockDetect.lock, waitReasonDeadlockDetectIdle, traceEvGoBlock, 1)
+		// this goroutine is explicitly resumed by sysmon
+
+		stopTheWorld("partial deadlock check")
+		gr := deadlockDetect.stwGraph
+		gr.build()
+		if gr.findCycles() > 0 {
+			gr.report()
+			if debug.deadlockdetect > 1 {
+				throw("partial deadlock")
+			}
+		}
+		startTheWorld()
+	}
+}
+
+// checkPartialDeadlock looks for a cycle of goroutines blocked on each
+// other's mutexes and, if it finds one, wakes the deadlock detector to
+// confirm and report it. It is called by sysmon.
+func checkPartialDeadlock() {
+	if atomic.Load(&lockOwners.n) == 0 || atomic.Load(&deadlockDetect.idle) == 0 {
+		return
+	}
+	gr := deadlockDetect.sysmonGraph
+	gr.build()
+	if gr.findCycles() == 0 {
+		return
+	}
+	lock(&deadlockDetect.lock)
+	deadlockDetect.idle = 0
+	var list gList
+	list.push(deadlockDetect.g)
+	injectglist(&list)
+	unlock(&deadlockDetect.lock)
+}
+
+// build fills in gr from the goroutines waiting in semaphore queues.
+func (gr *deadlockGraph) build() {
+	gr.n = 0
+	gr.cycles = 0
+	for i := range semtable {
+		root := &semtable[i].root
+		if atomic.Load(&root.nwait) == 0 {
+			continue
+		}
+		lockWithRank(&root.lock, lockRankRoot)
+		gr.addTree(root.treap)
+		unlock(&root.lock)
+	}
+	for i := 0; i < gr.n; i++ {
+		w := &gr.w[i]
+		w.owner = lockOwnerOf(w.lock)
+		w.next = -1
+		if w.owner == 0 {
+			continue
+		}
+		for j := 0; j < gr.n; j++ {
+			if gr.w[j].goid == w.owner {
+				w.next = int32(j)
+				break
+			}
+		}
+	}
+}
+
+// addTree adds the goroutines waiting on the semaphores in the treap t.
+func (gr *deadlockGraph) addTree(t *sudog) {
+	if t == nil {
+		return
+	}
+	gr.addTree(t.prev)
+	for s := t; s != nil; s = s.waitlink {
+		if gr.n == len(gr.w) {
+			return
+		}
+		gp := s.g
+		if readgstatus(gp)&^_Gscan != _Gwaiting {
+			continue
+		}
+		var addr uintptr
+		switch s.semaReason {
+		case waitReasonSyncMutexLock:
+			addr = uintptr(s.elem) - syncMutexSemaOffset
+		case waitReasonSyncRWMutexRLock:
+			addr = uintptr(s.elem) - syncRWMutexReaderSemOffset
+		default:
+			// Waiting for a semaphore without an owner, such
+			// as RWMutex readers or a WaitGroup.
+			continue
+		}
+		gr.w[gr.n] = deadlockWaiter{gp: guintptr(unsafe.Pointer(gp)), goid: gp.goid, lock: addr}
+		gr.n++
+	}
+	gr.addTree(t.next)
+}
+
+// findCycles marks the cycles in gr that have not been reported yet
+// and returns their number.
+func (gr *deadlockGraph) findCycles() int {
+	for i := 0; i < gr.n; i++ {
+		gr.w[i].mark = 0
+		gr.w[i].cycle = 0
+	}
+	for i := 0; i < gr.n; i++ {
+		stamp := int32(i + 1)
+		j := int32(i)
+		for j >= 0 && gr.w[j].mark == 0 {
+			gr.w[j].mark = stamp
+			j = gr.w[j].next
+		}
+		if j < 0 || gr.w[j].mark != stamp {
+			// Reached a goroutine that is not blocked or
+			// one visited from an earlier start.
+			continue
+		}
+		// j is in a new cycle.
+		minGoid := gr.w[j].goid
+		for k := gr.w[j].next; k != j; k = gr.w[k].next {
+			if gr.w[k].goid < minGoid {
+				minGoid = gr.w[k].goid
+			}
+		}
+		if deadlockReported(minGoid) {
+			continue
+		}
+		gr.cycles++
+		gr.w[j].cycle = int32(gr.cycles)
+		for k := gr.w[j].next; k != j; k = gr.w[k].next {
+			gr.w[k].cycle = int32(gr.cycles)
+		}
+	}
+	return gr.cycles
+}
+
+func deadlockReported(minGoid int64) bool {
+	n := atomic.Load(&deadlockDetect.nreport)
+	if n > maxReportedDeadlocks {
+		n = maxReportedDeadlocks
+	}
+	for i := uint32(0); i < n; i++ {
+		if int64(atomic.Load64(&deadlockDetect.reported[i])) == minGoid {
+			return true
+		}
+	}
+	return false
+}
+
+// report prints the cycles marked by findCycles and the stacks of the
+// goroutines in them, and remembers them as reported. The world must
+// be stopped.
+func (gr *deadlockGraph) report() {
+	printlock()
+	for c := int32(1); c <= int32(gr.cycles); c++ {
+		print("partial deadlock detected:\n")
+		minGoid := int64(-1)
+		for i := 0; i < gr.n; i++ {
+			w := &gr.w[i]
+			if w.cycle != c {
+				continue
+			}
+			if minGoid < 0 || w.goid < minGoid {
+				minGoid = w.goid
+			}
+			print("\tgoroutine ", w.goid, " [", w.gp.ptr().waitreason.String(), "] waits for lock ", hex(w.lock), " held by goroutine ", w.owner, "\n")
+		}
+		print("\n")
+		for i := 0; i < gr.n; i++ {
+			w := &gr.w[i]
+			if w.cycle != c {
+				continue
+			}
+			gp := w.gp.ptr()
+			systemstack(func() {
+				goroutineheader(gp)
+				traceback(^uintptr(0), ^uintptr(0), 0, gp)
+			})
+			print("\n")
+		}
+		n := atomic.Xadd(&deadlockDetect.nreport, 1) - 1
+		atomic.Store64(&deadlockDetect.reported[n%maxReportedDeadlocks], uint64(minGoid))
+	}
+	printunlock()
+}
+
+// throwAllAsleep is like throw("all goroutines are asleep - deadlock!"),
+// but also describes what each blocked goroutine is waiting on before
+// the stacks are printed.
+func throwAllAsleep() {
+	systemstack(func() {
+		print("fatal error: all goroutines are asleep - deadlock!\n")
+		printBlockedGoroutines()
+	})
+	gp := getg()
+	if gp.m.throwing == 0 {
+		gp.m.throwing = 1
+	}
+	fatalthrow()
+	*(*int)(nil) = 0 // not reached
+}
+
+// printBlockedGoroutines prints a line for each goroutine blocked on a
+// channel or a semaphore, giving the address of the object it waits on
+// and the other goroutines involved with it, as far as they are known.
+// It is only called once every goroutine is blocked.
+func printBlockedGoroutines() {
+	print("\n")
+	lock(&allglock)
+	for _, gp := range allgs {
+		if isSystemGoroutine(gp, false) || readgstatus(gp)&^_Gscan != _Gwaiting || gp.waiting == nil {
+			continue
+		}
+		print("goroutine ", gp.goid, " [", gp.waitreason.String(), "]:")
+		sep := " "
+		for s := gp.waiting; s != nil; s = s.waitlink {
+			if s.c == nil {
+				continue
+			}
+			print(sep)
+			printChanWaiters(s.c, gp)
+			sep = "; "
+		}
+		print("\n")
+	}
+	unlock(&allglock)
+
+	for i := range semtable {
+		root := &semtable[i].root
+		lockWithRank(&root.lock, lockRankRoot)
+		printSemaWaiters(root.treap)
+		unlock(&root.lock)
+	}
+}
+
+// printChanWaiters describes c and the goroutines other than gp queued
+// on it.
+func printChanWaiters(c *hchan, gp *g) {
+	print("chan ", c, " (elem ", c.elemtype.string(), ", ", c.qcount, "/", c.dataqsiz, " buffered")
+	if c.closed != 0 {
+		print(", closed")
+	}
+	print(")")
+	printWaitq(&c.sendq, gp, "send")
+	printWaitq(&c.recvq, gp, "receive")
+}
+
+func printWaitq(q *waitq, gp *g, op string) {
+	n := 0
+	for s := q.first; s != nil; s = s.next {
+		if s.g == gp {
+			continue
+		}
+		if n == 0 {
+			print(", waiting to ", op, ":")
+		}
+		print(" ", s.g.goid)
+		n++
+	}
+}
+
+// printSemaWaiters describes the goroutines waiting on the semaphores
+// in the treap t.
+func printSemaWaiters(t *sudog) {
+	if t == nil {
+		return
+	}
+	printSemaWaiters(t.prev)
+	for s := t; s != nil; s = s.waitlink {
+		gp := s.g
+		if isSystemGoroutine(gp, false) {
+			continue
+		}
+		addr := uintptr(s.elem)
+		print("goroutine ", gp.goid, " [", gp.waitreason.String(), "]: ")
+		switch s.semaReason {
+		case waitReasonSyncMutexLock:
+			printMutexOwner("sync.Mutex", addr-syncMutexSemaOffset)
+		case waitReasonSyncRWMutexRLock:
+			printMutexOwner("sync.RWMutex", addr-syncRWMutexReaderSemOffset)
+		case waitReasonSyncRWMutexLock:
+			print("sync.RWMutex ", hex(addr-syncRWMutexWriterSemOffset), ", waiting for readers to unlock")
+		default:
+			print("semaphore ", hex(addr))
+		}
+		print("\n")
+	}
+	printSemaWaiters(t.next)
+}
+
+// printMutexOwner prints the sync mutex at addr and, if lock owners
+// are tracked, the goroutine holding it. An RWMutex starts with the
+// sync.Mutex held by its writer.
+func printMutexOwner(kind string, addr uintptr) {
+	print(kind, " ", hex(addr))
+	if debug.deadlockdetect == 0 {
+		print(", holder unknown (GODEBUG=deadlockdetect=1 records holders)")
+		return
+	}
+	if goid := lockOwnerOf(addr); goid != 0 {
+		print(", held by goroutine ", goid)
+	} else {
+		print(", holder unknown")
+	}
+}
@@ -17,6 +17,9 @@ func GOMAXPROCS(n int) int {
 	if GOARCH == "wasm" && n > 1 {
 		n = 1 // WebAssembly has no threads yet, so only one CPU is possible.
 	}
+	if detsched.enabled && n > 1 {
+		n = 1 // Deterministic scheduling runs a single P.
+	}
 
 	lock(&sched.lock)
 	ret := int(gomaxprocs)
@@ -25,6 +28,24 @@ func GOMAXPROCS(n int) int {
 		return ret
 	}
 
+	// Up to the number of Ps that exist, the change does not stop
+	// the world; see procpark.go. It still waits for a GC cycle in
+	// progress to finish, since the GC assumes that gomaxprocs does
+	// not change during a cycle.
+	semacquire(&gcsema)
+	parked := false
+	systemstack(func() {
+		parked = procsetlimit(int32(n))
+	})
+	semrelease(&gcsema)
+	if parked {
+		if getg().m.p.ptr().id >= int32(n) {
+			// Let our own P park, too.
+			Gosched()
+		}
+		return ret
+	}
+
 	stopTheWorldGC("GOMAXPROCS")
 
 	// newprocs will be processed by startTheWorld
@@ -52,6 +73,25 @@ func NumCgoCall() int64 {
 	return n
 }
 
+// GoroutineCgoCalls returns the number of cgo calls made by the calling
+// goroutine and the time, in nanoseconds, spent in them. The time
+// includes any calls back from C into Go made during those calls.
+// Comparing the values returned at the start and at the end of a
+// piece of work tells how much of it was spent in C.
+func GoroutineCgoCalls() (n, ns int64) {
+	gp := getg()
+	return gp.cgoCalls, cgoTicksToNanos(gp.cgoTicks)
+}
+
+// cgoTicksToNanos converts the cputicks spent in cgo calls to
+// nanoseconds.
+func cgoTicksToNanos(ticks int64) int64 {
+	if ticks == 0 {
+		return 0
+	}
+	return int64(float64(ticks) * 1e9 / float64(tickspersecond()))
+}
+
 // NumGoroutine returns the number of goroutines that currently exist.
 func NumGoroutine() int {
 	return int(gcount())
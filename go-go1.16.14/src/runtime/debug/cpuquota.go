@@ -0,0 +1,45 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import "time"
+
+// SetCPUQuota makes the runtime keep the program within a CPU quota of
+// cpus CPUs, as the CFS bandwidth controller of Linux enforces it: the
+// program may use cpus*period of CPU time in each period. Unlike the
+// kernel, which stops every thread of the program once the quota of a
+// period is used up, the runtime throttles the processors: it lets all
+// GOMAXPROCS of them run while there is time to spare, and parks the
+// ones above the whole number of CPUs of the quota for the rest of the
+// period once there is not. A program can thus run with GOMAXPROCS=3
+// under a quota of 2.5 CPUs without being stopped in the middle of a
+// garbage collection. The garbage collector also aims its background
+// work at 25% of the quota, rather than of GOMAXPROCS.
+//
+// A cpus of zero or less removes the quota, which is the initial
+// setting unless GODEBUG=cpuquota=1 makes the runtime read it from the
+// cgroup of the process. A period of zero or less means 100ms, the
+// kernel's default. The runtime cannot know when the kernel's periods
+// start, so the quota should match that of the kernel, if any, rather
+// than exceed it.
+//
+// The runtime/metrics package reports the number of periods in which
+// the processors were throttled in /sched/cpuquota/throttled:periods.
+//
+// SetCPUQuota returns the previous setting.
+func SetCPUQuota(cpus float64, period time.Duration) (prevCPUs float64, prevPeriod time.Duration) {
+	var quota int64
+	if cpus > 0 {
+		if period <= 0 {
+			period = 100 * time.Millisecond
+		}
+		quota = int64(cpus * float64(period))
+	}
+	q, p := setCPUQuota(quota, int64(period))
+	if q == 0 {
+		return 0, 0
+	}
+	return float64(q) / float64(p), time.Duration(p)
+}
@@ -0,0 +1,64 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug_test
+
+import (
+	"runtime"
+	. "runtime/debug"
+	"runtime/metrics"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSetCPUQuota(t *testing.T) {
+	if runtime.GOOS == "js" {
+		t.Skip("no sysmon on js")
+	}
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(2))
+	prevCPUs, prevPeriod := SetCPUQuota(1.5, 10*time.Millisecond)
+	defer SetCPUQuota(prevCPUs, prevPeriod)
+	if cpus, period := SetCPUQuota(1.5, 10*time.Millisecond); cpus != 1.5 || period != 10*time.Millisecond {
+		t.Fatalf("SetCPUQuota returned %v, %v, want 1.5, 10ms", cpus, period)
+	}
+
+	samples := []metrics.Sample{{Name: "/sched/cpuquota/throttled:periods"}}
+	metrics.Read(samples)
+	before := samples[0].Value.Uint64()
+
+	// Two goroutines that never block keep both Ps busy, so half way
+	// through each period the second P must park.
+	var stop uint32
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for atomic.LoadUint32(&stop) == 0 {
+			}
+		}()
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		time.Sleep(50 * time.Millisecond)
+		metrics.Read(samples)
+		if samples[0].Value.Uint64()-before >= 3 || time.Now().After(deadline) {
+			break
+		}
+	}
+	atomic.StoreUint32(&stop, 1)
+	wg.Wait()
+	if n := samples[0].Value.Uint64() - before; n < 3 {
+		t.Errorf("throttled in %d periods, want at least 3", n)
+	}
+
+	if cpus, period := SetCPUQuota(0, 0); cpus != 1.5 || period != 10*time.Millisecond {
+		t.Errorf("SetCPUQuota(0, 0) returned %v, %v, want 1.5, 10ms", cpus, period)
+	}
+	if cpus, period := SetCPUQuota(0, 0); cpus != 0 || period != 0 {
+		t.Errorf("SetCPUQuota(0, 0) returned %v, %v after removing the quota, want 0, 0", cpus, period)
+	}
+}
@@ -0,0 +1,28 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import "time"
+
+// SetGoroutineDeadline declares that the calling goroutine should
+// finish within d. If the goroutine has not exited d from now, the
+// runtime takes its stack, formatted as by runtime.Stack, and calls
+// f with the goroutine's ID and that stack in a new goroutine. The
+// late goroutine is not stopped or otherwise disturbed, so f can log
+// the stack of a stuck request handler long before an external
+// timeout would notice.
+//
+// A goroutine has at most one deadline, and each call replaces the
+// previous one; a d <= 0 only removes it. A deadline fires at most
+// once. Goroutines do not inherit deadlines. The runtime checks for
+// missed deadlines about every 10ms, and takes the stack with the
+// world stopped, so deadlines are meant for the rare cases where
+// something has gone wrong.
+func SetGoroutineDeadline(d time.Duration, f func(goid int64, stack []byte)) {
+	if f == nil {
+		d = 0
+	}
+	setGoroutineDeadline(int64(d), f)
+}
@@ -0,0 +1,67 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug_test
+
+import (
+	"regexp"
+	. "runtime/debug"
+	"testing"
+	"time"
+)
+
+type deadlineMiss struct {
+	goid  int64
+	stack string
+}
+
+func TestSetGoroutineDeadline(t *testing.T) {
+	missed := make(chan deadlineMiss, 10)
+	report := func(goid int64, stack []byte) {
+		missed <- deadlineMiss{goid, string(stack)}
+	}
+
+	release := make(chan bool)
+	done := make(chan bool)
+	go func() {
+		SetGoroutineDeadline(10*time.Millisecond, report)
+		<-release
+		done <- true
+	}()
+	// Neither a removed deadline nor one of a goroutine that has
+	// exited may fire.
+	go func() {
+		SetGoroutineDeadline(50*time.Millisecond, report)
+		SetGoroutineDeadline(0, report)
+		<-release
+		done <- true
+	}()
+	go func() {
+		SetGoroutineDeadline(50*time.Millisecond, report)
+		done <- true
+	}()
+	<-done
+
+	var m deadlineMiss
+	select {
+	case m = <-missed:
+	case <-time.After(10 * time.Second):
+		t.Fatal("missed deadline not reported")
+	}
+	if !regexp.MustCompile(`^goroutine \d+ \[chan receive\]:\n`).MatchString(m.stack) {
+		t.Errorf("stack of goroutine %d does not show it blocked:\n%s", m.goid, m.stack)
+	}
+	if !regexp.MustCompile(`TestSetGoroutineDeadline\.func2\(`).MatchString(m.stack) {
+		t.Errorf("stack of goroutine %d does not show the late function:\n%s", m.goid, m.stack)
+	}
+	time.Sleep(150 * time.Millisecond)
+	close(release)
+	<-done
+	<-done
+	select {
+	case m := <-missed:
+		t.Errorf("unexpected missed deadline of goroutine %d:\n%s", m.goid, m.stack)
+	default:
+	}
+}
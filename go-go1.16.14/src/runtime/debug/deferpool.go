@@ -0,0 +1,56 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+// deferPoolClasses is the number of defer pool size classes.
+const deferPoolClasses = 5
+
+// DeferPoolStats describes the defer pools of one size class.
+//
+// A defer statement that the compiler cannot implement inline needs a
+// record holding the deferred call and its arguments. The runtime
+// takes such records from per-processor pools, one per size class of
+// the arguments, and allocates them on the heap when the pool is empty.
+type DeferPoolStats struct {
+	ArgSize int    // largest argument size, in bytes, of the class
+	Depth   int    // capacity of the pool of each processor
+	Hits    uint64 // records taken from a pool
+	Misses  uint64 // records allocated on the heap
+}
+
+// ReadDeferPoolStats returns the statistics of the defer pools, one
+// per size class in increasing order of ArgSize. Records for calls
+// with arguments larger than the last class are always allocated on
+// the heap, and are not counted.
+func ReadDeferPoolStats() []DeferPoolStats {
+	var argSize, depth [deferPoolClasses]int
+	var hits, misses [deferPoolClasses]uint64
+	readDeferPoolClasses(argSize[:], depth[:], hits[:], misses[:])
+	stats := make([]DeferPoolStats, deferPoolClasses)
+	for i := range stats {
+		stats[i] = DeferPoolStats{
+			ArgSize: argSize[i],
+			Depth:   depth[i],
+			Hits:    hits[i],
+			Misses:  misses[i],
+		}
+	}
+	return stats
+}
+
+// SetDeferPoolDepth sets the capacity of the defer pool of each
+// processor for the size class with index class in the result of
+// ReadDeferPoolStats, 32 records by default. A processor whose pool is
+// full moves half of it to a shared pool, and refills an empty pool
+// from the shared pool, so deeper pools cut that traffic and the heap
+// allocations of programs that nest many defers. The depth is capped
+// at 1024; a depth of zero disables the pools of the class. Changing
+// the depth briefly stops all goroutines.
+//
+// SetDeferPoolDepth returns the previous setting, or -1 if class is
+// not a size class.
+func SetDeferPoolDepth(class, depth int) (prev int) {
+	return setDeferPoolDepth(class, depth)
+}
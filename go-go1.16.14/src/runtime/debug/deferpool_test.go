@@ -0,0 +1,67 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug_test
+
+import (
+	. "runtime/debug"
+	"testing"
+)
+
+// deferInLoop runs n defers that the compiler cannot open-code, so
+// that they take records from the defer pools.
+func deferInLoop(n int) (sum int) {
+	for i := 0; i < n; i++ {
+		defer func() { sum++ }()
+	}
+	return 0
+}
+
+func TestDeferPoolStats(t *testing.T) {
+	before := ReadDeferPoolStats()
+	if len(before) == 0 {
+		t.Fatalf("no defer pool classes")
+	}
+	for i, s := range before {
+		if s.Depth != 32 {
+			t.Errorf("class %d: depth %d, want 32", i, s.Depth)
+		}
+		if i > 0 && s.ArgSize <= before[i-1].ArgSize {
+			t.Errorf("class %d: argument size %d not above %d", i, s.ArgSize, before[i-1].ArgSize)
+		}
+	}
+
+	for i := 0; i < 100; i++ {
+		deferInLoop(10)
+	}
+	after := ReadDeferPoolStats()
+	if after[0].Hits == before[0].Hits {
+		t.Errorf("no defer pool hits for class 0")
+	}
+
+	// Without a pool, every record is allocated.
+	if prev := SetDeferPoolDepth(0, 0); prev != 32 {
+		t.Errorf("SetDeferPoolDepth returned %d, want 32", prev)
+	}
+	before = ReadDeferPoolStats()
+	deferInLoop(100)
+	after = ReadDeferPoolStats()
+	if prev := SetDeferPoolDepth(0, 64); prev != 0 {
+		t.Errorf("SetDeferPoolDepth returned %d, want 0", prev)
+	}
+	if hits, misses := after[0].Hits-before[0].Hits, after[0].Misses-before[0].Misses; hits != 0 || misses != 100 {
+		t.Errorf("with depth 0, got %d hits and %d misses, want 0 and 100", hits, misses)
+	}
+
+	// A pool deeper than the built-in buffer.
+	deferInLoop(100)
+	if depth := ReadDeferPoolStats()[0].Depth; depth != 64 {
+		t.Errorf("depth %d after setting 64", depth)
+	}
+	SetDeferPoolDepth(0, 32)
+
+	if prev := SetDeferPoolDepth(len(before), 32); prev != -1 {
+		t.Errorf("SetDeferPoolDepth of class %d returned %d, want -1", len(before), prev)
+	}
+}
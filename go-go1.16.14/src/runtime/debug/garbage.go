@@ -81,6 +81,32 @@ func ReadGCStats(stats *GCStats) {
 	}
 }
 
+// AllocStats holds allocation counters of runtime.MemStats accumulated
+// over an interval.
+type AllocStats struct {
+	TotalAlloc uint64 // bytes allocated for heap objects
+	Mallocs    uint64 // heap objects allocated
+	Frees      uint64 // heap objects freed
+}
+
+// ReadAndResetAllocStats returns the allocation counters accumulated
+// since the previous call to ReadAndResetAllocStats, or since the
+// program started, and starts a new interval. Concurrent calls split
+// the allocations between them: each allocation is counted by exactly
+// one call. The counters are shared by the whole program, so a call
+// affects the intervals of all other callers.
+//
+// Like runtime.ReadMemStats, ReadAndResetAllocStats briefly stops the
+// world, so that the counters are exact.
+func ReadAndResetAllocStats() AllocStats {
+	totalAlloc, mallocs, frees := readAndResetAllocStats()
+	return AllocStats{
+		TotalAlloc: totalAlloc,
+		Mallocs:    mallocs,
+		Frees:      frees,
+	}
+}
+
 // SetGCPercent sets the garbage collection target percentage:
 // a collection is triggered when the ratio of freshly allocated data
 // to live data remaining after the previous collection reaches this percentage.
@@ -92,6 +118,38 @@ func SetGCPercent(percent int) int {
 	return int(setGCPercent(int32(percent)))
 }
 
+// SetForceGCPeriod sets the longest time the runtime lets pass without
+// a garbage collection. If no collection has run for that long, for
+// instance because the program allocates little, one is forced so that
+// finalizers run and unused memory is eventually returned to the
+// operating system. Collections are only forced after the first
+// collection of the program. A period of zero or less disables forced
+// collections, which suits nearly idle programs for which they are
+// the main source of wakeups. The initial setting is two minutes.
+// SetForceGCPeriod returns the previous setting, or -1 if forced
+// collections were disabled.
+func SetForceGCPeriod(d time.Duration) (prev time.Duration) {
+	return time.Duration(setForceGCPeriod(int64(d)))
+}
+
+// SetIdleGCThreshold sets the heap size, in bytes, below which garbage
+// collections use only idle CPU. Such a collection marks only on
+// processors, of the GOMAXPROCS, that have no goroutine to run, and
+// does not make allocating goroutines help it, so it takes no CPU time
+// away from the program and finishes whenever the program leaves some
+// CPU idle. Meanwhile the heap keeps growing, past the size at which
+// the collection would otherwise have finished. Once the heap reaches
+// the threshold, the collection continues normally, taking about a
+// quarter of the CPU and assists until it finishes. Batch programs can
+// thus trade memory for throughput, up to the threshold. Explicit
+// collections, by runtime.GC and FreeOSMemory, are never restricted to
+// idle CPU. A threshold of zero or less, the initial setting, disables
+// idle-only collections. SetIdleGCThreshold returns the previous
+// setting.
+func SetIdleGCThreshold(bytes int64) (prev int64) {
+	return setIdleGCThreshold(bytes)
+}
+
 // FreeOSMemory forces a garbage collection followed by an
 // attempt to return as much memory to the operating system
 // as possible. (Even if this is not called, the runtime gradually
@@ -134,6 +192,17 @@ func SetMaxThreads(threads int) int {
 	return setMaxThreads(threads)
 }
 
+// SetIdleThreadTimeout sets how long an operating system thread that
+// the Go program created may stay idle, waiting for a goroutine to run,
+// before it exits. A burst of blocking system calls can leave many such
+// threads behind. One idle thread is always kept, as is the main
+// thread. A timeout of zero, the initial setting, keeps idle threads
+// forever. SetIdleThreadTimeout returns the previous setting. Threads
+// that are already idle keep the timeout they started with.
+func SetIdleThreadTimeout(d time.Duration) (prev time.Duration) {
+	return time.Duration(setIdleThreadTimeout(int64(d)))
+}
+
 // SetPanicOnFault controls the runtime's behavior when a program faults
 // at an unexpected (non-nil) address. Such faults are typically caused by
 // bugs such as runtime memory corruption, so the default response is to crash
@@ -163,6 +232,19 @@ func SetPanicOnFault(enabled bool) bool {
 // The heap dump format is defined at https://golang.org/s/go15heapdump.
 func WriteHeapDump(fd uintptr)
 
+// WriteStateDump writes a textual snapshot of the runtime's state to
+// the given file descriptor: the scheduler counters and the state of
+// every P, M and goroutine as printed by GODEBUG=scheddetail=1, the
+// contents of the run queues, the main runtime.MemStats fields, and
+// the stacks of all goroutines.
+//
+// WriteStateDump suspends the execution of all goroutines until the
+// dump is completely written, so that all parts of it describe the same
+// moment. As with WriteHeapDump, the file descriptor must not be
+// connected to a pipe or socket whose other end is in the same Go
+// process.
+func WriteStateDump(fd uintptr)
+
 // SetTraceback sets the amount of detail printed by the runtime in
 // the traceback it prints before exiting due to an unrecovered panic
 // or an internal runtime error.
@@ -173,3 +255,97 @@ func WriteHeapDump(fd uintptr)
 // If SetTraceback is called with a level lower than that of the
 // environment variable, the call is ignored.
 func SetTraceback(level string)
+
+// SetTracebackFrames sets the number of frames printed for each goroutine
+// in the traceback the runtime prints before exiting. If a goroutine's
+// stack has more than head+tail frames, the innermost head frames and the
+// outermost tail frames are printed and the frames in between are elided,
+// so that both the point of failure and the start of a deep recursion
+// remain visible. A tail of zero prints only the innermost frames.
+// Head is always at least 1.
+// SetTracebackFrames returns the previous settings.
+// The initial setting is 100 head frames and no tail frames.
+func SetTracebackFrames(head, tail int) (prevHead, prevTail int) {
+	return setTracebackFrames(head, tail)
+}
+
+// SetCrashDumpFD sets the file descriptor to which the runtime writes a
+// minidump when the program dies of a fatal signal. A minidump is a
+// compact binary record of the faulting goroutine's registers and stack
+// memory and of the loaded modules, meant for offline symbolization when
+// operating system core dumps are unavailable. It is written in addition
+// to the traceback printed on standard error.
+//
+// The descriptor must remain open for as long as it is set; the runtime
+// writes to it from a signal handler and cannot reopen it. A negative fd
+// disables minidumps, which is the initial setting.
+// SetCrashDumpFD returns the previous setting.
+//
+// Minidumps are only written on Unix systems.
+func SetCrashDumpFD(fd int) (prev int) {
+	return setCrashDumpFD(fd)
+}
+
+// SetCgoSignalStackSize sets the minimum size, in bytes, of the signal
+// stack the runtime installs on threads created by C when they call
+// into Go. It applies to such threads that call into Go from now on;
+// threads that already have a signal stack of their own keep it.
+// C signal handlers, such as crash handlers that unwind the stack, run
+// on this stack and may overflow the default of 32 KB.
+// The size is rounded up to a power of two and is never smaller than
+// the default. A size of zero restores the default.
+// SetCgoSignalStackSize returns the previous setting.
+//
+// To raise the signal stack size of all threads from program start,
+// use GODEBUG=sigstacksize=N instead.
+func SetCgoSignalStackSize(bytes int) (prev int) {
+	return setCgoSignalStackSize(bytes)
+}
+
+// SetCgoCheck sets the level of the checks that code using cgo follows
+// the rules for passing pointers between Go and C, with the same meaning
+// as the cgocheck setting of the GODEBUG environment variable: 0 disables
+// the checks, 1 enables the cheap checks made on calls, and 2 also checks
+// every pointer write, at a considerable cost. Levels outside that range
+// are clamped to it. The change applies to the whole program; setting
+// level 2 around suspect code only finds errors while it is set.
+// SetCgoCheck suspends the execution of all goroutines while the level
+// changes, and returns the previous level.
+// The initial setting is 1, or the value of GODEBUG=cgocheck.
+func SetCgoCheck(level int) (prev int) {
+	return setCgoCheck(level)
+}
+
+// SetMaxExtraMs sets the maximum number of threads created outside of
+// Go, for instance by a C library, that can be running Go code at once.
+// Each such thread runs Go code on an extra M, runtime state with stacks
+// of its own that is kept for reuse once the thread returns to C.
+// When the limit is reached, further threads calling into Go wait until
+// another one returns. Lowering the limit frees the unused extra Ms
+// beyond it. A limit of zero, the initial setting, means no limit.
+// SetMaxExtraMs returns the previous setting.
+func SetMaxExtraMs(n int) (prev int) {
+	return setMaxExtraMs(n)
+}
+
+// SetExtraMIdleTimeout sets how long an extra M, see SetMaxExtraMs, may
+// go unused before it is freed. One unused extra M is always kept. A
+// timeout of zero, the initial setting, keeps unused extra Ms forever.
+// SetExtraMIdleTimeout returns the previous setting.
+func SetExtraMIdleTimeout(d time.Duration) (prev time.Duration) {
+	return time.Duration(setExtraMIdleTimeout(int64(d)))
+}
+
+// SetMemProfileDecay sets the half-life of the RecentAllocBytes and
+// RecentAllocObjects counts in the records returned by
+// runtime.MemProfile. The counts halve once per half-life, as of the
+// end of each garbage collection and each call to runtime.MemProfile,
+// so they describe the allocations of roughly the last half-life
+// rather than those of the whole run. Comparing them with LiveBytes
+// tells a call site that is still allocating from one that only holds
+// on to memory it allocated long ago.
+// A half-life of zero stops the decay, which is the initial setting.
+// SetMemProfileDecay returns the previous setting.
+func SetMemProfileDecay(halfLife time.Duration) (prev time.Duration) {
+	return time.Duration(setMemProfileDecay(int64(halfLife)))
+}
@@ -8,6 +8,8 @@ import (
 	"internal/testenv"
 	"runtime"
 	. "runtime/debug"
+	"runtime/metrics"
+	"sync"
 	"testing"
 	"time"
 )
@@ -87,6 +89,29 @@ func TestReadGCStats(t *testing.T) {
 	}
 }
 
+var allocStatsSink []*[64]byte
+
+func TestReadAndResetAllocStats(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+	ReadAndResetAllocStats()
+	const n = 1000
+	for i := 0; i < n; i++ {
+		allocStatsSink = append(allocStatsSink, new([64]byte))
+	}
+	allocStatsSink = nil
+	runtime.GC()
+	s := ReadAndResetAllocStats()
+	if s.Mallocs < n || s.TotalAlloc < n*64 {
+		t.Errorf("after %d 64-byte allocations, got %+v", n, s)
+	}
+	if s.Frees < n {
+		t.Errorf("after freeing %d objects, got %+v", n, s)
+	}
+	if s2 := ReadAndResetAllocStats(); s2.Mallocs >= s.Mallocs {
+		t.Errorf("counters not reset: got %+v, then %+v", s, s2)
+	}
+}
+
 var big = make([]byte, 1<<20)
 
 func TestFreeOSMemory(t *testing.T) {
@@ -110,6 +135,62 @@ var (
 	setGCPercentSink    interface{}
 )
 
+func TestSetForceGCPeriod(t *testing.T) {
+	orig := SetForceGCPeriod(10 * time.Millisecond)
+	defer SetForceGCPeriod(orig)
+	if orig != 2*time.Minute {
+		t.Errorf("initial period is %v, want 2m", orig)
+	}
+
+	// Collections are only forced after the first one. Without
+	// allocation, only forced collections run afterwards.
+	runtime.GC()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	numGC := ms.NumGC
+	for i := 0; ; i++ {
+		time.Sleep(50 * time.Millisecond)
+		runtime.ReadMemStats(&ms)
+		if ms.NumGC > numGC {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("no garbage collection in 5s with a 10ms period")
+		}
+	}
+
+	if prev := SetForceGCPeriod(0); prev != 10*time.Millisecond {
+		t.Errorf("SetForceGCPeriod returned %v, want 10ms", prev)
+	}
+	if prev := SetForceGCPeriod(orig); prev != -1 {
+		t.Errorf("SetForceGCPeriod returned %v after disabling, want -1", prev)
+	}
+}
+
+func TestSetIdleGCThreshold(t *testing.T) {
+	// With a single P kept busy allocating, an idle-only cycle
+	// never gets to mark, so it cannot finish.
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+	runtime.GC()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	orig := SetIdleGCThreshold(int64(ms.HeapAlloc) + 256<<20)
+	defer SetIdleGCThreshold(orig)
+	if orig != 0 {
+		t.Errorf("initial threshold is %d, want 0", orig)
+	}
+	if n := allocateGarbage(32 << 20); n != 0 {
+		t.Errorf("%d collections finished below the idle GC threshold on a busy P", n)
+	}
+
+	// Once the heap is above the threshold, the cycle finishes.
+	runtime.ReadMemStats(&ms)
+	SetIdleGCThreshold(int64(ms.HeapAlloc) + 8<<20)
+	if n := allocateGarbage(64 << 20); n == 0 {
+		t.Errorf("no collection finished above the idle GC threshold")
+	}
+}
+
 func TestSetGCPercent(t *testing.T) {
 	testenv.SkipFlaky(t, 20076)
 
@@ -191,3 +272,152 @@ func TestSetMaxThreadsOvf(t *testing.T) {
 	nt := SetMaxThreads(1 << (30 + ^uint(0)>>63))
 	SetMaxThreads(nt) // restore previous value
 }
+
+func TestSetIdleThreadTimeout(t *testing.T) {
+	if runtime.GOOS == "js" || runtime.GOOS == "plan9" {
+		t.Skipf("idle threads do not exit on %s", runtime.GOOS)
+	}
+	defer SetIdleThreadTimeout(SetIdleThreadTimeout(10 * time.Millisecond))
+	if prev := SetIdleThreadTimeout(10 * time.Millisecond); prev != 10*time.Millisecond {
+		t.Errorf("SetIdleThreadTimeout returned %v, want 10ms", prev)
+	}
+	samples := []metrics.Sample{
+		{Name: "/sched/threads/idle-exits:threads"},
+	}
+	metrics.Read(samples)
+	before := samples[0].Value.Uint64()
+
+	// Each goroutine blocked while locked to its thread holds on to
+	// the thread, so that others are started. They go idle once the
+	// goroutines unlock them.
+	const n = 10
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			runtime.LockOSThread()
+			time.Sleep(20 * time.Millisecond)
+			runtime.UnlockOSThread()
+		}()
+	}
+	wg.Wait()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		metrics.Read(samples)
+		if samples[0].Value.Uint64() > before {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("no idle thread exited")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+var (
+	memProfileDecayRetained [][]byte
+	memProfileDecayLast     []byte
+)
+
+//go:noinline
+func memProfileDecayRetain() {
+	for i := 0; i < 100; i++ {
+		memProfileDecayRetained = append(memProfileDecayRetained, make([]byte, 1024))
+	}
+}
+
+//go:noinline
+func memProfileDecayChurn() {
+	for i := 0; i < 100; i++ {
+		memProfileDecayLast = make([]byte, 1024)
+	}
+}
+
+// memProfileDecayRecord sums the memory profile records whose stacks
+// contain the function fn.
+func memProfileDecayRecord(t *testing.T, fn string) (r runtime.MemProfileRecord) {
+	var p []runtime.MemProfileRecord
+	n, ok := runtime.MemProfile(nil, true)
+	for !ok {
+		p = make([]runtime.MemProfileRecord, n+50)
+		n, ok = runtime.MemProfile(p, true)
+	}
+	for _, rec := range p[:n] {
+		for _, pc := range rec.Stack() {
+			if f := runtime.FuncForPC(pc); f != nil && f.Name() == "runtime/debug_test."+fn {
+				r.AllocBytes += rec.AllocBytes
+				r.LiveBytes += rec.LiveBytes
+				r.RecentAllocBytes += rec.RecentAllocBytes
+				break
+			}
+		}
+	}
+	if r.AllocBytes == 0 {
+		t.Fatalf("no memory profile records for %s", fn)
+	}
+	return r
+}
+
+func TestSetMemProfileDecay(t *testing.T) {
+	defer func(rate int) { runtime.MemProfileRate = rate }(runtime.MemProfileRate)
+	runtime.MemProfileRate = 1
+	defer SetMemProfileDecay(SetMemProfileDecay(0))
+
+	memProfileDecayRetain()
+	memProfileDecayChurn()
+	runtime.GC()
+	runtime.GC()
+
+	retain := memProfileDecayRecord(t, "memProfileDecayRetain")
+	churn := memProfileDecayRecord(t, "memProfileDecayChurn")
+	if retain.LiveBytes < 100*1024 {
+		t.Errorf("retaining call site has %d live bytes, want at least %d", retain.LiveBytes, 100*1024)
+	}
+	if churn.LiveBytes > churn.AllocBytes/2 {
+		t.Errorf("churning call site has %d live bytes of %d allocated, want most freed", churn.LiveBytes, churn.AllocBytes)
+	}
+	for _, r := range []runtime.MemProfileRecord{retain, churn} {
+		if r.RecentAllocBytes < 100*1024 || r.RecentAllocBytes > r.AllocBytes {
+			t.Errorf("recent allocations are %d bytes of %d without decay, want at least %d", r.RecentAllocBytes, r.AllocBytes, 100*1024)
+		}
+	}
+
+	// With a tiny half-life, old allocations no longer count as
+	// recent, but they stay live.
+	SetMemProfileDecay(time.Nanosecond)
+	runtime.GC()
+	decayed := memProfileDecayRecord(t, "memProfileDecayRetain")
+	if decayed.RecentAllocBytes != 0 {
+		t.Errorf("recent allocations are %d bytes after decay, want 0", decayed.RecentAllocBytes)
+	}
+	if decayed.AllocBytes != retain.AllocBytes || decayed.LiveBytes != retain.LiveBytes {
+		t.Errorf("decay changed the record from %+v to %+v", retain, decayed)
+	}
+}
+
+func TestSetCgoCheck(t *testing.T) {
+	orig := SetCgoCheck(2)
+	defer SetCgoCheck(orig)
+
+	// Pointer writes to Go memory pass the checks, with or
+	// without a GC running.
+	var s [][]byte
+	for i := 0; i < 1000; i++ {
+		s = append(s, make([]byte, 1024))
+		if i%100 == 0 {
+			runtime.GC()
+		}
+	}
+	if prev := SetCgoCheck(5); prev != 2 {
+		t.Errorf("SetCgoCheck(5) returned %d, want 2", prev)
+	}
+	if prev := SetCgoCheck(-1); prev != 2 {
+		t.Errorf("SetCgoCheck(-1) returned %d, want 2 after clamping", prev)
+	}
+	if prev := SetCgoCheck(orig); prev != 0 {
+		t.Errorf("SetCgoCheck returned %d, want 0", prev)
+	}
+	runtime.KeepAlive(s)
+}
@@ -0,0 +1,61 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import (
+	"runtime"
+	"sync/atomic"
+)
+
+// A GCDisable keeps garbage collection disabled until it is released.
+// It is returned by DisableGC.
+type GCDisable struct {
+	released uint32
+}
+
+// DisableGC disables the garbage collections triggered by heap growth
+// until the returned GCDisable is released, and returns once any
+// collection in progress has finished marking. Collections stay
+// disabled while any GCDisable is held, so independent parts of a
+// program can each disable them around a latency-sensitive section,
+// such as rendering a frame, without coordinating with each other or
+// with SetGCPercent, whose setting applies again once the last
+// GCDisable is released. Explicit collections, by runtime.GC or
+// FreeOSMemory, still run.
+//
+// The heap keeps growing while collections are disabled. To bound it,
+// SetGCDisableLimit sets a heap size at which collections run anyway.
+//
+// A GCDisable that becomes unreachable without being released is
+// released when a collection finds it unreachable, which may never
+// happen if no limit is set.
+func DisableGC() *GCDisable {
+	d := new(GCDisable)
+	setGCDisabled(true)
+	runtime.SetFinalizer(d, (*GCDisable).Release)
+	return d
+}
+
+// Release releases d. Releasing a GCDisable more than once has no
+// effect beyond the first time.
+func (d *GCDisable) Release() {
+	if !atomic.CompareAndSwapUint32(&d.released, 0, 1) {
+		return
+	}
+	runtime.SetFinalizer(d, nil)
+	setGCDisabled(false)
+}
+
+// SetGCDisableLimit sets the heap size, in bytes, at which garbage
+// collections are triggered even while a GCDisable is held, so that a
+// program whose collections are disabled for longer than planned does
+// not run out of memory. The heap size is measured as for triggering
+// collections normally, and is close to runtime.MemStats.HeapAlloc.
+// If the heap stays above the limit, collections run back to back.
+// A limit of zero or less, the initial setting, removes the limit.
+// SetGCDisableLimit returns the previous setting.
+func SetGCDisableLimit(bytes int64) (prev int64) {
+	return setGCDisableLimit(bytes)
+}
@@ -0,0 +1,57 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug_test
+
+import (
+	"runtime"
+	. "runtime/debug"
+	"testing"
+)
+
+var gcDisableSink []byte
+
+// allocateGarbage allocates n bytes of garbage and returns the number
+// of collections that ran meanwhile.
+func allocateGarbage(n int) uint32 {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	before := ms.NumGC
+	for i := 0; i < n>>16; i++ {
+		gcDisableSink = make([]byte, 64<<10)
+	}
+	gcDisableSink = nil
+	runtime.ReadMemStats(&ms)
+	return ms.NumGC - before
+}
+
+func TestDisableGC(t *testing.T) {
+	runtime.GC()
+	d1 := DisableGC()
+	d2 := DisableGC()
+	if n := allocateGarbage(64 << 20); n != 0 {
+		t.Errorf("%d collections with two GC disables held", n)
+	}
+	d1.Release()
+	d1.Release() // no effect
+	if n := allocateGarbage(64 << 20); n != 0 {
+		t.Errorf("%d collections with one GC disable held", n)
+	}
+	d2.Release()
+	if n := allocateGarbage(64 << 20); n == 0 {
+		t.Errorf("no collection after all GC disables were released")
+	}
+}
+
+func TestSetGCDisableLimit(t *testing.T) {
+	runtime.GC()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	defer SetGCDisableLimit(SetGCDisableLimit(int64(ms.HeapAlloc) + 16<<20))
+	d := DisableGC()
+	defer d.Release()
+	if n := allocateGarbage(64 << 20); n == 0 {
+		t.Errorf("no collection past the GC disable limit")
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import (
+	"errors"
+	"io"
+)
+
+var errNoGStatusTrace = errors.New("debug: runtime not built with -tags gstatustrace")
+
+// WriteGStatusTrace writes the most recent goroutine status
+// transitions to w, oldest first. Each line holds the time of the
+// transition in nanoseconds, the goroutine ID, the old and new status
+// named as in goroutine tracebacks, and the runtime function that
+// made the transition.
+//
+// Transitions are only recorded when the program is built with
+// -tags gstatustrace, which also prints them when the program dies of
+// a fatal error. Otherwise WriteGStatusTrace returns an error.
+func WriteGStatusTrace(w io.Writer) error {
+	b := gstatusTraceDump()
+	if b == nil {
+		return errNoGStatusTrace
+	}
+	_, err := w.Write(b)
+	return err
+}
@@ -0,0 +1,38 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug_test
+
+import (
+	"bytes"
+	"regexp"
+	"runtime"
+	. "runtime/debug"
+	"testing"
+)
+
+func TestWriteGStatusTrace(t *testing.T) {
+	done := make(chan bool)
+	go func() {
+		runtime.Gosched()
+		done <- true
+	}()
+	<-done
+
+	var buf bytes.Buffer
+	if err := WriteGStatusTrace(&buf); err != nil {
+		// Only when built with -tags gstatustrace.
+		t.Skip(err)
+	}
+	out := buf.String()
+	for _, re := range []string{
+		`(?m)^goroutine status transitions \(\d+ of \d+\):$`,
+		`(?m)^\d+ goroutine \d+: runnable -> running in runtime\.execute\+0x[0-9a-f]+$`,
+		`(?m)^\d+ goroutine \d+: running -> waiting in runtime\.park_m\+0x[0-9a-f]+$`,
+	} {
+		if !regexp.MustCompile(re).MatchString(out) {
+			t.Errorf("trace does not match %s:\n%.4000s", re, out)
+		}
+	}
+}
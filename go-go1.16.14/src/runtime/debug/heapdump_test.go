@@ -5,9 +5,11 @@
 package debug_test
 
 import (
+	"io"
 	"os"
 	"runtime"
 	. "runtime/debug"
+	"strings"
 	"testing"
 )
 
@@ -67,3 +69,45 @@ func TestWriteHeapDumpFinalizers(t *testing.T) {
 	WriteHeapDump(f.Fd())
 	println("done dump")
 }
+
+func TestWriteStateDump(t *testing.T) {
+	if runtime.GOOS == "js" {
+		t.Skipf("WriteStateDump is not available on %s.", runtime.GOOS)
+	}
+	f, err := os.CreateTemp("", "statedumptest")
+	if err != nil {
+		t.Fatalf("TempFile failed: %v", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	c := make(chan int)
+	go func() {
+		<-c
+	}()
+	runtime.Gosched()
+	WriteStateDump(f.Fd())
+	close(c)
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(b)
+	for _, want := range []string{
+		"runtime state dump\n",
+		"SCHED ",
+		"\n  P0 runq:",
+		"\nruntime.MemStats\nAlloc = ",
+		"\nNumGC = ",
+		"runtime/debug_test.TestWriteStateDump(",
+		"[chan receive]:\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("state dump does not contain %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import "time"
+
+// SetDeepIdle makes a program that has nothing to run wake up less
+// often, which saves power on battery-powered and embedded systems.
+// While every processor, one of the GOMAXPROCS, is idle, the runtime
+// delays its wakeups by less than slack, to the next multiple of slack
+// since an arbitrary point in time, so that timers due close together
+// run after one wakeup. It also stops waking up to check whether a
+// garbage collection is to be forced, see SetForceGCPeriod, before one
+// is due. Timers may therefore run up to slack late. A slack of zero or
+// less disables deep idle, which is the initial setting.
+//
+// An idle program still wakes up for its timers, including the ones
+// of the runtime that return memory to the operating system; for
+// forced garbage collections; to make threads that stay idle exit, see
+// SetIdleThreadTimeout and SetExtraMIdleTimeout; and for network and
+// file descriptor events. The runtime/metrics package reports how
+// often the thread that waits for timers and events wakes up at a
+// deadline while the program is idle, in
+// /sched/idle/poller-wakeups:wakeups, and how often the runtime
+// otherwise wakes up to check on the program, in
+// /sched/idle/sysmon-wakeups:wakeups.
+//
+// SetDeepIdle returns the previous setting.
+func SetDeepIdle(slack time.Duration) (prev time.Duration) {
+	return time.Duration(setDeepIdle(int64(slack)))
+}
+
+// A SleepForecast describes when the runtime expects to wake up next,
+// as returned by ReadSleepForecast.
+type SleepForecast struct {
+	// NextTimer is when the earliest pending timer is due, across
+	// all processors, including the timers of the runtime. It may be
+	// a timer that has just been stopped or reset to a later time.
+	// It is the zero Time if there are no timers.
+	NextTimer time.Time
+
+	// Wakeup is when the runtime next wakes up on its own, if no
+	// goroutine is made ready otherwise: for a timer, possibly later
+	// than NextTimer because of the slack of SetNetpollTuning or
+	// SetDeepIdle, or to check on the program. It is the zero Time if
+	// the runtime does not expect to wake up.
+	Wakeup time.Time
+}
+
+// ReadSleepForecast returns when the runtime expects to wake up next.
+// A program that sleeps on its own schedule, such as a game loop or a
+// coordinator of batches of work, can align its sleeps with these
+// wakeups, so that the program as a whole wakes up less often. The
+// forecast changes as goroutines start and stop timers.
+func ReadSleepForecast() SleepForecast {
+	wall := time.Now()
+	now, nextTimer, wakeup := readSleepForecast()
+	var f SleepForecast
+	if nextTimer != 0 {
+		f.NextTimer = wall.Add(time.Duration(nextTimer - now))
+	}
+	if wakeup != 0 {
+		f.Wakeup = wall.Add(time.Duration(wakeup - now))
+	}
+	return f
+}
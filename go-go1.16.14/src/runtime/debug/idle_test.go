@@ -0,0 +1,64 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug_test
+
+import (
+	"runtime"
+	. "runtime/debug"
+	"runtime/metrics"
+	"testing"
+	"time"
+)
+
+func TestSetDeepIdle(t *testing.T) {
+	if runtime.GOOS == "js" || runtime.GOOS == "plan9" {
+		t.Skip("no network poller waiting for timers on " + runtime.GOOS)
+	}
+	const slack = 100 * time.Millisecond
+	prev := SetDeepIdle(slack)
+	defer SetDeepIdle(prev)
+	if got := SetDeepIdle(slack); got != slack {
+		t.Fatalf("SetDeepIdle returned %v, want %v", got, slack)
+	}
+
+	samples := []metrics.Sample{{Name: "/sched/idle/poller-wakeups:wakeups"}}
+	metrics.Read(samples)
+	before := samples[0].Value.Uint64()
+
+	// Timers due a few milliseconds apart, within about one slack,
+	// run after a few wakeups rather than one each.
+	const n = 10
+	start := time.Now()
+	late := make(chan time.Duration, n)
+	for i := 1; i <= n; i++ {
+		due := time.Duration(i) * 5 * time.Millisecond
+		time.AfterFunc(due, func() { late <- time.Since(start) - due })
+	}
+	for i := 0; i < n; i++ {
+		if d := <-late; d < 0 {
+			t.Errorf("timer ran %v early", -d)
+		}
+	}
+
+	metrics.Read(samples)
+	if w := samples[0].Value.Uint64() - before; w >= n/2 {
+		t.Errorf("%d idle wakeups for %d timers due within %v", w, n, slack)
+	}
+}
+
+func TestReadSleepForecast(t *testing.T) {
+	const d = 50 * time.Millisecond
+	due := time.Now().Add(d)
+	tm := time.AfterFunc(d, func() {})
+	defer tm.Stop()
+
+	f := ReadSleepForecast()
+	if f.NextTimer.IsZero() || f.NextTimer.After(due.Add(10*time.Millisecond)) {
+		t.Errorf("next timer at %v, want by %v", f.NextTimer, due)
+	}
+	if f.Wakeup.IsZero() {
+		t.Errorf("no wakeup forecast with a timer pending")
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import "errors"
+
+var (
+	errPatchArgs        = errors.New("debug: PatchFunc needs two different non-nil functions of the same type")
+	errPatchUnsupported = errors.New("debug: live patching is not supported on this platform")
+	errPatchTarget      = errors.New("debug: function cannot be patched")
+	errPatchClosure     = errors.New("debug: replacement function is a closure or method value")
+	errPatchBusy        = errors.New("debug: timed out waiting for goroutines to leave the patched code")
+	errPatchWrite       = errors.New("debug: cannot write the program's code")
+	errNoPatch          = errors.New("debug: no such patch")
+)
+
+// PatchFunc redirects calls of the function target to replacement, a
+// function of the same type, until RevertPatch is called with the
+// returned ID. It is meant for hot-fix and instrumentation tools,
+// which otherwise have to rewrite code themselves, racing with the
+// goroutines that run it and with their asynchronous preemption.
+//
+// PatchFunc stops the world and overwrites the entry of target with a
+// jump to replacement, once no goroutine is stopped in the code the
+// jump replaces. Calls of target in progress complete as they began;
+// calls made after PatchFunc returns run replacement instead. Calls
+// that the compiler inlined into their callers are not redirected, so
+// target should be marked //go:noinline, or the program built with
+// -gcflags=-l. Functions of package runtime cannot be patched, and
+// neither can a function that is patched already.
+//
+// replacement must be a function declared at package level, or a
+// function literal that refers to no variables of the function it is
+// in: it cannot be a closure or a method value.
+//
+// Live patching is only supported on linux/amd64, and only where the
+// operating system lets the program write to its code.
+func PatchFunc(target, replacement interface{}) (id int, err error) {
+	switch id := patchFunc(target, replacement); id {
+	case -1:
+		return 0, errPatchArgs
+	case -2:
+		return 0, errPatchUnsupported
+	case -3:
+		return 0, errPatchTarget
+	case -4:
+		return 0, errPatchClosure
+	case -5:
+		return 0, errPatchBusy
+	case -6:
+		return 0, errPatchWrite
+	default:
+		return id, nil
+	}
+}
+
+// RevertPatch undoes the patch id made by PatchFunc, so that calls of
+// its target run the target again. Like PatchFunc, it stops the world.
+func RevertPatch(id int) error {
+	switch revertPatch(id) {
+	case -1:
+		return errNoPatch
+	case -5:
+		return errPatchBusy
+	case -6:
+		return errPatchWrite
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug_test
+
+import (
+	"runtime"
+	. "runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+//go:noinline
+func patchTarget(x int) int {
+	return x + 1
+}
+
+func patchReplacement(x int) int {
+	return x * 10
+}
+
+func TestPatchFunc(t *testing.T) {
+	if runtime.GOOS != "linux" || runtime.GOARCH != "amd64" {
+		if _, err := PatchFunc(patchTarget, patchReplacement); err == nil {
+			t.Fatal("PatchFunc succeeded on an unsupported platform")
+		}
+		t.Skip("live patching is only supported on linux/amd64")
+	}
+
+	y := 3
+	for _, c := range []struct {
+		name        string
+		target, rep interface{}
+	}{
+		{"different types", patchTarget, strings.ToUpper},
+		{"nil target", (func(int) int)(nil), patchReplacement},
+		{"same function", patchTarget, patchTarget},
+		{"runtime function", runtime.GC, func() {}},
+		{"closure", patchTarget, func(x int) int { return x + y }},
+	} {
+		if _, err := PatchFunc(c.target, c.rep); err == nil {
+			t.Errorf("PatchFunc of %s succeeded", c.name)
+		}
+	}
+
+	id, err := PatchFunc(patchTarget, patchReplacement)
+	if err != nil {
+		t.Fatalf("PatchFunc: %v", err)
+	}
+	if got := patchTarget(2); got != 20 {
+		t.Errorf("patched patchTarget(2) = %d, want 20", got)
+	}
+	f := patchTarget
+	if got := f(3); got != 30 {
+		t.Errorf("patched patchTarget(3) through a func value = %d, want 30", got)
+	}
+	if _, err := PatchFunc(patchTarget, func(x int) int { return x }); err == nil {
+		t.Error("PatchFunc of a patched function succeeded")
+	}
+	if err := RevertPatch(id); err != nil {
+		t.Fatalf("RevertPatch: %v", err)
+	}
+	if got := patchTarget(2); got != 3 {
+		t.Errorf("reverted patchTarget(2) = %d, want 3", got)
+	}
+	if err := RevertPatch(id); err == nil {
+		t.Error("second RevertPatch succeeded")
+	}
+}
+
+func TestPatchFuncConcurrent(t *testing.T) {
+	if runtime.GOOS != "linux" || runtime.GOARCH != "amd64" {
+		t.Skip("live patching is only supported on linux/amd64")
+	}
+	var stop uint32
+	var bad int64
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for atomic.LoadUint32(&stop) == 0 {
+				if got := patchTarget(2); got != 3 && got != 20 {
+					atomic.AddInt64(&bad, 1)
+				}
+			}
+		}()
+	}
+	n := 50
+	if testing.Short() {
+		n = 10
+	}
+	for i := 0; i < n; i++ {
+		id, err := PatchFunc(patchTarget, patchReplacement)
+		if err != nil {
+			t.Error(err)
+			break
+		}
+		if err := RevertPatch(id); err != nil {
+			t.Error(err)
+			break
+		}
+	}
+	atomic.StoreUint32(&stop, 1)
+	wg.Wait()
+	if bad != 0 {
+		t.Errorf("%d calls of patchTarget returned neither the result of patchTarget nor of its replacement", bad)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import "time"
+
+// NetpollTuning holds settings of the network poller, with which the
+// runtime waits for network connections and other file descriptors to
+// be ready and wakes the goroutines blocked on them. The zero value
+// holds the initial settings.
+//
+// The runtime/metrics package reports how often the poller is woken
+// without anything to do, in /sched/netpoll/spurious-wakeups:wakeups,
+// how often BreakSlack avoids waking it, in
+// /sched/netpoll/skipped-wakeups:wakeups, how often it wakes for a
+// timer, in /sched/netpoll/timer-wakeups:wakeups, and for a timer that
+// is no longer due, in /sched/netpoll/wasted-wakeups:wakeups, and how
+// many threads are woken for the goroutines readied by a poll, in
+// /sched/inject/wakeups:wakeups.
+type NetpollTuning struct {
+	// MaxEvents is the maximum number of ready file descriptors
+	// fetched from the operating system by one poll. Zero means as
+	// many as the poller's buffer holds, 128 on Linux and 64 on most
+	// other systems. It has no effect on AIX, Plan 9 and js/wasm.
+	MaxEvents int
+
+	// MaxWakeups is the maximum number of idle processors started to
+	// run a batch of goroutines that become ready at once, such as the
+	// goroutines woken by one poll. The other goroutines of the batch
+	// are left to processors that are already running, and spread by
+	// work stealing. Zero means no limit: an idle processor is started
+	// for each goroutine of the batch while there are any.
+	MaxWakeups int
+
+	// BreakSlack allows timers to run late to avoid waking a thread
+	// blocked in the poller. When a timer is due before the poller
+	// would wake up, the poller is woken early to run it, unless the
+	// timer is due less than BreakSlack before then. Zero means that
+	// the poller is always woken.
+	BreakSlack time.Duration
+}
+
+// SetNetpollTuning sets the settings of the network poller and returns
+// the previous settings. Negative values are treated as zero.
+func SetNetpollTuning(t NetpollTuning) (prev NetpollTuning) {
+	maxEvents, maxWakeups, breakSlack := setNetpollTuning(t.MaxEvents, t.MaxWakeups, int64(t.BreakSlack))
+	return NetpollTuning{
+		MaxEvents:  maxEvents,
+		MaxWakeups: maxWakeups,
+		BreakSlack: time.Duration(breakSlack),
+	}
+}
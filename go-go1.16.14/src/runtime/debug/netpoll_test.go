@@ -0,0 +1,121 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug_test
+
+import (
+	"net"
+	"runtime"
+	. "runtime/debug"
+	"runtime/metrics"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSetNetpollTuning(t *testing.T) {
+	want := NetpollTuning{MaxEvents: 1, MaxWakeups: 1, BreakSlack: time.Second}
+	orig := SetNetpollTuning(want)
+	defer SetNetpollTuning(orig)
+	if got := SetNetpollTuning(want); got != want {
+		t.Fatalf("SetNetpollTuning returned %+v, want %+v", got, want)
+	}
+
+	// Ready connections are still all handled one event at a time,
+	// with a single processor woken per poll.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen: %v", err)
+	}
+	defer ln.Close()
+	const conns = 10
+	go func() {
+		for i := 0; i < conns; i++ {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			c.Write([]byte("x"))
+			c.Close()
+		}
+	}()
+	var wg sync.WaitGroup
+	for i := 0; i < conns; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, err := net.Dial("tcp", ln.Addr().String())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer c.Close()
+			c.SetDeadline(time.Now().Add(10 * time.Second))
+			var b [1]byte
+			if _, err := c.Read(b[:]); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	samples := []metrics.Sample{
+		{Name: "/sched/netpoll/skipped-wakeups:wakeups"},
+		{Name: "/sched/netpoll/spurious-wakeups:wakeups"},
+	}
+	metrics.Read(samples)
+	for _, s := range samples {
+		if s.Value.Kind() != metrics.KindUint64 {
+			t.Errorf("metric %s has kind %v", s.Name, s.Value.Kind())
+		}
+	}
+
+	if got := SetNetpollTuning(NetpollTuning{MaxEvents: -1}); got != want {
+		t.Errorf("SetNetpollTuning returned %+v, want %+v", got, want)
+	}
+	if got := SetNetpollTuning(want); got != (NetpollTuning{}) {
+		t.Errorf("negative setting gave %+v, want zero", got)
+	}
+}
+
+func TestNetpollTimerWakeups(t *testing.T) {
+	if runtime.GOOS == "js" || runtime.GOOS == "plan9" {
+		t.Skip("no network poller waiting for timers on " + runtime.GOOS)
+	}
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+	// Leave the background scavenger, which adds timers, nothing to do.
+	FreeOSMemory()
+	samples := []metrics.Sample{
+		{Name: "/sched/netpoll/timer-wakeups:wakeups"},
+		{Name: "/sched/netpoll/wasted-wakeups:wakeups"},
+	}
+	metrics.Read(samples)
+	timerWakeups, wastedWakeups := samples[0].Value.Uint64(), samples[1].Value.Uint64()
+
+	// Timers far in the future keep the timer stopped below in the
+	// timer heap, where the poller still waits for it, unless a new
+	// timer, such as one of the runtime's, cleans the heap first. So
+	// try a few times.
+	for i := 0; i < 64; i++ {
+		defer time.AfterFunc(time.Hour, func() {}).Stop()
+	}
+	for try := 0; try < 5; try++ {
+		done := make(chan bool)
+		stopped := time.AfterFunc(30*time.Millisecond, func() { t.Error("stopped timer ran") })
+		time.AfterFunc(10*time.Millisecond, func() { stopped.Stop() })
+		time.AfterFunc(60*time.Millisecond, func() { close(done) })
+		<-done
+
+		metrics.Read(samples)
+		if samples[1].Value.Uint64() != wastedWakeups {
+			break
+		}
+	}
+	if samples[0].Value.Uint64() == timerWakeups {
+		t.Errorf("no timer wakeups of the poller")
+	}
+	if samples[1].Value.Uint64() == wastedWakeups {
+		t.Errorf("no wasted wakeup of the poller for the stopped timer")
+	}
+}
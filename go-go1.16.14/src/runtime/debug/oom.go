@@ -0,0 +1,30 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+// SetOutOfMemoryHandler registers f to be called when the garbage
+// collected heap cannot grow because the operating system refuses to
+// provide more memory, which otherwise makes the program crash with a
+// fatal "out of memory" error.
+//
+// SetOutOfMemoryHandler sets aside an emergency reserve of reserve
+// bytes of memory at once, rounded up to a page. When the heap cannot
+// grow, the runtime returns the reserve to the heap instead of
+// crashing and lets the program continue, and f is called soon after
+// in a new goroutine. f can thus allocate from what is left of the
+// reserve, and should quickly release memory, for instance by
+// dropping caches, or report the condition. The reserve should be
+// large enough for the program to continue until f has done so.
+//
+// f is called at most once per call to SetOutOfMemoryHandler. Once the
+// reserve is used, the next failure to grow the heap is fatal, unless
+// SetOutOfMemoryHandler is called again to register a handler and set
+// aside a new reserve. A call replaces the previous handler and frees
+// its reserve, and a nil f only removes the handler. Failures to
+// allocate memory outside the heap, such as for goroutine stacks and
+// runtime metadata, remain fatal.
+func SetOutOfMemoryHandler(f func(), reserve int) {
+	setOutOfMemoryHandler(f, int64(reserve))
+}
@@ -0,0 +1,23 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+// PrewarmGoroutines prepares n goroutines, each with a stack of at
+// least stackSize bytes, for reuse by go statements. The runtime keeps
+// the goroutines that have exited for reuse, and only allocates a new
+// goroutine and its stack when none is left. A program that starts
+// many goroutines at once, as in a burst of fan-out, can call
+// PrewarmGoroutines beforehand to take those allocations off the
+// critical path.
+//
+// Like the stacks of goroutines that have exited, the prepared stacks
+// are freed by the next garbage collection, after which only the
+// goroutines themselves are reused, until the collection after that
+// drops those still unused. PrewarmGoroutines should thus be
+// called shortly before the goroutines are needed. A stackSize below
+// the initial stack size of a goroutine selects that size.
+func PrewarmGoroutines(n, stackSize int) {
+	prewarmGoroutines(n, stackSize)
+}
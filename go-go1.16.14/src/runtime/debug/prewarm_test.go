@@ -0,0 +1,46 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug_test
+
+import (
+	"runtime"
+	. "runtime/debug"
+	"sync"
+	"testing"
+)
+
+func prewarmBlocker(wg *sync.WaitGroup, c chan bool) {
+	<-c
+	wg.Done()
+}
+
+func TestPrewarmGoroutines(t *testing.T) {
+	const n = 1000
+	// A garbage collection would free the prepared goroutines.
+	defer SetGCPercent(SetGCPercent(-1))
+	before := runtime.NumGoroutine()
+	PrewarmGoroutines(n, 0)
+	if got := runtime.NumGoroutine(); got != before {
+		t.Errorf("NumGoroutine is %d after PrewarmGoroutines, was %d", got, before)
+	}
+
+	// Starting n goroutines now reuses the prepared ones, so it
+	// allocates nothing on the heap.
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	mallocs := ms.Mallocs
+	var wg sync.WaitGroup
+	wg.Add(n)
+	c := make(chan bool)
+	for i := 0; i < n; i++ {
+		go prewarmBlocker(&wg, c)
+	}
+	runtime.ReadMemStats(&ms)
+	close(c)
+	wg.Wait()
+	if d := ms.Mallocs - mallocs; d >= n/10 {
+		t.Errorf("starting %d goroutines made %d heap allocations", n, d)
+	}
+}
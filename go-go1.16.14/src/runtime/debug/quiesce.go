@@ -0,0 +1,39 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import "time"
+
+// Quiesce stops the scheduling of user goroutines other than the calling
+// one and waits up to timeout for each of them to stop making progress,
+// so that a program can bring its user code to a consistent point, for
+// example before a final flush during a graceful shutdown.
+//
+// Goroutines that are blocked, for instance on a channel or a network
+// connection, stay blocked. Goroutines that become runnable while
+// quiesced, because they are woken, preempted, or yield, are held and
+// do not run again until Resume is called. A goroutine has quiesced
+// once it is blocked, held, or has exited; goroutines that are running
+// without reaching a preemption point or that are in a system call or
+// C code have not.
+//
+// If every other user goroutine quiesces within the timeout, Quiesce
+// returns nil. Otherwise it returns the stack traces of the goroutines
+// that have not, formatted as by runtime.Stack.
+//
+// Either way, scheduling stays disabled until Resume is called, so the
+// calling goroutine must not wait on other user goroutines until then.
+// If another goroutine calls Quiesce in the meantime, it waits for that
+// Resume before it quiesces the program in turn.
+func Quiesce(timeout time.Duration) []byte {
+	return quiesce(int64(timeout))
+}
+
+// Resume re-enables the scheduling of user goroutines disabled by
+// Quiesce and lets held goroutines run. It does nothing if Quiesce has
+// not been called.
+func Resume() {
+	resume()
+}
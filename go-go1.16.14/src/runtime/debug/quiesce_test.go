@@ -0,0 +1,96 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug_test
+
+import (
+	"runtime"
+	. "runtime/debug"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestQuiesce(t *testing.T) {
+	var spins uint64
+	stop := make(chan bool)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				atomic.AddUint64(&spins, 1)
+			}
+		}
+	}()
+	wake := make(chan bool)
+	woken := make(chan bool)
+	go func() {
+		<-wake
+		woken <- true
+	}()
+
+	if stragglers := Quiesce(5 * time.Second); stragglers != nil {
+		Resume()
+		t.Fatalf("Quiesce timed out; stragglers:\n%s", stragglers)
+	}
+	n := atomic.LoadUint64(&spins)
+	// Waking a goroutine while quiesced must not let it run.
+	close(wake)
+	time.Sleep(50 * time.Millisecond)
+	if m := atomic.LoadUint64(&spins); m != n {
+		Resume()
+		t.Fatalf("spinning goroutine ran while quiesced: %d spins, was %d", m, n)
+	}
+	select {
+	case <-woken:
+		Resume()
+		t.Fatal("woken goroutine ran while quiesced")
+	default:
+	}
+	Resume()
+
+	<-woken
+	for atomic.LoadUint64(&spins) == n {
+		time.Sleep(time.Millisecond)
+	}
+	close(stop)
+}
+
+func TestQuiesceConcurrent(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(2))
+	// Call Quiesce from a running goroutine while another Quiesce is
+	// in progress. The quiescers take turns.
+	var quiescing, second uint32
+	done := make(chan []byte)
+	go func() {
+		for atomic.LoadUint32(&quiescing) == 0 {
+		}
+		// Keep running for a bit, so that the first Quiesce is
+		// waiting for us, but not long enough to be preempted.
+		for start := time.Now(); time.Since(start) < 2*time.Millisecond; {
+		}
+		stragglers := Quiesce(5 * time.Second)
+		atomic.StoreUint32(&second, 1)
+		Resume()
+		done <- stragglers
+	}()
+	atomic.StoreUint32(&quiescing, 1)
+	if stragglers := Quiesce(5 * time.Second); stragglers != nil {
+		Resume()
+		t.Fatalf("Quiesce timed out; stragglers:\n%s", stragglers)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if atomic.LoadUint32(&second) != 0 {
+		Resume()
+		t.Fatal("second Quiesce returned before the first Resume")
+	}
+	Resume()
+	if stragglers := <-done; stragglers != nil {
+		t.Fatalf("second Quiesce timed out; stragglers:\n%s", stragglers)
+	}
+	// Resume without Quiesce does nothing.
+	Resume()
+}
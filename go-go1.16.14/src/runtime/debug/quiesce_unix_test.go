@@ -0,0 +1,45 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package debug_test
+
+import (
+	. "runtime/debug"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestQuiesceStragglers(t *testing.T) {
+	var p [2]int
+	if err := syscall.Pipe(p[:]); err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Close(p[0])
+	defer syscall.Close(p[1])
+
+	done := make(chan bool)
+	go func() {
+		// A blocking read is a system call, not a blocked goroutine.
+		var b [1]byte
+		syscall.Read(p[0], b[:])
+		done <- true
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	stragglers := Quiesce(50 * time.Millisecond)
+	Resume()
+	if stragglers == nil {
+		t.Fatal("Quiesce reported no stragglers with a goroutine in a system call")
+	}
+	if !strings.Contains(string(stragglers), "syscall.Read") {
+		t.Errorf("stragglers do not include the goroutine in syscall.Read:\n%s", stragglers)
+	}
+
+	syscall.Write(p[1], []byte{0})
+	<-done
+}
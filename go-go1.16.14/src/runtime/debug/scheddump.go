@@ -0,0 +1,21 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import "io"
+
+// DumpSchedulerState writes a description of the scheduler's state to
+// w: the global scheduler counters, and for each P (processor) and M
+// (OS thread) its status and main fields. Each field is followed by a
+// short explanation of its meaning, so the output can be read without
+// the runtime source at hand.
+//
+// Unlike WriteStateDump, DumpSchedulerState does not stop the world,
+// so the fields of different Ps and Ms may describe slightly different
+// moments.
+func DumpSchedulerState(w io.Writer) error {
+	_, err := w.Write(schedStateDump())
+	return err
+}
@@ -0,0 +1,40 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug_test
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	. "runtime/debug"
+	"strings"
+	"testing"
+)
+
+func TestDumpSchedulerState(t *testing.T) {
+	var buf bytes.Buffer
+	if err := DumpSchedulerState(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	want := []string{
+		"sched: global scheduler state",
+		fmt.Sprintf("  gomaxprocs=%d\t// ", runtime.GOMAXPROCS(0)),
+		"\nM0:\n",
+		"  curg=",
+	}
+	for i := 0; i < runtime.GOMAXPROCS(0); i++ {
+		want = append(want, fmt.Sprintf("\nP%d: ", i))
+	}
+	for _, s := range want {
+		if !strings.Contains(out, s) {
+			t.Errorf("dump does not contain %q:\n%s", s, out)
+		}
+	}
+	// The calling goroutine's P is running.
+	if !strings.Contains(out, ": running\t// ") {
+		t.Errorf("dump has no running P:\n%s", out)
+	}
+}
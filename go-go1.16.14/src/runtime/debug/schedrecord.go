@@ -0,0 +1,86 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import "errors"
+
+// A SchedEvent is a scheduling event recorded with GODEBUG=schedrecord.
+type SchedEvent struct {
+	// P is the ID of the processor on which the event happened.
+	P int
+
+	// Goroutine is the ID of the goroutine concerned.
+	Goroutine int64
+
+	// Preempted reports whether the goroutine was preempted, at PC.
+	// Otherwise the goroutine started running.
+	Preempted bool
+	PC        uintptr
+}
+
+// Kinds of events in the log, as in package runtime.
+const (
+	schedEventRun     = 1
+	schedEventPreempt = 2
+)
+
+const schedRecordMagic = "gosched1"
+
+var errBadSchedRecord = errors.New("debug: malformed scheduling record")
+
+// ReadSchedRecord returns the scheduling events recorded by the
+// runtime as a compact log, which ParseSchedRecord decodes. Setting
+// GODEBUG=schedrecord=N makes the runtime record the last N events: a
+// goroutine starting to run on a processor, or being preempted.
+// ReadSchedRecord returns nil without that setting.
+//
+// A log saved to a file by a run under GODEBUG=detsched, holding
+// every event of that run, can be replayed with GODEBUG=schedreplay
+// (see package runtime).
+func ReadSchedRecord() []byte {
+	return schedRecordLog()
+}
+
+// ParseSchedRecord decodes a log returned by ReadSchedRecord into its
+// events, oldest first. dropped is the number of earlier events that
+// the runtime no longer kept.
+func ParseSchedRecord(log []byte) (events []SchedEvent, dropped uint64, err error) {
+	if len(log) < len(schedRecordMagic) || string(log[:len(schedRecordMagic)]) != schedRecordMagic {
+		return nil, 0, errBadSchedRecord
+	}
+	b := log[len(schedRecordMagic):]
+	next := func() uint64 {
+		var v uint64
+		for shift := uint(0); len(b) > 0 && shift < 64; shift += 7 {
+			c := b[0]
+			b = b[1:]
+			v |= uint64(c&0x7f) << shift
+			if c < 0x80 {
+				return v
+			}
+		}
+		err = errBadSchedRecord
+		return 0
+	}
+	dropped = next()
+	for err == nil && len(b) > 0 {
+		kind := b[0]
+		b = b[1:]
+		ev := SchedEvent{P: int(next()), Goroutine: int64(next())}
+		switch kind {
+		case schedEventRun:
+		case schedEventPreempt:
+			ev.Preempted = true
+			ev.PC = uintptr(next())
+		default:
+			err = errBadSchedRecord
+		}
+		events = append(events, ev)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	return events, dropped, nil
+}
@@ -0,0 +1,39 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug_test
+
+import (
+	"reflect"
+	. "runtime/debug"
+	"testing"
+)
+
+func TestParseSchedRecord(t *testing.T) {
+	log := []byte("gosched1\x03" + "\x01\x00\x05" + "\x02\x01\x07\x80\x01")
+	events, dropped, err := ParseSchedRecord(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SchedEvent{
+		{P: 0, Goroutine: 5},
+		{P: 1, Goroutine: 7, Preempted: true, PC: 128},
+	}
+	if dropped != 3 || !reflect.DeepEqual(events, want) {
+		t.Errorf("got %+v, %d dropped, want %+v, 3 dropped", events, dropped, want)
+	}
+
+	for _, bad := range []string{
+		"",
+		"gosched2\x00",
+		"gosched1",
+		"gosched1\x00\x03\x00\x05",
+		"gosched1\x00\x02\x01\x07",
+		"gosched1\x00\x01\x00\x85",
+	} {
+		if _, _, err := ParseSchedRecord([]byte(bad)); err == nil {
+			t.Errorf("ParseSchedRecord(%q) succeeded", bad)
+		}
+	}
+}
@@ -28,3 +28,21 @@ func Stack() []byte {
 		buf = make([]byte, 2*len(buf))
 	}
 }
+
+// SetStackMove sets whether the calling goroutine moves its stack to
+// new memory at function calls, and returns the previous setting.
+// Goroutines started by a goroutine inherit its setting, so
+//
+//	defer debug.SetStackMove(debug.SetStackMove(true))
+//
+// at the start of a test covers the code the test runs.
+//
+// When enabled, the stack moves at the first call after the setting
+// changes or the goroutine is rescheduled, and at every call that goes
+// deeper than the last move. The old stack is overwritten, so code
+// keeping pointers into the stack in uintptrs or other places hidden
+// from the runtime fails quickly. This makes every such call much
+// slower; it is meant for tests only.
+func SetStackMove(enabled bool) bool {
+	return setStackMove(enabled)
+}
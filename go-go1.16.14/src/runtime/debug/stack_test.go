@@ -8,6 +8,7 @@ import (
 	. "runtime/debug"
 	"strings"
 	"testing"
+	"unsafe"
 )
 
 type T int
@@ -63,3 +64,86 @@ func check(t *testing.T, line, has string) {
 		t.Errorf("expected %q in %q", has, line)
 	}
 }
+
+//go:noinline
+func deepStack(n int) []byte {
+	if n == 0 {
+		return Stack()
+	}
+	return deepStack(n - 1)
+}
+
+func TestSetTracebackFrames(t *testing.T) {
+	prevHead, prevTail := SetTracebackFrames(10, 5)
+	defer SetTracebackFrames(prevHead, prevTail)
+
+	stk := string(deepStack(200))
+	if n := strings.Count(stk, "debug_test.deepStack("); n < 5 || n > 15 {
+		t.Errorf("got %d deepStack frames, want between 5 and 15:\n%s", n, stk)
+	}
+	if !strings.Contains(stk, " frames elided...\n") {
+		t.Errorf("missing elided frames marker:\n%s", stk)
+	}
+	if !strings.Contains(stk, "debug_test.TestSetTracebackFrames(") {
+		t.Errorf("outermost frames not printed:\n%s", stk)
+	}
+
+	SetTracebackFrames(10, 0)
+	stk = string(deepStack(200))
+	if !strings.Contains(stk, "...additional frames elided...\n") {
+		t.Errorf("missing elided frames marker:\n%s", stk)
+	}
+	if strings.Contains(stk, "debug_test.TestSetTracebackFrames(") {
+		t.Errorf("outermost frames printed with no tail:\n%s", stk)
+	}
+
+	SetTracebackFrames(1000, 1000)
+	stk = string(deepStack(200))
+	if n := strings.Count(stk, "debug_test.deepStack("); n != 201 {
+		t.Errorf("got %d deepStack frames, want 201", n)
+	}
+	if strings.Contains(stk, "elided") {
+		t.Errorf("frames elided from a shallow stack:\n%s", stk)
+	}
+}
+
+//go:noinline
+func stackMoveCall(n int) int {
+	if n == 0 {
+		return 0
+	}
+	return stackMoveCall(n-1) + 1
+}
+
+// stackMoves reports whether a local of the calling goroutine moves
+// across a call.
+func stackMoves() bool {
+	var x int
+	before := uintptr(unsafe.Pointer(&x))
+	x = stackMoveCall(10)
+	return uintptr(unsafe.Pointer(&x)) != before
+}
+
+func TestSetStackMove(t *testing.T) {
+	results := make(chan [3]bool)
+	go func() {
+		off := stackMoves()
+		prev := SetStackMove(true)
+		on := stackMoves()
+		inherited := make(chan bool)
+		go func() { inherited <- stackMoves() }()
+		child := <-inherited
+		SetStackMove(prev)
+		results <- [3]bool{off, on, child}
+	}()
+	r := <-results
+	if r[0] {
+		t.Errorf("stack moved without SetStackMove")
+	}
+	if !r[1] {
+		t.Errorf("stack did not move with SetStackMove(true)")
+	}
+	if !r[2] {
+		t.Errorf("stack of new goroutine did not move")
+	}
+}
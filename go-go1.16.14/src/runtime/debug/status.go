@@ -0,0 +1,34 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+// The functions below name the internal states of the scheduler, for
+// tools that read them from core files, heap dumps or the runtime's
+// own data structures and want to print the names the runtime uses.
+// The numeric values are those of the _G*, _P* and waitReason*
+// constants in the runtime source and may change between releases.
+// Threads (Ms) have no status of their own.
+
+// GStatusString returns the name of goroutine status s as printed in
+// goroutine tracebacks, such as "runnable" for _Grunnable. If the
+// _Gscan bit is set, " (scan)" is appended to the name. Unknown
+// statuses are named "???".
+func GStatusString(s uint32) string {
+	return gStatusString(s)
+}
+
+// PStatusString returns the name of processor status s, such as
+// "gcstop" for _Pgcstop. Unknown statuses are named "???".
+func PStatusString(s uint32) string {
+	return pStatusString(s)
+}
+
+// WaitReasonString returns the reason a goroutine blocked in status
+// _Gwaiting, as printed in goroutine tracebacks in place of
+// "waiting", such as "chan receive". It returns "" for
+// waitReasonZero and "unknown wait reason" for unknown values.
+func WaitReasonString(r uint8) string {
+	return waitReasonString(r)
+}
@@ -0,0 +1,33 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug_test
+
+import (
+	. "runtime/debug"
+	"testing"
+)
+
+func TestStatusStrings(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		f    func() string
+		want string
+	}{
+		{"GStatusString(1)", func() string { return GStatusString(1) }, "runnable"},
+		{"GStatusString(4)", func() string { return GStatusString(4) }, "waiting"},
+		{"GStatusString(0x1002)", func() string { return GStatusString(0x1002) }, "running (scan)"},
+		{"GStatusString(7)", func() string { return GStatusString(7) }, "???"},
+		{"GStatusString(100)", func() string { return GStatusString(100) }, "???"},
+		{"PStatusString(3)", func() string { return PStatusString(3) }, "gcstop"},
+		{"PStatusString(100)", func() string { return PStatusString(100) }, "???"},
+		{"WaitReasonString(0)", func() string { return WaitReasonString(0) }, ""},
+		{"WaitReasonString(2)", func() string { return WaitReasonString(2) }, "IO wait"},
+		{"WaitReasonString(255)", func() string { return WaitReasonString(255) }, "unknown wait reason"},
+	} {
+		if got := tt.f(); got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
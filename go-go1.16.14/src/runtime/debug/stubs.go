@@ -15,3 +15,43 @@ func setMaxStack(int) int
 func setGCPercent(int32) int32
 func setPanicOnFault(bool) bool
 func setMaxThreads(int) int
+func setIdleThreadTimeout(int64) int64
+func setTracebackFrames(int, int) (int, int)
+func setCrashDumpFD(int) int
+func setCgoSignalStackSize(int) int
+func setCgoCheck(int) int
+func setMaxExtraMs(int) int
+func setNetpollTuning(int, int, int64) (int, int, int64)
+func setSyscallTuning(int, bool, int64) (int, bool, int64)
+func setExtraMIdleTimeout(int64) int64
+func setDeepIdle(int64) int64
+func readSleepForecast() (int64, int64, int64)
+func quiesce(timeout int64) []byte
+func resume()
+func setMemProfileDecay(int64) int64
+func setWatchpoint(uintptr, uintptr, bool) int
+func clearWatchpoint(int)
+func readWatchpointHits([]WatchpointHit) int
+func gStatusString(uint32) string
+func pStatusString(uint32) string
+func waitReasonString(uint8) string
+func schedStateDump() []byte
+func gstatusTraceDump() []byte
+func readAndResetAllocStats() (uint64, uint64, uint64)
+func setForceGCPeriod(int64) int64
+func prewarmGoroutines(int, int)
+func schedRecordLog() []byte
+func setStackMove(bool) bool
+func readSTWDelay() (string, int64, int64, []uintptr)
+func setGoroutineDeadline(int64, func(int64, []byte))
+func setGCDisabled(bool)
+func setGCDisableLimit(int64) int64
+func setIdleGCThreshold(int64) int64
+func setOutOfMemoryHandler(func(), int64)
+func setThreadWarning(int, func(int, int, []byte))
+func setThreadLimitHandler(func(int, int, []byte))
+func readDeferPoolClasses([]int, []int, []uint64, []uint64)
+func setDeferPoolDepth(int, int) int
+func patchFunc(interface{}, interface{}) int
+func revertPatch(int) int
+func setCPUQuota(int64, int64) (int64, int64)
@@ -0,0 +1,60 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import (
+	"runtime"
+	"strings"
+	"time"
+)
+
+// STWDelay describes a stop of the world that had to wait for a
+// running goroutine.
+type STWDelay struct {
+	Reason    string        // why the world was stopped, such as "gcing"; "" at the start of a GC cycle
+	Wait      time.Duration // time spent waiting for goroutines to stop
+	Goroutine int64         // ID of the last goroutine seen running
+	Stack     []uintptr     // stack of that goroutine where it stopped, as from runtime.Callers
+	Function  string        // function that kept running, as far as the stack shows
+}
+
+// ReadSTWDelay returns the last stop of the world, as done by the
+// garbage collector, runtime.ReadMemStats and others, that had to ask
+// running goroutines more than once to stop, and false if no stop had
+// to. A goroutine running a loop without function calls in assembly,
+// or in any code under GODEBUG=asyncpreemptoff=1, cannot stop until
+// the loop ends; see runtime.Safepoint.
+//
+// The stack is taken where the goroutine finally stopped, which is the
+// first point after the loop where it could. Function is the innermost
+// function on it outside the runtime, other than a function that was
+// just being called, and so names the function running the loop in
+// most cases.
+func ReadSTWDelay() (STWDelay, bool) {
+	reason, wait, goid, stk := readSTWDelay()
+	if goid == 0 {
+		return STWDelay{}, false
+	}
+	d := STWDelay{
+		Reason:    reason,
+		Wait:      time.Duration(wait),
+		Goroutine: goid,
+		Stack:     stk,
+	}
+	frames := runtime.CallersFrames(stk)
+	for {
+		f, more := frames.Next()
+		// A frame stopped at its entry was stopped by the stack
+		// check of a call made after the delay.
+		if f.Function != "" && !strings.HasPrefix(f.Function, "runtime.") && f.PC != f.Entry {
+			d.Function = f.Function
+			break
+		}
+		if !more {
+			break
+		}
+	}
+	return d, true
+}
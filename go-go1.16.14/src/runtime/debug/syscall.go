@@ -0,0 +1,47 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import "time"
+
+// SyscallTuning holds settings for goroutines returning from system
+// calls. While a goroutine is in a system call, the runtime may give
+// its processor, one of the GOMAXPROCS, to other work. If it has, the
+// goroutine needs an idle processor on return; if there is none, its
+// thread parks until the scheduler runs the goroutine again, which adds
+// latency. The zero value holds the initial settings.
+//
+// The runtime/metrics package reports how returns from system calls
+// fare in /sched/syscalls/idle-p:calls, /sched/syscalls/parked:calls
+// and /sched/syscalls/retaken-p:calls.
+type SyscallTuning struct {
+	// ReservePs is the number of idle processors that are not used
+	// to start threads looking for work, so that they are left to
+	// goroutines returning from system calls. Processors are used
+	// regardless when all of them are idle.
+	ReservePs int
+
+	// PreferOldP makes a goroutine returning from a system call take
+	// its own processor back if it is idle, rather than any idle
+	// processor, so that it keeps the goroutines queued on it.
+	PreferOldP bool
+
+	// SpinTime is how long a goroutine returning from a system call
+	// keeps trying to get a processor, using CPU time, before its
+	// thread parks.
+	SpinTime time.Duration
+}
+
+// SetSyscallTuning sets the settings for goroutines returning from
+// system calls and returns the previous settings. Negative values are
+// treated as zero.
+func SetSyscallTuning(t SyscallTuning) (prev SyscallTuning) {
+	reservePs, preferOldP, spin := setSyscallTuning(t.ReservePs, t.PreferOldP, int64(t.SpinTime))
+	return SyscallTuning{
+		ReservePs:  reservePs,
+		PreferOldP: preferOldP,
+		SpinTime:   time.Duration(spin),
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug_test
+
+import (
+	"os"
+	"runtime"
+	. "runtime/debug"
+	"runtime/metrics"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSetSyscallTuning(t *testing.T) {
+	want := SyscallTuning{ReservePs: 1, PreferOldP: true, SpinTime: 50 * time.Microsecond}
+	orig := SetSyscallTuning(want)
+	defer SetSyscallTuning(orig)
+	if got := SetSyscallTuning(want); got != want {
+		t.Fatalf("SetSyscallTuning returned %+v, want %+v", got, want)
+	}
+
+	// Goroutines busy with system calls and computation still all
+	// make progress.
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(2))
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var b [1]byte
+			for j := 0; j < 100; j++ {
+				w.Write(b[:])
+				r.Read(b[:])
+				for k := 0; k < 1000; k++ {
+					b[0]++
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	samples := []metrics.Sample{
+		{Name: "/sched/syscalls/idle-p:calls"},
+		{Name: "/sched/syscalls/parked:calls"},
+		{Name: "/sched/syscalls/retaken-p:calls"},
+	}
+	metrics.Read(samples)
+	for _, s := range samples {
+		if s.Value.Kind() != metrics.KindUint64 {
+			t.Errorf("metric %s has kind %v", s.Name, s.Value.Kind())
+		}
+	}
+
+	if got := SetSyscallTuning(SyscallTuning{ReservePs: -1, SpinTime: -1}); got != want {
+		t.Errorf("SetSyscallTuning returned %+v, want %+v", got, want)
+	}
+	if got := SetSyscallTuning(want); got != (SyscallTuning{}) {
+		t.Errorf("negative setting gave %+v, want zero", got)
+	}
+}
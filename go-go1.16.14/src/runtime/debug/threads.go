@@ -0,0 +1,63 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+// A ThreadWarning describes the operating system threads of the
+// program when their number reached the threshold of SetThreadWarning,
+// or the limit of SetThreadLimitHandler.
+type ThreadWarning struct {
+	Threads int // threads in use
+	Limit   int // limit set by SetMaxThreads
+
+	// Stacks holds the stacks of the goroutines that hold a
+	// thread, because they are in a system call or locked to their
+	// thread, in the format of runtime.Stack.
+	Stacks []byte
+}
+
+// SetThreadWarning registers f to be called when the number of
+// operating system threads used by the program reaches threshold.
+// A program that exceeds the limit set by SetMaxThreads crashes with
+// a fatal "thread exhaustion" error; a threshold below that limit lets
+// it learn that it is getting close, typically because many goroutines
+// are blocked in system calls, and which goroutines hold the threads.
+//
+// f is called in a new goroutine, soon after the threshold is reached.
+// It is not called again until the number of threads has dropped below
+// threshold and reached it anew. A call replaces the previous function,
+// and a nil f or a threshold of zero or less only removes it.
+func SetThreadWarning(threshold int, f func(ThreadWarning)) {
+	if f == nil {
+		setThreadWarning(0, nil)
+		return
+	}
+	setThreadWarning(threshold, func(threads, limit int, stacks []byte) {
+		f(ThreadWarning{Threads: threads, Limit: limit, Stacks: stacks})
+	})
+}
+
+// SetThreadLimitHandler makes reaching the limit set by SetMaxThreads
+// non-fatal, and registers f to be called when it happens. Instead of
+// crashing with a fatal "thread exhaustion" error, the program then
+// waits for a thread to become available, such as one returning from a
+// system call, to run the goroutines that would have needed a new one.
+// The program is slower in the meantime, and stops if no thread ever
+// becomes available. Threads created outside of Go that call into Go,
+// as by a C library, still count against the limit, and exceeding it
+// with them remains fatal.
+//
+// f is called in a new goroutine, once the program gets to run it,
+// each time the program starts waiting for a thread. A call replaces
+// the previous function, and a nil f removes it, making the limit
+// fatal again.
+func SetThreadLimitHandler(f func(ThreadWarning)) {
+	if f == nil {
+		setThreadLimitHandler(nil)
+		return
+	}
+	setThreadLimitHandler(func(threads, limit int, stacks []byte) {
+		f(ThreadWarning{Threads: threads, Limit: limit, Stacks: stacks})
+	})
+}
@@ -0,0 +1,78 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug_test
+
+import (
+	"runtime"
+	. "runtime/debug"
+	"runtime/metrics"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func threadWarningHold(release <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	<-release
+}
+
+func TestSetThreadWarning(t *testing.T) {
+	if runtime.GOOS == "js" {
+		t.Skip("no threads on js")
+	}
+	samples := []metrics.Sample{
+		{Name: "/sched/threads/total:threads"},
+		{Name: "/sched/threads/peak:threads"},
+		{Name: "/sched/threads/idle:threads"},
+	}
+	metrics.Read(samples)
+	threads := int(samples[0].Value.Uint64())
+	if threads == 0 || samples[1].Value.Uint64() < uint64(threads) {
+		t.Fatalf("%s = %d, %s = %d", samples[0].Name, threads, samples[1].Name, samples[1].Value.Uint64())
+	}
+
+	warned := make(chan ThreadWarning, 1)
+	threshold := threads + 2
+	SetThreadWarning(threshold, func(w ThreadWarning) {
+		select {
+		case warned <- w:
+		default:
+		}
+	})
+	defer SetThreadWarning(0, nil)
+
+	// Each goroutine blocked while locked to its thread holds on to
+	// the thread, so that others are started once the idle ones are
+	// taken.
+	n := int(samples[2].Value.Uint64()) + 4
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go threadWarningHold(release, &wg)
+	}
+	defer wg.Wait()
+	defer close(release)
+
+	select {
+	case w := <-warned:
+		if w.Threads < threshold {
+			t.Errorf("warning with %d threads, want at least %d", w.Threads, threshold)
+		}
+		limit := SetMaxThreads(10000)
+		SetMaxThreads(limit)
+		if w.Limit != limit {
+			t.Errorf("warning with limit %d, want %d", w.Limit, limit)
+		}
+		if !strings.Contains(string(w.Stacks), "threadWarningHold") {
+			t.Errorf("warning stacks do not show the goroutines holding threads:\n%s", w.Stacks)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("no warning after starting %d threads", n)
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import "errors"
+
+// MaxWatchpoints is the number of watchpoints that can be set at once.
+const MaxWatchpoints = 4
+
+// A WatchpointHit is an access to an address watched by a watchpoint.
+type WatchpointHit struct {
+	ID        int     // watchpoint, as returned by SetWatchpoint
+	Goroutine int64   // ID of the goroutine that made the access
+	PC        uintptr // PC at which the goroutine was stopped, just after the access
+}
+
+var (
+	errWatchpointArgs        = errors.New("debug: watchpoint size must be 1, 2, 4 or 8 and divide the address")
+	errNoWatchpoint          = errors.New("debug: all watchpoints are in use")
+	errWatchpointUnsupported = errors.New("debug: hardware watchpoints are not supported")
+)
+
+// SetWatchpoint sets a hardware watchpoint on the size bytes at addr,
+// which fires on every write to them by Go code and, if reads is set,
+// on every read too. It returns the watchpoint's ID, which is between
+// 0 and MaxWatchpoints-1.
+//
+// Hardware watchpoints are a per-thread setting. The runtime installs
+// the watchpoint on every thread that runs goroutines, before SetWatchpoint
+// returns for the calling goroutine and at their next scheduling point
+// for the others, so goroutines stay watched as they move between
+// threads. Accesses made by the operating system, such as a read system
+// call filling a watched buffer, and by threads that the runtime did not
+// create are not seen.
+//
+// Hits are collected by ReadWatchpointHits. A debugger that cannot run
+// code in the process otherwise can inject a call to SetWatchpoint.
+//
+// Watchpoints are only supported on Linux, and only where the kernel
+// allows the process to use hardware breakpoints.
+func SetWatchpoint(addr, size uintptr, reads bool) (id int, err error) {
+	switch id := setWatchpoint(addr, size, reads); id {
+	case -1:
+		return 0, errWatchpointArgs
+	case -2:
+		return 0, errNoWatchpoint
+	case -3:
+		return 0, errWatchpointUnsupported
+	default:
+		return id, nil
+	}
+}
+
+// ClearWatchpoint removes the watchpoint id set by SetWatchpoint. Hits
+// recorded before it was removed can still be read.
+func ClearWatchpoint(id int) {
+	clearWatchpoint(id)
+}
+
+// ReadWatchpointHits stores into hits the accesses to watched addresses
+// that have not been read yet, oldest first, and returns the number
+// stored. Hits are reported asynchronously, shortly after the access.
+// The runtime keeps a limited number of hits: if they are not read
+// quickly enough, the oldest ones are lost.
+func ReadWatchpointHits(hits []WatchpointHit) int {
+	return readWatchpointHits(hits)
+}
@@ -0,0 +1,72 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug_test
+
+import (
+	"runtime"
+	. "runtime/debug"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// watched holds words that a single store writes, so that each write
+// hits a watchpoint once on 32-bit systems too.
+var watched [2]uintptr
+
+//go:noinline
+func writeWatched(i int, v uintptr) {
+	watched[i] = v
+}
+
+func TestWatchpoint(t *testing.T) {
+	size := unsafe.Sizeof(watched[0])
+	if _, err := SetWatchpoint(uintptr(unsafe.Pointer(&watched[0]))+1, size, false); err == nil {
+		t.Fatal("SetWatchpoint of misaligned address succeeded")
+	}
+	id, err := SetWatchpoint(uintptr(unsafe.Pointer(&watched[0])), size, false)
+	if err != nil {
+		if runtime.GOOS != "linux" {
+			return
+		}
+		t.Skipf("SetWatchpoint: %v", err)
+	}
+	defer ClearWatchpoint(id)
+
+	// Write from another goroutine, on whatever thread it runs.
+	done := make(chan bool)
+	go func() {
+		writeWatched(1, 1) // not watched
+		writeWatched(0, 1)
+		done <- true
+	}()
+	<-done
+
+	var hits [MaxWatchpoints]WatchpointHit
+	n := 0
+	for i := 0; i < 100 && n == 0; i++ {
+		n = ReadWatchpointHits(hits[:])
+		time.Sleep(time.Millisecond)
+	}
+	if n != 1 {
+		t.Fatalf("got %d hits, want 1: %+v", n, hits[:n])
+	}
+	if hits[0].ID != id {
+		t.Errorf("hit ID = %d, want %d", hits[0].ID, id)
+	}
+	if f := runtime.FuncForPC(hits[0].PC - 1); f == nil || f.Name() != "runtime/debug_test.writeWatched" {
+		t.Errorf("hit PC %#x is not in writeWatched", hits[0].PC)
+	}
+	if hits[0].Goroutine == 0 {
+		t.Errorf("hit has no goroutine")
+	}
+
+	ClearWatchpoint(id)
+	writeWatched(0, 2)
+	time.Sleep(10 * time.Millisecond)
+	if n := ReadWatchpointHits(hits[:]); n != 0 {
+		t.Errorf("got %d hits after ClearWatchpoint: %+v", n, hits[:n])
+	}
+}
@@ -163,6 +163,38 @@ func TestDebugCallLarge(t *testing.T) {
 	}
 }
 
+func TestDebugCallV2Large(t *testing.T) {
+	g, after := startDebugCallWorker(t)
+	defer after()
+
+	// Inject a call with a call frame too large for debugCallV1.
+	const N = 8192
+	var args struct {
+		in  [N]int
+		out [N]int
+	}
+	fn := func(in [N]int) (out [N]int) {
+		for i := range in {
+			out[i] = in[i] + 1
+		}
+		return
+	}
+	if _, err := runtime.InjectDebugCall(g, fn, &args, debugCallTKill, false); err == nil || err.Error() != "call frame too large" {
+		t.Fatalf("want %q from debugCallV1, got %v", "call frame too large", err)
+	}
+	var want [N]int
+	for i := range args.in {
+		args.in[i] = i
+		want[i] = i + 1
+	}
+	if _, err := runtime.InjectDebugCallV2(g, fn, &args, debugCallTKill, false); err != nil {
+		t.Fatal(err)
+	}
+	if want != args.out {
+		t.Fatalf("want %v, got %v", want, args.out)
+	}
+}
+
 func TestDebugCallGC(t *testing.T) {
 	g, after := startDebugCallWorker(t)
 	defer after()
@@ -219,6 +251,10 @@ func TestDebugCallUnsafePoint(t *testing.T) {
 	if msg := "call not at safe point"; err == nil || err.Error() != msg {
 		t.Fatalf("want %q, got %s", msg, err)
 	}
+	_, err = runtime.InjectDebugCallV2(g, func() {}, nil, debugCallTKill, true)
+	if msg := "call not at safe point"; err == nil || err.Error() != msg {
+		t.Fatalf("debugCallV2: want %q, got %s", msg, err)
+	}
 }
 
 func TestDebugCallPanic(t *testing.T) {
@@ -9,13 +9,38 @@ package runtime
 import "unsafe"
 
 const (
-	debugCallSystemStack = "executing on Go runtime stack"
-	debugCallUnknownFunc = "call from unknown function"
-	debugCallRuntime     = "call from within the Go runtime"
-	debugCallUnsafePoint = "call not at safe point"
+	debugCallSystemStack   = "executing on Go runtime stack"
+	debugCallUnknownFunc   = "call from unknown function"
+	debugCallRuntime       = "call from within the Go runtime"
+	debugCallUnsafePoint   = "call not at safe point"
+	debugCallFrameTooLarge = "call frame too large"
 )
 
+// Reasons a debugger call cannot be injected. debugCallV2 reports
+// these codes to the debugger along with the reason string, so it can
+// tell transient conditions, which it may step past and retry, from
+// permanent ones. The values are part of the debugCallV2 protocol and
+// must not change.
+const (
+	debugCallOK               = 0
+	debugCallErrSystemStack   = 1
+	debugCallErrUnknownFunc   = 2
+	debugCallErrRuntime       = 3
+	debugCallErrUnsafePoint   = 4
+	debugCallErrFrameTooLarge = 5
+)
+
+var debugCallErrors = [...]string{
+	debugCallOK:               "",
+	debugCallErrSystemStack:   debugCallSystemStack,
+	debugCallErrUnknownFunc:   debugCallUnknownFunc,
+	debugCallErrRuntime:       debugCallRuntime,
+	debugCallErrUnsafePoint:   debugCallUnsafePoint,
+	debugCallErrFrameTooLarge: debugCallFrameTooLarge,
+}
+
 func debugCallV1()
+func debugCallV2()
 func debugCallPanicked(val interface{})
 
 // debugCallCheck checks whether it is safe to inject a debugger
@@ -24,25 +49,43 @@ func debugCallPanicked(val interface{})
 //
 //go:nosplit
 func debugCallCheck(pc uintptr) string {
+	return debugCallErrors[debugCallCheckCode(pc)]
+}
+
+// debugCallCheckV2 is debugCallCheck for debugCallV2. It also returns
+// the code of the reason.
+//
+//go:nosplit
+func debugCallCheckV2(pc uintptr) (string, uintptr) {
+	code := debugCallCheckCode(pc)
+	return debugCallErrors[code], code
+}
+
+// debugCallCheckCode returns debugCallOK if it is safe to inject a
+// debugger function call with return PC pc, and otherwise the code of
+// the reason it is not.
+//
+//go:nosplit
+func debugCallCheckCode(pc uintptr) uintptr {
 	// No user calls from the system stack.
 	if getg() != getg().m.curg {
-		return debugCallSystemStack
+		return debugCallErrSystemStack
 	}
 	if sp := getcallersp(); !(getg().stack.lo < sp && sp <= getg().stack.hi) {
 		// Fast syscalls (nanotime) and racecall switch to the
 		// g0 stack without switching g. We can't safely make
 		// a call in this state. (We can't even safely
 		// systemstack.)
-		return debugCallSystemStack
+		return debugCallErrSystemStack
 	}
 
 	// Switch to the system stack to avoid overflowing the user
 	// stack.
-	var ret string
+	var ret uintptr
 	systemstack(func() {
 		f := findfunc(pc)
 		if !f.valid() {
-			ret = debugCallUnknownFunc
+			ret = debugCallErrUnknownFunc
 			return
 		}
 
@@ -60,10 +103,20 @@ func debugCallCheck(pc uintptr) string {
 			"debugCall8192",
 			"debugCall16384",
 			"debugCall32768",
-			"debugCall65536":
+			"debugCall65536",
+			"debugCall131072",
+			"debugCall262144",
+			"debugCall524288",
+			"debugCall1048576":
 			// These functions are allowed so that the debugger can initiate multiple function calls.
 			// See: https://golang.org/cl/161137/
 			return
+		case "runtime.Breakpoint", "runtime.BreakpointIf":
+			// A goroutine stopped at a call to Breakpoint is
+			// at a safe point in its caller, so the debugger
+			// can evaluate expressions that call functions
+			// there.
+			return
 		}
 
 		// Disallow calls from the runtime. We could
@@ -72,7 +125,7 @@ func debugCallCheck(pc uintptr) string {
 		// coded sequences (e.g., defer handling) that it's
 		// better to play it safe.
 		if pfx := "runtime."; len(name) > len(pfx) && name[:len(pfx)] == pfx {
-			ret = debugCallRuntime
+			ret = debugCallErrRuntime
 			return
 		}
 
@@ -83,7 +136,7 @@ func debugCallCheck(pc uintptr) string {
 		up := pcdatavalue(f, _PCDATA_UnsafePoint, pc, nil)
 		if up != _PCDATA_UnsafePointSafe {
 			// Not at a safe point.
-			ret = debugCallUnsafePoint
+			ret = debugCallErrUnsafePoint
 		}
 	})
 	return ret
@@ -95,7 +148,7 @@ func debugCallCheck(pc uintptr) string {
 // function at PC dispatch.
 //
 // This must be deeply nosplit because there are untyped values on the
-// stack from debugCallV1.
+// stack from debugCallV1 or debugCallV2.
 //
 //go:nosplit
 func debugCallWrap(dispatch uintptr) {
@@ -0,0 +1,205 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+import (
+	"runtime/internal/sys"
+	"unsafe"
+)
+
+// debugLayoutVersion is the version of the debugLayoutInfo format.
+//
+// Fields are only ever appended to debugLayoutInfo. Tools that know
+// version n can read any blob whose version is at least n, using the
+// size field to tell how much of it is present. The version is bumped
+// whenever fields are added or the meaning of an existing field
+// changes.
+const debugLayoutVersion = 2
+
+// debugLayoutInfo describes the layout of the scheduler structures and
+// the values of the status constants, so that debuggers and external
+// samplers can read g, m, p and schedt from a process or core file
+// without hard-coding offsets that change between releases.
+//
+// The blob is found through the runtime.debugLayout symbol. Every
+// field is a uint32 in the target's byte order. Offsets are in bytes
+// from the start of the enclosing structure; offsets of gobuf fields
+// are relative to the gobuf, and those of gQueue fields to the gQueue.
+type debugLayoutInfo struct {
+	version uint32 // debugLayoutVersion
+	size    uint32 // unsafe.Sizeof(debugLayoutInfo{})
+	ptrSize uint32
+
+	gSize      uint32
+	mSize      uint32
+	pSize      uint32
+	schedtSize uint32
+
+	// g
+	gStackLo      uint32
+	gStackHi      uint32
+	gSched        uint32
+	gM            uint32
+	gAtomicstatus uint32
+	gGoid         uint32
+	gWaitsince    uint32
+	gWaitreason   uint32
+	gGopc         uint32
+	gStartpc      uint32
+	gParentGoid   uint32
+	gLockedm      uint32
+	gLabels       uint32
+
+	// gobuf
+	gobufSP uint32
+	gobufPC uint32
+	gobufG  uint32
+	gobufLR uint32
+	gobufBP uint32
+
+	// m
+	mG0      uint32
+	mProcid  uint32
+	mCurg    uint32
+	mP       uint32
+	mID      uint32
+	mLockedg uint32
+	mAlllink uint32
+	mIncgo   uint32
+
+	// p
+	pID        uint32
+	pStatus    uint32
+	pM         uint32
+	pSchedtick uint32
+	pRunqhead  uint32
+	pRunqtail  uint32
+	pRunq      uint32
+	pRunqLen   uint32
+	pRunnext   uint32
+
+	// schedt
+	schedGoidgen    uint32
+	schedMidle      uint32
+	schedNmidle     uint32
+	schedPidle      uint32
+	schedNpidle     uint32
+	schedNmspinning uint32
+	schedRunq       uint32
+	schedRunqsize   uint32
+	schedGcwaiting  uint32
+
+	// gQueue
+	gQueueHead uint32
+	gQueueTail uint32
+
+	// g statuses
+	gIdle      uint32
+	gRunnable  uint32
+	gRunning   uint32
+	gSyscall   uint32
+	gWaiting   uint32
+	gDead      uint32
+	gCopystack uint32
+	gPreempted uint32
+	gScan      uint32
+
+	// p statuses
+	pIdle    uint32
+	pRunning uint32
+	pSyscall uint32
+	pGcstop  uint32
+	pDead    uint32
+
+	// Added in version 2. g.lineage is an array of gLineageLen
+	// entries of a goroutine ID (int64) and a PC (uintptr).
+	gLineage    uint32
+	gLineageLen uint32
+}
+
+// debugLayout is the layout blob read by debuggers. It is statically
+// initialized, so it can be read from a core file or from a process
+// that has not started running Go code.
+var debugLayout = debugLayoutInfo{
+	version: debugLayoutVersion,
+	size:    uint32(unsafe.Sizeof(debugLayoutInfo{})),
+	ptrSize: sys.PtrSize,
+
+	gSize:      uint32(unsafe.Sizeof(g{})),
+	mSize:      uint32(unsafe.Sizeof(m{})),
+	pSize:      uint32(unsafe.Sizeof(p{})),
+	schedtSize: uint32(unsafe.Sizeof(schedt{})),
+
+	gStackLo:      uint32(unsafe.Offsetof(g{}.stack) + unsafe.Offsetof(stack{}.lo)),
+	gStackHi:      uint32(unsafe.Offsetof(g{}.stack) + unsafe.Offsetof(stack{}.hi)),
+	gSched:        uint32(unsafe.Offsetof(g{}.sched)),
+	gM:            uint32(unsafe.Offsetof(g{}.m)),
+	gAtomicstatus: uint32(unsafe.Offsetof(g{}.atomicstatus)),
+	gGoid:         uint32(unsafe.Offsetof(g{}.goid)),
+	gWaitsince:    uint32(unsafe.Offsetof(g{}.waitsince)),
+	gWaitreason:   uint32(unsafe.Offsetof(g{}.waitreason)),
+	gGopc:         uint32(unsafe.Offsetof(g{}.gopc)),
+	gStartpc:      uint32(unsafe.Offsetof(g{}.startpc)),
+	gParentGoid:   uint32(unsafe.Offsetof(g{}.parentGoid)),
+	gLockedm:      uint32(unsafe.Offsetof(g{}.lockedm)),
+	gLabels:       uint32(unsafe.Offsetof(g{}.labels)),
+
+	gobufSP: uint32(unsafe.Offsetof(gobuf{}.sp)),
+	gobufPC: uint32(unsafe.Offsetof(gobuf{}.pc)),
+	gobufG:  uint32(unsafe.Offsetof(gobuf{}.g)),
+	gobufLR: uint32(unsafe.Offsetof(gobuf{}.lr)),
+	gobufBP: uint32(unsafe.Offsetof(gobuf{}.bp)),
+
+	mG0:      uint32(unsafe.Offsetof(m{}.g0)),
+	mProcid:  uint32(unsafe.Offsetof(m{}.procid)),
+	mCurg:    uint32(unsafe.Offsetof(m{}.curg)),
+	mP:       uint32(unsafe.Offsetof(m{}.p)),
+	mID:      uint32(unsafe.Offsetof(m{}.id)),
+	mLockedg: uint32(unsafe.Offsetof(m{}.lockedg)),
+	mAlllink: uint32(unsafe.Offsetof(m{}.alllink)),
+	mIncgo:   uint32(unsafe.Offsetof(m{}.incgo)),
+
+	pID:        uint32(unsafe.Offsetof(p{}.id)),
+	pStatus:    uint32(unsafe.Offsetof(p{}.status)),
+	pM:         uint32(unsafe.Offsetof(p{}.m)),
+	pSchedtick: uint32(unsafe.Offsetof(p{}.schedtick)),
+	pRunqhead:  uint32(unsafe.Offsetof(p{}.runqhead)),
+	pRunqtail:  uint32(unsafe.Offsetof(p{}.runqtail)),
+	pRunq:      uint32(unsafe.Offsetof(p{}.runq)),
+	pRunqLen:   uint32(len(p{}.runq)),
+	pRunnext:   uint32(unsafe.Offsetof(p{}.runnext)),
+
+	schedGoidgen:    uint32(unsafe.Offsetof(schedt{}.goidgen)),
+	schedMidle:      uint32(unsafe.Offsetof(schedt{}.midle)),
+	schedNmidle:     uint32(unsafe.Offsetof(schedt{}.nmidle)),
+	schedPidle:      uint32(unsafe.Offsetof(schedt{}.pidle)),
+	schedNpidle:     uint32(unsafe.Offsetof(schedt{}.npidle)),
+	schedNmspinning: uint32(unsafe.Offsetof(schedt{}.nmspinning)),
+	schedRunq:       uint32(unsafe.Offsetof(schedt{}.runq)),
+	schedRunqsize:   uint32(unsafe.Offsetof(schedt{}.runqsize)),
+	schedGcwaiting:  uint32(unsafe.Offsetof(schedt{}.gcwaiting)),
+
+	gQueueHead: uint32(unsafe.Offsetof(gQueue{}.head)),
+	gQueueTail: uint32(unsafe.Offsetof(gQueue{}.tail)),
+
+	gIdle:      _Gidle,
+	gRunnable:  _Grunnable,
+	gRunning:   _Grunning,
+	gSyscall:   _Gsyscall,
+	gWaiting:   _Gwaiting,
+	gDead:      _Gdead,
+	gCopystack: _Gcopystack,
+	gPreempted: _Gpreempted,
+	gScan:      _Gscan,
+
+	pIdle:    _Pidle,
+	pRunning: _Prunning,
+	pSyscall: _Psyscall,
+	pGcstop:  _Pgcstop,
+	pDead:    _Pdead,
+
+	gLineage:    uint32(unsafe.Offsetof(g{}.lineage)),
+	gLineageLen: lineageDepth,
+}
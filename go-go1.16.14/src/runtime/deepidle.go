@@ -0,0 +1,130 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Deep idle.
+//
+// A program with nothing to do still wakes up: the M blocked in the
+// network poller returns for each timer, and sysmon wakes from its
+// deep sleep at least every half forcegcperiod to check whether a GC
+// is to be forced. runtime/debug.SetDeepIdle sets a slack that makes
+// these wakeups fewer while all Ps are idle. The deadlines of the
+// poller and of sysmon are then pushed later, by less than the slack,
+// to the next multiple of it, so that timers due in the same window
+// of the slack run after a single wakeup. sysmon also sleeps until the
+// forced GC is due rather than half the period.
+//
+// The wakeups that remain are counted for runtime/metrics.
+//
+// runtime/debug.ReadSleepForecast reports when the earliest timer is
+// due and when the runtime expects to wake up next, the deadline of the
+// M blocked in the network poller or of sysmon's deep sleep, so that a
+// program can align its own sleeps with these wakeups.
+
+package runtime
+
+import (
+	"runtime/internal/atomic"
+	"unsafe"
+)
+
+// deepIdleSlack is the slack set by runtime/debug.SetDeepIdle, in
+// nanoseconds, or 0 if deep idle is disabled. Accessed atomically.
+var deepIdleSlack int64
+
+// Wakeups of an idle program, reported by runtime/metrics. Accessed
+// atomically.
+var (
+	// idlePollerWakeups counts blocking netpolls in findrunnable,
+	// entered with all Ps idle, that returned at their deadline.
+	idlePollerWakeups uint64
+
+	// idleSysmonWakeups counts deep sleeps of sysmon that ended at
+	// their deadline.
+	idleSysmonWakeups uint64
+)
+
+// sysmonSleepUntil is the deadline of the deep sleep of sysmon, or 0
+// if it is not sleeping deeply. Accessed atomically.
+var sysmonSleepUntil uint64
+
+//go:linkname setDeepIdle runtime/debug.setDeepIdle
+func setDeepIdle(slack int64) (prev int64) {
+	if slack < 0 {
+		slack = 0
+	}
+	prev = int64(atomic.Xchg64((*uint64)(unsafe.Pointer(&deepIdleSlack)), uint64(slack)))
+
+	// Wake sysmon so that it sleeps according to the new slack.
+	lock(&sched.lock)
+	if sched.sysmonwait != 0 {
+		sched.sysmonwait = 0
+		notewakeup(&sched.sysmonnote)
+	}
+	unlock(&sched.lock)
+	return prev
+}
+
+// deepIdleDeadline returns when, a time in nanotime, pushed later to
+// the next multiple of the deep idle slack. It returns when unchanged
+// if deep idle is disabled.
+func deepIdleDeadline(when int64) int64 {
+	slack := atomic.Loadint64(&deepIdleSlack)
+	if slack <= 0 || when <= 0 {
+		return when
+	}
+	if w := (when + slack - 1) / slack * slack; w >= when {
+		return w
+	}
+	return when // overflow
+}
+
+// deepIdleSysmonSleep returns how long sysmon, in its deep sleep at
+// now, may sleep in deep idle for the forced GC: until just after the
+// GC is due. It returns 0 if deep idle is disabled or no GC is to be
+// forced.
+func deepIdleSysmonSleep(now int64) int64 {
+	if atomic.Loadint64(&deepIdleSlack) <= 0 {
+		return 0
+	}
+	period := atomic.Loadint64(&forcegcperiod)
+	if period < 0 {
+		return 0
+	}
+	lastgc := int64(atomic.Load64(&memstats.last_gc_nanotime))
+	if lastgc == 0 {
+		return 0
+	}
+	sleep := lastgc + period + 1 - now
+	if sleep <= 0 {
+		return 0
+	}
+	return sleep
+}
+
+// readSleepForecast returns the current nanotime, when the earliest
+// timer of all Ps is due and when the runtime expects to wake up next
+// for a timer or for sysmon, as nanotimes, each 0 if there is none.
+//
+//go:linkname readSleepForecast runtime/debug.readSleepForecast
+func readSleepForecast() (now, nextTimer, wakeup int64) {
+	now = nanotime()
+	if next, _ := timeSleepUntil(); next != maxWhen {
+		nextTimer = next
+	}
+	// An M blocked in the poller wakes at its deadline, which any
+	// earlier timer would have moved up, unless within a slack.
+	// Otherwise the timer is run when due by a running P, or later
+	// within the deep idle slack if all Ps are idle.
+	if pollUntil := int64(atomic.Load64(&sched.pollUntil)); atomic.Load64(&sched.lastpoll) == 0 && pollUntil != 0 {
+		wakeup = pollUntil
+	} else if procsIdle() {
+		wakeup = deepIdleDeadline(nextTimer)
+	} else {
+		wakeup = nextTimer
+	}
+	if s := int64(atomic.Load64(&sysmonSleepUntil)); s != 0 && (wakeup == 0 || s < wakeup) {
+		wakeup = s
+	}
+	return
+}
@@ -0,0 +1,130 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Defer pool statistics and sizing.
+//
+// newdefer takes _defer records from the pool of its P for the size
+// class of the deferred call's arguments, and allocates them on the
+// heap when the pool and the central pool are empty. Each P counts
+// these hits and misses per class, and runtime/debug.SetDeferPoolDepth
+// sets the capacity of the pools of a class, 32 by default. A P moves
+// half of a full pool to the central pool, and takes up to half of its
+// capacity from the central pool when its pool is empty.
+
+package runtime
+
+import (
+	"runtime/internal/atomic"
+	_ "unsafe" // for go:linkname
+)
+
+// deferPoolMaxDepth is the largest capacity of a per-P defer pool.
+const deferPoolMaxDepth = 1024
+
+// deferPoolStats counts the records a P took from its defer pool for a
+// size class, and those it had to allocate. The counters are written
+// by the P's owner and read by others, all atomically.
+type deferPoolStats struct {
+	hits   uint64
+	misses uint64
+}
+
+var deferPool struct {
+	// depth is the capacity of the per-P pools of each class.
+	// Written atomically with the world stopped.
+	depth [len(p{}.deferpool)]uint32
+}
+
+// deferpoolinit sets the default depths. It runs in schedinit, before
+// the first Ps are created.
+func deferpoolinit() {
+	for i := range deferPool.depth {
+		deferPool.depth[i] = uint32(len(p{}.deferpoolbuf[i]))
+	}
+}
+
+// deferPoolBuf returns an empty pool for class sc with the configured
+// capacity, using pp's built-in buffer if it is large enough.
+func deferPoolBuf(pp *p, sc int) []*_defer {
+	depth := int(deferPool.depth[sc])
+	if depth <= len(pp.deferpoolbuf[sc]) {
+		return pp.deferpoolbuf[sc][:0:depth]
+	}
+	return make([]*_defer, 0, depth)
+}
+
+// readDeferPoolStats returns the total hits and misses of class sc.
+func readDeferPoolStats(sc int) (hits, misses uint64) {
+	lock(&allpLock)
+	// Include the Ps destroyed by procresize, which keep their counts.
+	for _, pp := range allp[:cap(allp)] {
+		if pp == nil {
+			continue
+		}
+		hits += atomic.Load64(&pp.deferstats[sc].hits)
+		misses += atomic.Load64(&pp.deferstats[sc].misses)
+	}
+	unlock(&allpLock)
+	return
+}
+
+//go:linkname readDeferPoolClasses runtime/debug.readDeferPoolClasses
+func readDeferPoolClasses(argSize []int, depth []int, hits, misses []uint64) {
+	for sc := range deferPool.depth {
+		argSize[sc] = int(minDeferArgs) + 16*sc
+		depth[sc] = int(atomic.Load(&deferPool.depth[sc]))
+		hits[sc], misses[sc] = readDeferPoolStats(sc)
+	}
+}
+
+// setDeferPoolDepth sets the capacity of the per-P defer pools of
+// class sc, returning the previous capacity, or -1 if sc is not a
+// class. The pools are resized with the world stopped; the records
+// that no longer fit go to the central pool.
+//
+//go:linkname setDeferPoolDepth runtime/debug.setDeferPoolDepth
+func setDeferPoolDepth(sc, depth int) (prev int) {
+	if sc < 0 || sc >= len(deferPool.depth) {
+		return -1
+	}
+	if depth < 0 {
+		depth = 0
+	}
+	if depth > deferPoolMaxDepth {
+		depth = deferPoolMaxDepth
+	}
+
+	stopTheWorldGC("defer pool depth")
+	prev = int(deferPool.depth[sc])
+	atomic.Store(&deferPool.depth[sc], uint32(depth))
+	for _, pp := range allp {
+		// pool and old may share pp.deferpoolbuf, but each record
+		// of old is read before its slot is written.
+		old := pp.deferpool[sc]
+		pool := deferPoolBuf(pp, sc)
+		var first, last *_defer
+		for i, d := range old {
+			old[i] = nil
+			if len(pool) < cap(pool) {
+				pool = append(pool, d)
+				continue
+			}
+			if first == nil {
+				first = d
+			} else {
+				last.link = d
+			}
+			last = d
+		}
+		pp.deferpool[sc] = pool
+		if first != nil {
+			lock(&sched.deferlock)
+			last.link = sched.deferpool[sc]
+			sched.deferpool[sc] = first
+			unlock(&sched.deferlock)
+		}
+	}
+	startTheWorldGC()
+	return prev
+}
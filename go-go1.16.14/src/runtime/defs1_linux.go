@@ -23,6 +23,7 @@ const (
 	O_RDONLY    = C.O_RDONLY
 	O_NONBLOCK  = C.O_NONBLOCK
 	O_CLOEXEC   = C.O_CLOEXEC
+	O_ASYNC     = C.O_ASYNC
 	SA_RESTORER = C.SA_RESTORER
 )
 
@@ -115,12 +115,20 @@ const (
 	SEGV_MAPERR = C.SEGV_MAPERR
 	SEGV_ACCERR = C.SEGV_ACCERR
 
+	SI_KERNEL = C.SI_KERNEL
+	SI_TIMER  = C.SI_TIMER
+
 	ITIMER_REAL    = C.ITIMER_REAL
 	ITIMER_VIRTUAL = C.ITIMER_VIRTUAL
 	ITIMER_PROF    = C.ITIMER_PROF
 
+	CLOCK_THREAD_CPUTIME_ID = C.CLOCK_THREAD_CPUTIME_ID
+
+	SIGEV_THREAD_ID = C.SIGEV_THREAD_ID
+
 	O_RDONLY  = C.O_RDONLY
 	O_CLOEXEC = C.O_CLOEXEC
+	O_ASYNC   = C.O_ASYNC
 
 	EPOLLIN       = C.POLLIN
 	EPOLLOUT      = C.POLLOUT
@@ -146,4 +154,5 @@ type StackT C.stack_t
 type Sigcontext C.struct_sigcontext
 type Ucontext C.struct_ucontext
 type Itimerval C.struct_itimerval
+type Itimerspec C.struct_itimerspec
 type EpollEvent C.struct_epoll_event
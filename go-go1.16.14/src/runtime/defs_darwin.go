@@ -112,6 +112,9 @@ const (
 	EV_EOF       = C.EV_EOF
 	EVFILT_READ  = C.EVFILT_READ
 	EVFILT_WRITE = C.EVFILT_WRITE
+	EVFILT_USER  = C.EVFILT_USER
+
+	NOTE_TRIGGER = C.NOTE_TRIGGER
 
 	PTHREAD_CREATE_DETACHED = C.PTHREAD_CREATE_DETACHED
 
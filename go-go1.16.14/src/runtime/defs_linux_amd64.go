@@ -75,10 +75,17 @@ const (
 	_SEGV_MAPERR = 0x1
 	_SEGV_ACCERR = 0x2
 
+	_SI_KERNEL = 0x80
+	_SI_TIMER  = -0x2
+
 	_ITIMER_REAL    = 0x0
 	_ITIMER_VIRTUAL = 0x1
 	_ITIMER_PROF    = 0x2
 
+	_CLOCK_THREAD_CPUTIME_ID = 0x3
+
+	_SIGEV_THREAD_ID = 0x4
+
 	_EPOLLIN       = 0x1
 	_EPOLLOUT      = 0x4
 	_EPOLLERR      = 0x8
@@ -134,6 +141,11 @@ type itimerval struct {
 	it_value    timeval
 }
 
+type itimerspec struct {
+	it_interval timespec
+	it_value    timespec
+}
+
 type epollevent struct {
 	events uint32
 	data   [8]byte // unaligned uintptr
@@ -146,6 +158,7 @@ const (
 	_O_RDONLY   = 0x0
 	_O_NONBLOCK = 0x800
 	_O_CLOEXEC  = 0x80000
+	_O_ASYNC    = 0x2000
 )
 
 type usigset struct {
@@ -72,12 +72,19 @@ const (
 	_BUS_OBJERR     = 0x3
 	_SEGV_MAPERR    = 0x1
 	_SEGV_ACCERR    = 0x2
+	_SI_KERNEL      = 0x80
+	_SI_TIMER       = -0x2
 	_ITIMER_REAL    = 0
 	_ITIMER_PROF    = 0x2
 	_ITIMER_VIRTUAL = 0x1
 	_O_RDONLY       = 0
 	_O_NONBLOCK     = 0x800
 	_O_CLOEXEC      = 0x80000
+	_O_ASYNC        = 0x2000
+
+	_CLOCK_THREAD_CPUTIME_ID = 0x3
+
+	_SIGEV_THREAD_ID = 0x4
 
 	_EPOLLIN       = 0x1
 	_EPOLLOUT      = 0x4
@@ -158,6 +165,11 @@ type itimerval struct {
 	it_value    timeval
 }
 
+type itimerspec struct {
+	it_interval timespec
+	it_value    timespec
+}
+
 type siginfo struct {
 	si_signo int32
 	si_errno int32
@@ -0,0 +1,136 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+import "runtime/internal/atomic"
+
+// Deterministic scheduling, enabled by GODEBUG=detsched=N with a
+// nonzero seed N, makes the interleaving of goroutines reproducible
+// from the seed, so that a flaky concurrency test can be run again
+// with the seed that made it fail. In this mode
+//
+//	- there is a single P, and GOMAXPROCS cannot change it;
+//	- time is virtual, as for the playground: the clock stands still
+//	  while any goroutine can run and jumps to the next timer when
+//	  none can (except on Windows, where time.Now reads the real clock);
+//	- the next goroutine to run is drawn from the run queues by a
+//	  generator seeded with N, and fastrand, which makes the choices
+//	  of select, is seeded with N too unless GODEBUG=schedseed is set;
+//	- goroutines are not preempted for running too long, neither by
+//	  sysmon nor by signals, so they only switch when they block or
+//	  yield, and sysmon does not force periodic garbage collections.
+//
+// Goroutines woken by system calls, cgo callbacks, the network poller
+// or signals may still run at times that depend on the outside world,
+// and so may other goroutines while one is in a system call for longer
+// than detschedSyscallNS.
+
+// detschedSyscallNS is how long, in real time, a goroutine may stay in
+// a system call before sysmon lets other goroutines run on its P.
+const detschedSyscallNS = 20 * 1000 * 1000 // 20ms
+
+var detsched struct {
+	enabled bool
+
+	// rand is the state of the generator choosing the next
+	// goroutine. It is only used by the M holding the single P.
+	rand uint64
+}
+
+// detschedinit enables deterministic scheduling if GODEBUG asks for
+// it. It is called by schedinit after parsedebugvars and before the
+// Ps are created.
+func detschedinit() {
+	if debug.detsched == 0 {
+		return
+	}
+	detsched.enabled = true
+	detsched.rand = uint64(uint32(debug.detsched))
+	debug.asyncpreemptoff = 1
+	if GOOS != "windows" && faketime == 0 {
+		faketime = 1257894000000000000 // as for the playground
+	}
+}
+
+// detschedrand returns the next value of the generator seeded by
+// GODEBUG=detsched. It is SplitMix64.
+func detschedrand() uint64 {
+	detsched.rand += 0x9e3779b97f4a7c15
+	return splitmix64(detsched.rand)
+}
+
+// splitmix64 returns the output of SplitMix64 for the state z.
+func splitmix64(z uint64) uint64 {
+	z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+	z = (z ^ z>>27) * 0x94d049bb133111eb
+	return z ^ z>>31
+}
+
+// detschedget removes and returns a goroutine chosen at random among
+// the runnable ones, or nil if there are none. It first moves the
+// global run queue into the local one, as far as it fits, so that
+// every runnable goroutine is a candidate. Under GODEBUG=schedreplay
+// it chooses the goroutine whose turn it is, if that one is runnable.
+//
+// It is only used with the single P of deterministic scheduling, so
+// nothing steals from _p_ and only the caller consumes its run queue.
+func detschedget(_p_ *p) (gp *g, inheritTime bool) {
+	if sched.runqsize != 0 {
+		lock(&sched.lock)
+		for sched.runqsize != 0 && _p_.runqtail-_p_.runqhead < uint32(len(_p_.runq)) {
+			sched.runqsize--
+			runqput(_p_, sched.runq.pop(), false)
+		}
+		unlock(&sched.lock)
+	}
+
+	h := atomic.LoadAcq(&_p_.runqhead)
+	n := _p_.runqtail - h
+	next := _p_.runnext
+	if next != 0 {
+		n++
+	}
+	if n == 0 {
+		return nil, false
+	}
+	i := uint32(detschedrand() % uint64(n))
+	if want := schedReplayNext(); want != 0 {
+		i = detschedfind(_p_, h, want, i)
+	}
+	if next != 0 {
+		if i == 0 {
+			_p_.runnext = 0
+			return next.ptr(), true
+		}
+		i--
+	}
+	// Swap the chosen goroutine to the head of the queue and take it.
+	q := &_p_.runq
+	hi, ci := h%uint32(len(q)), (h+i)%uint32(len(q))
+	q[hi], q[ci] = q[ci], q[hi]
+	gp = q[hi].ptr()
+	atomic.StoreRel(&_p_.runqhead, h+1)
+	return gp, false
+}
+
+// detschedfind returns the index, as chosen by detschedget, of the
+// goroutine with ID goid in _p_'s run queues with head h, or def if
+// that goroutine is not there.
+func detschedfind(_p_ *p, h uint32, goid int64, def uint32) uint32 {
+	i := uint32(0)
+	if next := _p_.runnext; next != 0 {
+		if next.ptr().goid == goid {
+			return 0
+		}
+		i++
+	}
+	for j := h; j != _p_.runqtail; j++ {
+		if _p_.runq[j%uint32(len(_p_.runq))].ptr().goid == goid {
+			return i
+		}
+		i++
+	}
+	return def
+}
@@ -21,6 +21,16 @@ import (
 // On success, InjectDebugCall returns the panic value of fn or nil.
 // If fn did not panic, its results will be available in args.
 func InjectDebugCall(gp *g, fn, args interface{}, tkill func(tid int) error, returnOnUnsafePoint bool) (interface{}, error) {
+	return injectDebugCall(gp, fn, args, tkill, returnOnUnsafePoint, false)
+}
+
+// InjectDebugCallV2 is like InjectDebugCall, but uses the debugCallV2
+// protocol.
+func InjectDebugCallV2(gp *g, fn, args interface{}, tkill func(tid int) error, returnOnUnsafePoint bool) (interface{}, error) {
+	return injectDebugCall(gp, fn, args, tkill, returnOnUnsafePoint, true)
+}
+
+func injectDebugCall(gp *g, fn, args interface{}, tkill func(tid int) error, returnOnUnsafePoint, v2 bool) (interface{}, error) {
 	if gp.lockedm == 0 {
 		return nil, plainError("goroutine not locked to thread")
 	}
@@ -52,6 +62,7 @@ func InjectDebugCall(gp *g, fn, args interface{}, tkill func(tid int) error, ret
 	// it will run on since it's locked.
 	h.mp = gp.lockedm.ptr()
 	h.fv, h.argp, h.argSize = fv, argp, argSize
+	h.v2 = v2
 	h.handleF = h.handle // Avoid allocating closure during signal
 
 	defer func() { testSigtrap = nil }()
@@ -94,6 +105,7 @@ type debugCallHandler struct {
 	argp    unsafe.Pointer
 	argSize uintptr
 	panic   interface{}
+	v2      bool
 
 	handleF func(info *siginfo, ctxt *sigctxt, gp2 *g) bool
 
@@ -120,8 +132,12 @@ func (h *debugCallHandler) inject(info *siginfo, ctxt *sigctxt, gp2 *g) bool {
 		h.savedRegs = *ctxt.regs()
 		h.savedFP = *h.savedRegs.fpstate
 		h.savedRegs.fpstate = nil
-		// Set PC to debugCallV1.
-		ctxt.set_rip(uint64(funcPC(debugCallV1)))
+		// Set PC to debugCallV1 or debugCallV2.
+		if h.v2 {
+			ctxt.set_rip(uint64(funcPC(debugCallV2)))
+		} else {
+			ctxt.set_rip(uint64(funcPC(debugCallV1)))
+		}
 		// Call injected. Switch to the debugCall protocol.
 		testSigtrap = h.handleF
 	case _Grunnable:
@@ -178,6 +194,13 @@ func (h *debugCallHandler) handle(info *siginfo, ctxt *sigctxt, gp2 *g) bool {
 		sp := ctxt.rsp()
 		reason := *(*string)(unsafe.Pointer(uintptr(sp)))
 		h.err = plainError(reason)
+		if h.v2 {
+			// Check that the reason code matches the reason.
+			code := *(*uintptr)(unsafe.Pointer(uintptr(sp + 16)))
+			if code >= uintptr(len(debugCallErrors)) || code == debugCallOK || debugCallErrors[code] != reason {
+				h.err = plainError("bad debugCallV2 reason code")
+			}
+		}
 		// Don't wake h.done. We need to transition to status 16 first.
 	case 16:
 		// Restore all registers except RIP and RSP.
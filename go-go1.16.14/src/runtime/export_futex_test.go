@@ -6,14 +6,26 @@
 
 package runtime
 
+import "runtime/internal/atomic"
+
 var Futexwakeup = futexwakeup
 
+// futexsleepers counts the goroutines in Futexsleep, so that the last
+// one to leave restores asyncpreemptoff. Saving and restoring it in
+// each call would leave it set if the calls overlap.
+var (
+	futexsleepers  uint32
+	futexsleepPoff = debug.asyncpreemptoff
+)
+
 //go:nosplit
 func Futexsleep(addr *uint32, val uint32, ns int64) {
 	// Temporarily disable preemption so that a preemption signal
 	// doesn't interrupt the system call.
-	poff := debug.asyncpreemptoff
+	atomic.Xadd(&futexsleepers, 1)
 	debug.asyncpreemptoff = 1
 	futexsleep(addr, val, ns)
-	debug.asyncpreemptoff = poff
+	if atomic.Xadd(&futexsleepers, -1) == 0 {
+		debug.asyncpreemptoff = futexsleepPoff
+	}
 }
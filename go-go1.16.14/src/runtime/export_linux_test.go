@@ -17,3 +17,15 @@ type EpollEvent epollevent
 func Epollctl(epfd, op, fd int32, ev unsafe.Pointer) int32 {
 	return epollctl(epfd, op, fd, (*epollevent)(ev))
 }
+
+var (
+	ParseCgroup2Path  = parseCgroup2Path
+	ParseCgroupUint   = parseCgroupUint
+	ParseMemoryEvents = parseMemoryEvents
+	ParsePSISomeAvg10 = parsePSISomeAvg10
+	ParseCPUMax       = parseCPUMax
+)
+
+func LockPIEnabled() bool {
+	return lockPI
+}
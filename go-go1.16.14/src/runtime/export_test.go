@@ -47,6 +47,7 @@ var Memmove = memmove
 var MemclrNoHeapPointers = memclrNoHeapPointers
 
 const PreemptMSupported = preemptMSupported
+const LoopPreemptChecks = loopPreemptChecks
 
 type LFNode struct {
 	Next    uint64
@@ -441,6 +442,20 @@ func (rw *RWMutex) Unlock() {
 	rw.rw.unlock()
 }
 
+// Mutex is a runtime lock. Goroutines that hold one cannot be
+// preempted, so they must not block while holding it.
+type Mutex struct {
+	l mutex
+}
+
+func (l *Mutex) Lock() {
+	lock(&l.l)
+}
+
+func (l *Mutex) Unlock() {
+	unlock(&l.l)
+}
+
 const RuntimeHmapSize = unsafe.Sizeof(hmap{})
 
 func MapBucketsCount(m map[int]int) int {
@@ -1226,3 +1241,120 @@ func (th *TimeHistogram) Count(bucket, subBucket uint) (uint64, bool) {
 func (th *TimeHistogram) Record(duration int64) {
 	(*timeHistogram)(th).record(duration)
 }
+
+// DebugLayout returns the bytes of the debugger layout blob.
+func DebugLayout() []byte {
+	return (*[unsafe.Sizeof(debugLayout)]byte)(unsafe.Pointer(&debugLayout))[:]
+}
+
+// ProcStats returns the number of Ps created and not yet destroyed,
+// which is len(allp), and the number of them running Go code.
+func ProcStats() (created, running int) {
+	lock(&allpLock)
+	for _, p := range allp {
+		if atomic.Load(&p.status) == _Prunning {
+			running++
+		}
+	}
+	created = len(allp)
+	unlock(&allpLock)
+	return
+}
+
+// InjectGoroutines starts n goroutines, waits for them to park, readies
+// them all with one injectglist and waits for them to run. It returns
+// the change of the statistics of injectglist meanwhile.
+func InjectGoroutines(n int) (batches, goroutines, wakeups uint64) {
+	s := new(struct {
+		lock mutex
+		gs   []*g
+		ran  uint32
+	})
+	for i := 0; i < n; i++ {
+		go func() {
+			gp := getg()
+			lock2(&s.lock)
+			s.gs = append(s.gs, gp)
+			unlock2(&s.lock)
+			gopark(nil, nil, waitReasonZero, traceEvGoBlock, 1)
+			atomic.Xadd(&s.ran, 1)
+		}()
+	}
+	for {
+		parked := 0
+		lock2(&s.lock)
+		for _, gp := range s.gs {
+			if readgstatus(gp) == _Gwaiting {
+				parked++
+			}
+		}
+		unlock2(&s.lock)
+		if parked == n {
+			break
+		}
+		Gosched()
+	}
+
+	b0, g0, w0 := atomic.Load64(&injectBatches), atomic.Load64(&injectGoroutines), atomic.Load64(&injectWakeups)
+	var list gList
+	for _, gp := range s.gs {
+		list.push(gp)
+	}
+	injectglist(&list)
+	for atomic.Load(&s.ran) != uint32(n) {
+		Gosched()
+	}
+	return atomic.Load64(&injectBatches) - b0, atomic.Load64(&injectGoroutines) - g0, atomic.Load64(&injectWakeups) - w0
+}
+
+// ItabInit fills in a new itab for the interface type iface points to
+// and the dynamic type of x, as getitab does the first time the pair
+// is asserted, and reports whether x implements the interface.
+func ItabInit(iface, x interface{}) bool {
+	inter := (*interfacetype)(unsafe.Pointer((*ptrtype)(unsafe.Pointer(efaceOf(&iface)._type)).elem))
+	typ := efaceOf(&x)._type
+	if typ.tflag&tflagUncommon == 0 {
+		return false
+	}
+	buf := make([]uintptr, unsafe.Sizeof(itab{})/sys.PtrSize+uintptr(len(inter.mhdr)))
+	m := (*itab)(unsafe.Pointer(&buf[0]))
+	m.inter = inter
+	m._type = typ
+	return m.init() == ""
+}
+
+// Symbolize returns the file, line and inline tree index of pc, as
+// symbolize does, using the symbolization cache.
+func Symbolize(pc uintptr) (file string, line int, inl int) {
+	f, l, ix := symbolize(findfunc(pc), pc, false)
+	return f, int(l), int(ix)
+}
+
+// SymbolizeUncached is like Symbolize but always decodes the tables.
+func SymbolizeUncached(pc uintptr) (file string, line int, inl int) {
+	f := findfunc(pc)
+	if !f.valid() {
+		return "?", 0, -1
+	}
+	fileno, _ := pcvalue(f, f.pcfile, pc, nil, false)
+	l, _ := pcvalue(f, f.pcln, pc, nil, false)
+	inl = -1
+	if funcdata(f, _FUNCDATA_InlTree) != nil {
+		inl = int(pcdatavalue1(f, _PCDATA_InlTreeIndex, pc, nil, false))
+	}
+	if fileno == -1 || l == -1 {
+		return "?", 0, inl
+	}
+	return funcfile(f, fileno), int(l), inl
+}
+
+// SymCacheStats returns the hits and misses of the symbolization cache.
+func SymCacheStats() (hits, misses uint64) {
+	return atomic.Load64(&symCache.hits), atomic.Load64(&symCache.misses)
+}
+
+const (
+	SyncMutexSemaOffset        = syncMutexSemaOffset
+	SyncRWMutexReaderSemOffset = syncRWMutexReaderSemOffset
+	SyncRWMutexWriterSemOffset = syncRWMutexWriterSemOffset
+)
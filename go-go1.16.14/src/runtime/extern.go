@@ -36,7 +36,41 @@ It is a comma-separated list of name=val pairs setting these named variables:
 	Setting cgocheck=1 (the default) enables relatively cheap
 	checks that may miss some errors.  Setting cgocheck=2 enables
 	expensive checks that should not miss any errors, but will
-	cause your program to run slower.
+	cause your program to run slower. The level can be changed while
+	the program runs with runtime/debug.SetCgoCheck.
+
+	cpuquota: setting cpuquota=1 makes the runtime read the CPU quota of the
+	process's cgroup v2 CPU controller (cpu.max) on Linux, and throttle itself
+	to stay within it: once the Ps have used as much of the quota of a period
+	as leaves only enough for the whole number of CPUs it allows, the Ps above
+	that number park until the period ends. This lets a program use a
+	fractional quota, such as 2.5 CPUs, with GOMAXPROCS=3 without the kernel
+	stopping all its threads in the middle of a period. The garbage collector
+	also aims to use 25% of the quota rather than of GOMAXPROCS. The quota can
+	be changed while the program runs with runtime/debug.SetCPUQuota.
+
+	deadlockdetect: setting deadlockdetect=1 makes the runtime track the owners of
+	locked sync.Mutex values and periodically look for groups of goroutines blocked
+	on each other's Mutex or RWMutex, even while other goroutines keep running.
+	Each such partial deadlock is reported once on standard error together with
+	the stacks of the goroutines involved. Setting deadlockdetect=2 also crashes
+	the program after the report. Deadlocks involving channels are not detected.
+	A Mutex is only tracked once a goroutine has had to wait for it, so a deadlock
+	on the first contention of a Mutex is not detected either. With deadlockdetect
+	set, tracebacks also show goroutines blocked in sync.Mutex.Lock,
+	sync.RWMutex.RLock and sync.RWMutex.Lock as such, rather than as "semacquire".
+
+	detsched: setting detsched=N for a nonzero seed N makes the interleaving of
+	goroutines reproducible from N, so that a flaky concurrency test can be rerun
+	with the seed that made it fail. The program runs on a single processor
+	(GOMAXPROCS is 1 and cannot be changed), the next goroutine to run is chosen
+	at random from those runnable using N, select chooses among ready cases using N
+	(unless schedseed is set), and goroutines are not preempted
+	for running too long, so a goroutine spinning until another one runs never
+	ends. Time is virtual, as on the playground: the clock stands still while any
+	goroutine can run and jumps to the next timer when none can; on Windows
+	time.Now still reads the real clock. Goroutines woken by system calls, cgo,
+	network I/O or signals may still run at times that depend on the outside world.
 
 	efence: setting efence=1 causes the allocator to run in a mode
 	where each object is allocated on a unique page and addresses are
@@ -91,11 +125,34 @@ It is a comma-separated list of name=val pairs setting these named variables:
 		# bytes     memory allocated on the heap
 		# allocs    number of heap allocations
 
+	lockpi: setting lockpi=1 makes the runtime's internal locks use
+	priority-inheritance futexes on Linux, if the kernel supports them. A
+	thread that waits for a lock then lends its priority to the thread that
+	holds it, which keeps that thread from being descheduled in favor of the
+	waiters when there are more threads than CPUs. Contention on the internal
+	locks is reported by the runtime/metrics package under /sched/lock. Other
+	systems ignore this setting.
+
+	lockrank: setting lockrank=1 makes the runtime check that its internal locks
+	are always acquired in a consistent order, and crash with "lock ordering
+	problem" if they are not. This is the check enabled by building with
+	GOEXPERIMENT=staticlockranking; it slows down every runtime lock.
+
 	madvdontneed: setting madvdontneed=0 will use MADV_FREE
 	instead of MADV_DONTNEED on Linux when returning memory to the
 	kernel. This is more efficient, but means RSS numbers will
 	drop only when the OS is under memory pressure.
 
+	memorypressure: setting memorypressure=1 makes the runtime watch for memory
+	pressure reported by the operating system and respond with a full garbage
+	collection that also returns free memory to the operating system, as
+	debug.FreeOSMemory does. On Linux the runtime samples the cgroup v2 memory
+	controller of the process once a second, and acts when usage exceeds 90% of
+	memory.max, when memory.events reports new high or max events, or when
+	memory.pressure shows tasks stalled on memory at least 10% of the time.
+	Such collections run at most once every 10 seconds and are reported as
+	forced. Other systems ignore this setting.
+
 	memprofilerate: setting memprofilerate=X will update the value of runtime.MemProfileRate.
 	When set to 0 memory profiling is disabled.  Refer to the description of
 	MemProfileRate for the default value.
@@ -130,9 +187,36 @@ It is a comma-separated list of name=val pairs setting these named variables:
 	detailed multiline info every X milliseconds, describing state of the scheduler,
 	processors, threads and goroutines.
 
+	schedrecord: setting schedrecord=N makes the scheduler record its last N events,
+	a goroutine starting to run on a processor or being preempted, for
+	runtime/debug.ReadSchedRecord.
+
+	schedreplay: with detsched, setting schedreplay=file makes the scheduler run
+	goroutines in the order recorded in file, which holds a log returned by
+	runtime/debug.ReadSchedRecord in an earlier run of the program with detsched and
+	with schedrecord large enough to keep every event. This reproduces an interleaving
+	even after changes to the program that change the order drawn from the seed.
+	As soon as a goroutine other than the recorded one runs, the runtime reports it
+	on standard error and goes back to the seed.
+
+	schedseed: setting schedseed=N for a nonzero seed N seeds the random choices of
+	the scheduler and of select with N instead of with random data: the order in
+	which a processor looks for work to steal, and the case a select chooses among
+	those ready. Each thread draws from its own sequence, derived from N and the
+	thread's ID, so a program whose goroutines run on the same threads in the same
+	order, for example with GOMAXPROCS=1, repeats the same choices, and a failing
+	interleaving can be retried with the same dice. Map iteration order and heap
+	profile sampling, which use the same source, follow N as well.
+
 	schedtrace: setting schedtrace=X causes the scheduler to emit a single line to standard
 	error every X milliseconds, summarizing the scheduler state.
 
+	schedtracejson: setting schedtrace=X and schedtracejson=1 causes the scheduler to emit
+	the summary of schedtrace as a line of JSON instead, such as
+		{"time_ms":1000,"gomaxprocs":4,"idleprocs":3,"threads":6,"spinningthreads":0,"idlethreads":2,"runqueue":0,"runqs":[0,1,0,0]}
+	where runqs holds the length of the run queue of each processor. It takes precedence
+	over scheddetail. schedtrace=json is short for schedtrace=1000,schedtracejson=1.
+
 	tracebackancestors: setting tracebackancestors=N extends tracebacks with the stacks at
 	which goroutines were created, where N limits the number of ancestor goroutines to
 	report. This also extends the information returned by runtime.Stack. Ancestor's goroutine
@@ -146,6 +230,25 @@ It is a comma-separated list of name=val pairs setting these named variables:
 	because it also disables the conservative stack scanning used
 	for asynchronously preempted goroutines.
 
+	sigstacksize: setting sigstacksize=N makes the signal stack of every thread at least
+	N bytes, rounded up to a power of two. The default is 32 KB. Signal handlers installed
+	by C code, and cgo callbacks made from them, may need more.
+
+	stwwatchdog: setting stwwatchdog=N makes the runtime report on standard error
+	whenever stopping the world has waited more than N milliseconds for the running
+	goroutines to stop. The report names the reason for stopping the world and, for
+	each processor still running, its thread, the goroutine on it, and why the thread
+	cannot be preempted, if it holds runtime locks. On Unix systems the threads then
+	print the stacks of what they are running. A goroutine looping without function
+	calls, in assembly or under asyncpreemptoff=1, is the usual culprit; see
+	runtime.Safepoint.
+
+	waitsince: setting waitsince=1 makes every goroutine record the time at which it
+	blocked, at the cost of reading the clock each time one does. Without it the garbage
+	collector records the time only when it first finds a goroutine blocked, so tracebacks,
+	goroutine profiles and runtime.Goroutines know only roughly how long a goroutine has
+	been waiting, and nothing about goroutines that blocked since the last collection.
+
 The net, net/http, and crypto/tls packages also refer to debugging variables in GODEBUG.
 See the documentation for those packages for details.
 
@@ -247,6 +350,42 @@ func Version() string {
 	return sys.TheVersion
 }
 
+// A BuildConfig describes how the running program was built, as far as
+// the runtime knows it.
+type BuildConfig struct {
+	// Mode is the build mode of the program, as the -buildmode flag
+	// of go build names it: "exe", "pie", "c-archive", "c-shared" or,
+	// for a program loaded with a Go plugin, that of the program that
+	// loaded it. It is "" if the linker did not record it, as for a
+	// program linked against Go shared libraries.
+	Mode string
+
+	// Library reports whether the program is a library for a C
+	// program, built with -buildmode=c-archive or c-shared. Its main
+	// function does not run, and the C program may have its own
+	// signal handlers.
+	Library bool
+
+	// FramePointers reports whether Go code maintains frame pointers.
+	FramePointers bool
+
+	Race bool // built with -race
+	MSan bool // built with -msan
+	Cgo  bool // runtime/cgo is linked in
+}
+
+// ReadBuildConfig returns how the running program was built.
+func ReadBuildConfig() BuildConfig {
+	return BuildConfig{
+		Mode:          buildMode,
+		Library:       islibrary || isarchive,
+		FramePointers: framepointer_enabled,
+		Race:          raceenabled,
+		MSan:          msanenabled,
+		Cgo:           iscgo,
+	}
+}
+
 // GOOS is the running program's operating system target:
 // one of darwin, freebsd, linux, and so on.
 // To view possible combinations of GOOS and GOARCH, run "go tool dist list".
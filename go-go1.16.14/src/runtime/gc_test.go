@@ -158,6 +158,30 @@ func TestHugeGCInfo(t *testing.T) {
 	}
 }
 
+func TestGCAsync(t *testing.T) {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	numGC, numForced := ms.NumGC, ms.NumForcedGC
+
+	// Concurrent requests are all notified.
+	done1 := runtime.GCAsync()
+	done2 := runtime.GCAsync()
+	for _, done := range []<-chan struct{}{done1, done2} {
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatal("GCAsync did not complete in 10s")
+		}
+	}
+	runtime.ReadMemStats(&ms)
+	if ms.NumGC <= numGC {
+		t.Errorf("NumGC is %d after GCAsync, was %d", ms.NumGC, numGC)
+	}
+	if ms.NumForcedGC <= numForced {
+		t.Errorf("NumForcedGC is %d after GCAsync, was %d", ms.NumForcedGC, numForced)
+	}
+}
+
 func TestPeriodicGC(t *testing.T) {
 	if runtime.GOARCH == "wasm" {
 		t.Skip("no sysmon on wasm yet")
@@ -0,0 +1,213 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Central cache of dead Gs.
+//
+// Each P caches the Gs of goroutines that have exited, for gfget to
+// reuse. gfput moves half of a P's cache to the central cache once it
+// holds 64 Gs, and gfget refills an empty P cache with up to 32 Gs from
+// the central cache. As with the central sudog cache, the central
+// cache is sharded by P ID: a P moves Gs to its own shard, and refills
+// from its own shard first and then from the others, skipping the
+// shards that are empty without locking them.
+//
+// The GC frees the stacks of the Gs in the central cache at each cycle
+// (see markrootFreeGStacks). The Gs that are already without a stack
+// by then went unused for a whole cycle, so that cycle retires them
+// instead: it takes them out of the central cache, and the background
+// sweeper drops them from allgs once the cycle is over, leaving them
+// to the garbage collector. This way a spike in the number of
+// goroutines does not keep its g structures forever.
+
+package runtime
+
+import (
+	"internal/cpu"
+	"runtime/internal/atomic"
+	"unsafe"
+)
+
+// gFreeShards is the number of shards of the central cache of dead Gs.
+const gFreeShards = 16
+
+type gFreeShard struct {
+	lock countedLock // first, for the alignment of its counter
+
+	// Counters, written with lock held and read atomically.
+	refills uint64 // refills of a per-P cache served from this shard
+	steals  uint64 // of which for Ps of other shards
+	spills  uint64 // moves of Gs from a per-P cache to this shard
+
+	stack   gList  // Gs with stacks
+	noStack gList  // Gs without stacks
+	n       uint32 // number of Gs; written with lock held, read atomically
+}
+
+var gFreeCentral [gFreeShards]struct {
+	shard gFreeShard
+	pad   [cpu.CacheLinePadSize - unsafe.Sizeof(gFreeShard{})%cpu.CacheLinePadSize]byte
+}
+
+var gRetired struct {
+	released uint64 // Gs dropped from allgs; written with allglock held, read atomically
+	pending  uint32 // Gs retired but still in allgs; written with allglock held, read atomically
+}
+
+// gFreeShardOf returns the shard of P pp.
+func gFreeShardOf(pp *p) *gFreeShard {
+	return &gFreeCentral[int(pp.id)%gFreeShards].shard
+}
+
+// put adds gp to s. s.lock must be held.
+func (s *gFreeShard) put(gp *g) {
+	if gp.stack.lo == 0 {
+		s.noStack.push(gp)
+	} else {
+		s.stack.push(gp)
+	}
+	atomic.Store(&s.n, s.n+1)
+}
+
+// get removes a G from s, preferring Gs with stacks, or returns nil
+// if s is empty. s.lock must be held.
+func (s *gFreeShard) get() *g {
+	gp := s.stack.pop()
+	if gp == nil {
+		gp = s.noStack.pop()
+		if gp == nil {
+			return nil
+		}
+	}
+	atomic.Store(&s.n, s.n-1)
+	return gp
+}
+
+// gFreeRefill moves up to 32 Gs from the central cache to the empty
+// cache of pp.
+func gFreeRefill(pp *p) {
+	home := int(pp.id) % gFreeShards
+	for i := 0; i < gFreeShards && pp.gFree.empty(); i++ {
+		s := &gFreeCentral[(home+i)%gFreeShards].shard
+		if atomic.Load(&s.n) == 0 {
+			continue
+		}
+		s.lock.lock()
+		n := pp.gFree.n
+		for pp.gFree.n < 32 {
+			gp := s.get()
+			if gp == nil {
+				break
+			}
+			pp.gFree.push(gp)
+			pp.gFree.n++
+		}
+		if pp.gFree.n != n {
+			atomic.Store64(&s.refills, s.refills+1)
+			if i > 0 {
+				atomic.Store64(&s.steals, s.steals+1)
+			}
+		}
+		s.lock.unlock()
+	}
+}
+
+// retireGs retires the Gs of list, which have been taken from shard s.
+// They stay in allgs until releaseRetiredGs.
+func retireGs(s *gFreeShard, list gList) {
+	if list.empty() {
+		return
+	}
+	n := uint32(0)
+	lock(&allglock)
+	for !list.empty() {
+		gp := list.pop()
+		gp.schedlink = 0
+		gp.retired = true
+		n++
+	}
+	atomic.Store(&gRetired.pending, gRetired.pending+n)
+	unlock(&allglock)
+
+	s.lock.lock()
+	atomic.Store(&s.n, s.n-n)
+	s.lock.unlock()
+}
+
+// releaseRetiredGs drops the retired Gs from allgs.
+//
+// It compacts allgs in place and only lowers allglen, so that readers
+// that do not lock allglock (see atomicAllG) keep indexing the same
+// array: one that loaded the previous length finds nil past the new
+// one. Such a reader walking allgs can also miss a G moved to an index
+// it has passed. The goroutine profile must visit every G, so nothing
+// is released while it holds its semaphore.
+func releaseRetiredGs() {
+	if atomic.Load(&gRetired.pending) == 0 {
+		return
+	}
+	if !cansemacquire(&goroutineProfile.sema) {
+		// Try again after the next cycle.
+		return
+	}
+	lock(&allglock)
+	// The mark phase indexes allgs without allglock. It cannot start
+	// while we hold the lock, since starting it stops the world.
+	if gcphase != _GCoff {
+		unlock(&allglock)
+		semrelease(&goroutineProfile.sema)
+		return
+	}
+	n := gRetired.pending
+	keep := 0
+	for _, gp := range allgs {
+		if !gp.retired {
+			allgs[keep] = gp
+			keep++
+		}
+	}
+	for i := keep; i < len(allgs); i++ {
+		allgs[i] = nil
+	}
+	allgs = allgs[:keep]
+	atomic.Storeuintptr(&allglen, uintptr(keep))
+	atomic.Store(&gRetired.pending, 0)
+	atomic.Store64(&gRetired.released, gRetired.released+uint64(n))
+	unlock(&allglock)
+	semrelease(&goroutineProfile.sema)
+}
+
+// gFreeRetainedBytes returns the memory taken by the g structures of
+// dead Gs that are cached or not yet released. The result may be
+// inconsistent, like gcount.
+func gFreeRetainedBytes() uint64 {
+	n := int64(gFreeCount()) + int64(atomic.Load(&gRetired.pending))
+	for _, pp := range allp {
+		n += int64(pp.gFree.n)
+	}
+	if n < 0 {
+		n = 0
+	}
+	return uint64(n) * uint64(unsafe.Sizeof(g{}))
+}
+
+// gFreeCount returns the number of Gs in the central cache.
+func gFreeCount() int32 {
+	n := int32(0)
+	for i := range gFreeCentral {
+		n += int32(atomic.Load(&gFreeCentral[i].shard.n))
+	}
+	return n
+}
+
+// readGFreeStats returns the sums of the counters of the shards.
+func readGFreeStats() (refills, steals, spills, contended uint64) {
+	for i := range gFreeCentral {
+		s := &gFreeCentral[i].shard
+		refills += atomic.Load64(&s.refills)
+		steals += atomic.Load64(&s.steals)
+		spills += atomic.Load64(&s.spills)
+		contended += atomic.Load64(&s.lock.contended)
+	}
+	return
+}
@@ -0,0 +1,208 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+import (
+	"runtime/internal/atomic"
+	"unsafe"
+)
+
+// Goroutine groups attribute the resources used by goroutines to an
+// ID chosen by the program, such as a tenant of a server. A goroutine
+// joins a group with SetGoroutineGroup and the goroutines it starts
+// join the same group. Each group counts its goroutines, the CPU time
+// they spend running Go code and the memory they allocate.
+//
+// CPU time is measured from execute until the goroutine stops running
+// (dropg) or enters a system call, and is credited to the group at
+// those points, so ReadGoroutineGroupStats does not see the time of
+// goroutines running at the moment, other than the caller's.
+
+// A goroutineGroup holds the counters of one group. Groups are never
+// freed, so that their totals survive their goroutines.
+//
+//go:notinheap
+type goroutineGroup struct {
+	// The counters are accessed atomically. They come first to
+	// keep them 8-byte aligned on 32-bit systems.
+	goroutines   int64
+	cpuTime      int64
+	allocBytes   uint64
+	allocObjects uint64
+
+	id      uint64
+	next    *goroutineGroup // next in the hash chain
+	allnext *goroutineGroup // next in goroutineGroups.all
+}
+
+const goroutineGroupHashSize = 256
+
+var goroutineGroups struct {
+	lock mutex
+	hash [goroutineGroupHashSize]*goroutineGroup
+	all  *goroutineGroup
+	n    int
+
+	// used is set once a goroutine has joined a group, so that
+	// mallocgc need not look at the group of every goroutine
+	// otherwise. Accessed atomically.
+	used uint32
+}
+
+// lookupGoroutineGroup returns the group with ID id, creating it if
+// it does not exist yet.
+func lookupGoroutineGroup(id uint64) *goroutineGroup {
+	h := &goroutineGroups.hash[id%goroutineGroupHashSize]
+	lock(&goroutineGroups.lock)
+	gg := findGoroutineGroup(*h, id)
+	unlock(&goroutineGroups.lock)
+	if gg != nil {
+		return gg
+	}
+
+	// Allocate without holding goroutineGroups.lock, which is a leaf
+	// lock, and check again in case another goroutine created the
+	// group in the meantime. The loser's allocation is leaked.
+	gg = (*goroutineGroup)(persistentalloc(unsafe.Sizeof(goroutineGroup{}), 8, &memstats.other_sys))
+	gg.id = id
+	lock(&goroutineGroups.lock)
+	if g1 := findGoroutineGroup(*h, id); g1 != nil {
+		gg = g1
+	} else {
+		gg.next = *h
+		*h = gg
+		gg.allnext = goroutineGroups.all
+		goroutineGroups.all = gg
+		goroutineGroups.n++
+	}
+	unlock(&goroutineGroups.lock)
+	return gg
+}
+
+// findGoroutineGroup returns the group with ID id in the hash chain
+// starting at gg, or nil. goroutineGroups.lock must be held.
+func findGoroutineGroup(gg *goroutineGroup, id uint64) *goroutineGroup {
+	for gg != nil && gg.id != id {
+		gg = gg.next
+	}
+	return gg
+}
+
+// SetGoroutineGroup puts the calling goroutine in the group with ID
+// id and returns the ID of its previous group. ID 0 means no group.
+// Goroutines start in the group of the goroutine that created them.
+//
+// Each group counts its goroutines, their CPU time and their memory
+// allocations, as reported by ReadGoroutineGroupStats. The counts of
+// a goroutine go to the group it is in at the time, so
+//
+//	defer runtime.SetGoroutineGroup(runtime.SetGoroutineGroup(tenant))
+//
+// attributes the rest of a function to tenant. The runtime keeps the
+// totals of every group ever used for the life of the program, so
+// IDs should come from a bounded set.
+func SetGoroutineGroup(id uint64) (prev uint64) {
+	gp := getg()
+	old := gp.group
+	if old != nil {
+		prev = old.id
+	}
+	if id == prev {
+		return prev
+	}
+	var gg *goroutineGroup
+	if id != 0 {
+		gg = lookupGoroutineGroup(id)
+		atomic.Store(&goroutineGroups.used, 1)
+	}
+	mp := acquirem() // keep dropg from crediting the CPU time meanwhile
+	now := nanotime()
+	if old != nil {
+		atomic.Xaddint64(&old.cpuTime, now-gp.groupwhen)
+		atomic.Xaddint64(&old.goroutines, -1)
+	}
+	gp.group = gg
+	gp.groupwhen = 0
+	if gg != nil {
+		atomic.Xaddint64(&gg.goroutines, 1)
+		gp.groupwhen = now
+	}
+	releasem(mp)
+	return prev
+}
+
+// groupStop credits gp's group with the CPU time gp has used since it
+// last started running. gp is about to stop running or to enter a
+// system call.
+//
+//go:nosplit
+func groupStop(gp *g) {
+	atomic.Xaddint64(&gp.group.cpuTime, nanotime()-gp.groupwhen)
+	gp.groupwhen = 0
+}
+
+// groupExit takes the exiting goroutine gp out of its group.
+func groupExit(gp *g) {
+	if gp.groupwhen != 0 {
+		groupStop(gp)
+	}
+	atomic.Xaddint64(&gp.group.goroutines, -1)
+	gp.group = nil
+}
+
+// groupMalloc adds an allocation of size bytes to the group of the
+// current goroutine, if it is in one.
+func groupMalloc(size uintptr) {
+	if gp := getg().m.curg; gp != nil && gp.group != nil {
+		atomic.Xadd64(&gp.group.allocBytes, int64(size))
+		atomic.Xadd64(&gp.group.allocObjects, 1)
+	}
+}
+
+// GoroutineGroupStats holds the totals of a goroutine group, as set
+// by SetGoroutineGroup.
+type GoroutineGroupStats struct {
+	Group        uint64 // group ID
+	Goroutines   int64  // number of goroutines in the group now
+	CPUTime      int64  // nanoseconds spent running Go code
+	AllocBytes   uint64 // bytes allocated
+	AllocObjects uint64 // objects allocated
+}
+
+// ReadGoroutineGroupStats returns n, the number of goroutine groups
+// used so far. If len(p) >= n, ReadGoroutineGroupStats copies the
+// totals of the groups into p and returns n, true. If len(p) < n,
+// ReadGoroutineGroupStats does not change p and returns n, false.
+//
+// CPU time is counted while goroutines run Go code, not while they
+// wait or are in system calls or cgo calls, and is credited to the
+// group when a goroutine stops running. The totals of a group
+// never decrease, except for Goroutines.
+func ReadGoroutineGroupStats(p []GoroutineGroupStats) (n int, ok bool) {
+	if gp := getg(); gp.group != nil {
+		mp := acquirem()
+		groupStop(gp)
+		gp.groupwhen = nanotime()
+		releasem(mp)
+	}
+	lock(&goroutineGroups.lock)
+	n = goroutineGroups.n
+	if n <= len(p) {
+		ok = true
+		i := 0
+		for gg := goroutineGroups.all; gg != nil; gg = gg.allnext {
+			p[i] = GoroutineGroupStats{
+				Group:        gg.id,
+				Goroutines:   atomic.Loadint64(&gg.goroutines),
+				CPUTime:      atomic.Loadint64(&gg.cpuTime),
+				AllocBytes:   atomic.Load64(&gg.allocBytes),
+				AllocObjects: atomic.Load64(&gg.allocObjects),
+			}
+			i++
+		}
+	}
+	unlock(&goroutineGroups.lock)
+	return
+}
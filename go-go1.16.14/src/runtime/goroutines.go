@@ -0,0 +1,150 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+// A GoroutineInfo describes a goroutine, as reported by Goroutines.
+type GoroutineInfo struct {
+	ID    int64  // goroutine ID
+	State string // "running", "runnable", "waiting", "syscall", and so on
+
+	// WaitReason describes why a goroutine in the waiting state is
+	// blocked, such as "chan receive" or "select", as printed in
+	// tracebacks. It is empty for goroutines that are not waiting.
+	WaitReason string
+
+	// WaitSince is the approximate time, in nanoseconds since the Unix
+	// epoch, at which a waiting goroutine or one in a system call
+	// blocked. The garbage collector records it the first time it
+	// finds the goroutine blocked, so it is 0 until then and is never
+	// earlier than the start of that collection. With
+	// GODEBUG=waitsince=1, goroutines record the exact time at which
+	// they start waiting, though not when they enter a system call.
+	WaitSince int64
+
+	// WaitTime is how long, in nanoseconds, the goroutine had been
+	// blocked since WaitSince when Goroutines stopped the world, or 0
+	// if WaitSince is 0. It is measured with the monotonic clock, so
+	// unlike WaitSince it is unaffected by changes to the wall clock,
+	// and sorting by it in decreasing order ranks the longest-blocked
+	// goroutines first.
+	WaitTime int64
+
+	CreatorID int64   // ID of the goroutine that created this one, or 0 if the runtime did
+	CreatorPC uintptr // PC of the go statement that created this goroutine
+	StartPC   uintptr // entry PC of the goroutine's function
+	PC        uintptr // PC at which the goroutine will resume, or 0 if it is running
+
+	// CgoCalls and CgoTime are the number of cgo calls made by the
+	// goroutine and the time, in nanoseconds, spent in them, as
+	// reported by GoroutineCgoCalls.
+	CgoCalls int64
+	CgoTime  int64
+
+	// Ancestors continues the chain of creators past the creator:
+	// Ancestors[0] is the goroutine that created the creator,
+	// Ancestors[1] the one that created Ancestors[0], and so on.
+	// The chain is recorded for every goroutine, so it is available
+	// even if the ancestors have exited, but it ends after three
+	// generations or at a goroutine created by the runtime. Unused
+	// entries are zero.
+	Ancestors [3]GoroutineAncestor
+}
+
+// A GoroutineAncestor identifies a goroutine in the chain of creators
+// of another goroutine.
+type GoroutineAncestor struct {
+	ID int64   // goroutine ID
+	PC uintptr // PC of the go statement by which it created the next goroutine in the chain
+}
+
+// Goroutines returns n, the number of goroutines that currently
+// exist, as counted by NumGoroutine. If len(p) >= n, Goroutines
+// describes each of them in an element of p, starting with the calling
+// goroutine, and returns n, true. If len(p) < n, Goroutines does not
+// change p and returns n, false.
+//
+// Goroutines reports the state of each goroutine without formatting
+// its stack, so it is much cheaper than Stack(buf, true), but it
+// briefly stops the world like GoroutineProfile.
+func Goroutines(p []GoroutineInfo) (n int, ok bool) {
+	gp := getg()
+
+	isOK := func(gp1 *g) bool {
+		// Checking isSystemGoroutine here makes Goroutines
+		// consistent with NumGoroutine.
+		return gp1 != gp && readgstatus(gp1) != _Gdead && !isSystemGoroutine(gp1, false)
+	}
+
+	stopTheWorld("goroutines")
+
+	n = 1
+	for _, gp1 := range allgs {
+		if isOK(gp1) {
+			n++
+		}
+	}
+
+	if n <= len(p) {
+		ok = true
+		now := nanotime()
+		sec, nsec, _ := time_now()
+		unixNow := sec*1e9 + int64(nsec)
+
+		goroutineInfo(&p[0], gp, now, unixNow)
+		i := 1
+		for _, gp1 := range allgs {
+			if isOK(gp1) {
+				goroutineInfo(&p[i], gp1, now, unixNow)
+				i++
+			}
+		}
+	}
+
+	startTheWorld()
+	return n, ok
+}
+
+// goroutineInfo fills in r with the state of gp. now is the current
+// nanotime and unixNow the current wall time. The world must be
+// stopped.
+func goroutineInfo(r *GoroutineInfo, gp *g, now, unixNow int64) {
+	status := readgstatus(gp) &^ _Gscan
+	r.ID = gp.goid
+	if status < uint32(len(gStatusStrings)) {
+		r.State = gStatusStrings[status]
+	} else {
+		r.State = "???"
+	}
+	r.WaitReason = ""
+	if status == _Gwaiting {
+		r.WaitReason = gp.waitreason.String()
+	}
+	r.WaitSince = 0
+	r.WaitTime = 0
+	if (status == _Gwaiting || status == _Gsyscall) && gp.waitsince != 0 {
+		r.WaitTime = now - gp.waitsince
+		r.WaitSince = unixNow - r.WaitTime
+	}
+	r.CreatorID = gp.parentGoid
+	r.CreatorPC = gp.gopc
+	r.StartPC = gp.startpc
+	r.CgoCalls = gp.cgoCalls
+	r.CgoTime = cgoTicksToNanos(gp.cgoTicks)
+	r.Ancestors = [lineageDepth]GoroutineAncestor{}
+	for i, a := range gp.lineage {
+		if a.goid == 0 {
+			break
+		}
+		r.Ancestors[i] = GoroutineAncestor{a.goid, a.gopc}
+	}
+	switch {
+	case status == _Grunning:
+		r.PC = 0
+	case gp.syscallsp != 0:
+		r.PC = gp.syscallpc
+	default:
+		r.PC = gp.sched.pc
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Random goroutine sampling.
+//
+// While SetGoroutineSampleRate has set a rate, a sampler goroutine
+// sleeps for random intervals averaging the sampling period, picks a
+// goroutine at random from allgs, suspends it at a safe point as the
+// GC does to scan its stack, and records its stack and state in the
+// goroutine sample profile. Only the goroutine picked is disturbed, so
+// unlike the goroutine profile the cost of a sample does not grow with
+// the number of goroutines. Over many samples the profile shows what
+// the goroutines of the process spend their time doing.
+//
+// The state is part of the bucket key, stored in the bucket size: the
+// goroutine status in the low byte and, for waiting goroutines, the
+// wait reason above it.
+
+package runtime
+
+import "runtime/internal/atomic"
+
+var gsample struct {
+	period  uint64 // average sampling period in nanoseconds, or 0
+	running uint32 // a sampler goroutine is running
+}
+
+// SetGoroutineSampleRate makes the runtime pick a goroutine at random,
+// whatever its state, hz times per second on average, and record its
+// stack and state in the goroutine sample profile. The profile counts
+// how often goroutines were found at each stack in each state, a cheap
+// statistical view of what the process is doing.
+//
+// To turn off sampling, pass hz <= 0.
+func SetGoroutineSampleRate(hz int) {
+	if hz > 1e9 {
+		hz = 1e9
+	}
+	var period uint64
+	if hz > 0 {
+		period = uint64(1e9 / hz)
+	}
+	atomic.Store64(&gsample.period, period)
+	if period != 0 && atomic.Cas(&gsample.running, 0, 1) {
+		go gsampler()
+	}
+}
+
+// gsampler takes goroutine samples until sampling is turned off.
+func gsampler() {
+	for {
+		period := atomic.Load64(&gsample.period)
+		if period == 0 {
+			atomic.Store(&gsample.running, 0)
+			// SetGoroutineSampleRate may have turned sampling back
+			// on without starting a sampler, seeing this one still
+			// running.
+			if atomic.Load64(&gsample.period) == 0 || !atomic.Cas(&gsample.running, 0, 1) {
+				return
+			}
+			continue
+		}
+		// Spread the samples uniformly over [period/2, 3*period/2)
+		// so that they don't fall in step with periodic work.
+		timeSleep(int64(period/2 + uint64(fastrandn(uint32(period)))))
+		if atomic.Load64(&gsample.period) != 0 {
+			sampleGoroutine()
+		}
+	}
+}
+
+// sampleGoroutine records a goroutine picked at random in the goroutine
+// sample profile. It gives up if it picks only goroutines that are dead
+// or belong to the runtime a few times in a row.
+func sampleGoroutine() {
+	for tries := 0; tries < 4; tries++ {
+		ptr, n := atomicAllG()
+		if n == 0 {
+			return
+		}
+		gp := atomicAllGIndex(ptr, uintptr(fastrandn(uint32(n))))
+		if gp == nil || readgstatus(gp) == _Gdead || isSystemGoroutine(gp, false) {
+			continue
+		}
+		sampled := false
+		systemstack(func() {
+			sampled = doSampleGoroutine(gp)
+		})
+		if sampled {
+			return
+		}
+	}
+}
+
+// doSampleGoroutine suspends gp and records its stack and state in the
+// goroutine sample profile. It reports false if gp had exited.
+//
+//go:systemstack
+func doSampleGoroutine(gp *g) bool {
+	// suspendG requires the user goroutine of this M to be
+	// preemptible, as in doRecordGoroutineProfile.
+	userG := getg().m.curg
+	self := userG != nil && readgstatus(userG) == _Grunning
+	if self {
+		casgstatus(userG, _Grunning, _Gwaiting)
+		userG.waitreason = waitReasonGoroutineProfile
+	}
+
+	var r StackRecord
+	var state uintptr
+	stopped := suspendG(gp)
+	if !stopped.dead {
+		if stopped.stopped {
+			// gp was running, and suspendG stopped it.
+			state = _Grunning
+		} else {
+			state = uintptr(readgstatus(gp) &^ _Gscan)
+			if state == _Gwaiting {
+				state |= uintptr(gp.waitreason) << 8
+			}
+		}
+		saveg(^uintptr(0), ^uintptr(0), gp, &r)
+	}
+	resumeG(stopped)
+
+	if self {
+		casgstatus(userG, _Gwaiting, _Grunning)
+	}
+	if stopped.dead {
+		return false
+	}
+
+	lock(&proflock)
+	b := stkbucket(gsampleProfile, state, r.Stack(), true)
+	b.bp().count++
+	unlock(&proflock)
+	return true
+}
+
+// gsampleState returns the name of the goroutine state stored in the
+// size of a goroutine sample profile bucket.
+func gsampleState(size uintptr) string {
+	status := uint32(size & 0xff)
+	if status == _Gwaiting {
+		return waitReason(size >> 8).String()
+	}
+	if int(status) < len(gStatusStrings) {
+		return gStatusStrings[status]
+	}
+	return "???"
+}
@@ -0,0 +1,124 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file provides a recorder of goroutine status transitions. Each
+// transition made by casgstatus and the other cas*status functions is
+// recorded, with the goroutine, the old and new status and the caller,
+// in a global lock-free ring buffer. The runtime prints the recorded
+// transitions on a fatal error, and runtime/debug.WriteGStatusTrace
+// returns them on demand.
+//
+// This facility can be enabled by passing -tags gstatustrace when
+// building. Without this tag, the recording compiles to nothing.
+
+package runtime
+
+import (
+	"runtime/internal/atomic"
+	_ "unsafe" // for go:linkname
+)
+
+// A gstatusRecord is one goroutine status transition.
+type gstatusRecord struct {
+	// seq is 1 more than the position of the record in the trace
+	// once it is complete, and 0 while it is written.
+	seq  uint64
+	when int64
+	goid int64
+	pc   uint64
+	old  uint32
+	new  uint32
+}
+
+var gstatusTrace struct {
+	pos  uint64 // position of the next record; accessed atomically
+	recs [gstatusTraceRecords]gstatusRecord
+}
+
+// recordGStatus records the transition of gp from status oldval to
+// newval, requested by the function containing pc.
+//
+// Records are claimed with an atomic add, so any number of Ms may
+// record at once. A record that is overwritten while it is read is
+// detected by its sequence number and skipped.
+//go:nosplit
+func recordGStatus(gp *g, oldval, newval uint32, pc uintptr) {
+	i := atomic.Xadd64(&gstatusTrace.pos, 1) - 1
+	r := &gstatusTrace.recs[i%gstatusTraceRecords]
+	atomic.Store64(&r.seq, 0)
+	r.when = nanotime()
+	r.goid = gp.goid
+	r.pc = uint64(pc)
+	r.old = oldval
+	r.new = newval
+	atomic.Store64(&r.seq, i+1)
+}
+
+// printGStatusTrace prints the recorded transitions, oldest first.
+// It does not allocate, so it can be used while crashing.
+func printGStatusTrace() {
+	if !gstatusTraceEnabled {
+		return
+	}
+	end := atomic.Load64(&gstatusTrace.pos)
+	start := uint64(0)
+	if end > gstatusTraceRecords {
+		start = end - gstatusTraceRecords
+	}
+	print("goroutine status transitions (", end-start, " of ", end, "):\n")
+	for i := start; i < end; i++ {
+		r := &gstatusTrace.recs[i%gstatusTraceRecords]
+		if atomic.Load64(&r.seq) != i+1 {
+			continue
+		}
+		when, goid, pc, old, new := r.when, r.goid, uintptr(r.pc), r.old, r.new
+		if atomic.Load64(&r.seq) != i+1 {
+			continue
+		}
+		print(when, " goroutine ", goid, ": ")
+		printGStatus(old)
+		print(" -> ")
+		printGStatus(new)
+		if f := findfunc(pc); f.valid() {
+			print(" in ", funcname(f), "+", hex(pc-f.entry))
+		} else {
+			print(" at pc=", hex(pc))
+		}
+		print("\n")
+	}
+}
+
+// printGStatus prints the name of G status s.
+func printGStatus(s uint32) {
+	if st := s &^ _Gscan; st < uint32(len(gStatusStrings)) && gStatusStrings[st] != "" {
+		print(gStatusStrings[st])
+	} else {
+		print(hex(st))
+	}
+	if s&_Gscan != 0 {
+		print(" (scan)")
+	}
+}
+
+// gstatusTraceDump returns the output of printGStatusTrace, or nil if
+// the runtime was not built with -tags gstatustrace.
+//go:linkname gstatusTraceDump runtime/debug.gstatusTraceDump
+func gstatusTraceDump() []byte {
+	if !gstatusTraceEnabled {
+		return nil
+	}
+	for n := 64 << 10; ; n *= 2 {
+		buf := make([]byte, n)
+		systemstack(func() {
+			g0 := getg()
+			g0.writebuf = buf[0:0:len(buf)]
+			printGStatusTrace()
+			buf = g0.writebuf
+			g0.writebuf = nil
+		})
+		if len(buf) < n {
+			return buf
+		}
+	}
+}
@@ -197,6 +197,11 @@ func (m *itab) init() string {
 	// and interface names are unique,
 	// so can iterate over both in lock step;
 	// the loop is O(ni+nt) not O(ni*nt).
+	// Types have many more methods than most interfaces
+	// they are asserted to, so when many of typ's methods
+	// remain, skip ahead by binary search to the first one
+	// not sorting before inter's: like the compiler
+	// (types.Sym.Less), exported names first, then by name.
 	ni := len(inter.mhdr)
 	nt := int(x.mcount)
 	xmhdr := (*[1 << 16]method)(add(unsafe.Pointer(x), uintptr(x.moff)))[:nt:nt]
@@ -213,9 +218,30 @@ imethods:
 		if ipkg == "" {
 			ipkg = inter.pkgpath.name()
 		}
+		searched := false
+		if nt-j > itabSearchMin {
+			searched = true
+			iexported := name.isExported()
+			lo, hi := j, nt
+			for lo < hi {
+				h := int(uint(lo+hi) >> 1)
+				tname := typ.nameOff(xmhdr[h].name)
+				if methodNameLess(tname.isExported(), tname.name(), iexported, iname) {
+					lo = h + 1
+				} else {
+					hi = h
+				}
+			}
+			j = lo
+		}
 		for ; j < nt; j++ {
 			t := &xmhdr[j]
 			tname := typ.nameOff(t.name)
+			if searched && tname.name() != iname {
+				// Past the methods named iname, which
+				// sort together.
+				break
+			}
 			if typ.typeOff(t.mtyp) == itype && tname.name() == iname {
 				pkgPath := tname.pkgPath()
 				if pkgPath == "" {
@@ -242,6 +268,20 @@ imethods:
 	return ""
 }
 
+// itabSearchMin is the number of methods of a type left to scan above
+// which itab.init binary searches for the next method of an interface.
+const itabSearchMin = 32
+
+// methodNameLess reports whether a method named aname sorts before one
+// named bname, given whether each is exported, in the order of the
+// method tables of types and interfaces.
+func methodNameLess(aexported bool, aname string, bexported bool, bname string) bool {
+	if aexported != bexported {
+		return aexported
+	}
+	return aname < bname
+}
+
 func itabsinit() {
 	lockInit(&itabLock, lockRankItab)
 	lock(&itabLock)
@@ -5,8 +5,13 @@
 package runtime_test
 
 import (
+	"bytes"
+	"fmt"
+	"io"
+	mathbig "math/big"
 	"runtime"
 	"testing"
+	"time"
 )
 
 type I1 interface {
@@ -437,3 +442,78 @@ func BenchmarkConvT2Ezero(b *testing.B) {
 		})
 	})
 }
+
+func TestItabInit(t *testing.T) {
+	for _, tt := range []struct {
+		iface, x interface{}
+		want     bool
+	}{
+		{(*I1)(nil), TM(0), true},
+		{(*I2)(nil), TM(0), true},
+		{(*io.Writer)(nil), new(bytes.Buffer), true},
+		{(*io.ReadWriter)(nil), new(bytes.Buffer), true},
+		{(*fmt.Stringer)(nil), new(bytes.Buffer), true},
+		{(*io.Closer)(nil), new(bytes.Buffer), false},
+		{(*io.ReadWriteCloser)(nil), new(bytes.Buffer), false},
+		{(*io.Writer)(nil), bytes.Buffer{}, false},
+		{(*fmt.Stringer)(nil), new(mathbig.Int), true},
+		{(*jsonMarshaler)(nil), new(mathbig.Int), true},
+		{(*textMarshaler)(nil), new(mathbig.Int), true},
+		{(*io.Closer)(nil), new(mathbig.Int), false},
+	} {
+		if got := runtime.ItabInit(tt.iface, tt.x); got != tt.want {
+			t.Errorf("ItabInit(%T, %T) = %v, want %v", tt.iface, tt.x, got, tt.want)
+		}
+	}
+}
+
+func BenchmarkItabInit(b *testing.B) {
+	for _, bb := range []struct {
+		name     string
+		iface, x interface{}
+	}{
+		{"Small", (*I2)(nil), TM(0)},
+		{"Writer", (*io.Writer)(nil), new(bytes.Buffer)},
+		{"ReadWriter", (*io.ReadWriter)(nil), new(bytes.Buffer)},
+		{"Missing", (*io.ReadWriteCloser)(nil), new(bytes.Buffer)},
+		{"LargeStringer", (*fmt.Stringer)(nil), new(mathbig.Int)},
+		{"LargeMarshaler", (*jsonMarshaler)(nil), new(mathbig.Int)},
+		{"LargeMissing", (*io.Closer)(nil), new(mathbig.Int)},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				runtime.ItabInit(bb.iface, bb.x)
+			}
+		})
+	}
+}
+
+type jsonMarshaler interface {
+	MarshalJSON() ([]byte, error)
+}
+
+type textMarshaler interface {
+	MarshalText() ([]byte, error)
+}
+
+// BenchmarkTypeSwitchInterfaces switches on interface types, as an
+// encoder looking for marshaling methods of each value does.
+func BenchmarkTypeSwitchInterfaces(b *testing.B) {
+	vals := []interface{}{time.Time{}, new(bytes.Buffer), new(mathbig.Int), TM(0), io.EOF, new(time.Location), TS(0)}
+	n := 0
+	for i := 0; i < b.N; i++ {
+		for _, v := range vals {
+			switch v.(type) {
+			case jsonMarshaler:
+				n++
+			case textMarshaler:
+				n += 2
+			case error:
+				n += 3
+			case fmt.Stringer:
+				n += 4
+			}
+		}
+	}
+	sink = uint64(n)
+}
@@ -0,0 +1,196 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+import "unsafe"
+
+// Live patching, for runtime/debug.PatchFunc.
+//
+// A patch overwrites the entry of a function with a jump to another
+// function of the same type, so that calls of the first run the second.
+// The jump must not be written while a goroutine is stopped in the
+// middle of the code it replaces, or that goroutine would resume in the
+// middle of the jump. The runtime therefore stops the world, which
+// parks every goroutine at a safe point, by asynchronous preemption if
+// need be, and walks their stacks for frames of the function stopped
+// near its entry. If there is one, it starts the world again so that
+// the goroutine moves on, and retries.
+//
+// The jump is a single instruction, so once it is written no goroutine
+// can be stopped in the middle of it. It goes directly to the code of
+// the replacement, with no closure context, so the replacement must be
+// a function whose func value is allocated statically.
+//
+// Patches are only made and reverted with the world stopped, so
+// worldsema serializes them.
+
+// livePatchMaxWait bounds how long patchFunc and revertPatch wait for
+// goroutines to leave the code they overwrite.
+const livePatchMaxWait = 1e9 // 1s
+
+// A livePatch records the bytes a patch overwrote.
+type livePatch struct {
+	id    int
+	entry uintptr // entry of the patched function
+	orig  [livePatchLen]byte
+	next  *livePatch
+}
+
+// livePatches holds the patches in effect. Accessed with the world
+// stopped. Patches are allocated beforehand, since allocating with the
+// world stopped could wait for a GC assist that cannot happen.
+var livePatches struct {
+	list   *livePatch
+	nextID int
+}
+
+//go:linkname patchFunc runtime/debug.patchFunc
+func patchFunc(target, replacement interface{}) int {
+	t, r := efaceOf(&target), efaceOf(&replacement)
+	if t._type == nil || t._type != r._type || t._type.kind&kindMask != kindFunc || t.data == nil || r.data == nil {
+		return -1
+	}
+	if !livePatchSupported {
+		return -2
+	}
+	entry := (*funcval)(t.data).fn
+	f := findfunc(entry)
+	if !f.valid() || f.entry != entry || f.funcID != funcID_normal ||
+		hasPrefix(funcname(f), "runtime.") || hasPrefix(funcname(f), "runtime/internal/") ||
+		findfunc(entry+livePatchLen-1)._func != f._func {
+		return -3
+	}
+	if (*funcval)(r.data).fn == entry {
+		return -1
+	}
+	if spanOfHeap(uintptr(r.data)) != nil {
+		// A closure or method value, which needs its context.
+		return -4
+	}
+	code, ok := livePatchJump(entry, (*funcval)(r.data).fn)
+	if !ok {
+		return -6
+	}
+
+	p := &livePatch{entry: entry}
+	return livePatchStopped(func() uintptr { return entry }, func() int {
+		for q := livePatches.list; q != nil; q = q.next {
+			if q.entry == entry {
+				return -3
+			}
+		}
+		copy(p.orig[:], (*[livePatchLen]byte)(unsafe.Pointer(entry))[:])
+		if !writeText(entry, code[:]) {
+			return -6
+		}
+		p.id = livePatches.nextID
+		livePatches.nextID++
+		p.next = livePatches.list
+		livePatches.list = p
+		return p.id
+	})
+}
+
+//go:linkname revertPatch runtime/debug.revertPatch
+func revertPatch(id int) int {
+	find := func() *livePatch {
+		for p := livePatches.list; p != nil; p = p.next {
+			if p.id == id {
+				return p
+			}
+		}
+		return nil
+	}
+	return livePatchStopped(func() uintptr {
+		if p := find(); p != nil {
+			return p.entry
+		}
+		return 0
+	}, func() int {
+		p := find()
+		if p == nil {
+			return -1
+		}
+		if !writeText(p.entry, p.orig[:]) {
+			return -6
+		}
+		for pp := &livePatches.list; ; pp = &(*pp).next {
+			if *pp == p {
+				*pp = p.next
+				break
+			}
+		}
+		return 0
+	})
+}
+
+// livePatchStopped stops the world once no goroutine is stopped in the
+// code that a patch overwrites at entry(), and returns the result of
+// write, which it calls with the world stopped. entry is called with
+// the world stopped too. It returns -5 if goroutines do not leave that
+// code within livePatchMaxWait.
+//
+//go:noinline
+func livePatchStopped(entry func() uintptr, write func() int) int {
+	gp := getg()
+	pc, sp := getcallerpc(), getcallersp()
+	deadline := nanotime() + livePatchMaxWait
+	for {
+		stopTheWorld("live patch")
+		var busy bool
+		systemstack(func() {
+			if e := entry(); e != 0 {
+				busy = livePatchBusy(e, gp, pc, sp)
+			}
+		})
+		if !busy {
+			ret := write()
+			startTheWorld()
+			return ret
+		}
+		startTheWorld()
+		if nanotime() >= deadline {
+			return -5
+		}
+		timeSleep(1e6)
+	}
+}
+
+// livePatchBusy reports whether a frame of the function at entry is
+// stopped in the code that a patch overwrites, other than at entry.
+// me is the calling goroutine, whose stack starts at pc and sp.
+// The world must be stopped.
+func livePatchBusy(entry uintptr, me *g, pc, sp uintptr) bool {
+	busy := false
+	check := func(frame *stkframe, unused unsafe.Pointer) bool {
+		if frame.fn.entry == entry && frame.pc > entry && frame.pc < entry+livePatchWindow {
+			busy = true
+			return false
+		}
+		return true
+	}
+	gentraceback(pc, sp, 0, me, 0, nil, 0x7fffffff, check, nil, 0)
+	lock(&allglock)
+	for _, gp := range allgs {
+		if busy {
+			break
+		}
+		if gp == me {
+			continue
+		}
+		switch readgstatus(gp) &^ _Gscan {
+		case _Gidle, _Gdead:
+			continue
+		case _Grunning:
+			// Cannot happen with the world stopped, but its
+			// stack could not be walked.
+			busy = true
+			continue
+		}
+		gentraceback(^uintptr(0), ^uintptr(0), 0, gp, 0, nil, 0x7fffffff, check, nil, 0)
+	}
+	unlock(&allglock)
+	return busy
+}
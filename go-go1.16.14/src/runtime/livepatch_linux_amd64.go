@@ -0,0 +1,52 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+import "unsafe"
+
+const livePatchSupported = true
+
+// livePatchLen is the length of the jump a patch writes, a JMP with a
+// 32-bit displacement.
+const livePatchLen = 5
+
+// livePatchWindow bounds the code a goroutine must not be stopped in
+// when a patch is written: the jump, and the rest of an instruction of
+// up to 15 bytes that the jump cuts into.
+const livePatchWindow = livePatchLen + 15
+
+// livePatchJump returns the jump from the code at from to the code at
+// to, and whether to is close enough to jump to.
+func livePatchJump(from, to uintptr) (code [livePatchLen]byte, ok bool) {
+	rel := int64(to) - int64(from+livePatchLen)
+	if rel != int64(int32(rel)) {
+		return code, false
+	}
+	code[0] = 0xe9
+	for i := 1; i < livePatchLen; i++ {
+		code[i] = byte(rel)
+		rel >>= 8
+	}
+	return code, true
+}
+
+// writeText overwrites the code at addr with b, and reports whether it
+// could. The world must be stopped, so that no thread runs that code
+// meanwhile.
+func writeText(addr uintptr, b []byte) bool {
+	start := alignDown(addr, physPageSize)
+	end := alignUp(addr+uintptr(len(b)), physPageSize)
+	if mprotect(unsafe.Pointer(start), end-start, _PROT_READ|_PROT_WRITE|_PROT_EXEC) != 0 {
+		return false
+	}
+	for i, c := range b {
+		*(*byte)(unsafe.Pointer(addr + uintptr(i))) = c
+	}
+	mprotect(unsafe.Pointer(start), end-start, _PROT_READ|_PROT_EXEC)
+	return true
+}
+
+//go:noescape
+func mprotect(addr unsafe.Pointer, n uintptr, prot int32) int32
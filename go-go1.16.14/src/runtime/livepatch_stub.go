@@ -0,0 +1,23 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux !amd64
+
+package runtime
+
+// Live patching is only implemented on linux/amd64.
+
+const (
+	livePatchSupported = false
+	livePatchLen       = 1
+	livePatchWindow    = 1
+)
+
+func livePatchJump(from, to uintptr) (code [livePatchLen]byte, ok bool) {
+	return code, false
+}
+
+func writeText(addr uintptr, b []byte) bool {
+	return false
+}
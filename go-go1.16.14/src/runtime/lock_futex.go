@@ -36,6 +36,14 @@ const (
 // mutex_sleeping means that there is presumably at least one sleeping thread.
 // Note that there can be spinning threads during all states - they do not
 // affect mutex's state.
+//
+// With lockPI set, the lock word holds the thread ID of the owner
+// instead, and threads sleep in priority-inheritance futexes; see
+// lockpi_linux.go.
+
+// lockPI is set, before any lock is held and for good, if the
+// runtime locks use priority-inheritance futexes.
+var lockPI bool
 
 // We use the uintptr mutex.key and note.key as a uint32.
 //go:nosplit
@@ -55,11 +63,17 @@ func lock2(l *mutex) {
 	}
 	gp.m.locks++
 
+	if lockPI {
+		lock2pi(l, gp.m)
+		return
+	}
+
 	// Speculative grab for lock.
 	v := atomic.Xchg(key32(&l.key), mutex_locked)
 	if v == mutex_unlocked {
 		return
 	}
+	start := lockContendedStart()
 
 	// wait is either MUTEX_LOCKED or MUTEX_SLEEPING
 	// depending on whether there is a thread sleeping
@@ -81,6 +95,7 @@ func lock2(l *mutex) {
 		for i := 0; i < spin; i++ {
 			for l.key == mutex_unlocked {
 				if atomic.Cas(key32(&l.key), mutex_unlocked, wait) {
+					lockContendedEnd(start)
 					return
 				}
 			}
@@ -91,6 +106,7 @@ func lock2(l *mutex) {
 		for i := 0; i < passive_spin; i++ {
 			for l.key == mutex_unlocked {
 				if atomic.Cas(key32(&l.key), mutex_unlocked, wait) {
+					lockContendedEnd(start)
 					return
 				}
 			}
@@ -100,9 +116,11 @@ func lock2(l *mutex) {
 		// Sleep.
 		v = atomic.Xchg(key32(&l.key), mutex_sleeping)
 		if v == mutex_unlocked {
+			lockContendedEnd(start)
 			return
 		}
 		wait = mutex_sleeping
+		lockSleep()
 		futexsleep(key32(&l.key), mutex_sleeping, -1)
 	}
 }
@@ -112,15 +130,19 @@ func unlock(l *mutex) {
 }
 
 func unlock2(l *mutex) {
-	v := atomic.Xchg(key32(&l.key), mutex_unlocked)
-	if v == mutex_unlocked {
-		throw("unlock of unlocked lock")
-	}
-	if v == mutex_sleeping {
-		futexwakeup(key32(&l.key), 1)
+	gp := getg()
+	if lockPI {
+		unlock2pi(l, gp.m)
+	} else {
+		v := atomic.Xchg(key32(&l.key), mutex_unlocked)
+		if v == mutex_unlocked {
+			throw("unlock of unlocked lock")
+		}
+		if v == mutex_sleeping {
+			futexwakeup(key32(&l.key), 1)
+		}
 	}
 
-	gp := getg()
 	gp.m.locks--
 	if gp.m.locks < 0 {
 		throw("runtime·unlock: lock count")
@@ -48,6 +48,7 @@ func lock2(l *mutex) {
 		return
 	}
 	semacreate(gp.m)
+	start := lockContendedStart()
 
 	// On uniprocessor's, no point spinning.
 	// On multiprocessors, spin for ACTIVE_SPIN attempts.
@@ -61,6 +62,7 @@ Loop:
 		if v&locked == 0 {
 			// Unlocked. Try to lock.
 			if atomic.Casuintptr(&l.key, v, v|locked) {
+				lockContendedEnd(start)
 				return
 			}
 			i = 0
@@ -86,6 +88,7 @@ Loop:
 			}
 			if v&locked != 0 {
 				// Queued. Wait.
+				lockSleep()
 				semasleep(-1)
 				i = 0
 			}
@@ -0,0 +1,40 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+import "runtime/internal/atomic"
+
+// lockContention counts the contention on runtime locks, for the
+// /sched/lock metrics. Only the slow paths of lock2 update it.
+var lockContention struct {
+	// All accessed atomically, so they come first to be 8-byte
+	// aligned on 32-bit systems.
+	contended uint64 // acquisitions that found the lock held
+	sleeps    uint64 // times a thread slept in the kernel for a lock
+	waitTime  int64  // nanoseconds spent acquiring held locks
+}
+
+// lockContendedStart records that an acquisition found its lock held,
+// and returns the time it started to wait, for lockContendedEnd.
+//
+//go:nosplit
+func lockContendedStart() int64 {
+	atomic.Xadd64(&lockContention.contended, 1)
+	return nanotime()
+}
+
+// lockContendedEnd records the end of a wait that started at start.
+//
+//go:nosplit
+func lockContendedEnd(start int64) {
+	atomic.Xaddint64(&lockContention.waitTime, nanotime()-start)
+}
+
+// lockSleep records that a thread is about to sleep for a lock.
+//
+//go:nosplit
+func lockSleep() {
+	atomic.Xadd64(&lockContention.sleeps, 1)
+}
@@ -0,0 +1,117 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Priority-inheritance runtime locks.
+//
+// When there are more runnable threads than CPUs, the kernel may
+// deschedule a thread holding sched.lock or mheap_.lock while the
+// threads that wait for it spin, yield to whoever the kernel picks, and
+// eventually sleep, none of which gets the owner running again any
+// sooner. With GODEBUG=lockpi=1, the runtime locks are instead
+// priority-inheritance futexes: the lock word holds the thread ID of
+// the owner, and a thread that finds the lock held, after a short
+// active spin, sleeps in FUTEX_LOCK_PI. The kernel then lends the
+// owner the priority of its highest-priority waiter until it unlocks,
+// and hands the lock directly to that waiter. Neither locking nor
+// unlocking an uncontended lock enters the kernel.
+
+package runtime
+
+import (
+	"runtime/internal/atomic"
+	"unsafe"
+)
+
+const (
+	_FUTEX_LOCK_PI   = 6
+	_FUTEX_UNLOCK_PI = 7
+
+	_FUTEX_TID_MASK = 0x3fffffff
+)
+
+// lockPIInit turns on priority-inheritance locks if the kernel
+// supports them. No lock may be held, and no other M may be running.
+func lockPIInit() {
+	// m0's thread ID is otherwise only recorded by minit.
+	mp := getg().m
+	mp.procid = uint64(gettid())
+
+	// Lock and unlock a private lock word to find out whether the
+	// kernel supports priority-inheritance futexes, which some
+	// kernels and sandboxes do not.
+	var key uint32
+	if futex(unsafe.Pointer(&key), _FUTEX_LOCK_PI|_FUTEX_PRIVATE_FLAG, 0, nil, nil, 0) != 0 || key&_FUTEX_TID_MASK != uint32(mp.procid) {
+		return
+	}
+	if futex(unsafe.Pointer(&key), _FUTEX_UNLOCK_PI|_FUTEX_PRIVATE_FLAG, 0, nil, nil, 0) != 0 || key != 0 {
+		return
+	}
+	lockPI = true
+}
+
+// lock2pi is lock2 for priority-inheritance locks.
+func lock2pi(l *mutex, mp *m) {
+	k := key32(&l.key)
+	tid := uint32(mp.procid)
+	if tid == 0 {
+		throw("lock2pi: no thread ID")
+	}
+
+	// Speculative grab for lock.
+	if atomic.Cas(k, 0, tid) {
+		return
+	}
+	start := lockContendedStart()
+
+	// On multiprocessors, spin briefly in case the owner is about to
+	// unlock; otherwise leave the owner to the kernel.
+	if ncpu > 1 {
+		for i := 0; i < active_spin; i++ {
+			for atomic.Load(k) == 0 {
+				if atomic.Cas(k, 0, tid) {
+					lockContendedEnd(start)
+					return
+				}
+			}
+			procyield(active_spin_cnt)
+		}
+	}
+
+	for {
+		lockSleep()
+		ret := futex(unsafe.Pointer(k), _FUTEX_LOCK_PI|_FUTEX_PRIVATE_FLAG, 0, nil, nil, 0)
+		if ret == 0 {
+			// The kernel made this thread the owner.
+			break
+		}
+		// EAGAIN means that the owner is exiting, so the lock
+		// will be free soon.
+		if ret != -_EINTR && ret != -_EAGAIN {
+			print("runtime: FUTEX_LOCK_PI failed with errno=", -ret, "\n")
+			throw("lock2pi")
+		}
+	}
+	lockContendedEnd(start)
+}
+
+// unlock2pi is unlock2 for priority-inheritance locks.
+func unlock2pi(l *mutex, mp *m) {
+	k := key32(&l.key)
+	tid := uint32(mp.procid)
+	if atomic.Cas(k, tid, 0) {
+		return
+	}
+	v := atomic.Load(k)
+	if v == 0 {
+		throw("unlock of unlocked lock")
+	}
+	if v&_FUTEX_TID_MASK != tid {
+		throw("unlock of lock held by another thread")
+	}
+	// There are waiters; the kernel hands the lock to one of them.
+	if ret := futex(unsafe.Pointer(k), _FUTEX_UNLOCK_PI|_FUTEX_PRIVATE_FLAG, 0, nil, nil, 0); ret != 0 {
+		print("runtime: FUTEX_UNLOCK_PI failed with errno=", -ret, "\n")
+		throw("unlock2pi")
+	}
+}
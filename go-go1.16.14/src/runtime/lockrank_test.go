@@ -0,0 +1,19 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime_test
+
+import "testing"
+
+// Lock ranking is normally only checked by runtimes built with
+// GOEXPERIMENT=staticlockranking, but GODEBUG=lockrank=1 turns it on
+// in any build. A program that exercises the GC and the scheduler must
+// not report lock ordering problems.
+func TestLockRankGODEBUG(t *testing.T) {
+	got := runTestProg(t, "testprog", "GCSys", "GODEBUG=lockrank=1")
+	want := "OK\n"
+	if got != want {
+		t.Fatalf("expected %q, but got %q", want, got)
+	}
+}
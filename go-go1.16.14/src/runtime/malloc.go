@@ -1151,6 +1151,25 @@ func mallocgc(size uintptr, typ *_type, needzero bool) unsafe.Pointer {
 		}
 	}
 
+	if s := atomic.Load(&prof.scope); s != 0 {
+		// Allocations on the system stack are made on behalf of
+		// the runtime, not of the goroutine.
+		if gp := getg(); gp == gp.m.curg && gp.profscope == s {
+			if rate := MemProfileRate; rate > 0 {
+				if rate != 1 && size < c.nextScopeSample {
+					c.nextScopeSample -= size
+				} else {
+					c.nextScopeSample = nextSample()
+					mProf_ScopedMalloc(size)
+				}
+			}
+		}
+	}
+
+	if atomic.Load(&goroutineGroups.used) != 0 {
+		groupMalloc(size)
+	}
+
 	if rate := MemProfileRate; rate > 0 {
 		if rate != 1 && size < c.nextSample {
 			c.nextSample -= size
@@ -1319,6 +1338,11 @@ func persistentalloc(size, align uintptr, sysStat *sysMemStat) unsafe.Pointer {
 	systemstack(func() {
 		p = persistentalloc1(size, align, sysStat)
 	})
+	if msanenabled {
+		// The runtime does not instrument its own writes, so
+		// mark the block initialized before C code can see it.
+		msanmalloc(unsafe.Pointer(p), size)
+	}
 	return unsafe.Pointer(p)
 }
 
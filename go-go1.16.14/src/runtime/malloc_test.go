@@ -241,6 +241,19 @@ func TestPhysicalMemoryUtilization(t *testing.T) {
 	}
 }
 
+func TestOutOfMemoryHandler(t *testing.T) {
+	if GOOS != "linux" {
+		t.Skip("test limits the address space with setrlimit on Linux")
+	}
+	if race.Enabled {
+		t.Skip("race detector needs more address space")
+	}
+	got := runTestProg(t, "testprog", "OutOfMemoryHandler")
+	if !strings.Contains(got, "-byte reserve\n") || !strings.HasSuffix(got, "OK\n") {
+		t.Fatalf("want the reserve to be used and OK, got:\n%s", got)
+	}
+}
+
 func TestScavengedBitsCleared(t *testing.T) {
 	var mismatches [128]BitsMismatch
 	if n, ok := CheckScavengedBitsCleared(mismatches[:]); !ok {
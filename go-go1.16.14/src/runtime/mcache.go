@@ -20,8 +20,9 @@ import (
 type mcache struct {
 	// The following members are accessed on every malloc,
 	// so they are grouped here for better caching.
-	nextSample uintptr // trigger heap sample after allocating this many bytes
-	scanAlloc  uintptr // bytes of scannable heap allocated
+	nextSample      uintptr // trigger heap sample after allocating this many bytes
+	nextScopeSample uintptr // trigger scoped allocation sample after allocating this many bytes in scope
+	scanAlloc       uintptr // bytes of scannable heap allocated
 
 	// Allocator cache for tiny objects w/o pointers.
 	// See "Tiny allocator" comment in malloc.go.
@@ -93,6 +94,7 @@ func allocmcache() *mcache {
 		c.alloc[i] = &emptymspan
 	}
 	c.nextSample = nextSample()
+	c.nextScopeSample = nextSample()
 	return c
 }
 
@@ -160,6 +162,9 @@ func (c *mcache) refill(spc spanClass) {
 
 	// Get a new cached span from the central lists.
 	s = mheap_.central[spc].mcentral.cacheSpan()
+	if s == nil && useOutOfMemoryReserve() {
+		s = mheap_.central[spc].mcentral.cacheSpan()
+	}
 	if s == nil {
 		throw("out of memory")
 	}
@@ -222,6 +227,9 @@ func (c *mcache) allocLarge(size uintptr, needzero bool, noscan bool) *mspan {
 
 	spc := makeSpanClass(0, noscan)
 	s := mheap_.alloc(npages, spc, needzero)
+	if s == nil && useOutOfMemoryReserve() {
+		s = mheap_.alloc(npages, spc, needzero)
+	}
 	if s == nil {
 		throw("out of memory")
 	}
@@ -0,0 +1,124 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Memory-pressure driven garbage collection.
+//
+// The GC paces itself with GOGC and knows nothing about how much memory
+// the process may use. A program in a container can be killed by the
+// kernel's OOM killer long before its heap reaches the GC goal. With
+// GODEBUG=memorypressure=1, sysmon periodically samples the memory
+// controller of the process's cgroup (cgroup v2 on Linux only). It
+// considers memory to be under pressure when any of these hold:
+//
+//	- usage exceeds memPressureLimitPercent of the cgroup's limit;
+//	- the kernel reports new high or max events, meaning it has
+//	  throttled or reclaimed memory from the cgroup;
+//	- pressure stall information shows tasks stalled on memory for at
+//	  least memPressureStallPercent of the last 10 seconds.
+//
+// It then wakes a helper goroutine that runs a full GC and returns
+// free memory to the operating system, like debug.FreeOSMemory. These
+// collections are rate limited by memPressureMinInterval, since the
+// live heap alone may keep the cgroup near its limit.
+
+package runtime
+
+import "runtime/internal/atomic"
+
+const (
+	// memPressureCheckPeriod is how often sysmon samples memory
+	// pressure.
+	memPressureCheckPeriod = 1e9 // 1s
+
+	// memPressureMinInterval is the minimum time between two GCs
+	// triggered by memory pressure.
+	memPressureMinInterval = 10e9 // 10s
+
+	// memPressureLimitPercent is how full the cgroup may get, as a
+	// percentage of its limit, before it is considered under pressure.
+	memPressureLimitPercent = 90
+
+	// memPressureStallPercent is the share of time tasks may stall
+	// on memory, over the last 10 seconds, before memory is
+	// considered under pressure.
+	memPressureStallPercent = 10
+)
+
+var memPressure struct {
+	// gcs counts the GCs run because of memory pressure. Accessed
+	// atomically, so it comes first to be 8-byte aligned on 32-bit
+	// systems.
+	gcs uint64
+
+	lock mutex
+	g    *g
+	idle uint32
+
+	// lastEvents and lastGC are only accessed by sysmon once the
+	// helper is running.
+	lastEvents uint64
+	lastGC     int64
+}
+
+// A memPressureSample is one reading of the memory pressure sources.
+// Fields whose source is unavailable are left zero.
+type memPressureSample struct {
+	current uint64 // bytes in use by the cgroup
+	limit   uint64 // cgroup limit in bytes, 0 if unlimited
+	events  uint64 // total high and max events in the cgroup
+	stall   uint64 // share of time stalled on memory, in 0.01% units
+}
+
+func init() {
+	if debug.memorypressure > 0 && memPressureInit() {
+		var s memPressureSample
+		readMemPressure(&s)
+		memPressure.lastEvents = s.events
+		go mempressurehelper()
+	}
+}
+
+func mempressurehelper() {
+	memPressure.g = getg()
+	lockInit(&memPressure.lock, lockRankMemPressure)
+	for {
+		lock(&memPressure.lock)
+		atomic.Store(&memPressure.idle, 1)
+		goparkunlock(&memPressure.lock, waitReasonMemPressureIdle, traceEvGoBlock, 1)
+		// this goroutine is explicitly resumed by sysmon
+		if debug.gctrace > 0 {
+			println("GC forced by memory pressure")
+		}
+		GC()
+		systemstack(func() {
+			mheap_.scavengeAll()
+		})
+		atomic.Xadd64(&memPressure.gcs, 1)
+	}
+}
+
+// checkMemoryPressure samples memory pressure and, if memory is under
+// pressure, wakes the memory pressure helper. It is called by sysmon.
+func checkMemoryPressure(now int64) {
+	if atomic.Load(&memPressure.idle) == 0 {
+		// Not started, or still collecting.
+		return
+	}
+	var s memPressureSample
+	readMemPressure(&s)
+	pressure := s.limit > 0 && s.current >= s.limit/100*memPressureLimitPercent ||
+		s.events > memPressure.lastEvents ||
+		s.stall >= memPressureStallPercent*100
+	memPressure.lastEvents = s.events
+	if !pressure || memPressure.lastGC != 0 && now-memPressure.lastGC < memPressureMinInterval {
+		return
+	}
+	memPressure.lastGC = now
+	lock(&memPressure.lock)
+	memPressure.idle = 0
+	var list gList
+	list.push(memPressure.g)
+	injectglist(&list)
+	unlock(&memPressure.lock)
+}
@@ -0,0 +1,189 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+import (
+	"internal/bytealg"
+	"unsafe"
+)
+
+// memPressureFiles holds the NUL-terminated paths of the files sampled
+// for memory pressure, or nil for the ones that cannot be read.
+// Set by memPressureInit.
+var memPressureFiles struct {
+	current  []byte // memory.current
+	limit    []byte // memory.max
+	events   []byte // memory.events
+	pressure []byte // memory.pressure, or the system-wide PSI file
+}
+
+var procSelfCgroup = []byte("/proc/self/cgroup\x00")
+
+// cgroup2Root is where the cgroup v2 hierarchy is expected to be
+// mounted.
+const cgroup2Root = "/sys/fs/cgroup"
+
+// memPressureInit finds the cgroup v2 memory controller files of the
+// process and reports whether any of them can be read. Without a
+// cgroup v2 hierarchy it falls back to the system-wide pressure
+// stall information.
+func memPressureInit() bool {
+	var buf [4096]byte
+	n := readMemPressureFile(procSelfCgroup, buf[:])
+	if n > 0 {
+		if dir, ok := parseCgroup2Path(buf[:n]); ok {
+			if dir == "/" {
+				dir = ""
+			}
+			dir = cgroup2Root + dir + "/"
+			memPressureFiles.current = memPressurePath(dir + "memory.current")
+			memPressureFiles.limit = memPressurePath(dir + "memory.max")
+			memPressureFiles.events = memPressurePath(dir + "memory.events")
+			memPressureFiles.pressure = memPressurePath(dir + "memory.pressure")
+		}
+	}
+	if memPressureFiles.pressure == nil {
+		memPressureFiles.pressure = memPressurePath("/proc/pressure/memory")
+	}
+	f := &memPressureFiles
+	return f.current != nil || f.events != nil || f.pressure != nil
+}
+
+// memPressurePath returns name as a NUL-terminated path if the file
+// can be opened, and nil otherwise.
+func memPressurePath(name string) []byte {
+	path := []byte(name + "\x00")
+	fd := open(&path[0], 0 /* O_RDONLY */, 0)
+	if fd < 0 {
+		return nil
+	}
+	closefd(fd)
+	return path
+}
+
+// readMemPressure samples the files found by memPressureInit. It does
+// not allocate, so it can run on sysmon.
+func readMemPressure(s *memPressureSample) {
+	var buf [256]byte
+	if n := readMemPressureFile(memPressureFiles.current, buf[:]); n > 0 {
+		s.current, _ = parseCgroupUint(buf[:n])
+	}
+	if n := readMemPressureFile(memPressureFiles.limit, buf[:]); n > 0 {
+		// An unlimited cgroup reads "max", which leaves limit 0.
+		s.limit, _ = parseCgroupUint(buf[:n])
+	}
+	if n := readMemPressureFile(memPressureFiles.events, buf[:]); n > 0 {
+		s.events = parseMemoryEvents(buf[:n])
+	}
+	if n := readMemPressureFile(memPressureFiles.pressure, buf[:]); n > 0 {
+		s.stall = parsePSISomeAvg10(buf[:n])
+	}
+}
+
+// readMemPressureFile reads the start of the file at path into buf
+// and returns the number of bytes read, or 0 if path is nil or the
+// file cannot be read.
+func readMemPressureFile(path []byte, buf []byte) int {
+	if path == nil {
+		return 0
+	}
+	fd := open(&path[0], 0 /* O_RDONLY */, 0)
+	if fd < 0 {
+		return 0
+	}
+	n := read(fd, noescape(unsafe.Pointer(&buf[0])), int32(len(buf)))
+	closefd(fd)
+	if n < 0 {
+		return 0
+	}
+	return int(n)
+}
+
+// parseCgroup2Path returns the cgroup v2 path from the contents of
+// /proc/self/cgroup, which has a "0::/path" line for the unified
+// hierarchy.
+func parseCgroup2Path(b []byte) (string, bool) {
+	for len(b) > 0 {
+		var line []byte
+		line, b = nextLine(b)
+		if len(line) > 3 && line[0] == '0' && line[1] == ':' && line[2] == ':' {
+			return string(line[3:]), true
+		}
+	}
+	return "", false
+}
+
+// parseCgroupUint parses a cgroup file holding a single decimal
+// number, such as memory.current.
+func parseCgroupUint(b []byte) (uint64, bool) {
+	line, _ := nextLine(b)
+	if len(line) == 0 {
+		return 0, false
+	}
+	var n uint64
+	for _, c := range line {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		n = n*10 + uint64(c-'0')
+	}
+	return n, true
+}
+
+// parseMemoryEvents returns the sum of the high and max counters in
+// the contents of a memory.events file.
+func parseMemoryEvents(b []byte) uint64 {
+	var total uint64
+	for len(b) > 0 {
+		var line []byte
+		line, b = nextLine(b)
+		i := bytealg.IndexByte(line, ' ')
+		if i < 0 {
+			continue
+		}
+		if key := line[:i]; string(key) == "high" || string(key) == "max" {
+			if v, ok := parseCgroupUint(line[i+1:]); ok {
+				total += v
+			}
+		}
+	}
+	return total
+}
+
+// parsePSISomeAvg10 returns the avg10 value of the "some" line of a
+// pressure stall information file, in hundredths of a percent.
+func parsePSISomeAvg10(b []byte) uint64 {
+	const prefix = "some avg10="
+	for len(b) > 0 {
+		var line []byte
+		line, b = nextLine(b)
+		if len(line) < len(prefix) || string(line[:len(prefix)]) != prefix {
+			continue
+		}
+		// The value always has two decimals, as in "12.34".
+		var v uint64
+		for _, c := range line[len(prefix):] {
+			if c == '.' {
+				continue
+			}
+			if c < '0' || c > '9' {
+				break
+			}
+			v = v*10 + uint64(c-'0')
+		}
+		return v
+	}
+	return 0
+}
+
+// nextLine splits b after its first line and returns the line,
+// without the newline, and the rest of b.
+func nextLine(b []byte) (line, rest []byte) {
+	i := bytealg.IndexByte(b, '\n')
+	if i < 0 {
+		return b, nil
+	}
+	return b[:i], b[i+1:]
+}
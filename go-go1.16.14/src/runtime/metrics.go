@@ -32,6 +32,10 @@ type metricData struct {
 	// compute is a function that populates a metricValue
 	// given a populated statAggregate structure.
 	compute func(in *statAggregate, out *metricValue)
+
+	// counter is the eventCounter the metric publishes if compute
+	// is nil.
+	counter eventCounter
 }
 
 // initMetrics initializes the metrics map if it hasn't been yet.
@@ -65,6 +69,27 @@ func initMetrics() {
 
 	timeHistBuckets = timeHistogramMetricsBuckets()
 	metrics = map[string]metricData{
+		"/cgo/extra-ms/freed:threads": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				_, _, freed := readExtraMStats()
+				out.kind = metricKindUint64
+				out.scalar = freed
+			},
+		},
+		"/cgo/extra-ms/idle:threads": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				_, idle, _ := readExtraMStats()
+				out.kind = metricKindUint64
+				out.scalar = uint64(idle)
+			},
+		},
+		"/cgo/extra-ms/in-use:threads": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				inUse, _, _ := readExtraMStats()
+				out.kind = metricKindUint64
+				out.scalar = uint64(inUse)
+			},
+		},
 		"/gc/cycles/automatic:gc-cycles": {
 			deps: makeStatDepSet(sysStatsDep),
 			compute: func(in *statAggregate, out *metricValue) {
@@ -79,6 +104,12 @@ func initMetrics() {
 				out.scalar = in.sysStats.gcCyclesForced
 			},
 		},
+		"/gc/cycles/pressure:gc-cycles": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = atomic.Load64(&memPressure.gcs)
+			},
+		},
 		"/gc/cycles/total:gc-cycles": {
 			deps: makeStatDepSet(sysStatsDep),
 			compute: func(in *statAggregate, out *metricValue) {
@@ -239,12 +270,350 @@ func initMetrics() {
 					in.sysStats.gcMiscSys + in.sysStats.otherSys
 			},
 		},
+		"/sched/cpuquota/throttled:periods": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = atomic.Load64(&cpuQuota.throttled)
+			},
+		},
+		"/sched/defer/pool-hits:defers": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = 0
+				for sc := range deferPool.depth {
+					hits, _ := readDeferPoolStats(sc)
+					out.scalar += hits
+				}
+			},
+		},
+		"/sched/defer/pool-misses:defers": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = 0
+				for sc := range deferPool.depth {
+					_, misses := readDeferPoolStats(sc)
+					out.scalar += misses
+				}
+			},
+		},
+		"/sched/gfree/contended:acquisitions": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				_, _, _, contended := readGFreeStats()
+				out.scalar = contended
+			},
+		},
+		"/sched/gfree/refills:refills": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				refills, _, _, _ := readGFreeStats()
+				out.scalar = refills
+			},
+		},
+		"/sched/gfree/released:goroutines": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = atomic.Load64(&gRetired.released)
+			},
+		},
+		"/sched/gfree/retained:bytes": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = gFreeRetainedBytes()
+			},
+		},
+		"/sched/gfree/spills:spills": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				_, _, spills, _ := readGFreeStats()
+				out.scalar = spills
+			},
+		},
+		"/sched/gfree/steals:refills": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				_, steals, _, _ := readGFreeStats()
+				out.scalar = steals
+			},
+		},
+		"/sched/globrunq/goroutines:goroutines": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = readStealStats().globalGs
+			},
+		},
+		"/sched/globrunq/grabs:grabs": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = readStealStats().globalGrabs
+			},
+		},
 		"/sched/goroutines:goroutines": {
 			compute: func(_ *statAggregate, out *metricValue) {
 				out.kind = metricKindUint64
 				out.scalar = uint64(gcount())
 			},
 		},
+		"/sched/idle/poller-wakeups:wakeups": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = atomic.Load64(&idlePollerWakeups)
+			},
+		},
+		"/sched/idle/sysmon-wakeups:wakeups": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = atomic.Load64(&idleSysmonWakeups)
+			},
+		},
+		"/sched/inject/batches:batches": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = atomic.Load64(&injectBatches)
+			},
+		},
+		"/sched/inject/goroutines:goroutines": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = atomic.Load64(&injectGoroutines)
+			},
+		},
+		"/sched/inject/wakeups:wakeups": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = atomic.Load64(&injectWakeups)
+			},
+		},
+		"/sched/lock/contended:acquisitions": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = atomic.Load64(&lockContention.contended)
+			},
+		},
+		"/sched/lock/sleeps:sleeps": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = atomic.Load64(&lockContention.sleeps)
+			},
+		},
+		"/sched/lock/wait:seconds": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindFloat64
+				out.scalar = float64bits(float64(atomic.Loadint64(&lockContention.waitTime)) / 1e9)
+			},
+		},
+		"/sched/netpoll/skipped-wakeups:wakeups": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = atomic.Load64(&netpollSkippedBreaks)
+			},
+		},
+		"/sched/netpoll/spurious-wakeups:wakeups": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = atomic.Load64(&netpollSpuriousWakeups)
+			},
+		},
+		"/sched/netpoll/timer-wakeups:wakeups": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = atomic.Load64(&netpollTimerWakeups)
+			},
+		},
+		"/sched/netpoll/wasted-wakeups:wakeups": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = atomic.Load64(&netpollWastedWakeups)
+			},
+		},
+		"/sched/preempt/dropped:requests": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = atomic.Load64(&asyncPreemptStats.dropped)
+			},
+		},
+		"/sched/preempt/injected:requests": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = atomic.Load64(&asyncPreemptStats.injected)
+			},
+		},
+		"/sched/preempt/requests:requests": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = atomic.Load64(&asyncPreemptStats.requests)
+			},
+		},
+		"/sched/steal/failures:attempts": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = readStealStats().failures
+			},
+		},
+		"/sched/steal/goroutines:goroutines": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = readStealStats().stolen
+			},
+		},
+		"/sched/steal/runnext:goroutines": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = readStealStats().runnext
+			},
+		},
+		"/sched/steal/successes:attempts": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = readStealStats().successes
+			},
+		},
+		"/sched/stw/gc:seconds": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				stwPauseHist(&stwPauses[stwGC], out)
+			},
+		},
+		"/sched/stw/gomaxprocs:seconds": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				stwPauseHist(&stwPauses[stwGOMAXPROCS], out)
+			},
+		},
+		"/sched/stw/memstats:seconds": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				stwPauseHist(&stwPauses[stwMemStats], out)
+			},
+		},
+		"/sched/stw/other:seconds": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				stwPauseHist(&stwPauses[stwOther], out)
+			},
+		},
+		"/sched/stw/profile:seconds": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				stwPauseHist(&stwPauses[stwProfile], out)
+			},
+		},
+		"/sched/stw/trace:seconds": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				stwPauseHist(&stwPauses[stwTrace], out)
+			},
+		},
+		"/sched/sudog/allocs:sudogs": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = atomic.Load64(&sudogAllocs)
+			},
+		},
+		"/sched/sudog/contended:acquisitions": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				_, _, _, contended := readSudogStats()
+				out.scalar = contended
+			},
+		},
+		"/sched/sudog/refills:refills": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				refills, _, _, _ := readSudogStats()
+				out.scalar = refills
+			},
+		},
+		"/sched/sudog/spills:spills": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				_, _, spills, _ := readSudogStats()
+				out.scalar = spills
+			},
+		},
+		"/sched/sudog/steals:refills": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				_, steals, _, _ := readSudogStats()
+				out.scalar = steals
+			},
+		},
+		"/sched/syscalls/idle-p:calls": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = atomic.Load64(&syscallExitStats.idleP)
+			},
+		},
+		"/sched/syscalls/parked:calls": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = atomic.Load64(&syscallExitStats.parked)
+			},
+		},
+		"/sched/syscalls/retaken-p:calls": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = atomic.Load64(&syscallExitStats.retaken)
+			},
+		},
+		"/sched/threads/deferred:threads": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = atomic.Load64(&threadLimit.deferred)
+			},
+		},
+		"/sched/threads/idle-exits:threads": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = atomic.Load64(&mIdleExits)
+			},
+		},
+		"/sched/threads/idle:threads": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				lock(&sched.lock)
+				out.scalar = uint64(sched.nmidle)
+				unlock(&sched.lock)
+			},
+		},
+		"/sched/threads/peak:threads": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = uint64(atomic.Load((*uint32)(unsafe.Pointer(&mpeak))))
+			},
+		},
+		"/sched/threads/total:threads": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				lock(&sched.lock)
+				out.scalar = uint64(mcount())
+				unlock(&sched.lock)
+			},
+		},
+		"/sched/timers/latency:seconds": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				hist := out.float64HistOrInit(timeHistBuckets)
+				hist.counts[0] = atomic.Load64(&timerLatency.underflow)
+				for i := range timerLatency.counts {
+					hist.counts[i+1] = atomic.Load64(&timerLatency.counts[i])
+				}
+			},
+		},
+		"/sched/timers/preemptions:requests": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = atomic.Load64(&timerPreempts)
+			},
+		},
+		"/symbols/cache/hits:lookups": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = atomic.Load64(&symCache.hits)
+			},
+		},
+		"/symbols/cache/misses:lookups": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = atomic.Load64(&symCache.misses)
+			},
+		},
+	}
+	for c := eventCounter(0); c < numEventCounters; c++ {
+		metrics[eventCounterNames[c]] = metricData{counter: c}
 	}
 	metricsInit = true
 }
@@ -482,7 +851,7 @@ func readMetrics(samplesp unsafe.Pointer, len int, cap int) {
 	// Acquire the metricsSema but with handoff. This operation
 	// is expensive enough that queueing up goroutines and handing
 	// off between them will be noticably better-behaved.
-	semacquire1(&metricsSema, true, 0, 0)
+	semacquire1(&metricsSema, true, 0, 0, waitReasonSemacquire, 0)
 
 	// Ensure the map is initialized.
 	initMetrics()
@@ -503,6 +872,11 @@ func readMetrics(samplesp unsafe.Pointer, len int, cap int) {
 		agg.ensure(&data.deps)
 
 		// Compute the value based on the stats we have.
+		if data.compute == nil {
+			sample.value.kind = metricKindUint64
+			sample.value.scalar = data.counter.load()
+			continue
+		}
 		data.compute(&agg, &sample.value)
 	}
 
@@ -51,6 +51,22 @@ type Description struct {
 // The English language descriptions below must be kept in sync with the
 // descriptions of each metric in doc.go.
 var allDesc = []Description{
+	{
+		Name:        "/cgo/extra-ms/freed:threads",
+		Description: "Count of extra Ms freed after being idle. An extra M is the runtime state that a thread created outside of Go uses to run Go code.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/cgo/extra-ms/idle:threads",
+		Description: "Number of extra Ms kept for threads created outside of Go to call into Go.",
+		Kind:        KindUint64,
+	},
+	{
+		Name:        "/cgo/extra-ms/in-use:threads",
+		Description: "Number of threads created outside of Go that are running Go code, each on an extra M.",
+		Kind:        KindUint64,
+	},
 	{
 		Name:        "/gc/cycles/automatic:gc-cycles",
 		Description: "Count of completed GC cycles generated by the Go runtime.",
@@ -63,6 +79,12 @@ var allDesc = []Description{
 		Kind:        KindUint64,
 		Cumulative:  true,
 	},
+	{
+		Name:        "/gc/cycles/pressure:gc-cycles",
+		Description: "Count of completed GC cycles run in response to memory pressure reported by the operating system. Only counted with GODEBUG=memorypressure=1. These cycles are also counted as forced.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
 	{
 		Name:        "/gc/cycles/total:gc-cycles",
 		Description: "Count of all completed GC cycles.",
@@ -171,11 +193,360 @@ var allDesc = []Description{
 		Description: "All memory mapped by the Go runtime into the current process as read-write. Note that this does not include memory mapped by code called via cgo or via the syscall package. Sum of all metrics in /memory/classes.",
 		Kind:        KindUint64,
 	},
+	{
+		Name:        "/sched/cpuquota/throttled:periods",
+		Description: "Count of CPU quota periods in which the processors above the whole number of CPUs of the quota were parked to stay within it. See runtime/debug.SetCPUQuota.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/defer/pool-hits:defers",
+		Description: "Count of defer records taken from a per-processor defer pool instead of being allocated on the heap.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/defer/pool-misses:defers",
+		Description: "Count of defer records allocated on the heap because the defer pool for their size was empty. See runtime/debug.SetDeferPoolDepth.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/gfree/contended:acquisitions",
+		Description: "Count of acquisitions of a lock of the central cache of exited goroutines, kept for reuse by new goroutines, that found the lock held by another processor.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/gfree/refills:refills",
+		Description: "Count of refills of the cache of exited goroutines of a processor from the central cache.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/gfree/released:goroutines",
+		Description: "Count of exited goroutines dropped by the runtime, leaving their memory to the garbage collector, after they went unused for a whole GC cycle.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/gfree/retained:bytes",
+		Description: "Memory occupied by the goroutine structures of exited goroutines, kept for reuse by new goroutines or not yet dropped. Their stacks are counted in /memory/classes/heap/stacks:bytes.",
+		Kind:        KindUint64,
+	},
+	{
+		Name:        "/sched/gfree/spills:spills",
+		Description: "Count of moves of half of a full processor cache of exited goroutines to the central cache.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/gfree/steals:refills",
+		Description: "Count of refills of the cache of exited goroutines of a processor from a shard of the central cache that belongs to other processors, because its own shard was empty.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/globrunq/goroutines:goroutines",
+		Description: "Count of goroutines processors took from the global run queue.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/globrunq/grabs:grabs",
+		Description: "Count of times a processor took goroutines from the global run queue, either to run one or because its local run queue was empty.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/goroutines-created:goroutines",
+		Description: "Count of goroutines created since the program started, not counting the runtime's own. A rate much higher than that of /sched/goroutines-exited:goroutines for a long time is an early sign of a goroutine leak.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/goroutines-exited:goroutines",
+		Description: "Count of goroutines that exited since the program started, not counting the runtime's own. Its difference from /sched/goroutines-created:goroutines is the number of goroutines alive.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
 	{
 		Name:        "/sched/goroutines:goroutines",
 		Description: "Count of live goroutines.",
 		Kind:        KindUint64,
 	},
+	{
+		Name:        "/sched/idle/poller-wakeups:wakeups",
+		Description: "Count of waits for timers and network events, started while every processor was idle, that ended at their deadline, usually to run a timer. See runtime/debug.SetDeepIdle.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/idle/sysmon-wakeups:wakeups",
+		Description: "Count of wakeups of the runtime's monitoring thread, sleeping while every processor was idle, at the end of its sleep, to check for timers and forced garbage collections. See runtime/debug.SetDeepIdle.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/inject/batches:batches",
+		Description: "Count of batches of goroutines made ready to run at once, such as by one poll of the network poller or by the garbage collector.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/inject/goroutines:goroutines",
+		Description: "Count of goroutines made ready to run in batches. See /sched/inject/batches:batches.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/inject/wakeups:wakeups",
+		Description: "Count of threads woken or started to run goroutines made ready in batches, with idle processors. Dividing by /sched/inject/batches:batches gives the wakeups per batch. See also runtime/debug.SetNetpollTuning.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/lock/contended:acquisitions",
+		Description: "Count of acquisitions of the runtime's internal locks, such as the scheduler and heap locks, that found the lock held.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/lock/sleeps:sleeps",
+		Description: "Count of times a thread slept in the operating system waiting for one of the runtime's internal locks.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/lock/wait:seconds",
+		Description: "Total time threads spent acquiring runtime internal locks that were held. See GODEBUG=lockpi.",
+		Kind:        KindFloat64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/netpoll/skipped-wakeups:wakeups",
+		Description: "Count of wake-ups of the blocked network poller skipped because a new timer was due within the slack set by runtime/debug.SetNetpollTuning.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/netpoll/spurious-wakeups:wakeups",
+		Description: "Count of blocking network polls that returned before their deadline with no goroutine ready to run, usually because the poller was woken for a new timer.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/netpoll/timer-wakeups:wakeups",
+		Description: "Count of blocking network polls that returned at their deadline, set by the earliest timer, with no goroutine ready to run.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/netpoll/waiters:goroutines",
+		Description: "Number of goroutines blocked waiting for network I/O.",
+		Kind:        KindUint64,
+	},
+	{
+		Name:        "/sched/netpoll/wakeups:wakeups",
+		Description: "Count of blocking network polls by idle threads that returned, for any reason.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/netpoll/wasted-wakeups:wakeups",
+		Description: "Count of blocking network polls counted in /sched/netpoll/timer-wakeups:wakeups after which no timer was due on the processor taken to run it, because the timer had been stopped or reset, was run by a busy processor, or no processor was idle.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/preempt/dropped:requests",
+		Description: "Count of asynchronous preemption requests that never reached their thread, because a preemption request was already pending or, on Windows, because the thread was running external code, exiting, or could not be suspended.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/preempt/handled:requests",
+		Description: "Count of asynchronous preemption requests handled by their thread, whether or not the goroutine running was stopped.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/preempt/injected:requests",
+		Description: "Count of asynchronous preemption requests that interrupted a goroutine at a point where it could be safely stopped.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/preempt/requests:requests",
+		Description: "Count of asynchronous preemption requests sent to threads whose goroutine the scheduler or garbage collector wanted to stop. Requests that were neither dropped nor injected found the goroutine at a point where it could not be stopped safely.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/stacks/copies:copies",
+		Description: "Count of goroutine stacks copied to a new stack, to grow or shrink them.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/steal/failures:attempts",
+		Description: "Count of attempts by an idle processor to steal goroutines from the local run queue of another processor that found none to steal. Many failures per success suggest GOMAXPROCS is higher than the parallelism of the program.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/steal/goroutines:goroutines",
+		Description: "Count of goroutines idle processors stole from the local run queues of other processors.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/steal/runnext:goroutines",
+		Description: "Count of goroutines idle processors stole from the slot of another processor for the goroutine it is to run next, which was made ready by the goroutine it is running. Such a steal breaks up goroutines that communicate with each other.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/steal/successes:attempts",
+		Description: "Count of attempts by an idle processor to steal goroutines from the local run queue of another processor that stole at least one.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/stw/gc:seconds",
+		Description: "Distribution of the time the world was stopped by the garbage collector, to start a cycle or to terminate marking, from the start of the stop until the world restarted.",
+		Kind:        KindFloat64Histogram,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/stw/gomaxprocs:seconds",
+		Description: "Distribution of the time the world was stopped to change GOMAXPROCS, from the start of the stop until the world restarted with the new processors.",
+		Kind:        KindFloat64Histogram,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/stw/memstats:seconds",
+		Description: "Distribution of the time the world was stopped to read memory statistics, as by runtime.ReadMemStats, from the start of the stop until the world restarted.",
+		Kind:        KindFloat64Histogram,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/stw/other:seconds",
+		Description: "Distribution of the time the world was stopped for reasons not covered by the other /sched/stw metrics, such as heap dumps, from the start of the stop until the world restarted.",
+		Kind:        KindFloat64Histogram,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/stw/profile:seconds",
+		Description: "Distribution of the time the world was stopped to collect goroutine, thread creation or stack profiles, from the start of the stop until the world restarted.",
+		Kind:        KindFloat64Histogram,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/stw/trace:seconds",
+		Description: "Distribution of the time the world was stopped to start, stop or snapshot the execution tracer, from the start of the stop until the world restarted.",
+		Kind:        KindFloat64Histogram,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/sudog/allocs:sudogs",
+		Description: "Count of sudogs, the records of goroutines waiting on channels, in select statements or on semaphores, allocated because the cache of their processor and the central cache were empty.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/sudog/contended:acquisitions",
+		Description: "Count of acquisitions of a lock of the central sudog cache that found the lock held by another processor.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/sudog/refills:refills",
+		Description: "Count of refills of the sudog cache of a processor from the central sudog cache.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/sudog/spills:spills",
+		Description: "Count of moves of half of a full processor sudog cache to the central sudog cache.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/sudog/steals:refills",
+		Description: "Count of refills of the sudog cache of a processor from a shard of the central sudog cache that belongs to other processors, because its own shard was empty.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/syscalls/idle-p:calls",
+		Description: "Count of returns from system calls that continued on another idle processor, one of the GOMAXPROCS, because theirs had been taken for other work during the call.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/syscalls/parked:calls",
+		Description: "Count of returns from system calls that found no idle processor, so that the goroutine was queued and its thread parked.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/syscalls/retaken-p:calls",
+		Description: "Count of returns from system calls that took back their own processor, which had been taken for other work during the call and had become idle again, as enabled by runtime/debug.SetSyscallTuning.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/threads/deferred:threads",
+		Description: "Count of threads not started, leaving goroutines waiting for a thread, because the limit set by runtime/debug.SetMaxThreads was reached while a handler registered by runtime/debug.SetThreadLimitHandler made it non-fatal.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/threads/idle-exits:threads",
+		Description: "Count of threads created by the runtime that exited after staying idle for longer than the timeout set by runtime/debug.SetIdleThreadTimeout.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/threads/idle:threads",
+		Description: "Number of threads created by the runtime that are idle, waiting for goroutines to run.",
+		Kind:        KindUint64,
+	},
+	{
+		Name:        "/sched/threads/peak:threads",
+		Description: "Highest number of threads used by the Go runtime at once.",
+		Kind:        KindUint64,
+	},
+	{
+		Name:        "/sched/threads/total:threads",
+		Description: "Number of threads used by the Go runtime, counted against the limit set by runtime/debug.SetMaxThreads.",
+		Kind:        KindUint64,
+	},
+	{
+		Name:        "/sched/timers/latency:seconds",
+		Description: "Distribution of the delays between the time at which timers were due and the time at which they ran.",
+		Kind:        KindFloat64Histogram,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/sched/timers/preemptions:requests",
+		Description: "Count of goroutines preempted because every processor was busy while a timer on one of them was overdue, so that the processor would run the timer.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/symbols/cache/hits:lookups",
+		Description: "Count of lookups of the file, line and inlined call at a program counter, for tracebacks, profiles and runtime.Frames, answered from the runtime's symbolization cache.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
+	{
+		Name:        "/symbols/cache/misses:lookups",
+		Description: "Count of lookups of the file, line and inlined call at a program counter that the symbolization cache could not answer, so that the runtime decoded its tables.",
+		Kind:        KindUint64,
+		Cumulative:  true,
+	},
 }
 
 // All returns a slice of containing metric descriptions for all supported metrics.
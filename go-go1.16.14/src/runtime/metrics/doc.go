@@ -51,12 +51,31 @@ Supported metrics
 
 Below is the full list of supported metrics, ordered lexicographically.
 
+	/cgo/extra-ms/freed:threads
+		Count of extra Ms freed after being idle. An extra M is the
+		runtime state that a thread created outside of Go uses to run
+		Go code.
+
+	/cgo/extra-ms/idle:threads
+		Number of extra Ms kept for threads created outside of Go to
+		call into Go.
+
+	/cgo/extra-ms/in-use:threads
+		Number of threads created outside of Go that are running Go
+		code, each on an extra M.
+
 	/gc/cycles/automatic:gc-cycles
 		Count of completed GC cycles generated by the Go runtime.
 
 	/gc/cycles/forced:gc-cycles
 		Count of completed GC cycles forced by the application.
 
+	/gc/cycles/pressure:gc-cycles
+		Count of completed GC cycles run in response to memory
+		pressure reported by the operating system. Only counted with
+		GODEBUG=memorypressure=1. These cycles are also counted as
+		forced.
+
 	/gc/cycles/total:gc-cycles
 		Count of all completed GC cycles.
 
@@ -137,7 +156,291 @@ Below is the full list of supported metrics, ordered lexicographically.
 		by code called via cgo or via the syscall package.
 		Sum of all metrics in /memory/classes.
 
+	/sched/cpuquota/throttled:periods
+		Count of CPU quota periods in which the processors above the
+		whole number of CPUs of the quota were parked to stay within
+		it. See runtime/debug.SetCPUQuota.
+
+	/sched/defer/pool-hits:defers
+		Count of defer records taken from a per-processor defer pool
+		instead of being allocated on the heap.
+
+	/sched/defer/pool-misses:defers
+		Count of defer records allocated on the heap because the defer
+		pool for their size was empty. See
+		runtime/debug.SetDeferPoolDepth.
+
+	/sched/gfree/contended:acquisitions
+		Count of acquisitions of a lock of the central cache of exited
+		goroutines, kept for reuse by new goroutines, that found the
+		lock held by another processor.
+
+	/sched/gfree/refills:refills
+		Count of refills of the cache of exited goroutines of a
+		processor from the central cache.
+
+	/sched/gfree/released:goroutines
+		Count of exited goroutines dropped by the runtime, leaving
+		their memory to the garbage collector, after they went unused
+		for a whole GC cycle.
+
+	/sched/gfree/retained:bytes
+		Memory occupied by the goroutine structures of exited
+		goroutines, kept for reuse by new goroutines or not yet
+		dropped. Their stacks are counted in
+		/memory/classes/heap/stacks:bytes.
+
+	/sched/gfree/spills:spills
+		Count of moves of half of a full processor cache of exited
+		goroutines to the central cache.
+
+	/sched/gfree/steals:refills
+		Count of refills of the cache of exited goroutines of a
+		processor from a shard of the central cache that belongs to
+		other processors, because its own shard was empty.
+
+	/sched/globrunq/goroutines:goroutines
+		Count of goroutines processors took from the global run queue.
+
+	/sched/globrunq/grabs:grabs
+		Count of times a processor took goroutines from the global run
+		queue, either to run one or because its local run queue was
+		empty.
+
+	/sched/goroutines-created:goroutines
+		Count of goroutines created since the program started, not
+		counting the runtime's own. A rate much higher than that of
+		/sched/goroutines-exited:goroutines for a long time is an early
+		sign of a goroutine leak.
+
+	/sched/goroutines-exited:goroutines
+		Count of goroutines that exited since the program started, not
+		counting the runtime's own. Its difference from
+		/sched/goroutines-created:goroutines is the number of goroutines
+		alive.
+
 	/sched/goroutines:goroutines
 		Count of live goroutines.
+
+	/sched/idle/poller-wakeups:wakeups
+		Count of waits for timers and network events, started while
+		every processor was idle, that ended at their deadline, usually
+		to run a timer. See runtime/debug.SetDeepIdle.
+
+	/sched/idle/sysmon-wakeups:wakeups
+		Count of wakeups of the runtime's monitoring thread, sleeping
+		while every processor was idle, at the end of its sleep, to
+		check for timers and forced garbage collections. See
+		runtime/debug.SetDeepIdle.
+
+	/sched/inject/batches:batches
+		Count of batches of goroutines made ready to run at once, such
+		as by one poll of the network poller or by the garbage
+		collector.
+
+	/sched/inject/goroutines:goroutines
+		Count of goroutines made ready to run in batches. See
+		/sched/inject/batches:batches.
+
+	/sched/inject/wakeups:wakeups
+		Count of threads woken or started to run goroutines made ready
+		in batches, with idle processors. Dividing by
+		/sched/inject/batches:batches gives the wakeups per batch. See
+		also runtime/debug.SetNetpollTuning.
+
+	/sched/lock/contended:acquisitions
+		Count of acquisitions of the runtime's internal locks, such as
+		the scheduler and heap locks, that found the lock held.
+
+	/sched/lock/sleeps:sleeps
+		Count of times a thread slept in the operating system waiting
+		for one of the runtime's internal locks.
+
+	/sched/lock/wait:seconds
+		Total time threads spent acquiring runtime internal locks that
+		were held. See GODEBUG=lockpi.
+
+	/sched/netpoll/skipped-wakeups:wakeups
+		Count of wake-ups of the blocked network poller skipped because
+		a new timer was due within the slack set by
+		runtime/debug.SetNetpollTuning.
+
+	/sched/netpoll/spurious-wakeups:wakeups
+		Count of blocking network polls that returned before their
+		deadline with no goroutine ready to run, usually because the
+		poller was woken for a new timer.
+
+	/sched/netpoll/timer-wakeups:wakeups
+		Count of blocking network polls that returned at their
+		deadline, set by the earliest timer, with no goroutine ready
+		to run.
+
+	/sched/netpoll/waiters:goroutines
+		Number of goroutines blocked waiting for network I/O.
+
+	/sched/netpoll/wakeups:wakeups
+		Count of blocking network polls by idle threads that returned,
+		for any reason.
+
+	/sched/netpoll/wasted-wakeups:wakeups
+		Count of blocking network polls counted in
+		/sched/netpoll/timer-wakeups:wakeups after which no timer was
+		due on the processor taken to run it, because the timer had
+		been stopped or reset, was run by a busy processor, or no
+		processor was idle.
+
+	/sched/preempt/dropped:requests
+		Count of asynchronous preemption requests that never reached
+		their thread, because a preemption request was already pending
+		or, on Windows, because the thread was running external code,
+		exiting, or could not be suspended.
+
+	/sched/preempt/handled:requests
+		Count of asynchronous preemption requests handled by their
+		thread, whether or not the goroutine running was stopped.
+
+	/sched/preempt/injected:requests
+		Count of asynchronous preemption requests that interrupted a
+		goroutine at a point where it could be safely stopped.
+
+	/sched/preempt/requests:requests
+		Count of asynchronous preemption requests sent to threads whose
+		goroutine the scheduler or garbage collector wanted to stop.
+		Requests that were neither dropped nor injected found the
+		goroutine at a point where it could not be stopped safely.
+
+	/sched/stacks/copies:copies
+		Count of goroutine stacks copied to a new stack, to grow or
+		shrink them.
+
+	/sched/steal/failures:attempts
+		Count of attempts by an idle processor to steal goroutines from
+		the local run queue of another processor that found none to
+		steal. Many failures per success suggest GOMAXPROCS is higher
+		than the parallelism of the program.
+
+	/sched/steal/goroutines:goroutines
+		Count of goroutines idle processors stole from the local run
+		queues of other processors.
+
+	/sched/steal/runnext:goroutines
+		Count of goroutines idle processors stole from the slot of
+		another processor for the goroutine it is to run next, which was
+		made ready by the goroutine it is running. Such a steal breaks
+		up goroutines that communicate with each other.
+
+	/sched/steal/successes:attempts
+		Count of attempts by an idle processor to steal goroutines from
+		the local run queue of another processor that stole at least
+		one.
+
+	/sched/stw/gc:seconds
+		Distribution of the time the world was stopped by the garbage
+		collector, to start a cycle or to terminate marking, from the
+		start of the stop until the world restarted.
+
+	/sched/stw/gomaxprocs:seconds
+		Distribution of the time the world was stopped to change
+		GOMAXPROCS, from the start of the stop until the world
+		restarted with the new processors.
+
+	/sched/stw/memstats:seconds
+		Distribution of the time the world was stopped to read memory
+		statistics, as by runtime.ReadMemStats, from the start of the
+		stop until the world restarted.
+
+	/sched/stw/other:seconds
+		Distribution of the time the world was stopped for reasons not
+		covered by the other /sched/stw metrics, such as heap dumps,
+		from the start of the stop until the world restarted.
+
+	/sched/stw/profile:seconds
+		Distribution of the time the world was stopped to collect
+		goroutine, thread creation or stack profiles, from the start of
+		the stop until the world restarted.
+
+	/sched/stw/trace:seconds
+		Distribution of the time the world was stopped to start, stop
+		or snapshot the execution tracer, from the start of the stop
+		until the world restarted.
+
+	/sched/sudog/allocs:sudogs
+		Count of sudogs, the records of goroutines waiting on channels,
+		in select statements or on semaphores, allocated because the
+		cache of their processor and the central cache were empty.
+
+	/sched/sudog/contended:acquisitions
+		Count of acquisitions of a lock of the central sudog cache that
+		found the lock held by another processor.
+
+	/sched/sudog/refills:refills
+		Count of refills of the sudog cache of a processor from the
+		central sudog cache.
+
+	/sched/sudog/spills:spills
+		Count of moves of half of a full processor sudog cache to the
+		central sudog cache.
+
+	/sched/sudog/steals:refills
+		Count of refills of the sudog cache of a processor from a shard
+		of the central sudog cache that belongs to other processors,
+		because its own shard was empty.
+
+	/sched/syscalls/idle-p:calls
+		Count of returns from system calls that continued on another
+		idle processor, one of the GOMAXPROCS, because theirs had been
+		taken for other work during the call.
+
+	/sched/syscalls/parked:calls
+		Count of returns from system calls that found no idle
+		processor, so that the goroutine was queued and its thread
+		parked.
+
+	/sched/syscalls/retaken-p:calls
+		Count of returns from system calls that took back their own
+		processor, which had been taken for other work during the call
+		and had become idle again, as enabled by
+		runtime/debug.SetSyscallTuning.
+
+	/sched/threads/deferred:threads
+		Count of threads not started, leaving goroutines waiting for
+		a thread, because the limit set by runtime/debug.SetMaxThreads
+		was reached while a handler registered by
+		runtime/debug.SetThreadLimitHandler made it non-fatal.
+
+	/sched/threads/idle-exits:threads
+		Count of threads created by the runtime that exited after
+		staying idle for longer than the timeout set by
+		runtime/debug.SetIdleThreadTimeout.
+
+	/sched/threads/idle:threads
+		Number of threads created by the runtime that are idle,
+		waiting for goroutines to run.
+
+	/sched/threads/peak:threads
+		Highest number of threads used by the Go runtime at once.
+
+	/sched/threads/total:threads
+		Number of threads used by the Go runtime, counted against the
+		limit set by runtime/debug.SetMaxThreads.
+
+	/sched/timers/latency:seconds
+		Distribution of the delays between the time at which timers
+		were due and the time at which they ran.
+
+	/sched/timers/preemptions:requests
+		Count of goroutines preempted because every processor was busy
+		while a timer on one of them was overdue, so that the processor
+		would run the timer.
+
+	/symbols/cache/hits:lookups
+		Count of lookups of the file, line and inlined call at a program
+		counter, for tracebacks, profiles and runtime.Frames, answered
+		from the runtime's symbolization cache.
+
+	/symbols/cache/misses:lookups
+		Count of lookups of the file, line and inlined call at a program
+		counter that the symbolization cache could not answer, so that
+		the runtime decoded its tables.
 */
 package metrics
@@ -5,10 +5,12 @@
 package runtime_test
 
 import (
+	"os"
 	"runtime"
 	"runtime/metrics"
 	"sort"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 	"unsafe"
@@ -221,6 +223,310 @@ func TestReadMetricsConsistency(t *testing.T) {
 	}
 }
 
+func TestReadMetricsAsyncPreempt(t *testing.T) {
+	if !runtime.PreemptMSupported {
+		t.Skip("asynchronous preemption not supported on this platform")
+	}
+	// Run in a new process, where nothing else has disabled
+	// asynchronous preemption.
+	output := runTestProg(t, "testprog", "AsyncPreemptMetrics")
+	want := "OK\n"
+	if output != want {
+		t.Fatalf("want %s, got %s\n", want, output)
+	}
+}
+
+func TestReadMetricsTimerPreempt(t *testing.T) {
+	if !runtime.PreemptMSupported {
+		t.Skip("asynchronous preemption not supported on this platform")
+	}
+	output := runTestProg(t, "testprog", "TimerPreemptMetrics")
+	want := "OK\n"
+	if output != want {
+		t.Fatalf("want %s, got %s\n", want, output)
+	}
+}
+
+func TestReadMetricsSTW(t *testing.T) {
+	samples := []metrics.Sample{
+		{Name: "/sched/stw/gc:seconds"},
+		{Name: "/sched/stw/gomaxprocs:seconds"},
+		{Name: "/sched/stw/memstats:seconds"},
+	}
+	count := func(s metrics.Sample) uint64 {
+		var n uint64
+		for _, c := range s.Value.Float64Histogram().Counts {
+			n += c
+		}
+		return n
+	}
+	metrics.Read(samples)
+	var before [3]uint64
+	for i := range samples {
+		before[i] = count(samples[i])
+	}
+
+	runtime.GC() // stops the world twice
+	// Only creating a P stops the world.
+	created, _ := runtime.ProcStats()
+	runtime.GOMAXPROCS(runtime.GOMAXPROCS(created + 1))
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	metrics.Read(samples)
+	for i, min := range []uint64{2, 1, 1} {
+		if got := count(samples[i]) - before[i]; got < min {
+			t.Errorf("%s: got %d new pauses, want at least %d", samples[i].Name, got, min)
+		}
+	}
+}
+
+func TestReadMetricsSudogCache(t *testing.T) {
+	samples := []metrics.Sample{
+		{Name: "/sched/sudog/allocs:sudogs"},
+		{Name: "/sched/sudog/refills:refills"},
+		{Name: "/sched/sudog/spills:spills"},
+	}
+	runtime.GC() // empty the central cache
+	metrics.Read(samples)
+	var before [3]uint64
+	for i := range samples {
+		before[i] = samples[i].Value.Uint64()
+	}
+
+	// Block many more goroutines than a P caches sudogs for, and
+	// wake them, twice: the first round allocates sudogs and spills
+	// them to the central cache, and the second takes them back.
+	const n = 1000
+	for round := 0; round < 2; round++ {
+		c := make(chan bool)
+		done := make(chan bool)
+		for i := 0; i < n; i++ {
+			go func() {
+				<-c
+				done <- true
+			}()
+		}
+		for runtime.NumGoroutine() < n {
+			runtime.Gosched()
+		}
+		time.Sleep(10 * time.Millisecond)
+		close(c)
+		for i := 0; i < n; i++ {
+			<-done
+		}
+	}
+
+	metrics.Read(samples)
+	for i := range samples {
+		if samples[i].Value.Uint64() == before[i] {
+			t.Errorf("%s did not change", samples[i].Name)
+		}
+	}
+}
+
+func TestReadMetricsSteal(t *testing.T) {
+	if runtime.NumCPU() < 2 {
+		t.Skip("need at least 2 CPUs")
+	}
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	samples := []metrics.Sample{
+		{Name: "/sched/globrunq/grabs:grabs"},
+		{Name: "/sched/globrunq/goroutines:goroutines"},
+		{Name: "/sched/steal/successes:attempts"},
+		{Name: "/sched/steal/goroutines:goroutines"},
+	}
+	metrics.Read(samples)
+	var before [4]uint64
+	for i := range samples {
+		before[i] = samples[i].Value.Uint64()
+	}
+
+	// Start more goroutines at once than fit in a local run queue,
+	// so that some go to the global run queue, and idle Ps steal
+	// from the local one.
+	const n = 1000
+	var wg sync.WaitGroup
+	for round := 0; round < 10; round++ {
+		wg.Add(n)
+		for i := 0; i < n; i++ {
+			go wg.Done()
+		}
+		wg.Wait()
+	}
+
+	metrics.Read(samples)
+	for i := range samples {
+		if samples[i].Value.Uint64() == before[i] {
+			t.Errorf("%s did not change", samples[i].Name)
+		}
+	}
+	if grabs, gs := samples[0].Value.Uint64()-before[0], samples[1].Value.Uint64()-before[1]; gs < grabs {
+		t.Errorf("%d goroutines taken from the global run queue in %d grabs", gs, grabs)
+	}
+	if steals, gs := samples[2].Value.Uint64()-before[2], samples[3].Value.Uint64()-before[3]; gs < steals {
+		t.Errorf("%d goroutines stolen in %d steals", gs, steals)
+	}
+}
+
+func TestReadMetricsGFreeCache(t *testing.T) {
+	samples := []metrics.Sample{
+		{Name: "/sched/gfree/refills:refills"},
+		{Name: "/sched/gfree/spills:spills"},
+	}
+	metrics.Read(samples)
+	var before [2]uint64
+	for i := range samples {
+		before[i] = samples[i].Value.Uint64()
+	}
+
+	// Start and end many more goroutines than a P caches at once,
+	// twice: the first round fills the central cache and the second
+	// takes the Gs back.
+	const n = 1000
+	for round := 0; round < 2; round++ {
+		var wg sync.WaitGroup
+		wg.Add(n)
+		for i := 0; i < n; i++ {
+			go wg.Done()
+		}
+		wg.Wait()
+	}
+
+	metrics.Read(samples)
+	for i := range samples {
+		if samples[i].Value.Uint64() == before[i] {
+			t.Errorf("%s did not change", samples[i].Name)
+		}
+	}
+}
+
+func TestReadMetricsGFreeRelease(t *testing.T) {
+	samples := []metrics.Sample{
+		{Name: "/sched/gfree/released:goroutines"},
+		{Name: "/sched/gfree/retained:bytes"},
+	}
+
+	// Leave many exited goroutines behind.
+	const n = 1000
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go wg.Done()
+	}
+	wg.Wait()
+	metrics.Read(samples)
+	released, retained := samples[0].Value.Uint64(), samples[1].Value.Uint64()
+	if retained == 0 {
+		t.Fatalf("%s is zero after %d goroutines exited", samples[1].Name, n)
+	}
+
+	// The first cycle frees their stacks, the second retires them,
+	// and the background sweeper drops them after it.
+	for i := 0; i < 100; i++ {
+		runtime.GC()
+		metrics.Read(samples)
+		if samples[0].Value.Uint64() > released && samples[1].Value.Uint64() < retained {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("exited goroutines not released: %s went from %d to %d, %s from %d to %d",
+		samples[0].Name, released, samples[0].Value.Uint64(),
+		samples[1].Name, retained, samples[1].Value.Uint64())
+}
+
+func TestReadMetricsGoroutineCounts(t *testing.T) {
+	samples := []metrics.Sample{
+		{Name: "/sched/goroutines-created:goroutines"},
+		{Name: "/sched/goroutines-exited:goroutines"},
+	}
+	metrics.Read(samples)
+	created, exited := samples[0].Value.Uint64(), samples[1].Value.Uint64()
+
+	const n = 100
+	stop := make(chan bool)
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			wg.Done()
+			<-stop
+		}()
+	}
+	wg.Wait()
+	metrics.Read(samples)
+	if got := samples[0].Value.Uint64() - created; got < n {
+		t.Errorf("%s grew by %d after starting %d goroutines", samples[0].Name, got, n)
+	}
+
+	// The goroutines exit some time after they receive.
+	close(stop)
+	for i := 0; ; i++ {
+		metrics.Read(samples)
+		if samples[1].Value.Uint64()-exited >= n {
+			break
+		}
+		if i == 1000 {
+			t.Fatalf("%s grew by %d after %d goroutines exited", samples[1].Name, samples[1].Value.Uint64()-exited, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestReadMetricsEventCounters(t *testing.T) {
+	samples := []metrics.Sample{
+		{Name: "/sched/stacks/copies:copies"},
+		{Name: "/sched/netpoll/waiters:goroutines"},
+	}
+	metrics.Read(samples)
+	copies := samples[0].Value.Uint64()
+
+	done := make(chan bool)
+	go func() {
+		growStack(nil)
+		done <- true
+	}()
+	<-done
+	metrics.Read(samples)
+	if samples[0].Value.Uint64() == copies {
+		t.Errorf("%s did not change after a goroutine grew its stack", samples[0].Name)
+	}
+
+	switch runtime.GOOS {
+	case "js", "plan9", "windows":
+		t.Skipf("pipes do not use the network poller on %s", runtime.GOOS)
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	waiters := samples[1].Value.Uint64()
+	go func() {
+		var b [1]byte
+		r.Read(b[:])
+		done <- true
+	}()
+	for i := 0; ; i++ {
+		metrics.Read(samples)
+		if samples[1].Value.Uint64() > waiters {
+			break
+		}
+		if i == 1000 {
+			t.Fatalf("%s did not grow while a goroutine read from a pipe", samples[1].Name)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	w.Write([]byte{0})
+	<-done
+	metrics.Read(samples)
+	if got := samples[1].Value.Uint64(); got > waiters {
+		t.Errorf("%s is %d after the reader returned, want at most %d", samples[1].Name, got, waiters)
+	}
+}
+
 func BenchmarkReadMetricsLatency(b *testing.B) {
 	stop := applyGCLoad(b)
 
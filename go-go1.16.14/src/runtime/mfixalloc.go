@@ -74,6 +74,9 @@ func (f *fixalloc) alloc() unsafe.Pointer {
 		if f.zero {
 			memclrNoHeapPointers(v, f.size)
 		}
+		if msanenabled {
+			msanmalloc(v, f.size)
+		}
 		return v
 	}
 	if uintptr(f.nchunk) < f.size {
@@ -88,10 +91,18 @@ func (f *fixalloc) alloc() unsafe.Pointer {
 	f.chunk = f.chunk + f.size
 	f.nchunk -= uint32(f.size)
 	f.inuse += f.size
+	if msanenabled {
+		msanmalloc(v, f.size)
+	}
 	return v
 }
 
 func (f *fixalloc) free(p unsafe.Pointer) {
+	if msanenabled {
+		// Poison the object so C code that keeps a pointer to it
+		// is reported. alloc unpoisons it again on reuse.
+		msanfree(p, f.size)
+	}
 	f.inuse -= f.size
 	v := (*mlink)(p)
 	v.next = f.list
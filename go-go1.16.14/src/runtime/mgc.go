@@ -453,8 +453,9 @@ func (c *gcControllerState) startCycle() {
 	// this may not come out exactly. We round the number of
 	// dedicated workers so that the utilization is closest to
 	// 25%. For small GOMAXPROCS, this would introduce too much
-	// error, so we add fractional workers in that case.
-	totalUtilizationGoal := float64(gomaxprocs) * gcBackgroundUtilization
+	// error, so we add fractional workers in that case. Under a
+	// CPU quota, the goal is 25% of the CPUs it allows.
+	totalUtilizationGoal := cpuQuotaProcs() * gcBackgroundUtilization
 	c.dedicatedMarkWorkersNeeded = int64(totalUtilizationGoal + 0.5)
 	utilError := float64(c.dedicatedMarkWorkersNeeded)/totalUtilizationGoal - 1
 	const maxUtilError = 0.3
@@ -484,6 +485,8 @@ func (c *gcControllerState) startCycle() {
 		p.gcFractionalMarkTime = 0
 	}
 
+	gcIdleOnlyStart()
+
 	// Compute initial values for controls that are updated
 	// throughout the cycle.
 	c.revise()
@@ -495,7 +498,11 @@ func (c *gcControllerState) startCycle() {
 			work.initialHeapLive>>20, "->",
 			memstats.next_gc>>20, " MB)",
 			" workers=", c.dedicatedMarkWorkersNeeded,
-			"+", c.fractionalUtilizationGoal, "\n")
+			"+", c.fractionalUtilizationGoal)
+		if gcIdleOnlyActive() {
+			print(" (idle-only)")
+		}
+		print("\n")
 	}
 }
 
@@ -530,6 +537,7 @@ func (c *gcControllerState) revise() {
 	live := atomic.Load64(&memstats.heap_live)
 	scan := atomic.Load64(&memstats.heap_scan)
 	work := atomic.Loadint64(&c.scanWork)
+	gcIdleOnlyCheck(live)
 
 	// Assume we're under the soft goal. Pace GC to complete at
 	// next_gc assuming the heap is in steady-state.
@@ -633,7 +641,7 @@ func (c *gcControllerState) endCycle() float64 {
 	utilization := gcBackgroundUtilization
 	// Add assist utilization; avoid divide by zero.
 	if assistDuration > 0 {
-		utilization += float64(c.assistTime) / float64(assistDuration*int64(gomaxprocs))
+		utilization += float64(c.assistTime) / (float64(assistDuration) * cpuQuotaProcs())
 	}
 
 	triggerError := goalGrowthRatio - memstats.triggerRatio - utilization/gcGoalUtilization*(actualGrowthRatio-memstats.triggerRatio)
@@ -728,6 +736,11 @@ func (c *gcControllerState) findRunnableGCWorker(_p_ *p) *g {
 		return nil
 	}
 
+	if gcIdleOnlyActive() {
+		// Only idle workers mark in this cycle.
+		return nil
+	}
+
 	// Grab a worker before we commit to running below.
 	node := (*gcBgMarkWorkerNode)(gcBgMarkWorkerPool.pop())
 	if node == nil {
@@ -903,6 +916,9 @@ func gcSetTriggerRatio(triggerRatio float64) {
 		}
 	}
 
+	// Let runtime/debug.DisableGC override them.
+	trigger, goal = gcDisableTrigger(trigger, goal)
+
 	// Commit to the trigger and goal.
 	memstats.gc_trigger = trigger
 	atomic.Store64(&memstats.next_gc, goal)
@@ -1196,6 +1212,28 @@ func GC() {
 	releasem(mp)
 }
 
+// GCAsync starts a garbage collection, like GC, but does not wait for
+// it. It returns a channel that is closed once the collection has
+// marked the heap. Runtime statistics such as MemStats then reflect
+// the collection, while freed memory is still swept in the background.
+//
+// If a collection is already running, it must finish before a new one
+// can start, as with GC. GCAsync does that waiting on a goroutine of
+// its own, so the caller is never blocked.
+func GCAsync() <-chan struct{} {
+	done := make(chan struct{})
+	// As in GC, wait for the current cycle N to finish marking
+	// before triggering cycle N+1, then wait for N+1 to be marked.
+	n := atomic.Load(&work.cycles)
+	go func() {
+		gcWaitOnMark(n)
+		gcStart(gcTrigger{kind: gcTriggerCycle, n: n + 1})
+		gcWaitOnMark(n + 1)
+		close(done)
+	}()
+	return done
+}
+
 // gcWaitOnMark blocks until GC finishes the Nth mark phase. If GC has
 // already completed this mark phase, it returns immediately.
 func gcWaitOnMark(n uint32) {
@@ -1271,11 +1309,15 @@ func (t gcTrigger) test() bool {
 		// own write.
 		return memstats.heap_live >= memstats.gc_trigger
 	case gcTriggerTime:
-		if gcpercent < 0 {
+		if gcpercent < 0 || gcDisabled() {
+			return false
+		}
+		period := atomic.Loadint64(&forcegcperiod)
+		if period < 0 {
 			return false
 		}
 		lastgc := int64(atomic.Load64(&memstats.last_gc_nanotime))
-		return lastgc != 0 && t.now-lastgc > forcegcperiod
+		return lastgc != 0 && t.now-lastgc > period
 	case gcTriggerCycle:
 		// t.n > work.cycles, but accounting for wraparound.
 		return int32(t.n-work.cycles) > 0
@@ -2269,16 +2311,7 @@ func clearpools() {
 
 	// Clear central sudog cache.
 	// Leave per-P caches alone, they have strictly bounded size.
-	// Disconnect cached list before dropping it on the floor,
-	// so that a dangling ref to one entry does not pin all of them.
-	lock(&sched.sudoglock)
-	var sg, sgnext *sudog
-	for sg = sched.sudogcache; sg != nil; sg = sgnext {
-		sgnext = sg.next
-		sg.next = nil
-	}
-	sched.sudogcache = nil
-	unlock(&sched.sudoglock)
+	clearSudogCentral()
 
 	// Clear central defer pools.
 	// Leave per-P pools alone, they have strictly bounded size.
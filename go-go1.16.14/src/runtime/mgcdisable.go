@@ -0,0 +1,100 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Scoped GC disables.
+//
+// runtime/debug.DisableGC turns off the collections triggered by heap
+// growth and by forcegc until every disable it handed out has been
+// released, unlike SetGCPercent(-1), whose effect lasts until somebody
+// sets the percentage again. gcSetTriggerRatio applies the disables on
+// top of the trigger computed from gcpercent, so they compose with it
+// and with each other. If a limit is set, the trigger is never above
+// it, so the heap cannot grow without bound while GC is disabled.
+
+package runtime
+
+import (
+	"runtime/internal/atomic"
+	_ "unsafe" // for go:linkname
+)
+
+var gcDisable struct {
+	// n is the number of disables held. It is written with
+	// mheap_.lock held and read atomically by gcTrigger.test.
+	n uint32
+
+	// limit is the heap size at which a collection starts even
+	// while disables are held, or 0 if there is none. Protected by
+	// mheap_.lock.
+	limit uint64
+}
+
+// gcDisableTrigger returns the trigger and goal to use instead of
+// trigger and goal while GC is disabled. mheap_.lock must be held.
+func gcDisableTrigger(trigger, goal uint64) (uint64, uint64) {
+	assertWorldStoppedOrLockHeld(&mheap_.lock)
+
+	if gcDisable.n == 0 {
+		return trigger, goal
+	}
+	if limit := gcDisable.limit; limit != 0 {
+		if trigger > limit || trigger == ^uint64(0) {
+			trigger = limit
+		}
+		if goal < trigger {
+			goal = trigger
+		}
+		return trigger, goal
+	}
+	return ^uint64(0), ^uint64(0)
+}
+
+// gcDisabled reports whether any disable is held.
+func gcDisabled() bool {
+	return atomic.Load(&gcDisable.n) != 0
+}
+
+// setGCDisabled takes a disable if disable is true and releases one
+// otherwise. Taking the first disable waits for the GC cycle in
+// progress, if any, to finish its mark phase.
+//
+//go:linkname setGCDisabled runtime/debug.setGCDisabled
+func setGCDisabled(disable bool) {
+	first := false
+	systemstack(func() {
+		lock(&mheap_.lock)
+		if disable {
+			first = gcDisable.n == 0
+			atomic.Store(&gcDisable.n, gcDisable.n+1)
+		} else {
+			if gcDisable.n == 0 {
+				unlock(&mheap_.lock)
+				throw("runtime: GC disable released too many times")
+			}
+			atomic.Store(&gcDisable.n, gcDisable.n-1)
+		}
+		gcSetTriggerRatio(memstats.triggerRatio)
+		unlock(&mheap_.lock)
+	})
+
+	// As in setGCPercent, return with no GC running.
+	if first {
+		gcWaitOnMark(atomic.Load(&work.cycles))
+	}
+}
+
+//go:linkname setGCDisableLimit runtime/debug.setGCDisableLimit
+func setGCDisableLimit(limit int64) (prev int64) {
+	if limit < 0 {
+		limit = 0
+	}
+	systemstack(func() {
+		lock(&mheap_.lock)
+		prev = int64(gcDisable.limit)
+		gcDisable.limit = uint64(limit)
+		gcSetTriggerRatio(memstats.triggerRatio)
+		unlock(&mheap_.lock)
+	})
+	return prev
+}
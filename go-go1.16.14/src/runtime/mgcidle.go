@@ -0,0 +1,81 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Idle-only GC cycles.
+//
+// Normally the mark phase takes 25% of the CPU with dedicated and
+// fractional workers, and allocating goroutines assist it in
+// proportion to their allocations, so that it finishes by the heap
+// goal. With runtime/debug.SetIdleGCThreshold, a cycle that starts
+// while the heap is below the threshold is idle-only instead: it
+// marks only with idle workers, on Ps that have nothing else to run,
+// and allocations do not assist. A busy program thus keeps all its CPU
+// and lets the heap grow past the goal. Once the heap reaches the
+// threshold, the cycle continues as a normal one, with the workers and
+// assists the pacer computed at its start.
+//
+// Explicit collections, by runtime.GC and the like, are never
+// idle-only, since their callers wait for them to finish.
+
+package runtime
+
+import (
+	"runtime/internal/atomic"
+	_ "unsafe" // for go:linkname
+)
+
+var gcIdleOnly struct {
+	// threshold is the heap size below which cycles are
+	// idle-only, or 0. Accessed atomically.
+	threshold uint64
+
+	// active is 1 while the current cycle is idle-only. It is set
+	// with the world stopped at the start of a cycle and cleared
+	// atomically.
+	active uint32
+}
+
+//go:linkname setIdleGCThreshold runtime/debug.setIdleGCThreshold
+func setIdleGCThreshold(threshold int64) (prev int64) {
+	if threshold < 0 {
+		threshold = 0
+	}
+	return int64(atomic.Xchg64(&gcIdleOnly.threshold, uint64(threshold)))
+}
+
+// gcIdleOnlyStart decides whether the cycle that is starting is
+// idle-only. The world must be stopped.
+func gcIdleOnlyStart() {
+	assertWorldStopped()
+
+	gcIdleOnly.active = 0
+	if work.userForced || debug.gcstoptheworld > 0 {
+		return
+	}
+	if threshold := atomic.Load64(&gcIdleOnly.threshold); memstats.heap_live < threshold {
+		gcIdleOnly.active = 1
+	}
+}
+
+// gcIdleOnlyCheck ends the idle-only mode of the current cycle if the
+// heap has reached the threshold since, or if the threshold was
+// removed. It is called by gcControllerState.revise, which runs as the
+// heap grows.
+func gcIdleOnlyCheck(live uint64) {
+	if atomic.Load(&gcIdleOnly.active) == 0 {
+		return
+	}
+	if live >= atomic.Load64(&gcIdleOnly.threshold) {
+		atomic.Store(&gcIdleOnly.active, 0)
+	}
+}
+
+// gcIdleOnlyActive reports whether the current cycle is idle-only, so
+// that it must run neither dedicated nor fractional workers nor
+// assists.
+//
+//go:nosplit
+func gcIdleOnlyActive() bool {
+	return atomic.Load(&gcIdleOnly.active) != 0
+}
@@ -269,16 +269,27 @@ func markrootBlock(b0, n0 uintptr, ptrmask0 *uint8, gcw *gcWork, shard int) {
 	scanblock(b, n, ptrmask, gcw, nil)
 }
 
-// markrootFreeGStacks frees stacks of dead Gs.
+// markrootFreeGStacks frees stacks of dead Gs, and retires the dead Gs
+// whose stacks the previous cycle freed.
 //
 // This does not free stacks of dead Gs cached on Ps, but having a few
 // cached stacks around isn't a problem.
 func markrootFreeGStacks() {
-	// Take list of dead Gs with stacks.
-	lock(&sched.gFree.lock)
-	list := sched.gFree.stack
-	sched.gFree.stack = gList{}
-	unlock(&sched.gFree.lock)
+	for i := range gFreeCentral {
+		markrootFreeGStacksShard(&gFreeCentral[i].shard)
+	}
+}
+
+func markrootFreeGStacksShard(s *gFreeShard) {
+	// Take list of dead Gs with stacks, and of those without,
+	// which went unused since their stacks were freed.
+	s.lock.lock()
+	list := s.stack
+	s.stack = gList{}
+	old := s.noStack
+	s.noStack = gList{}
+	s.lock.unlock()
+	retireGs(s, old)
 	if list.empty() {
 		return
 	}
@@ -295,9 +306,9 @@ func markrootFreeGStacks() {
 	}
 
 	// Put Gs back on the free list.
-	lock(&sched.gFree.lock)
-	sched.gFree.noStack.pushAll(q)
-	unlock(&sched.gFree.lock)
+	s.lock.lock()
+	s.noStack.pushAll(q)
+	s.lock.unlock()
 }
 
 // markrootSpans marks roots for one shard of markArenas.
@@ -393,6 +404,13 @@ func gcAssistAlloc(gp *g) {
 	if mp := getg().m; mp.locks > 0 || mp.preemptoff != "" {
 		return
 	}
+	if gcIdleOnlyActive() {
+		// Idle-only cycles do not charge allocations. Forgive
+		// the debt, or it would all come due if the cycle
+		// stops being idle-only.
+		gp.gcAssistBytes = 0
+		return
+	}
 
 	traced := false
 retry:
@@ -867,7 +885,7 @@ func scanframeworker(frame *stkframe, state *stackScanState, gcw *gcWork) {
 	}
 
 	isAsyncPreempt := frame.fn.valid() && frame.fn.funcID == funcID_asyncPreempt
-	isDebugCall := frame.fn.valid() && frame.fn.funcID == funcID_debugCallV1
+	isDebugCall := frame.fn.valid() && (frame.fn.funcID == funcID_debugCallV1 || frame.fn.funcID == funcID_debugCallV2)
 	if state.conservative || isAsyncPreempt || isDebugCall {
 		if debugScanConservative {
 			println("conservatively scanning function", funcname(frame.fn), "at PC", hex(frame.continpc))
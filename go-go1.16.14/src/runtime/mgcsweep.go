@@ -170,6 +170,7 @@ func bgsweep(c chan int) {
 		for freeSomeWbufs(true) {
 			Gosched()
 		}
+		releaseRetiredGs()
 		lock(&sweep.lock)
 		if !isSweepDone() {
 			// This can happen if a GC runs between
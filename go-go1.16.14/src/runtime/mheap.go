@@ -884,6 +884,7 @@ const (
 	spanAllocStack                              // stack span
 	spanAllocPtrScalarBits                      // unrolled GC prog bitmap span
 	spanAllocWorkBuf                            // work buf span
+	spanAllocOOMReserve                         // out of memory reserve span
 )
 
 // manual returns true if the span allocation is manually managed.
@@ -940,7 +941,14 @@ func (h *mheap) allocManual(npages uintptr, typ spanAllocType) *mspan {
 	if !typ.manual() {
 		throw("manual span allocation called with non-manually-managed type")
 	}
-	return h.allocSpan(npages, typ, 0)
+	s := h.allocSpan(npages, typ, 0)
+	if s != nil && msanenabled {
+		// freeSpan and freeManual poison the pages they release,
+		// and the runtime does not instrument its own writes, so
+		// mark the whole span initialized for its new owner.
+		msanmalloc(unsafe.Pointer(s.base()), npages<<_PageShift)
+	}
+	return s
 }
 
 // setSpans modifies the span map so [spanOf(base), spanOf(base+npage*pageSize))
@@ -1275,8 +1283,10 @@ HaveSpan:
 	if typ == spanAllocHeap {
 		atomic.Xadd64(&memstats.heap_inuse, int64(nbytes))
 	}
-	if typ.manual() {
+	if typ.manual() && typ != spanAllocOOMReserve {
 		// Manually managed memory doesn't count toward heap_sys.
+		// The out of memory reserve does, as idle heap memory,
+		// since it returns to the heap when it is used.
 		memstats.heap_sys.add(-int64(nbytes))
 	}
 	// Update consistent stats.
@@ -1432,6 +1442,9 @@ func (h *mheap) freeSpan(s *mspan) {
 //go:systemstack
 func (h *mheap) freeManual(s *mspan, typ spanAllocType) {
 	s.needzero = 1
+	if msanenabled {
+		msanfree(unsafe.Pointer(s.base()), s.npages<<_PageShift)
+	}
 	lock(&h.lock)
 	h.freeSpanLocked(s, typ)
 	unlock(&h.lock)
@@ -1466,7 +1479,7 @@ func (h *mheap) freeSpanLocked(s *mspan, typ spanAllocType) {
 	if typ == spanAllocHeap {
 		atomic.Xadd64(&memstats.heap_inuse, -int64(nbytes))
 	}
-	if typ.manual() {
+	if typ.manual() && typ != spanAllocOOMReserve {
 		// Manually managed memory doesn't count toward heap_sys, so add it back.
 		memstats.heap_sys.add(int64(nbytes))
 	}
@@ -0,0 +1,175 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package runtime
+
+import "unsafe"
+
+// A minidump is a compact binary record of a fatal signal, written to
+// the file descriptor set by runtime/debug.SetCrashDumpFD. It holds
+// enough state to symbolize the faulting goroutine offline when a full
+// core dump is not available.
+//
+// The dump starts with the 8-byte magic "GOMINI01" and is followed by
+// a sequence of records. Each record is a header of two uint64 words,
+// the record kind and the length of the payload in bytes, followed by
+// the payload. All words are uint64 in host byte order.
+//
+//	minidumpSignal: sig, sigcode, fault address, pc, sp, lr, goid, m id
+//	minidumpRegs:   the registers in the order dumpregs prints them
+//	minidumpStack:  the stack address, followed by the stack bytes
+//	minidumpModule: minpc, maxpc, text, etext, name length, name bytes
+//	minidumpEnd:    empty; marks a complete dump
+//
+// Stack and module payloads are padded with zero bytes to a multiple
+// of 8; the padding is not included in the record length.
+//
+// Everything here runs in a signal handler, so it must not allocate
+// or acquire locks.
+const (
+	minidumpEnd = iota
+	minidumpSignal
+	minidumpRegs
+	minidumpStack
+	minidumpModule
+)
+
+// minidumpMaxStack bounds the stack memory recorded in a minidump.
+const minidumpMaxStack = 64 << 10
+
+// minidump buffers writes of a minidump to a file descriptor.
+type minidump struct {
+	fd  int32
+	n   int
+	buf [512]byte
+
+	// regs is the start of the register record in buf, used to
+	// patch the record length once all registers are written.
+	regs  int
+	nregs int
+}
+
+func (d *minidump) flush() {
+	p := d.buf[:d.n]
+	for len(p) > 0 {
+		n := write(uintptr(d.fd), unsafe.Pointer(&p[0]), int32(len(p)))
+		if n <= 0 {
+			break
+		}
+		p = p[n:]
+	}
+	d.n = 0
+}
+
+func (d *minidump) bytes(p unsafe.Pointer, n uintptr) {
+	for n > 0 {
+		if d.n == len(d.buf) {
+			d.flush()
+		}
+		m := uintptr(len(d.buf) - d.n)
+		if m > n {
+			m = n
+		}
+		memmove(unsafe.Pointer(&d.buf[d.n]), p, m)
+		d.n += int(m)
+		p = add(p, m)
+		n -= m
+	}
+}
+
+func (d *minidump) word(v uint64) {
+	d.bytes(unsafe.Pointer(&v), 8)
+}
+
+func (d *minidump) pad(n uintptr) {
+	var zero [8]byte
+	if r := n % 8; r != 0 {
+		d.bytes(unsafe.Pointer(&zero[0]), 8-r)
+	}
+}
+
+func (d *minidump) header(kind, length uint64) {
+	d.word(kind)
+	d.word(length)
+}
+
+// reg appends a register to the register record started by writeMinidump.
+// The whole record fits in buf, so its length can be patched in place.
+func (d *minidump) reg(v uint64) {
+	d.word(v)
+	d.nregs++
+}
+
+// writeMinidump writes a minidump for the fatal signal sig, delivered
+// with context c while gp was running, to crashDumpFD.
+func writeMinidump(sig uint32, c *sigctxt, gp *g) {
+	var d minidump
+	d.fd = crashDumpFD
+	d.bytes(unsafe.Pointer(&minidumpMagic[0]), uintptr(len(minidumpMagic)))
+
+	d.header(minidumpSignal, 8*8)
+	d.word(uint64(sig))
+	d.word(uint64(c.sigcode()))
+	d.word(uint64(c.fault()))
+	d.word(uint64(c.sigpc()))
+	d.word(uint64(c.sigsp()))
+	d.word(uint64(c.siglr()))
+	var goid, mid int64
+	if gp != nil {
+		goid = gp.goid
+		if gp.m != nil {
+			mid = gp.m.id
+		}
+	}
+	d.word(uint64(goid))
+	d.word(uint64(mid))
+
+	d.flush()
+	d.header(minidumpRegs, 0)
+	d.regs = d.n
+	minidumpregs(c, &d)
+	*(*uint64)(unsafe.Pointer(&d.buf[d.regs-8])) = uint64(d.nregs * 8)
+	d.flush()
+
+	// Record the stack of the faulting goroutine, from the signal's stack
+	// pointer up. If the signal arrived while not on gp's stack (say, on
+	// g0 during a system call), use gp's saved stack pointer instead.
+	if gp != nil {
+		sp := c.sigsp()
+		if sp < gp.stack.lo || sp >= gp.stack.hi {
+			sp = gp.sched.sp
+		}
+		if sp >= gp.stack.lo && sp < gp.stack.hi {
+			n := gp.stack.hi - sp
+			if n > minidumpMaxStack {
+				n = minidumpMaxStack
+			}
+			d.header(minidumpStack, uint64(8+n))
+			d.word(uint64(sp))
+			d.bytes(unsafe.Pointer(sp), n)
+			d.pad(n)
+		}
+	}
+
+	for md := &firstmoduledata; md != nil; md = md.next {
+		name := md.modulename
+		d.header(minidumpModule, uint64(5*8+len(name)))
+		d.word(uint64(md.minpc))
+		d.word(uint64(md.maxpc))
+		d.word(uint64(md.text))
+		d.word(uint64(md.etext))
+		d.word(uint64(len(name)))
+		if len(name) > 0 {
+			d.bytes(unsafe.Pointer(stringStructOf(&name).str), uintptr(len(name)))
+			d.pad(uintptr(len(name)))
+		}
+	}
+
+	d.header(minidumpEnd, 0)
+	d.flush()
+}
+
+var minidumpMagic = [8]byte{'G', 'O', 'M', 'I', 'N', 'I', '0', '1'}
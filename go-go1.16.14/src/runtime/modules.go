@@ -0,0 +1,84 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+// A ModuleSection is the range of addresses [Start, End) of a section
+// of a module.
+type ModuleSection struct {
+	Start, End uintptr
+}
+
+// Contains reports whether addr is in r.
+func (r ModuleSection) Contains(addr uintptr) bool {
+	return r.Start <= addr && addr < r.End
+}
+
+// A Module describes a module of the running program: the executable,
+// a shared library it was dynamically linked against, or a plugin
+// loaded by the plugin package. The sections are those of the
+// module's Go code and data; in a module linked with C code they may
+// hold C code and data as well.
+//
+// For the versions of the main module and its dependencies, see
+// runtime/debug.ReadBuildInfo.
+type Module struct {
+	// Name is the file name of a shared library, as the linker
+	// knew it, "the executable" for an executable linked against
+	// shared libraries, or "" otherwise.
+	Name string
+
+	// PluginPath is the package path of a plugin, or "" for other
+	// modules.
+	PluginPath string
+
+	// Main reports whether the module holds the main function.
+	Main bool
+
+	Text      ModuleSection // code
+	NoPtrData ModuleSection // initialized data without pointers
+	Data      ModuleSection // initialized data with pointers
+	BSS       ModuleSection // zeroed data with pointers
+	NoPtrBSS  ModuleSection // zeroed data without pointers
+	Types     ModuleSection // type descriptors
+}
+
+// Modules returns the modules of the program, in the order they were
+// loaded, the executable first. Modules loaded by a plugin.Open call
+// in progress may be missing.
+func Modules() []Module {
+	mds := activeModules()
+	ms := make([]Module, len(mds))
+	for i, md := range mds {
+		ms[i] = md.module()
+	}
+	return ms
+}
+
+// ModuleForPC returns the module whose code holds pc, and whether
+// there is one. It does not allocate, so crash handlers and profilers
+// may call it on program counters they sample.
+func ModuleForPC(pc uintptr) (Module, bool) {
+	for _, md := range activeModules() {
+		if md.text <= pc && pc < md.etext {
+			return md.module(), true
+		}
+	}
+	return Module{}, false
+}
+
+// module returns the description of md.
+func (md *moduledata) module() Module {
+	return Module{
+		Name:       md.modulename,
+		PluginPath: md.pluginpath,
+		Main:       md.hasmain != 0,
+		Text:       ModuleSection{md.text, md.etext},
+		NoPtrData:  ModuleSection{md.noptrdata, md.enoptrdata},
+		Data:       ModuleSection{md.data, md.edata},
+		BSS:        ModuleSection{md.bss, md.ebss},
+		NoPtrBSS:   ModuleSection{md.noptrbss, md.enoptrbss},
+		Types:      ModuleSection{md.types, md.etypes},
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime_test
+
+import (
+	"runtime"
+	"testing"
+	"unsafe"
+)
+
+var (
+	moduleNoPtrData = 7
+	moduleData      = &moduleNoPtrData
+	moduleBSS       *int
+	moduleNoPtrBSS  int
+)
+
+func TestModules(t *testing.T) {
+	ms := runtime.Modules()
+	if len(ms) == 0 {
+		t.Fatal("Modules returned no modules")
+	}
+	exe := ms[0]
+	if !exe.Main {
+		t.Errorf("first module %+v does not hold the main function", exe)
+	}
+	for _, c := range []struct {
+		name string
+		r    runtime.ModuleSection
+		addr uintptr
+	}{
+		{"Text", exe.Text, runtime.FuncPC(TestModules)},
+		{"NoPtrData", exe.NoPtrData, uintptr(unsafe.Pointer(&moduleNoPtrData))},
+		{"Data", exe.Data, uintptr(unsafe.Pointer(&moduleData))},
+		{"BSS", exe.BSS, uintptr(unsafe.Pointer(&moduleBSS))},
+		{"NoPtrBSS", exe.NoPtrBSS, uintptr(unsafe.Pointer(&moduleNoPtrBSS))},
+	} {
+		if !c.r.Contains(c.addr) {
+			t.Errorf("%s %#x-%#x does not contain %#x", c.name, c.r.Start, c.r.End, c.addr)
+		}
+	}
+
+	m, ok := runtime.ModuleForPC(runtime.FuncPC(TestModules))
+	if !ok || m != exe {
+		t.Errorf("ModuleForPC(TestModules) = %+v, %v; want %+v, true", m, ok, exe)
+	}
+	if m, ok := runtime.ModuleForPC(exe.Text.End); ok && m == exe {
+		t.Errorf("ModuleForPC(%#x) is the executable, whose text ends there", exe.Text.End)
+	}
+	if m, ok := runtime.ModuleForPC(0); ok {
+		t.Errorf("ModuleForPC(0) = %+v, true; want false", m)
+	}
+}
@@ -23,6 +23,10 @@ const (
 	memProfile bucketType = 1 + iota
 	blockProfile
 	mutexProfile
+	offcpuProfile
+	scopeProfile
+	starveProfile
+	gsampleProfile
 
 	// size of bucket hash table
 	buckHashSize = 179999
@@ -48,7 +52,7 @@ type bucketType int
 type bucket struct {
 	next    *bucket
 	allnext *bucket
-	typ     bucketType // memBucket or blockBucket (includes mutexProfile)
+	typ     bucketType // memBucket or blockBucket (includes mutexProfile, offcpuProfile, scopeProfile, starveProfile, and gsampleProfile)
 	hash    uintptr
 	size    uintptr
 	nstk    uintptr
@@ -114,6 +118,11 @@ type memRecord struct {
 	// C becomes the active cycle and when we've flushed it to
 	// active.
 	future [3]memRecordCycle
+
+	// recentAllocs and recentAllocBytes count the allocations
+	// published to active, halved every mProf.decay nanoseconds so
+	// that they reflect recent allocation rather than the whole run.
+	recentAllocs, recentAllocBytes uintptr
 }
 
 // memRecordCycle
@@ -130,8 +139,20 @@ func (a *memRecordCycle) add(b *memRecordCycle) {
 	a.free_bytes += b.free_bytes
 }
 
+// publish accumulates the cycle c into the published profile and
+// clears it for reuse.
+func (mp *memRecord) publish(c *memRecordCycle) {
+	mp.active.add(c)
+	mp.recentAllocs += c.allocs
+	mp.recentAllocBytes += c.alloc_bytes
+	*c = memRecordCycle{}
+}
+
 // A blockRecord is the bucket data for a bucket of type blockProfile,
-// which is used in blocking and mutex profiles.
+// which is used in blocking, mutex, off-CPU, scoped allocation, and mutex
+// starvation profiles. For the off-CPU and mutex starvation profiles, cycles
+// is in nanoseconds. For the scoped allocation profile, count is in objects
+// and cycles is in bytes.
 type blockRecord struct {
 	count  int64
 	cycles int64
@@ -141,6 +162,10 @@ var (
 	mbuckets  *bucket // memory profile buckets
 	bbuckets  *bucket // blocking profile buckets
 	xbuckets  *bucket // mutex profile buckets
+	obuckets  *bucket // off-CPU profile buckets
+	sbuckets  *bucket // scoped allocation profile buckets
+	vbuckets  *bucket // mutex starvation profile buckets
+	gbuckets  *bucket // goroutine sample profile buckets
 	buckhash  *[179999]*bucket
 	bucketmem uintptr
 
@@ -153,6 +178,12 @@ var (
 		// flushed indicates that future[cycle] in all buckets
 		// has been flushed to the active profile.
 		flushed bool
+		// decay is the half-life in nanoseconds of the recent
+		// allocation counts, or 0 if they do not decay.
+		decay int64
+		// decayed is the nanotime up to which the recent
+		// allocation counts have been decayed.
+		decayed int64
 	}
 )
 
@@ -166,7 +197,7 @@ func newBucket(typ bucketType, nstk int) *bucket {
 		throw("invalid profile bucket type")
 	case memProfile:
 		size += unsafe.Sizeof(memRecord{})
-	case blockProfile, mutexProfile:
+	case blockProfile, mutexProfile, offcpuProfile, scopeProfile, starveProfile, gsampleProfile:
 		size += unsafe.Sizeof(blockRecord{})
 	}
 
@@ -194,7 +225,7 @@ func (b *bucket) mp() *memRecord {
 
 // bp returns the blockRecord associated with the blockProfile bucket b.
 func (b *bucket) bp() *blockRecord {
-	if b.typ != blockProfile && b.typ != mutexProfile {
+	if b.typ != blockProfile && b.typ != mutexProfile && b.typ != offcpuProfile && b.typ != scopeProfile && b.typ != starveProfile && b.typ != gsampleProfile {
 		throw("bad use of bucket.bp")
 	}
 	data := add(unsafe.Pointer(b), unsafe.Sizeof(*b)+b.nstk*unsafe.Sizeof(uintptr(0)))
@@ -249,6 +280,18 @@ func stkbucket(typ bucketType, size uintptr, stk []uintptr, alloc bool) *bucket
 	} else if typ == mutexProfile {
 		b.allnext = xbuckets
 		xbuckets = b
+	} else if typ == offcpuProfile {
+		b.allnext = obuckets
+		obuckets = b
+	} else if typ == scopeProfile {
+		b.allnext = sbuckets
+		sbuckets = b
+	} else if typ == starveProfile {
+		b.allnext = vbuckets
+		vbuckets = b
+	} else if typ == gsampleProfile {
+		b.allnext = gbuckets
+		gbuckets = b
 	} else {
 		b.allnext = bbuckets
 		bbuckets = b
@@ -303,16 +346,47 @@ func mProf_Flush() {
 }
 
 func mProf_FlushLocked() {
+	mProf_DecayLocked()
 	c := mProf.cycle
 	for b := mbuckets; b != nil; b = b.allnext {
 		mp := b.mp()
 
 		// Flush cycle C into the published profile and clear
 		// it for reuse.
-		mpc := &mp.future[c%uint32(len(mp.future))]
-		mp.active.add(mpc)
-		*mpc = memRecordCycle{}
+		mp.publish(&mp.future[c%uint32(len(mp.future))])
+	}
+}
+
+// mProf_DecayLocked halves the recent allocation counts of every
+// bucket once for each half-life that has passed since they were last
+// decayed.
+func mProf_DecayLocked() {
+	if mProf.decay == 0 {
+		return
 	}
+	n := (nanotime() - mProf.decayed) / mProf.decay
+	if n <= 0 {
+		return
+	}
+	mProf.decayed += n * mProf.decay
+	for b := mbuckets; b != nil; b = b.allnext {
+		mp := b.mp()
+		mp.recentAllocs >>= uint64(n)
+		mp.recentAllocBytes >>= uint64(n)
+	}
+}
+
+//go:linkname setMemProfileDecay runtime/debug.setMemProfileDecay
+func setMemProfileDecay(halfLife int64) (prev int64) {
+	if halfLife < 0 {
+		halfLife = 0
+	}
+	lock(&proflock)
+	prev = mProf.decay
+	mProf.decay = halfLife
+	mProf.decayed = nanotime()
+	unlock(&proflock)
+	return prev
 }
 
 // mProf_PostSweep records that all sweep frees for this GC cycle have
@@ -329,9 +403,7 @@ func mProf_PostSweep() {
 	c := mProf.cycle
 	for b := mbuckets; b != nil; b = b.allnext {
 		mp := b.mp()
-		mpc := &mp.future[(c+1)%uint32(len(mp.future))]
-		mp.active.add(mpc)
-		*mpc = memRecordCycle{}
+		mp.publish(&mp.future[(c+1)%uint32(len(mp.future))])
 	}
 	unlock(&proflock)
 }
@@ -369,6 +441,20 @@ func mProf_Free(b *bucket, size uintptr) {
 	unlock(&proflock)
 }
 
+// mProf_ScopedMalloc records a sampled allocation of size bytes by a
+// goroutine in the active profiling scope. The allocations are sampled
+// at MemProfileRate, like those in the heap profile, but with a
+// sampler of their own.
+func mProf_ScopedMalloc(size uintptr) {
+	var stk [maxStack]uintptr
+	nstk := callers(3, stk[:])
+	lock(&proflock)
+	b := stkbucket(scopeProfile, 0, stk[:nstk], true)
+	b.bp().count++
+	b.bp().cycles += int64(size)
+	unlock(&proflock)
+}
+
 var blockprofilerate uint64 // in CPU ticks
 
 // SetBlockProfileRate controls the fraction of goroutine blocking events
@@ -458,6 +544,85 @@ func mutexevent(cycles int64, skip int) {
 	}
 }
 
+// Kinds of mutex starvation events, stored in the size of their
+// starveProfile buckets.
+const (
+	mutexStarve  = iota // a Lock switched a mutex to starvation mode
+	mutexHandoff        // an Unlock handed a starving mutex to a waiter
+)
+
+// sync_runtime_mutexStarving is called by sync.Mutex.Lock when it
+// switches the mutex to starvation mode after waiting ns nanoseconds.
+//go:linkname sync_runtime_mutexStarving sync.runtime_mutexStarving
+func sync_runtime_mutexStarving(ns int64) {
+	mutexstarveevent(mutexStarve, ns, 3)
+}
+
+// mutexstarveevent records a mutex starvation event of the given kind
+// in the mutex starvation profile. ns is how long the waiter involved
+// had queued for the mutex. The events are sampled at the mutex
+// profile rate.
+func mutexstarveevent(kind uintptr, ns int64, skip int) {
+	if ns < 0 {
+		ns = 0
+	}
+	rate := int64(atomic.Load64(&mutexprofilerate))
+	if rate <= 0 || int64(fastrand())%rate != 0 {
+		return
+	}
+	var stk [maxStack]uintptr
+	nstk := callers(skip, stk[:])
+	lock(&proflock)
+	b := stkbucket(starveProfile, kind, stk[:nstk], true)
+	b.bp().count++
+	b.bp().cycles += ns
+	unlock(&proflock)
+}
+
+var offcpuprofilerate uint64 // in nanoseconds
+
+// SetOffCPUProfileRate controls the fraction of time goroutines spend
+// off CPU that is reported in the off-CPU profile. A goroutine is off
+// CPU while it is parked, for example waiting on a channel, a lock,
+// a timer, or the network, and while it is blocked in a system call.
+// The profiler aims to sample an average of one event per rate
+// nanoseconds spent off CPU.
+//
+// To include every event in the profile, pass rate = 1.
+// To turn off profiling entirely, pass rate <= 0.
+func SetOffCPUProfileRate(rate int) {
+	if rate < 0 {
+		rate = 0
+	}
+	atomic.Store64(&offcpuprofilerate, uint64(rate))
+}
+
+// offcpuevent records that the current goroutine spent ns nanoseconds
+// off CPU for the given reason, which is waitReasonZero for system
+// calls.
+func offcpuevent(ns int64, reason waitReason, skip int) {
+	if ns <= 0 {
+		ns = 1
+	}
+	rate := int64(atomic.Load64(&offcpuprofilerate))
+	if rate <= 0 || (rate > ns && int64(fastrand())%rate > ns) {
+		return
+	}
+	gp := getg()
+	if isSystemGoroutine(gp, false) {
+		return
+	}
+	var stk [maxStack]uintptr
+	nstk := callers(skip, stk[:])
+	lock(&proflock)
+	// The wait reason is part of the bucket key, so the same stack
+	// blocked for different reasons is reported separately.
+	b := stkbucket(offcpuProfile, uintptr(reason), stk[:nstk], true)
+	b.bp().count++
+	b.bp().cycles += ns
+	unlock(&proflock)
+}
+
 // Go interface to profile data.
 
 // A StackRecord describes a single execution stack.
@@ -497,7 +662,17 @@ var MemProfileRate int = 512 * 1024
 type MemProfileRecord struct {
 	AllocBytes, FreeBytes     int64       // number of bytes allocated, freed
 	AllocObjects, FreeObjects int64       // number of objects allocated, freed
+	LiveBytes, LiveObjects    int64       // number of bytes, objects allocated and not yet freed
 	Stack0                    [32]uintptr // stack trace for this record; ends at first 0 entry
+
+	// RecentAllocBytes and RecentAllocObjects are AllocBytes and
+	// AllocObjects with older allocations discounted: they halve
+	// every half-life set by runtime/debug.SetMemProfileDecay.
+	// Without decay, they equal AllocBytes and AllocObjects.
+	// A stack whose live bytes stay high while it hardly allocates
+	// any more is retaining memory; one that allocates a lot but has
+	// few live bytes is churning.
+	RecentAllocBytes, RecentAllocObjects int64
 }
 
 // InUseBytes returns the number of bytes in use (AllocBytes - FreeBytes).
@@ -565,8 +740,7 @@ func MemProfile(p []MemProfileRecord, inuseZero bool) (n int, ok bool) {
 		for b := mbuckets; b != nil; b = b.allnext {
 			mp := b.mp()
 			for c := range mp.future {
-				mp.active.add(&mp.future[c])
-				mp.future[c] = memRecordCycle{}
+				mp.publish(&mp.future[c])
 			}
 			if inuseZero || mp.active.alloc_bytes != mp.active.free_bytes {
 				n++
@@ -595,6 +769,10 @@ func record(r *MemProfileRecord, b *bucket) {
 	r.FreeBytes = int64(mp.active.free_bytes)
 	r.AllocObjects = int64(mp.active.allocs)
 	r.FreeObjects = int64(mp.active.frees)
+	r.LiveBytes = r.AllocBytes - r.FreeBytes
+	r.LiveObjects = r.AllocObjects - r.FreeObjects
+	r.RecentAllocBytes = int64(mp.recentAllocBytes)
+	r.RecentAllocObjects = int64(mp.recentAllocs)
 	if raceenabled {
 		racewriterangepc(unsafe.Pointer(&r.Stack0[0]), unsafe.Sizeof(r.Stack0), getcallerpc(), funcPC(MemProfile))
 	}
@@ -689,6 +867,132 @@ func MutexProfile(p []BlockProfileRecord) (n int, ok bool) {
 	return
 }
 
+// OffCPUProfileRecord describes time goroutines spent off CPU
+// for a particular reason at a particular call sequence (stack trace).
+type OffCPUProfileRecord struct {
+	Count    int64
+	Duration int64  // total time off CPU, in nanoseconds
+	Reason   string // wait reason, or "syscall" for system calls
+	StackRecord
+}
+
+// OffCPUProfile returns n, the number of records in the current off-CPU profile.
+// If len(p) >= n, OffCPUProfile copies the profile into p and returns n, true.
+// If len(p) < n, OffCPUProfile does not change p and returns n, false.
+//
+// Most clients should use the runtime/pprof package
+// instead of calling OffCPUProfile directly.
+func OffCPUProfile(p []OffCPUProfileRecord) (n int, ok bool) {
+	lock(&proflock)
+	for b := obuckets; b != nil; b = b.allnext {
+		n++
+	}
+	if n <= len(p) {
+		ok = true
+		for b := obuckets; b != nil; b = b.allnext {
+			bp := b.bp()
+			r := &p[0]
+			r.Count = bp.count
+			r.Duration = bp.cycles
+			if reason := waitReason(b.size); reason == waitReasonZero {
+				r.Reason = "syscall"
+			} else {
+				r.Reason = reason.String()
+			}
+			i := copy(r.Stack0[:], b.stk())
+			for ; i < len(r.Stack0); i++ {
+				r.Stack0[i] = 0
+			}
+			p = p[1:]
+		}
+	}
+	unlock(&proflock)
+	return
+}
+
+// MutexStarvationRecord describes sync.Mutex starvation mode events
+// originated at a particular call sequence (stack trace).
+type MutexStarvationRecord struct {
+	// Handoff reports whether the events are Unlocks handing a
+	// starving mutex directly to a waiter, rather than Locks that
+	// waited long enough to switch the mutex to starvation mode.
+	Handoff  bool
+	Count    int64
+	WaitTime int64 // total time the waiters involved queued, in nanoseconds
+	StackRecord
+}
+
+// MutexStarvationProfile returns n, the number of records in the current
+// mutex starvation profile.
+// If len(p) >= n, MutexStarvationProfile copies the profile into p and returns n, true.
+// Otherwise, MutexStarvationProfile does not change p, and returns n, false.
+//
+// The profile is sampled at the rate set by SetMutexProfileFraction.
+// Most clients should use the runtime/pprof package
+// instead of calling MutexStarvationProfile directly.
+func MutexStarvationProfile(p []MutexStarvationRecord) (n int, ok bool) {
+	lock(&proflock)
+	for b := vbuckets; b != nil; b = b.allnext {
+		n++
+	}
+	if n <= len(p) {
+		ok = true
+		for b := vbuckets; b != nil; b = b.allnext {
+			bp := b.bp()
+			r := &p[0]
+			r.Handoff = b.size == mutexHandoff
+			r.Count = bp.count
+			r.WaitTime = bp.cycles
+			i := copy(r.Stack0[:], b.stk())
+			for ; i < len(r.Stack0); i++ {
+				r.Stack0[i] = 0
+			}
+			p = p[1:]
+		}
+	}
+	unlock(&proflock)
+	return
+}
+
+// GoroutineSampleRecord describes how often the goroutine sampler found
+// goroutines in a particular state at a particular call sequence
+// (stack trace).
+type GoroutineSampleRecord struct {
+	Count int64
+	State string // "running", "runnable", "syscall", or why a waiting goroutine waits
+	StackRecord
+}
+
+// GoroutineSampleProfile returns n, the number of records in the current
+// goroutine sample profile.
+// If len(p) >= n, GoroutineSampleProfile copies the profile into p and returns n, true.
+// If len(p) < n, GoroutineSampleProfile does not change p and returns n, false.
+//
+// The profile is only collected after a call to SetGoroutineSampleRate.
+// Most clients should use the runtime/pprof package
+// instead of calling GoroutineSampleProfile directly.
+func GoroutineSampleProfile(p []GoroutineSampleRecord) (n int, ok bool) {
+	lock(&proflock)
+	for b := gbuckets; b != nil; b = b.allnext {
+		n++
+	}
+	if n <= len(p) {
+		ok = true
+		for b := gbuckets; b != nil; b = b.allnext {
+			r := &p[0]
+			r.Count = b.bp().count
+			r.State = gsampleState(b.size)
+			i := copy(r.Stack0[:], b.stk())
+			for ; i < len(r.Stack0); i++ {
+				r.Stack0[i] = 0
+			}
+			p = p[1:]
+		}
+	}
+	unlock(&proflock)
+	return
+}
+
 // ThreadCreateProfile returns n, the number of records in the thread creation profile.
 // If len(p) >= n, ThreadCreateProfile copies the profile into p and returns n, true.
 // If len(p) < n, ThreadCreateProfile does not change p and returns n, false.
@@ -712,70 +1016,218 @@ func ThreadCreateProfile(p []StackRecord) (n int, ok bool) {
 }
 
 //go:linkname runtime_goroutineProfileWithLabels runtime/pprof.runtime_goroutineProfileWithLabels
-func runtime_goroutineProfileWithLabels(p []StackRecord, labels []unsafe.Pointer) (n int, ok bool) {
-	return goroutineProfileWithLabels(p, labels)
+func runtime_goroutineProfileWithLabels(p []StackRecord, labels []unsafe.Pointer, names []string, waits []int64) (n int, ok bool) {
+	return goroutineProfileWithLabels(p, labels, names, waits)
+}
+
+// goroutineProfile holds the state of the goroutine profile being
+// collected. Collecting a profile only stops the world long enough to
+// count the goroutines and mark the start of the profile; the stacks
+// are then gathered one goroutine at a time with the world running.
+//
+// Each goroutine's profiled field tracks whether its stack has been
+// recorded yet. A goroutine that is about to run while the profile is
+// active records its own stack first, so every record shows the
+// goroutine as it was when the world stopped. Goroutines created after
+// that point are marked satisfied and never recorded.
+var goroutineProfile = struct {
+	sema    uint32
+	active  bool
+	offset  uint32 // next free index in records, updated atomically
+	records []StackRecord
+	labels  []unsafe.Pointer
+	names   []string
+	waits   []int64
+	start   int64 // nanotime when the world stopped
+}{
+	sema: 1,
 }
 
-// labels may be nil. If labels is non-nil, it must have the same length as p.
-func goroutineProfileWithLabels(p []StackRecord, labels []unsafe.Pointer) (n int, ok bool) {
+// Values of g.profiled.
+const (
+	goroutineProfileAbsent     = iota // stack not yet recorded
+	goroutineProfileInProgress        // someone is recording the stack
+	goroutineProfileSatisfied         // stack recorded, or not wanted
+)
+
+// labels, names and waits may be nil. If they are non-nil, they must
+// have the same length as p, and receive the profiler labels, the names
+// set with SetGoroutineName and the time in nanoseconds for which each
+// waiting goroutine had been blocked when the world stopped, or 0.
+func goroutineProfileWithLabels(p []StackRecord, labels []unsafe.Pointer, names []string, waits []int64) (n int, ok bool) {
 	if labels != nil && len(labels) != len(p) {
 		labels = nil
 	}
+	if names != nil && len(names) != len(p) {
+		names = nil
+	}
+	if waits != nil && len(waits) != len(p) {
+		waits = nil
+	}
 	gp := getg()
 
-	isOK := func(gp1 *g) bool {
-		// Checking isSystemGoroutine here makes GoroutineProfile
-		// consistent with both NumGoroutine and Stack.
-		return gp1 != gp && readgstatus(gp1) != _Gdead && !isSystemGoroutine(gp1, false)
-	}
+	semacquire(&goroutineProfile.sema)
 
 	stopTheWorld("profile")
 
-	n = 1
-	for _, gp1 := range allgs {
-		if isOK(gp1) {
-			n++
-		}
+	// Count the goroutines with the same rules as NumGoroutine.
+	// The finalizer goroutine is a system goroutine except while
+	// it runs user code.
+	n = int(gcount())
+	if fingRunning {
+		n++
 	}
 
-	if n <= len(p) {
-		ok = true
-		r, lbl := p, labels
+	if n > len(p) {
+		// There's not enough space in p to store the whole profile, so
+		// (per the contract of runtime.GoroutineProfile) we're not
+		// allowed to write to p at all and must return n, false.
+		startTheWorld()
+		semrelease(&goroutineProfile.sema)
+		return n, false
+	}
 
-		// Save current goroutine.
-		sp := getcallersp()
-		pc := getcallerpc()
-		systemstack(func() {
-			saveg(pc, sp, gp, &r[0])
-		})
-		r = r[1:]
+	// Save current goroutine.
+	sp := getcallersp()
+	pc := getcallerpc()
+	systemstack(func() {
+		saveg(pc, sp, gp, &p[0])
+	})
+	if labels != nil {
+		labels[0] = gp.labels
+	}
+	if names != nil && gp.name != nil {
+		names[0] = *gp.name
+	}
+	if waits != nil {
+		waits[0] = 0
+	}
+	atomic.Store(&gp.profiled, goroutineProfileSatisfied)
+	atomic.Store(&goroutineProfile.offset, 1)
+
+	// Prepare for all other goroutines to enter the profile. Goroutines
+	// that start running from now on record themselves in execute.
+	goroutineProfile.active = true
+	goroutineProfile.records = p
+	goroutineProfile.labels = labels
+	goroutineProfile.names = names
+	goroutineProfile.waits = waits
+	goroutineProfile.start = nanotime()
+	startTheWorld()
+
+	// Visit each goroutine that existed when the world restarted. New
+	// goroutines in this list are already marked satisfied.
+	ptr, length := atomicAllG()
+	for i := uintptr(0); i < length; i++ {
+		tryRecordGoroutineProfile(atomicAllGIndex(ptr, i), Gosched)
+	}
+
+	stopTheWorld("profile cleanup")
+	endOffset := atomic.Xchg(&goroutineProfile.offset, 0)
+	goroutineProfile.active = false
+	goroutineProfile.records = nil
+	goroutineProfile.labels = nil
+	goroutineProfile.names = nil
+	goroutineProfile.waits = nil
+	startTheWorld()
+
+	// Clear the profiled state of every goroutine for the next profile.
+	// None of them changes it while no profile is active.
+	ptr, length = atomicAllG()
+	for i := uintptr(0); i < length; i++ {
+		atomic.Store(&atomicAllGIndex(ptr, i).profiled, goroutineProfileAbsent)
+	}
 
-		// If we have a place to put our goroutine labelmap, insert it there.
-		if labels != nil {
-			lbl[0] = gp.labels
-			lbl = lbl[1:]
+	semrelease(&goroutineProfile.sema)
+
+	if int(endOffset) < n {
+		// Goroutines that exited before we reached them have no
+		// record. Report the records we have.
+		n = int(endOffset)
+	}
+	return n, true
+}
+
+// tryRecordGoroutineProfile ensures that gp1's stack is in the active
+// goroutine profile, recording it if nobody has yet. If another thread
+// is recording it, tryRecordGoroutineProfile calls yield until that
+// finishes.
+func tryRecordGoroutineProfile(gp1 *g, yield func()) {
+	if readgstatus(gp1) == _Gdead {
+		return
+	}
+	if isSystemGoroutine(gp1, false) {
+		return
+	}
+	for {
+		prev := atomic.Load(&gp1.profiled)
+		if prev == goroutineProfileSatisfied {
+			return
+		}
+		if prev == goroutineProfileInProgress {
+			yield()
+			continue
 		}
 
-		// Save other goroutines.
-		for _, gp1 := range allgs {
-			if isOK(gp1) {
-				if len(r) == 0 {
-					// Should be impossible, but better to return a
-					// truncated profile than to crash the entire process.
-					break
-				}
-				saveg(^uintptr(0), ^uintptr(0), gp1, &r[0])
-				if labels != nil {
-					lbl[0] = gp1.labels
-					lbl = lbl[1:]
-				}
-				r = r[1:]
+		// Keep this thread from being descheduled while gp1 is
+		// marked in progress, or whoever waits on it would spin.
+		mp := acquirem()
+		if atomic.Cas(&gp1.profiled, goroutineProfileAbsent, goroutineProfileInProgress) {
+			systemstack(func() {
+				doRecordGoroutineProfile(gp1)
+			})
+			atomic.Store(&gp1.profiled, goroutineProfileSatisfied)
+		}
+		releasem(mp)
+	}
+}
+
+// doRecordGoroutineProfile suspends gp1 and writes its stack to the
+// next free record of the active goroutine profile.
+//
+//go:systemstack
+func doRecordGoroutineProfile(gp1 *g) {
+	// suspendG requires the user goroutine of this M, if any, to be
+	// preemptible. Park it for the duration, as the GC does when it
+	// scans stacks.
+	userG := getg().m.curg
+	self := userG != nil && readgstatus(userG) == _Grunning
+	if self {
+		casgstatus(userG, _Grunning, _Gwaiting)
+		userG.waitreason = waitReasonGoroutineProfile
+	}
+
+	stopped := suspendG(gp1)
+	if !stopped.dead {
+		offset := atomic.Xadd(&goroutineProfile.offset, 1) - 1
+		if int(offset) < len(goroutineProfile.records) {
+			saveg(^uintptr(0), ^uintptr(0), gp1, &goroutineProfile.records[offset])
+			if goroutineProfile.labels != nil {
+				goroutineProfile.labels[offset] = gp1.labels
+			}
+			if goroutineProfile.names != nil && gp1.name != nil {
+				goroutineProfile.names[offset] = *gp1.name
+			}
+			if goroutineProfile.waits != nil {
+				goroutineProfile.waits[offset] = goroutineWait(gp1, goroutineProfile.start)
 			}
 		}
 	}
+	resumeG(stopped)
 
-	startTheWorld()
-	return n, ok
+	if self {
+		casgstatus(userG, _Gwaiting, _Grunning)
+	}
+}
+
+// goroutineWait returns how long, in nanoseconds, gp had been waiting at
+// time now, or 0 if it is not waiting or the runtime does not know when
+// it blocked. gp must be suspended or the world stopped.
+func goroutineWait(gp *g, now int64) int64 {
+	if readgstatus(gp)&^_Gscan != _Gwaiting || gp.waitsince == 0 || gp.waitsince > now {
+		return 0
+	}
+	return now - gp.waitsince
 }
 
 // GoroutineProfile returns n, the number of records in the active goroutine stack profile.
@@ -786,7 +1238,7 @@ func goroutineProfileWithLabels(p []StackRecord, labels []unsafe.Pointer) (n int
 // of calling GoroutineProfile directly.
 func GoroutineProfile(p []StackRecord) (n int, ok bool) {
 
-	return goroutineProfileWithLabels(p, nil)
+	return goroutineProfileWithLabels(p, nil, nil, nil)
 }
 
 func saveg(pc, sp uintptr, gp *g, r *StackRecord) {
@@ -834,6 +1286,127 @@ func Stack(buf []byte, all bool) int {
 	return n
 }
 
+// A StackFilter selects the goroutines whose stacks StackFiltered
+// formats. A goroutine is selected if it passes every test whose
+// field is set; the zero StackFilter selects every goroutine.
+type StackFilter struct {
+	// State selects goroutines in the given state, as reported
+	// in GoroutineInfo.State, such as "waiting" or "runnable".
+	State string
+
+	// MinWait selects goroutines that have been blocked, waiting
+	// or in a system call, for at least MinWait nanoseconds. As
+	// with GoroutineInfo.WaitSince, a goroutine's blocking time
+	// is only known once a garbage collection has seen it blocked.
+	MinWait int64
+
+	// FuncPrefix selects goroutines whose innermost function
+	// outside package runtime has a name starting with
+	// FuncPrefix, such as "net/http." or "main.(*server).".
+	FuncPrefix string
+
+	// LabelKey and LabelValue select goroutines carrying the
+	// profiler label LabelKey with the value LabelValue.
+	// See runtime/pprof.SetGoroutineLabels.
+	LabelKey   string
+	LabelValue string
+}
+
+// StackFiltered is like Stack(buf, true), but formats only the
+// stacks of the goroutines selected by filter. The calling goroutine
+// is included only if filter selects it too. StackFiltered returns
+// the number of bytes written into buf.
+func StackFiltered(buf []byte, filter *StackFilter) int {
+	stopTheWorld("stack trace")
+
+	n := 0
+	if len(buf) > 0 {
+		gp := getg()
+		sp := getcallersp()
+		pc := getcallerpc()
+		systemstack(func() {
+			g0 := getg()
+			g0.m.traceback = 1
+			g0.writebuf = buf[0:0:len(buf)]
+			now := nanotime()
+			first := true
+			if filter.match(gp, pc, now) {
+				goroutineheader(gp)
+				traceback(pc, sp, 0, gp)
+				first = false
+			}
+			for _, gp1 := range allgs {
+				if gp1 == gp || readgstatus(gp1) == _Gdead || isSystemGoroutine(gp1, false) || !filter.match(gp1, 0, now) {
+					continue
+				}
+				if !first {
+					print("\n")
+				}
+				first = false
+				goroutineheader(gp1)
+				traceback(^uintptr(0), ^uintptr(0), 0, gp1)
+			}
+			g0.m.traceback = 0
+			n = len(g0.writebuf)
+			g0.writebuf = nil
+		})
+	}
+
+	startTheWorld()
+	return n
+}
+
+// match reports whether f selects gp. If gp is the calling goroutine,
+// pc is its PC; otherwise pc is 0 and gp must be stopped.
+func (f *StackFilter) match(gp *g, pc uintptr, now int64) bool {
+	status := readgstatus(gp) &^ _Gscan
+	if f.State != "" && (status >= uint32(len(gStatusStrings)) || gStatusStrings[status] != f.State) {
+		return false
+	}
+	if f.MinWait > 0 {
+		if status != _Gwaiting && status != _Gsyscall || gp.waitsince == 0 || now-gp.waitsince < f.MinWait {
+			return false
+		}
+	}
+	if f.LabelKey != "" {
+		// gp.labels is a *labelMap from runtime/pprof.
+		if gp.labels == nil {
+			return false
+		}
+		v, ok := (*(*map[string]string)(gp.labels))[f.LabelKey]
+		if !ok || v != f.LabelValue {
+			return false
+		}
+	}
+	if f.FuncPrefix != "" && !hasPrefix(stackFilterFunc(gp, pc), f.FuncPrefix) {
+		return false
+	}
+	return true
+}
+
+// stackFilterFunc returns the name of the innermost function of gp
+// outside package runtime, or "" if there is none among the frames
+// it looks at. pc is as for StackFilter.match.
+func stackFilterFunc(gp *g, pc uintptr) string {
+	var pcs [32]uintptr
+	n := 1
+	if pc != 0 {
+		pcs[0] = pc
+	} else {
+		n = gcallers(gp, 0, pcs[:])
+	}
+	for _, pc := range pcs[:n] {
+		f := findfunc(pc - 1)
+		if !f.valid() {
+			continue
+		}
+		if name := funcname(f); !hasPrefix(name, "runtime.") {
+			return name
+		}
+	}
+	return ""
+}
+
 // Tracing of alloc/free/gc.
 
 var tracelock mutex
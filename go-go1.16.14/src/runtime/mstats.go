@@ -595,6 +595,47 @@ func readGCStats_m(pauses *[]uint64) {
 	*pauses = p[:n+n+3]
 }
 
+// allocStatsBase holds the allocation counters at the last call to
+// readAndResetAllocStats. Protected by worldsema.
+var allocStatsBase struct {
+	totalAlloc, mallocs, frees uint64
+}
+
+// readAndResetAllocStats returns the bytes allocated and the number of
+// allocations and frees since its last call, or since the program
+// started. Like ReadMemStats, it stops the world and flushes the
+// mcaches, since the heap stats count the free slots of cached spans
+// as allocated until the spans are released.
+//go:linkname readAndResetAllocStats runtime/debug.readAndResetAllocStats
+func readAndResetAllocStats() (totalAlloc, mallocs, frees uint64) {
+	stopTheWorld("read alloc stats")
+	systemstack(func() {
+		flushallmcaches()
+
+		var consStats heapStatsDelta
+		memstats.heapStats.unsafeRead(&consStats)
+		totalAlloc = uint64(consStats.largeAlloc)
+		mallocs = uint64(consStats.largeAllocCount)
+		frees = uint64(consStats.largeFreeCount)
+		for i := 0; i < _NumSizeClasses; i++ {
+			a := uint64(consStats.smallAllocCount[i])
+			totalAlloc += a * uint64(class_to_size[i])
+			mallocs += a
+			frees += uint64(consStats.smallFreeCount[i])
+		}
+		mallocs += memstats.tinyallocs
+		frees += memstats.tinyallocs
+	})
+
+	b := &allocStatsBase
+	totalAlloc, b.totalAlloc = totalAlloc-b.totalAlloc, totalAlloc
+	mallocs, b.mallocs = mallocs-b.mallocs, mallocs
+	frees, b.frees = frees-b.frees, frees
+
+	startTheWorld()
+	return
+}
+
 // Updates the memstats structure.
 //
 // The world must be stopped.
@@ -727,7 +768,7 @@ func flushmcache(i int) {
 func flushallmcaches() {
 	assertWorldStopped()
 
-	for i := 0; i < int(gomaxprocs); i++ {
+	for i := range allp {
 		flushmcache(i)
 	}
 }
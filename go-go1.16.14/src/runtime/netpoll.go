@@ -444,7 +444,9 @@ func netpollblock(pd *pollDesc, mode int32, waitio bool) bool {
 	// this is necessary because runtime_pollUnblock/runtime_pollSetDeadline/deadlineimpl
 	// do the opposite: store to closing/rd/wd, membarrier, load of rg/wg
 	if waitio || netpollcheckerr(pd, mode) == 0 {
+		gaugeNetpollWaiters.add(1)
 		gopark(netpollblockcommit, unsafe.Pointer(gpp), waitReasonIOWait, traceEvGoBlockNet, 5)
+		gaugeNetpollWaiters.add(-1)
 	}
 	// be careful to not lose concurrent pdReady notification
 	old := atomic.Xchguintptr(gpp, 0)
@@ -24,7 +24,7 @@ func closeonexec(fd int32)
 var (
 	epfd int32 = -1 // epoll descriptor
 
-	netpollBreakRd, netpollBreakWr uintptr // for netpollBreak
+	netpollEventFd uintptr // eventfd for netpollBreak
 
 	netpollWakeSig uint32 // used to avoid duplicate calls of netpollBreak
 )
@@ -39,26 +39,25 @@ func netpollinit() {
 		}
 		closeonexec(epfd)
 	}
-	r, w, errno := nonblockingPipe()
-	if errno != 0 {
-		println("runtime: pipe failed with", -errno)
-		throw("runtime: pipe failed")
+	efd := eventfd(0, _O_NONBLOCK|_O_CLOEXEC)
+	if efd < 0 {
+		println("runtime: eventfd failed with", -efd)
+		throw("runtime: eventfd failed")
 	}
 	ev := epollevent{
 		events: _EPOLLIN,
 	}
-	*(**uintptr)(unsafe.Pointer(&ev.data)) = &netpollBreakRd
-	errno = epollctl(epfd, _EPOLL_CTL_ADD, r, &ev)
+	*(**uintptr)(unsafe.Pointer(&ev.data)) = &netpollEventFd
+	errno := epollctl(epfd, _EPOLL_CTL_ADD, efd, &ev)
 	if errno != 0 {
 		println("runtime: epollctl failed with", -errno)
 		throw("runtime: epollctl failed")
 	}
-	netpollBreakRd = uintptr(r)
-	netpollBreakWr = uintptr(w)
+	netpollEventFd = uintptr(efd)
 }
 
 func netpollIsPollDescriptor(fd uintptr) bool {
-	return fd == uintptr(epfd) || fd == netpollBreakRd || fd == netpollBreakWr
+	return fd == uintptr(epfd) || fd == netpollEventFd
 }
 
 func netpollopen(fd uintptr, pd *pollDesc) int32 {
@@ -81,9 +80,9 @@ func netpollarm(pd *pollDesc, mode int) {
 func netpollBreak() {
 	if atomic.Cas(&netpollWakeSig, 0, 1) {
 		for {
-			var b byte
-			n := write(netpollBreakWr, unsafe.Pointer(&b), 1)
-			if n == 1 {
+			var one uint64 = 1
+			n := write(netpollEventFd, unsafe.Pointer(&one), 8)
+			if n == 8 {
 				break
 			}
 			if n == -_EINTR {
@@ -124,7 +123,7 @@ func netpoll(delay int64) gList {
 	}
 	var events [128]epollevent
 retry:
-	n := epollwait(epfd, &events[0], int32(len(events)), waitms)
+	n := epollwait(epfd, &events[0], netpollEventLimit(int32(len(events))), waitms)
 	if n < 0 {
 		if n != -_EINTR {
 			println("runtime: epollwait on fd", epfd, "failed with", -n)
@@ -144,17 +143,17 @@ retry:
 			continue
 		}
 
-		if *(**uintptr)(unsafe.Pointer(&ev.data)) == &netpollBreakRd {
+		if *(**uintptr)(unsafe.Pointer(&ev.data)) == &netpollEventFd {
 			if ev.events != _EPOLLIN {
 				println("runtime: netpoll: break fd ready for", ev.events)
 				throw("runtime: netpoll: break fd ready for something unexpected")
 			}
 			if delay != 0 {
 				// netpollBreak could be picked up by a
-				// nonblocking poll. Only read the byte
+				// nonblocking poll. Only reset the counter
 				// if blocking.
-				var tmp [16]byte
-				read(int32(netpollBreakRd), noescape(unsafe.Pointer(&tmp[0])), int32(len(tmp)))
+				var tmp uint64
+				read(int32(netpollEventFd), noescape(unsafe.Pointer(&tmp)), 8)
 				atomic.Store(&netpollWakeSig, 0)
 			}
 			continue
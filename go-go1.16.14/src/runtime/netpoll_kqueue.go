@@ -16,8 +16,6 @@ import (
 var (
 	kq int32 = -1
 
-	netpollBreakRd, netpollBreakWr uintptr // for netpollBreak
-
 	netpollWakeSig uint32 // used to avoid duplicate calls of netpollBreak
 )
 
@@ -28,27 +26,11 @@ func netpollinit() {
 		throw("runtime: netpollinit failed")
 	}
 	closeonexec(kq)
-	r, w, errno := nonblockingPipe()
-	if errno != 0 {
-		println("runtime: pipe failed with", -errno)
-		throw("runtime: pipe failed")
-	}
-	ev := keventt{
-		filter: _EVFILT_READ,
-		flags:  _EV_ADD,
-	}
-	*(*uintptr)(unsafe.Pointer(&ev.ident)) = uintptr(r)
-	n := kevent(kq, &ev, 1, nil, 0, nil)
-	if n < 0 {
-		println("runtime: kevent failed with", -n)
-		throw("runtime: kevent failed")
-	}
-	netpollBreakRd = uintptr(r)
-	netpollBreakWr = uintptr(w)
+	addWakeupEvent(kq)
 }
 
 func netpollIsPollDescriptor(fd uintptr) bool {
-	return fd == uintptr(kq) || fd == netpollBreakRd || fd == netpollBreakWr
+	return fd == uintptr(kq) || isWakeupFd(fd)
 }
 
 func netpollopen(fd uintptr, pd *pollDesc) int32 {
@@ -84,18 +66,7 @@ func netpollarm(pd *pollDesc, mode int) {
 // netpollBreak interrupts a kevent.
 func netpollBreak() {
 	if atomic.Cas(&netpollWakeSig, 0, 1) {
-		for {
-			var b byte
-			n := write(netpollBreakWr, unsafe.Pointer(&b), 1)
-			if n == 1 || n == -_EAGAIN {
-				break
-			}
-			if n == -_EINTR {
-				continue
-			}
-			println("runtime: netpollBreak write failed with", -n)
-			throw("runtime: netpollBreak write failed")
-		}
+		wakeNetpoll(kq)
 	}
 }
 
@@ -124,7 +95,7 @@ func netpoll(delay int64) gList {
 	}
 	var events [64]keventt
 retry:
-	n := kevent(kq, nil, 0, &events[0], int32(len(events)), tp)
+	n := kevent(kq, nil, 0, &events[0], netpollEventLimit(int32(len(events))), tp)
 	if n < 0 {
 		if n != -_EINTR {
 			println("runtime: kevent on fd", kq, "failed with", -n)
@@ -141,18 +112,15 @@ retry:
 	for i := 0; i < int(n); i++ {
 		ev := &events[i]
 
-		if uintptr(ev.ident) == netpollBreakRd {
-			if ev.filter != _EVFILT_READ {
-				println("runtime: netpoll: break fd ready for", ev.filter)
-				throw("runtime: netpoll: break fd ready for something unexpected")
-			}
+		if isWakeup(ev) {
 			if delay != 0 {
 				// netpollBreak could be picked up by a
-				// nonblocking poll. Only read the byte
-				// if blocking.
-				var tmp [16]byte
-				read(int32(netpollBreakRd), noescape(unsafe.Pointer(&tmp[0])), int32(len(tmp)))
+				// nonblocking poll. Only drain the wakeup
+				// event if blocking.
+				drainWakeupEvent(kq)
 				atomic.Store(&netpollWakeSig, 0)
+			} else {
+				keepWakeupEvent(kq)
 			}
 			continue
 		}
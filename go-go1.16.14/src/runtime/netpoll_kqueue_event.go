@@ -0,0 +1,80 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin dragonfly freebsd
+
+package runtime
+
+// Wakeups for the kqueue poller using an EVFILT_USER event, which
+// needs no file descriptors.
+
+import "unsafe"
+
+// kqIdent is the identifier of the EVFILT_USER event. It only has
+// to be unique among the EVFILT_USER events of the kqueue.
+const kqIdent = 0xee1eb9f4
+
+func addWakeupEvent(kq int32) {
+	// EV_CLEAR resets the event once it has been returned
+	// by kevent, so it doesn't have to be drained.
+	ev := keventt{
+		filter: _EVFILT_USER,
+		flags:  _EV_ADD | _EV_CLEAR,
+	}
+	*(*uintptr)(unsafe.Pointer(&ev.ident)) = kqIdent
+	for {
+		n := kevent(kq, &ev, 1, nil, 0, nil)
+		if n == 0 {
+			break
+		}
+		if n == -_EINTR {
+			continue
+		}
+		println("runtime: kevent for EVFILT_USER failed with", -n)
+		throw("runtime: kevent failed")
+	}
+}
+
+func wakeNetpoll(kq int32) {
+	ev := keventt{
+		filter: _EVFILT_USER,
+		fflags: _NOTE_TRIGGER,
+	}
+	*(*uintptr)(unsafe.Pointer(&ev.ident)) = kqIdent
+	for {
+		n := kevent(kq, &ev, 1, nil, 0, nil)
+		if n == 0 {
+			break
+		}
+		if n == -_EINTR {
+			continue
+		}
+		println("runtime: netpollBreak kevent failed with", -n)
+		throw("runtime: netpollBreak kevent failed")
+	}
+}
+
+func isWakeup(ev *keventt) bool {
+	if ev.filter == _EVFILT_USER {
+		if uintptr(ev.ident) == kqIdent {
+			return true
+		}
+		println("runtime: netpoll: EVFILT_USER event with ident", ev.ident)
+		throw("runtime: netpoll: unexpected EVFILT_USER event")
+	}
+	return false
+}
+
+func isWakeupFd(fd uintptr) bool {
+	return false
+}
+
+func drainWakeupEvent(kq int32) {}
+
+// keepWakeupEvent triggers the wakeup event again after a
+// nonblocking poll has consumed it, so that a blocking poll still
+// sees it.
+func keepWakeupEvent(kq int32) {
+	wakeNetpoll(kq)
+}
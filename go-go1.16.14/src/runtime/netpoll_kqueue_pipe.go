@@ -0,0 +1,73 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build netbsd openbsd
+
+package runtime
+
+// Wakeups for the kqueue poller using a pipe, for systems without
+// EVFILT_USER.
+
+import "unsafe"
+
+var netpollBreakRd, netpollBreakWr uintptr // for netpollBreak
+
+func addWakeupEvent(kq int32) {
+	r, w, errno := nonblockingPipe()
+	if errno != 0 {
+		println("runtime: pipe failed with", -errno)
+		throw("runtime: pipe failed")
+	}
+	ev := keventt{
+		filter: _EVFILT_READ,
+		flags:  _EV_ADD,
+	}
+	*(*uintptr)(unsafe.Pointer(&ev.ident)) = uintptr(r)
+	n := kevent(kq, &ev, 1, nil, 0, nil)
+	if n < 0 {
+		println("runtime: kevent failed with", -n)
+		throw("runtime: kevent failed")
+	}
+	netpollBreakRd = uintptr(r)
+	netpollBreakWr = uintptr(w)
+}
+
+func wakeNetpoll(kq int32) {
+	for {
+		var b byte
+		n := write(netpollBreakWr, unsafe.Pointer(&b), 1)
+		if n == 1 || n == -_EAGAIN {
+			break
+		}
+		if n == -_EINTR {
+			continue
+		}
+		println("runtime: netpollBreak write failed with", -n)
+		throw("runtime: netpollBreak write failed")
+	}
+}
+
+func isWakeup(ev *keventt) bool {
+	if uintptr(ev.ident) == netpollBreakRd {
+		if ev.filter == _EVFILT_READ {
+			return true
+		}
+		println("runtime: netpoll: break fd ready for", ev.filter)
+		throw("runtime: netpoll: break fd ready for something unexpected")
+	}
+	return false
+}
+
+func isWakeupFd(fd uintptr) bool {
+	return fd == netpollBreakRd || fd == netpollBreakWr
+}
+
+func drainWakeupEvent(kq int32) {
+	var tmp [16]byte
+	read(int32(netpollBreakRd), noescape(unsafe.Pointer(&tmp[0])), int32(len(tmp)))
+}
+
+// keepWakeupEvent does nothing: the byte stays in the pipe until a
+// blocking poll drains it.
+func keepWakeupEvent(kq int32) {}
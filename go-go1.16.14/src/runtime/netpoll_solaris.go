@@ -235,7 +235,7 @@ func netpoll(delay int64) gList {
 	var events [128]portevent
 retry:
 	var n uint32 = 1
-	r := port_getn(portfd, &events[0], uint32(len(events)), &n, wait)
+	r := port_getn(portfd, &events[0], uint32(netpollEventLimit(int32(len(events)))), &n, wait)
 	e := errno()
 	if r < 0 && e == _ETIME && n > 0 {
 		// As per port_getn(3C), an ETIME failure does not preclude the
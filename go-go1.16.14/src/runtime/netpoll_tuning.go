@@ -0,0 +1,77 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+import (
+	"runtime/internal/atomic"
+	_ "unsafe" // for go:linkname
+)
+
+// Tuning of the network poller, set by runtime/debug.SetNetpollTuning.
+// All are accessed atomically.
+var (
+	// netpollMaxEvents is the maximum number of events fetched by
+	// one netpoll call, or 0 for as many as its buffer holds.
+	netpollMaxEvents uint32
+
+	// netpollMaxWakeups is the maximum number of idle Ps that
+	// injectglist starts for one list of goroutines, or 0 for no
+	// limit.
+	netpollMaxWakeups uint32
+
+	// wakeNetPoller does not interrupt a blocked netpoll for a timer
+	// due less than netpollBreakSlack nanoseconds before the poll
+	// would return anyway.
+	netpollBreakSlack uint64
+)
+
+// Netpoll statistics, reported by runtime/metrics. Accessed atomically.
+var (
+	// netpollSpuriousWakeups counts blocking netpolls in findrunnable
+	// that returned before their deadline with no goroutine to run,
+	// usually because of a netpollBreak.
+	netpollSpuriousWakeups uint64
+
+	// netpollSkippedBreaks counts the netpollBreaks that
+	// wakeNetPoller skipped because of netpollBreakSlack.
+	netpollSkippedBreaks uint64
+
+	// netpollTimerWakeups counts blocking netpolls in findrunnable
+	// that returned at their deadline, for a timer, with no
+	// goroutine to run.
+	netpollTimerWakeups uint64
+
+	// netpollWastedWakeups counts the netpolls of
+	// netpollTimerWakeups after which the M ran no timer on the P
+	// it took, because the timer was stopped, reset later or run on
+	// a busy P, or because no P was idle.
+	netpollWastedWakeups uint64
+)
+
+// netpollEventLimit returns the number of events to fetch in a netpoll
+// call whose buffer holds n events.
+func netpollEventLimit(n int32) int32 {
+	if max := int32(atomic.Load(&netpollMaxEvents)); max > 0 && max < n {
+		return max
+	}
+	return n
+}
+
+//go:linkname setNetpollTuning runtime/debug.setNetpollTuning
+func setNetpollTuning(maxEvents, maxWakeups int, breakSlack int64) (prevMaxEvents, prevMaxWakeups int, prevBreakSlack int64) {
+	if maxEvents < 0 {
+		maxEvents = 0
+	}
+	if maxWakeups < 0 {
+		maxWakeups = 0
+	}
+	if breakSlack < 0 {
+		breakSlack = 0
+	}
+	prevMaxEvents = int(atomic.Xchg(&netpollMaxEvents, uint32(maxEvents)))
+	prevMaxWakeups = int(atomic.Xchg(&netpollMaxWakeups, uint32(maxWakeups)))
+	prevBreakSlack = int64(atomic.Xchg64(&netpollBreakSlack, uint64(breakSlack)))
+	return
+}
@@ -110,6 +110,7 @@ func netpoll(delay int64) gList {
 	if n < 8 {
 		n = 8
 	}
+	n = uint32(netpollEventLimit(int32(n)))
 	if delay != 0 {
 		mp.blocked = true
 	}
@@ -0,0 +1,148 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Out of memory handler.
+//
+// runtime/debug.SetOutOfMemoryHandler registers a function to call
+// when the heap cannot grow, together with the size of a reserve of
+// heap pages that the runtime takes from the operating system at once
+// and holds back. When an allocation then finds that the heap cannot
+// grow, instead of throwing, the runtime returns the reserve to the
+// heap and retries the allocation, which the reserve normally
+// satisfies. The program keeps running on the reserve, and the
+// handler runs soon after in a new goroutine, which can allocate from
+// what is left of the reserve to drop caches or report the condition.
+//
+// The allocation that fails cannot call the handler itself, since it
+// may be deep inside mallocgc. It only marks the handler pending;
+// sysmon notices and wakes the out of memory helper goroutine, which
+// starts the handler. Each reserve is used once: the next failure to
+// grow the heap throws as usual, unless a handler has been registered
+// again since.
+
+package runtime
+
+import (
+	"runtime/internal/atomic"
+	"unsafe"
+)
+
+var oomHandler struct {
+	lock    mutex  // protects the fields below; leaf lock
+	f       func() // registered handler
+	reserve *mspan // held back reserve, nil once used
+	run     func() // handler to start, set when reserve is used
+	started bool
+	g       *g
+	idle    bool
+
+	pending uint32 // run != nil; accessed atomically
+}
+
+// setOutOfMemoryHandler registers f, with a reserve of reserve bytes,
+// replacing any previous handler and releasing its reserve. A nil f
+// removes the handler.
+//
+//go:linkname setOutOfMemoryHandler runtime/debug.setOutOfMemoryHandler
+func setOutOfMemoryHandler(f func(), reserve int64) {
+	var s *mspan
+	if f != nil {
+		npages := (uintptr(reserve) + pageSize - 1) / pageSize
+		if reserve <= 0 || npages == 0 {
+			npages = 1
+		}
+		systemstack(func() {
+			s = mheap_.allocManual(npages, spanAllocOOMReserve)
+		})
+		if s == nil {
+			throw("out of memory allocating out of memory reserve")
+		}
+		// Touch the reserve so that the operating system commits
+		// it now, rather than when it is needed.
+		memclrNoHeapPointers(unsafe.Pointer(s.base()), s.npages*pageSize)
+	}
+
+	start := false
+	lock(&oomHandler.lock)
+	old := oomHandler.reserve
+	oomHandler.f = f
+	oomHandler.reserve = s
+	if f != nil {
+		start = !oomHandler.started
+		oomHandler.started = true
+	}
+	unlock(&oomHandler.lock)
+
+	if old != nil {
+		systemstack(func() {
+			mheap_.freeManual(old, spanAllocOOMReserve)
+		})
+	}
+	if start {
+		go oomhelper()
+	}
+}
+
+// useOutOfMemoryReserve returns the out of memory reserve to the heap
+// and marks the handler pending. It reports whether there was a
+// reserve, in which case the caller should retry the allocation that
+// failed before throwing.
+func useOutOfMemoryReserve() bool {
+	lock(&oomHandler.lock)
+	s := oomHandler.reserve
+	if s != nil {
+		oomHandler.reserve = nil
+		oomHandler.run = oomHandler.f
+		atomic.Store(&oomHandler.pending, 1)
+	}
+	unlock(&oomHandler.lock)
+	if s == nil {
+		return false
+	}
+	print("runtime: out of memory: using the ", s.npages*pageSize, "-byte reserve\n")
+	systemstack(func() {
+		mheap_.freeManual(s, spanAllocOOMReserve)
+	})
+	return true
+}
+
+// checkOutOfMemory wakes the out of memory helper if the handler is
+// pending. It is called by sysmon.
+func checkOutOfMemory() {
+	if atomic.Load(&oomHandler.pending) == 0 {
+		return
+	}
+	var gp *g
+	lock(&oomHandler.lock)
+	if oomHandler.idle {
+		oomHandler.idle = false
+		gp = oomHandler.g
+	}
+	unlock(&oomHandler.lock)
+	if gp != nil {
+		var list gList
+		list.push(gp)
+		injectglist(&list)
+	}
+}
+
+func oomhelper() {
+	lock(&oomHandler.lock)
+	oomHandler.g = getg()
+	for {
+		oomHandler.idle = true
+		goparkunlock(&oomHandler.lock, waitReasonOutOfMemoryIdle, traceEvGoBlock, 1)
+		// this goroutine is explicitly resumed by sysmon
+
+		lock(&oomHandler.lock)
+		f := oomHandler.run
+		oomHandler.run = nil
+		atomic.Store(&oomHandler.pending, 0)
+		unlock(&oomHandler.lock)
+		if f != nil {
+			go f()
+		}
+		lock(&oomHandler.lock)
+	}
+}
@@ -155,6 +155,10 @@ func sigdisable(sig uint32) {
 func sigignore(sig uint32) {
 }
 
+func sigAddHandler(sig uint32, fn unsafe.Pointer) {
+	panic("AddSignalHandler: not supported on plan9")
+}
+
 func setProcessCPUProfiler(hz int32) {
 }
 
@@ -157,7 +157,7 @@ func libpreinit() {
 
 // Ms related functions
 func mpreinit(mp *m) {
-	mp.gsignal = malg(32 * 1024) // AIX wants >= 8K
+	mp.gsignal = malg(gsignalSize(mp, 32*1024)) // AIX wants >= 8K
 	mp.gsignal.m = mp
 }
 
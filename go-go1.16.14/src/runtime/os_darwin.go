@@ -293,8 +293,14 @@ func libpreinit() {
 // Called to initialize a new m (including the bootstrap m).
 // Called on the parent thread (main thread in case of bootstrap), can allocate memory.
 func mpreinit(mp *m) {
-	mp.gsignal = malg(32 * 1024) // OS X wants >= 8K
+	mp.gsignal = malg(gsignalSize(mp, 32*1024)) // OS X wants >= 8K
 	mp.gsignal.m = mp
+	osGsignalAlloc(mp)
+}
+
+// osGsignalAlloc performs OS-specific initialization of mp's newly
+// allocated signal stack.
+func osGsignalAlloc(mp *m) {
 	if GOOS == "darwin" && GOARCH == "arm64" {
 		// mlock the signal stack to work around a kernel bug where it may
 		// SIGILL when the signal stack is not faulted in while a signal
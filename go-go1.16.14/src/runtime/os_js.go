@@ -54,7 +54,7 @@ type sigset struct{}
 // Called to initialize a new m (including the bootstrap m).
 // Called on the parent thread (main thread in case of bootstrap), can allocate memory.
 func mpreinit(mp *m) {
-	mp.gsignal = malg(32 * 1024)
+	mp.gsignal = malg(gsignalSize(mp, 32*1024))
 	mp.gsignal.m = mp
 }
 
@@ -127,6 +127,10 @@ func sigdisable(uint32)              {}
 func sigenable(uint32)               {}
 func sigignore(uint32)               {}
 
+func sigAddHandler(sig uint32, fn unsafe.Pointer) {
+	panic("AddSignalHandler: not supported on js")
+}
+
 //go:linkname os_sigpipe os.sigpipe
 func os_sigpipe() {
 	throw("too many writes on closed pipe")
@@ -153,3 +157,9 @@ const preemptMSupported = false
 func preemptM(mp *m) {
 	// No threads, so nothing to do.
 }
+
+// stwDumpM would ask mp to print its stack for GODEBUG=stwwatchdog.
+// It is not supported here.
+func stwDumpM(mp *m) bool {
+	return false
+}
@@ -5,11 +5,33 @@
 package runtime
 
 import (
+	"runtime/internal/atomic"
 	"runtime/internal/sys"
 	"unsafe"
 )
 
-type mOS struct{}
+type mOS struct {
+	// profileTimer holds the ID of the POSIX interval timer for profiling CPU
+	// usage on this thread.
+	//
+	// It is valid when the profileTimerValid field is non-zero. A thread
+	// creates and manages its own timer, and these fields are read and written
+	// only by this thread. But because some of the reads on profileTimerValid
+	// are in signal handling code, access to that field uses atomic operations.
+	profileTimer      int32
+	profileTimerValid uint32
+
+	// profileTimerPerf is set if profileTimer is the file descriptor
+	// of a perf event rather than a timer ID; see perf_linux.go.
+	profileTimerPerf bool
+
+	// watchfds are the file descriptors of the perf events of the
+	// watchpoints installed on this thread, or -1, and watchcounts
+	// their counts when last read. They are only accessed by this
+	// thread, including its signal handler.
+	watchfds    [maxWatchpoints]int32
+	watchcounts [maxWatchpoints]uint64
+}
 
 //go:noescape
 func futex(addr unsafe.Pointer, op int32, val uint32, ts, addr2 unsafe.Pointer, val3 uint32) int32
@@ -68,6 +90,19 @@ func futexwakeup(addr *uint32, cnt uint32) {
 	*(*int32)(unsafe.Pointer(uintptr(0x1006))) = 0x1006
 }
 
+// extraMSleep sleeps while *addr == val. It runs on a thread without
+// an m, in needm, so it must not split the stack.
+//go:nosplit
+func extraMSleep(addr *uint32, val uint32) {
+	futexsleep(addr, val, -1)
+}
+
+// extraMWakeup wakes the threads sleeping in extraMSleep on addr.
+//go:nosplit
+func extraMWakeup(addr *uint32) {
+	futexwakeup(addr, ^uint32(0)>>1)
+}
+
 func getproccount() int32 {
 	// This buffer is huge (8 kB) but we are on the system stack
 	// and there should be plenty of space (64 kB).
@@ -352,8 +387,11 @@ func libpreinit() {
 // Called to initialize a new m (including the bootstrap m).
 // Called on the parent thread (main thread in case of bootstrap), can allocate memory.
 func mpreinit(mp *m) {
-	mp.gsignal = malg(32 * 1024) // Linux wants >= 2K
+	mp.gsignal = malg(gsignalSize(mp, 32*1024)) // Linux wants >= 2K
 	mp.gsignal.m = mp
+	for i := range mp.watchfds {
+		mp.watchfds[i] = -1
+	}
 }
 
 func gettid() uint32
@@ -374,6 +412,8 @@ func minit() {
 //go:nosplit
 func unminit() {
 	unminitSignals()
+	deleteThreadProfileTimer(getg().m)
+	clearThreadWatchpoints(getg().m)
 }
 
 // Called from exitm, but not from drop, to undo the effect of thread-owned
@@ -395,6 +435,19 @@ func sigaltstack(new, old *stackt)
 //go:noescape
 func setitimer(mode int32, new, old *itimerval)
 
+//go:noescape
+func timer_create(clockid int32, sevp *sigevent, timerid *int32) int32
+
+//go:noescape
+func timer_settime(timerid int32, flags int32, new, old *itimerspec) int32
+
+func timer_delete(timerid int32) int32
+
+//go:noescape
+func perf_event_open(attr *perfEventAttr, pid, cpu, groupfd int32, flags uintptr) int32
+
+func fcntl(fd, cmd int32, arg uintptr) int32
+
 //go:noescape
 func rtsigprocmask(how int32, new, old *sigset, size int32)
 
@@ -413,6 +466,7 @@ func osyield()
 
 func pipe() (r, w int32, errno int32)
 func pipe2(flags int32) (r, w int32, errno int32)
+func eventfd(initval uint32, flags int32) int32
 func setNonblock(fd int32)
 
 //go:nosplit
@@ -503,3 +557,267 @@ func tgkill(tgid, tid, sig int)
 func signalM(mp *m, sig int) {
 	tgkill(getpid(), int(mp.procid), sig)
 }
+
+// sigevent is the kernel's struct sigevent. Only the fields the runtime
+// uses are named; sigev_notify_thread_id is the first member of the union
+// that pads the struct out to _sigev_max_size bytes.
+type sigevent struct {
+	sigeventFields
+	_ [_sigev_max_size - unsafe.Sizeof(sigeventFields{})]byte
+}
+
+type sigeventFields struct {
+	value                  uintptr
+	signo                  int32
+	notify                 int32
+	sigev_notify_thread_id int32
+}
+
+const _sigev_max_size = 64
+
+// setProcessCPUProfiler is called when the profiling timer changes.
+// It is called with prof.lock held. hz is the new timer, and is 0 if
+// profiling is being disabled.
+//
+// The process-wide timer only delivers a signal to whichever thread is
+// running when it expires, so with many busy threads it undercounts.
+// Threads running Go code therefore also get a timer of their own in
+// setThreadCPUProfiler, and threads created by C code can get one with
+// AddCPUProfileThread. The process-wide timer still covers all other
+// threads; validSIGPROF keeps a thread from being sampled by both.
+//
+// When CPU profiling samples a perf event, there is no process-wide
+// timer: each thread samples itself with an event of its own. The
+// cpu-clock events used for high sampling rates still measure CPU
+// time, so the process-wide timer keeps covering the other threads.
+func setProcessCPUProfiler(hz int32) {
+	if hz != 0 && cpuProfileEvent.period != 0 && !cpuProfileEvent.clock {
+		setSigprofHandler(true)
+	} else {
+		setProcessCPUProfilerTimer(hz)
+	}
+	setProfThreadTimers(hz)
+}
+
+// setThreadCPUProfiler makes any thread-specific changes required to
+// implement profiling at a rate of hz. On Linux it replaces the calling
+// thread's profiling timer, if any, with one firing every 1/hz seconds
+// of the thread's CPU time, or with a perf event if one is selected.
+func setThreadCPUProfiler(hz int32) {
+	mp := getg().m
+	mp.profilehz = hz
+
+	deleteThreadProfileTimer(mp)
+	if hz == 0 || profThreadRegistered(int32(mp.procid)) {
+		// A registered thread already has a timer of its own.
+		return
+	}
+	if timerid, perf, ok := newThreadProfiler(_CLOCK_THREAD_CPUTIME_ID, int32(mp.procid), hz); ok {
+		mp.profileTimer = timerid
+		mp.profileTimerPerf = perf
+		atomic.Store(&mp.profileTimerValid, 1)
+	}
+}
+
+// deleteThreadProfileTimer stops and deletes mp's profiling timer, if it
+// has one. It must be called on mp's thread.
+//
+//go:nosplit
+func deleteThreadProfileTimer(mp *m) {
+	if atomic.Load(&mp.profileTimerValid) == 0 {
+		return
+	}
+	// Mark the timer invalid before deleting it so that signals it
+	// already sent are treated as coming from the process-wide timer
+	// source that this thread no longer listens to.
+	atomic.Store(&mp.profileTimerValid, 0)
+	deleteThreadProfiler(mp.profileTimer, mp.profileTimerPerf)
+	mp.profileTimer = 0
+	mp.profileTimerPerf = false
+}
+
+// newThreadProfiler starts sampling thread tid with cpuProfileEvent, if
+// it is set, and otherwise with a timer firing every 1/hz seconds of
+// the CPU time measured by clock. It returns the perf event's file
+// descriptor or the timer's ID, and whether it is a perf event.
+func newThreadProfiler(clock, tid, hz int32) (id int32, perf, ok bool) {
+	if cpuProfileEvent.period != 0 {
+		id, ok = newPerfEvent(tid)
+		return id, true, ok
+	}
+	id, ok = newProfileTimer(clock, tid, hz)
+	return id, false, ok
+}
+
+// deleteThreadProfiler stops a thread's sampling started by
+// newThreadProfiler.
+//
+//go:nosplit
+func deleteThreadProfiler(id int32, perf bool) {
+	if perf {
+		closefd(id)
+	} else {
+		timer_delete(id)
+	}
+}
+
+// newProfileTimer creates a timer that sends SIGPROF to thread tid every
+// 1/hz seconds of the CPU time measured by clock.
+// It reports false if the kernel does not support such timers, in which
+// case the thread is left to the process-wide timer.
+func newProfileTimer(clock, tid, hz int32) (timerid int32, ok bool) {
+	var sevp sigevent
+	sevp.notify = _SIGEV_THREAD_ID
+	sevp.signo = _SIGPROF
+	sevp.sigev_notify_thread_id = tid
+	if timer_create(clock, &sevp, &timerid) != 0 {
+		return 0, false
+	}
+
+	// Start each timer at a random point in its first period so that
+	// threads that start running together are not sampled in lockstep.
+	var spec itimerspec
+	spec.it_interval.setNsec(1e9 / int64(hz))
+	spec.it_value.setNsec(1 + int64(fastrandn(uint32(1e9/hz))))
+	if timer_settime(timerid, 0, &spec, nil) != 0 {
+		timer_delete(timerid)
+		return 0, false
+	}
+	return timerid, true
+}
+
+// threadCPUClock returns the clock ID measuring the CPU time of thread tid,
+// as computed by glibc's pthread_getcpuclockid.
+func threadCPUClock(tid int32) int32 {
+	const (
+		cpuclockPerthreadMask = 4
+		cpuclockSched         = 2
+	)
+	return ^tid<<3 | cpuclockPerthreadMask | cpuclockSched
+}
+
+// profThreads holds the threads created by C code that were registered
+// with AddCPUProfileThread.
+var profThreads struct {
+	lock mutex
+	hz   int32 // current profiling rate
+
+	// list is a *[]profThread. It is replaced, never resized, so that
+	// validSIGPROF can read the thread IDs without locking.
+	// The timer fields of its elements are protected by lock.
+	list unsafe.Pointer
+}
+
+type profThread struct {
+	tid     int32
+	timerid int32
+	timer   bool // timerid is valid
+	perf    bool // timerid is a perf event file descriptor
+}
+
+func addProfThread(tid int32) {
+	lock(&profThreads.lock)
+	list := new([]profThread)
+	if old := profThreads.list; old != nil {
+		for _, t := range *(*[]profThread)(old) {
+			if t.tid == tid {
+				unlock(&profThreads.lock)
+				return
+			}
+			*list = append(*list, t)
+		}
+	}
+	t := profThread{tid: tid}
+	if profThreads.hz != 0 {
+		t.timerid, t.perf, t.timer = newThreadProfiler(threadCPUClock(tid), tid, profThreads.hz)
+	}
+	*list = append(*list, t)
+	atomicstorep(unsafe.Pointer(&profThreads.list), unsafe.Pointer(list))
+	unlock(&profThreads.lock)
+}
+
+func removeProfThread(tid int32) {
+	lock(&profThreads.lock)
+	if old := profThreads.list; old != nil {
+		list := new([]profThread)
+		for _, t := range *(*[]profThread)(old) {
+			if t.tid != tid {
+				*list = append(*list, t)
+			} else if t.timer {
+				deleteThreadProfiler(t.timerid, t.perf)
+			}
+		}
+		atomicstorep(unsafe.Pointer(&profThreads.list), unsafe.Pointer(list))
+	}
+	unlock(&profThreads.lock)
+}
+
+// setProfThreadTimers replaces the timers of the registered threads with
+// ones for the profiling rate hz.
+func setProfThreadTimers(hz int32) {
+	lock(&profThreads.lock)
+	profThreads.hz = hz
+	if p := profThreads.list; p != nil {
+		list := *(*[]profThread)(p)
+		for i := range list {
+			t := &list[i]
+			if t.timer {
+				deleteThreadProfiler(t.timerid, t.perf)
+				t.timer = false
+			}
+			if hz != 0 {
+				t.timerid, t.perf, t.timer = newThreadProfiler(threadCPUClock(t.tid), t.tid, hz)
+			}
+		}
+	}
+	unlock(&profThreads.lock)
+}
+
+// profThreadRegistered reports whether tid was registered with
+// AddCPUProfileThread.
+//
+//go:nosplit
+func profThreadRegistered(tid int32) bool {
+	p := (*[]profThread)(atomic.Loadp(unsafe.Pointer(&profThreads.list)))
+	if p == nil {
+		return false
+	}
+	for i := range *p {
+		if (*p)[i].tid == tid {
+			return true
+		}
+	}
+	return false
+}
+
+// validSIGPROF compares this signal delivery's code against the signal
+// sources that the profiler uses, returning whether the delivery should be
+// processed. A thread with a profiling timer of its own only counts that
+// timer's signals, so that it is not sampled twice. Signals from other
+// sources, including the perf events of setThreadCPUProfiler, are always
+// considered valid.
+//
+//go:nosplit
+func validSIGPROF(mp *m, c *sigctxt) bool {
+	code := int32(c.sigcode())
+	setitimer := code == _SI_KERNEL
+	timer := code == _SI_TIMER
+	if !(setitimer || timer) {
+		// The signal doesn't correspond to a profiling timer at all.
+		return true
+	}
+
+	var tid int32
+	if mp != nil {
+		if atomic.Load(&mp.profileTimerValid) != 0 {
+			return timer
+		}
+		tid = int32(mp.procid)
+	} else {
+		tid = int32(gettid())
+	}
+	if profThreadRegistered(tid) {
+		return timer
+	}
+	return setitimer
+}
@@ -0,0 +1,12 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !darwin
+
+package runtime
+
+// osGsignalAlloc performs OS-specific initialization of mp's newly
+// allocated signal stack.
+func osGsignalAlloc(mp *m) {
+}
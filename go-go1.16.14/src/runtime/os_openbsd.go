@@ -151,11 +151,11 @@ func goenvs() {
 // Called to initialize a new m (including the bootstrap m).
 // Called on the parent thread (main thread in case of bootstrap), can allocate memory.
 func mpreinit(mp *m) {
-	gsignalSize := int32(32 * 1024)
+	size := int32(32 * 1024)
 	if GOARCH == "mips64" {
-		gsignalSize = int32(64 * 1024)
+		size = int32(64 * 1024)
 	}
-	mp.gsignal = malg(gsignalSize)
+	mp.gsignal = malg(gsignalSize(mp, size))
 	mp.gsignal.m = mp
 }
 
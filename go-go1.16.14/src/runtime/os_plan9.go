@@ -176,7 +176,7 @@ type sigset struct{}
 // Called on the parent thread (main thread in case of bootstrap), can allocate memory.
 func mpreinit(mp *m) {
 	// Initialize stack and goroutine for note handling.
-	mp.gsignal = malg(32 * 1024)
+	mp.gsignal = malg(gsignalSize(mp, 32*1024))
 	mp.gsignal.m = mp
 	mp.notesig = (*int8)(mallocgc(_ERRMAX, nil, true))
 	// Initialize stack for handling strings from the
@@ -512,3 +512,9 @@ func preemptM(mp *m) {
 	//
 	// TODO: Use a note like we use signals on POSIX OSes
 }
+
+// stwDumpM would ask mp to print its stack for GODEBUG=stwwatchdog.
+// It is not supported here.
+func stwDumpM(mp *m) bool {
+	return false
+}
@@ -0,0 +1,30 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build aix darwin dragonfly freebsd netbsd openbsd solaris
+
+package runtime
+
+// setProcessCPUProfiler is called when the profiling timer changes.
+// It is called with prof.lock held. hz is the new timer, and is 0 if
+// profiling is being disabled. Enable or disable the signal as
+// required for -buildmode=c-archive.
+func setProcessCPUProfiler(hz int32) {
+	setProcessCPUProfilerTimer(hz)
+}
+
+// setThreadCPUProfiler makes any thread-specific changes required to
+// implement profiling at a rate of hz.
+// No changes required on Unix systems other than Linux.
+func setThreadCPUProfiler(hz int32) {
+	getg().m.profilehz = hz
+}
+
+// validSIGPROF reports whether a SIGPROF delivery should be processed.
+// Only Linux has more than one source of profiling signals.
+//
+//go:nosplit
+func validSIGPROF(mp *m, c *sigctxt) bool {
+	return true
+}
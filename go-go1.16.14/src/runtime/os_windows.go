@@ -1120,12 +1120,16 @@ func profilem(mp *m, thread uintptr) {
 	var cbuf [unsafe.Sizeof(*c) + 15]byte
 	c = (*context)(unsafe.Pointer((uintptr(unsafe.Pointer(&cbuf[15]))) &^ 15))
 
-	c.contextflags = _CONTEXT_CONTROL
-	stdcall2(_GetThreadContext, thread, uintptr(unsafe.Pointer(c)))
+	if !suspendThread(thread, c) {
+		return
+	}
 
-	gp := gFromTLS(mp)
+	if mp.profilehz != 0 && !mp.blocked {
+		gp := gFromTLS(mp)
+		sigprof(c.ip(), c.sp(), c.lr(), gp, mp)
+	}
 
-	sigprof(c.ip(), c.sp(), c.lr(), gp, mp)
+	stdcall1(_ResumeThread, thread)
 }
 
 func gFromTLS(mp *m) *g {
@@ -1167,18 +1171,10 @@ func profileloop1(param uintptr) uint32 {
 			// mp may exit between the DuplicateHandle
 			// above and the SuspendThread. The handle
 			// will remain valid, but SuspendThread may
-			// fail.
-			if int32(stdcall1(_SuspendThread, thread)) == -1 {
-				// The thread no longer exists.
-				stdcall1(_CloseHandle, thread)
-				continue
-			}
-			if mp.profilehz != 0 && !mp.blocked {
-				// Pass the thread handle in case mp
-				// was in the process of shutting down.
-				profilem(mp, thread)
-			}
-			stdcall1(_ResumeThread, thread)
+			// fail, in which case profilem does nothing.
+			// Pass the thread handle in case mp was in
+			// the process of shutting down.
+			profilem(mp, thread)
 			stdcall1(_CloseHandle, thread)
 		}
 	}
@@ -1214,6 +1210,37 @@ const preemptMSupported = GOARCH != "arm"
 // suspending each other.
 var suspendLock mutex
 
+// suspendThread suspends thread, a handle to an M's thread, and reads
+// its control registers into c, which must be 16-byte aligned. If it
+// returns true, the caller must resume the thread with ResumeThread.
+// It returns false, leaving the thread running, if the thread no
+// longer exists or its context cannot be read.
+//
+// Suspension is serialized by suspendLock for preemption and
+// profiling alike: SuspendThread is asynchronous, so two threads
+// could otherwise suspend each other, and exit takes suspendLock so
+// that ExitProcess cannot kill a suspending thread and leave the
+// exiting thread suspended forever.
+func suspendThread(thread uintptr, c *context) bool {
+	lock(&suspendLock)
+	if int32(stdcall1(_SuspendThread, thread)) == -1 {
+		unlock(&suspendLock)
+		return false
+	}
+
+	// SuspendThread only requests a suspend. GetThreadContext
+	// blocks until the thread is actually suspended, so the lock
+	// must be held until it returns.
+	c.contextflags = _CONTEXT_CONTROL
+	if stdcall2(_GetThreadContext, thread, uintptr(unsafe.Pointer(c))) == 0 {
+		stdcall1(_ResumeThread, thread)
+		unlock(&suspendLock)
+		return false
+	}
+	unlock(&suspendLock)
+	return true
+}
+
 func preemptM(mp *m) {
 	if GOARCH == "arm" {
 		// TODO: Implement call injection
@@ -1224,11 +1251,15 @@ func preemptM(mp *m) {
 		throw("self-preempt")
 	}
 
+	atomic.Xadd64(&asyncPreemptStats.requests, 1)
+
 	// Synchronize with external code that may try to ExitProcess.
 	if !atomic.Cas(&mp.preemptExtLock, 0, 1) {
 		// External code is running. Fail the preemption
 		// attempt.
+		atomic.Xadd64(&asyncPreemptStats.dropped, 1)
 		atomic.Xadd(&mp.preemptGen, 1)
+		counterPreemptsHandled.add(1)
 		return
 	}
 
@@ -1238,7 +1269,9 @@ func preemptM(mp *m) {
 		// The M hasn't been minit'd yet (or was just unminit'd).
 		unlock(&mp.threadLock)
 		atomic.Store(&mp.preemptExtLock, 0)
+		atomic.Xadd64(&asyncPreemptStats.dropped, 1)
 		atomic.Xadd(&mp.preemptGen, 1)
+		counterPreemptsHandled.add(1)
 		return
 	}
 	var thread uintptr
@@ -1252,23 +1285,16 @@ func preemptM(mp *m) {
 	var c *context
 	var cbuf [unsafe.Sizeof(*c) + 15]byte
 	c = (*context)(unsafe.Pointer((uintptr(unsafe.Pointer(&cbuf[15]))) &^ 15))
-	c.contextflags = _CONTEXT_CONTROL
-
-	// Serialize thread suspension. SuspendThread is asynchronous,
-	// so it's otherwise possible for two threads to suspend each
-	// other and deadlock. We must hold this lock until after
-	// GetThreadContext, since that blocks until the thread is
-	// actually suspended.
-	lock(&suspendLock)
 
-	// Suspend the thread.
-	if int32(stdcall1(_SuspendThread, thread)) == -1 {
-		unlock(&suspendLock)
+	if !suspendThread(thread, c) {
 		stdcall1(_CloseHandle, thread)
 		atomic.Store(&mp.preemptExtLock, 0)
-		// The thread no longer exists. This shouldn't be
-		// possible, but just acknowledge the request.
+		// The thread no longer exists or its context could
+		// not be read. This shouldn't be possible, but just
+		// acknowledge the request.
+		atomic.Xadd64(&asyncPreemptStats.dropped, 1)
 		atomic.Xadd(&mp.preemptGen, 1)
+		counterPreemptsHandled.add(1)
 		return
 	}
 
@@ -1278,13 +1304,6 @@ func preemptM(mp *m) {
 	// anything when we stopped it, including holding arbitrary
 	// locks.
 
-	// We have to get the thread context before inspecting the M
-	// because SuspendThread only requests a suspend.
-	// GetThreadContext actually blocks until it's suspended.
-	stdcall2(_GetThreadContext, thread, uintptr(unsafe.Pointer(c)))
-
-	unlock(&suspendLock)
-
 	// Does it want a preemption and is it safe to preempt?
 	gp := gFromTLS(mp)
 	if wantAsyncPreempt(gp) {
@@ -1304,6 +1323,7 @@ func preemptM(mp *m) {
 			}
 
 			stdcall2(_SetThreadContext, thread, uintptr(unsafe.Pointer(c)))
+			atomic.Xadd64(&asyncPreemptStats.injected, 1)
 		}
 	}
 
@@ -1311,11 +1331,18 @@ func preemptM(mp *m) {
 
 	// Acknowledge the preemption.
 	atomic.Xadd(&mp.preemptGen, 1)
+	counterPreemptsHandled.add(1)
 
 	stdcall1(_ResumeThread, thread)
 	stdcall1(_CloseHandle, thread)
 }
 
+// stwDumpM would ask mp to print its stack for GODEBUG=stwwatchdog.
+// It is not supported here.
+func stwDumpM(mp *m) bool {
+	return false
+}
+
 // osPreemptExtEnter is called before entering external code that may
 // call ExitProcess.
 //
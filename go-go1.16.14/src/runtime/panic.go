@@ -395,12 +395,12 @@ func newdefer(siz int32) *_defer {
 	gp := getg()
 	if sc < uintptr(len(p{}.deferpool)) {
 		pp := gp.m.p.ptr()
-		if len(pp.deferpool[sc]) == 0 && sched.deferpool[sc] != nil {
+		if len(pp.deferpool[sc]) == 0 && cap(pp.deferpool[sc]) > 0 && sched.deferpool[sc] != nil {
 			// Take the slow path on the system stack so
 			// we don't grow newdefer's stack.
 			systemstack(func() {
 				lock(&sched.deferlock)
-				for len(pp.deferpool[sc]) < cap(pp.deferpool[sc])/2 && sched.deferpool[sc] != nil {
+				for len(pp.deferpool[sc]) < (cap(pp.deferpool[sc])+1)/2 && sched.deferpool[sc] != nil {
 					d := sched.deferpool[sc]
 					sched.deferpool[sc] = d.link
 					d.link = nil
@@ -413,6 +413,9 @@ func newdefer(siz int32) *_defer {
 			d = pp.deferpool[sc][n-1]
 			pp.deferpool[sc][n-1] = nil
 			pp.deferpool[sc] = pp.deferpool[sc][:n-1]
+			atomic.Store64(&pp.deferstats[sc].hits, pp.deferstats[sc].hits+1)
+		} else {
+			atomic.Store64(&pp.deferstats[sc].misses, pp.deferstats[sc].misses+1)
 		}
 	}
 	if d == nil {
@@ -449,6 +452,11 @@ func freedefer(d *_defer) {
 		return
 	}
 	pp := getg().m.p.ptr()
+	if cap(pp.deferpool[sc]) == 0 {
+		// Pooling is disabled for this class by
+		// runtime/debug.SetDeferPoolDepth.
+		return
+	}
 	if len(pp.deferpool[sc]) == cap(pp.deferpool[sc]) {
 		// Transfer half of local cache to the central cache.
 		//
@@ -1331,6 +1339,7 @@ func dopanic_m(gp *g, pc, sp uintptr) bool {
 	}
 
 	printDebugLog()
+	printGStatusTrace()
 
 	return docrash
 }
@@ -0,0 +1,185 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+import "unsafe"
+
+// CPU profiling can sample threads on the overflow of a perf event,
+// such as a hardware performance counter, rather than on a CPU time
+// timer. Each thread then opens an event counting its own execution,
+// which sends it SIGPROF every cpuProfileEvent.period occurrences;
+// the signal is handled by sigprof as usual.
+
+// perfEventAttr is struct perf_event_attr, up to PERF_ATTR_SIZE_VER5.
+type perfEventAttr struct {
+	typ              uint32
+	size             uint32
+	config           uint64
+	samplePeriod     uint64
+	sampleType       uint64
+	readFormat       uint64
+	flags            uint64
+	wakeupEvents     uint32
+	bpType           uint32
+	bpAddr           uint64
+	bpLen            uint64
+	branchSampleType uint64
+	sampleRegsUser   uint64
+	sampleStackUser  uint32
+	clockid          int32
+	sampleRegsIntr   uint64
+	auxWatermark     uint32
+	sampleMaxStack   uint16
+	_                uint16
+}
+
+const (
+	_PERF_TYPE_HARDWARE = 0
+	_PERF_TYPE_SOFTWARE = 1
+
+	_PERF_COUNT_HW_CPU_CYCLES    = 0
+	_PERF_COUNT_HW_CACHE_MISSES  = 3
+	_PERF_COUNT_HW_BRANCH_MISSES = 5
+	_PERF_COUNT_SW_CPU_CLOCK     = 0
+
+	_PERF_FLAG_FD_CLOEXEC = 0x8
+
+	// perfEventAttr.flags bits.
+	_PERF_ATTR_EXCLUDE_KERNEL = 1 << 5
+	_PERF_ATTR_EXCLUDE_HV     = 1 << 6
+
+	_F_SETFL     = 0x4
+	_F_SETSIG    = 0xa
+	_F_SETOWN_EX = 0xf
+	_F_OWNER_TID = 0
+)
+
+type fOwnerEx struct {
+	typ int32
+	pid int32
+}
+
+// cpuProfileEvent is the perf event that CPU profiling samples.
+// It is only changed while profiling is disabled.
+var cpuProfileEvent struct {
+	typ    uint32
+	config uint64
+	period uint64 // events per sample, or 0 to sample CPU time with timers
+	clock  bool   // selected by setCPUProfileClock rather than runtime/pprof
+}
+
+// setCPUProfileEvent selects the event that CPU profiling samples from
+// then on, one of the runtime/pprof.CPUProfileEvent values, sampled
+// every period occurrences. Event 0 selects CPU time. It reports
+// whether the event can be counted.
+func setCPUProfileEvent(event int32, period uint64) bool {
+	if event == 0 {
+		cpuProfileEvent.typ = 0
+		cpuProfileEvent.config = 0
+		cpuProfileEvent.period = 0
+		cpuProfileEvent.clock = false
+		return true
+	}
+	var typ uint32
+	var config uint64
+	switch event {
+	case 1:
+		typ, config = _PERF_TYPE_HARDWARE, _PERF_COUNT_HW_CPU_CYCLES
+	case 2:
+		typ, config = _PERF_TYPE_HARDWARE, _PERF_COUNT_HW_CACHE_MISSES
+	case 3:
+		typ, config = _PERF_TYPE_HARDWARE, _PERF_COUNT_HW_BRANCH_MISSES
+	case 4:
+		typ, config = _PERF_TYPE_SOFTWARE, _PERF_COUNT_SW_CPU_CLOCK
+	default:
+		return false
+	}
+	if period == 0 {
+		return false
+	}
+
+	// Check that the kernel lets us count the event, which depends on
+	// the hardware and on perf_event_paranoid.
+	fd := openPerfEvent(typ, config, period, int32(gettid()))
+	if fd < 0 {
+		return false
+	}
+	closefd(fd)
+	cpuProfileEvent.typ = typ
+	cpuProfileEvent.config = config
+	cpuProfileEvent.period = period
+	return true
+}
+
+// setCPUProfileClock arranges for CPU time to be sampled hz times per
+// second with cpu-clock perf events rather than timers, unless another
+// event is selected. The kernel only checks timers that measure thread
+// CPU time on its scheduler tick, so they cannot fire more often than
+// the tick rate, usually between 100 and 1000 Hz, while cpu-clock
+// events run on high-resolution timers. It reports whether the events
+// are in use. Like setCPUProfileEvent, it must only be called while
+// profiling is disabled.
+//
+// Unlike the ITIMER_PROF timer, which measures user and system time,
+// the events exclude time spent in the kernel (see openPerfEvent), so
+// the profile only covers user time.
+func setCPUProfileClock(hz int32) bool {
+	if cpuProfileEvent.period != 0 || !setCPUProfileEvent(4, uint64(1e9/hz)) {
+		return false
+	}
+	cpuProfileEvent.clock = true
+	return true
+}
+
+// newPerfEvent opens cpuProfileEvent for thread tid. It reports false
+// if the event cannot be opened, in which case the thread is not
+// sampled.
+func newPerfEvent(tid int32) (fd int32, ok bool) {
+	fd = openPerfEvent(cpuProfileEvent.typ, cpuProfileEvent.config, cpuProfileEvent.period, tid)
+	return fd, fd >= 0
+}
+
+// openPerfEvent opens a perf event counting the user space occurrences
+// of config on thread tid, which sends SIGPROF to tid every period
+// occurrences. It returns the event's file descriptor, or a negative
+// errno.
+func openPerfEvent(typ uint32, config, period uint64, tid int32) int32 {
+	attr := perfEventAttr{
+		typ:          typ,
+		size:         uint32(unsafe.Sizeof(perfEventAttr{})),
+		config:       config,
+		samplePeriod: period,
+		flags:        _PERF_ATTR_EXCLUDE_KERNEL | _PERF_ATTR_EXCLUDE_HV,
+		wakeupEvents: 1,
+	}
+	return openPerfEventAttr(&attr, tid, _SIGPROF)
+}
+
+// openPerfEventAttr opens the perf event described by attr on thread
+// tid, which sends sig to tid on every overflow. It returns the event's
+// file descriptor, or a negative errno.
+func openPerfEventAttr(attr *perfEventAttr, tid int32, sig uint32) int32 {
+	fd := perf_event_open(attr, tid, -1, -1, _PERF_FLAG_FD_CLOEXEC)
+	if fd < 0 {
+		return fd
+	}
+
+	// Direct the overflow signal at the thread itself rather than at
+	// the process, so that the handler sees the thread's state.
+	owner := fOwnerEx{typ: _F_OWNER_TID, pid: tid}
+	if r := fcntl(fd, _F_SETOWN_EX, uintptr(unsafe.Pointer(&owner))); r < 0 {
+		closefd(fd)
+		return r
+	}
+	if r := fcntl(fd, _F_SETSIG, uintptr(sig)); r < 0 {
+		closefd(fd)
+		return r
+	}
+	if r := fcntl(fd, _F_SETFL, _O_ASYNC); r < 0 {
+		closefd(fd)
+		return r
+	}
+	return fd
+}
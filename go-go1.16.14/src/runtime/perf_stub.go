@@ -0,0 +1,19 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux
+
+package runtime
+
+// setCPUProfileEvent reports that only CPU time can be sampled:
+// perf events are a Linux feature.
+func setCPUProfileEvent(event int32, period uint64) bool {
+	return event == 0
+}
+
+// setCPUProfileClock reports that CPU time is always sampled with
+// timers.
+func setCPUProfileClock(hz int32) bool {
+	return false
+}
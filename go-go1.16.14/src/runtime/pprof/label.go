@@ -35,6 +35,9 @@ func labelValue(ctx context.Context) labelMap {
 // labelMap is the representation of the label set held in the context type.
 // This is an initial implementation, but it will be replaced with something
 // that admits incremental immutable modification more efficiently.
+// runtime.StackFiltered reads the goroutine's *labelMap as a
+// *map[string]string, and runtime/cgo.PinThread creates one, so the
+// two must be changed together.
 type labelMap map[string]string
 
 // String statisfies Stringer and returns key, value pairs in a consistent
@@ -79,6 +79,7 @@ import (
 	"io"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"text/tabwriter"
@@ -105,6 +106,9 @@ import (
 //	threadcreate - stack traces that led to the creation of new OS threads
 //	block        - stack traces that led to blocking on synchronization primitives
 //	mutex        - stack traces of holders of contended mutexes
+//	offcpu       - stack traces of time goroutines spent off CPU
+//	starvation   - stack traces of mutexes entering and handing off in starvation mode
+//	gsample      - stack traces and states of goroutines sampled at random
 //
 // These predefined profiles maintain themselves and panic on an explicit
 // Add or Remove method call.
@@ -126,6 +130,32 @@ import (
 // pprof display to -alloc_space, the total number of bytes allocated since
 // the program began (including garbage-collected bytes).
 //
+// The goroutine profile labels goroutines named with
+// runtime.SetGoroutineName with goroutine.name, and goroutines that
+// have been waiting for a second or more with goroutine.blocked: the
+// largest of 1s, 10s, 1m, 10m and 1h they have been waiting for. The
+// runtime knows when a goroutine blocked only roughly, from the first
+// garbage collection that found it blocked, unless the program runs
+// with GODEBUG=waitsince=1.
+//
+// The offcpu profile complements the CPU profile: it reports the wall
+// time goroutines spent parked or blocked in system calls, attributed
+// to their stacks and labeled with the reason they were waiting.
+// It is only collected after a call to runtime.SetOffCPUProfileRate.
+//
+// The starvation profile reports the sync.Mutex Lock calls that
+// waited long enough to switch their mutex to starvation mode, and the
+// Unlock calls that then handed the mutex directly to a waiter, labeled
+// with the event and weighted by how long the waiters had queued.
+// Like the mutex profile, it is sampled at the rate set by
+// runtime.SetMutexProfileFraction.
+//
+// The gsample profile counts how often goroutines picked at random were
+// found at each stack, labeled with the state they were in: running,
+// runnable, in a system call, or why they were waiting. It is a cheap
+// statistical view of what the process is doing, and is only collected
+// after a call to runtime.SetGoroutineSampleRate.
+//
 // The CPU profile is not available as a Profile. It has a special API,
 // the StartCPUProfile and StopCPUProfile functions, because it streams
 // output to a writer during profiling.
@@ -180,6 +210,24 @@ var mutexProfile = &Profile{
 	write: writeMutex,
 }
 
+var offcpuProfile = &Profile{
+	name:  "offcpu",
+	count: countOffCPU,
+	write: writeOffCPU,
+}
+
+var starvationProfile = &Profile{
+	name:  "starvation",
+	count: countMutexStarvation,
+	write: writeMutexStarvation,
+}
+
+var gsampleProfile = &Profile{
+	name:  "gsample",
+	count: countGoroutineSample,
+	write: writeGoroutineSample,
+}
+
 func lockProfiles() {
 	profiles.mu.Lock()
 	if profiles.m == nil {
@@ -191,6 +239,9 @@ func lockProfiles() {
 			"allocs":       allocsProfile,
 			"block":        blockProfile,
 			"mutex":        mutexProfile,
+			"offcpu":       offcpuProfile,
+			"starvation":   starvationProfile,
+			"gsample":      gsampleProfile,
 		}
 	}
 }
@@ -674,14 +725,82 @@ func countGoroutine() int {
 }
 
 // runtime_goroutineProfileWithLabels is defined in runtime/mprof.go
-func runtime_goroutineProfileWithLabels(p []runtime.StackRecord, labels []unsafe.Pointer) (n int, ok bool)
+func runtime_goroutineProfileWithLabels(p []runtime.StackRecord, labels []unsafe.Pointer, names []string, waits []int64) (n int, ok bool)
+
+// goroutineNameLabel is the label under which the goroutine profile
+// reports the names set with runtime.SetGoroutineName.
+const goroutineNameLabel = "goroutine.name"
+
+// goroutineBlockedLabel is the label under which the goroutine profile
+// reports how long a waiting goroutine has been blocked, rounded down
+// to one of goroutineBlockedBuckets so that goroutines blocked at the
+// same stack for a similar time are still counted together.
+const goroutineBlockedLabel = "goroutine.blocked"
+
+var goroutineBlockedBuckets = []struct {
+	d    time.Duration
+	name string
+}{
+	{time.Hour, "1h"},
+	{10 * time.Minute, "10m"},
+	{time.Minute, "1m"},
+	{10 * time.Second, "10s"},
+	{time.Second, "1s"},
+}
+
+// goroutineBlocked returns the value of goroutineBlockedLabel for a
+// goroutine blocked for d, or "" if it has not been blocked for a second.
+func goroutineBlocked(d time.Duration) string {
+	for _, b := range goroutineBlockedBuckets {
+		if d >= b.d {
+			return b.name
+		}
+	}
+	return ""
+}
 
 // writeGoroutine writes the current runtime GoroutineProfile to w.
 func writeGoroutine(w io.Writer, debug int) error {
 	if debug >= 2 {
 		return writeGoroutineStacks(w)
 	}
-	return writeRuntimeProfile(w, debug, "goroutine", runtime_goroutineProfileWithLabels)
+	return writeRuntimeProfile(w, debug, "goroutine", fetchGoroutineProfile)
+}
+
+// fetchGoroutineProfile fetches the goroutine profile, adding the name
+// of each named goroutine and how long each goroutine blocked for a
+// second or more has been waiting to its labels.
+func fetchGoroutineProfile(p []runtime.StackRecord, labels []unsafe.Pointer) (int, bool) {
+	var names []string
+	var waits []int64
+	if labels != nil {
+		names = make([]string, len(labels))
+		waits = make([]int64, len(labels))
+	}
+	n, ok := runtime_goroutineProfileWithLabels(p, labels, names, waits)
+	if !ok || names == nil {
+		return n, ok
+	}
+	for i, name := range names[:n] {
+		blocked := goroutineBlocked(time.Duration(waits[i]))
+		if name == "" && blocked == "" {
+			continue
+		}
+		m := labelMap{}
+		if old := (*labelMap)(labels[i]); old != nil {
+			for k, v := range *old {
+				m[k] = v
+			}
+		}
+		if name != "" {
+			m[goroutineNameLabel] = name
+		}
+		if blocked != "" {
+			m[goroutineBlockedLabel] = blocked
+		}
+		labels[i] = unsafe.Pointer(&m)
+	}
+	return n, ok
 }
 
 func writeGoroutineStacks(w io.Writer) error {
@@ -745,6 +864,12 @@ var cpu struct {
 	sync.Mutex
 	profiling bool
 	done      chan bool
+
+	// While profiling for StartWindow, rotate and windows connect
+	// to its windowWriter, and window is the open window, if any.
+	rotate  chan bool
+	windows chan *profileBuilder
+	window  *profWindow
 }
 
 // StartCPUProfile enables CPU profiling for the current process.
@@ -759,17 +884,101 @@ var cpu struct {
 // for syscall.SIGPROF, but note that doing so may break any profiling
 // being done by the main program.
 func StartCPUProfile(w io.Writer) error {
-	// The runtime routines allow a variable profiling rate,
-	// but in practice operating systems cannot trigger signals
-	// at more than about 500 Hz, and our processing of the
-	// signal is not cheap (mostly getting the stack trace).
-	// 100 Hz is a reasonable choice: it is frequent enough to
-	// produce useful data, rare enough not to bog down the
-	// system, and a nice round number to make it easy to
-	// convert sample counts to seconds. Instead of requiring
-	// each client to specify the frequency, we hard code it.
-	const hz = 100
+	return startCPUProfile(w, defaultCPUProfileRate, 0, 0)
+}
+
+// The runtime routines allow a variable profiling rate, but our
+// processing of the signal is not cheap (mostly getting the stack
+// trace). 100 Hz is a reasonable default: it is frequent enough to
+// produce useful data, rare enough not to bog down the system, and a
+// nice round number to make it easy to convert sample counts to
+// seconds.
+const defaultCPUProfileRate = 100
+
+// StartCPUProfileRate is like StartCPUProfile, but samples at hz
+// samples per second of CPU time instead of the default 100. Rates of
+// 1 to 10 kHz resolve short-lived programs and brief latency spikes
+// that 100 Hz sampling misses, at a proportionally higher cost. The
+// time spent collecting the samples is reported in the profile as
+// runtime._ProfilerOverhead.
+//
+// Operating system timers that measure CPU time usually fire no more
+// often than the kernel's scheduler tick, a few hundred times per
+// second. On Linux, rates above 100 Hz therefore sample each thread
+// with a high-resolution cpu-clock perf event where the kernel allows
+// it. Such events only count time spent outside the kernel, so unlike
+// the profile written by StartCPUProfile, which covers both user and
+// system CPU time, the profile then leaves out CPU time spent in system
+// calls and other kernel work done on the program's behalf. On other
+// systems the timer's resolution limits the effective rate.
+func StartCPUProfileRate(w io.Writer, hz int) error {
+	if hz <= 0 || hz > maxCPUProfileRate {
+		return fmt.Errorf("pprof: invalid CPU profile rate %d", hz)
+	}
+	return startCPUProfile(w, hz, 0, 0)
+}
+
+// maxCPUProfileRate is the highest rate StartCPUProfileRate accepts.
+// Beyond it the signal handler would be running most of the time.
+const maxCPUProfileRate = 100000
+
+// A CPUProfileEvent is an event that StartCPUProfileEvent can sample
+// instead of the CPU time sampled by StartCPUProfile.
+type CPUProfileEvent int
+
+const (
+	CPUCycles    CPUProfileEvent = 1 + iota // CPU cycles
+	CacheMisses                             // last level cache misses
+	BranchMisses                            // mispredicted branch instructions
+	CPUClock                                // CPU time, in nanoseconds, from a high-resolution clock
+)
+
+var cpuProfileEventNames = [...]string{
+	CPUCycles:    "cycles",
+	CacheMisses:  "cache-misses",
+	BranchMisses: "branch-misses",
+	CPUClock:     "cpu-clock",
+}
+
+func (e CPUProfileEvent) String() string {
+	if e <= 0 || int(e) >= len(cpuProfileEventNames) {
+		return "CPUProfileEvent(" + strconv.Itoa(int(e)) + ")"
+	}
+	return cpuProfileEventNames[e]
+}
 
+// unit returns the unit in which e is counted.
+func (e CPUProfileEvent) unit() string {
+	if e == CPUClock {
+		return "nanoseconds"
+	}
+	return "count"
+}
+
+// StartCPUProfileEvent is like StartCPUProfile, but each thread is
+// sampled every period occurrences of ev, as counted by the processor's
+// performance counters, rather than at intervals of CPU time measured
+// by operating system timers. The resolution of timers is limited, and
+// they can be skewed under high interrupt load; counting cycles or
+// cache misses instead shows where the hardware actually spends its
+// time. The profile reports the total count of ev for each stack.
+//
+// StartCPUProfileEvent is only supported on Linux, using perf events,
+// and only if the hardware and the kernel allow counting ev: see the
+// perf_event_paranoid setting. It returns an error otherwise.
+func StartCPUProfileEvent(w io.Writer, ev CPUProfileEvent, period int64) error {
+	if ev <= 0 || int(ev) >= len(cpuProfileEventNames) {
+		return fmt.Errorf("pprof: unknown CPU profile event %v", ev)
+	}
+	if period <= 0 {
+		return fmt.Errorf("pprof: invalid CPU profile event period %d", period)
+	}
+	return startCPUProfile(w, defaultCPUProfileRate, ev, period)
+}
+
+// startCPUProfile starts a CPU profile sampling ev every period
+// occurrences, or CPU time hz times per second if ev is 0.
+func startCPUProfile(w io.Writer, hz int, ev CPUProfileEvent, period int64) error {
 	cpu.Lock()
 	defer cpu.Unlock()
 	if cpu.done == nil {
@@ -779,12 +988,25 @@ func StartCPUProfile(w io.Writer) error {
 	if cpu.profiling {
 		return fmt.Errorf("cpu profiling already in use")
 	}
+	if ev != 0 && !setCPUProfileEvent(int32(ev), uint64(period)) {
+		return fmt.Errorf("pprof: CPU profile event %v is not supported", ev)
+	}
 	cpu.profiling = true
 	runtime.SetCPUProfileRate(hz)
-	go profileWriter(w)
+	b := newProfileBuilder(w)
+	if ev != 0 {
+		b.event = ev
+		b.period = period
+	}
+	go profileWriter(b, hz)
 	return nil
 }
 
+// setCPUProfileEvent, provided by the runtime, selects the event that
+// CPU profiling samples instead of CPU time, or CPU time if event is 0,
+// and reports whether the event is supported.
+func setCPUProfileEvent(event int32, period uint64) bool
+
 // readProfile, provided by the runtime, returns the next chunk of
 // binary CPU profiling stack trace data, blocking until data is available.
 // If profiling is turned off and all the profile data accumulated while it was
@@ -792,11 +1014,20 @@ func StartCPUProfile(w io.Writer) error {
 // The caller must save the returned data and tags before calling readProfile again.
 func readProfile() (data []uint64, tags []unsafe.Pointer, eof bool)
 
-func profileWriter(w io.Writer) {
-	b := newProfileBuilder(w)
+func profileWriter(b *profileBuilder, hz int) {
+	// Reading the runtime's fixed-size log every 100ms keeps up with
+	// the default rate. Drain it more often at higher rates so that
+	// samples are not dropped when it fills.
+	interval := 100 * time.Millisecond
+	if hz > defaultCPUProfileRate {
+		interval = interval * defaultCPUProfileRate / time.Duration(hz)
+		if interval < 10*time.Millisecond {
+			interval = 10 * time.Millisecond
+		}
+	}
 	var err error
 	for {
-		time.Sleep(100 * time.Millisecond)
+		time.Sleep(interval)
 		data, tags, eof := readProfile()
 		if e := b.addCPUData(data, tags); e != nil && err == nil {
 			err = e
@@ -816,7 +1047,8 @@ func profileWriter(w io.Writer) {
 
 // StopCPUProfile stops the current CPU profile, if any.
 // StopCPUProfile only returns after all the writes for the
-// profile have completed.
+// profile have completed. If profiling was started by StartWindow,
+// StopCPUProfile first ends the open window, if any, as StopWindow does.
 func StopCPUProfile() {
 	cpu.Lock()
 	defer cpu.Unlock()
@@ -826,7 +1058,12 @@ func StopCPUProfile() {
 	}
 	cpu.profiling = false
 	runtime.SetCPUProfileRate(0)
+	if cpu.windows != nil {
+		stopWindows()
+		return
+	}
 	<-cpu.done
+	setCPUProfileEvent(0, 0)
 }
 
 // countBlock returns the number of records in the blocking profile.
@@ -841,6 +1078,24 @@ func countMutex() int {
 	return n
 }
 
+// countOffCPU returns the number of records in the off-CPU profile.
+func countOffCPU() int {
+	n, _ := runtime.OffCPUProfile(nil)
+	return n
+}
+
+// countMutexStarvation returns the number of records in the mutex starvation profile.
+func countMutexStarvation() int {
+	n, _ := runtime.MutexStarvationProfile(nil)
+	return n
+}
+
+// countGoroutineSample returns the number of records in the goroutine sample profile.
+func countGoroutineSample() int {
+	n, _ := runtime.GoroutineSampleProfile(nil)
+	return n
+}
+
 // writeBlock writes the current blocking profile to w.
 func writeBlock(w io.Writer, debug int) error {
 	var p []runtime.BlockProfileRecord
@@ -942,4 +1197,191 @@ func scaleMutexProfile(cnt int64, ns float64) (int64, float64) {
 	return cnt * int64(period), ns * float64(period)
 }
 
+// writeMutexStarvation writes the current mutex starvation profile to w.
+func writeMutexStarvation(w io.Writer, debug int) error {
+	var p []runtime.MutexStarvationRecord
+	n, ok := runtime.MutexStarvationProfile(nil)
+	for {
+		p = make([]runtime.MutexStarvationRecord, n+50)
+		n, ok = runtime.MutexStarvationProfile(p)
+		if ok {
+			p = p[:n]
+			break
+		}
+	}
+
+	sort.Slice(p, func(i, j int) bool { return p[i].WaitTime > p[j].WaitTime })
+
+	event := func(r *runtime.MutexStarvationRecord) string {
+		if r.Handoff {
+			return "handoff"
+		}
+		return "starve"
+	}
+
+	if debug <= 0 {
+		// Output profile in protobuf form.
+		b := newProfileBuilder(w)
+		b.pbValueType(tagProfile_PeriodType, "events", "count")
+		b.pb.int64Opt(tagProfile_Period, 1)
+		b.pbValueType(tagProfile_SampleType, "events", "count")
+		b.pbValueType(tagProfile_SampleType, "delay", "nanoseconds")
+
+		values := []int64{0, 0}
+		var locs []uint64
+		for i := range p {
+			r := &p[i]
+			count, ns := scaleMutexProfile(r.Count, float64(r.WaitTime))
+			values[0] = count
+			values[1] = int64(ns)
+			locs = b.appendLocsForStack(locs[:0], r.Stack())
+			b.pbSample(values, locs, func() {
+				b.pbLabel(tagSample_Label, "event", event(r), 0)
+			})
+		}
+		b.build()
+		return nil
+	}
+
+	b := bufio.NewWriter(w)
+	tw := tabwriter.NewWriter(w, 1, 8, 1, '\t', 0)
+	w = tw
+
+	fmt.Fprintf(w, "--- starvation:\n")
+	fmt.Fprintf(w, "sampling period=%d\n", runtime.SetMutexProfileFraction(-1))
+	for i := range p {
+		r := &p[i]
+		fmt.Fprintf(w, "%v %v @", r.WaitTime, r.Count)
+		for _, pc := range r.Stack() {
+			fmt.Fprintf(w, " %#x", pc)
+		}
+		fmt.Fprint(w, "\n")
+		fmt.Fprintf(w, "# event: %s\n", event(r))
+		printStackRecord(w, r.Stack(), true)
+	}
+
+	if tw != nil {
+		tw.Flush()
+	}
+	return b.Flush()
+}
+
+// writeGoroutineSample writes the current goroutine sample profile to w.
+func writeGoroutineSample(w io.Writer, debug int) error {
+	var p []runtime.GoroutineSampleRecord
+	n, ok := runtime.GoroutineSampleProfile(nil)
+	for {
+		p = make([]runtime.GoroutineSampleRecord, n+50)
+		n, ok = runtime.GoroutineSampleProfile(p)
+		if ok {
+			p = p[:n]
+			break
+		}
+	}
+
+	sort.Slice(p, func(i, j int) bool { return p[i].Count > p[j].Count })
+
+	if debug <= 0 {
+		// Output profile in protobuf form.
+		b := newProfileBuilder(w)
+		b.pbValueType(tagProfile_PeriodType, "samples", "count")
+		b.pb.int64Opt(tagProfile_Period, 1)
+		b.pbValueType(tagProfile_SampleType, "samples", "count")
+
+		values := []int64{0}
+		var locs []uint64
+		for i := range p {
+			r := &p[i]
+			values[0] = r.Count
+			locs = b.appendLocsForStack(locs[:0], r.Stack())
+			b.pbSample(values, locs, func() {
+				b.pbLabel(tagSample_Label, "state", r.State, 0)
+			})
+		}
+		b.build()
+		return nil
+	}
+
+	b := bufio.NewWriter(w)
+	tw := tabwriter.NewWriter(w, 1, 8, 1, '\t', 0)
+	w = tw
+
+	fmt.Fprintf(w, "--- gsample:\n")
+	for i := range p {
+		r := &p[i]
+		fmt.Fprintf(w, "%v @", r.Count)
+		for _, pc := range r.Stack() {
+			fmt.Fprintf(w, " %#x", pc)
+		}
+		fmt.Fprint(w, "\n")
+		fmt.Fprintf(w, "# state: %s\n", r.State)
+		printStackRecord(w, r.Stack(), true)
+	}
+
+	if tw != nil {
+		tw.Flush()
+	}
+	return b.Flush()
+}
+
+// writeOffCPU writes the current off-CPU profile to w.
+func writeOffCPU(w io.Writer, debug int) error {
+	var p []runtime.OffCPUProfileRecord
+	n, ok := runtime.OffCPUProfile(nil)
+	for {
+		p = make([]runtime.OffCPUProfileRecord, n+50)
+		n, ok = runtime.OffCPUProfile(p)
+		if ok {
+			p = p[:n]
+			break
+		}
+	}
+
+	sort.Slice(p, func(i, j int) bool { return p[i].Duration > p[j].Duration })
+
+	if debug <= 0 {
+		// Output profile in protobuf form.
+		b := newProfileBuilder(w)
+		b.pbValueType(tagProfile_PeriodType, "samples", "count")
+		b.pb.int64Opt(tagProfile_Period, 1)
+		b.pbValueType(tagProfile_SampleType, "samples", "count")
+		b.pbValueType(tagProfile_SampleType, "delay", "nanoseconds")
+
+		values := []int64{0, 0}
+		var locs []uint64
+		for i := range p {
+			r := &p[i]
+			values[0] = r.Count
+			values[1] = r.Duration
+			locs = b.appendLocsForStack(locs[:0], r.Stack())
+			b.pbSample(values, locs, func() {
+				b.pbLabel(tagSample_Label, "reason", r.Reason, 0)
+			})
+		}
+		b.build()
+		return nil
+	}
+
+	b := bufio.NewWriter(w)
+	tw := tabwriter.NewWriter(w, 1, 8, 1, '\t', 0)
+	w = tw
+
+	fmt.Fprintf(w, "--- offcpu:\n")
+	for i := range p {
+		r := &p[i]
+		fmt.Fprintf(w, "%v %v @", r.Duration, r.Count)
+		for _, pc := range r.Stack() {
+			fmt.Fprintf(w, " %#x", pc)
+		}
+		fmt.Fprint(w, "\n")
+		fmt.Fprintf(w, "# reason: %s\n", r.Reason)
+		printStackRecord(w, r.Stack(), true)
+	}
+
+	if tw != nil {
+		tw.Flush()
+	}
+	return b.Flush()
+}
+
 func runtime_cyclesPerSecond() int64
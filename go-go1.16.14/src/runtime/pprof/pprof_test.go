@@ -90,6 +90,90 @@ func TestCPUProfile(t *testing.T) {
 	})
 }
 
+func TestCPUProfileEvent(t *testing.T) {
+	if err := StartCPUProfileEvent(new(bytes.Buffer), 0, 1); err == nil {
+		StopCPUProfile()
+		t.Fatalf("StartCPUProfileEvent accepted an unknown event")
+	}
+
+	var prof bytes.Buffer
+	if err := StartCPUProfileEvent(&prof, CPUClock, 1e6); err != nil {
+		if runtime.GOOS == "linux" {
+			t.Skipf("perf events not available: %v", err)
+		}
+		return
+	}
+	if runtime.GOOS != "linux" {
+		t.Errorf("StartCPUProfileEvent succeeded on %s", runtime.GOOS)
+	}
+	cpuHogger(cpuHog1, &salt1, 200*time.Millisecond)
+	StopCPUProfile()
+
+	p, err := profile.Parse(&prof)
+	if err != nil {
+		t.Fatalf("failed to parse profile: %v", err)
+	}
+	if typ := p.SampleType[1]; typ.Type != "cpu-clock" || typ.Unit != "nanoseconds" {
+		t.Errorf("sample type is %s/%s, want cpu-clock/nanoseconds", typ.Type, typ.Unit)
+	}
+	if p.Period != 1e6 {
+		t.Errorf("period is %d, want %d", p.Period, int64(1e6))
+	}
+	if !containsStack(stacks(p), []string{"runtime/pprof.cpuHog0", "runtime/pprof.cpuHog1"}) &&
+		!containsStack(stacks(p), []string{"runtime/pprof.cpuHog1"}) {
+		t.Errorf("profile has no samples in cpuHog1: %v", stacks(p))
+	}
+
+	// The next CPU profile samples CPU time again.
+	prof.Reset()
+	if err := StartCPUProfile(&prof); err != nil {
+		t.Fatal(err)
+	}
+	StopCPUProfile()
+	p, err = profile.Parse(&prof)
+	if err != nil {
+		t.Fatalf("failed to parse profile: %v", err)
+	}
+	if typ := p.SampleType[1].Type; typ != "cpu" {
+		t.Errorf("sample type after StartCPUProfileEvent is %s, want cpu", typ)
+	}
+}
+
+func TestCPUProfileRate(t *testing.T) {
+	if err := StartCPUProfileRate(new(bytes.Buffer), 0); err == nil {
+		StopCPUProfile()
+		t.Fatalf("StartCPUProfileRate accepted a rate of 0")
+	}
+	if runtime.GOOS != "linux" {
+		t.Skipf("high profiling rates are only accurate on Linux")
+	}
+
+	const hz = 1000
+	var prof bytes.Buffer
+	if err := StartCPUProfileRate(&prof, hz); err != nil {
+		t.Fatal(err)
+	}
+	cpuHogger(cpuHog1, &salt1, 200*time.Millisecond)
+	StopCPUProfile()
+
+	p, err := profile.Parse(&prof)
+	if err != nil {
+		t.Fatalf("failed to parse profile: %v", err)
+	}
+	if want := int64(1e9 / hz); p.Period != want {
+		t.Errorf("period is %d, want %d", p.Period, want)
+	}
+	var samples int64
+	for _, s := range p.Sample {
+		samples += s.Value[0]
+	}
+	// 200ms of CPU time is 200 samples at 1 kHz, but only 20 at the
+	// default rate. Allow for a slow or loaded machine.
+	if samples < 60 {
+		t.Errorf("profile has %d samples, want about %d", samples, 200*hz/1000)
+	}
+}
+
 func TestCPUProfileMultithreaded(t *testing.T) {
 	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(2))
 	testCPUProfile(t, stackContains, []string{"runtime/pprof.cpuHog1", "runtime/pprof.cpuHog2"}, avoidFunctions(), func(dur time.Duration) {
@@ -956,6 +1040,300 @@ func TestMutexProfile(t *testing.T) {
 	})
 }
 
+func TestOffCPUProfile(t *testing.T) {
+	runtime.SetOffCPUProfileRate(1)
+	defer runtime.SetOffCPUProfileRate(0)
+
+	blockChanRecv()
+	offCPUSleep()
+
+	t.Run("debug=1", func(t *testing.T) {
+		var w bytes.Buffer
+		Lookup("offcpu").WriteTo(&w, 1)
+		prof := w.String()
+		t.Logf("received profile: %v", prof)
+
+		if !strings.HasPrefix(prof, "--- offcpu:\n") {
+			t.Errorf("Bad profile header:\n%v", prof)
+		}
+		for _, want := range []string{
+			`(?m)^# reason: chan receive\n#\t0x[[:xdigit:]]+\truntime\.chanrecv\+`,
+			`(?m)^# reason: sleep\n#\t0x[[:xdigit:]]+\ttime\.Sleep\+`,
+		} {
+			if !regexp.MustCompile(want).MatchString(prof) {
+				t.Errorf("profile does not match %q", want)
+			}
+		}
+	})
+	t.Run("proto", func(t *testing.T) {
+		var w bytes.Buffer
+		Lookup("offcpu").WriteTo(&w, 0)
+		p, err := profile.Parse(&w)
+		if err != nil {
+			t.Fatalf("failed to parse profile: %v", err)
+		}
+		if err := p.CheckValid(); err != nil {
+			t.Fatalf("invalid profile: %v", err)
+		}
+
+		for _, want := range []struct {
+			reason string
+			stk    []string
+		}{
+			{"chan receive", []string{"runtime.chanrecv", "runtime.chanrecv1", "runtime/pprof.blockChanRecv"}},
+			{"sleep", []string{"time.Sleep", "runtime/pprof.offCPUSleep"}},
+		} {
+			var stks [][]string
+			for _, s := range p.Sample {
+				if s.Label["reason"][0] != want.reason {
+					continue
+				}
+				if s.Value[1] < int64(blockDelay) {
+					continue
+				}
+				var stk []string
+				for _, l := range s.Location {
+					for _, line := range l.Line {
+						stk = append(stk, line.Function.Name)
+					}
+				}
+				stks = append(stks, stk)
+			}
+			if !containsStack(stks, want.stk) {
+				t.Errorf("No matching %q stack entry for %+v", want.reason, want.stk)
+			}
+		}
+	})
+}
+
+func offCPUSleep() {
+	time.Sleep(blockDelay)
+}
+
+func TestStarvationProfile(t *testing.T) {
+	old := runtime.SetMutexProfileFraction(1)
+	defer runtime.SetMutexProfileFraction(old)
+
+	// Whether a waiter starves depends on scheduling, so retry a few times.
+	for i := 0; i < 10 && !hasStarvationEvents(); i++ {
+		starveMutex()
+	}
+
+	t.Run("debug=1", func(t *testing.T) {
+		var w bytes.Buffer
+		Lookup("starvation").WriteTo(&w, 1)
+		prof := w.String()
+		t.Logf("received profile: %v", prof)
+
+		if !strings.HasPrefix(prof, "--- starvation:\nsampling period=1\n") {
+			t.Errorf("Bad profile header:\n%v", prof)
+		}
+		for _, want := range []string{
+			`(?m)^# event: starve\n#\t0x[[:xdigit:]]+\tsync\.\(\*Mutex\)\.Lock\+`,
+			`(?m)^# event: handoff\n#\t0x[[:xdigit:]]+\tsync\.\(\*Mutex\)\.Unlock\+`,
+		} {
+			if !regexp.MustCompile(want).MatchString(prof) {
+				t.Errorf("profile does not match %q", want)
+			}
+		}
+	})
+	t.Run("proto", func(t *testing.T) {
+		var w bytes.Buffer
+		Lookup("starvation").WriteTo(&w, 0)
+		p, err := profile.Parse(&w)
+		if err != nil {
+			t.Fatalf("failed to parse profile: %v", err)
+		}
+		if err := p.CheckValid(); err != nil {
+			t.Fatalf("invalid profile: %v", err)
+		}
+
+		for _, want := range []struct {
+			event string
+			stk   []string
+		}{
+			{"starve", []string{"sync.(*Mutex).Lock", "runtime/pprof.starveMutex"}},
+			{"handoff", []string{"sync.(*Mutex).Unlock", "runtime/pprof.starveMutex.func1"}},
+		} {
+			var stks [][]string
+			for _, s := range p.Sample {
+				if s.Label["event"][0] != want.event {
+					continue
+				}
+				var stk []string
+				for _, l := range s.Location {
+					for _, line := range l.Line {
+						stk = append(stk, line.Function.Name)
+					}
+				}
+				stks = append(stks, stk)
+			}
+			if !containsStack(stks, want.stk) {
+				t.Errorf("No matching %q stack entry for %+v", want.event, want.stk)
+			}
+		}
+	})
+}
+
+// starveMutex waits for a mutex that another goroutine keeps
+// relocking, so that it switches the mutex to starvation mode and
+// the other goroutine hands the mutex off to it.
+func starveMutex() {
+	var mu sync.Mutex
+	stop := make(chan bool)
+	done := make(chan bool)
+	mu.Lock()
+	go func() {
+		defer close(done)
+		for {
+			time.Sleep(2 * time.Millisecond)
+			mu.Unlock()
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			mu.Lock()
+		}
+	}()
+	mu.Lock()
+	close(stop)
+	mu.Unlock()
+	<-done
+}
+
+// hasStarvationEvents reports whether the mutex starvation profile
+// has both starvation and handoff events.
+func hasStarvationEvents() bool {
+	p := make([]runtime.MutexStarvationRecord, 100)
+	n, _ := runtime.MutexStarvationProfile(p)
+	var starve, handoff bool
+	for _, r := range p[:n] {
+		if r.Handoff {
+			handoff = true
+		} else {
+			starve = true
+		}
+	}
+	return starve && handoff
+}
+
+func TestGoroutineSampleProfile(t *testing.T) {
+	c := make(chan int)
+	stop := make(chan bool)
+	done := make(chan bool)
+	go gsampleBlocked(c)
+	go gsampleSpin(stop, done)
+	defer func() {
+		close(stop)
+		<-done
+		c <- 1
+	}()
+
+	runtime.SetGoroutineSampleRate(1000)
+	defer runtime.SetGoroutineSampleRate(0)
+	for i := 0; i < 100 && !hasGoroutineSamples(); i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	t.Run("debug=1", func(t *testing.T) {
+		var w bytes.Buffer
+		Lookup("gsample").WriteTo(&w, 1)
+		prof := w.String()
+
+		if !strings.HasPrefix(prof, "--- gsample:\n") {
+			t.Errorf("Bad profile header:\n%v", prof)
+		}
+		want := `(?m)^# state: chan receive\n#\t0x[[:xdigit:]]+\truntime\.gopark\+`
+		if !regexp.MustCompile(want).MatchString(prof) {
+			t.Errorf("profile does not match %q:\n%v", want, prof)
+		}
+	})
+	t.Run("proto", func(t *testing.T) {
+		var w bytes.Buffer
+		Lookup("gsample").WriteTo(&w, 0)
+		p, err := profile.Parse(&w)
+		if err != nil {
+			t.Fatalf("failed to parse profile: %v", err)
+		}
+		if err := p.CheckValid(); err != nil {
+			t.Fatalf("invalid profile: %v", err)
+		}
+
+		// The stacks of running goroutines may end in the runtime's
+		// preemption functions, so look for the test functions
+		// anywhere in them.
+		for _, want := range []struct {
+			states []string
+			fn     string
+		}{
+			{[]string{"chan receive"}, "runtime/pprof.gsampleBlocked"},
+			{[]string{"running", "runnable"}, "runtime/pprof.gsampleSpin"},
+		} {
+			found := false
+			for _, s := range p.Sample {
+				state := s.Label["state"][0]
+				if state != want.states[0] && state != want.states[len(want.states)-1] {
+					continue
+				}
+				for _, l := range s.Location {
+					for _, line := range l.Line {
+						if line.Function.Name == want.fn {
+							found = true
+						}
+					}
+				}
+			}
+			if !found {
+				t.Errorf("No %s sample in state %q", want.fn, want.states)
+			}
+		}
+	})
+}
+
+//go:noinline
+func gsampleBlocked(c chan int) { <-c }
+
+//go:noinline
+func gsampleSpin(stop, done chan bool) {
+	defer close(done)
+	x := 0
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		x = cpuHog0(x, 1e5)
+	}
+}
+
+// hasGoroutineSamples reports whether the goroutine sample profile has
+// samples of both gsampleBlocked and gsampleSpin.
+func hasGoroutineSamples() bool {
+	p := make([]runtime.GoroutineSampleRecord, 1000)
+	n, ok := runtime.GoroutineSampleProfile(p)
+	if !ok {
+		return false
+	}
+	var blocked, spin bool
+	for _, r := range p[:n] {
+		for _, pc := range r.Stack() {
+			f := runtime.FuncForPC(pc - 1)
+			if f == nil {
+				continue
+			}
+			switch f.Name() {
+			case "runtime/pprof.gsampleBlocked":
+				blocked = true
+			case "runtime/pprof.gsampleSpin":
+				spin = true
+			}
+		}
+	}
+	return blocked && spin
+}
+
 func func1(c chan int) { <-c }
 func func2(c chan int) { <-c }
 func func3(c chan int) { <-c }
@@ -1103,6 +1481,155 @@ func containsCountsLabels(prof *profile.Profile, countLabels map[int64]map[strin
 	return true
 }
 
+func goroutineProfileParked(c chan int) {
+	<-c
+}
+
+func TestGoroutineProfileName(t *testing.T) {
+	c := make(chan int)
+	ready := make(chan bool)
+	go func() {
+		runtime.SetGoroutineName("pprof-test")
+		ready <- true
+		<-c
+	}()
+	<-ready
+	defer close(c)
+
+	var w bytes.Buffer
+	Lookup("goroutine").WriteTo(&w, 1)
+	labels := labelMap{"goroutine.name": "pprof-test"}
+	if prof := w.String(); !strings.Contains(prof, "\n# labels: "+labels.String()) {
+		t.Errorf("goroutine profile does not label the named goroutine:\n%s", prof)
+	}
+}
+
+func TestGoroutineProfileBlocked(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping in short mode: waits for a goroutine to block for a second")
+	}
+	c := make(chan int)
+	ready := make(chan bool)
+	go func() {
+		runtime.SetGoroutineName("pprof-blocked")
+		ready <- true
+		<-c
+	}()
+	<-ready
+	defer close(c)
+
+	// Without GODEBUG=waitsince=1, the runtime learns when the
+	// goroutine blocked from a garbage collection, which records the
+	// end of the one before it.
+	time.Sleep(10 * time.Millisecond)
+	runtime.GC()
+	runtime.GC()
+	time.Sleep(1100 * time.Millisecond)
+
+	var w bytes.Buffer
+	Lookup("goroutine").WriteTo(&w, 1)
+	labels := labelMap{"goroutine.name": "pprof-blocked", "goroutine.blocked": "1s"}
+	if prof := w.String(); !strings.Contains(prof, "\n# labels: "+labels.String()) {
+		t.Errorf("goroutine profile does not label the blocked goroutine:\n%s", prof)
+	}
+}
+
+func TestGoroutineBlocked(t *testing.T) {
+	for _, tt := range []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, ""},
+		{999 * time.Millisecond, ""},
+		{time.Second, "1s"},
+		{59 * time.Second, "10s"},
+		{time.Minute, "1m"},
+		{30 * time.Minute, "10m"},
+		{48 * time.Hour, "1h"},
+	} {
+		if got := goroutineBlocked(tt.d); got != tt.want {
+			t.Errorf("goroutineBlocked(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+// TestGoroutineProfileConcurrency checks that goroutine profiles taken
+// while goroutines start, exit and run stay consistent.
+func TestGoroutineProfileConcurrency(t *testing.T) {
+	const parked = 100
+	c := make(chan int)
+	for i := 0; i < parked; i++ {
+		go goroutineProfileParked(c)
+	}
+	defer close(c)
+
+	done := make(chan bool)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		// Churn short-lived goroutines.
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				var inner sync.WaitGroup
+				inner.Add(1)
+				go inner.Done()
+				inner.Wait()
+			}
+		}()
+		// Keep a goroutine running so it has to be preempted.
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+			}
+		}()
+	}
+	defer func() {
+		close(done)
+		wg.Wait()
+	}()
+
+	countParked := func() int {
+		p := make([]runtime.StackRecord, 1)
+		n, ok := runtime.GoroutineProfile(p)
+		for !ok {
+			p = make([]runtime.StackRecord, n+10)
+			n, ok = runtime.GoroutineProfile(p)
+		}
+		count := 0
+		for _, r := range p[:n] {
+			for _, pc := range r.Stack() {
+				if f := runtime.FuncForPC(pc); f != nil && f.Name() == "runtime/pprof.goroutineProfileParked" {
+					count++
+					break
+				}
+			}
+		}
+		return count
+	}
+
+	// Let the parked goroutines reach their blocking point.
+	deadline := time.Now().Add(10 * time.Second)
+	for countParked() != parked && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	for i := 0; i < 50; i++ {
+		if got := countParked(); got != parked {
+			t.Fatalf("goroutine profile has %d parked goroutines, want %d", got, parked)
+		}
+	}
+}
+
 var emptyCallStackTestRun int64
 
 // Issue 18836.
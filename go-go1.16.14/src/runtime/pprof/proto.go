@@ -35,6 +35,10 @@ type profileBuilder struct {
 	period     int64
 	m          profMap
 
+	// event is the event sampled by a CPU profile, if not CPU time.
+	// period is then the number of events per sample.
+	event CPUProfileEvent
+
 	// encoding state
 	w         io.Writer
 	zw        *gzip.Writer
@@ -282,7 +286,9 @@ func (b *profileBuilder) addCPUData(data []uint64, tags []unsafe.Pointer) error
 		}
 		// data[2] is sampling rate in Hz. Convert to sampling
 		// period in nanoseconds.
-		b.period = 1e9 / int64(data[2])
+		if b.event == 0 {
+			b.period = 1e9 / int64(data[2])
+		}
 		b.havePeriod = true
 		data = data[3:]
 	}
@@ -339,10 +345,14 @@ func (b *profileBuilder) build() {
 
 	b.pb.int64Opt(tagProfile_TimeNanos, b.start.UnixNano())
 	if b.havePeriod { // must be CPU profile
+		typ, unit := "cpu", "nanoseconds"
+		if b.event != 0 {
+			typ, unit = b.event.String(), b.event.unit()
+		}
 		b.pbValueType(tagProfile_SampleType, "samples", "count")
-		b.pbValueType(tagProfile_SampleType, "cpu", "nanoseconds")
+		b.pbValueType(tagProfile_SampleType, typ, unit)
 		b.pb.int64Opt(tagProfile_DurationNanos, b.end.Sub(b.start).Nanoseconds())
-		b.pbValueType(tagProfile_PeriodType, "cpu", "nanoseconds")
+		b.pbValueType(tagProfile_PeriodType, typ, unit)
 		b.pb.int64Opt(tagProfile_Period, b.period)
 	}
 
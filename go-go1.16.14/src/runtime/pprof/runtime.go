@@ -6,6 +6,7 @@ package pprof
 
 import (
 	"context"
+	"runtime"
 	"unsafe"
 )
 
@@ -18,6 +19,12 @@ func runtime_setProfLabel(labels unsafe.Pointer)
 // runtime_getProfLabel is defined in runtime/proflabel.go.
 func runtime_getProfLabel() unsafe.Pointer
 
+// runtime_setProfScope is defined in runtime/profscope.go.
+func runtime_setProfScope(on bool)
+
+// runtime_scopedAllocProfile is defined in runtime/profscope.go.
+func runtime_scopedAllocProfile(p []runtime.MemProfileRecord) (n int, ok bool)
+
 // SetGoroutineLabels sets the current goroutine's labels to match ctx.
 // A new goroutine inherits the labels of the goroutine that created it.
 // This is a lower-level API than Do, which should be used instead when possible.
@@ -0,0 +1,103 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pprof
+
+import (
+	"errors"
+	"io"
+	"runtime"
+	"sync"
+)
+
+var scope struct {
+	sync.Mutex
+	active bool
+}
+
+// DoScoped calls f with profiling scoped to the calling goroutine and
+// the goroutines it starts, directly or indirectly, while f runs. This
+// makes it possible to profile a single suspect request or operation
+// in detail without measurably slowing down the rest of the process.
+//
+// If cpu is not nil, a CPU profile is written to it as by
+// StartCPUProfile, except that it only holds samples of goroutines
+// in the scope. DoScoped returns an error if CPU profiling is already
+// enabled.
+//
+// If allocs is not nil, the allocations made in the scope are sampled
+// at runtime.MemProfileRate, like those in the allocs profile, and
+// written to allocs as an allocation profile once f returns. The heap
+// and allocs profiles are not affected by the scope.
+//
+// Only one scope can be active at a time. DoScoped returns an error
+// if another call to DoScoped is in progress.
+func DoScoped(cpu, allocs io.Writer, f func()) error {
+	scope.Lock()
+	if scope.active {
+		scope.Unlock()
+		return errors.New("pprof: profiling scope already in use")
+	}
+	scope.active = true
+	scope.Unlock()
+	defer func() {
+		scope.Lock()
+		scope.active = false
+		scope.Unlock()
+	}()
+
+	// Start the CPU profile before entering the scope so that its
+	// writer goroutine does not inherit the scope.
+	if cpu != nil {
+		if err := StartCPUProfile(cpu); err != nil {
+			return err
+		}
+	}
+	func() {
+		runtime_setProfScope(true)
+		defer runtime_setProfScope(false)
+		if cpu != nil {
+			defer StopCPUProfile()
+		}
+		f()
+	}()
+
+	if allocs == nil {
+		return nil
+	}
+	return writeScopedAllocs(allocs)
+}
+
+// writeScopedAllocs writes the allocation profile of the last scope to w.
+func writeScopedAllocs(w io.Writer) error {
+	var p []runtime.MemProfileRecord
+	n, ok := runtime_scopedAllocProfile(nil)
+	for {
+		p = make([]runtime.MemProfileRecord, n+50)
+		n, ok = runtime_scopedAllocProfile(p)
+		if ok {
+			p = p[:n]
+			break
+		}
+	}
+
+	rate := int64(runtime.MemProfileRate)
+	b := newProfileBuilder(w)
+	b.pbValueType(tagProfile_PeriodType, "space", "bytes")
+	b.pb.int64Opt(tagProfile_Period, rate)
+	b.pbValueType(tagProfile_SampleType, "alloc_objects", "count")
+	b.pbValueType(tagProfile_SampleType, "alloc_space", "bytes")
+
+	values := []int64{0, 0}
+	var locs []uint64
+	for _, r := range p {
+		values[0], values[1] = scaleHeapSample(r.AllocObjects, r.AllocBytes, rate)
+		// As in the heap profile, all stack addresses are
+		// return PCs, which is what appendLocsForStack expects.
+		locs = b.appendLocsForStack(locs[:0], r.Stack())
+		b.pbSample(values, locs, nil)
+	}
+	b.build()
+	return nil
+}
@@ -0,0 +1,120 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !js
+
+package pprof
+
+import (
+	"bytes"
+	"internal/profile"
+	"runtime"
+	"testing"
+	"time"
+)
+
+var scopeSink []byte
+
+//go:noinline
+func scopedAlloc() {
+	for i := 0; i < 100; i++ {
+		scopeSink = make([]byte, 64)
+	}
+}
+
+//go:noinline
+func unscopedAlloc() {
+	for i := 0; i < 100; i++ {
+		scopeSink = make([]byte, 64)
+	}
+}
+
+func scopedHog(x int) int   { return cpuHog0(x, 1e5) }
+func unscopedHog(x int) int { return cpuHog0(x, 1e5) }
+
+// scopeFuncs returns the names of the functions in the samples of p
+// whose value at index i is non-zero.
+func scopeFuncs(p *profile.Profile, i int) map[string]int64 {
+	funcs := make(map[string]int64)
+	for _, s := range p.Sample {
+		for _, loc := range s.Location {
+			for _, line := range loc.Line {
+				funcs[line.Function.Name] += s.Value[i]
+			}
+		}
+	}
+	return funcs
+}
+
+func TestDoScoped(t *testing.T) {
+	if runtime.GOOS == "plan9" {
+		t.Skip("skipping on plan9")
+	}
+	// Sample every allocation, so that the counts are exact.
+	defer func(old int) { runtime.MemProfileRate = old }(runtime.MemProfileRate)
+	runtime.MemProfileRate = 1
+
+	stop := make(chan bool)
+	done := make(chan bool)
+	go func() {
+		defer close(done)
+		y := 0
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			unscopedHog(y)
+			unscopedAlloc()
+		}
+	}()
+
+	var cpu, allocs bytes.Buffer
+	err := DoScoped(&cpu, &allocs, func() {
+		if err := DoScoped(nil, nil, func() {}); err == nil {
+			t.Errorf("nested DoScoped succeeded")
+		}
+		c := make(chan bool)
+		go func() {
+			scopedAlloc()
+			y := 0
+			cpuHogger(scopedHog, &y, 200*time.Millisecond)
+			c <- true
+		}()
+		<-c
+	})
+	close(stop)
+	<-done
+	if err != nil {
+		t.Fatalf("DoScoped: %v", err)
+	}
+
+	p, err := profile.Parse(&allocs)
+	if err != nil {
+		t.Fatalf("failed to parse allocation profile: %v", err)
+	}
+	if err := p.CheckValid(); err != nil {
+		t.Fatalf("invalid allocation profile: %v", err)
+	}
+	funcs := scopeFuncs(p, 0)
+	if n := funcs["runtime/pprof.scopedAlloc"]; n != 100 {
+		t.Errorf("scoped allocation profile has %d allocations in scopedAlloc, want 100", n)
+	}
+	if n := funcs["runtime/pprof.unscopedAlloc"]; n != 0 {
+		t.Errorf("scoped allocation profile has %d allocations in unscopedAlloc, want 0", n)
+	}
+
+	p, err = profile.Parse(&cpu)
+	if err != nil {
+		t.Fatalf("failed to parse CPU profile: %v", err)
+	}
+	funcs = scopeFuncs(p, 0)
+	if funcs["runtime/pprof.scopedHog"] == 0 {
+		t.Errorf("scoped CPU profile has no samples in scopedHog")
+	}
+	if n := funcs["runtime/pprof.unscopedHog"]; n != 0 {
+		t.Errorf("scoped CPU profile has %d samples in unscopedHog, want 0", n)
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pprof
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"time"
+	"unsafe"
+)
+
+// A profWindow is a profiling window begun by StartWindow.
+type profWindow struct {
+	cpu, heap io.Writer
+}
+
+// heapBase is the heap profile when the last window with a heap
+// writer ended, or began if there was none. It is protected by cpu.
+var heapBase []runtime.MemProfileRecord
+
+// StartWindow begins a profiling window. Always-on agents that sample
+// a process continuously, say in 10-second windows, call StartWindow
+// and StopWindow in turn rather than starting and stopping the CPU
+// profiler, which loses the samples taken in between and perturbs the
+// sampling rate at each rotation.
+//
+// The first call to StartWindow starts CPU profiling at the default
+// rate, and it keeps running until StopCPUProfile is called. Each
+// window holds the samples taken since the previous window ended, so
+// that back-to-back windows lose none. StartWindow returns an error if
+// a window is already open, or if CPU profiling was started by
+// StartCPUProfile.
+//
+// When the window ends, its CPU profile is written to cpu and, if heap
+// is not nil, its allocation profile to heap. The allocation profile
+// holds the difference of the heap profile between the start and the
+// end of the window, so like the heap profile it is only as current
+// as the most recently completed garbage collection.
+func StartWindow(cpuw, heapw io.Writer) error {
+	cpu.Lock()
+	defer cpu.Unlock()
+	if cpu.window != nil {
+		return errors.New("pprof: profiling window already started")
+	}
+	if cpu.windows == nil {
+		if cpu.profiling {
+			return fmt.Errorf("cpu profiling already in use")
+		}
+		cpu.profiling = true
+		cpu.rotate = make(chan bool)
+		cpu.windows = make(chan *profileBuilder)
+		runtime.SetCPUProfileRate(defaultCPUProfileRate)
+		go windowWriter(newProfileBuilder(nil), cpu.rotate, cpu.windows)
+	}
+	if heapw != nil && heapBase == nil {
+		heapBase = memProfileRecords()
+	}
+	cpu.window = &profWindow{cpu: cpuw, heap: heapw}
+	return nil
+}
+
+// StopWindow ends the profiling window begun by StartWindow and writes
+// its profiles. CPU profiling continues, and the samples it takes go
+// to the next window. StopWindow returns an error if no window is
+// open.
+func StopWindow() error {
+	cpu.Lock()
+	defer cpu.Unlock()
+	if cpu.window == nil {
+		return errors.New("pprof: no profiling window started")
+	}
+	cpu.rotate <- true
+	return endWindow(<-cpu.windows)
+}
+
+// stopWindows finishes windowed CPU profiling once StopCPUProfile has
+// turned the profiler off. The caller must hold cpu.
+func stopWindows() {
+	b := <-cpu.windows
+	if cpu.window != nil {
+		endWindow(b)
+	}
+	cpu.rotate = nil
+	cpu.windows = nil
+	heapBase = nil
+}
+
+// endWindow writes the profiles of the open window, whose CPU profile
+// data b holds, and closes it. The caller must hold cpu.
+func endWindow(b *profileBuilder) error {
+	w := cpu.window
+	cpu.window = nil
+	b.zw.Reset(w.cpu)
+	b.build()
+	if w.heap == nil {
+		heapBase = nil
+		return nil
+	}
+	p := memProfileRecords()
+	d := memProfileDelta(p, heapBase)
+	heapBase = p
+	return writeHeapProto(w.heap, d, int64(runtime.MemProfileRate), "alloc_space")
+}
+
+// readProfileNonblocking, provided by the runtime, is like readProfile,
+// but returns no data rather than blocking when none is available.
+func readProfileNonblocking() (data []uint64, tags []unsafe.Pointer, eof bool)
+
+// windowWriter collects CPU profile data into b like profileWriter,
+// but each time it receives from rotate, it sends b, holding the
+// data of the window just ended, to windows and starts a new builder.
+// Once profiling is turned off, it sends its last builder to windows.
+func windowWriter(b *profileBuilder, rotate <-chan bool, windows chan<- *profileBuilder) {
+	var err error
+	drain := func() (eof bool) {
+		for {
+			data, tags, eof := readProfileNonblocking()
+			if len(data) > 0 {
+				if e := b.addCPUData(data, tags); e != nil && err == nil {
+					err = e
+				}
+			}
+			if eof || len(data) == 0 {
+				return eof
+			}
+		}
+	}
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if !drain() {
+				continue
+			}
+			if err != nil {
+				panic("runtime/pprof: converting profile: " + err.Error())
+			}
+			windows <- b
+			return
+		case <-rotate:
+			drain()
+			next := newProfileBuilder(nil)
+			next.havePeriod = b.havePeriod
+			next.period = b.period
+			windows <- b
+			b = next
+		}
+	}
+}
+
+// memProfileRecords returns the current heap profile records,
+// including those of stacks with no live objects.
+func memProfileRecords() []runtime.MemProfileRecord {
+	var p []runtime.MemProfileRecord
+	n, ok := runtime.MemProfile(nil, true)
+	for {
+		p = make([]runtime.MemProfileRecord, n+50)
+		n, ok = runtime.MemProfile(p, true)
+		if ok {
+			return p[:n]
+		}
+	}
+}
+
+// memProfileDelta returns the allocations and frees recorded by the
+// heap profile p since the heap profile base.
+func memProfileDelta(p, base []runtime.MemProfileRecord) []runtime.MemProfileRecord {
+	prev := make(map[[32]uintptr]*runtime.MemProfileRecord, len(base))
+	for i := range base {
+		prev[base[i].Stack0] = &base[i]
+	}
+	var d []runtime.MemProfileRecord
+	for _, r := range p {
+		if b := prev[r.Stack0]; b != nil {
+			r.AllocBytes -= b.AllocBytes
+			r.AllocObjects -= b.AllocObjects
+			r.FreeBytes -= b.FreeBytes
+			r.FreeObjects -= b.FreeObjects
+		}
+		if r.AllocObjects == 0 && r.FreeObjects == 0 {
+			continue
+		}
+		d = append(d, r)
+	}
+	return d
+}
@@ -0,0 +1,101 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !js
+
+package pprof
+
+import (
+	"bytes"
+	"internal/profile"
+	"runtime"
+	"testing"
+	"time"
+)
+
+var windowSink []byte
+
+//go:noinline
+func windowAlloc() {
+	for i := 0; i < 100; i++ {
+		windowSink = make([]byte, 64)
+	}
+}
+
+func windowHog(x int) int { return cpuHog0(x, 1e5) }
+
+func TestProfileWindow(t *testing.T) {
+	if runtime.GOOS == "plan9" {
+		t.Skip("skipping on plan9")
+	}
+	defer runtime.GC()
+	defer func(old int) { runtime.MemProfileRate = old }(runtime.MemProfileRate)
+	runtime.MemProfileRate = 1
+
+	var cpu1, heap1, cpu2 bytes.Buffer
+	if err := StartWindow(&cpu1, &heap1); err != nil {
+		t.Fatalf("StartWindow: %v", err)
+	}
+	if err := StartWindow(&cpu2, nil); err == nil {
+		t.Errorf("StartWindow succeeded with a window open")
+	}
+	if err := StartCPUProfile(&cpu2); err == nil {
+		StopCPUProfile()
+		t.Errorf("StartCPUProfile succeeded while profiling windows")
+	}
+	y := 0
+	windowAlloc()
+	cpuHogger(windowHog, &y, 200*time.Millisecond)
+	runtime.GC()
+	if err := StopWindow(); err != nil {
+		t.Fatalf("StopWindow: %v", err)
+	}
+	if err := StopWindow(); err == nil {
+		t.Errorf("StopWindow succeeded with no window open")
+	}
+
+	// The second window covers the time since the first ended.
+	time.Sleep(10 * time.Millisecond)
+	if err := StartWindow(&cpu2, nil); err != nil {
+		t.Fatalf("second StartWindow: %v", err)
+	}
+	windowAlloc()
+	cpuHogger(windowHog, &y, 200*time.Millisecond)
+	StopCPUProfile()
+	if err := StopWindow(); err == nil {
+		t.Errorf("StopWindow succeeded after StopCPUProfile")
+	}
+
+	var durations []int64
+	for i, buf := range []*bytes.Buffer{&cpu1, &cpu2} {
+		p, err := profile.Parse(buf)
+		if err != nil {
+			t.Fatalf("failed to parse CPU profile of window %d: %v", i+1, err)
+		}
+		if err := p.CheckValid(); err != nil {
+			t.Fatalf("invalid CPU profile of window %d: %v", i+1, err)
+		}
+		if want := int64(1e9 / defaultCPUProfileRate); p.Period != want {
+			t.Errorf("window %d: period %d, want %d", i+1, p.Period, want)
+		}
+		if scopeFuncs(p, 0)["runtime/pprof.windowHog"] == 0 {
+			t.Errorf("CPU profile of window %d has no samples in windowHog", i+1)
+		}
+		durations = append(durations, p.DurationNanos)
+	}
+	if durations[1] < int64(210*time.Millisecond) {
+		t.Errorf("second window lasted %v, want at least 210ms", time.Duration(durations[1]))
+	}
+
+	p, err := profile.Parse(&heap1)
+	if err != nil {
+		t.Fatalf("failed to parse heap profile: %v", err)
+	}
+	if err := p.CheckValid(); err != nil {
+		t.Fatalf("invalid heap profile: %v", err)
+	}
+	if n := scopeFuncs(p, 0)["runtime/pprof.windowAlloc"]; n != 100 {
+		t.Errorf("heap profile of window has %d allocations in windowAlloc, want 100", n)
+	}
+}
@@ -310,10 +310,51 @@ func asyncPreempt2() {
 	gp.asyncSafePoint = false
 }
 
+// Safepoint lets the scheduler preempt the calling goroutine if it has
+// been asked to stop, and otherwise returns at once. Go code is
+// usually stopped anywhere by asynchronous preemption, but assembly
+// functions, and all code under GODEBUG=asyncpreemptoff=1, can only
+// be stopped at function calls, so a long loop without calls delays
+// garbage collection and anything else that stops the world until it
+// ends. Such loops should call Safepoint every few microseconds of
+// work. Assembly functions call it like any Go function, and so need
+// a frame and NO_LOCAL_POINTERS.
+//
+// runtime/debug.ReadSTWDelay reports the goroutine that last delayed
+// stopping the world, and where it stopped, to find such loops.
+func Safepoint() {
+	gp := getg()
+	if !gp.preempt || gp != gp.m.curg || !canPreemptM(gp.m) {
+		return
+	}
+	if gp.preemptStop {
+		mcall(preemptPark)
+	} else {
+		mcall(gopreempt_m)
+	}
+}
+
 // asyncPreemptStack is the bytes of stack space required to inject an
 // asyncPreempt call.
 var asyncPreemptStack = ^uintptr(0)
 
+// asyncPreemptStats counts asynchronous preemption requests, for
+// runtime/metrics. All fields are accessed atomically.
+var asyncPreemptStats struct {
+	// requests counts calls to preemptM.
+	requests uint64
+
+	// injected counts requests that found the goroutine at an
+	// async safe-point and injected a call to asyncPreempt.
+	injected uint64
+
+	// dropped counts requests that never reached the thread: a
+	// preemption signal was already pending or, on Windows, the
+	// thread was running external code, exiting, or could not be
+	// suspended.
+	dropped uint64
+}
+
 func init() {
 	f := findfunc(funcPC(asyncPreempt))
 	total := funcMaxSPDelta(f)
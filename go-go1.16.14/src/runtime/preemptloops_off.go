@@ -0,0 +1,13 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !goexperiment.preemptibleloops
+
+package runtime
+
+// loopPreemptChecks reports whether the compiler inserted preemption
+// checks on loop backedges, which call goschedguarded. That is the
+// case with GOEXPERIMENT=preemptibleloops and on targets without
+// asynchronous preemption.
+const loopPreemptChecks = false
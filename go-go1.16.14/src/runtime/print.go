@@ -99,8 +99,29 @@ func gwrite(b []byte) {
 		return
 	}
 
-	n := copy(gp.writebuf[len(gp.writebuf):cap(gp.writebuf)], b)
-	gp.writebuf = gp.writebuf[:len(gp.writebuf)+n]
+	for {
+		n := copy(gp.writebuf[len(gp.writebuf):cap(gp.writebuf)], b)
+		gp.writebuf = gp.writebuf[:len(gp.writebuf)+n]
+		b = b[n:]
+		if len(b) == 0 || !gp.m.flushwrite {
+			return
+		}
+		flushwritebuf(gp)
+	}
+}
+
+// flushwritebuf writes the contents of gp.writebuf to gp.m.writefd
+// and empties it.
+func flushwritebuf(gp *g) {
+	p := gp.writebuf
+	for len(p) > 0 {
+		n := write(gp.m.writefd, unsafe.Pointer(&p[0]), int32(len(p)))
+		if n <= 0 {
+			break
+		}
+		p = p[n:]
+	}
+	gp.writebuf = gp.writebuf[:0]
 }
 
 func printsp() {
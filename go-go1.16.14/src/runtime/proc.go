@@ -293,6 +293,18 @@ func Gosched() {
 	mcall(gosched_m)
 }
 
+// GoschedLocal yields the processor like Gosched, but puts the calling
+// goroutine at the back of the run queue of its current processor
+// rather than of the global run queue. It is then likely to resume on
+// the same processor, once the goroutines queued there have run, so a
+// goroutine that waits for another by looping on GoschedLocal keeps
+// its data in the same CPU cache. Gosched instead lets any processor
+// resume it, which spreads the load but moves it between CPUs.
+func GoschedLocal() {
+	checkTimeouts()
+	mcall(goschedlocal_m)
+}
+
 // goschedguarded yields the processor like gosched, but also checks
 // for forbidden states and opts out of the yield in those cases.
 //go:nosplit
@@ -334,8 +346,20 @@ func gopark(unlockf func(*g, unsafe.Pointer) bool, lock unsafe.Pointer, reason w
 	mp.waittraceev = traceEv
 	mp.waittraceskip = traceskip
 	releasem(mp)
+	var t0 int64
+	if atomic.Load64(&offcpuprofilerate) != 0 || debug.waitsince != 0 {
+		t0 = nanotime()
+	}
+	if debug.waitsince != 0 {
+		// execute clears waitsince when gp runs again, including
+		// when park_m finds it cannot park after all.
+		gp.waitsince = t0
+	}
 	// can't do anything that might move the G between Ms here.
 	mcall(park_m) // 注释：保存现场，并且变更G的状态	casgstatus(gp, _Grunning, _Gwaiting)
+	if t0 != 0 && atomic.Load64(&offcpuprofilerate) != 0 {
+		offcpuevent(nanotime()-t0, reason, 2)
+	}
 }
 
 // Puts the current goroutine into a waiting state and unlocks the lock.
@@ -363,15 +387,8 @@ func acquireSudog() *sudog {
 	mp := acquirem()
 	pp := mp.p.ptr()
 	if len(pp.sudogcache) == 0 {
-		lock(&sched.sudoglock)
 		// First, try to grab a batch from central cache.
-		for len(pp.sudogcache) < cap(pp.sudogcache)/2 && sched.sudogcache != nil {
-			s := sched.sudogcache
-			sched.sudogcache = s.next
-			s.next = nil
-			pp.sudogcache = append(pp.sudogcache, s)
-		}
-		unlock(&sched.sudoglock)
+		sudogRefill(pp)
 		// If the central cache is empty, allocate a new one.
 		if len(pp.sudogcache) == 0 {
 			pp.sudogcache = append(pp.sudogcache, new(sudog))
@@ -417,6 +434,7 @@ func releaseSudog(s *sudog) {
 	if len(pp.sudogcache) == cap(pp.sudogcache) {
 		// Transfer half of local cache to the central cache.
 		var first, last *sudog
+		moved := len(pp.sudogcache) - cap(pp.sudogcache)/2
 		for len(pp.sudogcache) > cap(pp.sudogcache)/2 {
 			n := len(pp.sudogcache)
 			p := pp.sudogcache[n-1]
@@ -429,10 +447,7 @@ func releaseSudog(s *sudog) {
 			}
 			last = p
 		}
-		lock(&sched.sudoglock)
-		last.next = sched.sudogcache
-		sched.sudogcache = first
-		unlock(&sched.sudoglock)
+		sudogSpill(pp, first, last, moved)
 	}
 	pp.sudogcache = append(pp.sudogcache, s)
 	releasem(mp)
@@ -492,8 +507,8 @@ func lockedOSThread() bool {
 }
 
 var (
-	// allgs contains all Gs ever created (including dead Gs), and thus
-	// never shrinks.
+	// allgs contains all Gs ever created (including dead Gs), except
+	// for the dead Gs retired by the GC; see gfreecache.go.
 	//
 	// Access via the slice is protected by allglock or stop-the-world.
 	// Readers that cannot take the lock may (carefully!) use the atomic
@@ -510,6 +525,10 @@ var (
 	// Gs appended during the race can be missed. For a consistent view of
 	// all Gs, allglock must be held.
 	//
+	// releaseRetiredGs shrinks allgs in place: it clears the entries past
+	// the new length and lowers only allglen. A reader that loaded the
+	// previous allglen must therefore skip nil entries.
+	//
 	// allgptr copies should always be stored as a concrete type or
 	// unsafe.Pointer, not uintptr, to ensure that GC can still reach it
 	// even if it points to a stale array.
@@ -604,7 +623,7 @@ func schedinit() {
 	lockInit(&sched.lock, lockRankSched)
 	lockInit(&sched.sysmonlock, lockRankSysmon)
 	lockInit(&sched.deferlock, lockRankDefer)
-	lockInit(&sched.sudoglock, lockRankSudog)
+	sudogcacheinit()
 	lockInit(&deadlock, lockRankDeadlock)
 	lockInit(&paniclk, lockRankPanic)
 	lockInit(&allglock, lockRankAllg)
@@ -616,6 +635,7 @@ func schedinit() {
 	lockInit(&trace.lock, lockRankTrace)
 	lockInit(&cpuprof.lock, lockRankCpuprof)
 	lockInit(&trace.stackTab.lock, lockRankTraceStackTab)
+	lockInit(&goroutineDeadlines.lock, lockRankGoroutineDeadline)
 	// Enforce that this lock is always a leaf lock.
 	// All of this lock's critical sections should be
 	// extremely short.
@@ -650,14 +670,39 @@ func schedinit() {
 	goargs()
 	goenvs()
 	parsedebugvars()
+	detschedinit()
+	schedrecordinit()
+	schedseedinit()
+	deferpoolinit()
+	if debug.lockrank > 0 && !staticLockRanking {
+		// No lock is held and no other M has started yet, so
+		// lock ranking can be turned on here. The world started
+		// stopped above.
+		staticLockRanking = true
+		worldStopped()
+	}
+	if debug.lockpi > 0 {
+		// Likewise, the runtime locks can change how they use
+		// their lock words here.
+		lockPIInit()
+	}
 	gcinit()
 
+	if debug.sigstacksize > 0 {
+		// m0's signal stack was allocated before GODEBUG was parsed.
+		// It is not installed until minit, so it can still be resized.
+		resizeGsignal(_g_.m, debug.sigstacksize)
+	}
+
 	lock(&sched.lock)
 	sched.lastpoll = uint64(nanotime())
 	procs := ncpu // 注释：确认P的个数,默认等于cpu个数，可以通过GOMAXPROCS环境变量更改
 	if n, ok := atoi32(gogetenv("GOMAXPROCS")); ok && n > 0 {
 		procs = n
 	}
+	if detsched.enabled {
+		procs = 1
+	}
 	// 注释：调整P的个数，这里是新分配procs个P
 	// 注释：函数procresize很重要，所有的P都是从这里分配的，以后也不用担心没有P了
 	if procresize(procs) != nil {
@@ -689,6 +734,11 @@ func schedinit() {
 		// to ensure runtime·modinfo is kept in the resulting binary.
 		modinfo = ""
 	}
+	if debugLayout.version != debugLayoutVersion {
+		// Condition should never trigger. This code just serves
+		// to ensure runtime·debugLayout is kept in the resulting binary.
+		throw("bad debugLayout")
+	}
 }
 
 func dumpgstatus(gp *g) {
@@ -701,6 +751,7 @@ func dumpgstatus(gp *g) {
 func checkmcount() {
 	assertLockHeld(&sched.lock)
 
+	noteMCount(mcount())
 	if mcount() > sched.maxmcount {
 		print("runtime: program exceeds ", sched.maxmcount, "-thread limit\n")
 		throw("thread exhaustion")
@@ -742,11 +793,7 @@ func mcommoninit(mp *m, id int64) {
 		mp.id = mReserveID()
 	}
 
-	mp.fastrand[0] = uint32(int64Hash(uint64(mp.id), fastrandseed))
-	mp.fastrand[1] = uint32(int64Hash(uint64(cputicks()), ^fastrandseed))
-	if mp.fastrand[0]|mp.fastrand[1] == 0 {
-		mp.fastrand[1] = 1
-	}
+	mfastrandinit(mp)
 
 	mpreinit(mp)
 	if mp.gsignal != nil {
@@ -770,11 +817,48 @@ func mcommoninit(mp *m, id int64) {
 
 var fastrandseed uintptr
 
+// fastrandseeded is set if fastrandseed comes from GODEBUG.
+var fastrandseeded bool
+
 func fastrandinit() {
 	s := (*[unsafe.Sizeof(fastrandseed)]byte)(unsafe.Pointer(&fastrandseed))[:]
 	getRandomData(s)
 }
 
+// schedseedinit seeds fastrand with GODEBUG=schedseed, or else with
+// the seed of GODEBUG=detsched, so that scheduling and select choices
+// repeat from run to run. Each M draws from its own sequence, derived
+// from the seed and its ID alone. schedseedinit is called by schedinit
+// after parsedebugvars, and reseeds the current M, set up before.
+func schedseedinit() {
+	seed := debug.schedseed
+	if seed == 0 {
+		seed = debug.detsched
+	}
+	if seed == 0 {
+		return
+	}
+	fastrandseed = uintptr(uint32(seed))
+	fastrandseeded = true
+	mfastrandinit(getg().m)
+}
+
+// mfastrandinit seeds the fastrand state of mp.
+func mfastrandinit(mp *m) {
+	if fastrandseeded {
+		// int64Hash depends on hash keys that change from run
+		// to run, so mix the seed and ID by hand.
+		x := splitmix64(uint64(fastrandseed) + uint64(mp.id)*0x9e3779b97f4a7c15)
+		mp.fastrand[0], mp.fastrand[1] = uint32(x), uint32(x>>32)
+	} else {
+		mp.fastrand[0] = uint32(int64Hash(uint64(mp.id), fastrandseed))
+		mp.fastrand[1] = uint32(int64Hash(uint64(cputicks()), ^fastrandseed))
+	}
+	if mp.fastrand[0]|mp.fastrand[1] == 0 {
+		mp.fastrand[1] = 1
+	}
+}
+
 // Mark gp ready to run.
 func ready(gp *g, traceskip int, next bool) {
 	if trace.enabled {
@@ -864,6 +948,9 @@ func casfrom_Gscanstatus(gp *g, oldval, newval uint32) {
 		dumpgstatus(gp)
 		throw("casfrom_Gscanstatus: gp->status is not in scan state")
 	}
+	if gstatusTraceEnabled {
+		recordGStatus(gp, oldval, newval, getcallerpc())
+	}
 	releaseLockRank(lockRankGscan)
 }
 
@@ -879,6 +966,9 @@ func castogscanstatus(gp *g, oldval, newval uint32) bool {
 			r := atomic.Cas(&gp.atomicstatus, oldval, newval)
 			if r {
 				acquireLockRank(lockRankGscan)
+				if gstatusTraceEnabled {
+					recordGStatus(gp, oldval, newval, getcallerpc())
+				}
 			}
 			return r
 
@@ -928,6 +1018,9 @@ func casgstatus(gp *g, oldval, newval uint32) {
 			nextYield = nanotime() + yieldDelay/2
 		}
 	}
+	if gstatusTraceEnabled {
+		recordGStatus(gp, oldval, newval, getcallerpc())
+	}
 }
 
 // casgstatus(gp, oldstatus, Gcopystack), assuming oldstatus is Gwaiting or Grunnable.
@@ -943,6 +1036,9 @@ func casgcopystack(gp *g) uint32 {
 			throw("copystack: bad status, not Gwaiting or Grunnable")
 		}
 		if atomic.Cas(&gp.atomicstatus, oldstatus, _Gcopystack) {
+			if gstatusTraceEnabled {
+				recordGStatus(gp, oldstatus, _Gcopystack, getcallerpc())
+			}
 			return oldstatus
 		}
 	}
@@ -959,6 +1055,9 @@ func casGToPreemptScan(gp *g, old, new uint32) {
 	acquireLockRank(lockRankGscan)
 	for !atomic.Cas(&gp.atomicstatus, _Grunning, _Gscan|_Gpreempted) {
 	}
+	if gstatusTraceEnabled {
+		recordGStatus(gp, old, new, getcallerpc())
+	}
 }
 
 // casGFromPreempted attempts to transition gp from _Gpreempted to
@@ -968,7 +1067,13 @@ func casGFromPreempted(gp *g, old, new uint32) bool {
 	if old != _Gpreempted || new != _Gwaiting {
 		throw("bad g transition")
 	}
-	return atomic.Cas(&gp.atomicstatus, _Gpreempted, _Gwaiting)
+	if !atomic.Cas(&gp.atomicstatus, _Gpreempted, _Gwaiting) {
+		return false
+	}
+	if gstatusTraceEnabled {
+		recordGStatus(gp, old, new, getcallerpc())
+	}
+	return true
 }
 
 // stopTheWorld stops all P's from executing goroutines, interrupting
@@ -1011,7 +1116,9 @@ func startTheWorld() {
 	systemstack(func() { startTheWorldWithSema(false) })
 
 	// worldsema must be held over startTheWorldWithSema to ensure
-	// gomaxprocs cannot change while worldsema is held.
+	// gomaxprocs cannot change while worldsema is held. (GOMAXPROCS
+	// changes that only park Ps hold gcsema instead; they need a
+	// running P, so they cannot happen while the world is stopped.)
 	//
 	// Release worldsema with direct handoff to the next waiter, but
 	// acquirem so that semrelease1 doesn't try to yield our time.
@@ -1050,7 +1157,8 @@ var worldsema uint32 = 1
 
 // Holding gcsema grants the M the right to block a GC, and blocks
 // until the current GC is done. In particular, it prevents gomaxprocs
-// from changing concurrently.
+// from changing concurrently: GOMAXPROCS holds it even when it parks
+// Ps without stopping the world.
 //
 // TODO(mknyszek): Once gomaxprocs and the execution tracer can handle
 // being changed/enabled during a GC, remove this.
@@ -1087,8 +1195,9 @@ func stopTheWorldWithSema() {
 		throw("stopTheWorld: holding locks")
 	}
 
+	stwPauseStart(_g_.m.preemptoff)
 	lock(&sched.lock)
-	sched.stopwait = gomaxprocs
+	sched.stopwait = int32(len(allp))
 	atomic.Store(&sched.gcwaiting, 1)
 	preemptall()
 	// stop current P
@@ -1115,19 +1224,44 @@ func stopTheWorldWithSema() {
 		p.status = _Pgcstop
 		sched.stopwait--
 	}
+	// stop parked P's
+	for p := sched.pparked.ptr(); p != nil; p = p.link.ptr() {
+		p.status = _Pgcstop
+		sched.stopwait--
+	}
+	sched.pparked = 0
+	atomic.Store(&sched.nparked, 0)
 	wait := sched.stopwait > 0
 	unlock(&sched.lock)
 
 	// wait for remaining P's to stop voluntarily
 	if wait {
+		var delayer *g
+		start := nanotime()
+		watched := false
 		for {
 			// wait for 100us, then try to re-preempt in case of any races
 			if notetsleep(&sched.stopnote, 100*1000) {
 				noteclear(&sched.stopnote)
 				break
 			}
+			if gp := stwRunningG(); gp != nil {
+				delayer = gp
+			}
+			if debug.stwwatchdog > 0 && !watched {
+				if waited := nanotime() - start; waited > int64(debug.stwwatchdog)*1000*1000 {
+					watched = true
+					stwWatchdog(_g_.m.preemptoff, waited)
+				}
+			}
 			preemptall()
 		}
+		if delayer != nil {
+			recordSTWDelay(_g_.m.preemptoff, delayer, nanotime()-start)
+		}
+		if watched {
+			stwWatchdogDone()
+		}
 	}
 
 	// sanity checks
@@ -1193,13 +1327,24 @@ func startTheWorldWithSema(emitTraceEvent bool) int64 {
 			mp.nextp.set(p)
 			notewakeup(&mp.park)
 		} else {
+			lock(&sched.lock)
+			if threadLimitReached() {
+				// Let the work wait for an M; see threadwarn.go.
+				deferThread(p)
+				unlock(&sched.lock)
+				continue
+			}
+			// Reserve the ID under the same lock, as startm does.
+			id := mReserveID()
+			unlock(&sched.lock)
 			// Start M to run P.  Do not start another M below.
-			newm(nil, p, -1)
+			newm(nil, p, id)
 		}
 	}
 
 	// Capture start-the-world time before doing clean-up tasks.
 	startTime := nanotime()
+	stwPauseEnd(startTime)
 	if emitTraceEvent {
 		traceGCSTWDone()
 	}
@@ -1490,7 +1635,7 @@ func forEachP(fn func(*p)) {
 	if sched.safePointWait != 0 {
 		throw("forEachP: sched.safePointWait != 0")
 	}
-	sched.safePointWait = gomaxprocs - 1
+	sched.safePointWait = int32(len(allp)) - 1
 	sched.safePointFn = fn
 
 	// Ask all Ps to run the safe point function.
@@ -1505,14 +1650,21 @@ func forEachP(fn func(*p)) {
 	// p.runSafePointFn == 1 and will call runSafePointFn when
 	// changing its status to _Pidle/_Psyscall.
 
-	// Run safe point function for all idle Ps. sched.pidle will
-	// not change because we hold sched.lock.
+	// Run safe point function for all idle and parked Ps.
+	// sched.pidle and sched.pparked will not change because we
+	// hold sched.lock.
 	for p := sched.pidle.ptr(); p != nil; p = p.link.ptr() {
 		if atomic.Cas(&p.runSafePointFn, 1, 0) {
 			fn(p)
 			sched.safePointWait--
 		}
 	}
+	for p := sched.pparked.ptr(); p != nil; p = p.link.ptr() {
+		if atomic.Cas(&p.runSafePointFn, 1, 0) {
+			fn(p)
+			sched.safePointWait--
+		}
+	}
 
 	wait := sched.safePointWait > 0
 	unlock(&sched.lock)
@@ -1862,22 +2014,31 @@ func needm() {
 	sigsave(&sigmask)
 	sigblock(false)
 
-	// Lock extra list, take head, unlock popped list.
-	// nilokay=false is safe here because of the invariant above,
-	// that the extra list always contains or will soon contain
-	// at least one m.
-	mp := lockextra(false)
-
-	// Set needextram when we've just emptied the list,
-	// so that the eventual call into cgocallbackg will
-	// allocate a new m for the extra list. We delay the
-	// allocation until then so that it can be done
-	// after exitsyscall makes sure it is okay to be
-	// running at all (that is, there's no garbage collection
-	// running right now).
-	mp.needextram = mp.schedlink == 0
-	extraMCount--
-	unlockextra(mp.schedlink.ptr())
+	// Use the m this thread pinned, if any; see cgothread.go.
+	var mp *m
+	if atomic.Load(&extraMNumPinned) != 0 {
+		mp = takePinnedExtraM()
+	}
+	if mp != nil {
+		mp.needextram = false
+	} else {
+		// Lock extra list, take head, unlock popped list.
+		// nilokay=false is safe here because of the invariant above,
+		// that the extra list always contains or will soon contain
+		// at least one m.
+		mp = lockextra(false)
+
+		// Set needextram when we've just emptied the list,
+		// so that the eventual call into cgocallbackg will
+		// allocate a new m for the extra list. We delay the
+		// allocation until then so that it can be done
+		// after exitsyscall makes sure it is okay to be
+		// running at all (that is, there's no garbage collection
+		// running right now).
+		mp.needextram = mp.schedlink == 0
+		extraMCount--
+		unlockextra(mp.schedlink.ptr())
+	}
 
 	// Store the original signal mask for use by minit.
 	mp.sigmask = sigmask
@@ -1893,6 +2054,14 @@ func needm() {
 	_g_.stack.lo = getcallersp() - 32*1024
 	_g_.stackguard0 = _g_.stack.lo + _StackGuard
 
+	// setCgoSignalStackSize only resizes the signal stacks of the
+	// Ms on the extra list. mp may have been running a callback or
+	// pinned to its thread at the time, so resize it here, before
+	// minit installs its signal stack. Signals are blocked.
+	if n := int32(atomic.Load(&extraMGsignalSize)); n > mp.gsignalMin {
+		resizeGsignal(mp, n)
+	}
+
 	// Initialize this thread to use the m.
 	asminit()
 	minit()
@@ -1911,7 +2080,9 @@ func newextram() {
 	c := atomic.Xchg(&extraMWaiters, 0)
 	if c > 0 {
 		for i := uint32(0); i < c; i++ {
-			oneNewExtraM()
+			if !oneNewExtraM() {
+				break
+			}
 		}
 	} else {
 		// Make sure there is at least one extra M.
@@ -1923,14 +2094,27 @@ func newextram() {
 	}
 }
 
-// oneNewExtraM allocates an m and puts it on the extra list.
-func oneNewExtraM() {
+// oneNewExtraM allocates an m and puts it on the extra list. It
+// reports false, allocating nothing, if there are already extraMMax
+// extra Ms.
+func oneNewExtraM() bool {
+	mnext := lockextra(true)
+	if max := atomic.Load(&extraMMax); max != 0 && extraMTotal >= max {
+		unlockextra(mnext)
+		return false
+	}
+	extraMTotal++
+	unlockextra(mnext)
+
 	// Create extra goroutine locked to extra m.
 	// The goroutine is the context in which the cgo callback will run.
 	// The sched.pc will never be returned to, but setting it to
 	// goexit makes clear to the traceback routines where
 	// the goroutine stack ends.
 	mp := allocm(nil, nil, -1)
+	if n := int32(atomic.Load(&extraMGsignalSize)); n > 0 {
+		resizeGsignal(mp, n)
+	}
 	gp := malg(4096)
 	gp.sched.pc = funcPC(goexit) + sys.PCQuantum
 	gp.sched.sp = gp.stack.hi
@@ -1947,6 +2131,7 @@ func oneNewExtraM() {
 	casgstatus(gp, _Gidle, _Gdead)
 	gp.m = mp
 	mp.curg = gp
+	mp.isextra = true
 	mp.lockedInt++
 	mp.lockedg.set(gp)
 	gp.lockedm.set(mp)
@@ -1964,12 +2149,92 @@ func oneNewExtraM() {
 	atomic.Xadd(&sched.ngsys, +1)
 
 	// Add m to the extra list.
-	mnext := lockextra(true)
+	mp.extraIdleSince = nanotime()
+	mnext = lockextra(true)
 	mp.schedlink.set(mnext)
 	extraMCount++
 	unlockextra(mp)
+	return true
 }
 
+// extraMGsignalSize, if non-zero, is the minimum size of the signal
+// stack of extra Ms, which run cgo callbacks on threads created by C.
+// Such threads may run C signal handlers on the Go signal stack, and
+// those often need more room than Go's own handlers. It is set by
+// runtime/debug.SetCgoSignalStackSize.
+var extraMGsignalSize uint32
+
+// gsignalSize returns the size of the signal stack to allocate for mp,
+// given the operating system's default size def. The size may be
+// raised, but not lowered, with GODEBUG=sigstacksize=N or by
+// resizeGsignal.
+func gsignalSize(mp *m, def int32) int32 {
+	n := debug.sigstacksize
+	if n < mp.gsignalMin {
+		n = mp.gsignalMin
+	}
+	if n > maxSignalStackSize {
+		n = maxSignalStackSize
+	}
+	if n > def {
+		return round2(n)
+	}
+	return def
+}
+
+// resizeGsignal replaces mp's signal stack with one of at least n bytes
+// if it is currently smaller. mp must not be running and its signal
+// stack must not be installed with sigaltstack.
+func resizeGsignal(mp *m, n int32) {
+	if mp.gsignal == nil {
+		return
+	}
+	mp.gsignalMin = n
+	if uintptr(gsignalSize(mp, 0)) <= mp.gsignal.stack.hi-mp.gsignal.stack.lo {
+		return
+	}
+	// Only replace the stack: mpreinit would allocate a new gsignal
+	// and redo OS-specific setup of mp.
+	gp := mp.gsignal
+	old := gp.stack
+	size := round2(_StackSystem + gsignalSize(mp, 0))
+	systemstack(func() {
+		gp.stack = stackalloc(uint32(size))
+		stackfree(old)
+	})
+	gp.stackguard0 = gp.stack.lo + _StackGuard
+	// See malg.
+	*(*uintptr)(unsafe.Pointer(gp.stack.lo)) = 0
+	osGsignalAlloc(mp)
+}
+
+// setCgoSignalStackSize sets extraMGsignalSize and resizes the signal
+// stacks of the extra Ms on the extra list. needm resizes the others,
+// which are running a callback or pinned to a thread, when it next
+// takes them.
+//go:linkname setCgoSignalStackSize runtime/debug.setCgoSignalStackSize
+func setCgoSignalStackSize(n int) (prev int) {
+	if n < 0 {
+		n = 0
+	}
+	if n > maxSignalStackSize {
+		n = maxSignalStackSize
+	}
+	prev = int(atomic.Xchg(&extraMGsignalSize, uint32(n)))
+	if n == 0 || !iscgo {
+		return prev
+	}
+	mp := lockextra(true)
+	for m := mp; m != nil; m = m.schedlink.ptr() {
+		resizeGsignal(m, int32(n))
+	}
+	unlockextra(mp)
+	return prev
+}
+
+// maxSignalStackSize bounds the signal stack sizes that can be requested.
+const maxSignalStackSize = 64 << 20
+
 // dropm is called when a cgo callback has called needm but is now
 // done with the callback and returning back into the non-Go thread.
 // It puts the current m back onto the extra list.
@@ -2012,7 +2277,17 @@ func dropm() {
 	sigblock(false)
 	unminit()
 
+	mp.extraIdleSince = nanotime()
 	mnext := lockextra(true)
+	if mp.cthreadPinned {
+		// Keep mp for this thread's next call.
+		mp.schedlink = extraMPinned
+		extraMPinned.set(mp)
+		setg(nil)
+		unlockextra(mnext)
+		msigrestore(sigmask)
+		return
+	}
 	extraMCount++
 	mp.schedlink.set(mnext)
 
@@ -2032,6 +2307,30 @@ func getm() uintptr {
 var extram uintptr
 var extraMCount uint32 // Protected by lockextra
 var extraMWaiters uint32
+var extraMTotal uint32 // Extra Ms in use or on the list; protected by lockextra
+var extraMFreed uint64 // Protected by lockextra
+
+// extraMMax, if non-zero, is the maximum number of extra Ms. When they
+// are all in use, needm waits for one to be dropped. It is set by
+// runtime/debug.SetMaxExtraMs and accessed atomically.
+var extraMMax uint32
+
+// extraMIdleTimeout is how long, in nanoseconds, an extra M may stay
+// unused on the extra list before sysmon frees it, or 0 (the default)
+// to keep idle extra Ms. It is set by runtime/debug.SetExtraMIdleTimeout
+// and accessed atomically.
+var extraMIdleTimeout uint64
+
+// extraMWake is bumped by unlockextra when it puts Ms on the extra list
+// while threads in lockextra are waiting for one. extraMSleepers counts
+// those threads. Both are accessed atomically.
+var (
+	extraMWake     uint32
+	extraMSleepers uint32
+)
+
+// extraMCheckPeriod is how often sysmon looks for extra Ms to free.
+const extraMCheckPeriod = 1e9
 
 // lockextra locks the extra list and returns the list head.
 // The caller must unlock the list by storing a new list head
@@ -2057,7 +2356,15 @@ func lockextra(nilokay bool) *m {
 				atomic.Xadd(&extraMWaiters, 1)
 				incr = true
 			}
-			usleep(1)
+			// Sleep until unlockextra puts an M on the list,
+			// which may take long if there are extraMMax
+			// extra Ms in use.
+			atomic.Xadd(&extraMSleepers, 1)
+			wake := atomic.Load(&extraMWake)
+			if atomic.Loaduintptr(&extram) == 0 {
+				extraMSleep(&extraMWake, wake)
+			}
+			atomic.Xadd(&extraMSleepers, -1)
 			continue
 		}
 		if atomic.Casuintptr(&extram, old, locked) {
@@ -2071,6 +2378,143 @@ func lockextra(nilokay bool) *m {
 //go:nosplit
 func unlockextra(mp *m) {
 	atomic.Storeuintptr(&extram, uintptr(unsafe.Pointer(mp)))
+	if mp != nil && atomic.Load(&extraMSleepers) != 0 {
+		atomic.Xadd(&extraMWake, 1)
+		extraMWakeup(&extraMWake)
+	}
+}
+
+// reclaimExtraMs frees the extra Ms on the extra list that have been
+// idle for longer than extraMIdleTimeout, and those beyond extraMMax.
+// It keeps the most recently used M, so that needm does not have to
+// wait for a new one. It must run on the system stack.
+//
+//go:systemstack
+//go:nowritebarrierrec
+func reclaimExtraMs(now int64) {
+	timeout := int64(atomic.Load64(&extraMIdleTimeout))
+	max := atomic.Load(&extraMMax)
+	var freed *m
+	head := lockextra(true)
+	if head != nil {
+		prev := head
+		for mp := head.schedlink.ptr(); mp != nil; mp = prev.schedlink.ptr() {
+			if (max == 0 || extraMTotal <= max) && (timeout == 0 || now-mp.extraIdleSince <= timeout) {
+				prev = mp
+				continue
+			}
+			prev.schedlink = mp.schedlink
+			mp.schedlink.set(freed)
+			freed = mp
+			extraMCount--
+			extraMTotal--
+			extraMFreed++
+		}
+	}
+	unlockextra(head)
+
+	for freed != nil {
+		mp := freed
+		freed = mp.schedlink.ptr()
+		freeExtraM(mp)
+	}
+}
+
+// freeExtraM frees mp, an extra M taken off the extra list, and
+// returns its goroutine to the free list.
+//
+// It is called by sysmon, so it cannot have write barriers. The
+// pointers it stores are nil or already reachable.
+//
+//go:nowritebarrierrec
+func freeExtraM(mp *m) {
+	gp := mp.curg
+	setGNoWB(&mp.curg, nil)
+	mp.lockedg = 0
+	mp.lockedInt = 0
+	setMNoWB(&gp.m, nil)
+	gp.lockedm = 0
+
+	// The goroutine was counted as a system goroutine while on the
+	// extra list; see oneNewExtraM.
+	atomic.Xadd(&sched.ngsys, -1)
+	if gp.stack.hi-gp.stack.lo != _FixedStack {
+		stackfree(gp.stack)
+		gp.stack.lo = 0
+		gp.stack.hi = 0
+		gp.stackguard0 = 0
+	}
+	s := &gFreeCentral[gp.goid%gFreeShards].shard
+	s.lock.lock()
+	s.put(gp)
+	s.lock.unlock()
+
+	if mp.gsignal != nil {
+		stackfree(mp.gsignal.stack)
+		setGNoWB(&mp.gsignal, nil)
+	}
+	mdestroy(mp)
+
+	// Remove mp from allm. As in mexit, mp.alllink is left alone for
+	// the functions that walk allm without locking.
+	lock(&sched.lock)
+	for pprev := &allm; *pprev != nil; pprev = &(*pprev).alllink {
+		if *pprev == mp {
+			setMNoWB(pprev, mp.alllink)
+			sched.nmfreed++
+			unlock(&sched.lock)
+			return
+		}
+	}
+	throw("extra m not found in allm")
+}
+
+// readExtraMStats returns the number of extra Ms running cgo
+// callbacks, the number on the extra list, and the number freed by
+// reclaimExtraMs.
+func readExtraMStats() (inUse, idle uint32, freed uint64) {
+	mp := lockextra(true)
+	inUse = extraMTotal - extraMCount
+	idle = extraMCount
+	freed = extraMFreed
+	unlockextra(mp)
+	return
+}
+
+//go:linkname setMaxExtraMs runtime/debug.setMaxExtraMs
+func setMaxExtraMs(n int) int {
+	if n < 0 {
+		n = 0
+	}
+	prev := int(atomic.Xchg(&extraMMax, uint32(n)))
+	if cgoHasExtraM {
+		systemstack(func() {
+			reclaimExtraMs(nanotime())
+		})
+	}
+	return prev
+}
+
+//go:linkname setExtraMIdleTimeout runtime/debug.setExtraMIdleTimeout
+func setExtraMIdleTimeout(ns int64) int64 {
+	if ns < 0 {
+		ns = 0
+	}
+	prev := int64(atomic.Xchg64(&extraMIdleTimeout, uint64(ns)))
+	if cgoHasExtraM {
+		systemstack(func() {
+			reclaimExtraMs(nanotime())
+		})
+	}
+	return prev
+}
+
+//go:linkname setIdleThreadTimeout runtime/debug.setIdleThreadTimeout
+func setIdleThreadTimeout(ns int64) int64 {
+	if ns < 0 {
+		ns = 0
+	}
+	return int64(atomic.Xchg64(&mIdleTimeout, uint64(ns)))
 }
 
 // execLock serializes exec and clone to avoid bugs or unspecified behaviour
@@ -2312,11 +2756,74 @@ func stopm() {
 	lock(&sched.lock)
 	mput(_g_.m) // 注释：把当前的M加入到空闲M链表中(空闲M链表是在全局的调度器中，所以需要加锁执行)
 	unlock(&sched.lock)
-	mPark()
+	if !mParkIdle() {
+		// Return to mstart, which will release the P and exit
+		// the thread.
+		gogo(&_g_.m.g0.sched)
+	}
 	acquirep(_g_.m.nextp.ptr()) // 注释：(获得P)当前线程m和p相互绑定，并且把p的状态从_Pidle设置成_Prunning
 	_g_.m.nextp = 0
 }
 
+// mIdleTimeout is how long, in nanoseconds, an M may stay on the idle
+// list before it exits, or 0 (the default) to keep idle Ms. It is set
+// by runtime/debug.SetIdleThreadTimeout and accessed atomically.
+var mIdleTimeout uint64
+
+// mIdleExits counts the Ms that exited after staying idle for longer
+// than mIdleTimeout. Written with sched.lock held, read atomically.
+var mIdleExits uint64
+
+// mParkIdle parks the current M, which is on the idle list, like
+// mPark. If the M stays idle for longer than mIdleTimeout, it takes
+// itself off the list and returns false, with an idle P acquired for
+// mexit to release. It keeps the last idle M, and the Ms that cannot
+// exit, and leaves the list only when there is an idle P, retrying
+// after another timeout otherwise.
+func mParkIdle() bool {
+	g := getg()
+	timeout := int64(atomic.Load64(&mIdleTimeout))
+	if timeout == 0 || g.m == &m0 || GOOS == "plan9" || GOOS == "js" {
+		mPark()
+		return true
+	}
+	deadline := nanotime() + timeout
+	for {
+		ns := deadline - nanotime()
+		if ns <= 0 {
+			ns = 1 // not < 0, which sleeps forever
+		}
+		if notetsleep(&g.m.park, ns) {
+			noteclear(&g.m.park)
+			if !mDoFixup() {
+				return true
+			}
+			continue
+		}
+		lock(&sched.lock)
+		if !mremove(g.m) {
+			// mget took this M, and its caller will wake it.
+			unlock(&sched.lock)
+			mPark()
+			return true
+		}
+		var pp *p
+		if sched.nmidle > 0 {
+			pp = pidleget()
+		}
+		if pp == nil {
+			mput(g.m)
+			unlock(&sched.lock)
+			deadline = nanotime() + timeout
+			continue
+		}
+		atomic.Store64(&mIdleExits, mIdleExits+1)
+		unlock(&sched.lock)
+		acquirep(pp)
+		return false
+	}
+}
+
 func mspinning() {
 	// startm's caller incremented nmspinning. Set the new M's spinning.
 	getg().m.spinning = true
@@ -2381,6 +2888,18 @@ func startm(_p_ *p, spinning bool) {
 	}
 	nmp := mget() // 注释：获取空闲的m
 	// 注释：如果没有找到空闲的m则需要创建一个新m
+	if nmp == nil && threadLimitReached() {
+		// Let the work wait for an M; see threadwarn.go.
+		deferThread(_p_)
+		unlock(&sched.lock)
+		if spinning {
+			if int32(atomic.Xadd(&sched.nmspinning, -1)) < 0 {
+				throw("startm: negative nmspinning")
+			}
+		}
+		releasem(mp)
+		return
+	}
 	if nmp == nil {
 		// No M is available, we must drop sched.lock and call newm.
 		// However, we already own a P to assign to the M.
@@ -2430,6 +2949,61 @@ func startm(_p_ *p, spinning bool) {
 	releasem(mp) // 注释：释放线程m
 }
 
+// startmBatch starts up to n idle Ps to run goroutines just put on the
+// global run queue, as n calls to startm(nil, false) would, and returns
+// the number of Ms it woke or started. It takes sched.lock once for a
+// batch of idle Ms, instead of once per M, and wakes them after
+// dropping it.
+//
+// Spinning Ms are not counted on to find the goroutines: one may have
+// checked the global run queue already, before it stops spinning and
+// finds the local run queues alone.
+//
+// Must not have write barriers because this may be called without a P.
+//go:nowritebarrierrec
+func startmBatch(n int) int {
+	started := 0
+	for n > 0 && atomic.Load(&sched.npidle) != 0 {
+		var batch [8]*m
+		k := 0
+		// Disable preemption while owning the Ps; see startm.
+		mp := acquirem()
+		lock(&sched.lock)
+		for k < len(batch) && k < n && sched.midle != 0 {
+			_p_ := pidleget()
+			if _p_ == nil {
+				break
+			}
+			nmp := mget()
+			if nmp.spinning {
+				throw("startmBatch: m is spinning")
+			}
+			if nmp.nextp != 0 {
+				throw("startmBatch: m has p")
+			}
+			nmp.nextp.set(_p_)
+			batch[k] = nmp
+			k++
+		}
+		unlock(&sched.lock)
+		for _, nmp := range batch[:k] {
+			notewakeup(&nmp.park)
+		}
+		releasem(mp)
+		n -= k
+		started += k
+		if k < len(batch) && n > 0 {
+			// Out of idle Ms; new ones are started one at a time.
+			if atomic.Load(&sched.npidle) != 0 {
+				startm(nil, false)
+				n--
+				started++
+			}
+		}
+	}
+	return started
+}
+
 // Hands off P from syscall or locked M. // 注释：切换(移交、让渡)p从系统调用或者锁定的m
 // Always runs without a P, so write barriers are not allowed.
 // 注释：让渡p,调度另一个或新的m运行这个p
@@ -2438,21 +3012,24 @@ func handoffp(_p_ *p) {
 	// handoffp must start an M in any situation where
 	// findrunnable would return a G to run on _p_.
 
+	// A P above the limit is parked below instead.
+	beyond := _p_.id >= sched.pactive
+
 	// if it has local work, start it straight away
 	// 注释：如果是本地g运行队列有值或全局运行队列有值就直接启动
-	if !runqempty(_p_) || sched.runqsize != 0 {
+	if !beyond && (!runqempty(_p_) || sched.runqsize != 0) {
 		startm(_p_, false) // 注释：用另一个m跑这个p
 		return
 	}
 	// if it has GC work, start it straight away // 注释：如果是GC则直接启动
-	if gcBlackenEnabled != 0 && gcMarkWorkAvailable(_p_) {
+	if !beyond && gcBlackenEnabled != 0 && gcMarkWorkAvailable(_p_) {
 		startm(_p_, false) // 注释：用另一个m跑这个p
 		return
 	}
 	// no local work, check that there are no spinning/idle M's,
 	// otherwise our help is not required
 	// 注释：如果没有自旋的m并且没有空闲的p时，执行&sched.nmspinning=1并且执行startm
-	if atomic.Load(&sched.nmspinning)+atomic.Load(&sched.npidle) == 0 && atomic.Cas(&sched.nmspinning, 0, 1) { // TODO: fast atomic
+	if !beyond && atomic.Load(&sched.nmspinning)+atomic.Load(&sched.npidle) == 0 && atomic.Cas(&sched.nmspinning, 0, 1) { // TODO: fast atomic
 		startm(_p_, true) // 注释：用另一个m跑这个p
 		return
 	}
@@ -2473,14 +3050,18 @@ func handoffp(_p_ *p) {
 			notewakeup(&sched.safePointNote)
 		}
 	}
-	if sched.runqsize != 0 {
+	if _p_.id >= sched.pactive {
+		parkp(_p_) // releases sched.lock
+		return
+	}
+	if !runqempty(_p_) || sched.runqsize != 0 {
 		unlock(&sched.lock)
 		startm(_p_, false)
 		return
 	}
 	// If this is the last running P and nobody is polling network,
 	// need to wakeup another M to poll network.
-	if sched.npidle == uint32(gomaxprocs-1) && atomic.Load64(&sched.lastpoll) != 0 {
+	if lastRunningP() && atomic.Load64(&sched.lastpoll) != 0 {
 		unlock(&sched.lock)
 		startm(_p_, false)
 		return
@@ -2500,7 +3081,13 @@ func handoffp(_p_ *p) {
 // Tries to add one more P to execute G's.            // 注释：尝试再添加一个P以执行G。
 // Called when a G is made runnable (newproc, ready). // 注释：当G可以运行时调用（newproc，ready）。
 func wakep() {
-	if atomic.Load(&sched.npidle) == 0 {
+	npidle := atomic.Load(&sched.npidle)
+	if npidle == 0 {
+		return
+	}
+	// Leave the reserved Ps to Ms returning from system calls,
+	// unless all Ps are idle.
+	if npidle <= atomic.Load(&syscallReservePs) && int32(npidle) < sched.pactive {
 		return
 	}
 	// be conservative about spinning threads
@@ -2600,6 +3187,12 @@ func gcstopm() {
 func execute(gp *g, inheritTime bool) {
 	_g_ := getg()
 
+	if goroutineProfile.active {
+		// Make sure gp's stack is in the goroutine profile as it
+		// was when the profiler stopped the world, before gp runs.
+		tryRecordGoroutineProfile(gp, osyield)
+	}
+
 	// Assign gp.m before entering _Grunning so running Gs have an
 	// M.
 	_g_.m.curg = gp
@@ -2608,9 +3201,21 @@ func execute(gp *g, inheritTime bool) {
 	gp.waitsince = 0
 	gp.preempt = false
 	gp.stackguard0 = gp.stack.lo + _StackGuard
+	if gp.stackMove && gp.syscallsp == 0 {
+		gp.stackguard0 = stackForceMove
+	}
+	if gp.group != nil {
+		gp.groupwhen = nanotime()
+	}
 	if !inheritTime {
 		_g_.m.p.ptr().schedtick++
 	}
+	if schedRec.recs != nil {
+		recordSched(schedEventRun, gp, 0)
+	}
+	if detsched.enabled {
+		schedReplayRun(gp)
+	}
 
 	// Check whether the profiler needs to be turned on or off.
 	hz := sched.profilehz
@@ -2618,6 +3223,11 @@ func execute(gp *g, inheritTime bool) {
 		setThreadCPUProfiler(hz)
 	}
 
+	// Check whether the watchpoints need to be reinstalled.
+	if _g_.m.watchgen != atomic.Load(&watchpoints.gen) {
+		setThreadWatchpoints(_g_.m)
+	}
+
 	if trace.enabled {
 		// GoSysExit has to happen when we have a P, but before GoStart.
 		// So we emit it here.
@@ -2651,6 +3261,12 @@ top:
 	}
 
 	now, pollUntil, _ := checkTimers(_p_, 0)
+	// timerP is the P of the timer due at pollUntil, to take when the
+	// network poll returns for it.
+	var timerP *p
+	if pollUntil != 0 {
+		timerP = _p_
+	}
 
 	if fingwait && fingwake {
 		if gp := wakefing(); gp != nil {
@@ -2661,6 +3277,12 @@ top:
 		asmcgocall(*cgo_yield, nil)
 	}
 
+	if detsched.enabled {
+		if gp, inheritTime := detschedget(_p_); gp != nil {
+			return gp, inheritTime
+		}
+	}
+
 	// local runq
 	// 注释：在本地P队列中获取G
 	if gp, inheritTime := runqget(_p_); gp != nil {
@@ -2702,7 +3324,7 @@ top:
 	}
 
 	// Steal work from other P's.
-	procs := uint32(gomaxprocs)
+	procs := uint32(sched.pactive)
 	ranTimer := false
 	// If number of spinning M's >= number of busy P's, block.
 	// This is necessary to prevent excessive CPU consumption
@@ -2748,6 +3370,7 @@ top:
 				now = tnow
 				if w != 0 && (pollUntil == 0 || w < pollUntil) {
 					pollUntil = w
+					timerP = p2
 				}
 				if ran {
 					// Running the timers may have
@@ -2893,6 +3516,7 @@ stop:
 			w := nobarrierWakeTime(_p_)
 			if w != 0 && (pollUntil == 0 || w < pollUntil) {
 				pollUntil = w
+				timerP = _p_
 			}
 		}
 	}
@@ -2956,6 +3580,16 @@ stop:
 
 	// poll network
 	if netpollinited() && (atomic.Load(&netpollWaiters) > 0 || pollUntil != 0) && atomic.Xchg64(&sched.lastpoll, 0) != 0 {
+		idle := procsIdle()
+		if idle && pollUntil != 0 {
+			// Merge the wakeups for timers due close together;
+			// see deepidle.go.
+			pollUntil = deepIdleDeadline(pollUntil)
+			delta = pollUntil - now
+			if delta < 0 {
+				delta = 0
+			}
+		}
 		atomic.Store64(&sched.pollUntil, uint64(pollUntil))
 		if _g_.m.p != 0 {
 			throw("findrunnable: netpoll with p")
@@ -2968,8 +3602,19 @@ stop:
 			delta = 0
 		}
 		list := netpoll(delta) // block until new work is available
+		counterNetpollWakeups.add(1)
 		atomic.Store64(&sched.pollUntil, 0)
-		atomic.Store64(&sched.lastpoll, uint64(nanotime()))
+		now := nanotime()
+		atomic.Store64(&sched.lastpoll, uint64(now))
+		if list.empty() && (delta < 0 || now < pollUntil) {
+			atomic.Xadd64(&netpollSpuriousWakeups, 1)
+		} else if idle && delta >= 0 && now >= pollUntil {
+			atomic.Xadd64(&idlePollerWakeups, 1)
+		}
+		timerWake := list.empty() && delta >= 0 && now >= pollUntil
+		if timerWake {
+			atomic.Xadd64(&netpollTimerWakeups, 1)
+		}
 		if faketime != 0 && list.empty() {
 			// Using fake time and nothing is ready; stop M.
 			// When all M's stop, checkdead will call timejump.
@@ -2977,10 +3622,20 @@ stop:
 			goto top
 		}
 		lock(&sched.lock)
-		_p_ = pidleget()
+		// Take the P of the timer the poll returned for, if it is
+		// idle, and run the timer on it rather than steal it from
+		// there while spinning, which would wake another M.
+		if timerWake && timerP != nil && timerP.id < int32(len(allp)) && allp[timerP.id] == timerP && pidletake(timerP) {
+			_p_ = timerP
+		} else {
+			_p_ = pidleget()
+		}
 		unlock(&sched.lock)
 		if _p_ == nil {
 			injectglist(&list)
+			if timerWake {
+				atomic.Xadd64(&netpollWastedWakeups, 1)
+			}
 		} else {
 			acquirep(_p_)
 			if !list.empty() {
@@ -2992,6 +3647,11 @@ stop:
 				}
 				return gp, false
 			}
+			if timerWake {
+				if _, _, ran := checkTimers(_p_, now); !ran {
+					atomic.Xadd64(&netpollWastedWakeups, 1)
+				}
+			}
 			if wasSpinning {
 				_g_.m.spinning = true
 				atomic.Xadd(&sched.nmspinning, 1)
@@ -3001,7 +3661,7 @@ stop:
 	} else if pollUntil != 0 && netpollinited() {
 		pollerPollUntil := int64(atomic.Load64(&sched.pollUntil))
 		if pollerPollUntil == 0 || pollerPollUntil > pollUntil {
-			netpollBreak()
+			netpollBreakFor(pollUntil, pollerPollUntil)
 		}
 	}
 	stopm()
@@ -3040,7 +3700,7 @@ func wakeNetPoller(when int64) {
 		// but should never miss a wakeup.
 		pollerPollUntil := int64(atomic.Load64(&sched.pollUntil))
 		if pollerPollUntil == 0 || pollerPollUntil > when {
-			netpollBreak()
+			netpollBreakFor(when, pollerPollUntil)
 		}
 	} else {
 		// There are no threads in the network poller, try to get
@@ -3049,6 +3709,24 @@ func wakeNetPoller(when int64) {
 			wakep()
 		}
 	}
+	wakeSysmonForTimer(when)
+}
+
+// netpollBreakFor interrupts the blocked netpoll, which returns at
+// pollerPollUntil or, if zero, when interrupted, for a timer due at
+// when. It does nothing if the timer is due less than netpollBreakSlack
+// before the poll returns anyway, or, while all Ps are idle, if the
+// poll returns before the deadline for when given by deepIdleDeadline.
+func netpollBreakFor(when, pollerPollUntil int64) {
+	if pollerPollUntil != 0 && pollerPollUntil-when <= int64(atomic.Load64(&netpollBreakSlack)) {
+		atomic.Xadd64(&netpollSkippedBreaks, 1)
+		return
+	}
+	if pollerPollUntil != 0 && atomic.Loadint64(&deepIdleSlack) > 0 && procsIdle() && deepIdleDeadline(when) >= pollerPollUntil {
+		// The poll returns within the deep idle slack of when.
+		return
+	}
+	netpollBreak()
 }
 
 func resetspinning() {
@@ -3067,6 +3745,14 @@ func resetspinning() {
 	wakep()
 }
 
+// Statistics of injectglist, reported by runtime/metrics. Accessed
+// atomically.
+var (
+	injectBatches    uint64 // non-empty lists injected
+	injectGoroutines uint64 // goroutines in those lists
+	injectWakeups    uint64 // Ms woken or started to run them
+)
+
 // injectglist adds each runnable G on the list to some run queue,
 // and clears glist. If there is no current P, they are added to the
 // global queue, and up to npidle M's are started to run them.
@@ -3102,11 +3788,17 @@ func injectglist(glist *gList) {
 	q.tail.set(tail)
 	*glist = gList{}
 
+	// Start at most netpollMaxWakeups idle Ps, if set, so that a burst
+	// of goroutines does not wake every idle P at once.
+	maxWakeups := int(atomic.Load(&netpollMaxWakeups))
 	startIdle := func(n int) {
-		for ; n != 0 && sched.npidle != 0; n-- {
-			startm(nil, false)
+		if maxWakeups > 0 && n > maxWakeups {
+			n = maxWakeups
 		}
+		atomic.Xadd64(&injectWakeups, int64(startmBatch(n)))
 	}
+	atomic.Xadd64(&injectBatches, 1)
+	atomic.Xadd64(&injectGoroutines, int64(qsize))
 
 	pp := getg().m.p.ptr()
 	if pp == nil {
@@ -3118,6 +3810,9 @@ func injectglist(glist *gList) {
 	}
 
 	npidle := int(atomic.Load(&sched.npidle))
+	if maxWakeups > 0 && npidle > maxWakeups {
+		npidle = maxWakeups
+	}
 	var globq gQueue
 	var n int
 	for n = 0; n < npidle && !q.empty(); n++ {
@@ -3173,6 +3868,13 @@ top:
 	if pp.runSafePointFn != 0 {
 		runSafePointFn() // 注释：如果pp.runSafePointFn != 0,运行sched.safePointFn
 	}
+	if pp.id >= sched.pactive {
+		procpark()
+		goto top
+	}
+	if atomic.Load(&sched.ptimers) != 0 {
+		adoptParkedTimers(pp)
+	}
 
 	// Sanity check: if we are spinning, the run queue should be empty.
 	// Check this before calling checkTimers, as that might call
@@ -3203,6 +3905,9 @@ top:
 		gp = gcController.findRunnableGCWorker(_g_.m.p.ptr())
 		tryWakeP = tryWakeP || gp != nil
 	}
+	if gp == nil && detsched.enabled {
+		gp, inheritTime = detschedget(_g_.m.p.ptr())
+	}
 	// 注释：每隔61次调度尝试去全局队列中获取一个G
 	if gp == nil {
 		// Check the global runnable queue once in a while to ensure fairness.
@@ -3236,14 +3941,15 @@ top:
 		resetspinning()
 	}
 
-	if sched.disable.user && !schedEnabled(gp) {
-		// Scheduling of this goroutine is disabled. Put it on
-		// the list of pending runnable goroutines for when we
-		// re-enable user scheduling and look again.
+	if sched.disable.user {
+		// Scheduling of this goroutine may be disabled. If so,
+		// put it on the list of pending runnable goroutines for
+		// when we re-enable user scheduling and look again.
+		// schedEnabled requires sched.lock.
 		lock(&sched.lock)
 		if schedEnabled(gp) {
-			// Something re-enabled scheduling while we
-			// were acquiring the lock.
+			// gp may run, or something re-enabled scheduling
+			// while we were acquiring the lock.
 			unlock(&sched.lock)
 		} else {
 			sched.disable.runnable.pushBack(gp)
@@ -3279,6 +3985,9 @@ top:
 func dropg() {
 	_g_ := getg()
 
+	if gp := _g_.m.curg; gp.groupwhen != 0 {
+		groupStop(gp)
+	}
 	setMNoWB(&_g_.m.curg.m, nil)
 	setGNoWB(&_g_.m.curg, nil)
 }
@@ -3406,9 +4115,25 @@ func gosched_m(gp *g) {
 func goschedguarded_m(gp *g) {
 
 	if !canPreemptM(gp.m) {
+		// Let the goroutine keep running for now, as newstack
+		// does. gp.preempt is still set, so releasem will ask
+		// again.
+		gp.stackguard0 = gp.stack.lo + _StackGuard
 		gogo(&gp.sched) // never return
 	}
 
+	// goschedguarded is called by the loop preemption checks,
+	// which stand in for asynchronous preemption where it is not
+	// available, so handle the requests newstack handles.
+	if gp.preemptShrink {
+		gp.preemptShrink = false
+		shrinkstack(gp)
+	}
+
+	if gp.preemptStop {
+		preemptPark(gp) // never returns
+	}
+
 	if trace.enabled {
 		traceGoSched()
 	}
@@ -3419,6 +4144,9 @@ func gopreempt_m(gp *g) {
 	if trace.enabled {
 		traceGoPreempt()
 	}
+	if schedRec.recs != nil {
+		recordSched(schedEventPreempt, gp, preemptPC(gp))
+	}
 	goschedImpl(gp)
 }
 
@@ -3434,6 +4162,9 @@ func preemptPark(gp *g) {
 		dumpgstatus(gp)
 		throw("bad g status")
 	}
+	if schedRec.recs != nil {
+		recordSched(schedEventPreempt, gp, preemptPC(gp))
+	}
 	gp.waitreason = waitReasonPreempted
 	// Transition from _Grunning to _Gscan|_Gpreempted. We can't
 	// be in _Grunning when we dropg because then we'd be running
@@ -3459,6 +4190,20 @@ func goyield_m(gp *g) {
 	if trace.enabled {
 		traceGoPreempt()
 	}
+	goyieldImpl(gp)
+}
+
+// GoschedLocal continuation on g0.
+func goschedlocal_m(gp *g) {
+	if trace.enabled {
+		traceGoSched()
+	}
+	goyieldImpl(gp)
+}
+
+// goyieldImpl puts gp on the runq of the current P, like goschedImpl
+// does on the global runq.
+func goyieldImpl(gp *g) {
 	pp := gp.m.p.ptr()
 	casgstatus(gp, _Grunning, _Grunnable)
 	dropg()
@@ -3484,6 +4229,8 @@ func goexit0(gp *g) {
 	casgstatus(gp, _Grunning, _Gdead)
 	if isSystemGoroutine(gp, false) {
 		atomic.Xadd(&sched.ngsys, -1)
+	} else {
+		counterGoroutinesExited.add(1)
 	}
 	gp.m = nil
 	locked := gp.lockedm != 0
@@ -3497,6 +4244,16 @@ func goexit0(gp *g) {
 	gp.waitreason = 0
 	gp.param = nil
 	gp.labels = nil
+	gp.name = nil
+	gp.profscope = 0
+	if gp.group != nil {
+		groupExit(gp)
+	}
+	if gp.deadline != nil {
+		clearGoroutineDeadline(gp)
+	}
+	gp.cgoCalls = 0
+	gp.cgoTicks = 0
 	gp.timer = nil
 
 	if gcBlackenEnabled != 0 && gp.gcAssistBytes > 0 {
@@ -3618,6 +4375,12 @@ func reentersyscall(pc, sp uintptr) {
 	save(pc, sp)
 	_g_.syscallsp = sp
 	_g_.syscallpc = pc
+	if !_g_.m.incgo && atomic.Load64(&offcpuprofilerate) != 0 {
+		_g_.offcpuwhen = nanotime()
+	}
+	if _g_.groupwhen != 0 {
+		groupStop(_g_)
+	}
 	casgstatus(_g_, _Grunning, _Gsyscall)
 	if _g_.syscallsp < _g_.stack.lo || _g_.stack.hi < _g_.syscallsp {
 		systemstack(func() {
@@ -3715,6 +4478,12 @@ func entersyscallblock() {
 	save(pc, sp)
 	_g_.syscallsp = _g_.sched.sp
 	_g_.syscallpc = _g_.sched.pc
+	if atomic.Load64(&offcpuprofilerate) != 0 {
+		_g_.offcpuwhen = nanotime()
+	}
+	if _g_.groupwhen != 0 {
+		groupStop(_g_)
+	}
 	if _g_.syscallsp < _g_.stack.lo || _g_.stack.hi < _g_.syscallsp {
 		sp1 := sp
 		sp2 := _g_.sched.sp
@@ -3771,7 +4540,7 @@ func exitsyscall() {
 	_g_.waitsince = 0
 	oldp := _g_.m.oldp.ptr()
 	_g_.m.oldp = 0
-	if exitsyscallfast(oldp) {
+	if exitsyscallfast(oldp) || exitsyscallspin(oldp) {
 		if trace.enabled {
 			if oldp != _g_.m.p.ptr() || _g_.m.syscalltick != _g_.m.p.ptr().syscalltick {
 				systemstack(traceGoStart)
@@ -3794,6 +4563,19 @@ func exitsyscall() {
 			_g_.stackguard0 = _g_.stack.lo + _StackGuard
 		}
 		_g_.throwsplit = false
+		if _g_.offcpuwhen != 0 {
+			exitsyscalloffcpu(_g_)
+		}
+		if _g_.group != nil {
+			_g_.groupwhen = nanotime()
+		}
+		if _g_.m.watchgen != atomic.Load(&watchpoints.gen) {
+			// Watchpoints changed during the call, and this M does
+			// not go through execute.
+			systemstack(func() {
+				setThreadWatchpoints(getg().m)
+			})
+		}
 
 		if sched.disable.user && !schedEnabled(_g_) {
 			// Scheduling of this goroutine is disabled.
@@ -3831,6 +4613,82 @@ func exitsyscall() {
 	_g_.syscallsp = 0
 	_g_.m.p.ptr().syscalltick++
 	_g_.throwsplit = false
+	if _g_.offcpuwhen != 0 {
+		exitsyscalloffcpu(_g_)
+	}
+}
+
+// Tuning of the return from system calls, set by
+// runtime/debug.SetSyscallTuning. All are accessed atomically.
+var (
+	// syscallReservePs is the number of idle Ps that wakep leaves
+	// for Ms returning from system calls, or 0.
+	syscallReservePs uint32
+
+	// syscallPreferOldP is non-zero if an M returning from a system
+	// call takes back its old P from the idle list rather than any
+	// idle P.
+	syscallPreferOldP uint32
+
+	// syscallSpinNs is how long exitsyscall retries to get a P before
+	// it parks the M.
+	syscallSpinNs uint64
+)
+
+// Statistics of the returns from system calls that could not simply
+// take back their P, reported by runtime/metrics. Accessed atomically.
+var syscallExitStats struct {
+	idleP   uint64 // got an idle P other than their old one
+	retaken uint64 // got their old P back from the idle list
+	parked  uint64 // parked their M
+}
+
+//go:linkname setSyscallTuning runtime/debug.setSyscallTuning
+func setSyscallTuning(reservePs int, preferOldP bool, spinNs int64) (prevReservePs int, prevPreferOldP bool, prevSpinNs int64) {
+	if reservePs < 0 {
+		reservePs = 0
+	}
+	if spinNs < 0 {
+		spinNs = 0
+	}
+	var prefer uint32
+	if preferOldP {
+		prefer = 1
+	}
+	prevReservePs = int(atomic.Xchg(&syscallReservePs, uint32(reservePs)))
+	prevPreferOldP = atomic.Xchg(&syscallPreferOldP, prefer) != 0
+	prevSpinNs = int64(atomic.Xchg64(&syscallSpinNs, uint64(spinNs)))
+	return
+}
+
+// exitsyscallspin retries exitsyscallfast for up to syscallSpinNs, in
+// case a P becomes idle shortly, before exitsyscall parks the M.
+//
+//go:nosplit
+func exitsyscallspin(oldp *p) bool {
+	spin := int64(atomic.Load64(&syscallSpinNs))
+	if spin == 0 {
+		return false
+	}
+	end := nanotime() + spin
+	for nanotime() < end {
+		procyield(active_spin_cnt)
+		if exitsyscallfast(oldp) {
+			return true
+		}
+	}
+	return false
+}
+
+// exitsyscalloffcpu records the system call gp just returned from in
+// the off-CPU profile. exitsyscall has wired a P by the time it calls
+// this, so write barriers are allowed again.
+//
+//go:yeswritebarrierrec
+func exitsyscalloffcpu(gp *g) {
+	ns := nanotime() - gp.offcpuwhen
+	gp.offcpuwhen = 0
+	offcpuevent(ns, waitReasonZero, 3)
 }
 
 //go:nosplit
@@ -3842,8 +4700,9 @@ func exitsyscallfast(oldp *p) bool {
 		return false
 	}
 
-	// Try to re-acquire the last P.
-	if oldp != nil && oldp.status == _Psyscall && atomic.Cas(&oldp.status, _Psyscall, _Pidle) {
+	// Try to re-acquire the last P, unless it is above the
+	// limit and so must be parked instead.
+	if oldp != nil && oldp.status == _Psyscall && oldp.id < sched.pactive && atomic.Cas(&oldp.status, _Psyscall, _Pidle) {
 		// There's a cpu for us, so we can run.
 		wirep(oldp)
 		exitsyscallfast_reacquired()
@@ -3854,7 +4713,7 @@ func exitsyscallfast(oldp *p) bool {
 	if sched.pidle != 0 {
 		var ok bool
 		systemstack(func() {
-			ok = exitsyscallfast_pidle()
+			ok = exitsyscallfast_pidle(oldp)
 			if ok && trace.enabled {
 				if oldp != nil {
 					// Wait till traceGoSysBlock event is emitted.
@@ -3896,9 +4755,15 @@ func exitsyscallfast_reacquired() {
 	}
 }
 
-func exitsyscallfast_pidle() bool {
+func exitsyscallfast_pidle(oldp *p) bool {
 	lock(&sched.lock)
-	_p_ := pidleget()
+	var _p_ *p
+	if oldp != nil && atomic.Load(&syscallPreferOldP) != 0 && pidletake(oldp) {
+		_p_ = oldp
+		atomic.Xadd64(&syscallExitStats.retaken, 1)
+	} else if _p_ = pidleget(); _p_ != nil {
+		atomic.Xadd64(&syscallExitStats.idleP, 1)
+	}
 	if _p_ != nil && atomic.Load(&sched.sysmonwait) != 0 {
 		atomic.Store(&sched.sysmonwait, 0)
 		notewakeup(&sched.sysmonnote)
@@ -3927,6 +4792,7 @@ func exitsyscall0(gp *g) {
 	}
 	if _p_ == nil {
 		globrunqput(gp)
+		atomic.Xadd64(&syscallExitStats.parked, 1)
 	} else if atomic.Load(&sched.sysmonwait) != 0 {
 		atomic.Store(&sched.sysmonwait, 0)
 		notewakeup(&sched.sysmonnote)
@@ -4172,16 +5038,33 @@ func newproc1(fn *funcval, argp unsafe.Pointer, narg int32, callergp *g, callerp
 	newg.sched.g = guintptr(unsafe.Pointer(newg))
 	gostartcallfn(&newg.sched, fn)
 	newg.gopc = callerpc
+	newg.stackMove = callergp.stackMove
+	newg.parentGoid = callergp.goid
+	newg.lineage[0] = lineageEntry{callergp.parentGoid, callergp.gopc}
+	copy(newg.lineage[1:], callergp.lineage[:])
 	newg.ancestors = saveAncestors(callergp)
 	newg.startpc = fn.fn
 	if _g_.m.curg != nil {
 		newg.labels = _g_.m.curg.labels
+		newg.profscope = _g_.m.curg.profscope
+		newg.group = _g_.m.curg.group
 	}
 	if isSystemGoroutine(newg, false) {
 		atomic.Xadd(&sched.ngsys, +1)
+		newg.group = nil
+	} else {
+		counterGoroutinesCreated.add(1)
 	}
-	casgstatus(newg, _Gdead, _Grunnable)
-
+	if newg.group != nil {
+		atomic.Xaddint64(&newg.group.goroutines, 1)
+	}
+	if goroutineProfile.active {
+		// The active goroutine profile doesn't want newg: it started
+		// after the profile did.
+		atomic.Store(&newg.profiled, goroutineProfileSatisfied)
+	}
+	// Assign the goroutine ID before it becomes runnable, so that
+	// the transition is recorded with it by the status trace.
 	if _p_.goidcache == _p_.goidcacheend {
 		// Sched.goidgen is the last allocated id,
 		// this batch must be [sched.goidgen+1, sched.goidgen+GoidCacheBatch].
@@ -4192,6 +5075,8 @@ func newproc1(fn *funcval, argp unsafe.Pointer, narg int32, callergp *g, callerp
 	}
 	newg.goid = int64(_p_.goidcache)
 	_p_.goidcache++
+	casgstatus(newg, _Gdead, _Grunnable)
+
 	if raceenabled {
 		newg.racectx = racegostart(callerpc)
 	}
@@ -4257,43 +5142,23 @@ func gfput(_p_ *p, gp *g) {
 	_p_.gFree.push(gp)
 	_p_.gFree.n++
 	if _p_.gFree.n >= 64 {
-		lock(&sched.gFree.lock)
+		s := gFreeShardOf(_p_)
+		s.lock.lock()
 		for _p_.gFree.n >= 32 {
 			_p_.gFree.n--
-			gp = _p_.gFree.pop()
-			if gp.stack.lo == 0 {
-				sched.gFree.noStack.push(gp)
-			} else {
-				sched.gFree.stack.push(gp)
-			}
-			sched.gFree.n++
+			s.put(_p_.gFree.pop())
 		}
-		unlock(&sched.gFree.lock)
+		atomic.Store64(&s.spills, s.spills+1)
+		s.lock.unlock()
 	}
 }
 
 // Get from gfree list.
 // If local list is empty, grab a batch from global list.
 func gfget(_p_ *p) *g {
-retry:
-	if _p_.gFree.empty() && (!sched.gFree.stack.empty() || !sched.gFree.noStack.empty()) {
-		lock(&sched.gFree.lock)
+	if _p_.gFree.empty() {
 		// Move a batch of free Gs to the P.
-		for _p_.gFree.n < 32 {
-			// Prefer Gs with stacks.
-			gp := sched.gFree.stack.pop()
-			if gp == nil {
-				gp = sched.gFree.noStack.pop()
-				if gp == nil {
-					break
-				}
-			}
-			sched.gFree.n--
-			_p_.gFree.push(gp)
-			_p_.gFree.n++
-		}
-		unlock(&sched.gFree.lock)
-		goto retry
+		gFreeRefill(_p_)
 	}
 	gp := _p_.gFree.pop()
 	if gp == nil {
@@ -4319,18 +5184,46 @@ retry:
 
 // Purge all cached G's from gfree list to the global list.
 func gfpurge(_p_ *p) {
-	lock(&sched.gFree.lock)
+	s := gFreeShardOf(_p_)
+	s.lock.lock()
 	for !_p_.gFree.empty() {
-		gp := _p_.gFree.pop()
 		_p_.gFree.n--
-		if gp.stack.lo == 0 {
-			sched.gFree.noStack.push(gp)
-		} else {
-			sched.gFree.stack.push(gp)
-		}
-		sched.gFree.n++
+		s.put(_p_.gFree.pop())
+	}
+	s.lock.unlock()
+}
+
+// prewarmGoroutines adds n dead Gs with stacks of stackSize bytes to
+// the global free list, so that gfget can hand them out without
+// allocating.
+//go:linkname prewarmGoroutines runtime/debug.prewarmGoroutines
+func prewarmGoroutines(n, stackSize int) {
+	if n <= 0 {
+		return
+	}
+	if stackSize < _StackMin {
+		stackSize = _StackMin
+	}
+	if uintptr(stackSize) > maxstacksize {
+		stackSize = int(maxstacksize)
+	}
+	if stackSize > 1<<30 {
+		stackSize = 1 << 30
+	}
+	var list gList
+	for i := 0; i < n; i++ {
+		gp := malg(int32(stackSize))
+		casgstatus(gp, _Gidle, _Gdead)
+		allgadd(gp)
+		list.push(gp)
+	}
+	// Spread the Gs over the shards, for all Ps to find.
+	for i := 0; !list.empty(); i++ {
+		s := &gFreeCentral[i%gFreeShards].shard
+		s.lock.lock()
+		s.put(list.pop())
+		s.lock.unlock()
 	}
-	unlock(&sched.gFree.lock)
 }
 
 // Breakpoint executes a breakpoint trap.
@@ -4338,6 +5231,61 @@ func Breakpoint() {
 	breakpoint()
 }
 
+// BreakpointIf executes a breakpoint trap if a debugger is attached
+// to the process and pred is nil or returns true. Otherwise it does
+// nothing, and does not call pred, so unlike Breakpoint it is safe to
+// leave in programs run without a debugger.
+//
+// The runtime only knows of debuggers that tell it they are attached,
+// by calling runtime.debugSetAttached through the debugger function
+// call protocol.
+func BreakpointIf(pred func() bool) {
+	if atomic.Load(&debuggerAttached) == 0 {
+		return
+	}
+	if pred != nil && !pred() {
+		return
+	}
+	breakpoint()
+}
+
+// debuggerAttached is set while a debugger is attached. Accessed
+// atomically.
+var debuggerAttached uint32
+
+// debugSetAttached records whether a debugger is attached. Debuggers
+// call it through the debugger function call protocol (see
+// debugCallV2) with true after attaching and with false before
+// detaching.
+func debugSetAttached(attached bool) {
+	v := uint32(0)
+	if attached {
+		v = 1
+	}
+	atomic.Store(&debuggerAttached, v)
+}
+
+// SetGoroutineName names the calling goroutine, for people reading
+// its stack traces, goroutine profiles and execution traces. An empty
+// name removes the name. A goroutine starts without a name, even if
+// the goroutine that created it has one.
+func SetGoroutineName(name string) {
+	gp := getg()
+	if name == "" {
+		gp.name = nil
+	} else {
+		// Store a pointer to a new string rather than the string
+		// itself, so that tracebacks taken while gp runs never
+		// see half of an update.
+		p := new(string)
+		*p = name
+		gp.name = p
+	}
+	if trace.enabled {
+		traceGoName(gp, name)
+	}
+}
+
 // dolockOSThread is called by LockOSThread and lockOSThread below
 // after they modify m.locked. Do not allow preemption during this call,
 // or else the m might be different in this function than in the caller.
@@ -4443,7 +5391,7 @@ func badunlockosthread() {
 }
 
 func gcount() int32 {
-	n := int32(atomic.Loaduintptr(&allglen)) - sched.gFree.n - int32(atomic.Load(&sched.ngsys))
+	n := int32(atomic.Loaduintptr(&allglen)) - gFreeCount() - int32(atomic.Load(&gRetired.pending)) - int32(atomic.Load(&sched.ngsys))
 	for _, _p_ := range allp {
 		n -= _p_.gFree.n
 	}
@@ -4463,6 +5411,10 @@ func mcount() int32 {
 var prof struct {
 	signalLock uint32
 	hz         int32
+
+	// scope is the ID of the active profiling scope, or 0.
+	// See runtime_setProfScope.
+	scope uint32
 }
 
 func _System()                    { _System() }
@@ -4471,6 +5423,7 @@ func _LostExternalCode()          { _LostExternalCode() }
 func _GC()                        { _GC() }
 func _LostSIGPROFDuringAtomic64() { _LostSIGPROFDuringAtomic64() }
 func _VDSO()                      { _VDSO() }
+func _ProfilerOverhead()          { _ProfilerOverhead() }
 
 // Called if we receive a SIGPROF signal.
 // Called by the signal handler, may run during STW.
@@ -4487,6 +5440,12 @@ func sigprof(pc, sp, lr uintptr, gp *g, mp *m) {
 		return
 	}
 
+	// While a profiling scope is active, only goroutines in the
+	// scope are sampled.
+	if s := atomic.Load(&prof.scope); s != 0 && (mp == nil || mp.curg == nil || mp.curg.profscope != s) {
+		return
+	}
+
 	// On mips{,le}/arm, 64bit atomics are emulated with spinlocks, in
 	// runtime/internal/atomic. If SIGPROF arrives while the program is inside
 	// the critical section, it creates a deadlock (when writing the sample).
@@ -4509,6 +5468,9 @@ func sigprof(pc, sp, lr uintptr, gp *g, mp *m) {
 		}
 	}
 
+	// Time the sample, so the profile can report its own overhead.
+	t0 := nanotime()
+
 	// Profiling runs concurrently with GC, so it must not allocate.
 	// Set a trap in case the code does allocate.
 	// Note that on windows, one thread takes profiles of all the
@@ -4643,7 +5605,7 @@ func sigprof(pc, sp, lr uintptr, gp *g, mp *m) {
 	}
 
 	if prof.hz != 0 {
-		cpuprof.add(gp, stk[:n])
+		cpuprof.add(gp, stk[:n], nanotime()-t0)
 	}
 	getg().m.mallocing--
 }
@@ -4756,7 +5718,7 @@ func (pp *p) init(id int32) {
 	pp.status = _Pgcstop
 	pp.sudogcache = pp.sudogbuf[:0]
 	for i := range pp.deferpool {
-		pp.deferpool[i] = pp.deferpoolbuf[i][:0]
+		pp.deferpool[i] = deferPoolBuf(pp, i)
 	}
 	pp.wbBuf.reset()
 	if pp.mcache == nil {
@@ -4813,8 +5775,9 @@ func (pp *p) destroy() {
 		plocal := getg().m.p.ptr()
 		// The world is stopped, but we acquire timersLock to
 		// protect against sysmon calling timeSleepUntil.
-		// This is the only case where we hold the timersLock of
-		// more than one P, so there are no deadlock concerns.
+		// adoptParkedTimers, the only other place that holds
+		// the timersLock of two Ps, cannot run meanwhile, so
+		// there are no deadlock concerns.
 		lock(&plocal.timersLock)
 		lock(&pp.timersLock)
 		moveTimers(plocal, pp.timers)
@@ -4879,6 +5842,9 @@ func (pp *p) destroy() {
 
 // Change number of processors.
 //
+// The Ps above nprocs, which GOMAXPROCS may have parked without
+// stopping the world (see procpark.go), are destroyed here.
+//
 // sched.lock must be held, and the world must be stopped.
 //
 // gcworkbufs must not be being modified by either the GC or the write barrier
@@ -4907,7 +5873,8 @@ func procresize(nprocs int32) *p {
 	maskWords := (nprocs + 31) / 32
 
 	// Grow allp if necessary.
-	if nprocs > int32(len(allp)) {
+	oldlen := int32(len(allp))
+	if nprocs > oldlen {
 		// Synchronize with retake, which could be running
 		// concurrently since it doesn't run on a P.
 		lock(&allpLock)
@@ -4935,10 +5902,14 @@ func procresize(nprocs int32) *p {
 			timerpMask = ntimerpMask
 		}
 		unlock(&allpLock)
+
+		if trace.flightBufs > 0 {
+			traceFlightGrow(cap(allp))
+		}
 	}
 
 	// initialize new P's
-	for i := old; i < nprocs; i++ {
+	for i := oldlen; i < nprocs; i++ {
 		pp := allp[i]
 		if pp == nil {
 			pp = new(p)
@@ -4948,16 +5919,13 @@ func procresize(nprocs int32) *p {
 	}
 
 	_g_ := getg()
-	if _g_.m.p != 0 && _g_.m.p.ptr().id < nprocs {
+	active := cpuQuotaLimit(nprocs)
+	if _g_.m.p != 0 && _g_.m.p.ptr().id < active {
 		// continue to use the current P
 		_g_.m.p.ptr().status = _Prunning
 		_g_.m.p.ptr().mcache.prepareForSweep()
 	} else {
 		// release the current P and acquire allp[0].
-		//
-		// We must do this before destroying our current P
-		// because p.destroy itself has write barriers, so we
-		// need to do that from a valid P.
 		if _g_.m.p != 0 {
 			if trace.enabled {
 				// Pretend that we were descheduled
@@ -4982,7 +5950,7 @@ func procresize(nprocs int32) *p {
 	mcache0 = nil
 
 	// release resources from unused P's
-	for i := nprocs; i < old; i++ {
+	for i := nprocs; i < int32(len(allp)); i++ {
 		p := allp[i]
 		p.destroy()
 		// can't free P itself because it can be referenced by an M in syscall
@@ -4997,8 +5965,27 @@ func procresize(nprocs int32) *p {
 		unlock(&allpLock)
 	}
 
+	var int32p *int32 = &gomaxprocs // make compiler check that gomaxprocs is an int32
+	atomic.Store((*uint32)(unsafe.Pointer(int32p)), uint32(nprocs))
+	atomic.Store((*uint32)(unsafe.Pointer(&sched.pactive)), uint32(active))
+
+	// Park the Ps above the limit, leaving their local work to the
+	// others.
+	for i := int32(len(allp)) - 1; i >= active; i-- {
+		p := allp[i]
+		p.status = _Pidle
+		for {
+			gp, _ := runqget(p)
+			if gp == nil {
+				break
+			}
+			globrunqput(gp)
+		}
+		pidleput(p)
+	}
+
 	var runnablePs *p
-	for i := nprocs - 1; i >= 0; i-- {
+	for i := active - 1; i >= 0; i-- {
 		p := allp[i]
 		if _g_.m.p.ptr() == p {
 			continue
@@ -5012,9 +5999,7 @@ func procresize(nprocs int32) *p {
 			runnablePs = p
 		}
 	}
-	stealOrder.reset(uint32(nprocs))
-	var int32p *int32 = &gomaxprocs // make compiler check that gomaxprocs is an int32
-	atomic.Store((*uint32)(unsafe.Pointer(int32p)), uint32(nprocs))
+	stealOrder.reset(uint32(len(allp)))
 	return runnablePs
 }
 
@@ -5148,6 +6133,12 @@ func checkdead() {
 		throw("checkdead: inconsistent counts")
 	}
 
+	// Runnable goroutines may be waiting for an M at the thread limit,
+	// which a goroutine locked to its thread may yet release.
+	if atomic.Load(&threadLimit.waiting) != 0 {
+		return
+	}
+
 	grunning := 0
 	lock(&allglock)
 	for i := 0; i < len(allgs); i++ {
@@ -5205,15 +6196,36 @@ func checkdead() {
 
 	getg().m.throwing = -1 // do not dump full stacks
 	unlock(&sched.lock)    // unlock so that GODEBUG=scheddetail=1 doesn't hang
-	throw("all goroutines are asleep - deadlock!")
+	throwAllAsleep()
 }
 
 // forcegcperiod is the maximum time in nanoseconds between garbage
 // collections. If we go this long without a garbage collection, one
-// is forced to run.
+// is forced to run. If it is negative, collections are never forced.
 //
-// This is a variable for testing purposes. It normally doesn't change.
-var forcegcperiod int64 = 2 * 60 * 1e9
+// Set by runtime/debug.SetForceGCPeriod and for testing purposes.
+// Accessed atomically.
+var forcegcperiod int64 = defaultForcegcperiod
+
+const defaultForcegcperiod = 2 * 60 * 1e9
+
+//go:linkname setForceGCPeriod runtime/debug.setForceGCPeriod
+func setForceGCPeriod(period int64) int64 {
+	if period <= 0 {
+		period = -1
+	}
+	prev := int64(atomic.Xchg64((*uint64)(unsafe.Pointer(&forcegcperiod)), uint64(period)))
+
+	// Wake sysmon if it is sleeping, which it may do for half the
+	// previous period, so that the new period takes effect.
+	lock(&sched.lock)
+	if sched.sysmonwait != 0 {
+		sched.sysmonwait = 0
+		notewakeup(&sched.sysmonnote)
+	}
+	unlock(&sched.lock)
+	return prev
+}
 
 // Always runs without a P, so write barriers are not allowed.
 //
@@ -5229,8 +6241,12 @@ func sysmon() {
 	atomic.Store(&sched.sysmonStarting, 0)
 
 	lasttrace := int64(0)
+	lastdeadlockcheck := int64(0)
+	lastextramcheck := int64(0)
+	lastpressurecheck := int64(0)
 	idle := 0 // how many cycles in succession we had not wokeup somebody
 	delay := uint32(0)
+	timerwake := int64(0) // when a timer becomes overdue, see preemptForTimers, or the CPU quota needs a tick
 
 	for {
 		if idle == 0 { // start with 20us sleep...
@@ -5241,7 +6257,16 @@ func sysmon() {
 		if delay > 10*1000 { // up to 10ms
 			delay = 10 * 1000
 		}
-		usleep(delay)
+		sleep := delay
+		if timerwake != 0 {
+			if d := (timerwake - nanotime()) / 1000; d < int64(sleep) {
+				if d < 20 {
+					d = 20
+				}
+				sleep = uint32(d)
+			}
+		}
+		usleep(sleep)
 		mDoFixup()
 
 		// sysmon should not enter deep sleep if schedtrace is enabled so that
@@ -5260,25 +6285,44 @@ func sysmon() {
 		// from a timer to avoid adding system load to applications that spend
 		// most of their time sleeping.
 		now := nanotime()
-		if debug.schedtrace <= 0 && (sched.gcwaiting != 0 || atomic.Load(&sched.npidle) == uint32(gomaxprocs)) {
+		if debug.schedtrace <= 0 && (sched.gcwaiting != 0 || procsIdle()) && !threadWarningBusy() {
+			if procsIdle() {
+				// Nothing runs while sysmon sleeps.
+				cpuQuotaIdle()
+			}
 			lock(&sched.lock)
-			if atomic.Load(&sched.gcwaiting) != 0 || atomic.Load(&sched.npidle) == uint32(gomaxprocs) {
+			if (atomic.Load(&sched.gcwaiting) != 0 || procsIdle()) && !threadWarningBusy() {
 				syscallWake := false
 				next, _ := timeSleepUntil()
+				if d := goroutineDeadlineNext(); d != 0 && d < next {
+					next = d
+				}
 				if next > now {
 					atomic.Store(&sched.sysmonwait, 1)
 					unlock(&sched.lock)
 					// Make wake-up period small enough
 					// for the sampling to be correct.
-					sleep := forcegcperiod / 2
+					sleep := atomic.Loadint64(&forcegcperiod) / 2
+					if sleep < 0 {
+						sleep = defaultForcegcperiod / 2
+					}
+					if d := deepIdleSysmonSleep(now); d > sleep {
+						sleep = d
+					}
 					if next-now < sleep {
 						sleep = next - now
 					}
+					sleep = deepIdleDeadline(now+sleep) - now
 					shouldRelax := sleep >= osRelaxMinNS
 					if shouldRelax {
 						osRelax(true)
 					}
+					atomic.Store64(&sysmonSleepUntil, uint64(now+sleep))
 					syscallWake = notetsleep(&sched.sysmonnote, sleep)
+					atomic.Store64(&sysmonSleepUntil, 0)
+					if !syscallWake {
+						atomic.Xadd64(&idleSysmonWakeups, 1)
+					}
 					mDoFixup()
 					if shouldRelax {
 						osRelax(false)
@@ -5354,8 +6398,19 @@ func sysmon() {
 		} else {
 			idle++
 		}
+		timerwake = 0
+		if atomic.Load(&sched.npidle) == 0 {
+			var preempted bool
+			timerwake, preempted = preemptForTimers(now)
+			if preempted {
+				idle = 0
+			}
+		}
+		if w := cpuQuotaTick(now); w != 0 && (timerwake == 0 || w < timerwake) {
+			timerwake = w
+		}
 		// check if we need to force a GC
-		if t := (gcTrigger{kind: gcTriggerTime, now: now}); t.test() && atomic.Load(&forcegc.idle) != 0 {
+		if t := (gcTrigger{kind: gcTriggerTime, now: now}); !detsched.enabled && t.test() && atomic.Load(&forcegc.idle) != 0 {
 			lock(&forcegc.lock)
 			forcegc.idle = 0
 			var list gList
@@ -5365,8 +6420,28 @@ func sysmon() {
 		}
 		if debug.schedtrace > 0 && lasttrace+int64(debug.schedtrace)*1000000 <= now {
 			lasttrace = now
-			schedtrace(debug.scheddetail > 0)
+			if debug.schedtracejson > 0 {
+				schedtraceJSON()
+			} else {
+				schedtrace(debug.scheddetail > 0)
+			}
+		}
+		if debug.deadlockdetect > 0 && lastdeadlockcheck+deadlockCheckPeriod <= now {
+			lastdeadlockcheck = now
+			checkPartialDeadlock()
+		}
+		if cgoHasExtraM && (atomic.Load64(&extraMIdleTimeout) != 0 || atomic.Load(&extraMMax) != 0) && lastextramcheck+extraMCheckPeriod <= now {
+			lastextramcheck = now
+			reclaimExtraMs(now)
 		}
+		if debug.memorypressure > 0 && lastpressurecheck+memPressureCheckPeriod <= now {
+			lastpressurecheck = now
+			checkMemoryPressure(now)
+		}
+		checkGoroutineDeadlines(now)
+		checkOutOfMemory()
+		checkThreadWarning()
+		retryDeferredThreads()
 		unlock(&sched.sysmonlock)
 	}
 }
@@ -5376,6 +6451,15 @@ type sysmontick struct {
 	schedwhen   int64
 	syscalltick uint32
 	syscallwhen int64
+	syscallreal int64 // real time at which retake saw the syscall, under GODEBUG=detsched
+
+	// timertick is 1 more than the schedtick at which
+	// preemptForTimers last preempted the P, or 0 if it never did.
+	timertick uint32
+
+	// Keep the size a multiple of 8 so that p.timer0When stays
+	// 8-byte aligned on 32-bit systems.
+	_ uint32
 }
 
 // forcePreemptNS is the time slice given to a G before it is
@@ -5406,25 +6490,42 @@ func retake(now int64) uint32 {
 			if int64(pd.schedtick) != t {
 				pd.schedtick = uint32(t)
 				pd.schedwhen = now
-			} else if pd.schedwhen+forcePreemptNS <= now {
+			} else if pd.schedwhen+forcePreemptNS <= now && !detsched.enabled {
 				preemptone(_p_)
 				// In case of syscall, preemptone() doesn't
 				// work, because there is no M wired to P.
 				sysretake = true
 			}
 		}
+		beyond := _p_.id >= sched.pactive
+		if s == _Prunning && beyond {
+			// Ask a P above the limit to park.
+			preemptone(_p_)
+		}
 		if s == _Psyscall {
 			// Retake P from syscall if it's there for more than 1 sysmon tick (at least 20us).
 			t := int64(_p_.syscalltick)
 			if !sysretake && int64(pd.syscalltick) != t {
 				pd.syscalltick = uint32(t)
 				pd.syscallwhen = now
+				pd.syscallreal = 0
 				continue
 			}
+			// With deterministic scheduling, the clock may not move,
+			// and when the P is retaken depends on the outside world,
+			// so only retake it from long system calls.
+			if detsched.enabled {
+				if pd.syscallreal == 0 {
+					pd.syscallreal = nanotime1()
+				}
+				if nanotime1()-pd.syscallreal < detschedSyscallNS {
+					continue
+				}
+			}
 			// On the one hand we don't want to retake Ps if there is no other work to do,
 			// but on the other hand we want to retake them eventually
 			// because they can prevent the sysmon thread from deep sleep.
-			if runqempty(_p_) && atomic.Load(&sched.nmspinning)+atomic.Load(&sched.npidle) > 0 && pd.syscallwhen+10*1000*1000 > now {
+			if !beyond && runqempty(_p_) && atomic.Load(&sched.nmspinning)+atomic.Load(&sched.npidle) > 0 && pd.syscallwhen+10*1000*1000 > now {
 				continue
 			}
 			// Drop allpLock so we can take sched.lock.
@@ -5506,6 +6607,59 @@ func preemptone(_p_ *p) bool {
 	return true
 }
 
+// timerOverdueNS is how late a timer may be before sysmon preempts the
+// goroutine running on its P so that the timer runs.
+const timerOverdueNS = 1000 * 1000 // 1ms
+
+// preemptForTimers preempts the goroutine running on the P with the
+// earliest timer if that timer is overdue. Timers only run when their
+// P schedules, or when an idle P steals them, so with every P busy
+// running a goroutine they can be late by up to forcePreemptNS. The
+// preempted P runs its timers when it next schedules. A P is preempted
+// at most once per scheduling round, since its goroutine may not stop
+// at once. It is called by sysmon when no P is idle.
+//
+// preemptForTimers returns the time at which the earliest timer will
+// be overdue, for sysmon to check again, or 0 if there is no timer or
+// it is overdue already, and whether it preempted a goroutine.
+func preemptForTimers(now int64) (wake int64, preempted bool) {
+	next, pp := timeSleepUntil()
+	if pp == nil || next > maxWhen-timerOverdueNS {
+		return 0, false
+	}
+	if now-next < timerOverdueNS {
+		return next + timerOverdueNS, false
+	}
+	// Prevent allp slice changes. This is like retake.
+	lock(&allpLock)
+	pd := &pp.sysmontick
+	if s := pp.status; s == _Prunning && pd.timertick != pp.schedtick+1 {
+		pd.timertick = pp.schedtick + 1
+		if preemptone(pp) {
+			atomic.Xadd64(&timerPreempts, 1)
+			preempted = true
+		}
+	}
+	unlock(&allpLock)
+	return 0, preempted
+}
+
+// wakeSysmonForTimer wakes sysmon from a deep sleep that lasts until
+// after a timer due at when is overdue. sysmon sleeps deeply while all
+// Ps are idle, until the earliest timer it found, and then does not
+// preempt for a timer added later by a P that has become busy.
+func wakeSysmonForTimer(when int64) {
+	if s := int64(atomic.Load64(&sysmonSleepUntil)); s == 0 || s-timerOverdueNS <= when {
+		return
+	}
+	lock(&sched.lock)
+	if atomic.Load(&sched.sysmonwait) != 0 {
+		atomic.Store(&sched.sysmonwait, 0)
+		notewakeup(&sched.sysmonnote)
+	}
+	unlock(&sched.lock)
+}
+
 var starttime int64
 
 func schedtrace(detailed bool) {
@@ -5589,6 +6743,28 @@ func schedtrace(detailed bool) {
 	unlock(&sched.lock)
 }
 
+// schedtraceJSON prints the scheduler summary of schedtrace as a line
+// of JSON, for GODEBUG=schedtracejson=1.
+func schedtraceJSON() {
+	now := nanotime()
+	if starttime == 0 {
+		starttime = now
+	}
+
+	lock(&sched.lock)
+	print(`{"time_ms":`, (now-starttime)/1e6, `,"gomaxprocs":`, gomaxprocs, `,"idleprocs":`, sched.npidle, `,"threads":`, mcount(), `,"spinningthreads":`, sched.nmspinning, `,"idlethreads":`, sched.nmidle, `,"runqueue":`, sched.runqsize, `,"runqs":[`)
+	for i, _p_ := range allp {
+		if i > 0 {
+			print(",")
+		}
+		h := atomic.Load(&_p_.runqhead)
+		t := atomic.Load(&_p_.runqtail)
+		print(t - h)
+	}
+	print("]}\n")
+	unlock(&sched.lock)
+}
+
 // schedEnableUser enables or disables the scheduling of user
 // goroutines.
 //
@@ -5596,12 +6772,25 @@ func schedtrace(detailed bool) {
 // should first stop the world when disabling user goroutines.
 func schedEnableUser(enable bool) {
 	lock(&sched.lock)
-	if sched.disable.user == !enable {
+	sched.disable.gc = !enable
+	schedUpdateUser()
+}
+
+// schedUpdateUser sets sched.disable.user from the reasons scheduling
+// of user goroutines may be disabled, and if it is now enabled, makes
+// the pending runnable goroutines runnable again.
+//
+// sched.lock must be held. schedUpdateUser releases it.
+func schedUpdateUser() {
+	assertLockHeld(&sched.lock)
+
+	disable := sched.disable.gc || sched.disable.quiescer != 0
+	if sched.disable.user == disable {
 		unlock(&sched.lock)
 		return
 	}
-	sched.disable.user = !enable
-	if enable {
+	sched.disable.user = disable
+	if !disable {
 		n := sched.disable.n
 		sched.disable.n = 0
 		globrunqputbatch(&sched.disable.runnable, n)
@@ -5622,7 +6811,7 @@ func schedEnabled(gp *g) bool {
 	assertLockHeld(&sched.lock)
 
 	if sched.disable.user {
-		return isSystemGoroutine(gp, true)
+		return isSystemGoroutine(gp, true) || !sched.disable.gc && gp == sched.disable.quiescer.ptr()
 	}
 	return true
 }
@@ -5642,6 +6831,24 @@ func mput(mp *m) {
 	checkdead()
 }
 
+// mremove removes mp from the midle list, and reports whether it
+// was there.
+// sched.lock must be held.
+// May run during STW, so write barriers are not allowed.
+//go:nowritebarrierrec
+func mremove(mp *m) bool {
+	assertLockHeld(&sched.lock)
+
+	for pp := &sched.midle; pp.ptr() != nil; pp = &pp.ptr().schedlink {
+		if pp.ptr() == mp {
+			*pp = mp.schedlink
+			sched.nmidle--
+			return true
+		}
+	}
+	return false
+}
+
 // Try to get an m from midle list.
 // sched.lock must be held.
 // May run during STW, so write barriers are not allowed.
@@ -5716,6 +6923,9 @@ func globrunqget(_p_ *p, max int32) *g {
 	}
 
 	sched.runqsize -= n // 注释：全局队列个数减少n
+	st := &_p_.stealstats
+	atomic.Store64(&st.globalGrabs, st.globalGrabs+1)
+	atomic.Store64(&st.globalGs, st.globalGs+uint64(n))
 
 	gp := sched.runq.pop() // 注释：全局G队列出栈1个（准备执行，其余的放到本地队列里面）
 	n--
@@ -5808,8 +7018,13 @@ func pidleput(_p_ *p) {
 	if !runqempty(_p_) {
 		throw("pidleput: P has non-empty run queue")
 	}
-	updateTimerPMask(_p_)         // clear if there are no timers. // 注释：把p的id从定时器掩码中移除
-	idlepMask.set(_p_.id)         // 注释：设置空闲p的掩码(空闲的标记)，把p的id放在空闲p里
+	updateTimerPMask(_p_) // clear if there are no timers. // 注释：把p的id从定时器掩码中移除
+	idlepMask.set(_p_.id) // 注释：设置空闲p的掩码(空闲的标记)，把p的id放在空闲p里
+	if _p_.id >= sched.pactive {
+		// Above the limit; see procpark.go.
+		pparkput(_p_)
+		return
+	}
 	_p_.link = sched.pidle        // 注释：在链表的头部压入一个
 	sched.pidle.set(_p_)          // 注释：设置链表头部（把刚刚压入的那个链接上）
 	atomic.Xadd(&sched.npidle, 1) // TODO: fast atomic // 注释：原子操作，空闲p计数加一
@@ -5836,6 +7051,32 @@ func pidleget() *p {
 	return _p_
 }
 
+// pidletake removes _p_ from the _Pidle list, if it is there, acquiring
+// ownership, and reports whether it did.
+//
+// sched.lock must be held.
+//
+// May run during STW, so write barriers are not allowed.
+//go:nowritebarrierrec
+func pidletake(_p_ *p) bool {
+	assertLockHeld(&sched.lock)
+
+	if !idlepMask.read(uint32(_p_.id)) {
+		return false
+	}
+	for pp := &sched.pidle; pp.ptr() != nil; pp = &pp.ptr().link {
+		if pp.ptr() == _p_ {
+			// Timer may get added at any time now.
+			timerpMask.set(_p_.id)
+			idlepMask.clear(_p_.id)
+			*pp = _p_.link
+			atomic.Xadd(&sched.npidle, -1)
+			return true
+		}
+	}
+	return false
+}
+
 // runqempty reports whether _p_ has no Gs on its local run queue.
 // It never returns true spuriously.
 // 注释：本地的g运行队列为空时返回true，否则返回false
@@ -6026,10 +7267,11 @@ func runqget(_p_ *p) (gp *g, inheritTime bool) {
 
 // Grabs a batch of goroutines from _p_'s runnable queue into batch.
 // Batch is a ring buffer starting at batchHead.
-// Returns number of grabbed goroutines.
+// Returns number of grabbed goroutines, and whether the one goroutine
+// grabbed was _p_.runnext.
 // Can be executed by any P.
 // 注释：窃取（偷），目前只有窃取函数runqsteal调用此函数。_p_代表其他线程M下P，batch是本地队列,stealRunNextG代表是否是最有一次窃取
-func runqgrab(_p_ *p, batch *[256]guintptr, batchHead uint32, stealRunNextG bool) uint32 {
+func runqgrab(_p_ *p, batch *[256]guintptr, batchHead uint32, stealRunNextG bool) (n uint32, runnext bool) {
 	for {
 		h := atomic.LoadAcq(&_p_.runqhead) // load-acquire, synchronize with other consumers
 		t := atomic.LoadAcq(&_p_.runqtail) // load-acquire, synchronize with the producer
@@ -6064,10 +7306,10 @@ func runqgrab(_p_ *p, batch *[256]guintptr, batchHead uint32, stealRunNextG bool
 						continue
 					}
 					batch[batchHead%uint32(len(batch))] = next // 注释：把P2下一个要运行的G抢过来
-					return 1
+					return 1, true
 				}
 			}
-			return 0
+			return 0, false
 		}
 		if n > uint32(len(_p_.runq)/2) { // read inconsistent h and t
 			continue
@@ -6077,7 +7319,7 @@ func runqgrab(_p_ *p, batch *[256]guintptr, batchHead uint32, stealRunNextG bool
 			batch[(batchHead+i)%uint32(len(batch))] = g // 注释：把取出的G放到batch的尾部,(把偷过来的G放到本地队列P后面)
 		}
 		if atomic.CasRel(&_p_.runqhead, h, h+n) { // cas-release, commits consume // 注释：从新设置P2的队列头部偏移量&_p_.runqhead = h+n
-			return n // 注释：返回窃取（偷）的数量
+			return n, false // 注释：返回窃取（偷）的数量
 		}
 	}
 }
@@ -6088,10 +7330,17 @@ func runqgrab(_p_ *p, batch *[256]guintptr, batchHead uint32, stealRunNextG bool
 // 注释：从P2中窃取（偷）一些G
 func runqsteal(_p_, p2 *p, stealRunNextG bool) *g {
 	t := _p_.runqtail
-	n := runqgrab(p2, &_p_.runq, t, stealRunNextG) // 注释：从P2中窃取（偷）一下，如果P2中队列中没有，则尝试窃取下一个要运行的G（P2.runnext）
+	n, runnext := runqgrab(p2, &_p_.runq, t, stealRunNextG) // 注释：从P2中窃取（偷）一下，如果P2中队列中没有，则尝试窃取下一个要运行的G（P2.runnext）
+	st := &_p_.stealstats
 	if n == 0 {
+		atomic.Store64(&st.failures, st.failures+1)
 		return nil
 	}
+	atomic.Store64(&st.successes, st.successes+1)
+	atomic.Store64(&st.stolen, st.stolen+uint64(n))
+	if runnext {
+		atomic.Store64(&st.runnext, st.runnext+1)
+	}
 	n--
 	gp := _p_.runq[(t+n)%uint32(len(_p_.runq))].ptr() // 注释：取出最后一个（这时候已经窃取（偷）完并且已经放在本地队列里了）
 	if n == 0 {
@@ -6105,6 +7354,38 @@ func runqsteal(_p_, p2 *p, stealRunNextG bool) *g {
 	return gp
 }
 
+// stealStats counts a P's attempts to take goroutines from other Ps
+// and from the global run queue. Only the P's owner updates them, so
+// it does so with atomic stores, and readers use atomic loads.
+type stealStats struct {
+	successes   uint64 // runqsteal calls that stole goroutines
+	failures    uint64 // runqsteal calls that found none to steal
+	stolen      uint64 // goroutines stolen
+	runnext     uint64 // steals that took another P's runnext
+	globalGrabs uint64 // globrunqget calls that took goroutines
+	globalGs    uint64 // goroutines taken from the global run queue
+}
+
+// readStealStats returns the sums of the steal statistics of all Ps,
+// including Ps above GOMAXPROCS that may run again.
+func readStealStats() (s stealStats) {
+	lock(&allpLock)
+	for _, pp := range allp[:cap(allp)] {
+		if pp == nil {
+			continue
+		}
+		st := &pp.stealstats
+		s.successes += atomic.Load64(&st.successes)
+		s.failures += atomic.Load64(&st.failures)
+		s.stolen += atomic.Load64(&st.stolen)
+		s.runnext += atomic.Load64(&st.runnext)
+		s.globalGrabs += atomic.Load64(&st.globalGrabs)
+		s.globalGs += atomic.Load64(&st.globalGs)
+	}
+	unlock(&allpLock)
+	return
+}
+
 // A gQueue is a dequeue of Gs linked through g.schedlink. A G can only
 // be on one gQueue or gList at a time.
 type gQueue struct {
@@ -5,13 +5,16 @@
 package runtime_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"internal/race"
 	"internal/testenv"
 	"math"
 	"net"
+	"path/filepath"
 	"runtime"
 	"runtime/debug"
+	"runtime/metrics"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -220,6 +223,118 @@ func testGoroutineParallelism2(t *testing.T, load, netpoll bool) {
 	}
 }
 
+func TestGOMAXPROCSParked(t *testing.T) {
+	if runtime.GOARCH == "wasm" {
+		t.Skip("wasm has only one P")
+	}
+	// Create 4 Ps, then shrink and grow again within them,
+	// which must not stop the world. A GC would stop it and
+	// destroy the parked Ps.
+	defer debug.SetGCPercent(debug.SetGCPercent(-1))
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	created, _ := runtime.ProcStats()
+	if created < 4 {
+		t.Fatalf("%d Ps created, want at least 4", created)
+	}
+
+	var stop uint32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for atomic.LoadUint32(&stop) == 0 {
+			}
+		}()
+	}
+	defer func() {
+		atomic.StoreUint32(&stop, 1)
+		wg.Wait()
+	}()
+
+	stw := []metrics.Sample{{Name: "/sched/stw/gomaxprocs:seconds"}}
+	pauses := func() (n uint64) {
+		metrics.Read(stw)
+		for _, c := range stw[0].Value.Float64Histogram().Counts {
+			n += c
+		}
+		return
+	}
+	// waitRunning waits until ok reports true for the number of
+	// running Ps.
+	waitRunning := func(procs int, ok func(int) bool) {
+		deadline := time.Now().Add(10 * time.Second)
+		for {
+			_, running := runtime.ProcStats()
+			if ok(running) {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("GOMAXPROCS=%d: %d Ps running", procs, running)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	before := pauses()
+	for i := 0; i < 3; i++ {
+		runtime.GOMAXPROCS(1)
+		waitRunning(1, func(n int) bool { return n <= 1 })
+		runtime.GOMAXPROCS(4)
+		waitRunning(4, func(n int) bool { return n > 1 })
+	}
+	if after := pauses(); after != before {
+		t.Errorf("GOMAXPROCS changes within the created Ps stopped the world %d times", after-before)
+	}
+	if created2, _ := runtime.ProcStats(); created2 != created {
+		t.Errorf("%d Ps created, want %d", created2, created)
+	}
+}
+
+func TestGOMAXPROCSShrinkDuringGC(t *testing.T) {
+	if runtime.GOARCH == "wasm" {
+		t.Skip("wasm has only one P")
+	}
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	var stop uint32
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var sink [][]byte
+			for atomic.LoadUint32(&stop) == 0 {
+				sink = append(sink, make([]byte, 1024))
+				if len(sink) == 1024 {
+					sink = nil
+				}
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for atomic.LoadUint32(&stop) == 0 {
+			runtime.GC()
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		runtime.GOMAXPROCS(1 + i%4)
+		runtime.Gosched()
+	}
+	atomic.StoreUint32(&stop, 1)
+	wg.Wait()
+
+	// The next GC destroys the Ps parked above GOMAXPROCS.
+	runtime.GOMAXPROCS(2)
+	runtime.GC()
+	if created, _ := runtime.ProcStats(); created != 2 {
+		t.Errorf("%d Ps after GC with GOMAXPROCS=2, want 2", created)
+	}
+}
+
 func TestBlockLocked(t *testing.T) {
 	const N = 10
 	c := make(chan bool)
@@ -359,7 +474,7 @@ func TestPreemptionGC(t *testing.T) {
 }
 
 func TestAsyncPreempt(t *testing.T) {
-	if !runtime.PreemptMSupported {
+	if !runtime.PreemptMSupported && !runtime.LoopPreemptChecks {
 		t.Skip("asynchronous preemption not supported on this platform")
 	}
 	output := runTestProg(t, "testprog", "AsyncPreempt")
@@ -369,6 +484,105 @@ func TestAsyncPreempt(t *testing.T) {
 	}
 }
 
+// Test that the loop preemption checks alone can stop goroutines
+// spinning in loops without calls.
+func TestLoopPreempt(t *testing.T) {
+	if !runtime.LoopPreemptChecks {
+		t.Skip("loop preemption checks not enabled")
+	}
+	output := runTestProg(t, "testprog", "AsyncPreempt", "GODEBUG=asyncpreemptoff=1")
+	want := "OK\n"
+	if output != want {
+		t.Fatalf("want %s, got %s\n", want, output)
+	}
+}
+
+func TestGoschedLocal(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+	var ran uint32
+	go func() {
+		atomic.StoreUint32(&ran, 1)
+	}()
+	for i := 0; atomic.LoadUint32(&ran) == 0; i++ {
+		if i == 1000 {
+			t.Fatal("goroutine did not run while yielding")
+		}
+		runtime.GoschedLocal()
+	}
+}
+
+func TestSchedtraceJSON(t *testing.T) {
+	output := runTestProg(t, "testprog", "After1", "GODEBUG=schedtrace=json")
+	lines := 0
+	for _, line := range strings.Split(output, "\n") {
+		if line == "" {
+			continue
+		}
+		var rec struct {
+			TimeMs     int64 `json:"time_ms"`
+			Gomaxprocs int   `json:"gomaxprocs"`
+			Threads    int   `json:"threads"`
+			Runqs      []int `json:"runqs"`
+		}
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("bad line %q: %v\noutput:\n%s", line, err, output)
+		}
+		if rec.Gomaxprocs != runtime.GOMAXPROCS(0) || len(rec.Runqs) != rec.Gomaxprocs || rec.Threads < 1 {
+			t.Errorf("bad record %+v in line %q", rec, line)
+		}
+		lines++
+	}
+	if lines == 0 {
+		t.Fatalf("no schedtrace output")
+	}
+}
+
+func TestDetSched(t *testing.T) {
+	run := func(seed int) string {
+		return runTestProg(t, "testprog", "DetSched", fmt.Sprintf("GODEBUG=detsched=%d", seed))
+	}
+	want := run(1)
+	if !strings.HasPrefix(want, "1 [") || !strings.Contains(want, "h0m0.") {
+		t.Fatalf("want GOMAXPROCS 1 and about an hour of virtual time, got %q", want)
+	}
+	if got := run(1); got != want {
+		t.Fatalf("same seed, different runs:\n%s%s", want, got)
+	}
+	for seed := 2; seed < 6; seed++ {
+		if run(seed) != want {
+			return
+		}
+	}
+	t.Errorf("seeds 1 to 5 all ran goroutines in the same order:\n%s", want)
+}
+
+func TestSchedReplay(t *testing.T) {
+	record := filepath.Join(t.TempDir(), "record")
+	want := runTestProg(t, "testprog", "SchedRecord", "GODEBUG=detsched=1,schedrecord=100000", "SCHEDRECORD="+record)
+	if other := runTestProg(t, "testprog", "DetSched", "GODEBUG=detsched=2"); other == want {
+		t.Fatalf("seeds 1 and 2 ran goroutines in the same order:\n%s", want)
+	}
+	got := runTestProg(t, "testprog", "DetSched", "GODEBUG=detsched=2,schedreplay="+record)
+	if got != want {
+		t.Fatalf("replay of seed 1 with seed 2 ran\n%swant\n%s", got, want)
+	}
+}
+
+func TestSchedSeed(t *testing.T) {
+	for _, godebug := range []string{"schedseed", "detsched"} {
+		run := func(seed int) string {
+			return runTestProg(t, "testprog", "SchedSeed", fmt.Sprintf("GODEBUG=%s=%d", godebug, seed))
+		}
+		want := run(1)
+		if got := run(1); got != want {
+			t.Errorf("%s=1 chose\n%sthen\n%s", godebug, want, got)
+		}
+		if other := run(2); other == want {
+			t.Errorf("%s=1 and %s=2 both chose\n%s", godebug, godebug, want)
+		}
+	}
+}
+
 func TestGCFairness(t *testing.T) {
 	output := runTestProg(t, "testprog", "GCFairness")
 	want := "OK\n"
@@ -417,6 +631,126 @@ func TestNumGoroutine(t *testing.T) {
 	}
 }
 
+func goroutinesBlocked(c chan bool) {
+	<-c
+}
+
+func TestGoroutines(t *testing.T) {
+	const blocked = 10
+	c := make(chan bool)
+	for i := 0; i < blocked; i++ {
+		go goroutinesBlocked(c)
+	}
+	defer close(c)
+
+	var p []runtime.GoroutineInfo
+	for try := 0; ; try++ {
+		n, _ := runtime.Goroutines(nil)
+		p = make([]runtime.GoroutineInfo, n+10)
+		n, ok := runtime.Goroutines(p)
+		if !ok {
+			t.Fatalf("Goroutines reported %d goroutines but did not fill %d records", n, len(p))
+		}
+		p = p[:n]
+		found := 0
+		for _, r := range p[1:] {
+			if r.WaitReason == "chan receive" && runtime.FuncForPC(r.StartPC).Name() == "runtime_test.goroutinesBlocked" {
+				found++
+			}
+		}
+		if found == blocked {
+			break
+		}
+		if try >= 100 {
+			t.Fatalf("found %d blocked goroutines, want %d: %+v", found, blocked, p)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	self := p[0]
+	if self.State != "running" || self.PC != 0 || self.WaitReason != "" {
+		t.Errorf("calling goroutine is %+v, want running", self)
+	}
+	for _, r := range p[1:] {
+		if runtime.FuncForPC(r.StartPC).Name() != "runtime_test.goroutinesBlocked" {
+			continue
+		}
+		if r.State != "waiting" {
+			t.Errorf("blocked goroutine %d is %s, want waiting", r.ID, r.State)
+		}
+		if r.CreatorID != self.ID {
+			t.Errorf("blocked goroutine %d was created by %d, want %d", r.ID, r.CreatorID, self.ID)
+		}
+		if r.PC == 0 {
+			t.Errorf("blocked goroutine %d has no PC", r.ID)
+		}
+	}
+}
+
+func TestGoroutinesWaitSince(t *testing.T) {
+	output := runTestProg(t, "testprog", "WaitSince", "GODEBUG=waitsince=1")
+	if output != "OK\n" {
+		t.Fatalf("want OK, got:\n%s", output)
+	}
+}
+
+func goroutinesAncestorsChild(c chan bool) {
+	<-c
+}
+
+func goroutinesAncestorsParent(c chan bool) {
+	go goroutinesAncestorsChild(c)
+	<-c
+}
+
+func TestGoroutinesAncestors(t *testing.T) {
+	c := make(chan bool)
+	defer close(c)
+	go goroutinesAncestorsParent(c)
+
+	for try := 0; ; try++ {
+		n, _ := runtime.Goroutines(nil)
+		p := make([]runtime.GoroutineInfo, n+10)
+		n, _ = runtime.Goroutines(p)
+		p = p[:n]
+		self := p[0]
+		var parent, child *runtime.GoroutineInfo
+		for i := range p {
+			switch runtime.FuncForPC(p[i].StartPC).Name() {
+			case "runtime_test.goroutinesAncestorsParent":
+				parent = &p[i]
+			case "runtime_test.goroutinesAncestorsChild":
+				child = &p[i]
+			}
+		}
+		if parent == nil || child == nil {
+			if try >= 100 {
+				t.Fatalf("goroutines not found: %+v", p)
+			}
+			time.Sleep(time.Millisecond)
+			continue
+		}
+
+		if child.CreatorID != parent.ID {
+			t.Errorf("child created by %d, want %d", child.CreatorID, parent.ID)
+		}
+		if name := runtime.FuncForPC(child.CreatorPC).Name(); name != "runtime_test.goroutinesAncestorsParent" {
+			t.Errorf("child created at %s, want goroutinesAncestorsParent", name)
+		}
+		a := child.Ancestors[0]
+		if a.ID != self.ID {
+			t.Errorf("child's first ancestor is %d, want %d", a.ID, self.ID)
+		}
+		if name := runtime.FuncForPC(a.PC).Name(); name != "runtime_test.TestGoroutinesAncestors" {
+			t.Errorf("parent created at %s, want TestGoroutinesAncestors", name)
+		}
+		if parent.Ancestors[0].ID != self.CreatorID {
+			t.Errorf("parent's first ancestor is %d, want %d", parent.Ancestors[0].ID, self.CreatorID)
+		}
+		return
+	}
+}
+
 func TestPingPongHog(t *testing.T) {
 	if runtime.GOARCH == "wasm" {
 		t.Skip("no preemption on wasm yet")
@@ -1088,3 +1422,125 @@ func TestBigGOMAXPROCS(t *testing.T) {
 		t.Errorf("output:\n%s\nwanted:\nunknown function: NonexistentTest", output)
 	}
 }
+
+var goroutineGroupSink []byte
+
+func TestGoroutineGroup(t *testing.T) {
+	const (
+		group = 0x5eed_1909
+		n     = 4
+		alloc = 64 << 10
+	)
+	stats := func() runtime.GoroutineGroupStats {
+		for {
+			n, _ := runtime.ReadGoroutineGroupStats(nil)
+			p := make([]runtime.GoroutineGroupStats, n+10)
+			n, ok := runtime.ReadGoroutineGroupStats(p)
+			if !ok {
+				continue
+			}
+			for _, s := range p[:n] {
+				if s.Group == group {
+					return s
+				}
+			}
+			return runtime.GoroutineGroupStats{}
+		}
+	}
+
+	before := stats()
+	running := make(chan bool)
+	release := make(chan bool)
+	done := make(chan bool)
+	go func() {
+		if prev := runtime.SetGoroutineGroup(group); prev != 0 {
+			t.Errorf("SetGoroutineGroup(%#x) = %#x, want 0", group, prev)
+		}
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				goroutineGroupSink = make([]byte, alloc)
+				for start := time.Now(); time.Since(start) < 10*time.Millisecond; {
+				}
+				running <- true
+				<-release
+			}()
+		}
+		wg.Wait()
+		if prev := runtime.SetGoroutineGroup(0); prev != group {
+			t.Errorf("SetGoroutineGroup(0) = %#x, want %#x", prev, group)
+		}
+		done <- true
+	}()
+	for i := 0; i < n; i++ {
+		<-running
+	}
+	s := stats()
+	if s.Goroutines != n+1 {
+		t.Errorf("group has %d goroutines, want %d", s.Goroutines, n+1)
+	}
+	close(release)
+	<-done
+
+	s = stats()
+	if s.Goroutines != 0 {
+		t.Errorf("group has %d goroutines after they exited, want 0", s.Goroutines)
+	}
+	if cpu := s.CPUTime - before.CPUTime; cpu <= 0 {
+		t.Errorf("group used %v of CPU time, want more", time.Duration(cpu))
+	}
+	bytes, objects := s.AllocBytes-before.AllocBytes, s.AllocObjects-before.AllocObjects
+	if bytes < n*alloc || objects < n {
+		t.Errorf("group allocated %d bytes in %d objects, want at least %d bytes in %d objects", bytes, objects, n*alloc, n)
+	}
+}
+
+func TestSafepoint(t *testing.T) {
+	output := runTestProg(t, "testprog", "Safepoint", "GODEBUG=asyncpreemptoff=1")
+	if output != "OK\n" {
+		t.Fatalf("want OK, got:\n%s", output)
+	}
+}
+
+func TestSTWDelay(t *testing.T) {
+	output := runTestProg(t, "testprog", "STWDelay", "GODEBUG=asyncpreemptoff=1")
+	if output != "OK\n" {
+		t.Fatalf("want OK, got:\n%s", output)
+	}
+}
+
+func TestSTWWatchdog(t *testing.T) {
+	switch runtime.GOOS {
+	case "windows", "plan9", "js":
+		t.Skipf("no stacks from the STW watchdog on %s", runtime.GOOS)
+	}
+	output := runTestProg(t, "testprog", "STWWatchdog", "GODEBUG=asyncpreemptoff=1,stwwatchdog=5")
+	for _, want := range []string{
+		"runtime: stwwatchdog: stopping the world has waited ",
+		" (main.stwSpin)\n",
+		"runtime: stwwatchdog: M",
+		"\nmain.stwSpin(",
+		"OK\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("output does not contain %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestInjectglistBatch(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	const n = 16
+	batches, goroutines, wakeups := runtime.InjectGoroutines(n)
+	if batches < 1 || goroutines < n {
+		t.Errorf("injected %d goroutines in %d batches, want at least %d in 1", goroutines, batches, n)
+	}
+	// Other batches may be injected meanwhile, but no more than one
+	// thread is woken for each of their goroutines.
+	t.Logf("%d batches, %d goroutines, %d wakeups", batches, goroutines, wakeups)
+	if wakeups > goroutines {
+		t.Errorf("woke %d threads for %d goroutines", wakeups, goroutines)
+	}
+}
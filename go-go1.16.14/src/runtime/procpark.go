@@ -0,0 +1,273 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Parked Ps.
+//
+// When GOMAXPROCS shrinks, the Ps above the new limit are parked
+// rather than destroyed, which would need the world stopped: they sit
+// on sched.pparked rather than sched.pidle, so the scheduler does not
+// hand them out. The limit is sched.pactive, which is GOMAXPROCS
+// unless a CPU quota holds it lower for a while (see cpuquota.go).
+// pidleput parks any P above the limit, so a P parks whenever it
+// would otherwise go idle. A running P above the limit
+// parks at its next scheduling point (see procpark), after moving its
+// local work to the global run queue, and a P in a system call is
+// retaken for parking rather than given back to its M.
+//
+// The parked Ps above GOMAXPROCS keep their mcache, free Gs and other
+// caches only until the world next stops: procresize then destroys
+// them and trims allp to GOMAXPROCS, as it always has. Every GC cycle
+// starts by stopping the world, and GOMAXPROCS waits for a cycle in
+// progress to end before parking Ps, so len(allp) == gomaxprocs
+// throughout a cycle, as the mark worker accounting assumes. Only the
+// Ps parked by a CPU quota, which are below GOMAXPROCS, remain parked
+// during a cycle.
+//
+// Growing GOMAXPROCS up to len(allp) just moves the parked Ps back
+// to the idle list. Neither direction stops the world; only growing
+// beyond len(allp) does, to create or reinitialize the new Ps.
+//
+// Parked Ps have status _Pidle and are set in idlepMask, like idle
+// ones. Their timers are still run by other Ps, as for idle Ps, and
+// the world stopping and forEachP deal with them along with the idle
+// Ps, under sched.lock. Code that counts the running Ps counts the
+// Ps above the limit that have yet to park as running (see
+// lastRunningP and procsIdle).
+
+package runtime
+
+import (
+	"runtime/internal/atomic"
+	"unsafe"
+)
+
+// procsetlimit sets GOMAXPROCS to n without stopping the world and
+// reports whether it did. It does not if n exceeds len(allp), so new Ps
+// would have to be created.
+//
+// The caller must hold gcsema, so that no GC cycle is in progress.
+// Must run on the system stack, with a P, so that the world cannot
+// stop and change allp meanwhile.
+func procsetlimit(n int32) bool {
+	lock(&sched.lock)
+	if n > int32(len(allp)) {
+		unlock(&sched.lock)
+		return false
+	}
+	old := gomaxprocs
+	if n == old {
+		unlock(&sched.lock)
+		return true
+	}
+	if trace.enabled {
+		traceGomaxprocs(n)
+	}
+	now := nanotime()
+	sched.totaltime += int64(old) * (now - sched.procresizetime)
+	sched.procresizetime = now
+	atomic.Store((*uint32)(unsafe.Pointer(&gomaxprocs)), uint32(n))
+	procsetactive() // releases sched.lock
+	return true
+}
+
+// procsetactive brings sched.pactive in line with GOMAXPROCS and the
+// CPU quota, parking the Ps above it or making the parked Ps below it
+// idle again. sched.lock must be held, and procsetactive releases it.
+//
+// May run without a P, so write barriers are not allowed.
+//go:nowritebarrierrec
+func procsetactive() {
+	assertLockHeld(&sched.lock)
+
+	old := sched.pactive
+	n := cpuQuotaLimit(gomaxprocs)
+	if n == old {
+		unlock(&sched.lock)
+		return
+	}
+	atomic.Store((*uint32)(unsafe.Pointer(&sched.pactive)), uint32(n))
+
+	if n < old {
+		// Park the idle Ps above the limit.
+		for pp := &sched.pidle; pp.ptr() != nil; {
+			p := pp.ptr()
+			if p.id < n {
+				pp = &p.link
+				continue
+			}
+			*pp = p.link
+			atomic.Xadd(&sched.npidle, -1)
+			pparkput(p)
+		}
+	} else {
+		// Make the parked Ps below the limit idle again.
+		for pp := &sched.pparked; pp.ptr() != nil; {
+			p := pp.ptr()
+			if p.id >= n {
+				pp = &p.link
+				continue
+			}
+			*pp = p.link
+			atomic.Xadd(&sched.nparked, -1)
+			p.link = sched.pidle
+			sched.pidle.set(p)
+			atomic.Xadd(&sched.npidle, 1)
+		}
+	}
+	unlock(&sched.lock)
+
+	if n > old {
+		// The new Ps may have work to steal.
+		wakep()
+		return
+	}
+
+	// Ask the running Ps above the limit to park, and retake
+	// those in system calls, as retake does.
+	lock(&allpLock)
+	for i := int(n); i < len(allp); i++ {
+		p := allp[i]
+		if p == nil {
+			continue
+		}
+		switch s := p.status; s {
+		case _Prunning:
+			preemptone(p)
+		case _Psyscall:
+			unlock(&allpLock)
+			incidlelocked(-1)
+			if atomic.Cas(&p.status, s, _Pidle) {
+				if trace.enabled {
+					traceGoSysBlock(p)
+					traceProcStop(p)
+				}
+				p.syscalltick++
+				handoffp(p)
+			}
+			incidlelocked(1)
+			lock(&allpLock)
+		}
+	}
+	unlock(&allpLock)
+}
+
+// procpark parks the current P, which is above the limit,
+// and stops the current M until it is given a P again.
+func procpark() {
+	_g_ := getg()
+
+	spinning := _g_.m.spinning
+	if spinning {
+		_g_.m.spinning = false
+		if int32(atomic.Xadd(&sched.nmspinning, -1)) < 0 {
+			throw("procpark: negative nmspinning")
+		}
+	}
+	handoffp(releasep())
+	if spinning {
+		// This M was started to look for work; let another
+		// one look instead.
+		wakep()
+	}
+	stopm()
+}
+
+// parkp parks _p_, which is above the limit, moving its
+// local work to the global run queue. It is the tail of handoffp:
+// sched.lock must be held, and parkp releases it.
+//
+// Always runs without a P, so write barriers are not allowed.
+//go:nowritebarrierrec
+func parkp(_p_ *p) {
+	assertLockHeld(&sched.lock)
+
+	for {
+		gp, _ := runqget(_p_)
+		if gp == nil {
+			break
+		}
+		globrunqput(gp)
+	}
+	// Someone must run the work left behind and, as in handoffp,
+	// poll the network if this was the last running P.
+	wake := sched.runqsize != 0 || lastRunningP() && atomic.Load64(&sched.lastpoll) != 0
+	when := nobarrierWakeTime(_p_)
+	pidleput(_p_)
+	unlock(&sched.lock)
+
+	if wake {
+		wakep()
+	}
+	if when != 0 {
+		wakeNetPoller(when)
+	}
+}
+
+// pparkput puts _p_ on the parked list.
+//
+// sched.lock must be held.
+//
+// May run during STW, so write barriers are not allowed.
+//go:nowritebarrierrec
+func pparkput(_p_ *p) {
+	assertLockHeld(&sched.lock)
+
+	_p_.link = sched.pparked
+	sched.pparked.set(_p_)
+	atomic.Xadd(&sched.nparked, 1)
+	if atomic.Load(&_p_.numTimers) != 0 {
+		atomic.Store(&sched.ptimers, 1)
+	}
+}
+
+// adoptParkedTimers moves the timers of the parked Ps to pp. Other Ps
+// run the timers of idle and parked Ps only when they look for work
+// to steal, which they may never do if all the Ps below the limit
+// stay busy.
+//
+// pp must be the current P, so write barriers are allowed.
+//go:yeswritebarrierrec
+func adoptParkedTimers(pp *p) {
+	if atomic.Xchg(&sched.ptimers, 0) == 0 {
+		return
+	}
+	n := int32(atomic.Load((*uint32)(unsafe.Pointer(&sched.pactive))))
+	for _, p2 := range allp[n:] {
+		if p2 == pp || atomic.Load(&p2.numTimers) == 0 {
+			continue
+		}
+		// Lock in P order, in case p2 is adopting too.
+		first, second := pp, p2
+		if p2.id < pp.id {
+			first, second = p2, pp
+		}
+		lock(&first.timersLock)
+		lock(&second.timersLock)
+		moveTimers(pp, p2.timers)
+		p2.timers = nil
+		atomic.Store(&p2.numTimers, 0)
+		atomic.Store(&p2.deletedTimers, 0)
+		atomic.Store64(&p2.timer0When, 0)
+		atomic.Store64(&p2.timerModifiedEarliest, 0)
+		unlock(&second.timersLock)
+		unlock(&first.timersLock)
+	}
+}
+
+// lastRunningP reports whether every P other than the one being
+// given up by the caller is idle or parked. Ps above the limit that
+// have yet to park count as running.
+//
+// sched.lock must be held.
+func lastRunningP() bool {
+	assertLockHeld(&sched.lock)
+
+	return sched.npidle+sched.nparked == uint32(len(allp)-1)
+}
+
+// procsIdle reports whether every P is idle or parked. sysmon keeps
+// running while a P above the limit has yet to park, to preempt it.
+func procsIdle() bool {
+	return atomic.Load(&sched.npidle)+atomic.Load(&sched.nparked) == uint32(len(allp))
+}
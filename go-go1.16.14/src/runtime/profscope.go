@@ -0,0 +1,92 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+import (
+	"runtime/internal/atomic"
+	_ "unsafe" // for go:linkname
+)
+
+// A profiling scope restricts CPU profiling to a goroutine and the
+// goroutines it starts, directly or indirectly, and samples the
+// allocations they make into the scoped allocation profile.
+//
+// Each scope gets a new ID. Goroutines inherit the profscope of their
+// creator, and a goroutine is in the active scope if its profscope
+// matches prof.scope. Ending a scope clears prof.scope, so goroutines
+// still carrying its ID fall out of it at once.
+
+// profScopeGen is the last scope ID handed out.
+var profScopeGen uint32
+
+// runtime_setProfScope starts a profiling scope for the calling
+// goroutine if on is set, and ends the active scope otherwise.
+// Starting a scope discards the scoped allocation profile of the
+// previous one. The caller ensures only one scope is active.
+//
+//go:linkname runtime_setProfScope runtime/pprof.runtime_setProfScope
+func runtime_setProfScope(on bool) {
+	gp := getg()
+	if !on {
+		atomic.Store(&prof.scope, 0)
+		gp.profscope = 0
+		return
+	}
+
+	lock(&proflock)
+	for b := sbuckets; b != nil; b = b.allnext {
+		bp := b.bp()
+		bp.count = 0
+		bp.cycles = 0
+	}
+	unlock(&proflock)
+
+	id := atomic.Xadd(&profScopeGen, 1)
+	if id == 0 {
+		id = atomic.Xadd(&profScopeGen, 1)
+	}
+	gp.profscope = id
+	atomic.Store(&prof.scope, id)
+}
+
+// runtime_scopedAllocProfile returns n, the number of records in the
+// scoped allocation profile. If len(p) >= n, it copies the profile
+// into p and returns n, true. Frees are not tracked, so the records
+// only count allocations and never decay.
+//
+//go:linkname runtime_scopedAllocProfile runtime/pprof.runtime_scopedAllocProfile
+func runtime_scopedAllocProfile(p []MemProfileRecord) (n int, ok bool) {
+	lock(&proflock)
+	for b := sbuckets; b != nil; b = b.allnext {
+		if b.bp().count > 0 {
+			n++
+		}
+	}
+	if n <= len(p) {
+		ok = true
+		for b := sbuckets; b != nil; b = b.allnext {
+			bp := b.bp()
+			if bp.count == 0 {
+				continue
+			}
+			r := &p[0]
+			r.AllocBytes = bp.cycles
+			r.AllocObjects = bp.count
+			r.FreeBytes = 0
+			r.FreeObjects = 0
+			r.LiveBytes = 0
+			r.LiveObjects = 0
+			r.RecentAllocBytes = r.AllocBytes
+			r.RecentAllocObjects = r.AllocObjects
+			i := copy(r.Stack0[:], b.stk())
+			for ; i < len(r.Stack0); i++ {
+				r.Stack0[i] = 0
+			}
+			p = p[1:]
+		}
+	}
+	unlock(&proflock)
+	return
+}
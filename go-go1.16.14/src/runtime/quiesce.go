@@ -0,0 +1,143 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+import _ "unsafe" // for go:linkname
+
+// Quiescence.
+//
+// quiesce disables the scheduling of user goroutines other than its
+// caller, the quiescer, and waits for every other user goroutine to
+// stop making progress. A goroutine is quiesced when it is blocked
+// (_Gwaiting), has exited, or is runnable but held on
+// sched.disable.runnable because the scheduler declined to run it.
+// Goroutines that are running or in a system call are not.
+//
+// Goroutines that are preempted, yield, or are woken while quiesced
+// are held by schedule, so once every goroutine has quiesced none of
+// them runs again until resume.
+
+// quiescePollInterval is how often quiesce checks for quiescence.
+const quiescePollInterval = 1e6 // 1ms
+
+// quiesceSema serializes quiescers. It is held from quiesce until the
+// matching resume. A goroutine waiting for it is blocked, so it counts
+// as quiesced for the current quiescer.
+var quiesceSema uint32 = 1
+
+//go:linkname quiesce runtime/debug.quiesce
+func quiesce(timeout int64) []byte {
+	semacquire(&quiesceSema)
+	gp := getg()
+	lock(&sched.lock)
+	sched.disable.quiescer.set(gp)
+	schedUpdateUser()
+
+	deadline := nanotime() + timeout
+	for !quiesced(gp) {
+		if nanotime() >= deadline {
+			return quiesceStragglers(gp)
+		}
+		timeSleep(quiescePollInterval)
+	}
+	return nil
+}
+
+//go:linkname resume runtime/debug.resume
+func resume() {
+	lock(&sched.lock)
+	if sched.disable.quiescer == 0 {
+		unlock(&sched.lock)
+		return
+	}
+	sched.disable.quiescer = 0
+	schedUpdateUser()
+	semrelease(&quiesceSema)
+}
+
+// quiesced reports whether every user goroutine other than me has
+// quiesced.
+//
+// It does not stop the world, so it cannot tell whether a runnable
+// goroutine is held. Instead it checks that there are as many runnable
+// user goroutines as held ones.
+func quiesced(me *g) bool {
+	lock(&sched.lock)
+	lock(&allglock)
+	done := true
+	runnable := int32(0)
+	for _, gp := range allgs {
+		if gp == me || isSystemGoroutine(gp, true) {
+			continue
+		}
+		switch readgstatus(gp) &^ _Gscan {
+		case _Gidle, _Gwaiting, _Gdead:
+		case _Grunnable:
+			runnable++
+		default:
+			done = false
+		}
+		if !done {
+			break
+		}
+	}
+	held := sched.disable.n
+	unlock(&allglock)
+	unlock(&sched.lock)
+	return done && runnable == held
+}
+
+// quiesceStragglers stops the world and returns the stacks of the user
+// goroutines other than me that have not quiesced, in the format of
+// Stack. It returns nil if there are none.
+func quiesceStragglers(me *g) []byte {
+	for n := 16 << 10; ; n *= 2 {
+		buf := make([]byte, n)
+		stopTheWorld("quiesce")
+		systemstack(func() {
+			g0 := getg()
+			g0.m.traceback = 1
+			g0.writebuf = buf[0:0:len(buf)]
+			lock(&allglock)
+			for _, gp := range allgs {
+				if gp == me || isSystemGoroutine(gp, true) || !isStraggler(gp) {
+					continue
+				}
+				if len(g0.writebuf) > 0 {
+					print("\n")
+				}
+				goroutineheader(gp)
+				traceback(^uintptr(0), ^uintptr(0), 0, gp)
+			}
+			unlock(&allglock)
+			g0.m.traceback = 0
+			buf = g0.writebuf
+			g0.writebuf = nil
+		})
+		startTheWorld()
+		if len(buf) < n {
+			if len(buf) == 0 {
+				return nil
+			}
+			return buf
+		}
+	}
+}
+
+// isStraggler reports whether gp has not quiesced.
+// The world must be stopped.
+func isStraggler(gp *g) bool {
+	switch readgstatus(gp) &^ _Gscan {
+	case _Gidle, _Gwaiting, _Gdead:
+		return false
+	case _Grunnable:
+		for h := sched.disable.runnable.head.ptr(); h != nil; h = h.schedlink.ptr() {
+			if h == gp {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -4,7 +4,10 @@
 
 package runtime
 
-import _ "unsafe" // for go:linkname
+import (
+	"runtime/internal/atomic"
+	_ "unsafe" // for go:linkname
+)
 
 //go:linkname setMaxStack runtime/debug.setMaxStack
 func setMaxStack(in int) (out int) {
@@ -13,6 +16,40 @@ func setMaxStack(in int) (out int) {
 	return out
 }
 
+//go:linkname setTracebackFrames runtime/debug.setTracebackFrames
+func setTracebackFrames(head, tail int) (prevHead, prevTail int) {
+	if head < 1 {
+		head = 1
+	}
+	f := uint64(clampFrames(head))<<32 | uint64(clampFrames(tail))
+	f = atomic.Xchg64(&tracebackFrames, f)
+	return int(int32(f >> 32)), int(int32(f))
+}
+
+func clampFrames(n int) int32 {
+	if n < 0 {
+		return 0
+	}
+	if n > 0x7fffffff { // MaxInt32
+		return 0x7fffffff
+	}
+	return int32(n)
+}
+
+// crashDumpFD is the file descriptor that fatal signals write a
+// minidump to, or -1 if none. See writeMinidump.
+var crashDumpFD int32 = -1
+
+//go:linkname setCrashDumpFD runtime/debug.setCrashDumpFD
+func setCrashDumpFD(fd int) (prev int) {
+	prev = int(crashDumpFD)
+	if fd < 0 {
+		fd = -1
+	}
+	crashDumpFD = int32(fd)
+	return prev
+}
+
 //go:linkname setPanicOnFault runtime/debug.setPanicOnFault
 func setPanicOnFault(new bool) (old bool) {
 	_g_ := getg()
@@ -302,6 +302,9 @@ type dbgVar struct {
 var debug struct {
 	cgocheck           int32
 	clobberfree        int32
+	cpuquota           int32
+	deadlockdetect     int32
+	detsched           int32
 	efence             int32
 	gccheckmark        int32
 	gcpacertrace       int32
@@ -309,13 +312,25 @@ var debug struct {
 	gcstoptheworld     int32
 	gctrace            int32
 	invalidptr         int32
+	lockpi             int32
+	lockrank           int32
 	madvdontneed       int32 // for Linux; issue 28466
+	memorypressure     int32
 	scavenge           int32
 	scavtrace          int32
 	scheddetail        int32
 	schedtrace         int32
+	schedtracejson     int32
+	schedrecord        int32
+	schedseed          int32
 	tracebackancestors int32
 	asyncpreemptoff    int32
+	sigstacksize       int32
+	stwwatchdog        int32
+	waitsince          int32
+
+	// schedreplay is the file named by GODEBUG=schedreplay.
+	schedreplay string
 
 	// debug.malloc is used as a combined debug check
 	// in the malloc function and should be set
@@ -330,6 +345,9 @@ var dbgvars = []dbgVar{
 	{"allocfreetrace", &debug.allocfreetrace},
 	{"clobberfree", &debug.clobberfree},
 	{"cgocheck", &debug.cgocheck},
+	{"cpuquota", &debug.cpuquota},
+	{"deadlockdetect", &debug.deadlockdetect},
+	{"detsched", &debug.detsched},
 	{"efence", &debug.efence},
 	{"gccheckmark", &debug.gccheckmark},
 	{"gcpacertrace", &debug.gcpacertrace},
@@ -337,15 +355,24 @@ var dbgvars = []dbgVar{
 	{"gcstoptheworld", &debug.gcstoptheworld},
 	{"gctrace", &debug.gctrace},
 	{"invalidptr", &debug.invalidptr},
+	{"lockpi", &debug.lockpi},
+	{"lockrank", &debug.lockrank},
 	{"madvdontneed", &debug.madvdontneed},
+	{"memorypressure", &debug.memorypressure},
 	{"sbrk", &debug.sbrk},
 	{"scavenge", &debug.scavenge},
 	{"scavtrace", &debug.scavtrace},
 	{"scheddetail", &debug.scheddetail},
 	{"schedtrace", &debug.schedtrace},
+	{"schedtracejson", &debug.schedtracejson},
+	{"schedrecord", &debug.schedrecord},
+	{"schedseed", &debug.schedseed},
 	{"tracebackancestors", &debug.tracebackancestors},
 	{"asyncpreemptoff", &debug.asyncpreemptoff},
 	{"inittrace", &debug.inittrace},
+	{"sigstacksize", &debug.sigstacksize},
+	{"stwwatchdog", &debug.stwwatchdog},
+	{"waitsince", &debug.waitsince},
 }
 
 func parsedebugvars() {
@@ -385,6 +412,12 @@ func parsedebugvars() {
 			if n, ok := atoi(value); ok {
 				MemProfileRate = n
 			}
+		} else if key == "schedreplay" {
+			debug.schedreplay = value
+		} else if key == "schedtrace" && value == "json" {
+			// Short for schedtrace=1000,schedtracejson=1.
+			debug.schedtrace = 1000
+			debug.schedtracejson = 1
 		} else {
 			for _, v := range dbgvars {
 				if v.name == key {
@@ -160,7 +160,7 @@ const (
 // A zeroed Mutex is unlocked (no need to initialize each lock).
 // Initialization is helpful for static lock ranking, but not required.
 type mutex struct {
-	// Empty struct if lock ranking is disabled, otherwise includes the lock rank
+	// Lock rank, used if lock ranking is enabled
 	lockRankStruct
 	// Futex-based impl treats it as uint32 key,
 	// while sema-based impl as M* waitm.
@@ -377,6 +377,11 @@ type sudog struct {
 	// because c was closed.
 	success bool
 
+	// semaReason is what a goroutine waiting on a semaphore is
+	// waiting for, such as waitReasonSyncMutexLock. It may be more
+	// specific than g.waitreason.
+	semaReason waitReason
+
 	parent   *sudog // semaRoot binary tree
 	waitlink *sudog // g.waiting list or semaRoot
 	waittail *sudog // semaRoot
@@ -462,10 +467,22 @@ type g struct {
 	// park on a chansend or chanrecv. Used to signal an unsafe point
 	// for stack shrinking. It's a boolean value, but is updated atomically.
 	parkingOnChan uint8 // 注释：表示G是放在chansend还是chanrecv。用于栈的收缩，是一个布尔值，但是原子性更新
+	// stackMove makes every stack check of g move its stack, to
+	// catch code holding pointers into the old stack. It is set by
+	// runtime/debug.SetStackMove and inherited by new goroutines.
+	stackMove bool
+	// retired is set once g is dead and no longer kept for reuse,
+	// until g is dropped from allgs. Protected by allglock.
+	retired bool
 
 	raceignore     int8     // ignore race detection events
 	sysblocktraced bool     // StartTrace has emitted EvGoInSyscall about this goroutine
 	sysexitticks   int64    // cputicks when syscall has returned (for tracing)
+	offcpuwhen     int64    // nanotime when syscall was entered (for off-CPU profiling)
+	cgoCalls       int64    // number of cgo calls made by this goroutine
+	cgoTicks       int64    // cputicks spent in those cgo calls
+	profscope      uint32   // profiling scope this goroutine belongs to; see prof.scope
+	profiled       uint32   // goroutine profile state; see goroutineProfile
 	traceseq       uint64   // trace event sequencer
 	tracelastp     puintptr // last P emitted an event for this goroutine
 	lockedm        muintptr // 注释：g被锁定,只在这个m上运行
@@ -475,15 +492,34 @@ type g struct {
 	sigcode1       uintptr
 	sigpc          uintptr
 	gopc           uintptr         // pc of go statement that created this goroutine // 注释：创建当前G的PC(调用者的PC(rip))
+	parentGoid     int64           // goid of the goroutine that created this goroutine
 	ancestors      *[]ancestorInfo // ancestor information goroutine(s) that created this goroutine (only used if debug.tracebackancestors)
 	startpc        uintptr         // pc of goroutine function                       // 注释：任务函数(go函数对应的pc值)
 	racectx        uintptr
 	waiting        *sudog         // sudog structures this g is waiting on (that have a valid elem ptr); in lock order
 	cgoCtxt        []uintptr      // cgo traceback context
 	labels         unsafe.Pointer // profiler labels
+	name           *string        // name set by SetGoroutineName, or nil
 	timer          *timer         // cached timer for time.Sleep                    // 注释：通过time.Sleep缓存timer
 	selectDone     uint32         // are we participating in a select and did someone win the race?
 
+	// lineage records the goroutines that created this goroutine's
+	// creator, nearest first, so that the chain of creators of a
+	// goroutine can be reported without GODEBUG=tracebackancestors.
+	// Unused entries are zero. See newproc1.
+	lineage [lineageDepth]lineageEntry
+
+	// group is the goroutine group set by SetGoroutineGroup, or nil,
+	// and groupwhen is the nanotime when g last started running
+	// Go code in it, or 0 while g is not running or in a syscall.
+	group     *goroutineGroup
+	groupwhen int64
+
+	// deadline is the soft deadline set by
+	// runtime/debug.SetGoroutineDeadline, or nil. It is protected by
+	// goroutineDeadlines.lock.
+	deadline *goroutineDeadline
+
 	// Per-G GC state
 
 	// gcAssistBytes is this G's GC assist credit in terms of
@@ -507,6 +543,7 @@ type m struct {
 	procid     uint64       // for debuggers, but offset not hard-coded // 注释：p的ID,用来调试时使用,一般是协成ID，初始化m时是线程ID
 	gsignal    *g           // signal-handling g                        // 注释：运行中的g(信号处理)
 	goSigStack gsignalStack // Go-allocated signal handling stack
+	gsignalMin int32        // minimum size of gsignal's stack; see gsignalSize
 	sigmask    sigset       // storage for saved signal mask
 	tls        [6]uintptr   // thread-local storage (for x86 extern register) // 注释：通过TLS实现m结构体对象与工作线程之间的绑定,第一个元素是g(程序当前运行的g)
 	mstartfn   func()       // 注释：(起始函数)启动m（mstart）时执行的函数，如果不等于nil就执行
@@ -528,6 +565,7 @@ type m struct {
 	newSigstack   bool // minit on C thread called sigaltstack
 	printlock     int8
 	incgo         bool   // m is executing a cgo call
+	incgofast     bool   // m is executing a cgo call made by cgocallfast
 	freeWait      uint32 // if == 0, safe to free g0 and delete m (atomic)
 	fastrand      [2]uint32
 	needextram    bool
@@ -574,6 +612,28 @@ type m struct {
 	vdsoSP uintptr // SP for traceback while in VDSO call (0 if not in call)
 	vdsoPC uintptr // PC for traceback while in VDSO call
 
+	// If flushwrite is set, print output that does not fit in
+	// g0.writebuf flushes it to writefd rather than being dropped.
+	flushwrite bool
+	writefd    uintptr
+
+	// isextra is set on extra Ms, which run Go code on threads
+	// created by C; see needm. extraIdleSince is when an extra M
+	// was last put on the extra list; see reclaimExtraMs.
+	isextra        bool
+	extraIdleSince int64
+
+	// An extra M pinned to its thread by runtime/cgo.PinThread
+	// has cthreadPinned set, and the thread's ID and name.
+	// See cgothread.go.
+	cthreadPinned bool
+	cthreadID     uint64
+	cthreadName   string
+
+	// watchgen is the watchpoints.gen installed on this thread;
+	// see watchpoint.go.
+	watchgen uint32
+
 	// preemptGen counts the number of completed preemption
 	// signals. This is used to detect when a preemption is
 	// requested, but fails. Accessed atomically.
@@ -583,6 +643,11 @@ type m struct {
 	// Accessed atomically.
 	signalPending uint32
 
+	// stwdump asks this M to print its stack when it gets the
+	// preemption signal, for GODEBUG=stwwatchdog. Accessed
+	// atomically.
+	stwdump uint32
+
 	dlogPerM
 
 	mOS
@@ -607,6 +672,17 @@ type p struct {
 
 	deferpool    [5][]*_defer // pool of available defer structs of different sizes (see panic.go)
 	deferpoolbuf [5][32]*_defer
+	deferstats   [5]deferPoolStats // pool hits and misses (see deferpool.go)
+
+	// stealstats counts the goroutines this P took from other Ps and
+	// from the global run queue (see readStealStats). It follows
+	// deferstats to keep its counters 8-byte aligned for atomic
+	// access on 32-bit systems.
+	stealstats stealStats
+
+	// counters is this P's slot of each eventCounter (see
+	// counters.go). Like stealstats, it must stay 8-byte aligned.
+	counters [numEventCounters]uint64
 
 	// Cache of goroutine ids, amortizes accesses to runtime·sched.goidgen.
 	goidcache    uint64
@@ -756,6 +832,10 @@ type schedt struct {
 
 	pidle      puintptr // idle p's // 注释：由空闲的p结构体对象组成的链表(这里指向的链表的头部)
 	npidle     uint32   // 注释：空闲的p结构体对象的数量
+	pparked    puintptr // Ps above pactive; see procpark.go
+	nparked    uint32   // number of parked Ps; updated atomically
+	pactive    int32    // Ps allowed to run: gomaxprocs, or fewer under a CPU quota; see cpuquota.go
+	ptimers    uint32   // parked Ps may hold timers; updated atomically
 	nmspinning uint32   // See "Worker thread parking/unparking" comment in proc.go. // 注释：自旋的线程m数量（工作线程数据）(自旋说明当前线程M已经没有需要执行的G，正在打算去其他线程M偷G了)
 
 	// Global runnable queue. // 注释：全局可运行队列
@@ -766,28 +846,28 @@ type schedt struct {
 
 	// disable controls selective disabling of the scheduler.
 	//
-	// Use schedEnableUser to control this.
+	// Use schedEnableUser and quiesce to control this.
 	//
 	// disable is protected by sched.lock.
 	disable struct {
 		// user disables scheduling of user goroutines.
+		// It is set if gc is set or quiescer is non-zero.
 		user     bool
 		runnable gQueue // pending runnable Gs
 		n        int32  // length of runnable
+
+		// gc is set by schedEnableUser(false).
+		gc bool
+
+		// quiescer is the goroutine that called quiesce, if any.
+		// It keeps running while other user goroutines are held.
+		quiescer guintptr
 	}
 
-	// Global cache of dead G's.
+	// The global cache of dead G's is gFreeCentral.
 	// 注释：gFree是所有已经退出的goroutine对应的g结构体对象组成的链表，用于缓存g结构体对象，避免每次创建goroutine时都重新分配内存
-	gFree struct {
-		lock    mutex
-		stack   gList // Gs with stacks
-		noStack gList // Gs without stacks
-		n       int32
-	}
 
-	// Central cache of sudog structs.
-	sudoglock  mutex
-	sudogcache *sudog
+	// The central cache of sudog structs is sudogCentral.
 
 	// Central pool of available defer structs of different sizes.
 	deferlock mutex
@@ -996,6 +1076,18 @@ type ancestorInfo struct {
 	gopc uintptr   // pc of go statement that created this goroutine
 }
 
+// lineageDepth is the number of generations of creators beyond its
+// direct creator recorded for every goroutine in g.lineage.
+const lineageDepth = 3
+
+// A lineageEntry identifies a goroutine in the chain of creators of
+// another goroutine, and the go statement in it that created the next
+// goroutine in the chain.
+type lineageEntry struct {
+	goid int64   // creating goroutine; possibly dead
+	gopc uintptr // pc of the go statement in it
+}
+
 const (
 	_TraceRuntimeFrames = 1 << iota // include frames for internal runtime functions.
 	_TraceTrap                      // the initial PC, SP are from a trap, not a return PC from a call
@@ -1037,6 +1129,15 @@ const (
 	waitReasonGCWorkerIdle                            // "GC worker (idle)"
 	waitReasonPreempted                               // "preempted"
 	waitReasonDebugCall                               // "debug call"
+	waitReasonGoroutineProfile                        // "goroutine profile"
+	waitReasonSyncMutexLock                           // "sync.Mutex.Lock"
+	waitReasonSyncRWMutexRLock                        // "sync.RWMutex.RLock"
+	waitReasonSyncRWMutexLock                         // "sync.RWMutex.Lock"
+	waitReasonDeadlockDetectIdle                      // "deadlock detector (idle)"
+	waitReasonMemPressureIdle                         // "memory pressure helper (idle)"
+	waitReasonDeadlineIdle                            // "goroutine deadline helper (idle)"
+	waitReasonOutOfMemoryIdle                         // "out of memory helper (idle)"
+	waitReasonThreadWarningIdle                       // "thread warning helper (idle)"
 )
 
 var waitReasonStrings = [...]string{
@@ -1067,6 +1168,15 @@ var waitReasonStrings = [...]string{
 	waitReasonGCWorkerIdle:          "GC worker (idle)",
 	waitReasonPreempted:             "preempted",
 	waitReasonDebugCall:             "debug call",
+	waitReasonGoroutineProfile:      "goroutine profile",
+	waitReasonSyncMutexLock:         "sync.Mutex.Lock",
+	waitReasonSyncRWMutexRLock:      "sync.RWMutex.RLock",
+	waitReasonSyncRWMutexLock:       "sync.RWMutex.Lock",
+	waitReasonDeadlockDetectIdle:    "deadlock detector (idle)",
+	waitReasonMemPressureIdle:       "memory pressure helper (idle)",
+	waitReasonDeadlineIdle:          "goroutine deadline helper (idle)",
+	waitReasonOutOfMemoryIdle:       "out of memory helper (idle)",
+	waitReasonThreadWarningIdle:     "thread warning helper (idle)",
 }
 
 func (w waitReason) String() string {
@@ -1088,9 +1198,10 @@ var (
 	// allpLock protects P-less reads and size changes of allp, idlepMask,
 	// and timerpMask, and all writes to allp.
 	allpLock mutex
-	// len(allp) == gomaxprocs; may change at safe points, otherwise
-	// immutable.
-	allp []*p // 注释：保存所有的p，len(allp) == gomaxprocs
+	// len(allp) >= gomaxprocs; the Ps above gomaxprocs are parked
+	// until the next safe point, which trims allp to gomaxprocs.
+	// May change at safe points, otherwise immutable.
+	allp []*p // 注释：保存所有的p，len(allp) >= gomaxprocs
 	// Bitmask of Ps in _Pidle list, one bit per P. Reads and writes must
 	// be atomic. Length may change at safe points.
 	//
@@ -1126,8 +1237,9 @@ var (
 
 // Set by the linker so the runtime can determine the buildmode.
 var (
-	islibrary bool // -buildmode=c-shared
-	isarchive bool // -buildmode=c-archive
+	islibrary bool   // -buildmode=c-shared
+	isarchive bool   // -buildmode=c-archive
+	buildMode string // -buildmode, or "" if unknown
 )
 
 // Must agree with cmd/internal/objabi.Framepointer_enabled.
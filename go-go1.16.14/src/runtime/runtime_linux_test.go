@@ -5,7 +5,15 @@
 package runtime_test
 
 import (
+	"bytes"
+	"debug/elf"
+	"internal/testenv"
+	"os"
+	"os/exec"
 	. "runtime"
+	"runtime/metrics"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"testing"
 	"time"
@@ -61,3 +69,167 @@ func TestEpollctlErrorSign(t *testing.T) {
 		t.Errorf("epollctl = %v, want %v", v, -EBADF)
 	}
 }
+
+func TestDebugLayoutSymbol(t *testing.T) {
+	exe, err := buildTestProg(t, "testprog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := elf.Open(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	syms, err := f.Symbols()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DebugLayout()
+	for _, sym := range syms {
+		if sym.Name != "runtime.debugLayout" {
+			continue
+		}
+		if sym.Size != uint64(len(want)) {
+			t.Fatalf("runtime.debugLayout has size %d, want %d", sym.Size, len(want))
+		}
+		sect := f.Sections[sym.Section]
+		got := make([]byte, sym.Size)
+		if _, err := sect.ReadAt(got, int64(sym.Value-sect.Addr)); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("runtime.debugLayout in binary is\n%x\nwant\n%x", got, want)
+		}
+		return
+	}
+	t.Fatal("runtime.debugLayout not found in binary")
+}
+
+func TestMemPressureParse(t *testing.T) {
+	cgroup := "12:memory:/v1\n0::/system.slice/app.service\n"
+	if path, ok := ParseCgroup2Path([]byte(cgroup)); !ok || path != "/system.slice/app.service" {
+		t.Errorf("ParseCgroup2Path = %q, %v, want %q, true", path, ok, "/system.slice/app.service")
+	}
+	if _, ok := ParseCgroup2Path([]byte("4:memory:/v1\n")); ok {
+		t.Errorf("ParseCgroup2Path found a path without a unified hierarchy")
+	}
+
+	for _, tt := range []struct {
+		in   string
+		want uint64
+		ok   bool
+	}{
+		{"1073741824\n", 1 << 30, true},
+		{"max\n", 0, false},
+		{"", 0, false},
+	} {
+		if got, ok := ParseCgroupUint([]byte(tt.in)); got != tt.want || ok != tt.ok {
+			t.Errorf("ParseCgroupUint(%q) = %d, %v, want %d, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	events := "low 1\nhigh 20\nmax 3\noom 400\noom_kill 5000\n"
+	if got := ParseMemoryEvents([]byte(events)); got != 23 {
+		t.Errorf("ParseMemoryEvents = %d, want 23", got)
+	}
+
+	psi := "some avg10=12.34 avg60=5.00 avg300=1.00 total=123456\nfull avg10=99.00 avg60=0.00 avg300=0.00 total=0\n"
+	if got := ParsePSISomeAvg10([]byte(psi)); got != 1234 {
+		t.Errorf("ParsePSISomeAvg10 = %d, want 1234", got)
+	}
+}
+
+func TestParseCPUMax(t *testing.T) {
+	for _, tt := range []struct {
+		in            string
+		quota, period int64
+		ok            bool
+	}{
+		{"250000 100000\n", 250e6, 100e6, true},
+		{"50000 10000\n", 50e6, 10e6, true},
+		{"max 100000\n", 0, 0, false},
+		{"0 100000\n", 0, 0, false},
+		{"250000\n", 0, 0, false},
+		{"", 0, 0, false},
+	} {
+		quota, period, ok := ParseCPUMax([]byte(tt.in))
+		if quota != tt.quota || period != tt.period || ok != tt.ok {
+			t.Errorf("ParseCPUMax(%q) = %d, %d, %v, want %d, %d, %v", tt.in, quota, period, ok, tt.quota, tt.period, tt.ok)
+		}
+	}
+}
+
+func TestLockPI(t *testing.T) {
+	if os.Getenv("GO_TEST_LOCKPI") != "1" {
+		// Contend with the usual locks, then with GODEBUG=lockpi=1
+		// in a child process.
+		testRuntimeLockContention(t)
+
+		testenv.MustHaveExec(t)
+		cmd := testenv.CleanCmdEnv(exec.Command(os.Args[0], "-test.run=^TestLockPI$", "-test.v"))
+		cmd.Env = append(cmd.Env, "GO_TEST_LOCKPI=1", "GODEBUG=lockpi=1")
+		out, err := cmd.CombinedOutput()
+		t.Logf("%s", out)
+		if err != nil {
+			t.Fatalf("child process failed: %v", err)
+		}
+		return
+	}
+	if !LockPIEnabled() {
+		t.Skip("kernel does not support priority-inheritance futexes")
+	}
+	testRuntimeLockContention(t)
+}
+
+// testRuntimeLockContention makes more threads than CPUs fight over a
+// runtime lock that they hold most of the time, so that the kernel
+// deschedules its owner, and checks the /sched/lock metrics.
+func testRuntimeLockContention(t *testing.T) {
+	defer GOMAXPROCS(GOMAXPROCS(4))
+	samples := []metrics.Sample{
+		{Name: "/sched/lock/contended:acquisitions"},
+		{Name: "/sched/lock/wait:seconds"},
+	}
+	metrics.Read(samples)
+	contended, wait := samples[0].Value.Uint64(), samples[1].Value.Float64()
+
+	const (
+		workers = 4
+		iters   = 5000
+	)
+	// The race detector does not know about runtime locks, so count
+	// is accessed atomically. Increments would still be lost without
+	// mutual exclusion.
+	var (
+		l     Mutex
+		count int64
+		work  [workers]int
+		wg    sync.WaitGroup
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < iters; j++ {
+				l.Lock()
+				atomic.StoreInt64(&count, atomic.LoadInt64(&count)+1)
+				for k := 0; k < 1000; k++ {
+					work[i] += k
+				}
+				l.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+	if count != workers*iters {
+		t.Fatalf("count = %d, want %d", count, workers*iters)
+	}
+
+	metrics.Read(samples)
+	if samples[0].Value.Uint64() <= contended {
+		t.Errorf("/sched/lock/contended:acquisitions did not grow")
+	}
+	if samples[1].Value.Float64() <= wait {
+		t.Errorf("/sched/lock/wait:seconds did not grow")
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Implementation of runtime/debug.DumpSchedulerState. Prints the
+// global scheduler state, each P and each M, with a short explanation
+// of every field, for readers learning how the scheduler works.
+
+package runtime
+
+import (
+	"runtime/internal/atomic"
+	_ "unsafe" // for go:linkname
+)
+
+// pStatusDocs explains each P status, indexed like pStatusStrings.
+var pStatusDocs = [...]string{
+	_Pidle:    "not running anything, usually on the idle P list",
+	_Prunning: "owned by an M, running user code or the scheduler",
+	_Psyscall: "its M is in a system call; sysmon may take it away",
+	_Pgcstop:  "halted to stop the world",
+	_Pdead:    "unused since GOMAXPROCS shrank",
+}
+
+// schedStateDump returns the annotated scheduler state.
+//
+// The world is not stopped, since every P would then be in _Pgcstop.
+// As in schedtrace, the state is read with sched.lock held, but P and
+// M fields can still change while they are printed.
+//go:linkname schedStateDump runtime/debug.schedStateDump
+func schedStateDump() []byte {
+	for n := 16 << 10; ; n *= 2 {
+		buf := make([]byte, n)
+		systemstack(func() {
+			g0 := getg()
+			g0.writebuf = buf[0:0:len(buf)]
+			lock(&sched.lock)
+			dumpSchedt()
+			for i, _p_ := range allp {
+				print("\n")
+				dumpP(i, _p_)
+			}
+			for mp := allm; mp != nil; mp = mp.alllink {
+				print("\n")
+				dumpM(mp)
+			}
+			unlock(&sched.lock)
+			buf = g0.writebuf
+			g0.writebuf = nil
+		})
+		if len(buf) < n {
+			return buf
+		}
+	}
+}
+
+// dumpSchedField prints one field of the scheduler state with its
+// explanation.
+func dumpSchedField(name string, v int64, doc string) {
+	print("  ", name, "=", v, "\t// ", doc, "\n")
+}
+
+func dumpSchedBool(name string, v bool, doc string) {
+	print("  ", name, "=", v, "\t// ", doc, "\n")
+}
+
+// dumpSchedt prints the global scheduler state. sched.lock must be held.
+func dumpSchedt() {
+	print("sched: global scheduler state (schedt)\n")
+	dumpSchedField("gomaxprocs", int64(gomaxprocs), "number of Ps, set by GOMAXPROCS")
+	dumpSchedField("npidle", int64(atomic.Load(&sched.npidle)), "Ps on the idle P list")
+	dumpSchedField("mcount", int64(mcount()), "live Ms (OS threads)")
+	dumpSchedField("maxmcount", int64(sched.maxmcount), "most Ms allowed before the program dies")
+	dumpSchedField("nmidle", int64(sched.nmidle), "idle Ms waiting for work")
+	dumpSchedField("nmidlelocked", int64(sched.nmidlelocked), "idle Ms locked to a goroutine")
+	dumpSchedField("nmsys", int64(sched.nmsys), "system Ms, left out of deadlock detection")
+	dumpSchedField("nmspinning", int64(atomic.Load(&sched.nmspinning)), "Ms looking for work to steal")
+	dumpSchedField("ngsys", int64(atomic.Load(&sched.ngsys)), "system goroutines")
+	dumpSchedField("runqsize", int64(sched.runqsize), "goroutines in the global run queue")
+	dumpSchedField("gfree", int64(gFreeCount()), "dead goroutines cached for reuse")
+	dumpSchedField("gcwaiting", int64(sched.gcwaiting), "nonzero while stopping the world")
+	dumpSchedField("stopwait", int64(sched.stopwait), "Ps still to stop before the world is stopped")
+	dumpSchedField("sysmonwait", int64(atomic.Load(&sched.sysmonwait)), "nonzero while sysmon sleeps")
+}
+
+// dumpP prints P number i. sched.lock must be held.
+func dumpP(i int, _p_ *p) {
+	print("P", i, ": ")
+	status := atomic.Load(&_p_.status)
+	if status < uint32(len(pStatusStrings)) {
+		print(pStatusStrings[status], "\t// ", pStatusDocs[status], "\n")
+	} else {
+		print("status=", status, "\n")
+	}
+	id := int64(-1)
+	if mp := _p_.m.ptr(); mp != nil {
+		id = mp.id
+	}
+	dumpSchedField("m", id, "M that owns this P, -1 if none")
+	h := atomic.Load(&_p_.runqhead)
+	t := atomic.Load(&_p_.runqtail)
+	dumpSchedField("runq", int64(t-h), "goroutines in the local run queue, of 256")
+	next := int64(-1)
+	if gp := _p_.runnext.ptr(); gp != nil {
+		next = gp.goid
+	}
+	dumpSchedField("runnext", next, "goroutine run next, ahead of runq; -1 if none")
+	dumpSchedField("schedtick", int64(_p_.schedtick), "incremented on every scheduler call")
+	dumpSchedField("syscalltick", int64(_p_.syscalltick), "incremented on every system call")
+	dumpSchedField("gfree", int64(_p_.gFree.n), "dead goroutines cached on this P")
+	dumpSchedField("timers", int64(len(_p_.timers)), "timers on this P's heap")
+}
+
+// dumpM prints mp. sched.lock must be held.
+func dumpM(mp *m) {
+	print("M", mp.id, ":\n")
+	pid := int64(-1)
+	if _p_ := mp.p.ptr(); _p_ != nil {
+		pid = int64(_p_.id)
+	}
+	dumpSchedField("p", pid, "P held to run Go code, -1 if none")
+	curg := int64(-1)
+	if gp := mp.curg; gp != nil {
+		curg = gp.goid
+	}
+	dumpSchedField("curg", curg, "user goroutine running on this M, -1 if none")
+	lockedg := int64(-1)
+	if gp := mp.lockedg.ptr(); gp != nil {
+		lockedg = gp.goid
+	}
+	dumpSchedField("lockedg", lockedg, "goroutine locked to this M by LockOSThread, -1 if none")
+	dumpSchedBool("spinning", mp.spinning, "looking for work to steal")
+	dumpSchedBool("blocked", mp.blocked, "sleeping on a note")
+	dumpSchedField("locks", int64(mp.locks), "runtime locks held; nonzero disables preemption")
+	dumpSchedField("mallocing", int64(mp.mallocing), "nonzero while allocating")
+	dumpSchedField("throwing", int64(mp.throwing), "nonzero while crashing")
+	dumpSchedField("dying", int64(mp.dying), "nonzero while dying after a crash")
+	dumpSchedBool("incgo", mp.incgo, "running C code called through cgo")
+}
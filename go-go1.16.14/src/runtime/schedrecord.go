@@ -0,0 +1,241 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file records scheduling decisions and replays them.
+//
+// With GODEBUG=schedrecord=N, the runtime keeps the last N scheduling
+// events in a lock-free ring buffer, like the goroutine status trace:
+// a goroutine starting to run on a P, and a goroutine being preempted,
+// with the PC at which it stopped. runtime/debug.ReadSchedRecord
+// returns them as a compact log.
+//
+// Under GODEBUG=detsched, GODEBUG=schedreplay=file makes the
+// scheduler run goroutines in the order of such a log, saved to file
+// by an earlier run, instead of the order drawn from the seed, for as
+// long as the goroutines the log names are runnable when their turn
+// comes.
+
+package runtime
+
+import (
+	"runtime/internal/atomic"
+	"unsafe"
+)
+
+// Kinds of scheduling events. They appear in the log of
+// runtime/debug.ReadSchedRecord.
+const (
+	schedEventRun     = 1 // goroutine started running on a P
+	schedEventPreempt = 2 // goroutine was preempted at pc
+)
+
+// schedRecordMagic starts the log of runtime/debug.ReadSchedRecord.
+const schedRecordMagic = "gosched1"
+
+// maxSchedRecords bounds GODEBUG=schedrecord.
+const maxSchedRecords = 1 << 24
+
+// A schedRecord is one scheduling event.
+type schedRecord struct {
+	goid int64
+	pc   uintptr
+	pid  int32
+	kind uint8
+}
+
+var schedRec struct {
+	pos  uint64        // position of the next record; accessed atomically
+	recs []schedRecord // off the heap; nil unless GODEBUG=schedrecord
+}
+
+// schedReplay is the order in which GODEBUG=schedreplay makes the
+// scheduler run goroutines. It is only used under GODEBUG=detsched,
+// by the M holding the single P.
+var schedReplay struct {
+	goids []int64 // goroutines still to run, in order
+	runs  uint64  // goroutines run so far, counted under detsched
+}
+
+// schedrecordinit allocates the ring buffer for GODEBUG=schedrecord
+// and loads the log for GODEBUG=schedreplay. It is called by schedinit
+// after detschedinit.
+func schedrecordinit() {
+	if n := debug.schedrecord; n > 0 {
+		if n > maxSchedRecords {
+			n = maxSchedRecords
+		}
+		p := persistentalloc(uintptr(n)*unsafe.Sizeof(schedRecord{}), 8, &memstats.other_sys)
+		*(*slice)(unsafe.Pointer(&schedRec.recs)) = slice{p, int(n), int(n)}
+	}
+	if name := debug.schedreplay; name != "" {
+		if !detsched.enabled {
+			print("runtime: GODEBUG=schedreplay needs detsched\n")
+			return
+		}
+		log := readSchedReplayFile(name)
+		if log == nil {
+			print("runtime: GODEBUG=schedreplay: cannot read ", name, "\n")
+			return
+		}
+		goids, ok := parseSchedReplay(log)
+		if !ok {
+			print("runtime: GODEBUG=schedreplay: ", name, " is not a complete scheduling record\n")
+			return
+		}
+		schedReplay.goids = goids
+	}
+}
+
+// readSchedReplayFile returns the contents of the file name, or nil
+// if it cannot be read.
+func readSchedReplayFile(name string) []byte {
+	path := []byte(name + "\x00")
+	fd := open(&path[0], 0 /* O_RDONLY */, 0)
+	if fd < 0 {
+		return nil
+	}
+	buf := make([]byte, 0, 64<<10)
+	for {
+		if len(buf) == cap(buf) {
+			buf = append(buf, 0)[:len(buf)]
+		}
+		n := read(fd, unsafe.Pointer(&buf[len(buf):cap(buf)][0]), int32(cap(buf)-len(buf)))
+		if n < 0 {
+			buf = nil
+		}
+		if n <= 0 {
+			break
+		}
+		buf = buf[:len(buf)+int(n)]
+	}
+	closefd(fd)
+	return buf
+}
+
+// parseSchedReplay returns the goroutines that start running in the
+// log of a scheduling record, in order, and whether the log is well
+// formed and holds every event since the recorded run started.
+func parseSchedReplay(log []byte) (goids []int64, ok bool) {
+	if len(log) < len(schedRecordMagic) || string(log[:len(schedRecordMagic)]) != schedRecordMagic {
+		return nil, false
+	}
+	b := log[len(schedRecordMagic):]
+	next := func() uint64 {
+		var v uint64
+		for shift := uint(0); len(b) > 0 && shift < 64; shift += 7 {
+			c := b[0]
+			b = b[1:]
+			v |= uint64(c&0x7f) << shift
+			if c < 0x80 {
+				return v
+			}
+		}
+		ok = false
+		return 0
+	}
+	ok = true
+	if next() != 0 {
+		return nil, false
+	}
+	for ok && len(b) > 0 {
+		kind := b[0]
+		b = b[1:]
+		next() // P
+		goid := int64(next())
+		switch kind {
+		case schedEventRun:
+			goids = append(goids, goid)
+		case schedEventPreempt:
+			next() // PC
+		default:
+			ok = false
+		}
+	}
+	return goids, ok
+}
+
+// recordSched records a scheduling event of kind for gp on the P of
+// the current M.
+//
+// Records are claimed with an atomic add, so any number of Ps may
+// record at once. They are only read with the world stopped, when no
+// record is being written.
+//go:nosplit
+func recordSched(kind uint8, gp *g, pc uintptr) {
+	recs := schedRec.recs
+	i := atomic.Xadd64(&schedRec.pos, 1) - 1
+	r := &recs[i%uint64(len(recs))]
+	r.goid = gp.goid
+	r.pc = pc
+	r.pid = getg().m.p.ptr().id
+	r.kind = kind
+}
+
+// preemptPC returns the PC at which gp, stopped by gopreempt_m or
+// preemptPark, was preempted: where it was interrupted for an
+// asynchronous preemption, or at the entry of a function otherwise.
+func preemptPC(gp *g) uintptr {
+	var pcbuf [3]uintptr
+	n := gentraceback(gp.sched.pc, gp.sched.sp, gp.sched.lr, gp, 0, &pcbuf[0], len(pcbuf), nil, nil, 0)
+	for i := 0; i+1 < n; i++ {
+		if f := findfunc(pcbuf[i]); f.valid() && f.funcID == funcID_asyncPreempt {
+			return pcbuf[i+1]
+		}
+	}
+	return gp.sched.pc
+}
+
+// schedRecordLog returns the recorded events encoded as documented by
+// runtime/debug.ParseSchedRecord, or nil without GODEBUG=schedrecord.
+//go:linkname schedRecordLog runtime/debug.schedRecordLog
+func schedRecordLog() []byte {
+	if schedRec.recs == nil {
+		return nil
+	}
+	stopTheWorld("sched record")
+	end := schedRec.pos
+	start := uint64(0)
+	if n := uint64(len(schedRec.recs)); end > n {
+		start = end - n
+	}
+	buf := make([]byte, 0, len(schedRecordMagic)+int(end-start)*4)
+	buf = append(buf, schedRecordMagic...)
+	buf = traceAppend(buf, start)
+	for i := start; i < end; i++ {
+		r := &schedRec.recs[i%uint64(len(schedRec.recs))]
+		buf = append(buf, r.kind)
+		buf = traceAppend(buf, uint64(r.pid))
+		buf = traceAppend(buf, uint64(r.goid))
+		if r.kind == schedEventPreempt {
+			buf = traceAppend(buf, uint64(r.pc))
+		}
+	}
+	startTheWorld()
+	return buf
+}
+
+// schedReplayNext returns the goroutine that should run next under
+// GODEBUG=schedreplay, or 0 if there is none.
+func schedReplayNext() int64 {
+	if len(schedReplay.goids) == 0 {
+		return 0
+	}
+	return schedReplay.goids[0]
+}
+
+// schedReplayRun notes that gp runs, under deterministic scheduling.
+// It ends the replay if gp is not the goroutine it called for.
+func schedReplayRun(gp *g) {
+	schedReplay.runs++
+	want := schedReplayNext()
+	if want == 0 {
+		return
+	}
+	if gp.goid != want {
+		print("runtime: sched replay diverged at goroutine run ", schedReplay.runs, ": goroutine ", gp.goid, " ran instead of ", want, "\n")
+		schedReplay.goids = nil
+		return
+	}
+	schedReplay.goids = schedReplay.goids[1:]
+}
@@ -48,17 +48,17 @@ const semTabSize = 251
 
 var semtable [semTabSize]struct {
 	root semaRoot
-	pad  [cpu.CacheLinePadSize - unsafe.Sizeof(semaRoot{})]byte
+	pad  [cpu.CacheLinePadSize - unsafe.Sizeof(semaRoot{})%cpu.CacheLinePadSize]byte
 }
 
 //go:linkname sync_runtime_Semacquire sync.runtime_Semacquire
 func sync_runtime_Semacquire(addr *uint32) {
-	semacquire1(addr, false, semaBlockProfile, 0)
+	semacquire1(addr, false, semaBlockProfile, 0, waitReasonSemacquire, 0)
 }
 
 //go:linkname poll_runtime_Semacquire internal/poll.runtime_Semacquire
 func poll_runtime_Semacquire(addr *uint32) {
-	semacquire1(addr, false, semaBlockProfile, 0)
+	semacquire1(addr, false, semaBlockProfile, 0, waitReasonSemacquire, 0)
 }
 
 //go:linkname sync_runtime_Semrelease sync.runtime_Semrelease
@@ -68,7 +68,32 @@ func sync_runtime_Semrelease(addr *uint32, handoff bool, skipframes int) {
 
 //go:linkname sync_runtime_SemacquireMutex sync.runtime_SemacquireMutex
 func sync_runtime_SemacquireMutex(addr *uint32, lifo bool, skipframes int) {
-	semacquire1(addr, lifo, semaBlockProfile|semaMutexProfile, skipframes)
+	semacquire1(addr, lifo, semaBlockProfile|semaMutexProfile, skipframes, waitReasonSyncMutexLock, 0)
+}
+
+//go:linkname sync_runtime_SemacquireRWMutexR sync.runtime_SemacquireRWMutexR
+func sync_runtime_SemacquireRWMutexR(addr *uint32, lifo bool, skipframes int) {
+	semacquire1(addr, lifo, semaBlockProfile|semaMutexProfile, skipframes, waitReasonSyncRWMutexRLock, 0)
+}
+
+//go:linkname sync_runtime_SemacquireRWMutex sync.runtime_SemacquireRWMutex
+func sync_runtime_SemacquireRWMutex(addr *uint32, lifo bool, skipframes int) {
+	semacquire1(addr, lifo, semaBlockProfile|semaMutexProfile, skipframes, waitReasonSyncRWMutexLock, 0)
+}
+
+// sync_runtime_SemacquireTry acquires the semaphore if it can do so
+// without blocking, and reports whether it did.
+//go:linkname sync_runtime_SemacquireTry sync.runtime_SemacquireTry
+func sync_runtime_SemacquireTry(addr *uint32) bool {
+	return cansemacquire(addr)
+}
+
+// sync_runtime_SemacquireDeadline is like sync_runtime_SemacquireMutex,
+// but gives up at deadline, a runtime_nanotime value, and reports
+// whether it acquired the semaphore.
+//go:linkname sync_runtime_SemacquireDeadline sync.runtime_SemacquireDeadline
+func sync_runtime_SemacquireDeadline(addr *uint32, lifo bool, skipframes int, deadline int64) bool {
+	return semacquire1(addr, lifo, semaBlockProfile|semaMutexProfile, skipframes, waitReasonSyncMutexLock, deadline)
 }
 
 //go:linkname poll_runtime_Semrelease internal/poll.runtime_Semrelease
@@ -92,10 +117,13 @@ const (
 
 // Called from runtime.
 func semacquire(addr *uint32) {
-	semacquire1(addr, false, 0, 0)
+	semacquire1(addr, false, 0, 0, waitReasonSemacquire, 0)
 }
 
-func semacquire1(addr *uint32, lifo bool, profile semaProfileFlags, skipframes int) {
+// semacquire1 acquires the semaphore at addr, blocking until it can.
+// If deadline is not 0, it gives up at that nanotime and reports
+// whether it acquired the semaphore.
+func semacquire1(addr *uint32, lifo bool, profile semaProfileFlags, skipframes int, reason waitReason, deadline int64) bool {
 	gp := getg()
 	if gp != gp.m.curg {
 		throw("semacquire not on the G stack")
@@ -103,7 +131,7 @@ func semacquire1(addr *uint32, lifo bool, profile semaProfileFlags, skipframes i
 
 	// Easy case.
 	if cansemacquire(addr) {
-		return
+		return true
 	}
 
 	// Harder case:
@@ -118,6 +146,12 @@ func semacquire1(addr *uint32, lifo bool, profile semaProfileFlags, skipframes i
 	s.releasetime = 0
 	s.acquiretime = 0
 	s.ticket = 0
+	s.semaReason = reason
+	if debug.deadlockdetect == 0 {
+		// Tracebacks show the sync wait reasons only with
+		// deadlock detection on.
+		reason = waitReasonSemacquire
+	}
 	if profile&semaBlockProfile != 0 && blockprofilerate > 0 {
 		t0 = cputicks()
 		s.releasetime = -1
@@ -128,7 +162,33 @@ func semacquire1(addr *uint32, lifo bool, profile semaProfileFlags, skipframes i
 		}
 		s.acquiretime = t0
 	}
+	var t *timer
+	var sd *semaDeadline
+	if deadline != 0 {
+		// semaTimeout takes s off the queue at the deadline,
+		// unless semrelease gets to it first.
+		t = gp.timer
+		if t == nil {
+			t = new(timer)
+			gp.timer = t
+		}
+		sd = &semaDeadline{s: s}
+		t.f = semaTimeout
+		t.arg = sd
+		t.seq = uintptr(root.index())
+	}
+	acquired := true
 	for {
+		if deadline != 0 {
+			if nanotime() >= deadline {
+				acquired = false
+				break
+			}
+			// Arm the timer before taking root.lock, under
+			// which resettimer cannot be called.
+			s.success = true
+			resettimer(t, deadline)
+		}
 		lockWithRank(&root.lock, lockRankRoot)
 		// Add ourselves to nwait to disable "easy case" in semrelease.
 		atomic.Xadd(&root.nwait, 1)
@@ -138,18 +198,70 @@ func semacquire1(addr *uint32, lifo bool, profile semaProfileFlags, skipframes i
 			unlock(&root.lock)
 			break
 		}
+		if deadline != 0 && !s.success {
+			// The deadline passed.
+			atomic.Xadd(&root.nwait, -1)
+			unlock(&root.lock)
+			acquired = false
+			break
+		}
 		// Any semrelease after the cansemacquire knows we're waiting
 		// (we set nwait above), so go to sleep.
 		root.queue(addr, s, lifo)
-		goparkunlock(&root.lock, waitReasonSemacquire, traceEvGoBlockSync, 4+skipframes)
+		goparkunlock(&root.lock, reason, traceEvGoBlockSync, 4+skipframes)
 		if s.ticket != 0 || cansemacquire(addr) {
 			break
 		}
 	}
+	if t != nil {
+		deltimer(t)
+		// deltimer does not wait for a semaTimeout that is already
+		// running, so detach s from it before s is released.
+		lockWithRank(&root.lock, lockRankRoot)
+		sd.s = nil
+		unlock(&root.lock)
+	}
 	if s.releasetime > 0 {
 		blockevent(s.releasetime-t0, 3+skipframes)
 	}
 	releaseSudog(s)
+	return acquired
+}
+
+// A semaDeadline is the argument of semaTimeout. The sudog of a
+// semacquire1 goes back to the pool when it returns, possibly while
+// semaTimeout still runs, but the semaDeadline is not reused, so
+// semaTimeout can tell that s is gone. s is protected by the lock of
+// the semaRoot it is queued on.
+type semaDeadline struct {
+	s *sudog
+}
+
+// semaTimeout is the timer function of a semacquire1 with a deadline.
+// arg is its *semaDeadline, and seq the index of its semaRoot. Unless
+// semacquire1 has returned, it sets s.success to false and, if s is
+// queued, takes it off the queue and wakes its goroutine.
+func semaTimeout(arg interface{}, seq uintptr) {
+	sd := arg.(*semaDeadline)
+	root := &semtable[seq].root
+	lockWithRank(&root.lock, lockRankRoot)
+	s := sd.s
+	if s == nil {
+		// semacquire1 has returned.
+		unlock(&root.lock)
+		return
+	}
+	s.success = false
+	if s.elem == nil {
+		// s is not queued yet, or semrelease dequeued it and
+		// woke its goroutine.
+		unlock(&root.lock)
+		return
+	}
+	root.unqueue(s)
+	atomic.Xadd(&root.nwait, -1)
+	unlock(&root.lock)
+	goready(s.g, 0)
 }
 
 func semrelease(addr *uint32) {
@@ -190,6 +302,11 @@ func semrelease1(addr *uint32, handoff bool, skipframes int) {
 		}
 		if handoff && cansemacquire(addr) {
 			s.ticket = 1
+			var waited int64
+			if acquiretime != 0 {
+				waited = int64(float64(t0-acquiretime) * 1e9 / float64(tickspersecond()))
+			}
+			mutexstarveevent(mutexHandoff, waited, 3+skipframes)
 		}
 		readyWithTime(s, 5+skipframes)
 		if s.ticket == 1 && getg().m.locks == 0 {
@@ -218,6 +335,11 @@ func semroot(addr *uint32) *semaRoot {
 	return &semtable[(uintptr(unsafe.Pointer(addr))>>3)%semTabSize].root
 }
 
+// index returns the index of root in semtable.
+func (root *semaRoot) index() int {
+	return int((uintptr(unsafe.Pointer(root)) - uintptr(unsafe.Pointer(&semtable))) / unsafe.Sizeof(semtable[0]))
+}
+
 func cansemacquire(addr *uint32) bool {
 	for {
 		v := atomic.Load(addr)
@@ -387,6 +509,42 @@ Found:
 	return s, now
 }
 
+// unqueue removes s, which is queued in root, wherever it is in the
+// queue of its address.
+func (root *semaRoot) unqueue(s *sudog) {
+	addr := s.elem
+	ps := &root.treap
+	for t := *ps; t.elem != addr; t = *ps {
+		if uintptr(addr) < uintptr(t.elem) {
+			ps = &t.prev
+		} else {
+			ps = &t.next
+		}
+	}
+	head := *ps
+	if head == s {
+		root.dequeue((*uint32)(addr))
+		return
+	}
+
+	// s is in the wait list of head.
+	prev := head
+	for prev.waitlink != s {
+		prev = prev.waitlink
+	}
+	prev.waitlink = s.waitlink
+	if head.waitlink == nil {
+		head.waittail = nil
+	} else if head.waittail == s {
+		head.waittail = prev
+	}
+	s.waitlink = nil
+	s.elem = nil
+	// s may have a ticket from its time at the head of the queue,
+	// which a woken semacquire1 would mistake for a handoff.
+	s.ticket = 0
+}
+
 // rotateLeft rotates the tree rooted at node x.
 // turning (x a (y b c)) into (y (x a b) c).
 func (root *semaRoot) rotateLeft(x *sudog) {
@@ -0,0 +1,51 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+import "unsafe"
+
+// AddSignalHandler installs a C function to be called by the runtime's
+// signal handler when the signal sig arrives, before the runtime acts on
+// the signal itself. It lets cgo libraries observe or take over signals
+// that the runtime also uses, such as SIGSEGV or SIGPROF, without
+// replacing the runtime's handler with sigaction.
+//
+// The handler will be called with a single argument, a pointer to a
+// struct:
+//
+//	struct {
+//		Sig     uintptr
+//		Info    unsafe.Pointer
+//		Context unsafe.Pointer
+//	}
+//
+// In C syntax, this struct will be
+//
+//	struct {
+//		uintptr_t Sig;
+//		siginfo_t* Info;
+//		void* Context;
+//	};
+//
+// The Sig, Info and Context fields are the arguments the runtime's signal
+// handler received. The handler returns an int: if it is non-zero the
+// signal is considered handled, and neither later handlers nor the runtime
+// will see it. Returning zero passes the signal on.
+//
+// Handlers for a signal are called in the order they were installed.
+// They are only called for signals the runtime handles, and only on
+// threads that are running Go code or that entered C code from Go.
+// Handlers run in a signal handler on the signal stack, and must
+// therefore use only async-signal safe functions. They may not call
+// back into Go.
+//
+// AddSignalHandler is only supported on Unix systems. It panics if sig
+// is not a valid signal number.
+func AddSignalHandler(sig int, handler unsafe.Pointer) {
+	if sig <= 0 || handler == nil {
+		panic("AddSignalHandler: invalid argument")
+	}
+	sigAddHandler(uint32(sig), handler)
+}
@@ -27,6 +27,23 @@ func dumpregs(c *sigctxt) {
 	print("gs     ", hex(c.gs()), "\n")
 }
 
+// minidumpregs records the registers of c in the order dumpregs prints them.
+func minidumpregs(c *sigctxt, d *minidump) {
+	d.reg(uint64(c.eax()))
+	d.reg(uint64(c.ebx()))
+	d.reg(uint64(c.ecx()))
+	d.reg(uint64(c.edx()))
+	d.reg(uint64(c.edi()))
+	d.reg(uint64(c.esi()))
+	d.reg(uint64(c.ebp()))
+	d.reg(uint64(c.esp()))
+	d.reg(uint64(c.eip()))
+	d.reg(uint64(c.eflags()))
+	d.reg(uint64(c.cs()))
+	d.reg(uint64(c.fs()))
+	d.reg(uint64(c.gs()))
+}
+
 //go:nosplit
 //go:nowritebarrierrec
 func (c *sigctxt) sigpc() uintptr { return uintptr(c.eip()) }
@@ -36,6 +36,31 @@ func dumpregs(c *sigctxt) {
 	print("gs     ", hex(c.gs()), "\n")
 }
 
+// minidumpregs records the registers of c in the order dumpregs prints them.
+func minidumpregs(c *sigctxt, d *minidump) {
+	d.reg(uint64(c.rax()))
+	d.reg(uint64(c.rbx()))
+	d.reg(uint64(c.rcx()))
+	d.reg(uint64(c.rdx()))
+	d.reg(uint64(c.rdi()))
+	d.reg(uint64(c.rsi()))
+	d.reg(uint64(c.rbp()))
+	d.reg(uint64(c.rsp()))
+	d.reg(uint64(c.r8()))
+	d.reg(uint64(c.r9()))
+	d.reg(uint64(c.r10()))
+	d.reg(uint64(c.r11()))
+	d.reg(uint64(c.r12()))
+	d.reg(uint64(c.r13()))
+	d.reg(uint64(c.r14()))
+	d.reg(uint64(c.r15()))
+	d.reg(uint64(c.rip()))
+	d.reg(uint64(c.rflags()))
+	d.reg(uint64(c.cs()))
+	d.reg(uint64(c.fs()))
+	d.reg(uint64(c.gs()))
+}
+
 //go:nosplit
 //go:nowritebarrierrec
 func (c *sigctxt) sigpc() uintptr { return uintptr(c.rip()) }
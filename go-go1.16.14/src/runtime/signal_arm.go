@@ -32,6 +32,31 @@ func dumpregs(c *sigctxt) {
 	print("fault   ", hex(c.fault()), "\n")
 }
 
+// minidumpregs records the registers of c in the order dumpregs prints them.
+func minidumpregs(c *sigctxt, d *minidump) {
+	d.reg(uint64(c.trap()))
+	d.reg(uint64(c.error()))
+	d.reg(uint64(c.oldmask()))
+	d.reg(uint64(c.r0()))
+	d.reg(uint64(c.r1()))
+	d.reg(uint64(c.r2()))
+	d.reg(uint64(c.r3()))
+	d.reg(uint64(c.r4()))
+	d.reg(uint64(c.r5()))
+	d.reg(uint64(c.r6()))
+	d.reg(uint64(c.r7()))
+	d.reg(uint64(c.r8()))
+	d.reg(uint64(c.r9()))
+	d.reg(uint64(c.r10()))
+	d.reg(uint64(c.fp()))
+	d.reg(uint64(c.ip()))
+	d.reg(uint64(c.sp()))
+	d.reg(uint64(c.lr()))
+	d.reg(uint64(c.pc()))
+	d.reg(uint64(c.cpsr()))
+	d.reg(uint64(c.fault()))
+}
+
 //go:nosplit
 //go:nowritebarrierrec
 func (c *sigctxt) sigpc() uintptr { return uintptr(c.pc()) }
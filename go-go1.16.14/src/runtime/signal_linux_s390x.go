@@ -77,6 +77,28 @@ func dumpregs(c *sigctxt) {
 	print("link ", hex(c.link()), "\n")
 }
 
+// minidumpregs records the registers of c in the order dumpregs prints them.
+func minidumpregs(c *sigctxt, d *minidump) {
+	d.reg(uint64(c.r0()))
+	d.reg(uint64(c.r1()))
+	d.reg(uint64(c.r2()))
+	d.reg(uint64(c.r3()))
+	d.reg(uint64(c.r4()))
+	d.reg(uint64(c.r5()))
+	d.reg(uint64(c.r6()))
+	d.reg(uint64(c.r7()))
+	d.reg(uint64(c.r8()))
+	d.reg(uint64(c.r9()))
+	d.reg(uint64(c.r10()))
+	d.reg(uint64(c.r11()))
+	d.reg(uint64(c.r12()))
+	d.reg(uint64(c.r13()))
+	d.reg(uint64(c.r14()))
+	d.reg(uint64(c.r15()))
+	d.reg(uint64(c.pc()))
+	d.reg(uint64(c.link()))
+}
+
 //go:nosplit
 //go:nowritebarrierrec
 func (c *sigctxt) sigpc() uintptr { return uintptr(c.pc()) }
@@ -51,6 +51,46 @@ func dumpregs(c *sigctxt) {
 	print("hi   ", hex(c.hi()), "\n")
 }
 
+// minidumpregs records the registers of c in the order dumpregs prints them.
+func minidumpregs(c *sigctxt, d *minidump) {
+	d.reg(uint64(c.r0()))
+	d.reg(uint64(c.r1()))
+	d.reg(uint64(c.r2()))
+	d.reg(uint64(c.r3()))
+	d.reg(uint64(c.r4()))
+	d.reg(uint64(c.r5()))
+	d.reg(uint64(c.r6()))
+	d.reg(uint64(c.r7()))
+	d.reg(uint64(c.r8()))
+	d.reg(uint64(c.r9()))
+	d.reg(uint64(c.r10()))
+	d.reg(uint64(c.r11()))
+	d.reg(uint64(c.r12()))
+	d.reg(uint64(c.r13()))
+	d.reg(uint64(c.r14()))
+	d.reg(uint64(c.r15()))
+	d.reg(uint64(c.r16()))
+	d.reg(uint64(c.r17()))
+	d.reg(uint64(c.r18()))
+	d.reg(uint64(c.r19()))
+	d.reg(uint64(c.r20()))
+	d.reg(uint64(c.r21()))
+	d.reg(uint64(c.r22()))
+	d.reg(uint64(c.r23()))
+	d.reg(uint64(c.r24()))
+	d.reg(uint64(c.r25()))
+	d.reg(uint64(c.r26()))
+	d.reg(uint64(c.r27()))
+	d.reg(uint64(c.r28()))
+	d.reg(uint64(c.r29()))
+	d.reg(uint64(c.r30()))
+	d.reg(uint64(c.r31()))
+	d.reg(uint64(c.pc()))
+	d.reg(uint64(c.link()))
+	d.reg(uint64(c.lo()))
+	d.reg(uint64(c.hi()))
+}
+
 func (c *sigctxt) sigpc() uintptr { return uintptr(c.pc()) }
 func (c *sigctxt) sigsp() uintptr { return uintptr(c.sp()) }
 func (c *sigctxt) siglr() uintptr { return uintptr(c.link()) }
@@ -46,6 +46,42 @@ func dumpregs(c *sigctxt) {
 	print("pc  ", hex(c.pc()), "\n")
 }
 
+// minidumpregs records the registers of c in the order dumpregs prints them.
+func minidumpregs(c *sigctxt, d *minidump) {
+	d.reg(uint64(c.ra()))
+	d.reg(uint64(c.sp()))
+	d.reg(uint64(c.gp()))
+	d.reg(uint64(c.tp()))
+	d.reg(uint64(c.t0()))
+	d.reg(uint64(c.t1()))
+	d.reg(uint64(c.t2()))
+	d.reg(uint64(c.s0()))
+	d.reg(uint64(c.s1()))
+	d.reg(uint64(c.a0()))
+	d.reg(uint64(c.a1()))
+	d.reg(uint64(c.a2()))
+	d.reg(uint64(c.a3()))
+	d.reg(uint64(c.a4()))
+	d.reg(uint64(c.a5()))
+	d.reg(uint64(c.a6()))
+	d.reg(uint64(c.a7()))
+	d.reg(uint64(c.s2()))
+	d.reg(uint64(c.s3()))
+	d.reg(uint64(c.s4()))
+	d.reg(uint64(c.s5()))
+	d.reg(uint64(c.s6()))
+	d.reg(uint64(c.s7()))
+	d.reg(uint64(c.s8()))
+	d.reg(uint64(c.s9()))
+	d.reg(uint64(c.s10()))
+	d.reg(uint64(c.s11()))
+	d.reg(uint64(c.t3()))
+	d.reg(uint64(c.t4()))
+	d.reg(uint64(c.t5()))
+	d.reg(uint64(c.t6()))
+	d.reg(uint64(c.pc()))
+}
+
 //go:nosplit
 //go:nowritebarrierrec
 func (c *sigctxt) sigpc() uintptr { return uintptr(c.pc()) }
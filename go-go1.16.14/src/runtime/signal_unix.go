@@ -261,23 +261,37 @@ func clearSignalHandlers() {
 	}
 }
 
-// setProcessCPUProfiler is called when the profiling timer changes.
+// setProcessCPUProfilerTimer is called when the profiling timer changes.
 // It is called with prof.lock held. hz is the new timer, and is 0 if
 // profiling is being disabled. Enable or disable the signal as
 // required for -buildmode=c-archive.
-func setProcessCPUProfiler(hz int32) {
+func setProcessCPUProfilerTimer(hz int32) {
+	setSigprofHandler(hz != 0)
 	if hz != 0 {
-		// Enable the Go signal handler if not enabled.
-		if atomic.Cas(&handlingSig[_SIGPROF], 0, 1) {
-			atomic.Storeuintptr(&fwdSig[_SIGPROF], getsig(_SIGPROF))
-			setsig(_SIGPROF, funcPC(sighandler))
-		}
-
 		var it itimerval
 		it.it_interval.tv_sec = 0
 		it.it_interval.set_usec(1000000 / hz)
 		it.it_value = it.it_interval
 		setitimer(_ITIMER_PROF, &it, nil)
+	} else {
+		setitimer(_ITIMER_PROF, &itimerval{}, nil)
+	}
+}
+
+// sigprofHandler is the SIGPROF handler installed by setSigprofHandler,
+// as reported by getsig.
+var sigprofHandler uintptr
+
+// setSigprofHandler enables the Go SIGPROF handler if on is set, and
+// otherwise restores the handler that was installed before profiling.
+func setSigprofHandler(on bool) {
+	if on {
+		// Enable the Go signal handler if not enabled.
+		if atomic.Cas(&handlingSig[_SIGPROF], 0, 1) {
+			atomic.Storeuintptr(&fwdSig[_SIGPROF], getsig(_SIGPROF))
+			setsig(_SIGPROF, funcPC(sighandler))
+			atomic.Storeuintptr(&sigprofHandler, getsig(_SIGPROF))
+		}
 	} else {
 		// If the Go signal handler should be disabled by default,
 		// switch back to the signal handler that was installed
@@ -294,6 +308,14 @@ func setProcessCPUProfiler(hz int32) {
 		// that use profiling don't want to crash on a stray SIGPROF.
 		// See issue 19320.
 		if !sigInstallGoHandler(_SIGPROF) {
+			// If another handler replaced ours while profiling,
+			// such as the one of the runtime of another Go shared
+			// library, it forwards SIGPROF to ours, and restoring
+			// the old handler would remove it. Leave ours in
+			// place; it drops the signals while profiling is off.
+			if getsig(_SIGPROF) != atomic.Loaduintptr(&sigprofHandler) {
+				return
+			}
 			if atomic.Cas(&handlingSig[_SIGPROF], 1, 0) {
 				h := atomic.Loaduintptr(&fwdSig[_SIGPROF])
 				if h == _SIG_DFL {
@@ -302,18 +324,9 @@ func setProcessCPUProfiler(hz int32) {
 				setsig(_SIGPROF, h)
 			}
 		}
-
-		setitimer(_ITIMER_PROF, &itimerval{}, nil)
 	}
 }
 
-// setThreadCPUProfiler makes any thread-specific changes required to
-// implement profiling at a rate of hz.
-// No changes required on Unix systems.
-func setThreadCPUProfiler(hz int32) {
-	getg().m.profilehz = hz
-}
-
 func sigpipe() {
 	if signal_ignored(_SIGPIPE) || sigsend(_SIGPIPE) {
 		return
@@ -329,11 +342,13 @@ func doSigPreempt(gp *g, ctxt *sigctxt) {
 		if ok, newpc := isAsyncSafePoint(gp, ctxt.sigpc(), ctxt.sigsp(), ctxt.siglr()); ok {
 			// Adjust the PC and inject a call to asyncPreempt.
 			ctxt.pushCall(funcPC(asyncPreempt), newpc)
+			atomic.Xadd64(&asyncPreemptStats.injected, 1)
 		}
 	}
 
 	// Acknowledge the preemption.
 	atomic.Xadd(&gp.m.preemptGen, 1)
+	counterPreemptsHandled.add(1)
 	atomic.Store(&gp.m.signalPending, 0)
 
 	if GOOS == "darwin" || GOOS == "ios" {
@@ -356,6 +371,7 @@ func preemptM(mp *m) {
 		execLock.rlock()
 	}
 
+	atomic.Xadd64(&asyncPreemptStats.requests, 1)
 	if atomic.Cas(&mp.signalPending, 0, 1) {
 		if GOOS == "darwin" || GOOS == "ios" {
 			atomic.Xadd(&pendingPreemptSignals, 1)
@@ -367,11 +383,32 @@ func preemptM(mp *m) {
 		// issue #37741.
 		// Only send a signal if there isn't already one pending.
 		signalM(mp, sigPreempt)
+	} else {
+		atomic.Xadd64(&asyncPreemptStats.dropped, 1)
+	}
+
+	if GOOS == "darwin" || GOOS == "ios" {
+		execLock.runlock()
 	}
+}
 
+// stwDumpM asks mp to print the stack of what it is running from its
+// preemption signal handler, for GODEBUG=stwwatchdog. It reports
+// whether it could ask.
+func stwDumpM(mp *m) bool {
+	if GOOS == "darwin" || GOOS == "ios" {
+		execLock.rlock()
+		if debug.asyncpreemptoff == 0 {
+			// The handler counts it as a preemption signal.
+			atomic.Xadd(&pendingPreemptSignals, 1)
+		}
+	}
+	atomic.Store(&mp.stwdump, 1)
+	signalM(mp, sigPreempt)
 	if GOOS == "darwin" || GOOS == "ios" {
 		execLock.runlock()
 	}
+	return true
 }
 
 // sigFetchG fetches the value of G safely when running in a signal handler.
@@ -381,7 +418,7 @@ func preemptM(mp *m) {
 //go:nosplit
 func sigFetchG(c *sigctxt) *g {
 	switch GOARCH {
-	case "arm", "arm64", "ppc64", "ppc64le":
+	case "arm", "arm64", "ppc64", "ppc64le", "riscv64", "s390x":
 		if !iscgo && inVDSOPage(c.sigpc()) {
 			// When using cgo, we save the g on TLS and load it from there
 			// in sigtramp. Just use that.
@@ -401,6 +438,59 @@ func sigFetchG(c *sigctxt) *g {
 	return getg()
 }
 
+// sigHandlers holds, for each signal, a *[]unsafe.Pointer listing the
+// handlers installed by AddSignalHandler in the order they were added.
+// A list is never modified once published, so that the signal handler
+// can read it without locking. Writers hold sigHandlersLock.
+var (
+	sigHandlers     [_NSIG]unsafe.Pointer
+	sigHandlersLock mutex
+)
+
+// sigHandlerArg is the argument passed to a handler installed by
+// AddSignalHandler. It must match the struct documented there.
+type sigHandlerArg struct {
+	sig     uintptr
+	info    *siginfo
+	context unsafe.Pointer
+}
+
+func sigAddHandler(sig uint32, fn unsafe.Pointer) {
+	if sig >= _NSIG {
+		panic("AddSignalHandler: invalid argument")
+	}
+	lock(&sigHandlersLock)
+	fns := new([]unsafe.Pointer)
+	if old := sigHandlers[sig]; old != nil {
+		*fns = append(*fns, *(*[]unsafe.Pointer)(old)...)
+	}
+	*fns = append(*fns, fn)
+	atomicstorep(unsafe.Pointer(&sigHandlers[sig]), unsafe.Pointer(fns))
+	unlock(&sigHandlersLock)
+}
+
+// sigRunHandlers calls the handlers installed by AddSignalHandler for sig
+// and reports whether one of them handled the signal.
+//
+//go:nosplit
+//go:nowritebarrierrec
+func sigRunHandlers(sig uint32, info *siginfo, ctx unsafe.Pointer) bool {
+	if sig >= _NSIG {
+		return false
+	}
+	fns := (*[]unsafe.Pointer)(atomic.Loadp(unsafe.Pointer(&sigHandlers[sig])))
+	if fns == nil {
+		return false
+	}
+	arg := sigHandlerArg{sig: uintptr(sig), info: info, context: ctx}
+	for _, fn := range *fns {
+		if asmcgocall(fn, noescape(unsafe.Pointer(&arg))) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
 // sigtrampgo is called from the signal handler function, sigtramp,
 // written in assembly code.
 // This is called by the signal handler, and the world may be stopped.
@@ -421,7 +511,9 @@ func sigtrampgo(sig uint32, info *siginfo, ctx unsafe.Pointer) {
 	setg(g)
 	if g == nil {
 		if sig == _SIGPROF {
-			sigprofNonGoPC(c.sigpc())
+			if validSIGPROF(nil, c) {
+				sigprofNonGoPC(c.sigpc())
+			}
 			return
 		}
 		if sig == sigPreempt && preemptMSupported && debug.asyncpreemptoff == 0 {
@@ -456,7 +548,9 @@ func sigtrampgo(sig uint32, info *siginfo, ctx unsafe.Pointer) {
 	}
 
 	c.fixsigcode(sig)
-	sighandler(sig, info, ctx, g)
+	if !sigRunHandlers(sig, info, ctx) {
+		sighandler(sig, info, ctx, g)
+	}
 	setg(g)
 	if setStack {
 		restoreGsignalStack(&gsignalStack)
@@ -538,7 +632,9 @@ func sighandler(sig uint32, info *siginfo, ctxt unsafe.Pointer, gp *g) {
 	c := &sigctxt{info, ctxt}
 
 	if sig == _SIGPROF {
-		sigprof(c.sigpc(), c.sigsp(), c.siglr(), gp, _g_.m)
+		if validSIGPROF(_g_.m, c) {
+			sigprof(c.sigpc(), c.sigsp(), c.siglr(), gp, _g_.m)
+		}
 		return
 	}
 
@@ -546,10 +642,18 @@ func sighandler(sig uint32, info *siginfo, ctxt unsafe.Pointer, gp *g) {
 		return
 	}
 
+	if sig == _SIGTRAP && watchpointSignal(_g_.m, gp, c.sigpc()) {
+		return
+	}
+
 	if sig == _SIGUSR1 && testSigusr1 != nil && testSigusr1(gp) {
 		return
 	}
 
+	if sig == sigPreempt && atomic.Load(&_g_.m.stwdump) != 0 && atomic.Cas(&_g_.m.stwdump, 1, 0) {
+		stwDumpStack(gp, c.sigpc(), c.sigsp(), c.siglr())
+	}
+
 	if sig == sigPreempt && debug.asyncpreemptoff == 0 {
 		// Might be a preemption signal.
 		doSigPreempt(gp, c)
@@ -671,6 +775,10 @@ func sighandler(sig uint32, info *siginfo, ctxt unsafe.Pointer, gp *g) {
 		dumpregs(c)
 	}
 
+	if crashing == 0 && crashDumpFD >= 0 {
+		writeMinidump(sig, c, gp)
+	}
+
 	if docrash {
 		crashing++
 		if crashing < mcount()-int32(extraMCount) {
@@ -692,6 +800,7 @@ func sighandler(sig uint32, info *siginfo, ctxt unsafe.Pointer, gp *g) {
 	}
 
 	printDebugLog()
+	printGStatusTrace()
 
 	exit(2)
 }
@@ -1000,6 +1109,15 @@ func sigfwdgo(sig uint32, info *siginfo, ctx unsafe.Pointer) bool {
 	}
 
 	c := &sigctxt{info, ctx}
+	// A SIGPROF on a thread that is not running this runtime's code
+	// is most likely meant for the handler we replaced, such as the
+	// one of the runtime of another Go shared library profiling its
+	// own threads. Let that handler have it, at the cost of a sample
+	// of non-Go code in our own profile.
+	if sig == _SIGPROF && fwdFn != _SIG_IGN && sigFetchG(c) == nil {
+		sigfwd(fwdFn, sig, info, ctx)
+		return true
+	}
 	// Only forward synchronous signals and SIGPIPE.
 	// Unfortunately, user generated SIGPIPEs will also be forwarded, because si_code
 	// is set to _SI_USER even for a SIGPIPE raised from a write to a closed socket
@@ -284,6 +284,10 @@ func sigdisable(sig uint32) {
 func sigignore(sig uint32) {
 }
 
+func sigAddHandler(sig uint32, fn unsafe.Pointer) {
+	panic("AddSignalHandler: not supported on windows")
+}
+
 func badsignal2()
 
 func raisebadsignal(sig uint32) {
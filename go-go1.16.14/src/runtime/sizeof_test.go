@@ -7,6 +7,7 @@ package runtime_test
 import (
 	"reflect"
 	"runtime"
+	"sync"
 	"testing"
 	"unsafe"
 )
@@ -21,7 +22,7 @@ func TestSizeof(t *testing.T) {
 		_32bit uintptr     // size on 32bit platforms
 		_64bit uintptr     // size on 64bit platforms
 	}{
-		{runtime.G{}, 216, 376},   // g, but exported for testing
+		{runtime.G{}, 316, 496},   // g, but exported for testing
 		{runtime.Sudog{}, 56, 88}, // sudog, but exported for testing
 	}
 
@@ -36,3 +37,35 @@ func TestSizeof(t *testing.T) {
 		}
 	}
 }
+
+// Assert that the offsets of the sync.Mutex and sync.RWMutex fields
+// that the deadlock detector relies on match package sync.
+
+func TestSyncMutexOffsets(t *testing.T) {
+	offset := func(typ reflect.Type, name string) uintptr {
+		f, ok := typ.FieldByName(name)
+		if !ok {
+			t.Fatalf("%v has no field %s", typ, name)
+		}
+		return f.Offset
+	}
+	mu := reflect.TypeOf(sync.Mutex{})
+	rw := reflect.TypeOf(sync.RWMutex{})
+
+	var tests = []struct {
+		field     string
+		got, want uintptr
+	}{
+		{"sync.Mutex.state", offset(mu, "state"), 0},
+		{"sync.Mutex.sema", offset(mu, "sema"), runtime.SyncMutexSemaOffset},
+		{"sync.RWMutex.w", offset(rw, "w"), 0},
+		{"sync.RWMutex.writerSem", offset(rw, "writerSem"), runtime.SyncRWMutexWriterSemOffset},
+		{"sync.RWMutex.readerSem", offset(rw, "readerSem"), runtime.SyncRWMutexReaderSemOffset},
+	}
+
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("offset of %s = %d, runtime assumes %d", tt.field, tt.got, tt.want)
+		}
+	}
+}
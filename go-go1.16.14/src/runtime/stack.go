@@ -133,6 +133,11 @@ const (
 	// Stored into g->stackguard0 to cause split stack check failure.
 	// Must be greater than any real sp.
 	stackFork = uintptrMask & -1234
+
+	// Goroutine stack move request, for g.stackMove.
+	// Stored into g->stackguard0 to cause split stack check failure.
+	// Must be greater than any real sp.
+	stackForceMove = uintptrMask & -275
 )
 
 // Global pool of spans that have free stacks.
@@ -845,6 +850,7 @@ func copystack(gp *g, newsize uintptr) {
 		throw("nil stackbase")
 	}
 	used := old.hi - gp.sched.sp
+	counterStackCopies.add(1)
 
 	// allocate new stack
 	new := stackalloc(uint32(newsize))
@@ -909,7 +915,7 @@ func copystack(gp *g, newsize uintptr) {
 	gentraceback(^uintptr(0), ^uintptr(0), 0, gp, 0, nil, 0x7fffffff, adjustframe, noescape(unsafe.Pointer(&adjinfo)), 0)
 
 	// free old stack
-	if stackPoisonCopy != 0 {
+	if stackPoisonCopy != 0 || gp.stackMove {
 		fillstack(old, 0xfc)
 	}
 	stackfree(old)
@@ -1053,13 +1059,19 @@ func newstack() {
 	// Make sure we grow at least as much as needed to fit the new frame.
 	// (This is just an optimization - the caller of morestack will
 	// recheck the bounds on return.)
+	var max uintptr
 	if f := findfunc(gp.sched.pc); f.valid() {
-		max := uintptr(funcMaxSPDelta(f))
+		max = uintptr(funcMaxSPDelta(f))
 		for newsize-oldsize < max+_StackGuard {
 			newsize *= 2
 		}
 	}
 
+	// With g.stackMove, only move the stack if the new frame fits.
+	if gp.stackMove && sp >= gp.stack.lo+_StackGuard+max {
+		newsize = oldsize
+	}
+
 	if newsize > maxstacksize || newsize > maxstackceiling {
 		if maxstacksize < maxstackceiling {
 			print("runtime: goroutine stack exceeds ", maxstacksize, "-byte limit\n")
@@ -1080,6 +1092,15 @@ func newstack() {
 	if stackDebug >= 1 {
 		print("stack grow done\n")
 	}
+	if gp.stackMove && !gp.preempt {
+		// Let the function that called morestack through, but
+		// make any call below its frame move the stack again.
+		guard := gp.sched.sp - max
+		if guard < gp.stack.lo+_StackGuard {
+			guard = gp.stack.lo + _StackGuard
+		}
+		gp.stackguard0 = guard
+	}
 	casgstatus(gp, _Gcopystack, _Grunning)
 	gogo(&gp.sched)
 }
@@ -1334,3 +1355,20 @@ type stackObjectRecord struct {
 func morestackc() {
 	throw("attempt to execute system stack code on user stack")
 }
+
+// setStackMove sets whether the calling goroutine moves its stack to a
+// new one at stack checks, and returns the previous setting.
+//go:linkname setStackMove runtime/debug.setStackMove
+func setStackMove(enabled bool) bool {
+	gp := getg()
+	old := gp.stackMove
+	gp.stackMove = enabled
+	if !gp.preempt {
+		if enabled {
+			gp.stackguard0 = stackForceMove
+		} else {
+			gp.stackguard0 = gp.stack.lo + _StackGuard
+		}
+	}
+	return old
+}
@@ -491,6 +491,48 @@ func TestStackAllOutput(t *testing.T) {
 	}
 }
 
+func TestStackFiltered(t *testing.T) {
+	c := make(chan int)
+	ready := make(chan bool)
+	go stackFilterBlocked(ready, c)
+	<-ready
+	defer close(c)
+
+	b := make([]byte, 64<<10)
+	for _, tt := range []struct {
+		filter StackFilter
+		want   bool
+	}{
+		{StackFilter{FuncPrefix: "runtime_test.stackFilterBlocked"}, true},
+		{StackFilter{State: "waiting", FuncPrefix: "runtime_test.stackFilter"}, true},
+		{StackFilter{State: "runnable", FuncPrefix: "runtime_test.stackFilterBlocked"}, false},
+		{StackFilter{FuncPrefix: "runtime_test.noSuchFunc"}, false},
+		{StackFilter{MinWait: int64(time.Hour), FuncPrefix: "runtime_test.stackFilterBlocked"}, false},
+	} {
+		// The goroutine may not have parked yet.
+		var stk string
+		for i := 0; i < 100; i++ {
+			stk = string(b[:StackFiltered(b, &tt.filter)])
+			if strings.Contains(stk, "stackFilterBlocked") == tt.want {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		if got := strings.Contains(stk, "stackFilterBlocked"); got != tt.want {
+			t.Errorf("StackFiltered(%+v) includes stackFilterBlocked = %v, want %v:\n%s", tt.filter, got, tt.want, stk)
+		}
+		if strings.Contains(stk, "\nruntime_test.TestStackFiltered(") {
+			t.Errorf("StackFiltered(%+v) includes the running caller:\n%s", tt.filter, stk)
+		}
+	}
+}
+
+//go:noinline
+func stackFilterBlocked(ready chan<- bool, c <-chan int) {
+	ready <- true
+	<-c
+}
+
 func TestStackPanic(t *testing.T) {
 	// Test that stack copying copies panics correctly. This is difficult
 	// to test because it is very unlikely that the stack will be copied
@@ -892,3 +934,24 @@ func tracebackNotExcluded() {
 	var x *int
 	*x = 0
 }
+
+func TestSetGoroutineName(t *testing.T) {
+	stack := func() string {
+		buf := make([]byte, 1024)
+		return string(buf[:Stack(buf, false)])
+	}
+	done := make(chan [2]string)
+	go func() {
+		SetGoroutineName("stack-test")
+		named := stack()
+		SetGoroutineName("")
+		done <- [2]string{named, stack()}
+	}()
+	r := <-done
+	if !regexp.MustCompile(`^goroutine \d+ \[running, "stack-test"\]:\n`).MatchString(r[0]) {
+		t.Errorf("stack of named goroutine:\n%s", r[0])
+	}
+	if !regexp.MustCompile(`^goroutine \d+ \[running\]:\n`).MatchString(r[1]) {
+		t.Errorf("stack of goroutine after removing its name:\n%s", r[1])
+	}
+}
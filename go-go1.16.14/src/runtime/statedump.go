@@ -0,0 +1,105 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Implementation of runtime/debug.WriteStateDump. Writes the scheduler
+// state, the run queues, the memory statistics and the stacks of all
+// goroutines to a file, all taken with the world stopped.
+
+package runtime
+
+import _ "unsafe" // for go:linkname
+
+// stateDumpBufSize is the size of the buffer the dump is formatted
+// into before it is written out.
+const stateDumpBufSize = 16 << 10
+
+//go:linkname runtime_debug_WriteStateDump runtime/debug.WriteStateDump
+func runtime_debug_WriteStateDump(fd uintptr) {
+	buf := make([]byte, stateDumpBufSize)
+	gp := getg()
+	sp := getcallersp()
+	pc := getcallerpc()
+
+	stopTheWorld("write state dump")
+
+	// Keep m on this G's stack instead of the system stack, as in
+	// WriteHeapDump. This is safe because the world is stopped.
+	var m MemStats
+	systemstack(func() {
+		readmemstats_m(&m)
+
+		g0 := getg()
+		g0.m.traceback = 1
+		g0.m.flushwrite = true
+		g0.m.writefd = fd
+		g0.writebuf = buf[0:0:len(buf)]
+
+		print("runtime state dump\n\n")
+		schedtrace(true)
+		print("\n")
+		dumprunqs()
+		print("\n")
+		printmemstats(&m)
+		print("\n")
+		goroutineheader(gp)
+		traceback(pc, sp, 0, gp)
+		tracebackothers(gp)
+
+		flushwritebuf(g0)
+		g0.writebuf = nil
+		g0.m.flushwrite = false
+		g0.m.writefd = 0
+		g0.m.traceback = 0
+	})
+
+	startTheWorld()
+}
+
+// dumprunqs prints the goroutines in the global run queue and in the
+// run queue of each P. The world must be stopped.
+func dumprunqs() {
+	print("runq:")
+	for gp := sched.runq.head.ptr(); gp != nil; gp = gp.schedlink.ptr() {
+		print(" ", gp.goid)
+	}
+	print("\n")
+	for i, _p_ := range allp {
+		print("  P", i, " runq:")
+		if next := _p_.runnext.ptr(); next != nil {
+			print(" runnext=", next.goid)
+		}
+		for j := _p_.runqhead; j != _p_.runqtail; j++ {
+			print(" ", _p_.runq[j%uint32(len(_p_.runq))].ptr().goid)
+		}
+		print("\n")
+	}
+}
+
+// printmemstats prints the main fields of m, in the format used by the
+// heap profile at debug=1 less the leading "# ".
+func printmemstats(m *MemStats) {
+	print("runtime.MemStats\n")
+	print("Alloc = ", m.Alloc, "\n")
+	print("TotalAlloc = ", m.TotalAlloc, "\n")
+	print("Sys = ", m.Sys, "\n")
+	print("Lookups = ", m.Lookups, "\n")
+	print("Mallocs = ", m.Mallocs, "\n")
+	print("Frees = ", m.Frees, "\n")
+	print("HeapAlloc = ", m.HeapAlloc, "\n")
+	print("HeapSys = ", m.HeapSys, "\n")
+	print("HeapIdle = ", m.HeapIdle, "\n")
+	print("HeapInuse = ", m.HeapInuse, "\n")
+	print("HeapReleased = ", m.HeapReleased, "\n")
+	print("HeapObjects = ", m.HeapObjects, "\n")
+	print("Stack = ", m.StackInuse, " / ", m.StackSys, "\n")
+	print("MSpan = ", m.MSpanInuse, " / ", m.MSpanSys, "\n")
+	print("MCache = ", m.MCacheInuse, " / ", m.MCacheSys, "\n")
+	print("BuckHashSys = ", m.BuckHashSys, "\n")
+	print("GCSys = ", m.GCSys, "\n")
+	print("OtherSys = ", m.OtherSys, "\n")
+	print("NextGC = ", m.NextGC, "\n")
+	print("LastGC = ", m.LastGC, "\n")
+	print("NumGC = ", m.NumGC, "\n")
+	print("NumForcedGC = ", m.NumForcedGC, "\n")
+}
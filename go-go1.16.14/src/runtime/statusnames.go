@@ -0,0 +1,43 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+import _ "unsafe" // for go:linkname
+
+var pStatusStrings = [...]string{
+	_Pidle:    "idle",
+	_Prunning: "running",
+	_Psyscall: "syscall",
+	_Pgcstop:  "gcstop",
+	_Pdead:    "dead",
+}
+
+// gStatusString returns the name of G status s as printed in
+// goroutine tracebacks, with " (scan)" appended if the _Gscan bit is
+// set.
+//go:linkname gStatusString runtime/debug.gStatusString
+func gStatusString(s uint32) string {
+	name := "???"
+	if st := s &^ _Gscan; st < uint32(len(gStatusStrings)) && gStatusStrings[st] != "" {
+		name = gStatusStrings[st]
+	}
+	if s&_Gscan != 0 {
+		name += " (scan)"
+	}
+	return name
+}
+
+//go:linkname pStatusString runtime/debug.pStatusString
+func pStatusString(s uint32) string {
+	if s < uint32(len(pStatusStrings)) {
+		return pStatusStrings[s]
+	}
+	return "???"
+}
+
+//go:linkname waitReasonString runtime/debug.waitReasonString
+func waitReasonString(r uint8) string {
+	return waitReason(r).String()
+}
@@ -6,7 +6,41 @@
 
 package runtime
 
+import "runtime/internal/atomic"
+
 // sbrk0 returns the current process brk, or 0 if not implemented.
 func sbrk0() uintptr {
 	return 0
 }
+
+// Threads created by C code only need to be registered with the profiler
+// on Linux, where each thread can have a profiling timer of its own.
+func addProfThread(tid int32)    {}
+func removeProfThread(tid int32) {}
+
+// Memory pressure is only sampled from cgroup v2 on Linux.
+func memPressureInit() bool                { return false }
+func readMemPressure(s *memPressureSample) {}
+
+// CPU quotas are only read from cgroup v2 on Linux.
+func readCgroupCPUQuota() (quota, period int64, ok bool) { return 0, 0, false }
+
+// Priority-inheritance locks are only available on Linux.
+func lockPIInit()               {}
+func lock2pi(l *mutex, mp *m)   { throw("lock2pi") }
+func unlock2pi(l *mutex, mp *m) { throw("unlock2pi") }
+
+// Only Linux can sleep on a futex without an m, as needm must. Other
+// systems poll, backing off to a millisecond between checks.
+//go:nosplit
+func extraMSleep(addr *uint32, val uint32) {
+	for delay := uint32(1); atomic.Load(addr) == val; {
+		usleep(delay)
+		if delay < 1000 {
+			delay *= 2
+		}
+	}
+}
+
+//go:nosplit
+func extraMWakeup(addr *uint32) {}
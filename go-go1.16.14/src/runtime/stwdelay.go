@@ -0,0 +1,133 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+import (
+	"runtime/internal/atomic"
+	"unsafe"
+)
+
+// stwDelay describes the last time stopTheWorldWithSema had to wait
+// more than one preemption round for a running goroutine to stop,
+// for runtime/debug.ReadSTWDelay. The goroutine recorded is the last
+// one seen running, and its stack is taken where it finally stopped,
+// which is the first point after the delay where it could.
+var stwDelay struct {
+	lock   mutex
+	reason string
+	wait   int64 // nanoseconds spent stopping the world
+	goid   int64 // 0 if no stop has been delayed yet
+	n      int
+	stk    [32]uintptr
+}
+
+// stwRunningG returns a goroutine still running on some P while the
+// world is being stopped, or nil. It reads the Ps without locks, as
+// preemptall does.
+func stwRunningG() *g {
+	for _, p := range allp {
+		if p.status != _Prunning {
+			continue
+		}
+		if mp := p.m.ptr(); mp != nil {
+			if gp := mp.curg; gp != nil {
+				return gp
+			}
+		}
+	}
+	return nil
+}
+
+// recordSTWDelay records that stopping the world for reason took wait
+// nanoseconds because of gp. The world is stopped, so gp's stack is
+// stable unless gp is still running without a P.
+//
+//go:systemstack
+func recordSTWDelay(reason string, gp *g, wait int64) {
+	var pc, sp, lr uintptr
+	switch readgstatus(gp) &^ _Gscan {
+	case _Grunnable, _Gwaiting, _Gpreempted:
+		pc, sp, lr = gp.sched.pc, gp.sched.sp, gp.sched.lr
+	case _Gsyscall:
+		pc, sp = gp.syscallpc, gp.syscallsp
+	}
+	lock(&stwDelay.lock)
+	stwDelay.reason = reason
+	stwDelay.wait = wait
+	stwDelay.goid = gp.goid
+	stwDelay.n = 0
+	if sp != 0 {
+		stwDelay.n = gentraceback(pc, sp, lr, gp, 0, &stwDelay.stk[0], len(stwDelay.stk), nil, nil, 0)
+	}
+	unlock(&stwDelay.lock)
+}
+
+//go:linkname readSTWDelay runtime/debug.readSTWDelay
+func readSTWDelay() (reason string, wait, goid int64, stk []uintptr) {
+	var buf [len(stwDelay.stk)]uintptr
+	lock(&stwDelay.lock)
+	reason, wait, goid = stwDelay.reason, stwDelay.wait, stwDelay.goid
+	n := copy(buf[:], stwDelay.stk[:stwDelay.n])
+	unlock(&stwDelay.lock)
+	if n > 0 {
+		stk = make([]uintptr, n)
+		copy(stk, buf[:n])
+	}
+	return
+}
+
+// stwWatchdog reports the Ps that keep stopping the world for reason
+// from completing after waited nanoseconds, for GODEBUG=stwwatchdog,
+// and asks their Ms to print their stacks. It reads the Ps and Ms
+// without locks, as preemptall does.
+func stwWatchdog(reason string, waited int64) {
+	printlock()
+	print("runtime: stwwatchdog: stopping the world")
+	if reason != "" {
+		print(" for \"", reason, "\"")
+	}
+	print(" has waited ", waited/1000, "us for:\n")
+	for _, p := range allp {
+		mp := p.m.ptr()
+		if p.status != _Prunning || mp == nil {
+			continue
+		}
+		print("\tP", p.id, " M", mp.id, " procid=", mp.procid)
+		if gp := mp.curg; gp != nil {
+			print(" goroutine ", gp.goid, " (", funcname(findfunc(gp.startpc)), ")")
+		}
+		if mp.locks != 0 {
+			print(" locks=", mp.locks)
+		}
+		if mp.mallocing != 0 {
+			print(" mallocing")
+		}
+		if !stwDumpM(mp) {
+			print(" (stack not available)")
+		}
+		print("\n")
+	}
+	printunlock()
+}
+
+// stwWatchdogDone cancels the stack requests of stwWatchdog that the
+// Ms have not handled yet, now that the world is stopped.
+func stwWatchdogDone() {
+	for mp := (*m)(atomic.Loadp(unsafe.Pointer(&allm))); mp != nil; mp = mp.alllink {
+		atomic.Store(&mp.stwdump, 0)
+	}
+}
+
+// stwDumpStack prints the stack of gp, interrupted at pc, sp and lr,
+// for stwWatchdog. It runs in the signal handler of the M asked.
+//
+//go:nowritebarrierrec
+func stwDumpStack(gp *g, pc, sp, lr uintptr) {
+	printlock()
+	print("runtime: stwwatchdog: M", getg().m.id, " is running:\n")
+	goroutineheader(gp)
+	tracebacktrap(pc, sp, lr, gp)
+	printunlock()
+}
@@ -0,0 +1,77 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+import "runtime/internal/atomic"
+
+// Stop-the-world pauses are grouped in classes by the reason given to
+// stopTheWorld, for the /sched/stw/*:seconds metrics.
+const (
+	stwGC = iota
+	stwGOMAXPROCS
+	stwMemStats
+	stwProfile
+	stwTrace
+	stwOther
+
+	stwClasses
+)
+
+// stwPauses holds, for each class, the distribution of the time from
+// the start of stopTheWorldWithSema to the end of the matching
+// startTheWorldWithSema, that is, how long the world was stopped,
+// including the time it took to stop it.
+var stwPauses [stwClasses]timeHistogram
+
+// stwPause describes the stop-the-world in progress. Only one can be
+// in progress at a time, so it is protected by worldsema.
+var stwPause struct {
+	start int64 // nanotime, 0 if none is in progress
+	class int
+}
+
+// stwClass returns the class of a stop-the-world for reason.
+func stwClass(reason string) int {
+	switch reason {
+	case "", "gcing":
+		// gcStart stops the world without a reason.
+		return stwGC
+	case "GOMAXPROCS":
+		return stwGOMAXPROCS
+	case "read mem stats", "read alloc stats":
+		return stwMemStats
+	case "profile", "profile cleanup", "stack trace", "goroutines":
+		return stwProfile
+	case "start tracing", "stop tracing", "trace snapshot":
+		return stwTrace
+	}
+	return stwOther
+}
+
+// stwPauseStart records that stopping the world for reason starts now.
+func stwPauseStart(reason string) {
+	stwPause.class = stwClass(reason)
+	stwPause.start = nanotime()
+}
+
+// stwPauseEnd records that the world stopped by the last call to
+// stwPauseStart restarts at now.
+func stwPauseEnd(now int64) {
+	if stwPause.start == 0 {
+		return
+	}
+	stwPauses[stwPause.class].record(now - stwPause.start)
+	stwPause.start = 0
+}
+
+// stwPauseHist copies the distribution h to out, for the
+// /sched/stw/*:seconds metrics.
+func stwPauseHist(h *timeHistogram, out *metricValue) {
+	hist := out.float64HistOrInit(timeHistBuckets)
+	hist.counts[0] = atomic.Load64(&h.underflow)
+	for i := range h.counts {
+		hist.counts[i+1] = atomic.Load64(&h.counts[i])
+	}
+}
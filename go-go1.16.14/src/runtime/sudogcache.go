@@ -0,0 +1,126 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Central sudog cache.
+//
+// Each P caches sudogs for acquireSudog and releaseSudog. A P whose
+// cache is empty refills half of it from the central cache, and a P
+// whose cache is full moves half of it there. The central cache is
+// sharded by P ID, so that Ps on a large machine do not all contend
+// for one lock. A P spills to its own shard, and refills from its own
+// shard first and then from the others, skipping the shards that are
+// empty without locking them.
+
+package runtime
+
+import (
+	"internal/cpu"
+	"runtime/internal/atomic"
+	"unsafe"
+)
+
+// sudogShards is the number of shards of the central sudog cache.
+const sudogShards = 16
+
+type sudogShard struct {
+	lock countedLock // first, for the alignment of its counter
+
+	// Counters, written with lock held and read atomically.
+	refills uint64 // refills of a per-P cache served from this shard
+	steals  uint64 // of which for Ps of other shards
+	spills  uint64 // spills of a per-P cache to this shard
+
+	cache *sudog
+	n     uint32 // number of sudogs in cache; written with lock held, read atomically
+}
+
+var sudogCentral [sudogShards]struct {
+	shard sudogShard
+	pad   [cpu.CacheLinePadSize - unsafe.Sizeof(sudogShard{})%cpu.CacheLinePadSize]byte
+}
+
+// sudogAllocs counts the sudogs allocated because the per-P and central
+// caches were empty. Accessed atomically.
+var sudogAllocs uint64
+
+func sudogcacheinit() {
+	for i := range sudogCentral {
+		sudogCentral[i].shard.lock.init(lockRankSudog)
+	}
+}
+
+// sudogRefill fills the empty sudog cache of pp up to half its
+// capacity from the central cache, if it has any sudogs.
+func sudogRefill(pp *p) {
+	home := int(pp.id) % sudogShards
+	for i := 0; i < sudogShards && len(pp.sudogcache) == 0; i++ {
+		s := &sudogCentral[(home+i)%sudogShards].shard
+		if atomic.Load(&s.n) == 0 {
+			continue
+		}
+		s.lock.lock()
+		n := s.n
+		for len(pp.sudogcache) < cap(pp.sudogcache)/2 && s.cache != nil {
+			sg := s.cache
+			s.cache = sg.next
+			sg.next = nil
+			pp.sudogcache = append(pp.sudogcache, sg)
+			n--
+		}
+		if n != s.n {
+			atomic.Store(&s.n, n)
+			atomic.Store64(&s.refills, s.refills+1)
+			if i > 0 {
+				atomic.Store64(&s.steals, s.steals+1)
+			}
+		}
+		s.lock.unlock()
+	}
+	if len(pp.sudogcache) == 0 {
+		atomic.Xadd64(&sudogAllocs, 1)
+	}
+}
+
+// sudogSpill moves the n sudogs linked from first to last, taken from
+// the cache of pp, to the central cache.
+func sudogSpill(pp *p, first, last *sudog, n int) {
+	s := &sudogCentral[int(pp.id)%sudogShards].shard
+	s.lock.lock()
+	last.next = s.cache
+	s.cache = first
+	atomic.Store(&s.n, s.n+uint32(n))
+	atomic.Store64(&s.spills, s.spills+1)
+	s.lock.unlock()
+}
+
+// clearSudogCentral drops the central sudog cache. It is called by
+// clearpools.
+func clearSudogCentral() {
+	for i := range sudogCentral {
+		s := &sudogCentral[i].shard
+		s.lock.lock()
+		// Disconnect cached list before dropping it on the floor,
+		// so that a dangling ref to one entry does not pin all of them.
+		var sg, sgnext *sudog
+		for sg = s.cache; sg != nil; sg = sgnext {
+			sgnext = sg.next
+			sg.next = nil
+		}
+		s.cache = nil
+		atomic.Store(&s.n, 0)
+		s.lock.unlock()
+	}
+}
+
+// readSudogStats returns the sums of the counters of the shards.
+func readSudogStats() (refills, steals, spills, contended uint64) {
+	for i := range sudogCentral {
+		s := &sudogCentral[i].shard
+		refills += atomic.Load64(&s.refills)
+		steals += atomic.Load64(&s.steals)
+		spills += atomic.Load64(&s.spills)
+		contended += atomic.Load64(&s.lock.contended)
+	}
+	return
+}
@@ -0,0 +1,149 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Symbolization cache.
+//
+// Finding the file and line of a program counter, and the call inlined
+// at it, decodes pcvalue tables from the entry of its function up to
+// the program counter. Tracebacks, profiles and runtime.Frames
+// symbolize the same program counters over and over, so the results
+// are kept in symCache, a set-associative cache that replaces the
+// least recently used entry of a set.
+//
+// symCache is read and written without locks, so tracebacks printed
+// from a signal handler or without a P can use it too. Each entry has
+// a sequence number that is odd while the entry is written. A reader
+// that sees it odd, or changed by the time it has read the entry,
+// misses; a writer that sees it odd leaves the entry to the other
+// writer. Entries point only into module data, which is never freed,
+// and hold the pointers as uintptrs, so writing them needs no write
+// barriers.
+
+package runtime
+
+import (
+	"runtime/internal/atomic"
+	"unsafe"
+)
+
+const (
+	symCacheWays     = 4
+	symCacheSetsLog2 = 9
+	symCacheSets     = 1 << symCacheSetsLog2
+)
+
+var symCache struct {
+	// hits and misses count lookups. Accessed atomically.
+	// First for 64-bit alignment.
+	hits   uint64
+	misses uint64
+
+	// clock advances with each fill. used records it on each hit.
+	clock uint32
+
+	sets [symCacheSets][symCacheWays]symCacheEntry
+}
+
+// symCacheEntry is the symbolization of pc in the function fn.
+// All fields are accessed atomically.
+type symCacheEntry struct {
+	seq  uint32 // odd while the entry is written
+	used uint32 // symCache.clock when last filled or hit
+
+	pc   uintptr // 0 if the entry is empty
+	fn   uintptr // *_func
+	file uintptr // file name data
+	flen uintptr // file name length
+	line uint32
+	inl  uint32 // inline tree index, or -1
+}
+
+// symCacheSet returns the set of symCache that may hold pc.
+func symCacheSet(pc uintptr) *[symCacheWays]symCacheEntry {
+	h := (uint32(pc) ^ uint32(uint64(pc)>>32)) * 0x9e3779b1
+	return &symCache.sets[h>>(32-symCacheSetsLog2)]
+}
+
+// symbolize returns the file and line of targetpc in f, and the index
+// in f's inline tree of the call inlined at targetpc, or -1 if there
+// is none. The file and line lookups are strict or not as strict is
+// for pcvalue; the inline tree index lookup is not strict, as
+// cgoTraceback may add bogus PCs with a valid funcInfo but invalid
+// PCDATA. Results are taken from, and added to, symCache.
+func symbolize(f funcInfo, targetpc uintptr, strict bool) (file string, line int32, inl int32) {
+	if !f.valid() {
+		return "?", 0, -1
+	}
+	fn := uintptr(unsafe.Pointer(f._func))
+	set := symCacheSet(targetpc)
+	for i := range set {
+		e := &set[i]
+		seq := atomic.Load(&e.seq)
+		if seq&1 != 0 || atomic.Loaduintptr(&e.pc) != targetpc || atomic.Loaduintptr(&e.fn) != fn {
+			continue
+		}
+		ss := stringStruct{
+			str: unsafe.Pointer(atomic.Loaduintptr(&e.file)),
+			len: int(atomic.Loaduintptr(&e.flen)),
+		}
+		line = int32(atomic.Load(&e.line))
+		inl = int32(atomic.Load(&e.inl))
+		if atomic.Load(&e.seq) != seq {
+			continue
+		}
+		atomic.Store(&e.used, atomic.Load(&symCache.clock))
+		atomic.Xadd64(&symCache.hits, 1)
+		return *(*string)(unsafe.Pointer(&ss)), line, inl
+	}
+	atomic.Xadd64(&symCache.misses, 1)
+
+	datap := f.datap
+	fileno, _ := pcvalue(f, f.pcfile, targetpc, nil, strict)
+	line, _ = pcvalue(f, f.pcln, targetpc, nil, strict)
+	inl = -1
+	if funcdata(f, _FUNCDATA_InlTree) != nil {
+		inl = pcdatavalue1(f, _PCDATA_InlTreeIndex, targetpc, nil, false)
+	}
+	if fileno == -1 || line == -1 || int(fileno) >= len(datap.filetab) {
+		// print("looking for ", hex(targetpc), " in ", funcname(f), " got file=", fileno, " line=", lineno, "\n")
+		// Not cached, so that a strict lookup still fails.
+		return "?", 0, inl
+	}
+	file = funcfile(f, fileno)
+	if targetpc != 0 {
+		symCacheFill(set, targetpc, fn, file, line, inl)
+	}
+	return file, line, inl
+}
+
+// symCacheFill records a symbolization in set, replacing an empty
+// entry or else the least recently used one. It gives up if another
+// writer is filling that entry.
+func symCacheFill(set *[symCacheWays]symCacheEntry, pc, fn uintptr, file string, line, inl int32) {
+	clock := atomic.Xadd(&symCache.clock, 1)
+	e := &set[0]
+	oldest := uint32(0)
+	for i := range set {
+		if atomic.Loaduintptr(&set[i].pc) == 0 {
+			e = &set[i]
+			break
+		}
+		if age := clock - atomic.Load(&set[i].used); age > oldest {
+			e, oldest = &set[i], age
+		}
+	}
+	seq := atomic.Load(&e.seq)
+	if seq&1 != 0 || !atomic.Cas(&e.seq, seq, seq+1) {
+		return
+	}
+	ss := (*stringStruct)(unsafe.Pointer(&file))
+	atomic.Storeuintptr(&e.pc, pc)
+	atomic.Storeuintptr(&e.fn, fn)
+	atomic.Storeuintptr(&e.file, uintptr(ss.str))
+	atomic.Storeuintptr(&e.flen, uintptr(ss.len))
+	atomic.Store(&e.line, uint32(line))
+	atomic.Store(&e.inl, uint32(inl))
+	atomic.Store(&e.used, clock)
+	atomic.Store(&e.seq, seq+2)
+}
@@ -53,6 +53,10 @@ type Frame struct {
 	// Func.Entry().
 	Entry uintptr
 
+	// Kind classifies the function of this call frame, as
+	// Func.Info does, including functions inlined into others.
+	Kind FuncKind
+
 	// The runtime's internal view of the function. This field
 	// is set (funcInfo.valid() returns true) only for Go functions,
 	// not for C functions.
@@ -82,7 +86,7 @@ func (ci *Frames) Next() (frame Frame, more bool) {
 		ci.callers = ci.callers[1:]
 		funcInfo := findfunc(pc)
 		if !funcInfo.valid() {
-			if cgoSymbolizer != nil {
+			if cgoHaveSymbolizer {
 				// Pre-expand cgo frames. We could do this
 				// incrementally, too, but there's no way to
 				// avoid allocation in this case anyway.
@@ -100,15 +104,16 @@ func (ci *Frames) Next() (frame Frame, more bool) {
 			pc--
 		}
 		name := funcname(funcInfo)
+		kind := funcKind(funcInfo.funcID)
 		if inldata := funcdata(funcInfo, _FUNCDATA_InlTree); inldata != nil {
 			inltree := (*[1 << 20]inlinedCall)(inldata)
-			// Non-strict as cgoTraceback may have added bogus PCs
-			// with a valid funcInfo but invalid PCDATA.
-			ix := pcdatavalue1(funcInfo, _PCDATA_InlTreeIndex, pc, nil, false)
+			// Also caches the file and line computed below.
+			_, _, ix := symbolize(funcInfo, pc, false)
 			if ix >= 0 {
 				// Note: entry is not modified. It always refers to a real frame, not an inlined one.
 				f = nil
 				name = funcnameFromNameoff(funcInfo, inltree[ix].func_)
+				kind = funcKind(inltree[ix].funcID)
 				// File/line is already correct.
 				// TODO: remove file/line from InlinedCall?
 			}
@@ -118,6 +123,7 @@ func (ci *Frames) Next() (frame Frame, more bool) {
 			Func:     f,
 			Function: name,
 			Entry:    entry,
+			Kind:     kind,
 			funcInfo: funcInfo,
 			// Note: File,Line set below
 		})
@@ -150,6 +156,87 @@ func (ci *Frames) Next() (frame Frame, more bool) {
 	return
 }
 
+// ExpandFrames appends to frames the frames of the raw program
+// counters in pcs and returns the extended slice. Unlike those Callers
+// returns, raw program counters, as a profiler may sample them, have
+// no frames of their own for calls inlined at them: ExpandFrames
+// returns, for each program counter in turn, the frames of all calls
+// inlined at it, innermost first, followed by the frame of the
+// function it is in. Frames of generated wrappers are kept, with Kind
+// FuncWrapper.
+//
+// As for Callers, each program counter but that of a function's entry
+// is taken to follow the instruction in question, and its innermost
+// frame has PC one less. Each outer frame has the PC of the call
+// inlined into it, as CallersFrames reports it. A program counter
+// outside Go code gives the frames from the symbolizer set by
+// SetCgoTraceback, if any, or else one frame with only PC set.
+//
+// ExpandFrames is faster than CallersFrames for many program counters,
+// in particular ones repeated within pcs.
+func ExpandFrames(pcs []uintptr, frames []Frame) []Frame {
+	type span struct{ start, end int }
+	seen := make(map[uintptr]span)
+	for _, pc := range pcs {
+		if s, ok := seen[pc]; ok {
+			frames = append(frames, frames[s.start:s.end]...)
+			continue
+		}
+		start := len(frames)
+		frames = expandFrames(pc, frames)
+		seen[pc] = span{start, len(frames)}
+	}
+	return frames
+}
+
+// expandFrames appends the frames of the raw program counter pc to
+// frames, for ExpandFrames.
+func expandFrames(pc uintptr, frames []Frame) []Frame {
+	funcInfo := findfunc(pc)
+	if !funcInfo.valid() {
+		if cgoHaveSymbolizer {
+			if cgo := expandCgoFrames(pc); cgo != nil {
+				return append(frames, cgo...)
+			}
+		}
+		return append(frames, Frame{PC: pc})
+	}
+	entry := funcInfo.entry
+	if pc > entry {
+		pc-- // see Frames.Next
+	}
+	tracepc := pc
+	if inldata := funcdata(funcInfo, _FUNCDATA_InlTree); inldata != nil {
+		inltree := (*[1 << 20]inlinedCall)(inldata)
+		for {
+			file, line, ix := symbolize(funcInfo, tracepc, false)
+			if ix < 0 {
+				break
+			}
+			frames = append(frames, Frame{
+				PC:       tracepc,
+				Function: funcnameFromNameoff(funcInfo, inltree[ix].func_),
+				File:     file,
+				Line:     int(line),
+				Entry:    entry,
+				Kind:     funcKind(inltree[ix].funcID),
+			})
+			// Back up to the inline mark in the caller.
+			tracepc = entry + uintptr(inltree[ix].parentPc)
+		}
+	}
+	file, line := funcline1(funcInfo, tracepc, false)
+	return append(frames, Frame{
+		PC:       tracepc,
+		Func:     funcInfo._Func(),
+		Function: funcname(funcInfo),
+		File:     file,
+		Line:     int(line),
+		Entry:    entry,
+		Kind:     funcKind(funcInfo.funcID),
+	})
+}
+
 // runtime_expandFinalInlineFrame expands the final pc in stk to include all
 // "callers" if pc is inline.
 //
@@ -209,11 +296,25 @@ func runtime_expandFinalInlineFrame(stk []uintptr) []uintptr {
 }
 
 // expandCgoFrames expands frame information for pc, known to be
-// a non-Go function, using the cgoSymbolizer hook. expandCgoFrames
-// returns nil if pc could not be expanded.
+// a non-Go function, using the first symbolizer registered with
+// SetCgoTraceback that knows about it. expandCgoFrames returns nil
+// if pc could not be expanded.
 func expandCgoFrames(pc uintptr) []Frame {
+	n := atomic.Load(&ncgoTracebackSets)
+	for i := uint32(0); i < n; i++ {
+		if symbolizer := cgoTracebackSets[i].symbolizer; symbolizer != nil {
+			if frames := expandCgoFrames1(symbolizer, pc); frames != nil {
+				return frames
+			}
+		}
+	}
+	return nil
+}
+
+// expandCgoFrames1 expands frame information for pc using symbolizer.
+func expandCgoFrames1(symbolizer unsafe.Pointer, pc uintptr) []Frame {
 	arg := cgoSymbolizerArg{pc: pc}
-	callCgoSymbolizer(&arg)
+	callCgoSymbolizer(symbolizer, &arg)
 
 	if arg.file == nil && arg.funcName == nil {
 		// No useful information from symbolizer.
@@ -235,7 +336,7 @@ func expandCgoFrames(pc uintptr) []Frame {
 		if arg.more == 0 {
 			break
 		}
-		callCgoSymbolizer(&arg)
+		callCgoSymbolizer(symbolizer, &arg)
 	}
 
 	// No more frames for this PC. Tell the symbolizer we are done.
@@ -243,7 +344,7 @@ func expandCgoFrames(pc uintptr) []Frame {
 	// whole use of Frames, because there would be no good way to tell
 	// the symbolizer when we are done.
 	arg.pc = 0
-	callCgoSymbolizer(&arg)
+	callCgoSymbolizer(symbolizer, &arg)
 
 	return frames
 }
@@ -334,6 +435,7 @@ const (
 	funcID_handleAsyncEvent
 	funcID_asyncPreempt
 	funcID_wrapper // any autogenerated code (hash/eq algorithms, method wrappers, etc.)
+	funcID_debugCallV2
 )
 
 // pcHeader holds data used by the pclntab lookups.
@@ -648,6 +750,123 @@ func (f *Func) FileLine(pc uintptr) (file string, line int) {
 	return file, int(line32)
 }
 
+// A FuncKind classifies a function as the runtime treats it.
+type FuncKind uint8
+
+const (
+	FuncNormal  FuncKind = iota // an ordinary function
+	FuncWrapper                 // generated code: method wrappers, hash and equality functions, etc.
+	FuncRuntime                 // a runtime function that tracebacks treat specially, such as runtime.goexit
+)
+
+// FuncInfo describes how a function was compiled, as the running
+// binary records it for the runtime. The details may change between
+// releases.
+type FuncInfo struct {
+	// Inlined reports whether the Func describes a call inlined
+	// into another function. The other fields are then zero.
+	Inlined bool
+
+	Kind FuncKind
+
+	// ArgsSize is the size in bytes of the arguments and results
+	// of the function on the stack, or -1 if it is unknown, as for
+	// some assembly functions.
+	ArgsSize int
+
+	// FrameSize is the largest SPDelta of the function.
+	FrameSize int
+
+	// DeferReturn reports whether the function calls
+	// runtime.deferreturn before it returns, to run calls it
+	// deferred on the heap or stack.
+	DeferReturn bool
+
+	// OpenCodedDefers reports whether the function runs its
+	// deferred calls inline at its exits.
+	OpenCodedDefers bool
+}
+
+// Info returns the details of how f was compiled.
+func (f *Func) Info() FuncInfo {
+	fn := f.raw()
+	if fn.entry == 0 { // inlined version
+		return FuncInfo{Inlined: true}
+	}
+	fi := f.funcInfo()
+	info := FuncInfo{
+		ArgsSize:        int(fn.args),
+		FrameSize:       int(funcMaxSPDelta(fi)),
+		DeferReturn:     fn.deferreturn != 0,
+		OpenCodedDefers: funcdata(fi, _FUNCDATA_OpenCodedDeferInfo) != nil,
+	}
+	if fn.args == _ArgsSizeUnknown {
+		info.ArgsSize = -1
+	}
+	info.Kind = funcKind(fn.funcID)
+	return info
+}
+
+// funcKind returns the FuncKind of functions with funcID id.
+func funcKind(id funcID) FuncKind {
+	switch id {
+	case funcID_normal:
+		return FuncNormal
+	case funcID_wrapper:
+		return FuncWrapper
+	}
+	return FuncRuntime
+}
+
+// SPDelta returns how many bytes f has moved the stack pointer down
+// from its value at entry, at the program counter pc. It returns -1
+// if pc is not within f or f is an inlined call.
+func (f *Func) SPDelta(pc uintptr) int {
+	fn := f.raw()
+	if fn.entry == 0 { // inlined version
+		return -1
+	}
+	if pc < fn.entry {
+		return -1
+	}
+	// Pass strict=false, as for FileLine.
+	x, _ := pcvalue(f.funcInfo(), fn.pcsp, pc, nil, false)
+	return int(x)
+}
+
+// Tables of PC-dependent values that the compiler records for each
+// function, for Func.PCData.
+const (
+	// PCDataUnsafePoint is -2 at program counters where the
+	// goroutine cannot be preempted asynchronously, -1 where it
+	// can, and -3 to -5 where it can but resumes at the start of a
+	// sequence of instructions or of the function.
+	PCDataUnsafePoint = _PCDATA_UnsafePoint
+
+	// PCDataStackMapIndex is the index of the map of live
+	// pointers in the frame of the function, at calls, or -1.
+	PCDataStackMapIndex = _PCDATA_StackMapIndex
+
+	// PCDataInlTreeIndex is the index in the inlining tree of the
+	// function of the innermost call inlined at the program
+	// counter, or -1 if none is.
+	PCDataInlTreeIndex = _PCDATA_InlTreeIndex
+)
+
+// PCData returns the value of table, one of the PCData constants, for
+// f at the program counter pc. It returns -1 if f has no such table,
+// pc is not within f or f is an inlined call.
+func (f *Func) PCData(table int, pc uintptr) int32 {
+	fn := f.raw()
+	if fn.entry == 0 { // inlined version
+		return -1
+	}
+	if table < 0 || pc < fn.entry {
+		return -1
+	}
+	return pcdatavalue1(f.funcInfo(), uint32(table), pc, nil, false)
+}
+
 func findmoduledatap(pc uintptr) *moduledata {
 	for datap := &firstmoduledata; datap != nil; datap = datap.next {
 		if datap.minpc <= pc && pc < datap.maxpc {
@@ -884,17 +1103,7 @@ func funcfile(f funcInfo, fileno int32) string {
 }
 
 func funcline1(f funcInfo, targetpc uintptr, strict bool) (file string, line int32) {
-	datap := f.datap
-	if !f.valid() {
-		return "?", 0
-	}
-	fileno, _ := pcvalue(f, f.pcfile, targetpc, nil, strict)
-	line, _ = pcvalue(f, f.pcln, targetpc, nil, strict)
-	if fileno == -1 || line == -1 || int(fileno) >= len(datap.filetab) {
-		// print("looking for ", hex(targetpc), " in ", funcname(f), " got file=", fileno, " line=", lineno, "\n")
-		return "?", 0
-	}
-	file = funcfile(f, fileno)
+	file, line, _ = symbolize(f, targetpc, strict)
 	return
 }
 
@@ -5,6 +5,7 @@
 package runtime_test
 
 import (
+	"internal/race"
 	"runtime"
 	"strings"
 	"testing"
@@ -250,3 +251,182 @@ func TestFunctionAlignmentTraceback(t *testing.T) {
 		t.Errorf("frames.Next() got %+v want %+v", frame.Func, f)
 	}
 }
+
+//go:noinline
+func funcInfoArgs(a, b int) int {
+	return a + b
+}
+
+//go:noinline
+func funcInfoOpenDefer(x *int) {
+	defer func() { *x++ }()
+	*x++
+}
+
+//go:noinline
+func funcInfoLoopDefer(x *int, n int) {
+	for i := 0; i < n; i++ {
+		defer func() { *x++ }()
+	}
+}
+
+//go:noinline
+func funcInfoCallers(pcs []uintptr) (int, uintptr) {
+	return runtime.Callers(1, pcs), runtime.FuncForPC(runtime.FuncPC(funcInfoCallers)).Entry()
+}
+
+func TestFuncInfo(t *testing.T) {
+	var x int
+	funcInfoArgs(1, 2)
+	funcInfoOpenDefer(&x)
+	funcInfoLoopDefer(&x, 2)
+
+	info := runtime.FuncForPC(runtime.FuncPC(funcInfoArgs)).Info()
+	if info.Inlined || info.Kind != runtime.FuncNormal {
+		t.Errorf("funcInfoArgs: %+v, want a normal function", info)
+	}
+	if want := 3 * int(unsafe.Sizeof(0)); info.ArgsSize != want {
+		t.Errorf("funcInfoArgs: ArgsSize = %d, want %d", info.ArgsSize, want)
+	}
+	info = runtime.FuncForPC(runtime.FuncPC(funcInfoOpenDefer)).Info()
+	if !info.OpenCodedDefers && !race.Enabled { // instrumented code does not open-code defers
+		t.Errorf("funcInfoOpenDefer: %+v, want open-coded defers", info)
+	}
+	info = runtime.FuncForPC(runtime.FuncPC(funcInfoLoopDefer)).Info()
+	if !info.DeferReturn || info.OpenCodedDefers {
+		t.Errorf("funcInfoLoopDefer: %+v, want a call to deferreturn and no open-coded defers", info)
+	}
+
+	pcs := make([]uintptr, 64)
+	n, entry := funcInfoCallers(pcs)
+	f := runtime.FuncForPC(pcs[0] - 1)
+	if f == nil || f.Entry() != entry {
+		t.Fatalf("FuncForPC(%#x) = %v, want funcInfoCallers", pcs[0]-1, f)
+	}
+	if d := f.SPDelta(entry); d != 0 {
+		t.Errorf("SPDelta at entry = %d, want 0", d)
+	}
+	if d, max := f.SPDelta(pcs[0]-1), f.Info().FrameSize; d <= 0 || d > max {
+		t.Errorf("SPDelta at call = %d, want within (0, %d]", d, max)
+	}
+	// This function has pointers live across the call.
+	if i := runtime.FuncForPC(pcs[1]-1).PCData(runtime.PCDataStackMapIndex, pcs[1]-1); i < 0 {
+		t.Errorf("PCData(PCDataStackMapIndex) at call = %d, want a stack map", i)
+	}
+	if v := f.PCData(100, pcs[0]-1); v != -1 {
+		t.Errorf("PCData(100) = %d, want -1", v)
+	}
+	if d := f.SPDelta(runtime.FuncPC(funcInfoArgs)); d != -1 {
+		t.Errorf("SPDelta outside the function = %d, want -1", d)
+	}
+
+	// The goroutine's outermost frame is runtime.goexit.
+	last := runtime.FuncForPC(pcs[n-1] - 1)
+	if last.Name() != "runtime.goexit" || last.Info().Kind != runtime.FuncRuntime {
+		t.Errorf("outermost frame %s: %+v, want runtime.goexit, a runtime function", last.Name(), last.Info())
+	}
+}
+
+func expandFramesInlined(pcs []uintptr) int {
+	return runtime.Callers(1, pcs)
+}
+
+//go:noinline
+func expandFramesOuter(pcs []uintptr) int {
+	return expandFramesInlined(pcs)
+}
+
+func TestExpandFrames(t *testing.T) {
+	pcs := make([]uintptr, 64)
+	n := expandFramesOuter(pcs)
+	pcs = pcs[:n]
+	var want []runtime.Frame
+	frames := runtime.CallersFrames(pcs)
+	for more := true; more; {
+		var f runtime.Frame
+		f, more = frames.Next()
+		want = append(want, f)
+	}
+	if want[0].Function != "runtime_test.expandFramesInlined" || want[0].Func != nil {
+		t.Skipf("expandFramesInlined not inlined: %+v", want[0])
+	}
+
+	// pcs[0] is a raw program counter in the inlined call.
+	// Callers added pcs[1] for expandFramesOuter.
+	got := runtime.ExpandFrames([]uintptr{pcs[0], 1, pcs[0]}, nil)
+	if len(got) != 5 {
+		t.Fatalf("ExpandFrames returned %d frames, want 5: %+v", len(got), got)
+	}
+	for i, f := range got {
+		if i == 2 {
+			continue
+		}
+		w := want[i%3]
+		if f.PC != w.PC || f.Func != w.Func || f.Function != w.Function || f.File != w.File || f.Line != w.Line || f.Entry != w.Entry || f.Kind != w.Kind {
+			t.Errorf("frame %d = %+v, want %+v", i, f, w)
+		}
+	}
+	if f := got[2]; f != (runtime.Frame{PC: 1}) {
+		t.Errorf("frame for an unknown program counter = %+v, want only PC set", f)
+	}
+
+	got = runtime.ExpandFrames(pcs[n-1:], got[:0])
+	if len(got) != 1 || got[0].Function != "runtime.goexit" || got[0].Kind != runtime.FuncRuntime {
+		t.Errorf("ExpandFrames of the outermost frame = %+v, want runtime.goexit, a runtime function", got)
+	}
+}
+
+func TestSymbolizeCache(t *testing.T) {
+	pcs := make([]uintptr, 64)
+	n := expandFramesOuter(pcs)
+	entry := runtime.FuncForPC(pcs[1]).Entry()
+	hits, _ := runtime.SymCacheStats()
+	var checked int
+	for _, pc := range pcs[:n] {
+		for pass := 0; pass < 2; pass++ {
+			file, line, inl := runtime.Symbolize(pc)
+			wfile, wline, winl := runtime.SymbolizeUncached(pc)
+			if file != wfile || line != wline || inl != winl {
+				t.Errorf("pass %d: Symbolize(%#x) = %s:%d, %d; want %s:%d, %d", pass, pc, file, line, inl, wfile, wline, winl)
+			}
+		}
+		checked++
+	}
+	// Every program counter of expandFramesOuter, including those
+	// of calls inlined into it.
+	for pc := entry; runtime.FuncForPC(pc) != nil && runtime.FuncForPC(pc).Entry() == entry; pc++ {
+		file, line, inl := runtime.Symbolize(pc)
+		wfile, wline, winl := runtime.SymbolizeUncached(pc)
+		if file != wfile || line != wline || inl != winl {
+			t.Errorf("Symbolize(%#x) = %s:%d, %d; want %s:%d, %d", pc, file, line, inl, wfile, wline, winl)
+		}
+		checked++
+	}
+	if hits2, _ := runtime.SymCacheStats(); hits2 == hits {
+		t.Errorf("no symbolization cache hits in %d lookups", checked)
+	}
+}
+
+func BenchmarkExpandFrames(b *testing.B) {
+	// A profile has many samples of the same few stacks.
+	stk := make([]uintptr, 32)
+	stk = stk[:runtime.Callers(0, stk)]
+	var pcs []uintptr
+	for i := 0; i < 100; i++ {
+		pcs = append(pcs, stk...)
+	}
+	b.Run("CallersFrames", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			frames := runtime.CallersFrames(pcs)
+			for more := true; more; {
+				_, more = frames.Next()
+			}
+		}
+	})
+	b.Run("ExpandFrames", func(b *testing.B) {
+		var frames []runtime.Frame
+		for i := 0; i < b.N; i++ {
+			frames = runtime.ExpandFrames(pcs, frames[:0])
+		}
+	})
+}
@@ -38,6 +38,10 @@
 #define SYS_munmap		91
 #define SYS_socketcall		102
 #define SYS_setittimer		104
+#define SYS_timer_create	259
+#define SYS_timer_settime	260
+#define SYS_timer_delete	263
+#define SYS_perf_event_open	336
 #define SYS_clone		120
 #define SYS_sched_yield 	158
 #define SYS_nanosleep		162
@@ -60,6 +64,7 @@
 #define SYS_tgkill		270
 #define SYS_epoll_create1	329
 #define SYS_pipe2		331
+#define SYS_eventfd2		328
 
 TEXT runtime·exit(SB),NOSPLIT,$0
 	MOVL	$SYS_exit_group, AX
@@ -144,6 +149,15 @@ TEXT runtime·pipe2(SB),NOSPLIT,$0-16
 	MOVL	AX, errno+12(FP)
 	RET
 
+// func eventfd(initval uint32, flags int32) int32
+TEXT runtime·eventfd(SB),NOSPLIT,$0-12
+	MOVL	$SYS_eventfd2, AX
+	MOVL	initval+0(FP), BX
+	MOVL	flags+4(FP), CX
+	INVOKE_SYSCALL
+	MOVL	AX, ret+8(FP)
+	RET
+
 TEXT runtime·usleep(SB),NOSPLIT,$8
 	MOVL	$0, DX
 	MOVL	usec+0(FP), AX
@@ -210,6 +224,52 @@ TEXT runtime·setitimer(SB),NOSPLIT,$0-12
 	INVOKE_SYSCALL
 	RET
 
+TEXT runtime·timer_create(SB),NOSPLIT,$0-16
+	MOVL	$SYS_timer_create, AX
+	MOVL	clockid+0(FP), BX
+	MOVL	sevp+4(FP), CX
+	MOVL	timerid+8(FP), DX
+	INVOKE_SYSCALL
+	MOVL	AX, ret+12(FP)
+	RET
+
+TEXT runtime·timer_settime(SB),NOSPLIT,$0-20
+	MOVL	$SYS_timer_settime, AX
+	MOVL	timerid+0(FP), BX
+	MOVL	flags+4(FP), CX
+	MOVL	new+8(FP), DX
+	MOVL	old+12(FP), SI
+	INVOKE_SYSCALL
+	MOVL	AX, ret+16(FP)
+	RET
+
+TEXT runtime·timer_delete(SB),NOSPLIT,$0-8
+	MOVL	$SYS_timer_delete, AX
+	MOVL	timerid+0(FP), BX
+	INVOKE_SYSCALL
+	MOVL	AX, ret+4(FP)
+	RET
+
+TEXT runtime·perf_event_open(SB),NOSPLIT,$0-24
+	MOVL	$SYS_perf_event_open, AX
+	MOVL	attr+0(FP), BX
+	MOVL	pid+4(FP), CX
+	MOVL	cpu+8(FP), DX
+	MOVL	groupfd+12(FP), SI
+	MOVL	flags+16(FP), DI
+	INVOKE_SYSCALL
+	MOVL	AX, ret+20(FP)
+	RET
+
+TEXT runtime·fcntl(SB),NOSPLIT,$0-16
+	MOVL	$SYS_fcntl, AX
+	MOVL	fd+0(FP), BX
+	MOVL	cmd+4(FP), CX
+	MOVL	arg+8(FP), DX
+	INVOKE_SYSCALL
+	MOVL	AX, ret+12(FP)
+	RET
+
 TEXT runtime·mincore(SB),NOSPLIT,$0-16
 	MOVL	$SYS_mincore, AX
 	MOVL	addr+0(FP), BX
@@ -16,6 +16,7 @@
 #define SYS_write		1
 #define SYS_close		3
 #define SYS_mmap		9
+#define SYS_mprotect		10
 #define SYS_munmap		11
 #define SYS_brk 		12
 #define SYS_rt_sigaction	13
@@ -27,6 +28,10 @@
 #define SYS_madvise		28
 #define SYS_nanosleep		35
 #define SYS_setittimer		38
+#define SYS_timer_create	222
+#define SYS_timer_settime	223
+#define SYS_timer_delete	226
+#define SYS_perf_event_open	298
 #define SYS_getpid		39
 #define SYS_socket		41
 #define SYS_connect		42
@@ -49,6 +54,7 @@
 #define SYS_epoll_pwait		281
 #define SYS_epoll_create1	291
 #define SYS_pipe2		293
+#define SYS_eventfd2		290
 
 TEXT runtime·exit(SB),NOSPLIT,$0-4
 	MOVL	code+0(FP), DI
@@ -127,6 +133,15 @@ TEXT runtime·pipe2(SB),NOSPLIT,$0-20
 	MOVL	AX, errno+16(FP)
 	RET
 
+// func eventfd(initval uint32, flags int32) int32
+TEXT runtime·eventfd(SB),NOSPLIT,$0-12
+	MOVL	initval+0(FP), DI
+	MOVL	flags+4(FP), SI
+	MOVL	$SYS_eventfd2, AX
+	SYSCALL
+	MOVL	AX, ret+8(FP)
+	RET
+
 TEXT runtime·usleep(SB),NOSPLIT,$16
 	MOVL	$0, DX
 	MOVL	usec+0(FP), AX
@@ -194,6 +209,57 @@ TEXT runtime·setitimer(SB),NOSPLIT,$0-24
 	SYSCALL
 	RET
 
+// int32 timer_create(int32 clockid, sigevent *sevp, int32 *timerid)
+TEXT runtime·timer_create(SB),NOSPLIT,$0-28
+	MOVL	clockid+0(FP), DI
+	MOVQ	sevp+8(FP), SI
+	MOVQ	timerid+16(FP), DX
+	MOVL	$SYS_timer_create, AX
+	SYSCALL
+	MOVL	AX, ret+24(FP)
+	RET
+
+// int32 timer_settime(int32 timerid, int32 flags, itimerspec *new, itimerspec *old)
+TEXT runtime·timer_settime(SB),NOSPLIT,$0-28
+	MOVL	timerid+0(FP), DI
+	MOVL	flags+4(FP), SI
+	MOVQ	new+8(FP), DX
+	MOVQ	old+16(FP), R10
+	MOVL	$SYS_timer_settime, AX
+	SYSCALL
+	MOVL	AX, ret+24(FP)
+	RET
+
+// int32 timer_delete(int32 timerid)
+TEXT runtime·timer_delete(SB),NOSPLIT,$0-12
+	MOVL	timerid+0(FP), DI
+	MOVL	$SYS_timer_delete, AX
+	SYSCALL
+	MOVL	AX, ret+8(FP)
+	RET
+
+// int32 perf_event_open(perfEventAttr *attr, int32 pid, int32 cpu, int32 groupfd, uintptr flags)
+TEXT runtime·perf_event_open(SB),NOSPLIT,$0-36
+	MOVQ	attr+0(FP), DI
+	MOVL	pid+8(FP), SI
+	MOVL	cpu+12(FP), DX
+	MOVL	groupfd+16(FP), R10
+	MOVQ	flags+24(FP), R8
+	MOVL	$SYS_perf_event_open, AX
+	SYSCALL
+	MOVL	AX, ret+32(FP)
+	RET
+
+// int32 fcntl(int32 fd, int32 cmd, uintptr arg)
+TEXT runtime·fcntl(SB),NOSPLIT,$0-20
+	MOVL	fd+0(FP), DI
+	MOVL	cmd+4(FP), SI
+	MOVQ	arg+8(FP), DX
+	MOVL	$SYS_fcntl, AX
+	SYSCALL
+	MOVL	AX, ret+16(FP)
+	RET
+
 TEXT runtime·mincore(SB),NOSPLIT,$0-28
 	MOVQ	addr+0(FP), DI
 	MOVQ	n+8(FP), SI
@@ -566,6 +632,15 @@ TEXT runtime·madvise(SB),NOSPLIT,$0
 	MOVL	AX, ret+24(FP)
 	RET
 
+TEXT runtime·mprotect(SB),NOSPLIT,$0-28
+	MOVQ	addr+0(FP), DI
+	MOVQ	n+8(FP), SI
+	MOVL	prot+16(FP), DX
+	MOVQ	$SYS_mprotect, AX
+	SYSCALL
+	MOVL	AX, ret+24(FP)
+	RET
+
 // int64 futex(int32 *uaddr, int32 op, int32 val,
 //	struct timespec *timeout, int32 *uaddr2, int32 val2);
 TEXT runtime·futex(SB),NOSPLIT,$0
@@ -35,6 +35,10 @@
 #define SYS_munmap (SYS_BASE + 91)
 #define SYS_madvise (SYS_BASE + 220)
 #define SYS_setitimer (SYS_BASE + 104)
+#define SYS_timer_create (SYS_BASE + 257)
+#define SYS_timer_settime (SYS_BASE + 258)
+#define SYS_timer_delete (SYS_BASE + 261)
+#define SYS_perf_event_open (SYS_BASE + 364)
 #define SYS_mincore (SYS_BASE + 219)
 #define SYS_gettid (SYS_BASE + 224)
 #define SYS_tgkill (SYS_BASE + 268)
@@ -47,6 +51,7 @@
 #define SYS_epoll_wait (SYS_BASE + 252)
 #define SYS_epoll_create1 (SYS_BASE + 357)
 #define SYS_pipe2 (SYS_BASE + 359)
+#define SYS_eventfd2 (SYS_BASE + 356)
 #define SYS_fcntl (SYS_BASE + 55)
 #define SYS_access (SYS_BASE + 33)
 #define SYS_connect (SYS_BASE + 283)
@@ -112,6 +117,15 @@ TEXT runtime·pipe2(SB),NOSPLIT,$0-16
 	MOVW	R0, errno+12(FP)
 	RET
 
+// func eventfd(initval uint32, flags int32) int32
+TEXT runtime·eventfd(SB),NOSPLIT,$0-12
+	MOVW	initval+0(FP), R0
+	MOVW	flags+4(FP), R1
+	MOVW	$SYS_eventfd2, R7
+	SWI	$0
+	MOVW	R0, ret+8(FP)
+	RET
+
 TEXT runtime·exit(SB),NOSPLIT|NOFRAME,$0
 	MOVW	code+0(FP), R0
 	MOVW	$SYS_exit_group, R7
@@ -233,6 +247,52 @@ TEXT runtime·setitimer(SB),NOSPLIT,$0
 	SWI	$0
 	RET
 
+TEXT runtime·timer_create(SB),NOSPLIT,$0-16
+	MOVW	clockid+0(FP), R0
+	MOVW	sevp+4(FP), R1
+	MOVW	timerid+8(FP), R2
+	MOVW	$SYS_timer_create, R7
+	SWI	$0
+	MOVW	R0, ret+12(FP)
+	RET
+
+TEXT runtime·timer_settime(SB),NOSPLIT,$0-20
+	MOVW	timerid+0(FP), R0
+	MOVW	flags+4(FP), R1
+	MOVW	new+8(FP), R2
+	MOVW	old+12(FP), R3
+	MOVW	$SYS_timer_settime, R7
+	SWI	$0
+	MOVW	R0, ret+16(FP)
+	RET
+
+TEXT runtime·timer_delete(SB),NOSPLIT,$0-8
+	MOVW	timerid+0(FP), R0
+	MOVW	$SYS_timer_delete, R7
+	SWI	$0
+	MOVW	R0, ret+4(FP)
+	RET
+
+TEXT runtime·perf_event_open(SB),NOSPLIT,$0-24
+	MOVW	attr+0(FP), R0
+	MOVW	pid+4(FP), R1
+	MOVW	cpu+8(FP), R2
+	MOVW	groupfd+12(FP), R3
+	MOVW	flags+16(FP), R4
+	MOVW	$SYS_perf_event_open, R7
+	SWI	$0
+	MOVW	R0, ret+20(FP)
+	RET
+
+TEXT runtime·fcntl(SB),NOSPLIT,$0-16
+	MOVW	fd+0(FP), R0
+	MOVW	cmd+4(FP), R1
+	MOVW	arg+8(FP), R2
+	MOVW	$SYS_fcntl, R7
+	SWI	$0
+	MOVW	R0, ret+12(FP)
+	RET
+
 TEXT runtime·mincore(SB),NOSPLIT,$0
 	MOVW	addr+0(FP), R0
 	MOVW	n+4(FP), R1
@@ -21,11 +21,16 @@
 #define SYS_openat		56
 #define SYS_close		57
 #define SYS_pipe2		59
+#define SYS_eventfd2		19
 #define SYS_fcntl		25
 #define SYS_nanosleep		101
 #define SYS_mmap		222
 #define SYS_munmap		215
 #define SYS_setitimer		103
+#define SYS_timer_create	107
+#define SYS_timer_settime	110
+#define SYS_timer_delete	111
+#define SYS_perf_event_open	241
 #define SYS_clone		220
 #define SYS_sched_yield		124
 #define SYS_rt_sigreturn	139
@@ -128,6 +133,15 @@ TEXT runtime·pipe2(SB),NOSPLIT|NOFRAME,$0-20
 	MOVW	R0, errno+16(FP)
 	RET
 
+// func eventfd(initval uint32, flags int32) int32
+TEXT runtime·eventfd(SB),NOSPLIT|NOFRAME,$0-12
+	MOVW	initval+0(FP), R0
+	MOVW	flags+4(FP), R1
+	MOVD	$SYS_eventfd2, R8
+	SVC
+	MOVW	R0, ret+8(FP)
+	RET
+
 TEXT runtime·usleep(SB),NOSPLIT,$24-4
 	MOVWU	usec+0(FP), R3
 	MOVD	R3, R5
@@ -197,6 +211,52 @@ TEXT runtime·setitimer(SB),NOSPLIT|NOFRAME,$0-24
 	SVC
 	RET
 
+TEXT runtime·timer_create(SB),NOSPLIT|NOFRAME,$0-28
+	MOVW	clockid+0(FP), R0
+	MOVD	sevp+8(FP), R1
+	MOVD	timerid+16(FP), R2
+	MOVD	$SYS_timer_create, R8
+	SVC
+	MOVW	R0, ret+24(FP)
+	RET
+
+TEXT runtime·timer_settime(SB),NOSPLIT|NOFRAME,$0-28
+	MOVW	timerid+0(FP), R0
+	MOVW	flags+4(FP), R1
+	MOVD	new+8(FP), R2
+	MOVD	old+16(FP), R3
+	MOVD	$SYS_timer_settime, R8
+	SVC
+	MOVW	R0, ret+24(FP)
+	RET
+
+TEXT runtime·timer_delete(SB),NOSPLIT|NOFRAME,$0-12
+	MOVW	timerid+0(FP), R0
+	MOVD	$SYS_timer_delete, R8
+	SVC
+	MOVW	R0, ret+8(FP)
+	RET
+
+TEXT runtime·perf_event_open(SB),NOSPLIT|NOFRAME,$0-36
+	MOVD	attr+0(FP), R0
+	MOVW	pid+8(FP), R1
+	MOVW	cpu+12(FP), R2
+	MOVW	groupfd+16(FP), R3
+	MOVD	flags+24(FP), R4
+	MOVD	$SYS_perf_event_open, R8
+	SVC
+	MOVW	R0, ret+32(FP)
+	RET
+
+TEXT runtime·fcntl(SB),NOSPLIT|NOFRAME,$0-20
+	MOVW	fd+0(FP), R0
+	MOVW	cmd+4(FP), R1
+	MOVD	arg+8(FP), R2
+	MOVD	$SYS_fcntl, R8
+	SVC
+	MOVW	R0, ret+16(FP)
+	RET
+
 TEXT runtime·mincore(SB),NOSPLIT|NOFRAME,$0-28
 	MOVD	addr+0(FP), R0
 	MOVD	n+8(FP), R1
@@ -25,6 +25,10 @@
 #define SYS_mmap		5009
 #define SYS_munmap		5011
 #define SYS_setitimer		5036
+#define SYS_timer_create	5216
+#define SYS_timer_settime	5217
+#define SYS_timer_delete	5220
+#define SYS_perf_event_open	5292
 #define SYS_clone		5055
 #define SYS_nanosleep		5034
 #define SYS_sched_yield		5023
@@ -47,6 +51,7 @@
 #define SYS_epoll_create1	5285
 #define SYS_brk			5012
 #define SYS_pipe2		5287
+#define SYS_eventfd2		5284
 
 TEXT runtime·exit(SB),NOSPLIT|NOFRAME,$0-4
 	MOVW	code+0(FP), R4
@@ -133,6 +138,17 @@ TEXT runtime·pipe2(SB),NOSPLIT|NOFRAME,$0-20
 	MOVW	R2, errno+16(FP)
 	RET
 
+// func eventfd(initval uint32, flags int32) int32
+TEXT runtime·eventfd(SB),NOSPLIT|NOFRAME,$0-12
+	MOVWU	initval+0(FP), R4
+	MOVW	flags+4(FP), R5
+	MOVV	$SYS_eventfd2, R2
+	SYSCALL
+	BEQ	R7, 2(PC)
+	SUBVU	R2, R0, R2	// caller expects negative errno
+	MOVW	R2, ret+8(FP)
+	RET
+
 TEXT runtime·usleep(SB),NOSPLIT,$16-4
 	MOVWU	usec+0(FP), R3
 	MOVV	R3, R5
@@ -203,6 +219,56 @@ TEXT runtime·setitimer(SB),NOSPLIT|NOFRAME,$0-24
 	SYSCALL
 	RET
 
+TEXT runtime·timer_create(SB),NOSPLIT|NOFRAME,$0-28
+	MOVW	clockid+0(FP), R4
+	MOVV	sevp+8(FP), R5
+	MOVV	timerid+16(FP), R6
+	MOVV	$SYS_timer_create, R2
+	SYSCALL
+	MOVW	R2, ret+24(FP)
+	RET
+
+TEXT runtime·timer_settime(SB),NOSPLIT|NOFRAME,$0-28
+	MOVW	timerid+0(FP), R4
+	MOVW	flags+4(FP), R5
+	MOVV	new+8(FP), R6
+	MOVV	old+16(FP), R7
+	MOVV	$SYS_timer_settime, R2
+	SYSCALL
+	MOVW	R2, ret+24(FP)
+	RET
+
+TEXT runtime·timer_delete(SB),NOSPLIT|NOFRAME,$0-12
+	MOVW	timerid+0(FP), R4
+	MOVV	$SYS_timer_delete, R2
+	SYSCALL
+	MOVW	R2, ret+8(FP)
+	RET
+
+TEXT runtime·perf_event_open(SB),NOSPLIT|NOFRAME,$0-36
+	MOVV	attr+0(FP), R4
+	MOVW	pid+8(FP), R5
+	MOVW	cpu+12(FP), R6
+	MOVW	groupfd+16(FP), R7
+	MOVV	flags+24(FP), R8
+	MOVV	$SYS_perf_event_open, R2
+	SYSCALL
+	BEQ	R7, 2(PC)
+	SUBVU	R2, R0, R2	// caller expects negative errno
+	MOVW	R2, ret+32(FP)
+	RET
+
+TEXT runtime·fcntl(SB),NOSPLIT|NOFRAME,$0-20
+	MOVW	fd+0(FP), R4
+	MOVW	cmd+4(FP), R5
+	MOVV	arg+8(FP), R6
+	MOVV	$SYS_fcntl, R2
+	SYSCALL
+	BEQ	R7, 2(PC)
+	SUBVU	R2, R0, R2	// caller expects negative errno
+	MOVW	R2, ret+16(FP)
+	RET
+
 TEXT runtime·mincore(SB),NOSPLIT|NOFRAME,$0-28
 	MOVV	addr+0(FP), R4
 	MOVV	n+8(FP), R5
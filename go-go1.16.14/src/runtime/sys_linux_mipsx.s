@@ -26,6 +26,10 @@
 #define SYS_mmap		4090
 #define SYS_munmap		4091
 #define SYS_setitimer		4104
+#define SYS_timer_create	4257
+#define SYS_timer_settime	4258
+#define SYS_timer_delete	4261
+#define SYS_perf_event_open	4333
 #define SYS_clone		4120
 #define SYS_sched_yield		4162
 #define SYS_nanosleep		4166
@@ -46,6 +50,7 @@
 #define SYS_tgkill		4266
 #define SYS_epoll_create1	4326
 #define SYS_pipe2		4328
+#define SYS_eventfd2		4325
 
 TEXT runtime·exit(SB),NOSPLIT,$0-4
 	MOVW	code+0(FP), R4
@@ -138,6 +143,17 @@ TEXT runtime·pipe2(SB),NOSPLIT,$0-16
 	MOVW	R2, errno+12(FP)
 	RET
 
+// func eventfd(initval uint32, flags int32) int32
+TEXT runtime·eventfd(SB),NOSPLIT,$0-12
+	MOVW	initval+0(FP), R4
+	MOVW	flags+4(FP), R5
+	MOVW	$SYS_eventfd2, R2
+	SYSCALL
+	BEQ	R7, 2(PC)
+	SUBU	R2, R0, R2	// caller expects negative errno
+	MOVW	R2, ret+8(FP)
+	RET
+
 TEXT runtime·usleep(SB),NOSPLIT,$28-4
 	MOVW	usec+0(FP), R3
 	MOVW	R3, R5
@@ -208,6 +224,57 @@ TEXT runtime·setitimer(SB),NOSPLIT,$0-12
 	SYSCALL
 	RET
 
+TEXT runtime·timer_create(SB),NOSPLIT,$0-16
+	MOVW	clockid+0(FP), R4
+	MOVW	sevp+4(FP), R5
+	MOVW	timerid+8(FP), R6
+	MOVW	$SYS_timer_create, R2
+	SYSCALL
+	MOVW	R2, ret+12(FP)
+	RET
+
+TEXT runtime·timer_settime(SB),NOSPLIT,$0-20
+	MOVW	timerid+0(FP), R4
+	MOVW	flags+4(FP), R5
+	MOVW	new+8(FP), R6
+	MOVW	old+12(FP), R7
+	MOVW	$SYS_timer_settime, R2
+	SYSCALL
+	MOVW	R2, ret+16(FP)
+	RET
+
+TEXT runtime·timer_delete(SB),NOSPLIT,$0-8
+	MOVW	timerid+0(FP), R4
+	MOVW	$SYS_timer_delete, R2
+	SYSCALL
+	MOVW	R2, ret+4(FP)
+	RET
+
+TEXT runtime·perf_event_open(SB),NOSPLIT,$20-24
+	MOVW	attr+0(FP), R4
+	MOVW	pid+4(FP), R5
+	MOVW	cpu+8(FP), R6
+	MOVW	groupfd+12(FP), R7
+	MOVW	flags+16(FP), R8
+	MOVW	R8, 16(R29)
+	MOVW	$SYS_perf_event_open, R2
+	SYSCALL
+	BEQ	R7, 2(PC)
+	SUBU	R2, R0, R2	// caller expects negative errno
+	MOVW	R2, ret+20(FP)
+	RET
+
+TEXT runtime·fcntl(SB),NOSPLIT,$0-16
+	MOVW	fd+0(FP), R4
+	MOVW	cmd+4(FP), R5
+	MOVW	arg+8(FP), R6
+	MOVW	$SYS_fcntl, R2
+	SYSCALL
+	BEQ	R7, 2(PC)
+	SUBU	R2, R0, R2	// caller expects negative errno
+	MOVW	R2, ret+12(FP)
+	RET
+
 TEXT runtime·mincore(SB),NOSPLIT,$0-16
 	MOVW	addr+0(FP), R4
 	MOVW	n+4(FP), R5
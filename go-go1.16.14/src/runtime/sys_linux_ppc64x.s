@@ -27,6 +27,10 @@
 #define SYS_mmap		 90
 #define SYS_munmap		 91
 #define SYS_setitimer		104
+#define SYS_timer_create	240
+#define SYS_timer_settime	241
+#define SYS_timer_delete	244
+#define SYS_perf_event_open	319
 #define SYS_clone		120
 #define SYS_sched_yield		158
 #define SYS_nanosleep		162
@@ -47,6 +51,7 @@
 #define SYS_tgkill		250
 #define SYS_epoll_create1	315
 #define SYS_pipe2		317
+#define SYS_eventfd2		314
 
 TEXT runtime·exit(SB),NOSPLIT|NOFRAME,$0-4
 	MOVW	code+0(FP), R3
@@ -117,6 +122,16 @@ TEXT runtime·pipe2(SB),NOSPLIT|NOFRAME,$0-20
 	MOVW	R3, errno+16(FP)
 	RET
 
+// func eventfd(initval uint32, flags int32) int32
+TEXT runtime·eventfd(SB),NOSPLIT|NOFRAME,$0-12
+	MOVWZ	initval+0(FP), R3
+	MOVW	flags+4(FP), R4
+	SYSCALL	$SYS_eventfd2
+	BVC	2(PC)
+	NEG	R3	// caller expects negative errno
+	MOVW	R3, ret+8(FP)
+	RET
+
 TEXT runtime·usleep(SB),NOSPLIT,$16-4
 	MOVW	usec+0(FP), R3
 	MOVD	R3, R5
@@ -175,6 +190,51 @@ TEXT runtime·setitimer(SB),NOSPLIT|NOFRAME,$0-24
 	SYSCALL	$SYS_setitimer
 	RET
 
+TEXT runtime·timer_create(SB),NOSPLIT|NOFRAME,$0-28
+	MOVW	clockid+0(FP), R3
+	MOVD	sevp+8(FP), R4
+	MOVD	timerid+16(FP), R5
+	SYSCALL	$SYS_timer_create
+	MOVW	R3, ret+24(FP)
+	RET
+
+TEXT runtime·timer_settime(SB),NOSPLIT|NOFRAME,$0-28
+	MOVW	timerid+0(FP), R3
+	MOVW	flags+4(FP), R4
+	MOVD	new+8(FP), R5
+	MOVD	old+16(FP), R6
+	SYSCALL	$SYS_timer_settime
+	MOVW	R3, ret+24(FP)
+	RET
+
+TEXT runtime·timer_delete(SB),NOSPLIT|NOFRAME,$0-12
+	MOVW	timerid+0(FP), R3
+	SYSCALL	$SYS_timer_delete
+	MOVW	R3, ret+8(FP)
+	RET
+
+TEXT runtime·perf_event_open(SB),NOSPLIT|NOFRAME,$0-36
+	MOVD	attr+0(FP), R3
+	MOVW	pid+8(FP), R4
+	MOVW	cpu+12(FP), R5
+	MOVW	groupfd+16(FP), R6
+	MOVD	flags+24(FP), R7
+	SYSCALL	$SYS_perf_event_open
+	BVC	2(PC)
+	NEG	R3	// caller expects negative errno
+	MOVW	R3, ret+32(FP)
+	RET
+
+TEXT runtime·fcntl(SB),NOSPLIT|NOFRAME,$0-20
+	MOVW	fd+0(FP), R3
+	MOVW	cmd+4(FP), R4
+	MOVD	arg+8(FP), R5
+	SYSCALL	$SYS_fcntl
+	BVC	2(PC)
+	NEG	R3	// caller expects negative errno
+	MOVW	R3, ret+16(FP)
+	RET
+
 TEXT runtime·mincore(SB),NOSPLIT|NOFRAME,$0-28
 	MOVD	addr+0(FP), R3
 	MOVD	n+8(FP), R4
@@ -36,6 +36,7 @@
 #define SYS_nanosleep		101
 #define SYS_openat		56
 #define SYS_pipe2		59
+#define SYS_eventfd2		19
 #define SYS_pselect6		72
 #define SYS_read		63
 #define SYS_rt_sigaction	134
@@ -44,6 +45,10 @@
 #define SYS_sched_getaffinity	123
 #define SYS_sched_yield		124
 #define SYS_setitimer		103
+#define SYS_timer_create	107
+#define SYS_timer_settime	110
+#define SYS_timer_delete	111
+#define SYS_perf_event_open	241
 #define SYS_sigaltstack		132
 #define SYS_socket		198
 #define SYS_tgkill		131
@@ -132,6 +137,15 @@ TEXT runtime·pipe2(SB),NOSPLIT|NOFRAME,$0-20
 	MOVW	A0, errno+16(FP)
 	RET
 
+// func eventfd(initval uint32, flags int32) int32
+TEXT runtime·eventfd(SB),NOSPLIT|NOFRAME,$0-12
+	MOVWU	initval+0(FP), A0
+	MOVW	flags+4(FP), A1
+	MOV	$SYS_eventfd2, A7
+	ECALL
+	MOVW	A0, ret+8(FP)
+	RET
+
 // func getrlimit(kind int32, limit unsafe.Pointer) int32
 TEXT runtime·getrlimit(SB),NOSPLIT|NOFRAME,$0-20
 	MOVW	kind+0(FP), A0
@@ -209,6 +223,57 @@ TEXT runtime·setitimer(SB),NOSPLIT|NOFRAME,$0-24
 	ECALL
 	RET
 
+// func timer_create(clockid int32, sevp *sigevent, timerid *int32) int32
+TEXT runtime·timer_create(SB),NOSPLIT|NOFRAME,$0-28
+	MOVW	clockid+0(FP), A0
+	MOV	sevp+8(FP), A1
+	MOV	timerid+16(FP), A2
+	MOV	$SYS_timer_create, A7
+	ECALL
+	MOVW	A0, ret+24(FP)
+	RET
+
+// func timer_settime(timerid int32, flags int32, new, old *itimerspec) int32
+TEXT runtime·timer_settime(SB),NOSPLIT|NOFRAME,$0-28
+	MOVW	timerid+0(FP), A0
+	MOVW	flags+4(FP), A1
+	MOV	new+8(FP), A2
+	MOV	old+16(FP), A3
+	MOV	$SYS_timer_settime, A7
+	ECALL
+	MOVW	A0, ret+24(FP)
+	RET
+
+// func timer_delete(timerid int32) int32
+TEXT runtime·timer_delete(SB),NOSPLIT|NOFRAME,$0-12
+	MOVW	timerid+0(FP), A0
+	MOV	$SYS_timer_delete, A7
+	ECALL
+	MOVW	A0, ret+8(FP)
+	RET
+
+// func perf_event_open(attr *perfEventAttr, pid, cpu, groupfd int32, flags uintptr) int32
+TEXT runtime·perf_event_open(SB),NOSPLIT|NOFRAME,$0-36
+	MOV	attr+0(FP), A0
+	MOVW	pid+8(FP), A1
+	MOVW	cpu+12(FP), A2
+	MOVW	groupfd+16(FP), A3
+	MOV	flags+24(FP), A4
+	MOV	$SYS_perf_event_open, A7
+	ECALL
+	MOVW	A0, ret+32(FP)
+	RET
+
+// func fcntl(fd, cmd int32, arg uintptr) int32
+TEXT runtime·fcntl(SB),NOSPLIT|NOFRAME,$0-20
+	MOVW	fd+0(FP), A0
+	MOVW	cmd+4(FP), A1
+	MOV	arg+8(FP), A2
+	MOV	$SYS_fcntl, A7
+	ECALL
+	MOVW	A0, ret+16(FP)
+	RET
+
 // func mincore(addr unsafe.Pointer, n uintptr, dst *byte) int32
 TEXT runtime·mincore(SB),NOSPLIT|NOFRAME,$0-28
 	MOV	addr+0(FP), A0
@@ -220,25 +285,155 @@ TEXT runtime·mincore(SB),NOSPLIT|NOFRAME,$0-28
 	RET
 
 // func walltime1() (sec int64, nsec int32)
-TEXT runtime·walltime1(SB),NOSPLIT,$24-12
+TEXT runtime·walltime1(SB),NOSPLIT,$40-12
 	MOV	$0, A0 // CLOCK_REALTIME
+
+	MOV	runtime·vdsoClockgettimeSym(SB), A7
+	BEQZ	A7, fallback
+	MOV	X2, S2 // S2, S3 and S4 are unchanged by C code
+	MOV	g_m(g), S3 // S3 = m
+
+	// Set vdsoPC and vdsoSP for SIGPROF traceback.
+	// Save the old values on stack and restore them on exit,
+	// so this function is reentrant.
+	MOV	m_vdsoPC(S3), T0
+	MOV	T0, 24(X2)
+	MOV	m_vdsoSP(S3), T0
+	MOV	T0, 32(X2)
+
+	MOV	RA, m_vdsoPC(S3)
+	MOV	$sec-8(FP), T1 // caller's SP
+	MOV	T1, m_vdsoSP(S3)
+
+	MOV	m_curg(S3), T1
+	BNE	g, T1, noswitch
+
+	MOV	m_g0(S3), T1
+	MOV	(g_sched+gobuf_sp)(T1), X2 // Set SP to g0 stack
+
+noswitch:
+	ADDI	$-24, X2 // Space for result
+	ANDI	$~15, X2 // Align for C code
+	MOV	$8(X2), A1
+
+	// Store g on gsignal's stack, see sys_linux_arm64.s for detail.
+	MOVBU	runtime·iscgo(SB), S4
+	BNEZ	S4, nosaveg
+	MOV	m_gsignal(S3), S4 // g.m.gsignal
+	BEQZ	S4, nosaveg
+	BEQ	g, S4, nosaveg
+	MOV	(g_stack+stack_lo)(S4), S4 // g.m.gsignal.stack.lo
+	MOV	g, (S4)
+
+	JALR	RA, A7
+
+	MOV	ZERO, (S4) // clear g slot, S4 is unchanged by C code
+	JMP	finish
+
+nosaveg:
+	JALR	RA, A7
+
+finish:
+	MOV	8(X2), T0	// sec
+	MOV	16(X2), T1	// nsec
+
+	MOV	S2, X2	// restore stack
+	// Restore vdsoPC, vdsoSP
+	// We don't worry about being signaled between the two stores.
+	// If we are not in a signal handler, we'll restore vdsoSP to 0,
+	// and no one will care about vdsoPC. If we are in a signal handler,
+	// we cannot receive another signal.
+	MOV	32(X2), A2
+	MOV	A2, m_vdsoSP(S3)
+	MOV	24(X2), A2
+	MOV	A2, m_vdsoPC(S3)
+	JMP	done
+
+fallback:
 	MOV	$8(X2), A1
 	MOV	$SYS_clock_gettime, A7
 	ECALL
 	MOV	8(X2), T0	// sec
 	MOV	16(X2), T1	// nsec
+
+done:
 	MOV	T0, sec+0(FP)
 	MOVW	T1, nsec+8(FP)
 	RET
 
 // func nanotime1() int64
-TEXT runtime·nanotime1(SB),NOSPLIT,$24-8
+TEXT runtime·nanotime1(SB),NOSPLIT,$40-8
 	MOV	$1, A0 // CLOCK_MONOTONIC
+
+	MOV	runtime·vdsoClockgettimeSym(SB), A7
+	BEQZ	A7, fallback
+	MOV	X2, S2 // S2, S3 and S4 are unchanged by C code
+	MOV	g_m(g), S3 // S3 = m
+
+	// Set vdsoPC and vdsoSP for SIGPROF traceback.
+	// Save the old values on stack and restore them on exit,
+	// so this function is reentrant.
+	MOV	m_vdsoPC(S3), T0
+	MOV	T0, 24(X2)
+	MOV	m_vdsoSP(S3), T0
+	MOV	T0, 32(X2)
+
+	MOV	RA, m_vdsoPC(S3)
+	MOV	$ret-8(FP), T1 // caller's SP
+	MOV	T1, m_vdsoSP(S3)
+
+	MOV	m_curg(S3), T1
+	BNE	g, T1, noswitch
+
+	MOV	m_g0(S3), T1
+	MOV	(g_sched+gobuf_sp)(T1), X2 // Set SP to g0 stack
+
+noswitch:
+	ADDI	$-24, X2 // Space for result
+	ANDI	$~15, X2 // Align for C code
+	MOV	$8(X2), A1
+
+	// Store g on gsignal's stack, see sys_linux_arm64.s for detail.
+	MOVBU	runtime·iscgo(SB), S4
+	BNEZ	S4, nosaveg
+	MOV	m_gsignal(S3), S4 // g.m.gsignal
+	BEQZ	S4, nosaveg
+	BEQ	g, S4, nosaveg
+	MOV	(g_stack+stack_lo)(S4), S4 // g.m.gsignal.stack.lo
+	MOV	g, (S4)
+
+	JALR	RA, A7
+
+	MOV	ZERO, (S4) // clear g slot, S4 is unchanged by C code
+	JMP	finish
+
+nosaveg:
+	JALR	RA, A7
+
+finish:
+	MOV	8(X2), T0	// sec
+	MOV	16(X2), T1	// nsec
+
+	MOV	S2, X2	// restore stack
+	// Restore vdsoPC, vdsoSP
+	// We don't worry about being signaled between the two stores.
+	// If we are not in a signal handler, we'll restore vdsoSP to 0,
+	// and no one will care about vdsoPC. If we are in a signal handler,
+	// we cannot receive another signal.
+	MOV	32(X2), A2
+	MOV	A2, m_vdsoSP(S3)
+	MOV	24(X2), A2
+	MOV	A2, m_vdsoPC(S3)
+	JMP	done
+
+fallback:
 	MOV	$8(X2), A1
 	MOV	$SYS_clock_gettime, A7
 	ECALL
 	MOV	8(X2), T0	// sec
 	MOV	16(X2), T1	// nsec
+
+done:
 	// sec is in T0, nsec in T1
 	// return nsec in T0
 	MOV	$1000000000, T2
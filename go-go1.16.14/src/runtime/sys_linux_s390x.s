@@ -22,6 +22,10 @@
 #define SYS_mmap                 90
 #define SYS_munmap               91
 #define SYS_setitimer           104
+#define SYS_timer_create        254
+#define SYS_timer_settime       255
+#define SYS_timer_delete        258
+#define SYS_perf_event_open        331
 #define SYS_clone               120
 #define SYS_sched_yield         158
 #define SYS_nanosleep           162
@@ -41,6 +45,7 @@
 #define SYS_epoll_wait          251
 #define SYS_clock_gettime       260
 #define SYS_pipe2		325
+#define SYS_eventfd2		323
 #define SYS_epoll_create1       327
 
 TEXT runtime·exit(SB),NOSPLIT|NOFRAME,$0-4
@@ -117,6 +122,15 @@ TEXT runtime·pipe2(SB),NOSPLIT|NOFRAME,$0-20
 	MOVW	R2, errno+16(FP)
 	RET
 
+// func eventfd(initval uint32, flags int32) int32
+TEXT runtime·eventfd(SB),NOSPLIT|NOFRAME,$0-12
+	MOVWZ	initval+0(FP), R2
+	MOVW	flags+4(FP), R3
+	MOVW	$SYS_eventfd2, R1
+	SYSCALL
+	MOVW	R2, ret+8(FP)
+	RET
+
 TEXT runtime·usleep(SB),NOSPLIT,$16-4
 	MOVW	usec+0(FP), R2
 	MOVD	R2, R4
@@ -185,6 +199,52 @@ TEXT runtime·setitimer(SB),NOSPLIT|NOFRAME,$0-24
 	SYSCALL
 	RET
 
+TEXT runtime·timer_create(SB),NOSPLIT|NOFRAME,$0-28
+	MOVW	clockid+0(FP), R2
+	MOVD	sevp+8(FP), R3
+	MOVD	timerid+16(FP), R4
+	MOVW	$SYS_timer_create, R1
+	SYSCALL
+	MOVW	R2, ret+24(FP)
+	RET
+
+TEXT runtime·timer_settime(SB),NOSPLIT|NOFRAME,$0-28
+	MOVW	timerid+0(FP), R2
+	MOVW	flags+4(FP), R3
+	MOVD	new+8(FP), R4
+	MOVD	old+16(FP), R5
+	MOVW	$SYS_timer_settime, R1
+	SYSCALL
+	MOVW	R2, ret+24(FP)
+	RET
+
+TEXT runtime·timer_delete(SB),NOSPLIT|NOFRAME,$0-12
+	MOVW	timerid+0(FP), R2
+	MOVW	$SYS_timer_delete, R1
+	SYSCALL
+	MOVW	R2, ret+8(FP)
+	RET
+
+TEXT runtime·perf_event_open(SB),NOSPLIT|NOFRAME,$0-36
+	MOVD	attr+0(FP), R2
+	MOVW	pid+8(FP), R3
+	MOVW	cpu+12(FP), R4
+	MOVW	groupfd+16(FP), R5
+	MOVD	flags+24(FP), R6
+	MOVW	$SYS_perf_event_open, R1
+	SYSCALL
+	MOVW	R2, ret+32(FP)
+	RET
+
+TEXT runtime·fcntl(SB),NOSPLIT|NOFRAME,$0-20
+	MOVW	fd+0(FP), R2
+	MOVW	cmd+4(FP), R3
+	MOVD	arg+8(FP), R4
+	MOVW	$SYS_fcntl, R1
+	SYSCALL
+	MOVW	R2, ret+16(FP)
+	RET
+
 TEXT runtime·mincore(SB),NOSPLIT|NOFRAME,$0-28
 	MOVD	addr+0(FP), R2
 	MOVD	n+8(FP), R3
@@ -195,23 +255,157 @@ TEXT runtime·mincore(SB),NOSPLIT|NOFRAME,$0-28
 	RET
 
 // func walltime1() (sec int64, nsec int32)
-TEXT runtime·walltime1(SB),NOSPLIT,$16
+TEXT runtime·walltime1(SB),NOSPLIT,$32-12
 	MOVW	$0, R2 // CLOCK_REALTIME
+
+	MOVD	runtime·vdsoClockgettimeSym(SB), R9
+	CMPBEQ	R9, $0, fallback
+	MOVD	R15, R7 // R6, R7 and R12 are unchanged by C code
+	MOVD	g_m(g), R6 // R6 = m
+
+	// Set vdsoPC and vdsoSP for SIGPROF traceback.
+	// Save the old values on stack and restore them on exit,
+	// so this function is reentrant.
+	MOVD	m_vdsoPC(R6), R4
+	MOVD	R4, 8(R15)
+	MOVD	m_vdsoSP(R6), R4
+	MOVD	R4, 16(R15)
+
+	MOVD	R14, m_vdsoPC(R6)
+	MOVD	$sec-8(FP), R4 // caller's SP
+	MOVD	R4, m_vdsoSP(R6)
+
+	MOVD	m_curg(R6), R5
+	CMPBNE	g, R5, noswitch
+
+	MOVD	m_g0(R6), R4
+	MOVD	(g_sched+gobuf_sp)(R4), R15 // Set SP to g0 stack
+
+noswitch:
+	// Make room for the 160-byte register save area that C code
+	// may use in its caller's frame, and for the result.
+	SUB	$176, R15
+	MOVD	$~7, R4
+	AND	R4, R15 // Align for C code
+	MOVD	$160(R15), R3
+
+	// Store g on gsignal's stack, see sys_linux_arm64.s for detail.
+	MOVBZ	runtime·iscgo(SB), R12
+	CMPBNE	R12, $0, nosaveg
+	MOVD	m_gsignal(R6), R12 // g.m.gsignal
+	CMPBEQ	R12, $0, nosaveg
+	CMPBEQ	g, R12, nosaveg
+	MOVD	(g_stack+stack_lo)(R12), R12 // g.m.gsignal.stack.lo
+	MOVD	g, (R12)
+
+	BL	R9
+
+	MOVD	$0, (R12) // clear g slot, R12 is unchanged by C code
+	BR	finish
+
+nosaveg:
+	BL	R9
+
+finish:
+	LMG	160(R15), R2, R3
+
+	MOVD	R7, R15 // restore SP
+	// Restore vdsoPC, vdsoSP
+	// We don't worry about being signaled between the two stores.
+	// If we are not in a signal handler, we'll restore vdsoSP to 0,
+	// and no one will care about vdsoPC. If we are in a signal handler,
+	// we cannot receive another signal.
+	MOVD	16(R15), R4
+	MOVD	R4, m_vdsoSP(R6)
+	MOVD	8(R15), R4
+	MOVD	R4, m_vdsoPC(R6)
+	BR	done
+
+fallback:
 	MOVD	$tp-16(SP), R3
 	MOVW	$SYS_clock_gettime, R1
 	SYSCALL
 	LMG	tp-16(SP), R2, R3
+
+done:
 	// sec is in R2, nsec in R3
 	MOVD	R2, sec+0(FP)
 	MOVW	R3, nsec+8(FP)
 	RET
 
-TEXT runtime·nanotime1(SB),NOSPLIT,$16
+TEXT runtime·nanotime1(SB),NOSPLIT,$32-8
 	MOVW	$1, R2 // CLOCK_MONOTONIC
+
+	MOVD	runtime·vdsoClockgettimeSym(SB), R9
+	CMPBEQ	R9, $0, fallback
+	MOVD	R15, R7 // R6, R7 and R12 are unchanged by C code
+	MOVD	g_m(g), R6 // R6 = m
+
+	// Set vdsoPC and vdsoSP for SIGPROF traceback.
+	// Save the old values on stack and restore them on exit,
+	// so this function is reentrant.
+	MOVD	m_vdsoPC(R6), R4
+	MOVD	R4, 8(R15)
+	MOVD	m_vdsoSP(R6), R4
+	MOVD	R4, 16(R15)
+
+	MOVD	R14, m_vdsoPC(R6)
+	MOVD	$ret-8(FP), R4 // caller's SP
+	MOVD	R4, m_vdsoSP(R6)
+
+	MOVD	m_curg(R6), R5
+	CMPBNE	g, R5, noswitch
+
+	MOVD	m_g0(R6), R4
+	MOVD	(g_sched+gobuf_sp)(R4), R15 // Set SP to g0 stack
+
+noswitch:
+	// Make room for the 160-byte register save area that C code
+	// may use in its caller's frame, and for the result.
+	SUB	$176, R15
+	MOVD	$~7, R4
+	AND	R4, R15 // Align for C code
+	MOVD	$160(R15), R3
+
+	// Store g on gsignal's stack, see sys_linux_arm64.s for detail.
+	MOVBZ	runtime·iscgo(SB), R12
+	CMPBNE	R12, $0, nosaveg
+	MOVD	m_gsignal(R6), R12 // g.m.gsignal
+	CMPBEQ	R12, $0, nosaveg
+	CMPBEQ	g, R12, nosaveg
+	MOVD	(g_stack+stack_lo)(R12), R12 // g.m.gsignal.stack.lo
+	MOVD	g, (R12)
+
+	BL	R9
+
+	MOVD	$0, (R12) // clear g slot, R12 is unchanged by C code
+	BR	finish
+
+nosaveg:
+	BL	R9
+
+finish:
+	LMG	160(R15), R2, R3
+
+	MOVD	R7, R15 // restore SP
+	// Restore vdsoPC, vdsoSP
+	// We don't worry about being signaled between the two stores.
+	// If we are not in a signal handler, we'll restore vdsoSP to 0,
+	// and no one will care about vdsoPC. If we are in a signal handler,
+	// we cannot receive another signal.
+	MOVD	16(R15), R4
+	MOVD	R4, m_vdsoSP(R6)
+	MOVD	8(R15), R4
+	MOVD	R4, m_vdsoPC(R6)
+	BR	done
+
+fallback:
 	MOVD	$tp-16(SP), R3
 	MOVW	$SYS_clock_gettime, R1
 	SYSCALL
 	LMG	tp-16(SP), R2, R3
+
+done:
 	// sec is in R2, nsec in R3
 	// return nsec in R2
 	MULLD	$1000000000, R2
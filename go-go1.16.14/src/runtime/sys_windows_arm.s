@@ -246,14 +246,17 @@ TEXT runtime·profileloop(SB),NOSPLIT|NOFRAME,$0
 	B	runtime·externalthreadhandler(SB)
 
 // int32 externalthreadhandler(uint32 arg, int (*func)(uint32))
+// The m and g structures are too large for a nosplit frame, so they
+// are allocated with VirtualAlloc (which zeroes them) and released
+// with VirtualFree on return.
 // stack layout:
 //   +----------------+
 //   | callee-save    |
 //   | registers      |
 //   +----------------+
-//   | m              |
+// 24| padding        |
 //   +----------------+
-// 20| g              |
+// 20| m and g memory |
 //   +----------------+
 // 16| func ptr (r1)  |
 //   +----------------+
@@ -268,20 +271,24 @@ TEXT runtime·profileloop(SB),NOSPLIT|NOFRAME,$0
 //
 TEXT runtime·externalthreadhandler(SB),NOSPLIT|NOFRAME,$0
 	MOVM.DB.W [R4-R11, R14], (R13)		// push {r4-r11, lr}
-	SUB	$(m__size + g__size + 20), R13	// space for locals
+	SUB	$28, R13			// space for locals
 	MOVW	R0, 12(R13)
 	MOVW	R1, 16(R13)
 
-	// zero out m and g structures
-	ADD	$20, R13, R0			// compute pointer to g
-	MOVW	R0, 4(R13)
-	MOVW	$(m__size + g__size), R0
-	MOVW	R0, 8(R13)
-	BL	runtime·memclrNoHeapPointers(SB)
+	// allocate m and g structures
+	MOVW	$0, R0				// lpAddress
+	MOVW	$(m__size + g__size), R1	// dwSize
+	MOVW	$0x3000, R2			// MEM_COMMIT | MEM_RESERVE
+	MOVW	$0x04, R3			// PAGE_READWRITE
+	MOVW	runtime·_VirtualAlloc(SB), R12
+	BL	(R12)
+	CMP	$0, R0
+	BEQ	nomem
+	MOVW	R0, 20(R13)
 
 	// initialize m and g structures
-	ADD	$20, R13, R2			// R2 = g
-	ADD	$(20 + g__size), R13, R3	// R3 = m
+	MOVW	R0, R2				// R2 = g
+	ADD	$g__size, R0, R3		// R3 = m
 	MOVW	R2, m_g0(R3)			// m->g0 = g
 	MOVW	R3, g_m(R2)			// g->m = m
 	MOVW	R2, m_curg(R3)			// m->curg = g
@@ -307,8 +314,20 @@ TEXT runtime·externalthreadhandler(SB),NOSPLIT|NOFRAME,$0
 	MOVW	$0, g
 	BL	runtime·save_g(SB)
 
+	// free m and g structures
+	MOVW	20(R13), R0			// lpAddress
+	MOVW	$0, R1				// dwSize
+	MOVW	$0x8000, R2			// MEM_RELEASE
+	MOVW	runtime·_VirtualFree(SB), R12
+	BL	(R12)
+
 	MOVW	0(R13), R0			// load return value
-	ADD	$(m__size + g__size + 20), R13	// free locals
+	ADD	$28, R13			// free locals
+	MOVM.IA.W (R13), [R4-R11, R15]		// pop {r4-r11, pc}
+
+nomem:
+	MOVW	$0, R0
+	ADD	$28, R13			// free locals
 	MOVM.IA.W (R13), [R4-R11, R15]		// pop {r4-r11, pc}
 
 GLOBL runtime·cbctxts(SB), NOPTR, $4
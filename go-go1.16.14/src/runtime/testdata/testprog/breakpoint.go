@@ -0,0 +1,39 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+	_ "unsafe" // for go:linkname
+)
+
+func init() {
+	register("BreakpointIf", BreakpointIf)
+}
+
+// debugSetAttached is what a debugger calls to tell the runtime it is
+// attached.
+//go:linkname debugSetAttached runtime.debugSetAttached
+func debugSetAttached(attached bool)
+
+func BreakpointIf() {
+	runtime.BreakpointIf(func() bool {
+		fmt.Println("BAD: predicate called without debugger")
+		return true
+	})
+	fmt.Println("no debugger")
+
+	debugSetAttached(true)
+	runtime.BreakpointIf(func() bool {
+		fmt.Println("predicate false")
+		return false
+	})
+	runtime.BreakpointIf(func() bool {
+		fmt.Println("predicate true")
+		return true
+	})
+	fmt.Println("BAD: after breakpoint")
+}
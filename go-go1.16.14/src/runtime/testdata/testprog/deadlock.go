@@ -8,7 +8,10 @@ import (
 	"fmt"
 	"runtime"
 	"runtime/debug"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unsafe"
 )
 
 func init() {
@@ -18,6 +21,8 @@ func init() {
 	register("SimpleDeadlock", SimpleDeadlock)
 	register("LockedDeadlock", LockedDeadlock)
 	register("LockedDeadlock2", LockedDeadlock2)
+	register("PartialDeadlock", PartialDeadlock)
+	register("DeadlockWaitInfo", DeadlockWaitInfo)
 	register("GoexitDeadlock", GoexitDeadlock)
 	register("StackOverflow", StackOverflow)
 	register("ThreadExhaustion", ThreadExhaustion)
@@ -56,6 +61,72 @@ func LockedDeadlock() {
 	select {}
 }
 
+// lockWaiting locks l after waiting for another goroutine holding it,
+// so that l is locked on the slow path, where GODEBUG=deadlockdetect
+// records its owner. state points at the state of the sync.Mutex that
+// l is or begins with.
+func lockWaiting(l sync.Locker, state *int32) {
+	held := make(chan bool)
+	go func() {
+		l.Lock()
+		held <- true
+		// Waiters are counted above the 4 low bits of the state.
+		for atomic.LoadInt32(state)>>4 == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		l.Unlock()
+	}()
+	<-held
+	l.Lock()
+}
+
+func PartialDeadlock() {
+	// Three goroutines each hold one lock and wait for the next,
+	// while the main goroutine keeps running.
+	var a, b sync.Mutex
+	var rw sync.RWMutex
+	aState := (*int32)(unsafe.Pointer(&a))
+	bState := (*int32)(unsafe.Pointer(&b))
+	rwState := (*int32)(unsafe.Pointer(&rw)) // RWMutex begins with its writer Mutex
+	var held sync.WaitGroup
+	held.Add(3)
+	go func() {
+		lockWaiting(&a, aState)
+		held.Done()
+		held.Wait()
+		rw.RLock()
+	}()
+	go func() {
+		lockWaiting(&rw, rwState)
+		held.Done()
+		held.Wait()
+		b.Lock()
+	}()
+	go func() {
+		lockWaiting(&b, bState)
+		held.Done()
+		held.Wait()
+		a.Lock()
+	}()
+	for {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func DeadlockWaitInfo() {
+	var mu sync.Mutex
+	c := make(chan int)
+	lockWaiting(&mu, (*int32)(unsafe.Pointer(&mu)))
+	go func() {
+		mu.Lock()
+	}()
+	go func() {
+		<-c
+	}()
+	time.Sleep(10 * time.Millisecond)
+	<-c
+}
+
 func LockedDeadlock2() {
 	go func() {
 		runtime.LockOSThread()
@@ -0,0 +1,82 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+func init() {
+	register("DetSched", DetSched)
+	register("SchedRecord", SchedRecord)
+	register("SchedSeed", SchedSeed)
+}
+
+// DetSched prints GOMAXPROCS, the order in which goroutines took
+// turns and the time that passed, which GODEBUG=detsched fixes.
+func DetSched() {
+	start := time.Now()
+	var (
+		mu    sync.Mutex
+		order []int
+		wg    sync.WaitGroup
+	)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 4; j++ {
+				mu.Lock()
+				order = append(order, i)
+				mu.Unlock()
+				if j == 1 {
+					time.Sleep(time.Duration(i) * time.Millisecond)
+				} else {
+					runtime.Gosched()
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	time.Sleep(time.Hour)
+	fmt.Println(runtime.GOMAXPROCS(0), order, time.Since(start))
+}
+
+// SchedRecord runs DetSched and saves the scheduling record to the
+// file $SCHEDRECORD.
+func SchedRecord() {
+	DetSched()
+	if name := os.Getenv("SCHEDRECORD"); name != "" {
+		if err := os.WriteFile(name, debug.ReadSchedRecord(), 0666); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+}
+
+// SchedSeed prints the cases chosen by selects with two cases ready,
+// which GODEBUG=schedseed or detsched fixes.
+func SchedSeed() {
+	a, b := make(chan int, 1), make(chan int, 1)
+	var choices []byte
+	for i := 0; i < 64; i++ {
+		a <- 0
+		b <- 0
+		select {
+		case <-a:
+			choices = append(choices, 'a')
+			<-b
+		case <-b:
+			choices = append(choices, 'b')
+			<-a
+		}
+	}
+	fmt.Println(string(choices))
+}
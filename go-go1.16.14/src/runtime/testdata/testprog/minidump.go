@@ -0,0 +1,33 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime/debug"
+	"syscall"
+	"time"
+)
+
+func init() {
+	register("CrashMinidump", CrashMinidump)
+}
+
+// CrashMinidump dies of a fatal signal after pointing the runtime at
+// the file named by $MINIDUMP_FILE.
+func CrashMinidump() {
+	f, err := os.Create(os.Getenv("MINIDUMP_FILE"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	debug.SetCrashDumpFD(int(f.Fd()))
+	syscall.Kill(syscall.Getpid(), syscall.SIGSEGV)
+	time.Sleep(10 * time.Second)
+	fmt.Println("not reached")
+}
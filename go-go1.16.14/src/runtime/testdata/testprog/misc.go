@@ -8,8 +8,14 @@ import "runtime"
 
 func init() {
 	register("NumGoroutine", NumGoroutine)
+	register("BuildConfig", BuildConfig)
 }
 
 func NumGoroutine() {
 	println(runtime.NumGoroutine())
 }
+
+func BuildConfig() {
+	c := runtime.ReadBuildConfig()
+	println(c.Mode, c.Library)
+}
@@ -0,0 +1,60 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"syscall"
+)
+
+func init() {
+	register("OutOfMemoryHandler", OutOfMemoryHandler)
+}
+
+// OutOfMemoryHandler limits its address space, so that the heap
+// cannot grow much, and allocates until the handler runs.
+func OutOfMemoryHandler() {
+	called := make(chan bool)
+	debug.SetOutOfMemoryHandler(func() { close(called) }, 128<<20)
+
+	statm, err := os.ReadFile("/proc/self/statm")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	pages, err := strconv.ParseUint(string(bytes.Fields(statm)[0]), 10, 64)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	limit := pages*uint64(os.Getpagesize()) + 256<<20
+	if err := syscall.Setrlimit(syscall.RLIMIT_AS, &syscall.Rlimit{Cur: limit, Max: limit}); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	var live [][]byte
+	for {
+		select {
+		case <-called:
+			live = nil
+			runtime.GC()
+			fmt.Println("OK")
+			return
+		default:
+		}
+		if len(live) > 4096 {
+			fmt.Println("no out of memory after 4GB")
+			return
+		}
+		live = append(live, make([]byte, 1<<20))
+		runtime.Gosched()
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/metrics"
+	"sync/atomic"
+	"time"
+)
+
+func init() {
+	register("AsyncPreemptMetrics", AsyncPreemptMetrics)
+	register("TimerPreemptMetrics", TimerPreemptMetrics)
+}
+
+// spin starts a goroutine spinning without calls, which can only be
+// stopped by asynchronous preemption, and returns a function that
+// stops it.
+func spin() (stop func()) {
+	var ready, done uint32
+	exited := make(chan bool)
+	go func() {
+		atomic.StoreUint32(&ready, 1)
+		for atomic.LoadUint32(&done) == 0 {
+		}
+		exited <- true
+	}()
+	for atomic.LoadUint32(&ready) == 0 {
+		runtime.Gosched()
+	}
+	return func() {
+		atomic.StoreUint32(&done, 1)
+		<-exited
+	}
+}
+
+func AsyncPreemptMetrics() {
+	// Read the outcomes before the requests, so that every
+	// outcome counted has its request counted too.
+	samples := []metrics.Sample{
+		{Name: "/sched/preempt/injected:requests"},
+		{Name: "/sched/preempt/dropped:requests"},
+		{Name: "/sched/preempt/requests:requests"},
+	}
+	metrics.Read(samples)
+	injected0 := samples[0].Value.Uint64()
+
+	// With the only P busy, the GC must preempt the spinning
+	// goroutine to stop the world.
+	runtime.GOMAXPROCS(1)
+	stop := spin()
+	runtime.GC()
+	stop()
+
+	metrics.Read(samples)
+	injected, dropped, requests := samples[0].Value.Uint64(), samples[1].Value.Uint64(), samples[2].Value.Uint64()
+	if injected == injected0 {
+		fmt.Println("no asynchronous preemption counted")
+		return
+	}
+	if injected+dropped > requests {
+		fmt.Printf("%d injected and %d dropped of only %d requests\n", injected, dropped, requests)
+		return
+	}
+	fmt.Println("OK")
+}
+
+func TimerPreemptMetrics() {
+	samples := []metrics.Sample{
+		{Name: "/sched/timers/preemptions:requests"},
+		{Name: "/sched/timers/latency:seconds"},
+	}
+	metrics.Read(samples)
+	preempts0 := samples[0].Value.Uint64()
+
+	// With the only P busy, the sleeps' timers are overdue until
+	// sysmon preempts the spinning goroutine.
+	runtime.GOMAXPROCS(1)
+	stop := spin()
+	// sysmon may have backed off to sleeping up to 10ms and find the
+	// goroutine due for preemption for running too long first, so
+	// sleep until a preemption for the timers is counted.
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+		metrics.Read(samples)
+		if samples[0].Value.Uint64() != preempts0 {
+			break
+		}
+	}
+	stop()
+
+	metrics.Read(samples)
+	if samples[0].Value.Uint64() == preempts0 {
+		fmt.Println("no preemption for overdue timers counted")
+		return
+	}
+	var n uint64
+	for _, c := range samples[1].Value.Float64Histogram().Counts {
+		n += c
+	}
+	if n == 0 {
+		fmt.Println("no timer latency recorded")
+		return
+	}
+	fmt.Println("OK")
+}
@@ -0,0 +1,87 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync/atomic"
+)
+
+func init() {
+	register("Safepoint", Safepoint)
+	register("STWDelay", STWDelay)
+	register("STWWatchdog", STWWatchdog)
+}
+
+// Safepoint, STWDelay and STWWatchdog run under
+// GODEBUG=asyncpreemptoff=1, so that a loop without calls cannot be
+// preempted.
+
+func Safepoint() {
+	runtime.GOMAXPROCS(2)
+	var started, stop uint32
+	done := make(chan bool)
+	go func() {
+		atomic.StoreUint32(&started, 1)
+		for atomic.LoadUint32(&stop) == 0 {
+			runtime.Safepoint()
+		}
+		done <- true
+	}()
+	for atomic.LoadUint32(&started) == 0 {
+		runtime.Gosched()
+	}
+	runtime.GC()
+	atomic.StoreUint32(&stop, 1)
+	<-done
+	fmt.Println("OK")
+}
+
+func STWDelay() {
+	runtime.GOMAXPROCS(2)
+	var started uint32
+	done := make(chan int)
+	go stwSpin(&started, done)
+	for atomic.LoadUint32(&started) == 0 {
+		runtime.Gosched()
+	}
+	runtime.GC()
+	<-done
+	d, ok := debug.ReadSTWDelay()
+	if !ok {
+		fmt.Println("no delayed stop of the world recorded")
+		return
+	}
+	if d.Function != "main.stwSpin" || d.Wait <= 0 || len(d.Stack) == 0 {
+		fmt.Printf("got delay %+v, want one by main.stwSpin\n", d)
+		return
+	}
+	fmt.Println("OK")
+}
+
+func STWWatchdog() {
+	runtime.GOMAXPROCS(2)
+	var started uint32
+	done := make(chan int)
+	go stwSpin(&started, done)
+	for atomic.LoadUint32(&started) == 0 {
+		runtime.Gosched()
+	}
+	runtime.GC()
+	<-done
+	fmt.Println("OK")
+}
+
+//go:noinline
+func stwSpin(started *uint32, done chan int) {
+	atomic.StoreUint32(started, 1)
+	x := 0
+	for i := 0; i < 1<<27; i++ {
+		x += i ^ x>>3
+	}
+	done <- x
+}
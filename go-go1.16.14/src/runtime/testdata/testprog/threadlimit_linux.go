@@ -0,0 +1,63 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+	"runtime/metrics"
+	"sync"
+	"syscall"
+)
+
+func init() {
+	register("ThreadLimitHandler", ThreadLimitHandler)
+}
+
+// ThreadLimitHandler blocks more goroutines in system calls than the
+// thread limit allows, which would crash without the handler.
+func ThreadLimitHandler() {
+	called := make(chan debug.ThreadWarning, 1)
+	debug.SetThreadLimitHandler(func(w debug.ThreadWarning) {
+		select {
+		case called <- w:
+		default:
+		}
+	})
+
+	samples := []metrics.Sample{{Name: "/sched/threads/total:threads"}}
+	metrics.Read(samples)
+	limit := int(samples[0].Value.Uint64()) + 2
+	debug.SetMaxThreads(limit)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ts := syscall.NsecToTimespec(20e6)
+			syscall.Nanosleep(&ts, nil)
+		}()
+	}
+	wg.Wait()
+
+	select {
+	case w := <-called:
+		if w.Limit != limit || w.Threads < limit {
+			fmt.Printf("handler called with %d threads, limit %d; want limit %d\n", w.Threads, w.Limit, limit)
+			return
+		}
+	default:
+		fmt.Println("handler not called")
+		return
+	}
+	samples[0].Name = "/sched/threads/deferred:threads"
+	metrics.Read(samples)
+	if samples[0].Value.Uint64() == 0 {
+		fmt.Println("no threads deferred")
+		return
+	}
+	fmt.Println("OK")
+}
@@ -0,0 +1,55 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+)
+
+func init() {
+	register("WaitSince", WaitSince)
+}
+
+func waitSinceBlocked(c chan bool) {
+	<-c
+}
+
+// WaitSince runs under GODEBUG=waitsince=1, so a goroutine records when
+// it blocked without waiting for the garbage collector to notice.
+func WaitSince() {
+	const wait = 100 * time.Millisecond
+	c := make(chan bool)
+	defer close(c)
+	start := time.Now()
+	go waitSinceBlocked(c)
+	time.Sleep(wait)
+
+	n, _ := runtime.Goroutines(nil)
+	p := make([]runtime.GoroutineInfo, n+10)
+	n, _ = runtime.Goroutines(p)
+	end := time.Now()
+	for _, r := range p[:n] {
+		if runtime.FuncForPC(r.StartPC).Name() != "main.waitSinceBlocked" {
+			continue
+		}
+		if r.WaitReason != "chan receive" {
+			fmt.Printf("blocked goroutine is %+v, want waiting on chan receive\n", r)
+			return
+		}
+		if r.WaitSince < start.UnixNano() || r.WaitSince > end.UnixNano() {
+			fmt.Printf("WaitSince is %v, want between %v and %v\n", time.Unix(0, r.WaitSince), start, end)
+			return
+		}
+		if r.WaitTime < int64(wait) || r.WaitTime > int64(end.Sub(start)) {
+			fmt.Printf("WaitTime is %v, want between %v and %v\n", time.Duration(r.WaitTime), wait, end.Sub(start))
+			return
+		}
+		fmt.Println("OK")
+		return
+	}
+	fmt.Println("blocked goroutine not found")
+}
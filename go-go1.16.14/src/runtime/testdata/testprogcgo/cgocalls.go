@@ -0,0 +1,57 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !plan9,!windows
+
+package main
+
+/*
+#include <unistd.h>
+
+static void cgoCallsSleep() {
+	usleep(20000);
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+)
+
+func init() {
+	register("GoroutineCgoCalls", GoroutineCgoCalls)
+}
+
+func GoroutineCgoCalls() {
+	n0, ns0 := runtime.GoroutineCgoCalls()
+	for i := 0; i < 3; i++ {
+		C.cgoCallsSleep()
+	}
+	n, ns := runtime.GoroutineCgoCalls()
+	if n-n0 != 3 {
+		fmt.Printf("got %d cgo calls, want 3\n", n-n0)
+	}
+	// Allow for a coarse conversion from ticks to time.
+	if d := time.Duration(ns - ns0); d < 30*time.Millisecond {
+		fmt.Printf("got %v in cgo calls, want about 60ms\n", d)
+	}
+
+	// Other goroutines are counted separately.
+	done := make(chan bool)
+	go func() {
+		if n, ns := runtime.GoroutineCgoCalls(); n != 0 || ns != 0 {
+			fmt.Printf("new goroutine has %d cgo calls and %dns\n", n, ns)
+		}
+		C.cgoCallsSleep()
+		close(done)
+	}()
+	<-done
+	if n2, _ := runtime.GoroutineCgoCalls(); n2 != n {
+		fmt.Printf("got %d cgo calls after other goroutine, want %d\n", n2, n)
+	}
+
+	fmt.Println("OK")
+}
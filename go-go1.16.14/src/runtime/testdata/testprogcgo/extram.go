@@ -0,0 +1,136 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !plan9,!windows
+
+package main
+
+// Test that runtime/debug.SetMaxExtraMs bounds the number of C threads
+// running Go code at once, and that idle extra Ms are freed.
+
+/*
+#include <pthread.h>
+
+extern void GoExtraM();
+
+#define EXTRAM_THREADS 8
+
+static void* extraMThread(void* p) {
+	GoExtraM();
+	return NULL;
+}
+
+static void runExtraMThreads() {
+	int i;
+	pthread_t t[EXTRAM_THREADS];
+
+	for (i = 0; i < EXTRAM_THREADS; i++) {
+		pthread_create(&t[i], NULL, extraMThread, NULL);
+	}
+	for (i = 0; i < EXTRAM_THREADS; i++) {
+		pthread_join(t[i], NULL);
+	}
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"os"
+	"runtime/debug"
+	"runtime/metrics"
+	"time"
+)
+
+func init() {
+	register("ExtraMLimit", ExtraMLimit)
+}
+
+var (
+	extraMEntered = make(chan bool, C.EXTRAM_THREADS)
+	extraMRelease chan bool
+)
+
+//export GoExtraM
+func GoExtraM() {
+	extraMEntered <- true
+	<-extraMRelease
+}
+
+// runExtraMThreads starts EXTRAM_THREADS C threads that call GoExtraM,
+// waits for want of them to enter it, and returns a function that lets
+// all of them finish.
+func runExtraMThreads(want int) (finish func()) {
+	extraMRelease = make(chan bool)
+	done := make(chan bool)
+	go func() {
+		C.runExtraMThreads()
+		done <- true
+	}()
+	for i := 0; i < want; i++ {
+		<-extraMEntered
+	}
+	return func() {
+		close(extraMRelease)
+		<-done
+		for len(extraMEntered) > 0 {
+			<-extraMEntered
+		}
+	}
+}
+
+func readExtraMMetrics() (inUse, idle, freed uint64) {
+	s := []metrics.Sample{
+		{Name: "/cgo/extra-ms/in-use:threads"},
+		{Name: "/cgo/extra-ms/idle:threads"},
+		{Name: "/cgo/extra-ms/freed:threads"},
+	}
+	metrics.Read(s)
+	return s[0].Value.Uint64(), s[1].Value.Uint64(), s[2].Value.Uint64()
+}
+
+func ExtraMLimit() {
+	const n = C.EXTRAM_THREADS
+
+	finish := runExtraMThreads(n)
+	if inUse, _, _ := readExtraMMetrics(); inUse != n {
+		fmt.Printf("%d extra Ms in use, want %d\n", inUse, n)
+		os.Exit(1)
+	}
+	finish()
+	if inUse, idle, _ := readExtraMMetrics(); inUse != 0 || idle < n {
+		fmt.Printf("%d extra Ms in use and %d idle, want 0 and at least %d\n", inUse, idle, n)
+		os.Exit(1)
+	}
+
+	// Lowering the limit frees the extra Ms beyond it.
+	debug.SetMaxExtraMs(2)
+	if _, idle, freed := readExtraMMetrics(); idle != 2 || freed < n-1 {
+		fmt.Printf("%d extra Ms idle and %d freed, want 2 and at least %d\n", idle, freed, n-1)
+		os.Exit(1)
+	}
+
+	// Only two threads get to run Go code at a time.
+	finish = runExtraMThreads(2)
+	time.Sleep(100 * time.Millisecond)
+	if inUse, _, _ := readExtraMMetrics(); inUse != 2 || len(extraMEntered) != 0 {
+		fmt.Printf("%d extra Ms in use and %d more threads entered Go, want 2 and 0\n", inUse, len(extraMEntered))
+		os.Exit(1)
+	}
+	finish()
+
+	// An idle timeout frees all but one idle extra M.
+	debug.SetMaxExtraMs(0)
+	debug.SetExtraMIdleTimeout(time.Nanosecond)
+	if inUse, idle, _ := readExtraMMetrics(); inUse != 0 || idle != 1 {
+		fmt.Printf("%d extra Ms in use and %d idle, want 0 and 1\n", inUse, idle)
+		os.Exit(1)
+	}
+
+	// Freed extra Ms are replaced as needed.
+	finish = runExtraMThreads(n)
+	finish()
+
+	fmt.Println("OK")
+}
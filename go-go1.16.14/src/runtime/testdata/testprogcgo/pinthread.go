@@ -0,0 +1,98 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux
+
+package main
+
+// Test that a C thread that calls runtime/cgo.PinThread runs on the
+// same goroutine in each call into Go, and that tracebacks name it.
+
+/*
+#include <pthread.h>
+
+extern void GoPinThread(int);
+
+#define PINTHREAD_CALLS 5
+
+static void* pinThread(void* p) {
+	int i;
+
+	for (i = 0; i < PINTHREAD_CALLS; i++) {
+		GoPinThread(i);
+	}
+	return NULL;
+}
+
+static void runPinThread() {
+	pthread_t t;
+
+	pthread_create(&t, NULL, pinThread, NULL);
+	pthread_join(t, NULL);
+}
+*/
+import "C"
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"runtime/cgo"
+	"strconv"
+)
+
+func init() {
+	register("PinThread", PinThread)
+}
+
+var (
+	pinThreadGoids []uint64
+	pinThreadErr   error
+)
+
+//export GoPinThread
+func GoPinThread(i C.int) {
+	if pinThreadErr != nil {
+		return
+	}
+	if i == 0 && !cgo.PinThread("worker") {
+		pinThreadErr = fmt.Errorf("PinThread failed")
+		return
+	}
+	buf := make([]byte, 4096)
+	buf = buf[:runtime.Stack(buf, false)]
+	if !bytes.Contains(buf, []byte("running on C thread worker\n")) {
+		pinThreadErr = fmt.Errorf("call %d: traceback does not name thread:\n%s", i, buf)
+		return
+	}
+	f := bytes.Fields(buf)
+	if len(f) < 2 {
+		pinThreadErr = fmt.Errorf("bad traceback:\n%s", buf)
+		return
+	}
+	goid, err := strconv.ParseUint(string(f[1]), 10, 64)
+	if err != nil {
+		pinThreadErr = err
+		return
+	}
+	pinThreadGoids = append(pinThreadGoids, goid)
+	if i == C.PINTHREAD_CALLS-1 {
+		cgo.UnpinThread()
+	}
+}
+
+func PinThread() {
+	C.runPinThread()
+	if pinThreadErr != nil {
+		fmt.Println(pinThreadErr)
+		return
+	}
+	for _, goid := range pinThreadGoids[1:] {
+		if goid != pinThreadGoids[0] {
+			fmt.Printf("goroutine changed between calls: %v\n", pinThreadGoids)
+			return
+		}
+	}
+	fmt.Println("OK")
+}
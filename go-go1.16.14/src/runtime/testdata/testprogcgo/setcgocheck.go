@@ -0,0 +1,37 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+// Test that runtime/debug.SetCgoCheck turns on the checks of
+// GODEBUG=cgocheck=2 in a running program.
+
+// #include <stdlib.h>
+import "C"
+
+import (
+	"fmt"
+	"runtime/debug"
+	"unsafe"
+)
+
+func init() {
+	register("SetCgoCheck", SetCgoCheck)
+}
+
+var setCgoCheckPtr = new(int)
+
+func SetCgoCheck() {
+	p := (*unsafe.Pointer)(C.malloc(C.size_t(unsafe.Sizeof(unsafe.Pointer(nil)))))
+
+	// Not caught at the default level.
+	*p = unsafe.Pointer(setCgoCheckPtr)
+	*p = nil
+
+	if prev := debug.SetCgoCheck(2); prev != 1 {
+		fmt.Printf("SetCgoCheck returned %d, want 1\n", prev)
+	}
+	*p = unsafe.Pointer(setCgoCheckPtr)
+	fmt.Println("write not caught")
+}
@@ -0,0 +1,86 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !plan9,!windows
+
+package main
+
+// Test that handlers installed by runtime.AddSignalHandler are called in
+// order and that a handler can keep a signal from the runtime.
+
+/*
+#include <signal.h>
+#include <stdint.h>
+
+struct sigHandlerArg {
+	uintptr_t Sig;
+	siginfo_t* Info;
+	void* Context;
+};
+
+static volatile int calls[2];
+static volatile int order;
+
+static int firstHandler(struct sigHandlerArg* arg) {
+	if (arg->Sig == SIGUSR1) {
+		calls[0] = ++order;
+	}
+	return 0;
+}
+
+static int secondHandler(struct sigHandlerArg* arg) {
+	if (arg->Sig == SIGUSR1) {
+		calls[1] = ++order;
+		return 1;
+	}
+	return 0;
+}
+
+static void* firstHandlerPtr() { return firstHandler; }
+static void* secondHandlerPtr() { return secondHandler; }
+static int firstCall() { return calls[0]; }
+static int secondCall() { return calls[1]; }
+*/
+import "C"
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"time"
+)
+
+func init() {
+	register("SignalHandlerChain", SignalHandlerChain)
+}
+
+func SignalHandlerChain() {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGUSR1)
+
+	runtime.AddSignalHandler(int(syscall.SIGUSR1), C.firstHandlerPtr())
+	runtime.AddSignalHandler(int(syscall.SIGUSR1), C.secondHandlerPtr())
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR1); err != nil {
+		fmt.Println(err)
+		return
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for C.secondCall() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if first, second := C.firstCall(), C.secondCall(); first != 1 || second != 2 {
+		fmt.Printf("handlers called in order %d, %d; want 1, 2\n", first, second)
+		return
+	}
+
+	select {
+	case <-c:
+		fmt.Println("handled signal delivered to os/signal")
+	case <-time.After(100 * time.Millisecond):
+		fmt.Println("OK")
+	}
+}
@@ -0,0 +1,32 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !plan9,!windows
+
+#include <pthread.h>
+#include <signal.h>
+#include <stddef.h>
+
+#include "_cgo_export.h"
+
+size_t sigStackSize(void) {
+	stack_t st;
+
+	if (sigaltstack(NULL, &st) < 0 || (st.ss_flags & SS_DISABLE) != 0) {
+		return 0;
+	}
+	return st.ss_size;
+}
+
+static void* sigStackSizeThread(void* arg) {
+	goSigStackSizeCallback();
+	return NULL;
+}
+
+void sigStackSizeOnCThread(void) {
+	pthread_t tid;
+
+	pthread_create(&tid, NULL, sigStackSizeThread, NULL);
+	pthread_join(tid, NULL);
+}
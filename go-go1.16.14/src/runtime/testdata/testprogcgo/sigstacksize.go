@@ -0,0 +1,60 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !plan9,!windows
+
+package main
+
+// Report the size of the signal stack that the runtime installs on a
+// thread created by Go and on a thread created by C.
+
+/*
+#include <stddef.h>
+
+extern size_t sigStackSize(void);
+extern void sigStackSizeOnCThread(void);
+*/
+import "C"
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+func init() {
+	register("CgoSignalStackSize", CgoSignalStackSize)
+}
+
+var (
+	setSigStackSize     bool
+	cThreadSigStackSize C.size_t
+)
+
+//export goSigStackSizeCallback
+func goSigStackSizeCallback() {
+	if setSigStackSize {
+		debug.SetCgoSignalStackSize(1 << 20)
+		return
+	}
+	cThreadSigStackSize = C.sigStackSize()
+}
+
+func CgoSignalStackSize() {
+	// Set the size during a callback, so that the extra M running
+	// it is in use. The callback below reuses that M.
+	setSigStackSize = true
+	C.sigStackSizeOnCThread()
+	setSigStackSize = false
+
+	done := make(chan C.size_t)
+	go func() {
+		runtime.LockOSThread()
+		done <- C.sigStackSize()
+	}()
+	fmt.Println("go thread:", <-done)
+
+	C.sigStackSizeOnCThread()
+	fmt.Println("c thread:", cThreadSigStackSize)
+}
@@ -0,0 +1,120 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux
+
+package main
+
+// Test that C threads registered with runtime.AddCPUProfileThread are
+// sampled by the CPU profiler, and that they are not sampled twice.
+
+/*
+#cgo LDFLAGS: -pthread
+
+#include <pthread.h>
+#include <sys/syscall.h>
+#include <unistd.h>
+
+#define NTHREADS 2
+
+static volatile int stopSpin;
+static volatile int tids[NTHREADS];
+
+static void* spinThread(void* arg) {
+	int i = (int)(long)(arg);
+
+	tids[i] = syscall(SYS_gettid);
+	while (!stopSpin) {
+	}
+	return NULL;
+}
+
+static pthread_t threads[NTHREADS];
+
+static void startSpinThreads() {
+	int i;
+
+	for (i = 0; i < NTHREADS; i++) {
+		pthread_create(&threads[i], NULL, spinThread, (void*)(long)(i));
+	}
+}
+
+static int spinThreadTid(int i) {
+	return tids[i];
+}
+
+static void stopSpinThreads() {
+	int i;
+
+	stopSpin = 1;
+	for (i = 0; i < NTHREADS; i++) {
+		pthread_join(threads[i], NULL);
+	}
+}
+*/
+import "C"
+
+import (
+	"bytes"
+	"fmt"
+	"internal/profile"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"syscall"
+	"time"
+)
+
+func init() {
+	register("CgoProfileThread", CgoProfileThread)
+}
+
+func CgoProfileThread() {
+	C.startSpinThreads()
+	var tids []int
+	for i := 0; i < C.NTHREADS; i++ {
+		for C.spinThreadTid(C.int(i)) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		tid := int(C.spinThreadTid(C.int(i)))
+		runtime.AddCPUProfileThread(tid)
+		tids = append(tids, tid)
+	}
+
+	var before, after syscall.Rusage
+	syscall.Getrusage(syscall.RUSAGE_SELF, &before)
+	var buf bytes.Buffer
+	if err := pprof.StartCPUProfile(&buf); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	time.Sleep(500 * time.Millisecond)
+	pprof.StopCPUProfile()
+	syscall.Getrusage(syscall.RUSAGE_SELF, &after)
+
+	for _, tid := range tids {
+		runtime.RemoveCPUProfileThread(tid)
+	}
+	C.stopSpinThreads()
+
+	p, err := profile.Parse(&buf)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	var samples int64
+	for _, s := range p.Sample {
+		samples += s.Value[0]
+	}
+
+	cpu := time.Duration(syscall.TimevalToNsec(after.Utime) - syscall.TimevalToNsec(before.Utime) +
+		syscall.TimevalToNsec(after.Stime) - syscall.TimevalToNsec(before.Stime))
+	// The default profiling rate is 100 Hz.
+	want := int64(cpu / (10 * time.Millisecond))
+	if samples < want*4/10 || samples > want*16/10 {
+		fmt.Printf("got %d samples for %v of CPU time, want about %d\n", samples, cpu, want)
+		return
+	}
+	fmt.Println("OK")
+}
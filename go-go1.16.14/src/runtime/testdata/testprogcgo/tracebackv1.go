@@ -0,0 +1,94 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+// Test version 1 of SetCgoTraceback: tracebacks longer than one call
+// of the traceback function, and several registrations.
+
+/*
+// Defined in tracebackv1_c.c.
+extern void TV1C(void);
+extern void tv1Context(void*);
+extern void tv1Traceback(void*);
+extern void tv1Symbolizer(void*);
+extern void tv2Symbolizer(void*);
+extern int getTV1Releases(void);
+extern int getTV1BadData(void);
+*/
+import "C"
+
+import (
+	"fmt"
+	"runtime"
+	"unsafe"
+)
+
+func init() {
+	register("TracebackV1", TracebackV1)
+}
+
+var tracebackV1Err error
+
+func TracebackV1() {
+	runtime.SetCgoTraceback(1, unsafe.Pointer(C.tv1Traceback), unsafe.Pointer(C.tv1Context), unsafe.Pointer(C.tv1Symbolizer))
+	runtime.SetCgoTraceback(1, nil, nil, unsafe.Pointer(C.tv2Symbolizer))
+	// Registering the same functions again has no effect.
+	runtime.SetCgoTraceback(1, nil, nil, unsafe.Pointer(C.tv2Symbolizer))
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				fmt.Println("second context function accepted")
+			}
+		}()
+		runtime.SetCgoTraceback(1, nil, unsafe.Pointer(C.tv1Traceback), nil)
+	}()
+
+	C.TV1C()
+	if tracebackV1Err != nil {
+		fmt.Println(tracebackV1Err)
+		return
+	}
+	if got := C.getTV1Releases(); got != 1 {
+		fmt.Printf("traceback data released %d times, want 1\n", got)
+		return
+	}
+	if got := C.getTV1BadData(); got != 0 {
+		fmt.Printf("traceback data not kept across calls %d times\n", got)
+		return
+	}
+	fmt.Println("OK")
+}
+
+//export TV1G
+func TV1G() {
+	pc := make([]uintptr, 128)
+	n := runtime.Callers(0, pc)
+	cf := runtime.CallersFrames(pc[:n])
+	line := 0
+	for {
+		frame, more := cf.Next()
+		if frame.Function == "tv1Func" {
+			if frame.Line != line {
+				tracebackV1Err = fmt.Errorf("tv1Func frame %d has line %d", line, frame.Line)
+				return
+			}
+			line++
+		}
+		if !more {
+			break
+		}
+	}
+	if line != 40 {
+		tracebackV1Err = fmt.Errorf("found %d tv1Func frames, want 40", line)
+		return
+	}
+
+	// PCs unknown to the first symbolizer go to the second.
+	frame, _ := runtime.CallersFrames([]uintptr{0x2003}).Next()
+	if frame.Function != "tv2Func" || frame.Line != 3 {
+		tracebackV1Err = fmt.Errorf("second symbolizer gave %s:%d", frame.Function, frame.Line)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// The C definitions for tracebackv1.go. That file uses //export so
+// it can't put function definitions in the "C" import comment.
+
+#include <stdint.h>
+
+// Function exported from Go.
+extern void TV1G(void);
+
+void TV1C() {
+	TV1G();
+}
+
+struct cgoContextArg {
+	uintptr_t context;
+};
+
+struct cgoTracebackArg {
+	uintptr_t  context;
+	uintptr_t  sigContext;
+	uintptr_t* buf;
+	uintptr_t  max;
+	uintptr_t  skip;
+	uintptr_t  data;
+};
+
+struct cgoSymbolizerArg {
+	uintptr_t   pc;
+	const char* file;
+	uintptr_t   lineno;
+	const char* func;
+	uintptr_t   entry;
+	uintptr_t   more;
+	uintptr_t   data;
+};
+
+// The traceback is longer than the runtime's buffer of 32 PCs, so that
+// it takes more than one call to the traceback function.
+#define TV1_DEPTH 40
+
+static int tv1Releases;
+static int tv1BadData;
+
+int getTV1Releases() {
+	return __sync_add_and_fetch(&tv1Releases, 0);
+}
+
+int getTV1BadData() {
+	return __sync_add_and_fetch(&tv1BadData, 0);
+}
+
+void tv1Context(void* parg) {
+	struct cgoContextArg* arg = (struct cgoContextArg*)(parg);
+	if (arg->context == 0) {
+		arg->context = 1;
+	}
+}
+
+void tv1Traceback(void* parg) {
+	uintptr_t i;
+	struct cgoTracebackArg* arg = (struct cgoTracebackArg*)(parg);
+	if (arg->context == 0) {
+		arg->buf[0] = 0;
+		return;
+	}
+	if (arg->max == 0) {
+		if (arg->data != TV1_DEPTH) {
+			__sync_add_and_fetch(&tv1BadData, 1);
+		}
+		__sync_add_and_fetch(&tv1Releases, 1);
+		arg->data = 0;
+		return;
+	}
+	// data is the number of PCs stored by the previous calls.
+	if (arg->data != arg->skip) {
+		__sync_add_and_fetch(&tv1BadData, 1);
+	}
+	for (i = 0; i < arg->max; i++) {
+		if (arg->skip + i == TV1_DEPTH) {
+			arg->buf[i] = 0;
+			break;
+		}
+		arg->buf[i] = 0x1000 + arg->skip + i;
+	}
+	arg->data = arg->skip + i;
+}
+
+void tv1Symbolizer(void* parg) {
+	struct cgoSymbolizerArg* arg = (struct cgoSymbolizerArg*)(parg);
+	if (arg->pc < 0x1000 || arg->pc >= 0x1000 + TV1_DEPTH) {
+		return;
+	}
+	arg->file = "tracebackv1.go";
+	arg->func = "tv1Func";
+	arg->lineno = arg->pc - 0x1000;
+}
+
+void tv2Symbolizer(void* parg) {
+	struct cgoSymbolizerArg* arg = (struct cgoSymbolizerArg*)(parg);
+	if (arg->pc < 0x2000 || arg->pc >= 0x2010) {
+		return;
+	}
+	arg->file = "tracebackv1.go";
+	arg->func = "tv2Func";
+	arg->lineno = arg->pc - 0x2000;
+}
@@ -0,0 +1,340 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Thread count warning.
+//
+// runtime/debug.SetThreadWarning registers a function to call when
+// the number of Ms reaches a threshold, so that a program learns it
+// is approaching the limit set by SetMaxThreads before the runtime
+// throws "thread exhaustion". The M being created cannot call the
+// function itself, since checkmcount runs with sched.lock held. It
+// only marks the warning pending and wakes sysmon, which wakes the thread
+// warning helper goroutine, which collects the stacks of the
+// goroutines holding threads and starts the function with them.
+//
+// A warning fires once, and is armed again when sysmon finds the
+// number of Ms below the threshold.
+//
+// runtime/debug.SetThreadLimitHandler registers a function that makes
+// the limit itself non-fatal. While one is registered, startm does not
+// create an M once there are as many as the limit allows; it leaves
+// its P idle instead, with its work on the global run queue (see
+// deferThread). The work waits for an M to free up, such as one
+// returning from a system call, which finds the idle P. Meanwhile
+// sysmon keeps trying to start an M, and does not sleep. The function
+// is started by the same helper as the warning, once for each period
+// of waiting.
+
+package runtime
+
+import (
+	"runtime/internal/atomic"
+	"unsafe"
+)
+
+var threadWarning struct {
+	lock      mutex // protects the fields below; leaf lock
+	threshold int32 // 0 if no function is registered
+	f         func(threads, limit int, stacks []byte)
+	armed     bool  // fire when the number of Ms reaches threshold
+	threads   int32 // number of Ms when the warning fired
+	started   bool
+	g         *g
+	idle      bool
+
+	state uint32 // threadWarnIdle and so on; accessed atomically
+}
+
+// Values of threadWarning.state.
+const (
+	threadWarnIdle    = iota // armed, or no function registered
+	threadWarnPending        // fired, the helper is to run the function
+	threadWarnFired          // fired, to be armed again
+)
+
+var threadLimit struct {
+	deferred uint64 // Ms not started; written with sched.lock held, read atomically
+
+	// Protected by sched.lock.
+	f     func(threads, limit int, stacks []byte)
+	fired bool // f was started in the current period of waiting
+
+	waiting uint32 // a P was left idle for want of an M; accessed atomically
+	pending uint32 // the helper is to start f; accessed atomically
+}
+
+// mpeak is the highest number of Ms. It is written with sched.lock
+// held and read atomically.
+var mpeak int32
+
+// setThreadWarning registers f, replacing any previous function. A nil
+// f or a threshold of zero or less removes it.
+//
+//go:linkname setThreadWarning runtime/debug.setThreadWarning
+func setThreadWarning(threshold int, f func(threads, limit int, stacks []byte)) {
+	if threshold <= 0 || f == nil {
+		threshold, f = 0, nil
+	}
+	if threshold > 0x7fffffff {
+		threshold = 0x7fffffff
+	}
+	start := false
+	lock(&threadWarning.lock)
+	threadWarning.threshold = int32(threshold)
+	threadWarning.f = f
+	threadWarning.armed = f != nil
+	atomic.Cas(&threadWarning.state, threadWarnFired, threadWarnIdle)
+	if f != nil {
+		start = !threadWarning.started
+		threadWarning.started = true
+	}
+	unlock(&threadWarning.lock)
+	if start {
+		go threadwarnhelper()
+	}
+}
+
+// setThreadLimitHandler registers f, replacing any previous function.
+// A nil f removes it, making the thread limit fatal again.
+//
+//go:linkname setThreadLimitHandler runtime/debug.setThreadLimitHandler
+func setThreadLimitHandler(f func(threads, limit int, stacks []byte)) {
+	lock(&sched.lock)
+	threadLimit.f = f
+	unlock(&sched.lock)
+	if f != nil {
+		start := false
+		lock(&threadWarning.lock)
+		start = !threadWarning.started
+		threadWarning.started = true
+		unlock(&threadWarning.lock)
+		if start {
+			go threadwarnhelper()
+		}
+	}
+}
+
+// threadLimitReached reports whether startm must not create an M,
+// because there are as many as the limit allows and a thread limit
+// handler is registered.
+//
+// sched.lock must be held.
+func threadLimitReached() bool {
+	assertLockHeld(&sched.lock)
+
+	return threadLimit.f != nil && mcount() >= sched.maxmcount
+}
+
+// deferThread leaves _p_, which startm could not give to a new M, idle,
+// moving its local work to the global run queue for the next M to find.
+// It deals with a stop of the world or a safe point function pending
+// on _p_, as handoffp would.
+//
+// sched.lock must be held.
+//
+//go:nowritebarrierrec
+func deferThread(_p_ *p) {
+	assertLockHeld(&sched.lock)
+
+	atomic.Store64(&threadLimit.deferred, threadLimit.deferred+1)
+	atomic.Store(&threadLimit.waiting, 1)
+	if !threadLimit.fired {
+		threadLimit.fired = true
+		atomic.Store(&threadLimit.pending, 1)
+	}
+	if atomic.Load(&sched.sysmonwait) != 0 {
+		atomic.Store(&sched.sysmonwait, 0)
+		notewakeup(&sched.sysmonnote)
+	}
+
+	for {
+		gp, _ := runqget(_p_)
+		if gp == nil {
+			break
+		}
+		globrunqput(gp)
+	}
+	if sched.gcwaiting != 0 {
+		_p_.status = _Pgcstop
+		sched.stopwait--
+		if sched.stopwait == 0 {
+			notewakeup(&sched.stopnote)
+		}
+		return
+	}
+	if _p_.runSafePointFn != 0 && atomic.Cas(&_p_.runSafePointFn, 1, 0) {
+		sched.safePointFn(_p_)
+		sched.safePointWait--
+		if sched.safePointWait == 0 {
+			notewakeup(&sched.safePointNote)
+		}
+	}
+	pidleput(_p_)
+}
+
+// retryDeferredThreads tries again to start an M for the work left
+// waiting by deferThread. A period of waiting ends once startm no
+// longer has to defer. It is called by sysmon.
+func retryDeferredThreads() {
+	if atomic.Load(&threadLimit.waiting) == 0 {
+		return
+	}
+	lock(&sched.lock)
+	blocked := threadLimitReached() && sched.midle == 0
+	unlock(&sched.lock)
+	if blocked {
+		return
+	}
+	atomic.Store(&threadLimit.waiting, 0)
+	startm(nil, false)
+	if atomic.Load(&threadLimit.waiting) == 0 {
+		lock(&sched.lock)
+		threadLimit.fired = false
+		unlock(&sched.lock)
+	}
+}
+
+// threadWarningBusy reports whether sysmon must not sleep deeply,
+// because the thread warning helper is to be woken or work waits for
+// an M at the thread limit. These are set with sched.lock held, which
+// sysmon holds to check again before it sleeps deeply; otherwise they
+// could be set after sysmon checked them but before it set sysmonwait,
+// and sysmon would sleep until the next timer.
+func threadWarningBusy() bool {
+	return atomic.Load(&threadWarning.state) == threadWarnPending ||
+		atomic.Load(&threadLimit.pending) != 0 ||
+		atomic.Load(&threadLimit.waiting) != 0
+}
+
+// noteMCount records n, the number of Ms after creating one, and fires
+// the thread warning if n reaches its threshold. It is called by
+// checkmcount, with sched.lock held.
+func noteMCount(n int32) {
+	assertLockHeld(&sched.lock)
+
+	if n > mpeak {
+		atomic.Store((*uint32)(unsafe.Pointer(&mpeak)), uint32(n))
+	}
+	lock(&threadWarning.lock)
+	if threadWarning.armed && n >= threadWarning.threshold {
+		threadWarning.armed = false
+		threadWarning.threads = n
+		atomic.Store(&threadWarning.state, threadWarnPending)
+		// sysmon may be sleeping, the Ps all idle, for instance
+		// while the goroutines holding threads block.
+		if atomic.Load(&sched.sysmonwait) != 0 {
+			atomic.Store(&sched.sysmonwait, 0)
+			notewakeup(&sched.sysmonnote)
+		}
+	}
+	unlock(&threadWarning.lock)
+}
+
+// checkThreadWarning wakes the thread warning helper if the warning
+// fired, and arms the warning again once the number of Ms is below the
+// threshold. It is called by sysmon.
+func checkThreadWarning() {
+	state := atomic.Load(&threadWarning.state)
+	if state == threadWarnPending || atomic.Load(&threadLimit.pending) != 0 {
+		var gp *g
+		lock(&threadWarning.lock)
+		if threadWarning.idle {
+			threadWarning.idle = false
+			gp = threadWarning.g
+		}
+		unlock(&threadWarning.lock)
+		if gp != nil {
+			var list gList
+			list.push(gp)
+			injectglist(&list)
+		}
+	}
+	if state == threadWarnFired {
+		lock(&sched.lock)
+		n := mcount()
+		unlock(&sched.lock)
+		lock(&threadWarning.lock)
+		if n < threadWarning.threshold && atomic.Cas(&threadWarning.state, threadWarnFired, threadWarnIdle) {
+			threadWarning.armed = threadWarning.f != nil
+		}
+		unlock(&threadWarning.lock)
+	}
+}
+
+func threadwarnhelper() {
+	lock(&threadWarning.lock)
+	threadWarning.g = getg()
+	for {
+		threadWarning.idle = true
+		goparkunlock(&threadWarning.lock, waitReasonThreadWarningIdle, traceEvGoBlock, 1)
+		// this goroutine is explicitly resumed by sysmon
+
+		lock(&threadWarning.lock)
+		var f func(threads, limit int, stacks []byte)
+		threads := threadWarning.threads
+		if atomic.Load(&threadWarning.state) == threadWarnPending {
+			f = threadWarning.f
+		}
+		unlock(&threadWarning.lock)
+		lock(&sched.lock)
+		var lf func(threads, limit int, stacks []byte)
+		if atomic.Xchg(&threadLimit.pending, 0) != 0 {
+			lf = threadLimit.f
+		}
+		limit := sched.maxmcount
+		n := mcount()
+		unlock(&sched.lock)
+
+		if f != nil || lf != nil {
+			stacks := threadHolderStacks()
+			if f != nil {
+				go f(int(threads), int(limit), stacks)
+			}
+			if lf != nil {
+				go lf(int(n), int(limit), stacks)
+			}
+		}
+		lock(&threadWarning.lock)
+		atomic.Cas(&threadWarning.state, threadWarnPending, threadWarnFired)
+	}
+}
+
+// threadHolderStacks stops the world and returns the stacks of the
+// user goroutines that hold a thread, being in a system call or locked
+// to their thread, in the format of Stack.
+func threadHolderStacks() []byte {
+	me := getg()
+	for n := 16 << 10; ; n *= 2 {
+		buf := make([]byte, n)
+		stopTheWorld("thread warning")
+		systemstack(func() {
+			g0 := getg()
+			g0.m.traceback = 1
+			g0.writebuf = buf[0:0:len(buf)]
+			lock(&allglock)
+			for _, gp := range allgs {
+				if gp == me || isSystemGoroutine(gp, false) {
+					continue
+				}
+				status := readgstatus(gp) &^ _Gscan
+				if status == _Gdead || status != _Gsyscall && gp.lockedm == 0 {
+					continue
+				}
+				if len(g0.writebuf) > 0 {
+					print("\n")
+				}
+				goroutineheader(gp)
+				traceback(^uintptr(0), ^uintptr(0), 0, gp)
+			}
+			unlock(&allglock)
+			g0.m.traceback = 0
+			buf = g0.writebuf
+			g0.writebuf = nil
+		})
+		startTheWorld()
+		if len(buf) < n {
+			return buf
+		}
+	}
+}
@@ -162,6 +162,14 @@ const (
 // maxWhen is the maximum value for timer's when field.
 const maxWhen = 1<<63 - 1
 
+// timerLatency is the distribution of how late timers run, for the
+// /sched/timers/latency:seconds metric.
+var timerLatency timeHistogram
+
+// timerPreempts counts the preemptions requested by sysmon to run
+// overdue timers. Accessed atomically.
+var timerPreempts uint64
+
 // verifyTimers can be set to true to add debugging checks that the
 // timer heaps are valid.
 const verifyTimers = false
@@ -392,7 +400,10 @@ func dodeltimer(pp *p, i int) int {
 	if i == 0 {
 		updateTimer0When(pp)
 	}
-	atomic.Xadd(&pp.numTimers, -1)
+	if n := atomic.Xadd(&pp.numTimers, -1); n == 0 {
+		// If there are no timers, then clearly none are modified.
+		atomic.Store64(&pp.timerModifiedEarliest, 0)
+	}
 	return smallestChanged
 }
 
@@ -416,7 +427,10 @@ func dodeltimer0(pp *p) {
 		siftdownTimer(pp.timers, 0)
 	}
 	updateTimer0When(pp)
-	atomic.Xadd(&pp.numTimers, -1)
+	if n := atomic.Xadd(&pp.numTimers, -1); n == 0 {
+		// If there are no timers, then clearly none are modified.
+		atomic.Store64(&pp.timerModifiedEarliest, 0)
+	}
 }
 
 // modtimer modifies an existing timer.
@@ -594,9 +608,8 @@ func cleantimers(pp *p) {
 }
 
 // moveTimers moves a slice of timers to pp. The slice has been taken
-// from a different P.
-// This is currently called when the world is stopped, but the caller
-// is expected to have locked the timers for pp.
+// from a different P, parked above the limit or being destroyed.
+// The caller must have locked the timers of both Ps.
 func moveTimers(pp *p, timers []*timer) {
 	for _, t := range timers {
 	loop:
@@ -822,6 +835,8 @@ func runtimer(pp *p, now int64) int64 {
 // This will temporarily unlock the timers while running the timer function.
 //go:systemstack
 func runOneTimer(pp *p, t *timer, now int64) {
+	timerLatency.record(now - t.when)
+
 	if raceenabled {
 		ppcur := getg().m.p.ptr()
 		if ppcur.timerRaceCtx == 0 {
@@ -881,7 +896,7 @@ func runOneTimer(pp *p, t *timer, now int64) {
 // For example, this can happen via context.WithTimeout.
 //
 // This is the only function that walks through the entire timer heap,
-// other than moveTimers which only runs when the world is stopped.
+// other than moveTimers.
 //
 // The caller must have locked the timers for pp.
 func clearDeletedTimers(pp *p) {
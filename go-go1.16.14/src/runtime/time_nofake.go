@@ -11,15 +11,22 @@ import "unsafe"
 // faketime is the simulated time in nanoseconds since 1970 for the
 // playground.
 //
-// Zero means not to use faketime.
+// Zero means not to use faketime. GODEBUG=detsched sets it too,
+// for a virtual clock (see detsched.go).
 var faketime int64
 
 //go:nosplit
 func nanotime() int64 {
+	if faketime != 0 {
+		return faketime
+	}
 	return nanotime1()
 }
 
 func walltime() (sec int64, nsec int32) {
+	if faketime != 0 {
+		return faketime / 1000000000, int32(faketime % 1000000000)
+	}
 	return walltime1()
 }
 
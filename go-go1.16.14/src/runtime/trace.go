@@ -69,7 +69,11 @@ const (
 	traceEvUserTaskEnd       = 46 // end of a task [timestamp, internal task id, stack]
 	traceEvUserRegion        = 47 // trace.WithRegion [timestamp, internal task id, mode(0:start, 1:end), stack, name string]
 	traceEvUserLog           = 48 // trace.Log [timestamp, internal task id, key string id, stack, value string]
-	traceEvCount             = 49
+	traceEvUserLogInt        = 49 // trace.LogInt [timestamp, internal task id, key string id, value, stack]
+	traceEvUserCounter       = 50 // trace.Counter [timestamp, name string id, value]
+	traceEvUserFlow          = 51 // trace.StartFlow [timestamp, flow id, mode(0:start, 1:end), name string id, stack]
+	traceEvGoName            = 52 // runtime.SetGoroutineName [timestamp, goroutine id, name string id]
+	traceEvCount             = 53
 	// Byte is used but only 6 bits are available for event type.
 	// The remaining 2 bits are used to specify the number of arguments.
 	// That means, the max event type value is 63.
@@ -136,12 +140,25 @@ var trace struct {
 	stringsLock mutex
 	strings     map[string]uint64
 	stringSeq   uint64
+	stringsSize uintptr // bytes of strings added to strings
 
 	// markWorkerLabels maps gcMarkWorkerMode to string ID.
 	markWorkerLabels [len(gcMarkWorkerModeStrings)]uint64
 
 	bufLock mutex       // protects buf
 	buf     traceBufPtr // global trace buffer, used when running without a p
+
+	// Flight recorder mode; see traceflight.go. Protected by trace.lock.
+	flightBufs   int      // max number of full buffers to keep, or 0 if not in flight recorder mode
+	flightQueued int      // number of buffers on the full queue
+	flightCut    uint64   // latest timestamp of a recycled buffer
+	flightG      []uint64 // goroutine running as of each P's oldest retained buffer, indexed by pid+1
+	flightSwitch uint64   // timestamp of the last table generation switch
+
+	// Previous generation of the string and stack tables of the
+	// flight recorder. Protected by traceFlightSema.
+	flightStrings map[string]uint64 // also protected by stringsLock
+	flightStacks  *traceStackTable
 }
 
 // traceBufHeader is per-P tracing buffer.
@@ -181,6 +198,13 @@ func traceBufPtrOf(b *traceBuf) traceBufPtr {
 // Most clients should use the runtime/trace package or the testing package's
 // -test.trace flag instead of calling StartTrace directly.
 func StartTrace() error {
+	return startTrace(0)
+}
+
+// startTrace implements StartTrace. If flightBufs is non-zero, the
+// trace runs in flight recorder mode, retaining at most flightBufs full
+// buffers.
+func startTrace(flightBufs int) error {
 	// Stop the world so that we can take a consistent snapshot
 	// of all goroutines at the beginning of the trace.
 	// Do not stop the world during GC so we ensure we always see
@@ -258,16 +282,33 @@ func StartTrace() error {
 	//  remaining: other strings registered by traceString
 	trace.stringSeq = 0
 	trace.strings = make(map[string]uint64)
+	trace.stringsSize = 0
 
 	trace.seqGC = 0
+	trace.flightBufs = flightBufs
+	trace.flightQueued = 0
+	trace.flightCut = 0
+	trace.flightSwitch = 0
+	if flightBufs > 0 {
+		trace.flightG = make([]uint64, cap(allp)+1)
+	}
 	_g_.m.startingtrace = false
 	trace.enabled = true
 
 	// Register runtime goroutine labels.
-	_, pid, bufp := traceAcquireBuffer()
+	mp, pid, bufp := traceAcquireBuffer()
 	for i, label := range gcMarkWorkerModeStrings[:] {
 		trace.markWorkerLabels[i], bufp = traceString(bufp, pid, label)
 	}
+
+	// Name the goroutines named before the trace started.
+	for _, gp := range allgs {
+		if name := gp.name; name != nil && readgstatus(gp) != _Gdead {
+			var nameID uint64
+			nameID, bufp = traceString(bufp, pid, *name)
+			traceEventLocked(0, mp, pid, bufp, traceEvGoName, -1, uint64(gp.goid), nameID)
+		}
+	}
 	traceReleaseBuffer(pid)
 
 	unlock(&trace.bufLock)
@@ -335,11 +376,36 @@ func StopTrace() {
 
 	startTheWorldGC()
 
-	// The world is started but we've set trace.shutdown, so new tracing can't start.
-	// Wait for the trace reader to flush pending buffers and stop.
-	semacquire(&trace.shutdownSema)
-	if raceenabled {
-		raceacquire(unsafe.Pointer(&trace.shutdownSema))
+	if trace.flightBufs > 0 {
+		// A flight recorder has no reader, so discard the
+		// retained buffers and tables here.
+		semacquire(&traceFlightSema)
+		lock(&trace.lock)
+		for trace.fullHead != 0 {
+			buf := traceFullDequeue()
+			buf.ptr().link = trace.empty
+			trace.empty = buf
+		}
+		trace.flightBufs = 0
+		trace.flightQueued = 0
+		trace.flightG = nil
+		unlock(&trace.lock)
+		trace.stackTab.reset()
+		if tab := trace.flightStacks; tab != nil {
+			tab.mem.drop()
+			trace.flightStacks = nil
+		}
+		lock(&trace.stringsLock)
+		trace.flightStrings = nil
+		unlock(&trace.stringsLock)
+		semrelease(&traceFlightSema)
+	} else {
+		// The world is started but we've set trace.shutdown, so new tracing can't start.
+		// Wait for the trace reader to flush pending buffers and stop.
+		semacquire(&trace.shutdownSema)
+		if raceenabled {
+			raceacquire(unsafe.Pointer(&trace.shutdownSema))
+		}
 	}
 
 	// The lock protects us from races with StartTrace/StopTrace because they do stop-the-world.
@@ -368,6 +434,75 @@ func StopTrace() {
 	unlock(&trace.lock)
 }
 
+// trace_flushEpoch ends the current trace epoch. It hands all buffered
+// events to the trace reader, even if their buffers are not yet full,
+// and writes out and releases the stack and string tables, so that a
+// long-running trace is delivered promptly and in bounded memory. Stacks
+// and strings seen again later get new ids.
+//
+// Unlike StopTrace, it does not stop the world; per-P buffers are
+// flushed at each P's next safe point.
+//
+//go:linkname trace_flushEpoch runtime/trace.flushEpoch
+func trace_flushEpoch() {
+	// Allocate up front: allocating while holding worldsema could
+	// deadlock with starting a GC.
+	old := new(traceStackTable)
+	strings := make(map[string]uint64)
+
+	// forEachP needs worldsema, which also excludes StartTrace and
+	// StopTrace.
+	semacquire(&worldsema)
+	if !trace.enabled || trace.flightBufs > 0 {
+		// The flight recorder needs the tables for its snapshots.
+		semrelease(&worldsema)
+		return
+	}
+
+	// Move the stacks collected so far to a table of their own. They
+	// are still visible to lookups that started before this point.
+	trace.stackTab.rotate(old)
+
+	gp := getg().m.curg
+	systemstack(func() {
+		// Let our own stack be scanned while we wait for the other
+		// Ps, like gcMarkDone does; see the comment there.
+		casgstatus(gp, _Grunning, _Gwaiting)
+		forEachP(func(pp *p) {
+			if buf := pp.tracebuf; buf != 0 {
+				lock(&trace.lock)
+				traceFullQueue(buf)
+				unlock(&trace.lock)
+				pp.tracebuf = 0
+			}
+		})
+		casgstatus(gp, _Gwaiting, _Grunning)
+	})
+	// Ms without a P hold bufLock while emitting events, so once we
+	// hold it nothing can be looking at the old stacks anymore.
+	lock(&trace.bufLock)
+	if buf := trace.buf; buf != 0 && buf.ptr().pos != 0 {
+		trace.buf = 0
+		lock(&trace.lock)
+		traceFullQueue(buf)
+		unlock(&trace.lock)
+	}
+	unlock(&trace.bufLock)
+	semrelease(&worldsema)
+
+	old.dump()
+
+	lock(&trace.stringsLock)
+	if raceenabled {
+		raceacquire(unsafe.Pointer(&trace.stringsLock))
+	}
+	trace.strings = strings
+	if raceenabled {
+		racerelease(unsafe.Pointer(&trace.stringsLock))
+	}
+	unlock(&trace.stringsLock)
+}
+
 // ReadTrace returns the next chunk of binary tracing data, blocking until data
 // is available. If tracing is turned off and all the data accumulated while it
 // was on has been returned, ReadTrace returns nil. The caller must copy the
@@ -383,6 +518,12 @@ func ReadTrace() []byte {
 	lock(&trace.lock)
 	trace.lockOwner = getg()
 
+	if trace.flightBufs > 0 {
+		// The trace is retained for snapshots, not streamed.
+		trace.lockOwner = nil
+		unlock(&trace.lock)
+		return nil
+	}
 	if trace.reader != 0 {
 		// More than one goroutine reads trace. This is bad.
 		// But we rather do not crash the program because of tracing,
@@ -484,6 +625,8 @@ func traceProcFree(pp *p) {
 }
 
 // traceFullQueue queues buf into queue of full buffers.
+// In flight recorder mode, it recycles the oldest buffers to keep
+// at most trace.flightBufs queued.
 func traceFullQueue(buf traceBufPtr) {
 	buf.ptr().link = 0
 	if trace.fullHead == 0 {
@@ -492,6 +635,10 @@ func traceFullQueue(buf traceBufPtr) {
 		trace.fullTail.ptr().link = buf
 	}
 	trace.fullTail = buf
+	if trace.flightBufs > 0 {
+		trace.flightQueued++
+		traceFlightTrim()
+	}
 }
 
 // traceFullDequeue dequeues from queue of full buffers.
@@ -687,6 +834,7 @@ func traceString(bufp *traceBufPtr, pid int32, s string) (uint64, *traceBufPtr)
 	trace.stringSeq++
 	id := trace.stringSeq
 	trace.strings[s] = id
+	trace.stringsSize += uintptr(len(s))
 
 	if raceenabled {
 		racerelease(unsafe.Pointer(&trace.stringsLock))
@@ -880,6 +1028,28 @@ func (tab *traceStackTable) dump() {
 	traceFullQueue(bufp)
 	unlock(&trace.lock)
 
+	tab.reset()
+}
+
+// rotate moves all cached stacks to old and empties tab, without
+// releasing any memory. Stack ids keep increasing. Stacks moved to old
+// may still be looked up by concurrent calls to put that started
+// before rotate.
+func (tab *traceStackTable) rotate(old *traceStackTable) {
+	lockInit(&old.lock, lockRankTraceStackTab)
+	lock(&tab.lock)
+	old.seq = tab.seq
+	old.mem = tab.mem
+	tab.mem = traceAlloc{}
+	for i := range tab.tab {
+		old.tab[i] = tab.tab[i]
+		atomic.Storeuintptr((*uintptr)(unsafe.Pointer(&tab.tab[i])), 0)
+	}
+	unlock(&tab.lock)
+}
+
+// reset releases all memory and resets state.
+func (tab *traceStackTable) reset() {
 	tab.mem.drop()
 	*tab = traceStackTable{}
 	lockInit(&((*tab).lock), lockRankTraceStackTab)
@@ -1229,3 +1399,67 @@ func trace_userLog(id uint64, category, message string) {
 
 	traceReleaseBuffer(pid)
 }
+
+//go:linkname trace_userLogInt runtime/trace.userLogInt
+func trace_userLogInt(id uint64, category string, value int64) {
+	if !trace.enabled {
+		return
+	}
+
+	mp, pid, bufp := traceAcquireBuffer()
+	if !trace.enabled && !mp.startingtrace {
+		traceReleaseBuffer(pid)
+		return
+	}
+
+	categoryID, bufp := traceString(bufp, pid, category)
+	traceEventLocked(0, mp, pid, bufp, traceEvUserLogInt, 3, id, categoryID, uint64(value))
+	traceReleaseBuffer(pid)
+}
+
+//go:linkname trace_userCounter runtime/trace.userCounter
+func trace_userCounter(name string, value int64) {
+	if !trace.enabled {
+		return
+	}
+
+	mp, pid, bufp := traceAcquireBuffer()
+	if !trace.enabled && !mp.startingtrace {
+		traceReleaseBuffer(pid)
+		return
+	}
+
+	nameStringID, bufp := traceString(bufp, pid, name)
+	traceEventLocked(0, mp, pid, bufp, traceEvUserCounter, -1, nameStringID, uint64(value))
+	traceReleaseBuffer(pid)
+}
+
+//go:linkname trace_userFlow runtime/trace.userFlow
+func trace_userFlow(id, mode uint64, name string) {
+	if !trace.enabled {
+		return
+	}
+
+	mp, pid, bufp := traceAcquireBuffer()
+	if !trace.enabled && !mp.startingtrace {
+		traceReleaseBuffer(pid)
+		return
+	}
+
+	nameStringID, bufp := traceString(bufp, pid, name)
+	traceEventLocked(0, mp, pid, bufp, traceEvUserFlow, 3, id, mode, nameStringID)
+	traceReleaseBuffer(pid)
+}
+
+// traceGoName records that gp is now named name.
+func traceGoName(gp *g, name string) {
+	mp, pid, bufp := traceAcquireBuffer()
+	if !trace.enabled && !mp.startingtrace {
+		traceReleaseBuffer(pid)
+		return
+	}
+
+	nameID, bufp := traceString(bufp, pid, name)
+	traceEventLocked(0, mp, pid, bufp, traceEvGoName, -1, uint64(gp.goid), nameID)
+	traceReleaseBuffer(pid)
+}
@@ -107,6 +107,64 @@ func Logf(ctx context.Context, category, format string, args ...interface{}) {
 	}
 }
 
+// LogInt is like Log, but the value is an integer. The execution
+// tracer keeps the value typed, so tools can plot or aggregate it.
+func LogInt(ctx context.Context, category string, value int64) {
+	id := fromContext(ctx).id
+	userLogInt(id, category, value)
+}
+
+// Counter records the current value of the named counter, such as a
+// queue length or the number of open connections. Counters are global
+// to the program rather than attached to a task, and the trace tool
+// plots each one over time alongside the runtime's own counters.
+//
+// The API assumes there are only a handful of unique counter names in
+// the system.
+func Counter(name string, value int64) {
+	userCounter(name, value)
+}
+
+const (
+	flowStartCode = uint64(0)
+	flowEndCode   = uint64(1)
+)
+
+var lastFlowID uint64 = 0 // flow id issued last time
+
+// StartFlow starts a flow on the calling goroutine and returns it.
+// A flow links a point in one goroutine, such as sending a request on
+// a channel, to a later point in another goroutine, such as receiving
+// it, and the trace tool draws an arrow between the two.
+//
+// Pass the returned Flow along with the data it describes and call its
+// End method where the data is consumed:
+//
+//     f := trace.StartFlow("request")
+//     ch <- req{f: f, ...}
+//     ...
+//     r := <-ch
+//     r.f.End()
+//
+// The flowType is used to classify flows, so there should be only a
+// handful of unique flow types.
+func StartFlow(flowType string) *Flow {
+	id := atomic.AddUint64(&lastFlowID, 1)
+	userFlow(id, flowStartCode, flowType)
+	return &Flow{id: id}
+}
+
+// Flow is a link between events on two goroutines.
+type Flow struct {
+	id uint64
+}
+
+// End marks the end of the flow on the calling goroutine.
+// Only the first call to End is used.
+func (f *Flow) End() {
+	userFlow(f.id, flowEndCode, "")
+}
+
 const (
 	regionStartCode = uint64(0)
 	regionEndCode   = uint64(1)
@@ -198,3 +256,12 @@ func userRegion(id, mode uint64, regionType string)
 
 // emits UserLog event.
 func userLog(id uint64, category, message string)
+
+// emits UserLogInt event.
+func userLogInt(id uint64, category string, value int64)
+
+// emits UserCounter event.
+func userCounter(name string, value int64)
+
+// emits UserFlow event.
+func userFlow(id, mode uint64, flowType string)
@@ -154,3 +154,86 @@ func TestUserTaskRegion(t *testing.T) {
 		t.Errorf("Got user region related events\n%+v\nwant:\n%+v", pretty(got), pretty(want))
 	}
 }
+
+func TestUserCounterFlow(t *testing.T) {
+	if IsEnabled() {
+		t.Skip("skipping because -test.trace is set")
+	}
+	buf := new(bytes.Buffer)
+	if err := Start(buf); err != nil {
+		t.Fatalf("failed to start tracing: %v", err)
+	}
+
+	ctx, task := NewTask(context.Background(), "task0")
+	LogInt(ctx, "size", -42) // EvUserLogInt("task0", "size", -42)
+	Counter("queue", 3)      // EvUserCounter("queue", 3)
+
+	ch := make(chan *Flow)
+	done := make(chan bool)
+	go func() {
+		f := <-ch
+		f.End()             // EvUserFlow("request", end)
+		f.End()             // ignored
+		Counter("queue", 2) // EvUserCounter("queue", 2)
+		done <- true
+	}()
+	ch <- StartFlow("request") // EvUserFlow("request", start)
+	<-done
+	task.End()
+
+	Stop()
+
+	saveTrace(t, buf, "TestUserCounterFlow")
+	res, err := trace.Parse(buf, "")
+	if err == trace.ErrTimeOrder {
+		// golang.org/issues/16755
+		t.Skipf("skipping trace: %v", err)
+	}
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	var logs, counters []string
+	var flowStart, flowEnd *trace.Event
+	for _, e := range res.Events {
+		switch e.Type {
+		case trace.EvUserLogInt:
+			logs = append(logs, fmt.Sprintf("%s=%s", e.SArgs[0], e.SArgs[1]))
+			if v := int64(e.Args[2]); v != -42 {
+				t.Errorf("LogInt value is %d, want -42", v)
+			}
+		case trace.EvUserCounter:
+			counters = append(counters, fmt.Sprintf("%s=%d", e.SArgs[0], int64(e.Args[1])))
+		case trace.EvUserFlow:
+			switch e.Args[1] {
+			case 0:
+				if flowStart != nil {
+					t.Errorf("more than one flow start: %v", e)
+				}
+				flowStart = e
+			case 1:
+				if flowEnd == nil {
+					flowEnd = e
+				}
+			}
+		}
+	}
+	if want := []string{"size=-42"}; !reflect.DeepEqual(logs, want) {
+		t.Errorf("got logs %v, want %v", logs, want)
+	}
+	if want := []string{"queue=3", "queue=2"}; !reflect.DeepEqual(counters, want) {
+		t.Errorf("got counters %v, want %v", counters, want)
+	}
+	if flowStart == nil || flowEnd == nil {
+		t.Fatalf("flow events missing: start %v, end %v", flowStart, flowEnd)
+	}
+	if flowStart.Link != flowEnd {
+		t.Errorf("flow start is linked to %v, want %v", flowStart.Link, flowEnd)
+	}
+	if flowStart.G == flowEnd.G {
+		t.Errorf("flow starts and ends on goroutine %d, want different goroutines", flowStart.G)
+	}
+	if flowStart.SArgs[0] != "request" || flowEnd.SArgs[0] != "request" {
+		t.Errorf("flow events have names %q and %q, want request", flowStart.SArgs[0], flowEnd.SArgs[0])
+	}
+}
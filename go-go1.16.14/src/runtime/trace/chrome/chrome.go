@@ -0,0 +1,169 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package chrome converts execution traces written by the runtime/trace
+// package to the Chrome trace event format, which chrome://tracing,
+// Perfetto and other standard trace viewers can display.
+//
+// The converted trace has two processes. "Goroutines" has a thread for
+// each goroutine, showing when it ran and why it stopped running.
+// "Procs" has a thread for each P, showing the goroutines it ran, and
+// a thread for the garbage collector, showing its cycles.
+package chrome
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"internal/trace"
+	"io"
+	rtrace "runtime/trace"
+	"time"
+)
+
+// Convert reads an execution trace written by the runtime/trace
+// package from r and writes it to w in the Chrome trace event format.
+func Convert(w io.Writer, r io.Reader) error {
+	res, err := trace.Parse(r, "")
+	if err != nil {
+		return err
+	}
+	return write(w, res.Events)
+}
+
+// WriteFlightRecorder is like runtime/trace.WriteFlightRecorder, but
+// writes the snapshot in the Chrome trace event format.
+func WriteFlightRecorder(w io.Writer, d time.Duration) error {
+	var buf bytes.Buffer
+	if err := rtrace.WriteFlightRecorder(&buf, d); err != nil {
+		return err
+	}
+	return Convert(w, &buf)
+}
+
+// Process IDs of the converted trace.
+const (
+	goroutinesPid = 1
+	procsPid      = 2
+)
+
+// gcTid is the thread ID of the garbage collector in the Procs process.
+const gcTid = trace.GCP
+
+// event is a Chrome trace event. Timestamps and durations are in
+// microseconds.
+type event struct {
+	Name  string                 `json:"name"`
+	Phase string                 `json:"ph"`
+	Ts    float64                `json:"ts"`
+	Dur   float64                `json:"dur,omitempty"`
+	Pid   uint64                 `json:"pid"`
+	Tid   uint64                 `json:"tid"`
+	Args  map[string]interface{} `json:"args,omitempty"`
+}
+
+// stopReasons names the events that stop a goroutine running.
+var stopReasons = map[byte]string{
+	trace.EvGoEnd:         "end",
+	trace.EvGoStop:        "stop",
+	trace.EvGoSched:       "Gosched",
+	trace.EvGoPreempt:     "preempted",
+	trace.EvGoSleep:       "sleep",
+	trace.EvGoBlock:       "block",
+	trace.EvGoBlockSend:   "chan send",
+	trace.EvGoBlockRecv:   "chan receive",
+	trace.EvGoBlockSelect: "select",
+	trace.EvGoBlockSync:   "sync",
+	trace.EvGoBlockCond:   "sync.Cond",
+	trace.EvGoBlockNet:    "network",
+	trace.EvGoSysBlock:    "syscall",
+	trace.EvGoBlockGC:     "GC assist",
+}
+
+// write writes the parsed events to w in the Chrome trace event format.
+func write(w io.Writer, events []*trace.Event) error {
+	gs := trace.GoroutineStats(events)
+	gname := func(goid uint64) string {
+		name := fmt.Sprintf("G%d", goid)
+		if g := gs[goid]; g != nil {
+			if g.UserName != "" {
+				name += " " + g.UserName
+			} else if g.Name != "" {
+				name += " " + g.Name
+			}
+		}
+		return name
+	}
+	slice := func(name string, start, end int64, pid, tid uint64, args map[string]interface{}) event {
+		return event{
+			Name:  name,
+			Phase: "X",
+			Ts:    float64(start) / 1e3,
+			Dur:   float64(end-start) / 1e3,
+			Pid:   pid,
+			Tid:   tid,
+			Args:  args,
+		}
+	}
+	meta := func(kind string, pid, tid uint64, name string) event {
+		return event{
+			Name:  kind,
+			Phase: "M",
+			Pid:   pid,
+			Tid:   tid,
+			Args:  map[string]interface{}{"name": name},
+		}
+	}
+
+	out := []event{
+		meta("process_name", goroutinesPid, 0, "Goroutines"),
+		meta("process_name", procsPid, 0, "Procs"),
+	}
+	// A goroutine or GC cycle still running when the trace ends ends
+	// with it.
+	lastTs := events[len(events)-1].Ts
+	seenG := make(map[uint64]bool)
+	seenP := make(map[int]bool)
+	for _, ev := range events {
+		switch ev.Type {
+		case trace.EvGoStart, trace.EvGoStartLabel:
+			end, reason := lastTs, "running"
+			if ev.Link != nil {
+				end = ev.Link.Ts
+				reason = stopReasons[ev.Link.Type]
+				if reason == "" {
+					reason = trace.EventDescriptions[ev.Link.Type].Name
+				}
+			}
+			name := gname(ev.G)
+			args := map[string]interface{}{"goroutine": ev.G, "stop": reason}
+			if ev.Type == trace.EvGoStartLabel {
+				args["label"] = ev.SArgs[0]
+			}
+			out = append(out,
+				slice(name, ev.Ts, end, goroutinesPid, ev.G, args),
+				slice(name, ev.Ts, end, procsPid, uint64(ev.P), args))
+			if !seenG[ev.G] {
+				seenG[ev.G] = true
+				out = append(out, meta("thread_name", goroutinesPid, ev.G, name))
+			}
+			if !seenP[ev.P] {
+				seenP[ev.P] = true
+				out = append(out, meta("thread_name", procsPid, uint64(ev.P), fmt.Sprintf("Proc %d", ev.P)))
+			}
+		case trace.EvGCStart:
+			end := lastTs
+			if ev.Link != nil {
+				end = ev.Link.Ts
+			}
+			out = append(out, slice("GC", ev.Ts, end, procsPid, gcTid, nil))
+		}
+	}
+	out = append(out, meta("thread_name", procsPid, gcTid, "GC"))
+
+	return json.NewEncoder(w).Encode(struct {
+		TraceEvents     []event `json:"traceEvents"`
+		DisplayTimeUnit string  `json:"displayTimeUnit"`
+	}{out, "ns"})
+}
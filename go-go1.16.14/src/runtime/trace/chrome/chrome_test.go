@@ -0,0 +1,134 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package chrome_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"runtime"
+	rtrace "runtime/trace"
+	. "runtime/trace/chrome"
+	"strings"
+	"testing"
+)
+
+type chromeTrace struct {
+	TraceEvents []struct {
+		Name  string
+		Phase string `json:"ph"`
+		Ts    float64
+		Dur   float64
+		Pid   uint64
+		Tid   uint64
+		Args  map[string]interface{}
+	}
+}
+
+// pingPong runs a goroutine named "pinger" that exchanges values with
+// the calling goroutine over an unbuffered channel.
+func pingPong() {
+	c := make(chan int)
+	done := make(chan bool)
+	go func() {
+		runtime.SetGoroutineName("pinger")
+		for i := 0; i < 100; i++ {
+			c <- i
+			<-c
+		}
+		done <- true
+	}()
+	for i := 0; i < 100; i++ {
+		c <- <-c
+	}
+	<-done
+}
+
+// checkPinger checks that the converted trace in data shows the
+// pinger goroutine blocking on channels, on its own thread and on Ps.
+func checkPinger(t *testing.T, data []byte) {
+	var tr chromeTrace
+	if err := json.Unmarshal(data, &tr); err != nil {
+		t.Fatalf("failed to decode converted trace: %v", err)
+	}
+	var named, onG, onP bool
+	for _, ev := range tr.TraceEvents {
+		if !strings.HasSuffix(ev.Name, " pinger") {
+			if ev.Phase == "M" && ev.Name == "thread_name" && strings.HasSuffix(ev.Args["name"].(string), " pinger") {
+				named = true
+			}
+			continue
+		}
+		if ev.Phase != "X" {
+			t.Errorf("pinger event has phase %q, want X", ev.Phase)
+			continue
+		}
+		if ev.Dur < 0 {
+			t.Errorf("pinger event has negative duration %v", ev.Dur)
+		}
+		if stop := ev.Args["stop"]; stop != "chan send" && stop != "chan receive" && stop != "end" {
+			continue
+		}
+		switch ev.Pid {
+		case 1:
+			onG = true
+		case 2:
+			onP = true
+		}
+	}
+	if !named || !onG || !onP {
+		t.Errorf("pinger named %v, shown blocking on its goroutine %v and on a P %v; want all", named, onG, onP)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	if rtrace.IsEnabled() {
+		t.Skip("skipping because -test.trace is set")
+	}
+	var buf bytes.Buffer
+	if err := rtrace.Start(&buf); err != nil {
+		t.Fatalf("failed to start tracing: %v", err)
+	}
+	pingPong()
+	runtime.GC()
+	rtrace.Stop()
+
+	var out bytes.Buffer
+	if err := Convert(&out, &buf); err != nil {
+		t.Fatalf("failed to convert trace: %v", err)
+	}
+	checkPinger(t, out.Bytes())
+
+	var tr chromeTrace
+	json.Unmarshal(out.Bytes(), &tr)
+	gc := false
+	for _, ev := range tr.TraceEvents {
+		if ev.Name == "GC" && ev.Phase == "X" && ev.Pid == 2 {
+			gc = true
+		}
+	}
+	if !gc {
+		t.Errorf("converted trace has no GC cycle")
+	}
+}
+
+func TestWriteFlightRecorder(t *testing.T) {
+	if rtrace.IsEnabled() {
+		t.Skip("skipping because -test.trace is set")
+	}
+	var buf bytes.Buffer
+	if err := WriteFlightRecorder(&buf, 0); err == nil {
+		t.Fatalf("WriteFlightRecorder succeeded without a flight recorder")
+	}
+
+	if err := rtrace.StartFlightRecorder(1 << 20); err != nil {
+		t.Fatalf("failed to start flight recorder: %v", err)
+	}
+	defer rtrace.StopFlightRecorder()
+	pingPong()
+	if err := WriteFlightRecorder(&buf, 0); err != nil {
+		t.Fatalf("failed to write flight recorder: %v", err)
+	}
+	checkPinger(t, buf.Bytes())
+}
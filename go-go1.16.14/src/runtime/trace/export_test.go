@@ -0,0 +1,15 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+// FlushEpoch ends the current trace epoch without waiting for the
+// periodic flush.
+func FlushEpoch() {
+	tracing.Lock()
+	defer tracing.Unlock()
+	if tracing.epochDone != nil {
+		flushEpoch()
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+import (
+	"errors"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// StartFlightRecorder enables tracing for the current program in
+// flight recorder mode. Instead of being written out, the trace is
+// kept in memory, in a ring of roughly size bytes that always holds
+// the most recent events. Use WriteFlightRecorder to write out a
+// snapshot of it, for example after detecting a problem.
+//
+// StartFlightRecorder returns an error if tracing is already enabled.
+func StartFlightRecorder(size int) error {
+	tracing.Lock()
+	defer tracing.Unlock()
+
+	if err := startFlight(size); err != nil {
+		return err
+	}
+	tracing.flight = true
+	atomic.StoreInt32(&tracing.enabled, 1)
+	return nil
+}
+
+// WriteFlightRecorder writes the events of the last d retained by the
+// flight recorder to w, or all retained events if d is zero. Tracing
+// continues afterwards.
+//
+// The snapshot is a partial trace: goroutines and processors that were
+// running before it starts are only known from their later events.
+// It can be read with "go tool trace" like any other trace.
+func WriteFlightRecorder(w io.Writer, d time.Duration) error {
+	tracing.Lock()
+	defer tracing.Unlock()
+
+	data := flightSnapshot(int64(d))
+	if data == nil {
+		return errors.New("trace: flight recorder is not running")
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// StopFlightRecorder stops the flight recorder, if it is running, and
+// discards the retained events. It does not stop tracing started by
+// Start.
+func StopFlightRecorder() {
+	tracing.Lock()
+	defer tracing.Unlock()
+
+	if !tracing.flight {
+		return
+	}
+	stop()
+}
+
+func startFlight(size int) error
+
+func flightSnapshot(window int64) []byte
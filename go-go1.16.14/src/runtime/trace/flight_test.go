@@ -0,0 +1,154 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"internal/trace"
+	. "runtime/trace"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flightLogs returns the messages of the user log events in a flight
+// recorder snapshot.
+func flightLogs(t *testing.T, data []byte) map[string]bool {
+	events, _ := parseTrace(t, bytes.NewReader(data))
+	logs := make(map[string]bool)
+	for _, ev := range events {
+		if ev.Type == trace.EvUserLog {
+			logs[ev.SArgs[1]] = true
+		}
+	}
+	return logs
+}
+
+func TestFlightRecorder(t *testing.T) {
+	if IsEnabled() {
+		t.Skip("skipping because -test.trace is set")
+	}
+	if err := StartFlightRecorder(256 << 10); err != nil {
+		t.Fatalf("failed to start flight recorder: %v", err)
+	}
+	defer StopFlightRecorder()
+	if err := Start(new(bytes.Buffer)); err == nil {
+		Stop()
+		t.Fatalf("started tracing while the flight recorder is running")
+	}
+
+	ctx := context.Background()
+	Log(ctx, "flight", "first")
+
+	// Produce enough events to wrap the ring many times over.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c := make(chan int)
+			go func() {
+				for v := range c {
+					_ = v
+				}
+			}()
+			for j := 0; j < 100000; j++ {
+				c <- j
+			}
+			close(c)
+		}()
+	}
+	wg.Wait()
+	Log(ctx, "flight", "last")
+
+	buf := new(bytes.Buffer)
+	if err := WriteFlightRecorder(buf, 0); err != nil {
+		t.Fatalf("failed to write flight recorder: %v", err)
+	}
+	if buf.Len() > 1<<20 {
+		t.Errorf("snapshot is %d bytes, want roughly the ring size", buf.Len())
+	}
+	logs := flightLogs(t, buf.Bytes())
+	if logs["first"] {
+		t.Errorf("snapshot contains events that should have been recycled")
+	}
+	if !logs["last"] {
+		t.Errorf("snapshot is missing the most recent events")
+	}
+
+	// Snapshots restricted to a window only contain recent events.
+	Log(ctx, "flight", "old")
+	time.Sleep(200 * time.Millisecond)
+	Log(ctx, "flight", "new")
+	buf.Reset()
+	if err := WriteFlightRecorder(buf, 100*time.Millisecond); err != nil {
+		t.Fatalf("failed to write flight recorder: %v", err)
+	}
+	logs = flightLogs(t, buf.Bytes())
+	if logs["old"] || !logs["new"] {
+		t.Errorf("windowed snapshot has logs %v, want only new", logs)
+	}
+
+	StopFlightRecorder()
+	if err := WriteFlightRecorder(buf, 0); err == nil {
+		t.Errorf("wrote a snapshot after stopping the flight recorder")
+	}
+}
+
+func TestStopFlightRecorderDuringTrace(t *testing.T) {
+	if IsEnabled() {
+		t.Skip("skipping because -test.trace is set")
+	}
+	StopFlightRecorder() // no-op without any tracing
+	buf := new(bytes.Buffer)
+	if err := Start(buf); err != nil {
+		t.Fatalf("failed to start tracing: %v", err)
+	}
+	StopFlightRecorder()
+	if !IsEnabled() {
+		t.Fatalf("StopFlightRecorder stopped tracing started by Start")
+	}
+	Log(context.Background(), "flight", "after")
+	Stop()
+	if logs := flightLogs(t, buf.Bytes()); !logs["after"] {
+		t.Errorf("trace is missing the events after StopFlightRecorder")
+	}
+}
+
+func TestFlightRecorderTables(t *testing.T) {
+	if IsEnabled() {
+		t.Skip("skipping because -test.trace is set")
+	}
+	if err := StartFlightRecorder(256 << 10); err != nil {
+		t.Fatalf("failed to start flight recorder: %v", err)
+	}
+	defer StopFlightRecorder()
+
+	// Add far more strings than the ring holds, slowly enough for
+	// the tables to be switched to new generations along the way.
+	ctx := context.Background()
+	n := 0
+	for i := 0; i < 40; i++ {
+		for j := 0; j < 2000; j++ {
+			Log(ctx, fmt.Sprintf("flight-table-test-category-%d", n), "x")
+			n++
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	Log(ctx, "flight", "last")
+
+	buf := new(bytes.Buffer)
+	if err := WriteFlightRecorder(buf, 0); err != nil {
+		t.Fatalf("failed to write flight recorder: %v", err)
+	}
+	if buf.Len() > 3<<20/2 {
+		t.Errorf("snapshot is %d bytes, want the string table to be bounded", buf.Len())
+	}
+	if logs := flightLogs(t, buf.Bytes()); !logs["last"] {
+		t.Errorf("snapshot is missing the most recent events")
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package summary computes aggregate statistics of execution traces
+// written by the runtime/trace package, for programs that need how
+// goroutines and the garbage collector spent their time rather than
+// the individual events.
+package summary
+
+import (
+	"bytes"
+	"internal/trace"
+	"io"
+	rtrace "runtime/trace"
+	"sort"
+	"time"
+)
+
+// A Summary holds the aggregate statistics of an execution trace.
+type Summary struct {
+	// Duration is the time from the first to the last event of the
+	// trace.
+	Duration time.Duration
+
+	// Goroutines holds the statistics of every goroutine seen in
+	// the trace, sorted by ID.
+	Goroutines []Goroutine
+
+	// GC holds the statistics of the garbage collector.
+	GC GC
+}
+
+// A Goroutine holds the statistics of a goroutine over the part of the
+// trace it lived in.
+type Goroutine struct {
+	ID uint64
+
+	// Name is the name the goroutine set with runtime.SetGoroutineName,
+	// or else the function it started in, if known.
+	Name string
+
+	Running  time.Duration // time running on a P
+	Runnable time.Duration // time waiting for a P to run on
+	Blocked  time.Duration // time blocked on channels, select, or package sync
+	Network  time.Duration // time blocked on the network
+	Syscall  time.Duration // time blocked in system calls
+	Sweep    time.Duration // time sweeping for the garbage collector
+}
+
+// A GC holds the statistics of the garbage collector over the trace.
+type GC struct {
+	Cycles     int           // number of cycles started
+	Time       time.Duration // time a cycle was in progress
+	STW        time.Duration // time the world was stopped
+	MarkAssist time.Duration // time goroutines spent assisting marking
+	Sweep      time.Duration // time spent sweeping
+}
+
+// Read reads an execution trace written by the runtime/trace package
+// from r and summarizes it.
+func Read(r io.Reader) (*Summary, error) {
+	res, err := trace.Parse(r, "")
+	if err != nil {
+		return nil, err
+	}
+	return summarize(res.Events), nil
+}
+
+// ReadFlightRecorder summarizes the events of the last d retained by
+// the flight recorder, or all retained events if d is zero. See
+// runtime/trace.WriteFlightRecorder.
+func ReadFlightRecorder(d time.Duration) (*Summary, error) {
+	var buf bytes.Buffer
+	if err := rtrace.WriteFlightRecorder(&buf, d); err != nil {
+		return nil, err
+	}
+	return Read(&buf)
+}
+
+// summarize computes the summary of the parsed events.
+func summarize(events []*trace.Event) *Summary {
+	s := new(Summary)
+	first, last := events[0].Ts, events[len(events)-1].Ts
+	s.Duration = time.Duration(last - first)
+
+	for _, g := range trace.GoroutineStats(events) {
+		name := g.UserName
+		if name == "" {
+			name = g.Name
+		}
+		s.Goroutines = append(s.Goroutines, Goroutine{
+			ID:       g.ID,
+			Name:     name,
+			Running:  time.Duration(g.ExecTime),
+			Runnable: time.Duration(g.SchedWaitTime),
+			Blocked:  time.Duration(g.BlockTime),
+			Network:  time.Duration(g.IOTime),
+			Syscall:  time.Duration(g.SyscallTime),
+			Sweep:    time.Duration(g.SweepTime),
+		})
+	}
+	sort.Slice(s.Goroutines, func(i, j int) bool {
+		return s.Goroutines[i].ID < s.Goroutines[j].ID
+	})
+
+	// span returns the duration of an event that has its end in
+	// Link, which is missing if the trace ended first.
+	span := func(ev *trace.Event) time.Duration {
+		if ev.Link == nil {
+			return time.Duration(last - ev.Ts)
+		}
+		return time.Duration(ev.Link.Ts - ev.Ts)
+	}
+	for _, ev := range events {
+		switch ev.Type {
+		case trace.EvGCStart:
+			s.GC.Cycles++
+			s.GC.Time += span(ev)
+		case trace.EvGCSTWStart:
+			s.GC.STW += span(ev)
+		case trace.EvGCMarkAssistStart:
+			s.GC.MarkAssist += span(ev)
+		case trace.EvGCSweepStart:
+			s.GC.Sweep += span(ev)
+		}
+	}
+	return s
+}
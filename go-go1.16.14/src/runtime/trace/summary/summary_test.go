@@ -0,0 +1,99 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package summary_test
+
+import (
+	"bytes"
+	"runtime"
+	rtrace "runtime/trace"
+	. "runtime/trace/summary"
+	"testing"
+)
+
+// pingPong runs a goroutine named "pinger" that exchanges values with
+// the calling goroutine over an unbuffered channel.
+func pingPong() {
+	c := make(chan int)
+	done := make(chan bool)
+	go func() {
+		runtime.SetGoroutineName("pinger")
+		for i := 0; i < 100; i++ {
+			c <- i
+			<-c
+		}
+		done <- true
+	}()
+	for i := 0; i < 100; i++ {
+		c <- <-c
+	}
+	<-done
+}
+
+// checkPinger checks that s shows the pinger goroutine running and
+// blocked on channels.
+func checkPinger(t *testing.T, s *Summary) {
+	if s.Duration <= 0 {
+		t.Errorf("trace lasted %v, want more than 0", s.Duration)
+	}
+	for i, g := range s.Goroutines {
+		if i > 0 && g.ID <= s.Goroutines[i-1].ID {
+			t.Errorf("goroutine %d follows goroutine %d", g.ID, s.Goroutines[i-1].ID)
+		}
+		if g.Name != "pinger" {
+			continue
+		}
+		if g.Running <= 0 || g.Blocked <= 0 {
+			t.Errorf("pinger ran for %v and was blocked for %v, want both more than 0", g.Running, g.Blocked)
+		}
+		total := g.Running + g.Runnable + g.Blocked + g.Network + g.Syscall
+		if total > s.Duration {
+			t.Errorf("pinger accounted for %v in a trace of %v", total, s.Duration)
+		}
+		return
+	}
+	t.Errorf("no pinger goroutine in summary")
+}
+
+func TestRead(t *testing.T) {
+	if rtrace.IsEnabled() {
+		t.Skip("skipping because -test.trace is set")
+	}
+	var buf bytes.Buffer
+	if err := rtrace.Start(&buf); err != nil {
+		t.Fatalf("failed to start tracing: %v", err)
+	}
+	pingPong()
+	runtime.GC()
+	rtrace.Stop()
+
+	s, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("failed to read trace: %v", err)
+	}
+	checkPinger(t, s)
+	if s.GC.Cycles < 1 || s.GC.Time <= 0 || s.GC.STW <= 0 {
+		t.Errorf("GC summary %+v, want at least a cycle with time stopped", s.GC)
+	}
+}
+
+func TestReadFlightRecorder(t *testing.T) {
+	if rtrace.IsEnabled() {
+		t.Skip("skipping because -test.trace is set")
+	}
+	if _, err := ReadFlightRecorder(0); err == nil {
+		t.Fatalf("ReadFlightRecorder succeeded without a flight recorder")
+	}
+
+	if err := rtrace.StartFlightRecorder(1 << 20); err != nil {
+		t.Fatalf("failed to start flight recorder: %v", err)
+	}
+	defer rtrace.StopFlightRecorder()
+	pingPong()
+	s, err := ReadFlightRecorder(0)
+	if err != nil {
+		t.Fatalf("failed to read flight recorder: %v", err)
+	}
+	checkPinger(t, s)
+}
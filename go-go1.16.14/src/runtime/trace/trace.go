@@ -105,6 +105,14 @@
 // The trace tool computes the latency of a task by measuring the
 // time between the task creation and the task end and provides
 // latency distributions for each task type found in the trace.
+//
+// Log messages carry a string value; LogInt records an integer value
+// instead. Counter records the value of a program-wide counter, which
+// the trace tool plots over time next to the heap and goroutine
+// counters, so application phases can be lined up with scheduler and
+// GC activity. StartFlow links a point in one goroutine to a later
+// point in another, such as the send and receive of a request, and
+// the trace tool draws an arrow between them.
 package trace
 
 import (
@@ -112,10 +120,14 @@ import (
 	"runtime"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 // Start enables tracing for the current program.
 // While tracing, the trace will be buffered and written to w.
+// Buffered events are written at least once per second, so w receives
+// the trace incrementally, and memory use does not grow with the
+// length of the trace.
 // Start returns an error if tracing is already enabled.
 func Start(w io.Writer) error {
 	tracing.Lock()
@@ -133,6 +145,24 @@ func Start(w io.Writer) error {
 			w.Write(data)
 		}
 	}()
+	done := make(chan struct{})
+	go func() {
+		t := time.NewTicker(epochPeriod)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				tracing.Lock()
+				if tracing.epochDone == done {
+					flushEpoch()
+				}
+				tracing.Unlock()
+			case <-done:
+				return
+			}
+		}
+	}()
+	tracing.epochDone = done
 	atomic.StoreInt32(&tracing.enabled, 1)
 	return nil
 }
@@ -142,12 +172,34 @@ func Start(w io.Writer) error {
 func Stop() {
 	tracing.Lock()
 	defer tracing.Unlock()
+	stop()
+}
+
+// stop implements Stop and StopFlightRecorder. tracing must be locked.
+func stop() {
 	atomic.StoreInt32(&tracing.enabled, 0)
+	if tracing.epochDone != nil {
+		close(tracing.epochDone)
+		tracing.epochDone = nil
+	}
+	tracing.flight = false
 
 	runtime.StopTrace()
 }
 
+// epochPeriod is how often buffered events are flushed to the
+// writer passed to Start.
+const epochPeriod = time.Second
+
 var tracing struct {
 	sync.Mutex       // gate mutators (Start, Stop)
 	enabled    int32 // accessed via atomic
+
+	epochDone chan struct{} // closed by Stop to end epoch flushes
+	flight    bool          // tracing was started by StartFlightRecorder
 }
+
+// flushEpoch hands all buffered events to the trace reader and releases
+// the stack and string tables. It must not run concurrently with
+// runtime.StopTrace.
+func flushEpoch()
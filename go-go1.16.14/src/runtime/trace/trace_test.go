@@ -137,6 +137,43 @@ func TestTrace(t *testing.T) {
 	}
 }
 
+func TestTraceGoroutineName(t *testing.T) {
+	if IsEnabled() {
+		t.Skip("skipping because -test.trace is set")
+	}
+	c := make(chan bool)
+	named := make(chan bool)
+	go func() {
+		runtime.SetGoroutineName("named-before")
+		named <- true
+		<-c
+	}()
+	<-named
+	buf := new(bytes.Buffer)
+	if err := Start(buf); err != nil {
+		t.Fatalf("failed to start tracing: %v", err)
+	}
+	go func() {
+		runtime.SetGoroutineName("named-during")
+		named <- true
+		<-c
+	}()
+	<-named
+	Stop()
+	close(c)
+	saveTrace(t, buf, "TestTraceGoroutineName")
+	_, gs := parseTrace(t, buf)
+	found := make(map[string]bool)
+	for _, g := range gs {
+		found[g.UserName] = true
+	}
+	for _, name := range []string{"named-before", "named-during"} {
+		if !found[name] {
+			t.Errorf("no goroutine named %q in trace", name)
+		}
+	}
+}
+
 func parseTrace(t *testing.T, r io.Reader) ([]*trace.Event, map[uint64]*trace.GDesc) {
 	res, err := trace.Parse(r, "")
 	if err == trace.ErrTimeOrder {
@@ -589,3 +626,67 @@ func saveTrace(t *testing.T, buf *bytes.Buffer, name string) {
 		t.Errorf("failed to write trace file: %s", err)
 	}
 }
+
+// syncBuffer is a bytes.Buffer that can be written to while its length
+// is being checked.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Len()
+}
+
+// TestTraceEpoch checks that epoch flushes deliver events before the
+// trace is stopped, and that stacks from all epochs are written out.
+func TestTraceEpoch(t *testing.T) {
+	if IsEnabled() {
+		t.Skip("skipping because -test.trace is set")
+	}
+	buf := new(syncBuffer)
+	if err := Start(buf); err != nil {
+		t.Fatalf("failed to start tracing: %v", err)
+	}
+	work := func() {
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				time.Sleep(time.Millisecond)
+			}()
+		}
+		wg.Wait()
+	}
+	work()
+	header := buf.Len()
+	FlushEpoch()
+	for start := time.Now(); buf.Len() == header; time.Sleep(time.Millisecond) {
+		if time.Since(start) > 10*time.Second {
+			Stop()
+			t.Fatalf("epoch flush did not deliver any events")
+		}
+	}
+	for i := 0; i < 3; i++ {
+		work()
+		FlushEpoch()
+	}
+	work()
+	Stop()
+
+	events, _ := parseTrace(t, bytes.NewReader(buf.buf.Bytes()))
+	for _, ev := range events {
+		if ev.StkID != 0 && ev.Stk == nil {
+			t.Fatalf("missing stack %v for %v", ev.StkID, ev)
+		}
+	}
+}
@@ -83,6 +83,12 @@ const sizeofSkipFunction = 256
 // PC in pcbuf can represent multiple calls). If a PC is partially skipped
 // and max > 1, pcbuf[1] will be runtime.skipPleaseUseCallersFrames+N where
 // N indicates the number of logical frames to skip in pcbuf[0].
+//
+// When printing, skip and max instead count the frames that would be
+// printed: only frames numbered [skip, max) are printed, and the result
+// is the number of printable frames walked, including the skipped ones.
+// This lets callers count frames without printing them by passing
+// skip == max.
 func gentraceback(pc0, sp0, lr0 uintptr, gp *g, skip int, pcbuf *uintptr, max int, callback func(*stkframe, unsafe.Pointer) bool, v unsafe.Pointer, flags uint) int {
 	if skip > 0 && callback != nil {
 		throw("gentraceback callback cannot be used with non-zero skip")
@@ -151,7 +157,7 @@ func gentraceback(pc0, sp0, lr0 uintptr, gp *g, skip int, pcbuf *uintptr, max in
 
 	f := findfunc(frame.pc)
 	if !f.valid() {
-		if callback != nil || printing {
+		if callback != nil || printing && skip == 0 {
 			print("runtime: unknown pc ", hex(frame.pc), "\n")
 			tracebackHexdump(gp.stack, &frame, 0)
 		}
@@ -166,7 +172,7 @@ func gentraceback(pc0, sp0, lr0 uintptr, gp *g, skip int, pcbuf *uintptr, max in
 
 	lastFuncID := funcID_normal
 	n := 0
-	for n < max {
+	for printing && nprint < max || !printing && n < max {
 		// Typically:
 		//	pc is the PC of the running function.
 		//	sp is the stack pointer at that program counter.
@@ -245,7 +251,7 @@ func gentraceback(pc0, sp0, lr0 uintptr, gp *g, skip int, pcbuf *uintptr, max in
 				// In that context it is okay to stop early.
 				// But if callback is set, we're doing a garbage collection and must
 				// get everything, so crash loudly.
-				doPrint := printing
+				doPrint := printing && nprint >= skip
 				if doPrint && gp.m.incgo && f.funcID == funcID_sigpanic {
 					// We can inject sigpanic
 					// calls directly into C code,
@@ -410,10 +416,12 @@ func gentraceback(pc0, sp0, lr0 uintptr, gp *g, skip int, pcbuf *uintptr, max in
 					inlFunc.funcID = inltree[ix].funcID
 
 					if (flags&_TraceRuntimeFrames) != 0 || showframe(inlFuncInfo, gp, nprint == 0, inlFuncInfo.funcID, lastFuncID) {
-						name := funcname(inlFuncInfo)
-						file, line := funcline(f, tracepc)
-						print(name, "(...)\n")
-						print("\t", file, ":", line, "\n")
+						if nprint >= skip && nprint < max {
+							name := funcname(inlFuncInfo)
+							file, line := funcline(f, tracepc)
+							print(name, "(...)\n")
+							print("\t", file, ":", line, "\n")
+						}
 						nprint++
 					}
 					lastFuncID = inltree[ix].funcID
@@ -422,36 +430,38 @@ func gentraceback(pc0, sp0, lr0 uintptr, gp *g, skip int, pcbuf *uintptr, max in
 				}
 			}
 			if (flags&_TraceRuntimeFrames) != 0 || showframe(f, gp, nprint == 0, f.funcID, lastFuncID) {
-				// Print during crash.
-				//	main(0x1, 0x2, 0x3)
-				//		/home/rsc/go/src/runtime/x.go:23 +0xf
-				//
-				name := funcname(f)
-				file, line := funcline(f, tracepc)
-				if name == "runtime.gopanic" {
-					name = "panic"
-				}
-				print(name, "(")
-				argp := (*[100]uintptr)(unsafe.Pointer(frame.argp))
-				for i := uintptr(0); i < frame.arglen/sys.PtrSize; i++ {
-					if i >= 10 {
-						print(", ...")
-						break
+				if nprint >= skip && nprint < max {
+					// Print during crash.
+					//	main(0x1, 0x2, 0x3)
+					//		/home/rsc/go/src/runtime/x.go:23 +0xf
+					//
+					name := funcname(f)
+					file, line := funcline(f, tracepc)
+					if name == "runtime.gopanic" {
+						name = "panic"
 					}
-					if i != 0 {
-						print(", ")
+					print(name, "(")
+					argp := (*[100]uintptr)(unsafe.Pointer(frame.argp))
+					for i := uintptr(0); i < frame.arglen/sys.PtrSize; i++ {
+						if i >= 10 {
+							print(", ...")
+							break
+						}
+						if i != 0 {
+							print(", ")
+						}
+						print(hex(argp[i]))
 					}
-					print(hex(argp[i]))
-				}
-				print(")\n")
-				print("\t", file, ":", line)
-				if frame.pc > f.entry {
-					print(" +", hex(frame.pc-f.entry))
-				}
-				if gp.m != nil && gp.m.throwing > 0 && gp == gp.m.curg || level >= 2 {
-					print(" fp=", hex(frame.fp), " sp=", hex(frame.sp), " pc=", hex(frame.pc))
+					print(")\n")
+					print("\t", file, ":", line)
+					if frame.pc > f.entry {
+						print(" +", hex(frame.pc-f.entry))
+					}
+					if gp.m != nil && gp.m.throwing > 0 && gp == gp.m.curg || level >= 2 {
+						print(" fp=", hex(frame.fp), " sp=", hex(frame.sp), " pc=", hex(frame.pc))
+					}
+					print("\n")
 				}
-				print("\n")
 				nprint++
 			}
 			lastFuncID = f.funcID
@@ -465,7 +475,7 @@ func gentraceback(pc0, sp0, lr0 uintptr, gp *g, skip int, pcbuf *uintptr, max in
 			// skip only applies to Go frames.
 			// callback != nil only used when we only care
 			// about Go frames.
-			if skip == 0 && callback == nil {
+			if printing && nprint >= skip && nprint < max || !printing && skip == 0 && callback == nil {
 				n = tracebackCgoContext(pcbuf, printing, ctxt, n, max)
 			}
 		}
@@ -630,31 +640,52 @@ func getArgInfo(frame *stkframe, f funcInfo, needArgMap bool, ctxt *funcval) (ar
 // the context argument to setCgoTraceback, for the gentraceback
 // function. It returns the new value of n.
 func tracebackCgoContext(pcbuf *uintptr, printing bool, ctxt uintptr, n, max int) int {
+	set := cgoContextFuncs()
+	if set == nil {
+		return n
+	}
 	var cgoPCs [32]uintptr
-	cgoContextPCs(ctxt, cgoPCs[:])
+	targ := cgoTracebackArg{
+		context: ctxt,
+		buf:     (*uintptr)(noescape(unsafe.Pointer(&cgoPCs[0]))),
+		max:     uintptr(len(cgoPCs)),
+	}
 	var arg cgoSymbolizerArg
 	anySymbolized := false
-	for _, pc := range cgoPCs {
-		if pc == 0 || n >= max {
-			break
-		}
-		if pcbuf != nil {
-			(*[1 << 20]uintptr)(unsafe.Pointer(pcbuf))[n] = pc
-		}
-		if printing {
-			if cgoSymbolizer == nil {
-				print("non-Go function at pc=", hex(pc), "\n")
-			} else {
-				c := printOneCgoTraceback(pc, max-n, &arg)
-				n += c - 1 // +1 a few lines down
-				anySymbolized = true
+	for {
+		more := cgoContextPCs(set, &targ, cgoPCs[:])
+		for _, pc := range cgoPCs {
+			if pc == 0 || n >= max {
+				break
 			}
+			if pcbuf != nil {
+				(*[1 << 20]uintptr)(unsafe.Pointer(pcbuf))[n] = pc
+			}
+			if printing {
+				if set.symbolizer == nil {
+					print("non-Go function at pc=", hex(pc), "\n")
+				} else {
+					c := printOneCgoTraceback(set.symbolizer, pc, max-n, &arg)
+					n += c - 1 // +1 a few lines down
+					anySymbolized = true
+				}
+			}
+			n++
 		}
-		n++
+		if !more || n >= max {
+			break
+		}
+		targ.skip += uintptr(len(cgoPCs))
+	}
+	if targ.data != 0 {
+		// Let a version 1 traceback function release its data.
+		targ.buf = nil
+		targ.max = 0
+		callCgoTraceback(set.traceback, &targ)
 	}
 	if anySymbolized {
 		arg.pc = 0
-		callCgoSymbolizer(&arg)
+		callCgoSymbolizer(set.symbolizer, &arg)
 	}
 	return n
 }
@@ -665,6 +696,8 @@ func printcreatedby(gp *g) {
 	f := findfunc(pc)
 	if f.valid() && showframe(f, gp, false, funcID_normal, funcID_normal) && gp.goid != 1 {
 		printcreatedby1(f, pc)
+	} else if mp := gp.lockedm.ptr(); mp != nil && mp.curg == gp && mp.cthreadName != "" {
+		print("running on C thread ", mp.cthreadName, "\n")
 	}
 }
 
@@ -729,12 +762,9 @@ func traceback1(pc, sp, lr uintptr, gp *g, flags uint) {
 	}
 	// Print traceback. By default, omits runtime frames.
 	// If that means we print nothing at all, repeat forcing all frames printed.
-	n = gentraceback(pc, sp, lr, gp, 0, nil, _TracebackMaxFrames, nil, nil, flags)
+	n = traceback2(pc, sp, lr, gp, flags)
 	if n == 0 && (flags&_TraceRuntimeFrames) == 0 {
-		n = gentraceback(pc, sp, lr, gp, 0, nil, _TracebackMaxFrames, nil, nil, flags|_TraceRuntimeFrames)
-	}
-	if n == _TracebackMaxFrames {
-		print("...additional frames elided...\n")
+		n = traceback2(pc, sp, lr, gp, flags|_TraceRuntimeFrames)
 	}
 	printcreatedby(gp)
 
@@ -746,6 +776,55 @@ func traceback1(pc, sp, lr uintptr, gp *g, flags uint) {
 	}
 }
 
+// tracebackFrames limits the number of frames printed for each
+// goroutine. It holds a head count in the upper 32 bits and a tail count
+// in the lower 32 bits, so that both are read and written together
+// atomically. If a stack is deeper than their sum, the innermost head
+// and the outermost tail frames are printed and the frames in between
+// are elided. It is set by runtime/debug.SetTracebackFrames.
+var tracebackFrames uint64 = _TracebackMaxFrames << 32
+
+// tracebackFrameLimits returns the head and tail counts in tracebackFrames.
+func tracebackFrameLimits() (head, tail int) {
+	f := atomic.Load64(&tracebackFrames)
+	return int(int32(f >> 32)), int(int32(f))
+}
+
+// traceback2 prints the frames of gp starting at pc, sp, lr, eliding the
+// middle of the stack if it is deeper than the configured limits.
+// It returns the number of frames printed.
+func traceback2(pc, sp, lr uintptr, gp *g, flags uint) int {
+	const all = 0x7fffffff
+	head, tail := tracebackFrameLimits()
+	n := gentraceback(pc, sp, lr, gp, 0, nil, head, nil, nil, flags)
+	if n < head {
+		return n
+	}
+
+	// Count the frames without printing them. If there is no tail
+	// to print we only need to know whether there are any more.
+	limit := head + 1
+	if tail > 0 {
+		limit = all
+	}
+	total := gentraceback(pc, sp, lr, gp, limit, nil, limit, nil, nil, flags)
+	if total <= head {
+		return n
+	}
+	if tail == 0 {
+		print("...additional frames elided...\n")
+		return n
+	}
+	skip := total - tail
+	if skip < head {
+		skip = head
+	}
+	if skip > head {
+		print("...", skip-head, " frames elided...\n")
+	}
+	return n + gentraceback(pc, sp, lr, gp, skip, nil, all, nil, nil, flags) - skip
+}
+
 // printAncestorTraceback prints the traceback of the given ancestor.
 // TODO: Unify this with gentraceback and CallersFrames.
 func printAncestorTraceback(ancestor ancestorInfo) {
@@ -910,6 +989,9 @@ func goroutineheader(gp *g) {
 	if gp.lockedm != 0 {
 		print(", locked to thread")
 	}
+	if name := gp.name; name != nil {
+		print(", \"", *name, "\"")
+	}
 	print("]:\n")
 }
 
@@ -935,7 +1017,7 @@ func tracebackothers(me *g) {
 	for i := uintptr(0); i < length; i++ {
 		gp := atomicAllGIndex(ptr, i)
 
-		if gp == me || gp == curgp || readgstatus(gp) == _Gdead || isSystemGoroutine(gp, false) && level < 2 {
+		if gp == nil || gp == me || gp == curgp || readgstatus(gp) == _Gdead || isSystemGoroutine(gp, false) && level < 2 {
 			continue
 		}
 		print("\n")
@@ -1098,6 +1180,8 @@ func isSystemGoroutine(gp *g, fixed bool) bool {
 //		SigContext uintptr
 //		Buf        *uintptr
 //		Max        uintptr
+//		Skip       uintptr // version 1 only
+//		Data       uintptr // version 1 only
 //	}
 //
 // In C syntax, this struct will be
@@ -1107,6 +1191,8 @@ func isSystemGoroutine(gp *g, fixed bool) bool {
 //		uintptr_t  SigContext;
 //		uintptr_t* Buf;
 //		uintptr_t  Max;
+//		uintptr_t  Skip;
+//		uintptr_t  Data;
 //	};
 //
 // The Context field will be zero to gather a traceback from the
@@ -1143,6 +1229,18 @@ func isSystemGoroutine(gp *g, fixed bool) bool {
 // to the symbolizer function, return the file/line of the call
 // instruction.  No additional subtraction is required or appropriate.
 //
+// With version 1, a traceback can be longer than Max. When the function
+// fills all Max entries of Buf, it may be called again for the same
+// Context to store the next PC values, with Skip set to the number of
+// values it has already stored; Skip is zero on the first call. The Data
+// field is zero on the first call and keeps the value the function left
+// in it from one call to the next, so that the function can keep the
+// state of its unwinding there instead of starting over. When the
+// runtime has all the values it wants and Data is not zero, the function
+// is called once more with Buf set to nil and Max set to zero, so that it
+// can release whatever Data refers to. Neither field is used with version
+// 0, nor when the traceback function is called from a signal handler.
+//
 // On all platforms, the traceback function is invoked when a call from
 // Go to C to Go requests a stack trace. On linux/amd64, linux/ppc64le,
 // and freebsd/amd64, the traceback function is also invoked when a
@@ -1196,7 +1294,8 @@ func isSystemGoroutine(gp *g, fixed bool) bool {
 //
 // When calling SetCgoTraceback, the version argument is the version
 // number of the structs that the functions expect to receive.
-// Currently this must be zero.
+// This must be 0 or 1. Version 1 adds the Skip and Data fields of the
+// traceback function's struct.
 //
 // The symbolizer function may be nil, in which case the results of
 // the traceback function will be displayed as numbers. If the
@@ -1206,39 +1305,126 @@ func isSystemGoroutine(gp *g, fixed bool) bool {
 // to zero.  If the context function is nil, then calls from Go to C
 // to Go will not show a traceback for the C portion of the call stack.
 //
-// SetCgoTraceback should be called only once, ideally from an init function.
+// With version 0, SetCgoTraceback should be called only once, ideally
+// from an init function. With version 1, it may be called several
+// times, for instance by different libraries, to register up to four
+// sets of functions. Calling it again with the same functions has no
+// effect. Only one of the sets may have a context function; contexts
+// are passed to the traceback and symbolizer functions of that set.
+// The traceback function of the first set is the one called from
+// signal handlers. The symbolizer functions are tried in the order
+// they were registered, until one of them returns a file or function
+// name for the PC, when symbolizing PC values returned by
+// runtime.Callers.
 func SetCgoTraceback(version int, traceback, context, symbolizer unsafe.Pointer) {
-	if version != 0 {
+	if version != 0 && version != 1 {
 		panic("unsupported version")
 	}
 
-	if cgoTraceback != nil && cgoTraceback != traceback ||
-		cgoContext != nil && cgoContext != context ||
-		cgoSymbolizer != nil && cgoSymbolizer != symbolizer {
-		panic("call SetCgoTraceback only once")
+	lock(&cgoTracebackLock)
+	added, err := addCgoTracebackSet(cgoTracebackFuncs{version, traceback, context, symbolizer})
+	unlock(&cgoTracebackLock)
+	if err != "" {
+		panic(err)
+	}
+	if !added {
+		return
 	}
-
-	cgoTraceback = traceback
-	cgoContext = context
-	cgoSymbolizer = symbolizer
 
 	// The context function is called when a C function calls a Go
 	// function. As such it is only called by C code in runtime/cgo.
-	if _cgo_set_context_function != nil {
+	if context != nil && _cgo_set_context_function != nil {
 		cgocall(_cgo_set_context_function, context)
 	}
 }
 
+// addCgoTracebackSet registers set for SetCgoTraceback. It reports
+// whether set was added, and if it cannot be, why. cgoTracebackLock
+// must be held.
+func addCgoTracebackSet(set cgoTracebackFuncs) (added bool, err string) {
+	n := ncgoTracebackSets
+	for i := uint32(0); i < n; i++ {
+		old := &cgoTracebackSets[i]
+		if *old == set {
+			return false, ""
+		}
+		if set.version == 0 && old.version == 0 {
+			return false, "call SetCgoTraceback only once"
+		}
+	}
+	if set.context != nil && cgoContext != nil {
+		return false, "SetCgoTraceback: only one context function may be registered"
+	}
+	if n == uint32(len(cgoTracebackSets)) {
+		return false, "SetCgoTraceback: too many registrations"
+	}
+
+	// Signal handlers and tracebacks read the sets without locking,
+	// so publish the new one by storing the count after it.
+	cgoTracebackSets[n] = set
+	if n == 0 {
+		cgoTraceback = set.traceback
+		cgoSymbolizer = set.symbolizer
+	}
+	if set.symbolizer != nil {
+		cgoHaveSymbolizer = true
+	}
+	if set.context != nil {
+		cgoContext = set.context
+	}
+	atomic.Store(&ncgoTracebackSets, n+1)
+	return true, ""
+}
+
+// cgoContextFuncs returns the registered set that has a context
+// function, or nil if there is none.
+func cgoContextFuncs() *cgoTracebackFuncs {
+	n := atomic.Load(&ncgoTracebackSets)
+	for i := uint32(0); i < n; i++ {
+		if set := &cgoTracebackSets[i]; set.context != nil {
+			return set
+		}
+	}
+	return nil
+}
+
+// cgoTraceback and cgoSymbolizer are the functions of the first set
+// registered with SetCgoTraceback; the signal handlers call
+// cgoTraceback. cgoContext is the only registered context function.
 var cgoTraceback unsafe.Pointer
 var cgoContext unsafe.Pointer
 var cgoSymbolizer unsafe.Pointer
 
+// cgoTracebackFuncs is a set of functions registered with SetCgoTraceback.
+type cgoTracebackFuncs struct {
+	version    int
+	traceback  unsafe.Pointer
+	context    unsafe.Pointer
+	symbolizer unsafe.Pointer
+}
+
+// cgoTracebackSets holds the sets registered with SetCgoTraceback, in
+// the order they were registered; the first ncgoTracebackSets are used.
+// Registered entries never change, so readers only need to load
+// ncgoTracebackSets atomically. cgoTracebackLock serializes writers.
+// cgoHaveSymbolizer reports whether any of them has a symbolizer.
+var (
+	cgoTracebackLock  mutex
+	cgoTracebackSets  [4]cgoTracebackFuncs
+	ncgoTracebackSets uint32
+	cgoHaveSymbolizer bool
+)
+
 // cgoTracebackArg is the type passed to cgoTraceback.
+// The skip and data fields are only used with version 1.
+// This must match struct cgoTracebackArg in runtime/cgo/libcgo.h.
 type cgoTracebackArg struct {
 	context    uintptr
 	sigContext uintptr
 	buf        *uintptr
 	max        uintptr
+	skip       uintptr
+	data       uintptr
 }
 
 // cgoContextArg is the type passed to the context function.
@@ -1274,20 +1460,20 @@ func printCgoTraceback(callers *cgoCallers) {
 		if c == 0 {
 			break
 		}
-		printOneCgoTraceback(c, 0x7fffffff, &arg)
+		printOneCgoTraceback(cgoSymbolizer, c, 0x7fffffff, &arg)
 	}
 	arg.pc = 0
-	callCgoSymbolizer(&arg)
+	callCgoSymbolizer(cgoSymbolizer, &arg)
 }
 
 // printOneCgoTraceback prints the traceback of a single cgo caller.
 // This can print more than one line because of inlining.
 // Returns the number of frames printed.
-func printOneCgoTraceback(pc uintptr, max int, arg *cgoSymbolizerArg) int {
+func printOneCgoTraceback(symbolizer unsafe.Pointer, pc uintptr, max int, arg *cgoSymbolizerArg) int {
 	c := 0
 	arg.pc = pc
 	for c <= max {
-		callCgoSymbolizer(arg)
+		callCgoSymbolizer(symbolizer, arg)
 		if arg.funcName != nil {
 			// Note that we don't print any argument
 			// information here, not even parentheses.
@@ -1309,8 +1495,8 @@ func printOneCgoTraceback(pc uintptr, max int, arg *cgoSymbolizerArg) int {
 	return c
 }
 
-// callCgoSymbolizer calls the cgoSymbolizer function.
-func callCgoSymbolizer(arg *cgoSymbolizerArg) {
+// callCgoSymbolizer calls the symbolizer function.
+func callCgoSymbolizer(symbolizer unsafe.Pointer, arg *cgoSymbolizerArg) {
 	call := cgocall
 	if panicking > 0 || getg().m.curg != getg() {
 		// We do not want to call into the scheduler when panicking
@@ -1320,27 +1506,33 @@ func callCgoSymbolizer(arg *cgoSymbolizerArg) {
 	if msanenabled {
 		msanwrite(unsafe.Pointer(arg), unsafe.Sizeof(cgoSymbolizerArg{}))
 	}
-	call(cgoSymbolizer, noescape(unsafe.Pointer(arg)))
+	call(symbolizer, noescape(unsafe.Pointer(arg)))
 }
 
-// cgoContextPCs gets the PC values from a cgo traceback.
-func cgoContextPCs(ctxt uintptr, buf []uintptr) {
-	if cgoTraceback == nil {
-		return
+// cgoContextPCs gets the PC values from a cgo traceback of set into
+// buf, which is arg.buf. It reports whether the traceback function
+// may have more values to return with a larger arg.skip.
+func cgoContextPCs(set *cgoTracebackFuncs, arg *cgoTracebackArg, buf []uintptr) bool {
+	for i := range buf {
+		buf[i] = 0
+	}
+	if set.traceback == nil {
+		return false
 	}
+	callCgoTraceback(set.traceback, arg)
+	return set.version >= 1 && buf[len(buf)-1] != 0
+}
+
+// callCgoTraceback calls the traceback function.
+func callCgoTraceback(traceback unsafe.Pointer, arg *cgoTracebackArg) {
 	call := cgocall
 	if panicking > 0 || getg().m.curg != getg() {
 		// We do not want to call into the scheduler when panicking
 		// or when on the system stack.
 		call = asmcgocall
 	}
-	arg := cgoTracebackArg{
-		context: ctxt,
-		buf:     (*uintptr)(noescape(unsafe.Pointer(&buf[0]))),
-		max:     uintptr(len(buf)),
-	}
 	if msanenabled {
-		msanwrite(unsafe.Pointer(&arg), unsafe.Sizeof(arg))
+		msanwrite(unsafe.Pointer(arg), unsafe.Sizeof(cgoTracebackArg{}))
 	}
-	call(cgoTraceback, noescape(unsafe.Pointer(&arg)))
+	call(traceback, noescape(unsafe.Pointer(arg)))
 }
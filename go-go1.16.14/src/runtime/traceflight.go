@@ -0,0 +1,533 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Flight recorder mode for the execution tracer.
+//
+// In flight recorder mode the tracer runs as usual, but full trace
+// buffers are not handed to a reader. They stay on the full queue,
+// and once more than trace.flightBufs buffers are queued the oldest
+// ones are recycled (see traceFullQueue). trace.flightCut records the
+// newest timestamp that has been lost that way.
+//
+// A snapshot copies the retained buffers and drops every event that is
+// not newer than the cut, so that all Ps cover the same span of time.
+// The string and stack tables are appended to the snapshot in full.
+//
+// To keep the tables from growing for as long as the trace runs, they
+// are split into generations. Once the current tables hold more than
+// the ring, and the cut has passed the start of the current generation,
+// traceFlightRotate drops the previous generation and starts a new one
+// (see traceFlightRotator). Events only refer to entries of the
+// generation current when they were written, so the dropped entries
+// are only referred to by events that are not newer than the cut. Ids
+// keep increasing across generations.
+//
+// A snapshot is a partial trace: it lacks the goroutine and P states
+// recorded when tracing started. Its header says "flight" instead of
+// "trace" so that parsers can relax their consistency checks.
+
+package runtime
+
+import (
+	"runtime/internal/atomic"
+	"unsafe"
+)
+
+// traceFlightHeader is the header of a flight recorder snapshot. It
+// has the same length as the header written by ReadTrace.
+const traceFlightHeader = "go 1.11 flight\x00\x00"
+
+//go:linkname trace_startFlight runtime/trace.startFlight
+func trace_startFlight(size int) error {
+	n := size / int(unsafe.Sizeof(traceBuf{}))
+	if n < 2 {
+		n = 2
+	}
+	if err := startTrace(n); err != nil {
+		return err
+	}
+	go traceFlightRotator(trace.timeStart)
+	return nil
+}
+
+// traceFlightSema serializes snapshots and table generation switches.
+var traceFlightSema uint32 = 1
+
+// traceFlightRotatePeriod is how often, in nanoseconds, the flight
+// recorder checks whether to start a new table generation.
+const traceFlightRotatePeriod = 100 * 1000 * 1000
+
+// traceFlightRotator runs traceFlightRotate periodically for the
+// flight recorder that was started at start, until it stops.
+func traceFlightRotator(start int64) {
+	for {
+		timeSleep(traceFlightRotatePeriod)
+		if !traceFlightRotate(start) {
+			return
+		}
+	}
+}
+
+// traceFlightRotate starts a new generation of the string and stack
+// tables of the flight recorder started at start, if the current
+// generation holds more than the ring and the previous one is no
+// longer needed. It reports whether that flight recorder is still
+// running.
+func traceFlightRotate(start int64) bool {
+	semacquire(&traceFlightSema)
+	lock(&trace.lock)
+	running := trace.enabled && trace.flightBufs > 0 && trace.timeStart == start
+	limit := uintptr(trace.flightBufs) * unsafe.Sizeof(traceBuf{})
+	done := trace.flightStacks == nil || trace.flightCut > trace.flightSwitch
+	unlock(&trace.lock)
+	if !running || !done || traceFlightSize() <= limit {
+		semrelease(&traceFlightSema)
+		return running
+	}
+
+	// Allocate before stopping the world. The GC worker labels were
+	// looked up when tracing started, so carry them over.
+	stacks := new(traceStackTable)
+	strings := make(map[string]uint64)
+	for i, label := range gcMarkWorkerModeStrings[:] {
+		strings[label] = trace.markWorkerLabels[i]
+	}
+
+	// Stop everything that could emit events, like trace_flightSnapshot,
+	// so that no event refers to the new generation before the switch.
+	stopTheWorldGC("trace flight rotate")
+	lock(&sched.sysmonlock)
+	lock(&trace.bufLock)
+
+	var dropped *traceStackTable
+	if trace.enabled && trace.flightBufs > 0 && trace.timeStart == start {
+		dropped = trace.flightStacks
+		tab := &trace.stackTab
+		lockInit(&stacks.lock, lockRankTraceStackTab)
+		stacks.seq = tab.seq
+		stacks.mem = tab.mem
+		stacks.tab = tab.tab
+		tab.mem = traceAlloc{}
+		for i := range tab.tab {
+			tab.tab[i] = 0
+		}
+		trace.flightStacks = stacks
+
+		lock(&trace.stringsLock)
+		if raceenabled {
+			raceacquire(unsafe.Pointer(&trace.stringsLock))
+		}
+		trace.flightStrings = trace.strings
+		trace.strings = strings
+		trace.stringsSize = 0
+		if raceenabled {
+			racerelease(unsafe.Pointer(&trace.stringsLock))
+		}
+		unlock(&trace.stringsLock)
+
+		lock(&trace.lock)
+		trace.flightSwitch = uint64(cputicks()) / traceTickDiv
+		unlock(&trace.lock)
+	} else {
+		running = false
+	}
+
+	unlock(&trace.bufLock)
+	unlock(&sched.sysmonlock)
+	startTheWorldGC()
+
+	if dropped != nil {
+		dropped.mem.drop()
+	}
+	semrelease(&traceFlightSema)
+	return running
+}
+
+// traceFlightSize returns the memory used by the current generation of
+// the string and stack tables.
+func traceFlightSize() uintptr {
+	lock(&trace.stringsLock)
+	size := trace.stringsSize
+	unlock(&trace.stringsLock)
+	tab := &trace.stackTab
+	lock(&tab.lock)
+	for block := tab.mem.head; block != 0; block = block.ptr().next {
+		size += unsafe.Sizeof(traceAllocBlock{})
+	}
+	unlock(&tab.lock)
+	return size
+}
+
+// traceFlightTrim recycles the oldest full buffers until no more than
+// trace.flightBufs remain queued. trace.lock must be held.
+func traceFlightTrim() {
+	for trace.flightQueued > trace.flightBufs {
+		buf := traceFullDequeue()
+		trace.flightQueued--
+		b := buf.ptr()
+		if b.lastTicks > trace.flightCut {
+			trace.flightCut = b.lastTicks
+		}
+		// Keep track of the goroutine running on the buffer's P
+		// for the next buffer in line.
+		data := b.arr[:b.pos]
+		pid, i := traceFlightVarint(data, 1)
+		_, i = traceFlightVarint(data, i)
+		if g := traceFlightGSlot(trace.flightG, pid); g != nil {
+			for i < len(data) {
+				var ev byte
+				var arg uint64
+				ev, _, arg, i = traceFlightEvent(data, i)
+				traceFlightRunning(g, ev, arg)
+			}
+		}
+		b.link = trace.empty
+		trace.empty = buf
+	}
+}
+
+// traceFlightGrow makes room in trace.flightG for n Ps.
+func traceFlightGrow(n int) {
+	if n+1 <= len(trace.flightG) {
+		return
+	}
+	gs := make([]uint64, n+1)
+	lock(&trace.lock)
+	copy(gs, trace.flightG)
+	trace.flightG = gs
+	unlock(&trace.lock)
+}
+
+// traceFlightGSlot returns the element of gs for the P with the given
+// pid, as written to the trace, or nil if there is none.
+func traceFlightGSlot(gs []uint64, pid uint64) *uint64 {
+	i := int(int32(pid)) + 1
+	if i < 0 || i >= len(gs) {
+		return nil
+	}
+	return &gs[i]
+}
+
+// trace_flightSnapshot returns a trace holding the events of the last
+// window nanoseconds that are still retained by the flight recorder,
+// or nil if the flight recorder is not running.
+//
+// The caller must prevent concurrent calls to StopTrace.
+//
+//go:linkname trace_flightSnapshot runtime/trace.flightSnapshot
+func trace_flightSnapshot(window int64) []byte {
+	// Keep the tables from being switched while they are copied.
+	semacquire(&traceFlightSema)
+	defer semrelease(&traceFlightSema)
+
+	stopTheWorldGC("trace snapshot")
+	lock(&sched.sysmonlock)
+	lock(&trace.bufLock)
+
+	if !trace.enabled || trace.flightBufs == 0 {
+		unlock(&trace.bufLock)
+		unlock(&sched.sysmonlock)
+		startTheWorldGC()
+		return nil
+	}
+
+	// Copy the full buffers, followed by the partially filled ones.
+	// Allocating may emit events of our own, and even recycle
+	// buffers, so allocate until everything fits and then copy
+	// without allocating.
+	var state traceFlightState
+	var raw []byte
+	var bufs [][]byte
+	for {
+		n, size := 0, 0
+		for buf := trace.fullHead; buf != 0; buf = buf.ptr().link {
+			n++
+			size += buf.ptr().pos
+		}
+		for _, p := range allp[:cap(allp)] {
+			if buf := p.tracebuf; buf != 0 {
+				n++
+				size += buf.ptr().pos
+			}
+		}
+		if buf := trace.buf; buf != 0 {
+			n++
+			size += buf.ptr().pos
+		}
+		if cap(raw) >= size && cap(bufs) >= n && len(state.g) == len(trace.flightG) {
+			break
+		}
+		raw = make([]byte, 0, size+size/8)
+		bufs = make([][]byte, 0, n+n/8)
+		state.g = make([]uint64, len(trace.flightG))
+		state.kept = make([]bool, len(trace.flightG))
+	}
+	add := func(buf traceBufPtr) {
+		b := buf.ptr()
+		raw = append(raw, b.arr[:b.pos]...)
+		bufs = append(bufs, raw[len(raw)-b.pos:])
+	}
+	lock(&trace.lock)
+	for buf := trace.fullHead; buf != 0; buf = buf.ptr().link {
+		add(buf)
+	}
+	cut := trace.flightCut
+	copy(state.g, trace.flightG)
+	unlock(&trace.lock)
+	for _, p := range allp[:cap(allp)] {
+		if buf := p.tracebuf; buf != 0 {
+			add(buf)
+		}
+	}
+	if buf := trace.buf; buf != 0 {
+		add(buf)
+	}
+
+	nowTicks := uint64(cputicks())
+	now := nanotime()
+	freq := float64(nowTicks-uint64(trace.ticksStart)) * 1e9 / float64(now-trace.timeStart) / traceTickDiv
+	if window > 0 {
+		start := nowTicks/traceTickDiv - uint64(float64(window)*freq/1e9)
+		if start > cut && start < nowTicks/traceTickDiv {
+			cut = start
+		}
+	}
+
+	unlock(&trace.bufLock)
+	unlock(&sched.sysmonlock)
+	startTheWorldGC()
+
+	out := make([]byte, 0, len(traceFlightHeader)+len(raw))
+	out = append(out, traceFlightHeader...)
+	for _, data := range bufs {
+		out = state.filter(out, data, cut)
+	}
+
+	out = append(out, traceEvFrequency|0<<traceArgCountShift)
+	out = traceAppend(out, uint64(freq))
+
+	// Copy both generations of the string dictionary. Every string
+	// the retained events refer to is in one of them. Strings carried
+	// over to the current generation must be written only once.
+	lock(&trace.stringsLock)
+	if raceenabled {
+		raceacquire(unsafe.Pointer(&trace.stringsLock))
+	}
+	strings := make(map[string]uint64, len(trace.strings))
+	for s, id := range trace.strings {
+		strings[s] = id
+	}
+	var old []traceFlightStringEntry
+	for s, id := range trace.flightStrings {
+		if strings[s] != id {
+			old = append(old, traceFlightStringEntry{id, s})
+		}
+	}
+	seq := trace.stringSeq
+	if raceenabled {
+		racerelease(unsafe.Pointer(&trace.stringsLock))
+	}
+	unlock(&trace.stringsLock)
+
+	for _, e := range old {
+		out = traceFlightString(out, e.id, e.s)
+	}
+	for s, id := range strings {
+		out = traceFlightString(out, id, s)
+	}
+	return traceFlightStacks(out, strings, seq)
+}
+
+// traceFlightStringEntry is an entry of the previous generation of the
+// string dictionary.
+type traceFlightStringEntry struct {
+	id uint64
+	s  string
+}
+
+// traceFlightState tracks, for each P, the goroutine that the trace
+// considers to be running, as events are filtered for a snapshot.
+type traceFlightState struct {
+	g    []uint64 // running goroutine or 0, indexed by pid+1
+	kept []bool   // whether any events were kept, indexed by pid+1
+}
+
+// filter appends to out the events of the trace buffer data that
+// happened after cut, as a new batch. The batch's base timestamp is
+// that of the last dropped event, so the deltas of the remaining events
+// are unchanged. String events are dropped, as the whole dictionary is
+// written separately.
+//
+// Events do not name the goroutine they belong to; parsers attribute
+// them to the goroutine last started on the same P. So if a goroutine
+// is running when the first batch for a P begins, the batch starts with
+// a synthetic EvGoStartLocal for it.
+func (s *traceFlightState) filter(out, data []byte, cut uint64) []byte {
+	// Every buffer starts with a batch event: pid and timestamp.
+	pid, i := traceFlightVarint(data, 1)
+	ticks, i := traceFlightVarint(data, i)
+	g := traceFlightGSlot(s.g, pid)
+	if g == nil {
+		return out
+	}
+	kept := &s.kept[int(int32(pid))+1]
+	base := ticks
+	keep := false
+	for i < len(data) {
+		start := i
+		ev, diff, arg, next := traceFlightEvent(data, i)
+		i = next
+		if ev == traceEvString {
+			continue
+		}
+		ticks += diff
+		if !keep && ticks <= cut {
+			base = ticks
+			traceFlightRunning(g, ev, arg)
+			continue
+		}
+		if !keep {
+			// Timestamps may not be monotonic across CPUs, so
+			// once the first event is kept, keep all the rest.
+			keep = true
+			out = append(out, traceEvBatch|1<<traceArgCountShift)
+			out = traceAppend(out, pid)
+			out = traceAppend(out, base)
+			if !*kept && *g != 0 {
+				out = append(out, traceEvGoStartLocal|1<<traceArgCountShift, 0)
+				out = traceAppend(out, *g)
+			}
+			*kept = true
+		}
+		traceFlightRunning(g, ev, arg)
+		out = append(out, data[start:i]...)
+	}
+	return out
+}
+
+// traceFlightEvent decodes the event at data[i:], which must not be a
+// batch event. It returns the event type, its timestamp delta, its
+// first argument, and the index of the next event. String events have
+// no timestamp, and are returned with zero delta and argument.
+func traceFlightEvent(data []byte, i int) (ev byte, diff, arg uint64, next int) {
+	ev = data[i] & (1<<traceArgCountShift - 1)
+	narg := data[i] >> traceArgCountShift
+	i++
+	if ev == traceEvString {
+		var n uint64
+		_, i = traceFlightVarint(data, i)
+		n, i = traceFlightVarint(data, i)
+		return ev, 0, 0, i + int(n)
+	}
+	if narg == 3 {
+		var n uint64
+		n, i = traceFlightVarint(data, i)
+		next = i + int(n)
+		diff, i = traceFlightVarint(data, i)
+		arg, _ = traceFlightVarint(data, i)
+	} else {
+		diff, i = traceFlightVarint(data, i)
+		for j := byte(0); j < narg; j++ {
+			var v uint64
+			v, i = traceFlightVarint(data, i)
+			if j == 0 {
+				arg = v
+			}
+		}
+		next = i
+	}
+	if ev == traceEvUserLog {
+		var n uint64
+		n, next = traceFlightVarint(data, next)
+		next += int(n)
+	}
+	return ev, diff, arg, next
+}
+
+// traceFlightRunning updates *g, the goroutine that the trace considers
+// to be running on a P, for event ev with first argument arg.
+func traceFlightRunning(g *uint64, ev byte, arg uint64) {
+	switch ev {
+	case traceEvGoStart, traceEvGoStartLocal, traceEvGoStartLabel:
+		*g = arg
+	case traceEvGoEnd, traceEvGoStop, traceEvGoSched, traceEvGoPreempt,
+		traceEvGoSleep, traceEvGoBlock, traceEvGoBlockSend, traceEvGoBlockRecv,
+		traceEvGoBlockSelect, traceEvGoBlockSync, traceEvGoBlockCond, traceEvGoBlockNet,
+		traceEvGoSysBlock, traceEvGoBlockGC:
+		*g = 0
+	}
+}
+
+// traceFlightVarint decodes the varint at data[i:] and returns it
+// along with the index following it.
+func traceFlightVarint(data []byte, i int) (uint64, int) {
+	var v uint64
+	for shift := uint(0); i < len(data); shift += 7 {
+		b := data[i]
+		i++
+		v |= uint64(b&0x7f) << shift
+		if b < 0x80 {
+			break
+		}
+	}
+	return v, i
+}
+
+// traceFlightString appends a string event to out.
+func traceFlightString(out []byte, id uint64, s string) []byte {
+	out = append(out, traceEvString)
+	out = traceAppend(out, id)
+	out = traceAppend(out, uint64(len(s)))
+	return append(out, s...)
+}
+
+// traceFlightStacks appends both generations of the stack table to
+// out, like traceStackTable.dump, without releasing them. Function and
+// file names
+// missing from strings are given ids after seq and written as string
+// events.
+func traceFlightStacks(out []byte, strings map[string]uint64, seq uint64) []byte {
+	stringID := func(s string) uint64 {
+		const maxLen = 1 << 10
+		if len(s) > maxLen {
+			s = s[len(s)-maxLen:]
+		}
+		if s == "" {
+			return 0
+		}
+		if id, ok := strings[s]; ok {
+			return id
+		}
+		seq++
+		strings[s] = seq
+		out = traceFlightString(out, seq, s)
+		return seq
+	}
+	var tmp []byte
+	for _, tab := range [...]*traceStackTable{trace.flightStacks, &trace.stackTab} {
+		if tab == nil {
+			continue
+		}
+		for i := range tab.tab {
+			// Stacks are only added while tracing, and published
+			// atomically; see traceStackTable.put.
+			stk := (*traceStack)(atomic.Loadp(unsafe.Pointer(&tab.tab[i])))
+			for ; stk != nil; stk = stk.link.ptr() {
+				frames := allFrames(stk.stack())
+				tmp = traceAppend(tmp[:0], uint64(stk.id))
+				tmp = traceAppend(tmp, uint64(len(frames)))
+				for _, f := range frames {
+					tmp = traceAppend(tmp, uint64(f.PC))
+					tmp = traceAppend(tmp, stringID(f.Function))
+					tmp = traceAppend(tmp, stringID(f.File))
+					tmp = traceAppend(tmp, uint64(f.Line))
+				}
+				out = append(out, traceEvStack|3<<traceArgCountShift)
+				out = traceAppend(out, uint64(len(tmp)))
+				out = append(out, tmp...)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,131 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Type lookup by name.
+//
+// reflect.TypeByName finds a named type from its package path and
+// name. The typelinks of a module list only the unnamed types that
+// reflect may need to look up, such as *T and []T, sorted by their
+// string, which names the package but not its path. The first lookup
+// builds an index of the named types these refer to, as elements,
+// fields, parameters or results, directly or through other types, by
+// package path and name. The index is built again if a plugin adds a module.
+
+package runtime
+
+import (
+	"runtime/internal/atomic"
+	"unsafe"
+)
+
+// typeIndex indexes the named types of the modules in a modulesSlice.
+type typeIndex struct {
+	modules *[]*moduledata    // the modules indexed, as in modulesSlice
+	types   map[string]*_type // by package path, ".", name
+}
+
+// typeIndexCache is the current *typeIndex, or nil if none has been
+// built. Accessed atomically.
+var typeIndexCache unsafe.Pointer
+
+// typeByName returns the named type with the package path and name
+// in name, "path.Name", or "Name" for predeclared types, or nil.
+func typeByName(name string) *_type {
+	modules := (*[]*moduledata)(atomic.Loadp(unsafe.Pointer(&modulesSlice)))
+	if modules == nil {
+		return nil
+	}
+	idx := (*typeIndex)(atomic.Loadp(unsafe.Pointer(&typeIndexCache)))
+	if idx == nil || idx.modules != modules {
+		// Concurrent lookups may each build the index; they
+		// build the same one.
+		idx = buildTypeIndex(modules)
+		atomicstorep(unsafe.Pointer(&typeIndexCache), unsafe.Pointer(idx))
+	}
+	return idx.types[name]
+}
+
+// buildTypeIndex indexes the named types referred to by the typelinks
+// of modules. A type defined in more than one module, as when a
+// plugin is loaded, is indexed as the first module has it.
+func buildTypeIndex(modules *[]*moduledata) *typeIndex {
+	idx := &typeIndex{
+		modules: modules,
+		types:   make(map[string]*_type),
+	}
+	seen := make(map[*_type]struct{})
+	var stack []*_type
+	push := func(t *_type) {
+		if t == nil {
+			return
+		}
+		if _, ok := seen[t]; ok {
+			return
+		}
+		seen[t] = struct{}{}
+		if t.tflag&tflagNamed != 0 {
+			key := t.name()
+			if pkg := t.pkgpath(); pkg != "" {
+				key = pkg + "." + key
+			}
+			if _, ok := idx.types[key]; !ok {
+				idx.types[key] = t
+			}
+		}
+		stack = append(stack, t)
+	}
+	for _, md := range *modules {
+		for _, tl := range md.typelinks {
+			push((*_type)(unsafe.Pointer(md.types + uintptr(tl))))
+			for len(stack) > 0 {
+				t := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				typeRefs(t, push)
+			}
+		}
+	}
+	return idx
+}
+
+// typeRefs calls push for each type that t refers to: its elements,
+// keys, parameters, results, fields or method types.
+func typeRefs(t *_type, push func(*_type)) {
+	switch t.kind & kindMask {
+	case kindArray:
+		push((*arraytype)(unsafe.Pointer(t)).elem)
+	case kindChan:
+		push((*chantype)(unsafe.Pointer(t)).elem)
+	case kindFunc:
+		ft := (*functype)(unsafe.Pointer(t))
+		for _, in := range ft.in() {
+			push(in)
+		}
+		for _, out := range ft.out() {
+			push(out)
+		}
+	case kindInterface:
+		it := (*interfacetype)(unsafe.Pointer(t))
+		for i := range it.mhdr {
+			push(it.typ.typeOff(it.mhdr[i].ityp))
+		}
+	case kindMap:
+		mt := (*maptype)(unsafe.Pointer(t))
+		push(mt.key)
+		push(mt.elem)
+	case kindPtr:
+		push((*ptrtype)(unsafe.Pointer(t)).elem)
+	case kindSlice:
+		push((*slicetype)(unsafe.Pointer(t)).elem)
+	case kindStruct:
+		st := (*structtype)(unsafe.Pointer(t))
+		for i := range st.fields {
+			push(st.fields[i].typ)
+		}
+	}
+}
+
+//go:linkname reflect_typeByName reflect.typeByName
+func reflect_typeByName(name string) unsafe.Pointer {
+	return unsafe.Pointer(typeByName(name))
+}
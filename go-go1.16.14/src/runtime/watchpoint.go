@@ -0,0 +1,171 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+import (
+	"runtime/internal/atomic"
+	_ "unsafe" // for go:linkname
+)
+
+// Hardware watchpoints, for runtime/debug.SetWatchpoint.
+//
+// A hardware data breakpoint is a per-thread setting, so a watchpoint
+// set on one thread would stop working as soon as the goroutine moved
+// to another M. The runtime instead keeps the process's watchpoints in
+// watchpoints and installs all of them on every M that runs Go code:
+// each M records the watchpoints.gen it last installed, and execute
+// reinstalls them when that is out of date, as it does for the CPU
+// profiler. Setting or clearing a watchpoint bumps gen and preempts
+// the running goroutines so that every M catches up promptly.
+//
+// An access to a watched address sends SIGTRAP to the thread that
+// made it. The signal handler records the current goroutine and PC in
+// the watchpoints.hits ring, which runtime/debug.ReadWatchpointHits
+// drains.
+
+// maxWatchpoints is the number of watchpoints that can be set at
+// once, the number of debug address registers on amd64.
+const maxWatchpoints = 4
+
+// watchpointHitsLen is the number of hits kept until they are read.
+const watchpointHitsLen = 64
+
+type watchpoint struct {
+	addr  uintptr
+	size  uintptr
+	reads bool // trigger on reads as well as writes
+	used  bool
+}
+
+// watchpointHit is an access to a watched address. It must match
+// runtime/debug.WatchpointHit.
+type watchpointHit struct {
+	id   int
+	goid int64
+	pc   uintptr
+}
+
+var watchpoints struct {
+	// hitpos is the number of hits ever recorded, and seq[i] is the
+	// value of hitpos for hits[i] once it is completely written.
+	// Both are accessed atomically, since hits are recorded by
+	// signal handlers.
+	hitpos uint64
+	seq    [watchpointHitsLen]uint64
+	hits   [watchpointHitsLen]watchpointHit
+
+	lock    mutex
+	gen     uint32 // incremented on every change of wp; accessed atomically
+	wp      [maxWatchpoints]watchpoint
+	readpos uint64 // number of hits read or dropped
+}
+
+//go:linkname setWatchpoint runtime/debug.setWatchpoint
+func setWatchpoint(addr, size uintptr, reads bool) int {
+	if size != 1 && size != 2 && size != 4 && size != 8 || addr%size != 0 {
+		return -1
+	}
+	w := watchpoint{addr: addr, size: size, reads: reads, used: true}
+	if !watchpointSupported(&w) {
+		return -3
+	}
+	id := -2
+	lock(&watchpoints.lock)
+	for i := range watchpoints.wp {
+		if !watchpoints.wp[i].used {
+			watchpoints.wp[i] = w
+			atomic.Xadd(&watchpoints.gen, 1)
+			id = i
+			break
+		}
+	}
+	unlock(&watchpoints.lock)
+	if id >= 0 {
+		updateWatchpoints()
+	}
+	return id
+}
+
+//go:linkname clearWatchpoint runtime/debug.clearWatchpoint
+func clearWatchpoint(id int) {
+	if id < 0 || id >= maxWatchpoints {
+		return
+	}
+	lock(&watchpoints.lock)
+	changed := watchpoints.wp[id].used
+	if changed {
+		watchpoints.wp[id] = watchpoint{}
+		atomic.Xadd(&watchpoints.gen, 1)
+	}
+	unlock(&watchpoints.lock)
+	if changed {
+		updateWatchpoints()
+	}
+}
+
+// updateWatchpoints installs the current watchpoints on this M right
+// away and makes the running goroutines pass through the scheduler,
+// which installs them on their Ms.
+func updateWatchpoints() {
+	systemstack(func() {
+		setThreadWatchpoints(getg().m)
+	})
+	preemptall()
+}
+
+// currentWatchpoints returns the watchpoints to install and their
+// generation.
+func currentWatchpoints() (wp [maxWatchpoints]watchpoint, gen uint32) {
+	lock(&watchpoints.lock)
+	wp = watchpoints.wp
+	gen = atomic.Load(&watchpoints.gen)
+	unlock(&watchpoints.lock)
+	return wp, gen
+}
+
+// recordWatchpointHit records an access to watchpoint id by gp at pc.
+// It is called from the signal handler.
+//
+//go:nosplit
+//go:nowritebarrierrec
+func recordWatchpointHit(id int, gp *g, pc uintptr) {
+	if mp := gp.m; mp != nil && (gp == mp.g0 || gp == mp.gsignal) && mp.curg != nil {
+		gp = mp.curg
+	}
+	n := atomic.Xadd64(&watchpoints.hitpos, 1)
+	i := (n - 1) % watchpointHitsLen
+	atomic.Store64(&watchpoints.seq[i], 0)
+	watchpoints.hits[i] = watchpointHit{id: id, goid: gp.goid, pc: pc}
+	atomic.Store64(&watchpoints.seq[i], n)
+}
+
+//go:linkname readWatchpointHits runtime/debug.readWatchpointHits
+func readWatchpointHits(hits []watchpointHit) int {
+	lock(&watchpoints.lock)
+	pos := watchpoints.readpos
+	end := atomic.Load64(&watchpoints.hitpos)
+	if end-pos > watchpointHitsLen {
+		pos = end - watchpointHitsLen
+	}
+	n := 0
+	for ; pos < end && n < len(hits); pos++ {
+		i := pos % watchpointHitsLen
+		seq := atomic.Load64(&watchpoints.seq[i])
+		if seq < pos+1 {
+			// Still being written.
+			break
+		}
+		h := watchpoints.hits[i]
+		if seq > pos+1 || atomic.Load64(&watchpoints.seq[i]) != seq {
+			// Overwritten by a later hit; it is lost.
+			continue
+		}
+		hits[n] = h
+		n++
+	}
+	watchpoints.readpos = pos
+	unlock(&watchpoints.lock)
+	return n
+}
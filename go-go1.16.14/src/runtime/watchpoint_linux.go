@@ -0,0 +1,109 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+import "unsafe"
+
+// On Linux, watchpoints are perf breakpoint events, opened by each
+// thread on itself. The events send their overflow signal, SIGTRAP,
+// on every access; the handler tells them apart from other traps by
+// their counts.
+
+const (
+	_PERF_TYPE_BREAKPOINT = 5
+
+	_HW_BREAKPOINT_W  = 2
+	_HW_BREAKPOINT_RW = 3
+)
+
+// watchpointSupported reports whether the kernel lets us set w, by
+// setting it on the calling thread.
+func watchpointSupported(w *watchpoint) bool {
+	fd := openWatchpoint(w, int32(gettid()))
+	if fd < 0 {
+		return false
+	}
+	closefd(fd)
+	return true
+}
+
+// openWatchpoint opens a breakpoint event for w on thread tid. It
+// returns the event's file descriptor, or a negative errno.
+func openWatchpoint(w *watchpoint, tid int32) int32 {
+	attr := perfEventAttr{
+		typ:          _PERF_TYPE_BREAKPOINT,
+		size:         uint32(unsafe.Sizeof(perfEventAttr{})),
+		samplePeriod: 1,
+		flags:        _PERF_ATTR_EXCLUDE_KERNEL | _PERF_ATTR_EXCLUDE_HV,
+		wakeupEvents: 1,
+		bpType:       _HW_BREAKPOINT_W,
+		bpAddr:       uint64(w.addr),
+		bpLen:        uint64(w.size),
+	}
+	if w.reads {
+		attr.bpType = _HW_BREAKPOINT_RW
+	}
+	return openPerfEventAttr(&attr, tid, _SIGTRAP)
+}
+
+// setThreadWatchpoints replaces mp's watchpoints with the current ones.
+// It must be called on mp's thread.
+func setThreadWatchpoints(mp *m) {
+	wp, gen := currentWatchpoints()
+	for i := range wp {
+		if fd := mp.watchfds[i]; fd >= 0 {
+			mp.watchfds[i] = -1
+			closefd(fd)
+		}
+		if !wp[i].used {
+			continue
+		}
+		// A watchpoint that cannot be opened on this thread, for
+		// instance because the thread is out of file descriptors,
+		// does not fire here.
+		if fd := openWatchpoint(&wp[i], int32(mp.procid)); fd >= 0 {
+			mp.watchcounts[i] = 0
+			mp.watchfds[i] = fd
+		}
+	}
+	mp.watchgen = gen
+}
+
+// clearThreadWatchpoints closes mp's watchpoints, which are
+// reinstalled if mp runs Go code again.
+//
+//go:nosplit
+func clearThreadWatchpoints(mp *m) {
+	for i, fd := range mp.watchfds {
+		if fd >= 0 {
+			mp.watchfds[i] = -1
+			closefd(fd)
+		}
+	}
+	mp.watchgen = 0
+}
+
+// watchpointSignal handles a SIGTRAP sent by one of mp's watchpoints,
+// which gp interrupted at pc. It reports whether the signal came from
+// a watchpoint.
+//
+//go:nosplit
+//go:nowritebarrierrec
+func watchpointSignal(mp *m, gp *g, pc uintptr) bool {
+	hit := false
+	for i, fd := range mp.watchfds {
+		if fd < 0 {
+			continue
+		}
+		var count uint64
+		if read(fd, noescape(unsafe.Pointer(&count)), 8) != 8 || count == mp.watchcounts[i] {
+			continue
+		}
+		mp.watchcounts[i] = count
+		recordWatchpointHit(i, gp, pc)
+		hit = true
+	}
+	return hit
+}
@@ -0,0 +1,25 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux
+
+package runtime
+
+import "runtime/internal/atomic"
+
+// Hardware watchpoints are only implemented on Linux.
+
+func watchpointSupported(w *watchpoint) bool {
+	return false
+}
+
+func setThreadWatchpoints(mp *m) {
+	mp.watchgen = atomic.Load(&watchpoints.gen)
+}
+
+//go:nosplit
+//go:nowritebarrierrec
+func watchpointSignal(mp *m, gp *g, pc uintptr) bool {
+	return false
+}
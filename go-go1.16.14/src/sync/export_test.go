@@ -7,6 +7,9 @@ package sync
 // Export for testing.
 var Runtime_Semacquire = runtime_Semacquire
 var Runtime_Semrelease = runtime_Semrelease
+var Runtime_SemacquireTry = runtime_SemacquireTry
+var Runtime_SemacquireDeadline = runtime_SemacquireDeadline
+var Runtime_nanotime = runtime_nanotime
 var Runtime_procPin = runtime_procPin
 var Runtime_procUnpin = runtime_procUnpin
 
@@ -37,6 +37,7 @@ const (
 	mutexLocked = 1 << iota // mutex is locked
 	mutexWoken
 	mutexStarving
+	mutexTracked     // owner is recorded for the deadlock detector; see sync/runtime.go
 	mutexWaiterShift = iota
 
 	// Mutex fairness.
@@ -126,10 +127,18 @@ func (m *Mutex) lockSlow() {
 			}
 			new &^= mutexWoken
 		}
+		if trackLockOwners && old&(mutexLocked|mutexStarving) == 0 {
+			// This CAS locks the mutex, so the owner is
+			// recorded below and Unlock must take the slow path.
+			new |= mutexTracked
+		}
 		if atomic.CompareAndSwapInt32(&m.state, old, new) {
 			if old&(mutexLocked|mutexStarving) == 0 {
 				break // locked the mutex with CAS
 			}
+			if new&mutexStarving != 0 && old&mutexStarving == 0 {
+				runtime_mutexStarving(runtime_nanotime() - waitStartTime)
+			}
 			// If we were already waiting before, queue at the front of the queue.
 			queueLifo := waitStartTime != 0
 			if waitStartTime == 0 {
@@ -147,6 +156,9 @@ func (m *Mutex) lockSlow() {
 					throw("sync: inconsistent mutex state")
 				}
 				delta := int32(mutexLocked - 1<<mutexWaiterShift)
+				if trackLockOwners && old&mutexTracked == 0 {
+					delta += mutexTracked
+				}
 				if !starving || old>>mutexWaiterShift == 1 {
 					// Exit starvation mode.
 					// Critical to do it here and consider wait time.
@@ -168,6 +180,9 @@ func (m *Mutex) lockSlow() {
 	if race.Enabled {
 		race.Acquire(unsafe.Pointer(m))
 	}
+	if trackLockOwners {
+		runtime_setLockOwner(m, true)
+	}
 }
 
 // Unlock unlocks m.
@@ -195,6 +210,21 @@ func (m *Mutex) unlockSlow(new int32) {
 	if (new+mutexLocked)&mutexLocked == 0 {
 		throw("sync: unlock of unlocked mutex")
 	}
+	if new&mutexTracked != 0 {
+		runtime_setLockOwner(m, false)
+		// Clear mutexTracked so that the mutex can be locked on the
+		// fast path again, unless a goroutine that has locked it
+		// since, or that it is being handed off to, records itself.
+		for {
+			old := m.state
+			if old&mutexTracked == 0 || old&(mutexLocked|mutexStarving) != 0 {
+				break
+			}
+			if atomic.CompareAndSwapInt32(&m.state, old, old&^mutexTracked) {
+				break
+			}
+		}
+	}
 	if new&mutexStarving == 0 {
 		old := new
 		for {
@@ -223,7 +223,11 @@ func (p *Pool) pinSlow() (*poolLocal, int) {
 		allPools = append(allPools, p)
 	}
 	// If GOMAXPROCS changes between GCs, we re-allocate the array and lose the old one.
+	// A P above a lowered GOMAXPROCS may run until it parks.
 	size := runtime.GOMAXPROCS(0)
+	if pid >= size {
+		size = pid + 1
+	}
 	local := make([]poolLocal, size)
 	atomic.StorePointer(&p.local, unsafe.Pointer(&local[0])) // store-release
 	runtime_StoreReluintptr(&p.localSize, uintptr(size))     // store-release
@@ -19,6 +19,21 @@ func runtime_Semacquire(s *uint32)
 // runtime_SemacquireMutex's caller.
 func runtime_SemacquireMutex(s *uint32, lifo bool, skipframes int)
 
+// SemacquireRWMutexR is like SemacquireMutex, but for blocking in RWMutex.RLock.
+func runtime_SemacquireRWMutexR(s *uint32, lifo bool, skipframes int)
+
+// SemacquireRWMutex is like SemacquireMutex, but for blocking in RWMutex.Lock.
+func runtime_SemacquireRWMutex(s *uint32, lifo bool, skipframes int)
+
+// SemacquireTry decrements *s if *s > 0 without blocking, and reports
+// whether it did.
+func runtime_SemacquireTry(s *uint32) bool
+
+// SemacquireDeadline is like SemacquireMutex, but gives up waiting once
+// runtime_nanotime reaches deadline. It reports whether it decremented *s.
+// A deadline of 0 means no deadline.
+func runtime_SemacquireDeadline(s *uint32, lifo bool, skipframes int, deadline int64) bool
+
 // Semrelease atomically increments *s and notifies a waiting goroutine
 // if one is blocked in Semacquire.
 // It is intended as a simple wakeup primitive for use by the synchronization
@@ -55,3 +70,30 @@ func runtime_canSpin(i int) bool
 func runtime_doSpin()
 
 func runtime_nanotime() int64
+
+// runtime_mutexStarving records in the mutex starvation profile that
+// the calling Lock switched its mutex to starvation mode after waiting
+// ns nanoseconds.
+func runtime_mutexStarving(ns int64)
+
+// Lock owner tracking for the runtime's partial deadlock detector.
+//
+// The fast paths of Lock and Unlock do not check whether owners are
+// tracked. Instead, when lockSlow locks a Mutex with tracking on, it
+// records the owner and sets mutexTracked in the state. The state is
+// then not 0 when the owner unlocks the Mutex, so Unlock takes the slow
+// path, which forgets the owner and clears mutexTracked again unless
+// another goroutine has locked the Mutex in the meantime. The owner of
+// a Mutex locked on the fast path is therefore unknown.
+
+// runtime_lockOwnersEnabled reports whether GODEBUG=deadlockdetect is set.
+func runtime_lockOwnersEnabled() bool
+
+// runtime_setLockOwner records that the calling goroutine has locked m,
+// or, if locked is false, that m has been unlocked. The latter is a
+// no-op if m has been locked again since.
+func runtime_setLockOwner(m *Mutex, locked bool)
+
+// trackLockOwners is set if lockSlow must record the owner of the
+// Mutex it locks.
+var trackLockOwners = runtime_lockOwnersEnabled()
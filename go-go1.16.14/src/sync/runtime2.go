@@ -2,8 +2,6 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// +build !goexperiment.staticlockranking
-
 package sync
 
 import "unsafe"
@@ -13,7 +11,10 @@ import "unsafe"
 type notifyList struct {
 	wait   uint32
 	notify uint32
+	rank   int     // rank field of the mutex
+	pad    int     // pad field of the mutex
 	lock   uintptr // key field of the mutex
-	head   unsafe.Pointer
-	tail   unsafe.Pointer
+
+	head unsafe.Pointer
+	tail unsafe.Pointer
 }
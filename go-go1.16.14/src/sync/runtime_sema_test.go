@@ -7,9 +7,122 @@ package sync_test
 import (
 	"runtime"
 	. "sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
+func TestSemacquireTry(t *testing.T) {
+	sem := uint32(0)
+	if Runtime_SemacquireTry(&sem) {
+		t.Fatal("SemacquireTry succeeded on an empty semaphore")
+	}
+	Runtime_Semrelease(&sem, false, 0)
+	if !Runtime_SemacquireTry(&sem) {
+		t.Fatal("SemacquireTry failed after Semrelease")
+	}
+	if sem != 0 {
+		t.Fatalf("semaphore is %d, want 0", sem)
+	}
+}
+
+func TestSemacquireDeadline(t *testing.T) {
+	sem := uint32(0)
+	const timeout = 20 * time.Millisecond
+	start := time.Now()
+	if Runtime_SemacquireDeadline(&sem, false, 0, Runtime_nanotime()+int64(timeout)) {
+		t.Fatal("SemacquireDeadline succeeded on an empty semaphore")
+	}
+	if d := time.Since(start); d < timeout {
+		t.Fatalf("SemacquireDeadline gave up after %v, want at least %v", d, timeout)
+	}
+
+	// A deadline in the past still takes an available count.
+	Runtime_Semrelease(&sem, false, 0)
+	if !Runtime_SemacquireDeadline(&sem, false, 0, Runtime_nanotime()-1) {
+		t.Fatal("SemacquireDeadline failed on an available semaphore")
+	}
+
+	done := make(chan bool)
+	go func() {
+		done <- Runtime_SemacquireDeadline(&sem, false, 0, Runtime_nanotime()+int64(time.Minute))
+	}()
+	time.Sleep(timeout)
+	Runtime_Semrelease(&sem, false, 0)
+	if !<-done {
+		t.Fatal("SemacquireDeadline failed after Semrelease")
+	}
+}
+
+func TestSemacquireDeadlineStress(t *testing.T) {
+	const (
+		waiters  = 20
+		releases = 200
+	)
+	sem := uint32(0)
+	var acquired int32
+	var wg WaitGroup
+	for i := 0; i < waiters; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < releases; j++ {
+				timeout := time.Duration(i%4) * 100 * time.Microsecond
+				if Runtime_SemacquireDeadline(&sem, i%2 == 0, 0, Runtime_nanotime()+int64(timeout)+1) {
+					atomic.AddInt32(&acquired, 1)
+				}
+			}
+		}(i)
+	}
+	for i := 0; i < releases; i++ {
+		Runtime_Semrelease(&sem, false, 0)
+		if i%10 == 0 {
+			time.Sleep(50 * time.Microsecond)
+		}
+	}
+	wg.Wait()
+	// Every release was either taken by a waiter or is still available.
+	if n := atomic.LoadInt32(&acquired) + int32(atomic.LoadUint32(&sem)); n != releases {
+		t.Fatalf("%d acquired + %d available = %d, want %d", acquired, sem, n, releases)
+	}
+}
+
+func TestSemacquireDeadlineSudogReuse(t *testing.T) {
+	// A timeout that fires as SemacquireDeadline returns must not
+	// touch the sudog, which the channel receive below reuses.
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	const (
+		waiters = 8
+		iters   = 5000
+	)
+	var wg WaitGroup
+	for i := 0; i < waiters; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem := uint32(0)
+			c := make(chan int)
+			go func() {
+				for j := 0; j < iters; j++ {
+					time.Sleep(time.Duration(j%3) * time.Microsecond)
+					c <- j
+				}
+			}()
+			for j := 0; j < iters; j++ {
+				if j%2 == 0 {
+					go Runtime_Semrelease(&sem, false, 0)
+				}
+				Runtime_SemacquireDeadline(&sem, false, 0, Runtime_nanotime()+int64(j%5)*1000)
+				if _, ok := <-c; !ok {
+					t.Error("receive from an open channel reported it closed")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
 func BenchmarkSemaUncontended(b *testing.B) {
 	type PaddedSem struct {
 		sem uint32